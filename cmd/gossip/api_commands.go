@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/api"
+)
+
+const (
+	// defaultApiAddress represents the address the API commands connect to by default, the default api_address of the configuration.
+	defaultApiAddress = "localhost:7001"
+	// apiDialTimeout represents the time to wait for the connection to the API of a node.
+	apiDialTimeout = 5 * time.Second
+)
+
+// runAnnounceCommand announces a message read from a file or stdin to a running node.
+func runAnnounceCommand(args []string) {
+	announceCmd := flag.NewFlagSet("announce", flag.ExitOnError)
+	apiAddress := announceCmd.String("api", defaultApiAddress, "TCP address of the API of the node")
+	dataType := announceCmd.Uint("type", 0, "Data type of the message")
+	ttl := announceCmd.Uint("ttl", 0, "Number of hops the message is spread, 0 for infinite hops")
+	highPriority := announceCmd.Bool("high-priority", false, "Spread the message in the high-priority lane")
+	inPath := announceCmd.String("file", "-", "Path of the file holding the data of the message, - for stdin")
+	wait := announceCmd.Duration("wait", time.Second, "Time to wait for a rejection of the announcement by the node")
+	_ = announceCmd.Parse(args)
+	if *dataType > 65535 || *ttl > 255 {
+		zap.L().Fatal("Data type must fit into 16 bits and TTL into 8 bits", zap.Uint("type", *dataType), zap.Uint("ttl", *ttl))
+	}
+
+	in := os.Stdin
+	if *inPath != "-" {
+		file, err := os.Open(*inPath)
+		if err != nil {
+			zap.L().Fatal("Error opening data file", zap.Error(err))
+		}
+		defer file.Close()
+		in = file
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		zap.L().Fatal("Error reading data", zap.Error(err))
+	}
+
+	client, err := api.Dial(*apiAddress, apiDialTimeout)
+	if err != nil {
+		zap.L().Fatal("Error connecting to node", zap.Error(err))
+	}
+	defer client.Close()
+	err = client.Announce(uint8(*ttl), uint16(*dataType), data, *highPriority)
+	if err != nil {
+		zap.L().Fatal("Error announcing message", zap.Error(err))
+	}
+
+	// the node only answers announcements it rejects
+	_ = client.SetReadDeadline(time.Now().Add(*wait))
+	_, err = client.ReadNotification()
+	var gossipError *api.GossipError
+	var netError net.Error
+	if errors.As(err, &gossipError) {
+		zap.L().Fatal("Node rejected the announcement", zap.Error(err))
+	} else if err != nil && !(errors.As(err, &netError) && netError.Timeout()) {
+		zap.L().Fatal("Error waiting for the node", zap.Error(err))
+	}
+	fmt.Printf("Announced %d bytes of data type %d\n", len(data), *dataType)
+}
+
+// runListenCommand subscribes to the messages of a data type at a running node and prints every notification until interrupted.
+func runListenCommand(args []string) {
+	listenCmd := flag.NewFlagSet("listen", flag.ExitOnError)
+	apiAddress := listenCmd.String("api", defaultApiAddress, "TCP address of the API of the node")
+	dataType := listenCmd.Uint("type", 0, "Data type to subscribe to")
+	reject := listenCmd.Bool("reject", false, "Report all received messages as invalid, so the node does not spread them further")
+	printHex := listenCmd.Bool("hex", false, "Print the data hex-encoded instead of as text")
+	replay := listenCmd.Bool("replay", false, "Print the notifications the node buffered shortly before subscribing first")
+	_ = listenCmd.Parse(args)
+	if *dataType > 65535 {
+		zap.L().Fatal("Data type must fit into 16 bits", zap.Uint("type", *dataType))
+	}
+
+	client, err := api.Dial(*apiAddress, apiDialTimeout)
+	if err != nil {
+		zap.L().Fatal("Error connecting to node", zap.Error(err))
+	}
+	defer client.Close()
+	err = client.Notify(uint16(*dataType), *replay)
+	if err != nil {
+		zap.L().Fatal("Error subscribing to data type", zap.Error(err))
+	}
+
+	for {
+		notification, err := client.ReadNotification()
+		if errors.Is(err, io.EOF) {
+			fmt.Println("Node closed the connection")
+			return
+		} else if err != nil {
+			zap.L().Fatal("Error receiving notification", zap.Error(err))
+		}
+		data := string(notification.Data)
+		if *printHex {
+			data = hex.EncodeToString(notification.Data)
+		}
+		fmt.Printf("message_id=%d data_type=%d size=%d data=%s\n", notification.MessageID, notification.DataType, len(notification.Data), data)
+		err = client.Validate(notification.MessageID, !*reject)
+		if err != nil {
+			zap.L().Fatal("Error validating notification", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/config"
+	"gossiphers/internal/gossip"
+)
+
+// runGenerateConfigCommand writes a fully commented default configuration file to stdout or the given output file.
+func runGenerateConfigCommand(args []string) {
+	generateCmd := flag.NewFlagSet("generate-config", flag.ExitOnError)
+	outPath := generateCmd.String("o", "", "Path of the generated configuration file (default: stdout)")
+	_ = generateCmd.Parse(args)
+
+	out := os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			zap.L().Fatal("Error creating configuration file", zap.Error(err))
+		}
+		defer file.Close()
+		out = file
+	}
+
+	err := config.WriteDefaultConfig(out)
+	if err != nil {
+		zap.L().Fatal("Error writing configuration", zap.Error(err))
+	}
+}
+
+// runPrintEffectiveConfigCommand prints the configuration resulting from the configuration file, environment variables and flags.
+func runPrintEffectiveConfigCommand(args []string) {
+	printCmd := flag.NewFlagSet("print-effective-config", flag.ExitOnError)
+	cfgPath := printCmd.String("c", "config.ini", "Path to configuration file")
+	overrides := overrideFlags{}
+	printCmd.Var(overrides, "set", "Override a configuration key, e.g. -set degree=20 (repeatable)")
+	_ = printCmd.Parse(args)
+
+	cfg, err := config.ReadConfigWithOverrides(*cfgPath, overrides)
+	if err != nil {
+		zap.L().Fatal("Error reading configuration", zap.Error(err))
+	}
+
+	err = config.WriteEffectiveConfig(os.Stdout, cfg)
+	if err != nil {
+		zap.L().Fatal("Error writing configuration", zap.Error(err))
+	}
+}
+
+// runCheckConfigCommand loads and validates the configuration, checks the environment the node would run in, and reports all problems found.
+// It exits with a non-zero status if there are any.
+func runCheckConfigCommand(args []string) {
+	checkCmd := flag.NewFlagSet("check-config", flag.ExitOnError)
+	cfgPath := checkCmd.String("c", "config.ini", "Path to configuration file")
+	overrides := overrideFlags{}
+	checkCmd.Var(overrides, "set", "Override a configuration key, e.g. -set degree=20 (repeatable)")
+	_ = checkCmd.Parse(args)
+
+	var problems []error
+	cfg, err := config.ReadConfigWithOverrides(*cfgPath, overrides)
+	if err != nil {
+		problems = splitJoinedErrors(err)
+	} else {
+		problems = gossip.CheckConfig(cfg)
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("%s: OK\n", *cfgPath)
+		return
+	}
+	fmt.Printf("%s: %d problem(s) found\n", *cfgPath, len(problems))
+	for _, problem := range problems {
+		fmt.Printf("  - %v\n", problem)
+	}
+	if cfg == nil {
+		fmt.Println("The hostkeys, addresses, and bootstrap nodes are checked once the configuration itself is valid.")
+	}
+	os.Exit(1)
+}
+
+// splitJoinedErrors returns the errors combined by errors.Join, or err itself if it does not combine multiple errors.
+func splitJoinedErrors(err error) []error {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
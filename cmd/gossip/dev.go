@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/config"
+	"gossiphers/internal/simulation"
+)
+
+// runDevCommand simulates the membership protocol of many nodes within this process, using the parameters of the configured
+// node, and prints the state of the simulated network every few rounds. No packets are sent and no keys besides the one of
+// the configuration are required, the rounds advance on a virtual clock as fast as they can be computed.
+func runDevCommand(args []string) {
+	devCmd := flag.NewFlagSet("dev", flag.ExitOnError)
+	cfgPath := devCmd.String("c", "config.ini", "Path to configuration file providing the view size, weights, and sampler size")
+	numNodes := devCmd.Int("n", 200, "Number of simulated nodes")
+	rounds := devCmd.Int("rounds", 100, "Number of simulated rounds")
+	every := devCmd.Int("every", 10, "Print the state of the network every this many rounds")
+	bootstrapPeers := devCmd.Int("bootstrap", 3, "Number of random earlier nodes every node joins with")
+	seed := devCmd.Int64("seed", 0, "Seed of the simulation, 0 for a random one")
+	failShare := devCmd.Float64("fail", 0, "Share of the nodes failing at once, e.g. 0.3")
+	failRound := devCmd.Int("fail-round", 50, "Round after which the nodes fail")
+	overrides := overrideFlags{}
+	devCmd.Var(overrides, "set", "Override a configuration key, e.g. -set degree=20 (repeatable)")
+	adversaries := overrideFlags{}
+	devCmd.Var(adversaries, "adversary", "Turn this many nodes into adversaries before the first round, e.g. -adversary push_flood=20 (repeatable)")
+	_ = devCmd.Parse(args)
+	if *every <= 0 || *failShare < 0 || *failShare > 1 {
+		zap.L().Fatal("-every must be positive and -fail within [0, 1]")
+	}
+
+	cfg, err := config.ReadConfigWithOverrides(*cfgPath, overrides)
+	if err != nil {
+		zap.L().Fatal("Error reading configuration", zap.Error(err))
+	}
+	options := []simulation.Option{simulation.WithBootstrapPeers(*bootstrapPeers)}
+	if *seed != 0 {
+		options = append(options, simulation.WithSeed(*seed))
+	}
+	sim, err := simulation.New(cfg, *numNodes, options...)
+	if err != nil {
+		zap.L().Fatal("Error creating simulation", zap.Error(err))
+	}
+	for behavior, value := range adversaries {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			zap.L().Fatal("Invalid number of adversaries", zap.String("behavior", behavior), zap.Error(err))
+		}
+		corrupted, err := sim.Corrupt(behavior, count)
+		if err != nil {
+			zap.L().Fatal("Error creating adversaries", zap.Error(err))
+		}
+		fmt.Printf("corrupted %d nodes into %s adversaries\n", corrupted, behavior)
+	}
+
+	fmt.Println(sim.Stats())
+	err = sim.Run(*rounds, func(stats simulation.RoundStats) {
+		if stats.Round%*every == 0 {
+			fmt.Println(stats)
+		}
+		if *failShare > 0 && stats.Round == *failRound {
+			failed := sim.Fail(int(*failShare * float64(stats.Alive)))
+			fmt.Printf("failed %d nodes after round %d\n", failed, stats.Round)
+		}
+	})
+	if err != nil {
+		zap.L().Fatal("Error during simulation", zap.Error(err))
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/config"
+	"gossiphers/internal/gossip"
+)
+
+// runIdentityCommand prints the identity, the fingerprint, and the type of the key within the given PEM file, and optionally the
+// hybrid public key of the hybrid private key within a hostkey file.
+func runIdentityCommand(args []string) {
+	identityCmd := flag.NewFlagSet("identity", flag.ExitOnError)
+	keyPath := identityCmd.String("key", "", "Path to a PEM file holding an RSA private or public key")
+	hybrid := identityCmd.Bool("hybrid", false, "Additionally print the PEM block of the hybrid public key of the hybrid private key within a hostkey file")
+	_ = identityCmd.Parse(args)
+	if *keyPath == "" {
+		zap.L().Fatal("Missing key file, set -key <path to PEM file>")
+	}
+
+	publicKey, err := gossip.ReadPublicKey(*keyPath)
+	if err != nil {
+		zap.L().Fatal("Error reading key", zap.Error(err))
+	}
+	identity, err := gossip.IdentityOfPublicKey(publicKey)
+	if err != nil {
+		zap.L().Fatal("Error deriving identity", zap.Error(err))
+	}
+	fingerprint, err := gossip.Fingerprint(publicKey)
+	if err != nil {
+		zap.L().Fatal("Error computing fingerprint", zap.Error(err))
+	}
+	fmt.Printf("identity:    %s\n", identity)
+	fmt.Printf("fingerprint: %s\n", fingerprint)
+	fmt.Printf("type:        RSA %d\n", publicKey.N.BitLen())
+	if !*hybrid {
+		return
+	}
+	hybridKey, err := config.ReadHybridPrivateKey(*keyPath)
+	if err != nil {
+		zap.L().Fatal("Error reading hybrid private key", zap.Error(err))
+	}
+	if hybridKey == nil {
+		zap.L().Fatal("Missing hybrid private key, hostkeys generated by gossip keygen hold one")
+	}
+	hybridPEM, err := gossip.HybridPublicKeyPEM(hybridKey)
+	if err != nil {
+		zap.L().Fatal("Error reading hybrid public key", zap.Error(err))
+	}
+	fmt.Print(string(hybridPEM))
+}
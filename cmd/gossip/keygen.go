@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/gossip"
+)
+
+// runKeygenCommand generates a new hostkey and hybrid key, writes their PEM files to the output directory, and prints the derived identity.
+// With -hybrid-for, it instead adds a hybrid key to an existing hostkey.
+func runKeygenCommand(args []string) {
+	keygenCmd := flag.NewFlagSet("keygen", flag.ExitOnError)
+	outDir := keygenCmd.String("out", ".", "Directory the private key and the public key named by the identity are written to")
+	hybridFor := keygenCmd.String("hybrid-for", "", "Only append a new hybrid private key to the existing hostkey file at this path and print the PEM block of its hybrid public key")
+	_ = keygenCmd.Parse(args)
+
+	if *hybridFor != "" {
+		hybridPEM, err := gossip.AppendHybridKey(*hybridFor)
+		if err != nil {
+			zap.L().Fatal("Error appending hybrid key", zap.Error(err))
+		}
+		fmt.Print(string(hybridPEM))
+		return
+	}
+
+	privateKey, err := gossip.GenerateHostkey()
+	if err != nil {
+		zap.L().Fatal("Error generating hostkey", zap.Error(err))
+	}
+	hybridKey, err := gossip.GenerateHybridKey()
+	if err != nil {
+		zap.L().Fatal("Error generating hybrid key", zap.Error(err))
+	}
+	identity, err := gossip.WriteHostkey(*outDir, privateKey, hybridKey)
+	if err != nil {
+		zap.L().Fatal("Error writing hostkey", zap.Error(err))
+	}
+	fmt.Println(identity.String())
+}
@@ -2,31 +2,143 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"strings"
+
 	"go.uber.org/zap"
 	"gossiphers/internal/config"
+	"gossiphers/internal/debug"
 	"gossiphers/internal/gossip"
+	"gossiphers/internal/health"
+	"gossiphers/internal/logging"
+	"gossiphers/internal/version"
 )
 
 func main() {
-	// Initialize global logger
+	// Initialize global logger used until the configured loggers are set up
 	logger, _ := zap.NewProduction()
 	zap.ReplaceGlobals(logger)
 
-	cfgPath := flag.String("c", "config.ini", "Path to configuration file")
-	flag.Parse()
+	// Without a subcommand the gossip node is started
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		runNode(os.Args[1:])
+		return
+	}
+
+	switch strings.ToLower(os.Args[1]) {
+	case "generate-config":
+		runGenerateConfigCommand(os.Args[2:])
+	case "print-effective-config":
+		runPrintEffectiveConfigCommand(os.Args[2:])
+	case "check-config":
+		runCheckConfigCommand(os.Args[2:])
+	case "keygen":
+		runKeygenCommand(os.Args[2:])
+	case "identity":
+		runIdentityCommand(os.Args[2:])
+	case "ping":
+		runPingCommand(os.Args[2:])
+	case "version":
+		runVersionCommand(os.Args[2:])
+	case "announce":
+		runAnnounceCommand(os.Args[2:])
+	case "listen":
+		runListenCommand(os.Args[2:])
+	case "shell":
+		runShellCommand(os.Args[2:])
+	case "dev":
+		runDevCommand(os.Args[2:])
+	case "peer-db":
+		runPeerDBCommand(os.Args[2:])
+	default:
+		fmt.Println("Usage: gossip [generate-config,print-effective-config,check-config,keygen,identity,ping,announce,listen,shell,dev,peer-db,version] [flags]")
+		os.Exit(1)
+	}
+}
+
+// runNode reads the configuration and runs the gossip protocol until a fatal error occurs or the process is asked to terminate.
+func runNode(args []string) {
+	nodeCmd := flag.NewFlagSet("gossip", flag.ExitOnError)
+	cfgPath := nodeCmd.String("c", "config.ini", "Path to configuration file")
+	overrides := overrideFlags{}
+	nodeCmd.Var(overrides, "set", "Override a configuration key, e.g. -set degree=20 (repeatable)")
+	_ = nodeCmd.Parse(args)
 
-	cfg, err := config.ReadConfig(*cfgPath)
+	cfg, err := config.ReadConfigWithOverrides(*cfgPath, overrides)
 	if err != nil {
 		zap.L().Fatal("Error reading configuration", zap.Error(err))
 	}
+	err = logging.Setup(cfg)
+	if err != nil {
+		zap.L().Fatal("Error setting up logging", zap.Error(err))
+	}
+	defer func() { _ = zap.L().Sync() }()
 
-	zap.L().Debug("Configuration read", zap.Any("config", cfg))
+	zap.L().Info("Starting gossip node", zap.Stringer("version", version.Get()), zap.Int("protocol_version", cfg.ProtocolVersion))
+	zap.L().Debug("Configuration read", zap.Stringer("config", cfg))
+	if err = gossip.SelfCheck(cfg); err != nil {
+		zap.L().Fatal("Self-check failed, refusing to start", zap.Error(err))
+	}
+	var closers []func() error
+	if cfg.DebugAddress != "" {
+		debugServer := debug.NewServer(cfg.DebugAddress)
+		err = debugServer.Start()
+		closers = append(closers, debugServer.Close)
+		if err != nil {
+			zap.L().Fatal("Error starting debug server", zap.Error(err))
+		}
+	}
 	gsp, err := gossip.NewGossip(cfg)
 	if err != nil {
 		zap.L().Fatal("Error creating gossip", zap.Error(err))
 	}
-	err = gsp.Start()
+	if cfg.StateDumpFile != "" {
+		handleStateDumpSignal(gsp, cfg.StateDumpFile)
+	}
+	if cfg.HealthAddress != "" {
+		healthServer := health.NewServer(cfg.HealthAddress, gsp.Ready)
+		err = healthServer.Start()
+		closers = append(closers, healthServer.Close)
+		if err != nil {
+			zap.L().Fatal("Error starting health server", zap.Error(err))
+		}
+	}
+
+	notifySystemd(gsp)
+
+	gossipErr := make(chan error, 1)
+	go func() { gossipErr <- gsp.Start() }()
+	err = handleLifecycleSignals(gsp, cfg, func() (*config.GossipConfig, error) {
+		return config.ReadConfigWithOverrides(*cfgPath, overrides)
+	}, gossipErr)
+	for _, closeFn := range closers {
+		_ = closeFn()
+	}
 	if err != nil {
 		zap.L().Fatal("Error during gossip rounds", zap.Error(err))
 	}
+	zap.L().Info("Gossip node stopped")
+}
+
+// overrideFlags collects repeated key=value flags overriding values of the configuration file.
+type overrideFlags map[string]string
+
+// String returns the string representation of the collected overrides.
+func (o overrideFlags) String() string {
+	var pairs []string
+	for key, value := range o {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses and stores a single key=value pair.
+func (o overrideFlags) Set(pair string) error {
+	key, value, found := strings.Cut(pair, "=")
+	if !found || key == "" {
+		return fmt.Errorf("expected key=value, received %s", pair)
+	}
+	o[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	return nil
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/gossip"
+)
+
+// runPeerDBCommand prints the peers recorded within the peer database file of a node, the most reputable first, e.g. to
+// inventory the peers of a fleet.
+func runPeerDBCommand(args []string) {
+	peerDBCmd := flag.NewFlagSet("peer-db", flag.ExitOnError)
+	dbPath := peerDBCmd.String("db", "", "Path to the peer database file, see peer_db_file")
+	asJSON := peerDBCmd.Bool("json", false, "Print the peers as JSON")
+	_ = peerDBCmd.Parse(args)
+	if *dbPath == "" {
+		zap.L().Fatal("Missing peer database file, set -db <path>")
+	}
+
+	records, err := gossip.ReadPeerDB(*dbPath)
+	if err != nil {
+		zap.L().Fatal("Error reading peer database", zap.Error(err))
+	}
+	now := time.Now()
+	for i := range records {
+		records[i].Reputation, records[i].DecayedAt = records[i].ReputationAt(now), now
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(records)
+		if err != nil {
+			zap.L().Fatal("Error writing peers", zap.Error(err))
+		}
+		return
+	}
+	if len(records) == 0 {
+		fmt.Println("No peers recorded")
+		return
+	}
+	for _, record := range records {
+		fmt.Printf("%s reputation=%.2f last_seen=%s first_seen=%s addresses=%s\n", record.Identity, record.Reputation,
+			record.LastSeen.Format(time.RFC3339), record.FirstSeen.Format(time.RFC3339), strings.Join(record.Addresses, ","))
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/api"
+	"gossiphers/internal/clock"
+	"gossiphers/internal/config"
+	"gossiphers/internal/gossip"
+	"gossiphers/internal/logging"
+)
+
+// runPingCommand sends signed pings to a peer using the key and hostkeys of the configured node and reports the round trip times or why no pong was received.
+// The node itself is not started, the pings are sent from a separate address so a node running with the same configuration is not disturbed.
+func runPingCommand(args []string) {
+	pingCmd := flag.NewFlagSet("ping", flag.ExitOnError)
+	cfgPath := pingCmd.String("c", "config.ini", "Path to configuration file")
+	peer := pingCmd.String("peer", "", "Peer to ping, of the form <identity>,<address>")
+	listenAddress := pingCmd.String("listen", "0.0.0.0:0", "UDP address the pings are sent from and the pongs are received at")
+	count := pingCmd.Int("n", 1, "Number of pings to send")
+	timeout := pingCmd.Duration("timeout", 0, "Time to wait for each pong (default: ping_timeout_ms of the configuration)")
+	overrides := overrideFlags{}
+	pingCmd.Var(overrides, "set", "Override a configuration key, e.g. -set degree=20 (repeatable)")
+	_ = pingCmd.Parse(args)
+	if *peer == "" {
+		zap.L().Fatal("Missing peer, set -peer <identity>,<address>")
+	}
+
+	node, err := gossip.ParseNode(*peer)
+	if err != nil {
+		zap.L().Fatal("Error parsing peer", zap.Error(err))
+	}
+	cfg, err := config.ReadConfigWithOverrides(*cfgPath, overrides)
+	if err != nil {
+		zap.L().Fatal("Error reading configuration", zap.Error(err))
+	}
+	err = logging.Setup(cfg)
+	if err != nil {
+		zap.L().Fatal("Error setting up logging", zap.Error(err))
+	}
+	defer func() { _ = zap.L().Sync() }()
+	if *timeout == 0 {
+		*timeout = time.Millisecond * time.Duration(cfg.PingTimeoutMs)
+	}
+	// never touch the state of a node running with the same configuration
+	cfg.GossipAddress = *listenAddress
+	cfg.MessageStoreFile = ""
+
+	gCrypto, err := gossip.NewCrypto(cfg)
+	if err != nil {
+		zap.L().Fatal("Error initializing crypto", zap.Error(err))
+	}
+	challenger, err := gossip.NewChallenger(cfg)
+	if err != nil {
+		zap.L().Fatal("Error initializing challenger", zap.Error(err))
+	}
+	server, err := gossip.NewServer(cfg, make(chan gossip.Node, 1), make(chan gossip.Node, 1), gCrypto, challenger, api.NewServer(cfg, clock.Or(cfg.Clock)))
+	if err != nil {
+		zap.L().Fatal("Error initializing gossip server", zap.Error(err))
+	}
+	err = server.Start()
+	if err != nil {
+		zap.L().Fatal("Error starting gossip server", zap.Error(err))
+	}
+
+	failed, timedOut := 0, 0
+	for i := 1; i <= *count; i++ {
+		rtt, err := server.Probe(node, *timeout)
+		if err != nil {
+			failed++
+			if errors.Is(err, gossip.ErrPingTimeout) {
+				timedOut++
+			}
+			fmt.Printf("ping %d to %s: %v\n", i, node, err)
+			continue
+		}
+		fmt.Printf("ping %d to %s: rtt %s\n", i, node, rtt)
+	}
+	if timedOut > 0 {
+		fmt.Println("The peer only answers pings if its hostkeys_path contains our public key, check it with the identity subcommand")
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d pings failed\n", failed, *count)
+		os.Exit(1)
+	}
+}
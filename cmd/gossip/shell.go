@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/api"
+)
+
+// shellReplyTimeout represents the time the shell waits for the reply to a status, peers, stats, or quarantine query.
+const shellReplyTimeout = 5 * time.Second
+
+// shellHelp lists the commands of the shell.
+const shellHelp = `Commands:
+  announce [-ttl <hops>] [-high-priority] <type> <data>  spread a message
+  notify [-replay] <type>                                subscribe to a data type, -replay includes recent messages
+  validate <message_id> [true|false]                     report a notification as valid or invalid
+  status <type> <data>                                   delivery status of a message announced through the node
+  peers                                                  peers with measured round trip times
+  stats                                                  packets, bytes, last activity, and failures per peer
+  quarantine                                             addresses whose packets are dropped after repeated failures
+  help                                                   print this help
+  quit                                                   close the connection`
+
+// shell represents an interactive session with the API of a node.
+type shell struct {
+	client *api.Client
+	out    io.Writer
+	// autoValidate reports every notification as valid right away
+	autoValidate bool
+	printHex     bool
+	statuses     chan *api.GossipStatus
+	latencies    chan *api.GossipLatency
+	peerStats    chan *api.GossipPeerStats
+	quarantine   chan *api.GossipQuarantine
+	// closed is closed once the node closed the connection
+	closed chan struct{}
+}
+
+// runShellCommand connects to the API of a running node and executes commands read from stdin until quit or EOF.
+func runShellCommand(args []string) {
+	shellCmd := flag.NewFlagSet("shell", flag.ExitOnError)
+	apiAddress := shellCmd.String("api", defaultApiAddress, "TCP address of the API of the node")
+	manualValidation := shellCmd.Bool("manual-validation", false, "Do not report notifications as valid automatically, use the validate command instead")
+	printHex := shellCmd.Bool("hex", false, "Print the data of notifications hex-encoded instead of as text")
+	_ = shellCmd.Parse(args)
+
+	client, err := api.Dial(*apiAddress, apiDialTimeout)
+	if err != nil {
+		zap.L().Fatal("Error connecting to node", zap.Error(err))
+	}
+	defer client.Close()
+
+	s := &shell{
+		client:       client,
+		out:          os.Stdout,
+		autoValidate: !*manualValidation,
+		printHex:     *printHex,
+		statuses:     make(chan *api.GossipStatus, 1),
+		latencies:    make(chan *api.GossipLatency, 1),
+		peerStats:    make(chan *api.GossipPeerStats, 1),
+		quarantine:   make(chan *api.GossipQuarantine, 1),
+		closed:       make(chan struct{}),
+	}
+	go s.readPackets()
+
+	fmt.Fprintf(s.out, "Connected to %s, type help for a list of commands\n", *apiAddress)
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	for {
+		fmt.Fprint(s.out, "gossip> ")
+		select {
+		case <-s.closed:
+			return
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			quit, err := s.execute(line)
+			if err != nil {
+				fmt.Fprintf(s.out, "error: %v\n", err)
+			}
+			if quit {
+				return
+			}
+		}
+	}
+}
+
+// execute runs a single command line, it returns true if the shell should be closed.
+func (s *shell) execute(line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	args := fields[1:]
+	switch strings.ToLower(fields[0]) {
+	case "announce":
+		return false, s.announce(args)
+	case "notify":
+		return false, s.notify(args)
+	case "validate":
+		return false, s.validate(args)
+	case "status":
+		return false, s.status(args)
+	case "peers":
+		return false, s.peers()
+	case "stats":
+		return false, s.stats()
+	case "quarantine":
+		return false, s.quarantined()
+	case "help":
+		fmt.Fprintln(s.out, shellHelp)
+		return false, nil
+	case "quit", "exit":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown command %s, type help for a list of commands", fields[0])
+	}
+}
+
+// announce spreads the data given after the data type.
+func (s *shell) announce(args []string) error {
+	announceCmd := flag.NewFlagSet("announce", flag.ContinueOnError)
+	announceCmd.SetOutput(s.out)
+	ttl := announceCmd.Uint("ttl", 0, "Number of hops the message is spread, 0 for infinite hops")
+	highPriority := announceCmd.Bool("high-priority", false, "Spread the message in the high-priority lane")
+	if err := announceCmd.Parse(args); err != nil {
+		return err
+	}
+	if announceCmd.NArg() < 2 {
+		return errors.New("usage: announce [-ttl <hops>] [-high-priority] <type> <data>")
+	}
+	if *ttl > 255 {
+		return fmt.Errorf("TTL must fit into 8 bits: %d", *ttl)
+	}
+	dataType, err := parseDataType(announceCmd.Arg(0))
+	if err != nil {
+		return err
+	}
+	data := []byte(strings.Join(announceCmd.Args()[1:], " "))
+	err = s.client.Announce(uint8(*ttl), dataType, data, *highPriority)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(data)
+	fmt.Fprintf(s.out, "Announced %d bytes of data type %d, hash %s\n", len(data), dataType, hex.EncodeToString(hash[:]))
+	return nil
+}
+
+// notify subscribes to a data type, requesting the buffered notifications of the data type if -replay is given.
+func (s *shell) notify(args []string) error {
+	notifyCmd := flag.NewFlagSet("notify", flag.ContinueOnError)
+	notifyCmd.SetOutput(s.out)
+	replay := notifyCmd.Bool("replay", false, "Receive the notifications the node buffered shortly before subscribing first")
+	if err := notifyCmd.Parse(args); err != nil {
+		return err
+	}
+	if notifyCmd.NArg() != 1 {
+		return errors.New("usage: notify [-replay] <type>")
+	}
+	dataType, err := parseDataType(notifyCmd.Arg(0))
+	if err != nil {
+		return err
+	}
+	err = s.client.Notify(dataType, *replay)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "Subscribed to data type %d\n", dataType)
+	return nil
+}
+
+// validate reports a notification as valid, or invalid if false is given.
+func (s *shell) validate(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: validate <message_id> [true|false]")
+	}
+	messageID, err := strconv.ParseUint(args[0], 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid message id %s: %w", args[0], err)
+	}
+	isValid := true
+	if len(args) == 2 {
+		isValid, err = strconv.ParseBool(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid validity %s: %w", args[1], err)
+		}
+	}
+	return s.client.Validate(uint16(messageID), isValid)
+}
+
+// status queries the delivery status of the message with the given data type and data.
+func (s *shell) status(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: status <type> <data>")
+	}
+	dataType, err := parseDataType(args[0])
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256([]byte(strings.Join(args[1:], " ")))
+	err = s.client.QueryStatus(dataType, hash[:])
+	if err != nil {
+		return err
+	}
+	select {
+	case status := <-s.statuses:
+		if !status.IsKnown {
+			fmt.Fprintln(s.out, "Message is unknown to the node")
+			return nil
+		}
+		fmt.Fprintf(s.out, "Message was received by at least %d peers\n", status.Peers)
+		return nil
+	case <-time.After(shellReplyTimeout):
+		return errors.New("node did not answer the status query")
+	}
+}
+
+// peers lists the peers the node measured round trip times to.
+func (s *shell) peers() error {
+	err := s.client.QueryLatency()
+	if err != nil {
+		return err
+	}
+	select {
+	case latency := <-s.latencies:
+		if len(latency.Peers) == 0 {
+			fmt.Fprintln(s.out, "No round trip times measured yet")
+			return nil
+		}
+		for _, peer := range latency.Peers {
+			fmt.Fprintf(s.out, "%s ping=%s/%s (%d samples) pull=%s/%s (%d samples)\n", hex.EncodeToString(peer.Identity),
+				peer.PingMedian, peer.PingP90, peer.PingSamples, peer.PullMedian, peer.PullP90, peer.PullSamples)
+		}
+		fmt.Fprintln(s.out, "Round trip times are given as median/90th percentile")
+		return nil
+	case <-time.After(shellReplyTimeout):
+		return errors.New("node did not answer the peers query")
+	}
+}
+
+// stats lists the protocol statistics the node recorded per peer.
+func (s *shell) stats() error {
+	err := s.client.QueryPeerStats()
+	if err != nil {
+		return err
+	}
+	select {
+	case stats := <-s.peerStats:
+		if len(stats.Peers) == 0 {
+			fmt.Fprintln(s.out, "No peer activity recorded yet")
+			return nil
+		}
+		for _, peer := range stats.Peers {
+			packets := make([]string, 0, len(peer.Packets))
+			for _, counts := range peer.Packets {
+				packets = append(packets, fmt.Sprintf("%#04x=%d/%d", counts.PacketType, counts.Received, counts.Sent))
+			}
+			lastActivity := "never"
+			if !peer.LastActivity.IsZero() {
+				lastActivity = time.Since(peer.LastActivity).Round(time.Second).String() + " ago"
+			}
+			fmt.Fprintf(s.out, "%s bytes=%d/%d send_failures=%d invalid=%d last_activity=%s packets=%s\n", hex.EncodeToString(peer.Identity),
+				peer.BytesReceived, peer.BytesSent, peer.SendFailures, peer.InvalidPackets, lastActivity, strings.Join(packets, ","))
+		}
+		fmt.Fprintln(s.out, "Bytes and packets are given as received/sent, packets per hex-encoded packet type")
+		return nil
+	case <-time.After(shellReplyTimeout):
+		return errors.New("node did not answer the stats query")
+	}
+}
+
+// quarantined lists the addresses whose packets the node drops after repeated invalid signatures or failed challenges.
+func (s *shell) quarantined() error {
+	err := s.client.QueryQuarantine()
+	if err != nil {
+		return err
+	}
+	select {
+	case quarantine := <-s.quarantine:
+		if len(quarantine.Peers) == 0 {
+			fmt.Fprintln(s.out, "No addresses quarantined")
+			return nil
+		}
+		for _, peer := range quarantine.Peers {
+			fmt.Fprintf(s.out, "%s identity=%s failures=%d remaining=%s\n", peer.Address, hex.EncodeToString(peer.Identity), peer.Failures, peer.Remaining)
+		}
+		fmt.Fprintln(s.out, "Identities are claimed by the latest failed packet and may be forged")
+		return nil
+	case <-time.After(shellReplyTimeout):
+		return errors.New("node did not answer the quarantine query")
+	}
+}
+
+// readPackets prints received notifications and rejections, and forwards replies to the waiting commands, until the connection is closed.
+func (s *shell) readPackets() {
+	defer close(s.closed)
+	for {
+		packet, err := s.client.ReadPacket()
+		var gossipError *api.GossipError
+		if errors.As(err, &gossipError) {
+			fmt.Fprintf(s.out, "\nerror: %v\n", gossipError)
+			continue
+		} else if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			fmt.Fprintf(s.out, "\nConnection closed: %v\n", err)
+			return
+		}
+		switch packet := packet.(type) {
+		case *api.GossipNotification:
+			data := string(packet.Data)
+			if s.printHex {
+				data = hex.EncodeToString(packet.Data)
+			}
+			fmt.Fprintf(s.out, "\nnotification message_id=%d data_type=%d size=%d data=%s\n", packet.MessageID, packet.DataType, len(packet.Data), data)
+			if s.autoValidate {
+				err = s.client.Validate(packet.MessageID, true)
+				if err != nil {
+					fmt.Fprintf(s.out, "error: %v\n", err)
+				}
+			}
+		// replies arriving after the command stopped waiting are dropped
+		case *api.GossipStatus:
+			select {
+			case s.statuses <- packet:
+			default:
+			}
+		case *api.GossipLatency:
+			select {
+			case s.latencies <- packet:
+			default:
+			}
+		case *api.GossipPeerStats:
+			select {
+			case s.peerStats <- packet:
+			default:
+			}
+		case *api.GossipQuarantine:
+			select {
+			case s.quarantine <- packet:
+			default:
+			}
+		}
+	}
+}
+
+// parseDataType parses a data type, which must fit into 16 bits.
+func parseDataType(str string) (uint16, error) {
+	dataType, err := strconv.ParseUint(str, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid data type %s: %w", str, err)
+	}
+	return uint16(dataType), nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/config"
+	"gossiphers/internal/gossip"
+	"gossiphers/internal/logging"
+	"gossiphers/internal/systemd"
+)
+
+// shutdownTimeout represents the time granted to the current gossip round to complete before the node stops without leaving.
+const shutdownTimeout = 10 * time.Second
+
+// handleLifecycleSignals blocks until the gossip rounds end with an error or the process receives SIGINT or SIGTERM, upon which the node is shut down gracefully.
+// Upon receiving SIGHUP, the configuration is read again using reload and the logging configuration is applied, changes of all other keys require a restart.
+func handleLifecycleSignals(gsp *gossip.Gossip, cfg *config.GossipConfig, reload func() (*config.GossipConfig, error), gossipErr <-chan error) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case err := <-gossipErr:
+			return err
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				notifySystemdState(systemd.Reloading)
+				reloadConfig(cfg, reload)
+				notifySystemdState(systemd.Ready)
+				continue
+			}
+			zap.L().Info("Shutting down gossip node", zap.Stringer("signal", sig))
+			notifySystemdState(systemd.Stopping)
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			err := gsp.Shutdown(ctx)
+			cancel()
+			if err != nil {
+				zap.L().Warn("Gossip node did not shut down gracefully", zap.Error(err))
+			}
+			return nil
+		}
+	}
+}
+
+// reloadConfig reads the configuration again and applies its logging keys, the current logging is kept if it cannot be read.
+// Changes of other keys compared to the configuration the node was started with are reported.
+func reloadConfig(cfg *config.GossipConfig, reload func() (*config.GossipConfig, error)) {
+	newCfg, err := reload()
+	if err != nil {
+		zap.L().Error("Could not reload configuration, keeping the current one", zap.Error(err))
+		return
+	}
+	_ = zap.L().Sync()
+	err = logging.Setup(newCfg)
+	if err != nil {
+		zap.L().Error("Could not apply reloaded logging configuration, keeping the current one", zap.Error(err))
+		return
+	}
+
+	var restartKeys []string
+	for _, key := range config.ChangedKeys(cfg, newCfg) {
+		if !strings.HasPrefix(key, "log_") {
+			restartKeys = append(restartKeys, key)
+		}
+	}
+	if len(restartKeys) > 0 {
+		zap.L().Warn("Changes of these configuration keys only take effect after a restart", zap.Strings("keys", restartKeys))
+	}
+	zap.L().Info("Reloaded configuration")
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/gossip"
+)
+
+// handleStateDumpSignal writes a state dump of the node to the given file whenever the process receives SIGUSR1.
+func handleStateDumpSignal(gsp *gossip.Gossip, path string) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			err := gsp.WriteStateDump(path)
+			if err != nil {
+				zap.L().Warn("Could not write state dump", zap.String("path", path), zap.Error(err))
+				continue
+			}
+			zap.L().Info("Wrote state dump", zap.String("path", path))
+		}
+	}()
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package main
+
+import (
+	"go.uber.org/zap"
+	"gossiphers/internal/gossip"
+)
+
+// handleStateDumpSignal does nothing, as Windows has no SIGUSR1 to trigger state dumps with.
+func handleStateDumpSignal(_ *gossip.Gossip, path string) {
+	zap.L().Warn("State dumps are not supported on Windows", zap.String("path", path))
+}
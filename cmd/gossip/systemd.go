@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/gossip"
+	"gossiphers/internal/systemd"
+)
+
+// notifySystemd reports readiness to systemd once the first gossip round completed and sends a watchdog ping at the end of every round,
+// so a node whose rounds stall is restarted. It does nothing unless the node runs as a systemd service of Type=notify.
+func notifySystemd(gsp *gossip.Gossip) {
+	watchdogInterval, err := systemd.WatchdogInterval()
+	if err != nil {
+		zap.L().Warn("Ignoring invalid systemd watchdog configuration", zap.Error(err))
+	}
+	if watchdogInterval > 0 {
+		zap.L().Info("Sending systemd watchdog pings every round", zap.Duration("watchdog_interval", watchdogInterval))
+	}
+
+	ready := false
+	gsp.Events().Subscribe(func(event gossip.Event) {
+		roundCompleted, ok := event.(gossip.RoundCompletedEvent)
+		if !ok {
+			return
+		}
+		state := systemd.Status(fmt.Sprintf("round %d, %d nodes in view", roundCompleted.Stats.Round, roundCompleted.ViewSize))
+		if !ready {
+			state = systemd.Ready + "\n" + state
+		}
+		if watchdogInterval > 0 {
+			state = systemd.Watchdog + "\n" + state
+		}
+		sent, err := systemd.Notify(state)
+		if err != nil {
+			zap.L().Warn("Could not notify systemd", zap.Error(err))
+			return
+		}
+		if sent && !ready {
+			zap.L().Info("Notified systemd of readiness")
+		}
+		ready = true
+	})
+}
+
+// notifySystemdState sends a state change to systemd, if the node runs as a systemd service of Type=notify.
+func notifySystemdState(state string) {
+	_, err := systemd.Notify(state)
+	if err != nil {
+		zap.L().Warn("Could not notify systemd", zap.String("state", state), zap.Error(err))
+	}
+}
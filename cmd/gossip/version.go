@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"gossiphers/internal/api"
+	"gossiphers/internal/gossip"
+	"gossiphers/internal/version"
+)
+
+// versionReport represents the build metadata and the protocols supported by the binary.
+type versionReport struct {
+	version.Info
+	ProtocolVersion           uint8    `json:"protocol_version"`
+	SupportedProtocolVersions []int    `json:"supported_protocol_versions"`
+	ApiMessageTypes           []uint16 `json:"api_message_types"`
+	CryptoSchemes             []string `json:"crypto_schemes"`
+	Capabilities              []string `json:"capabilities"`
+}
+
+// runVersionCommand prints the build metadata and the supported protocol versions and crypto schemes.
+func runVersionCommand(args []string) {
+	versionCmd := flag.NewFlagSet("version", flag.ExitOnError)
+	asJSON := versionCmd.Bool("json", false, "Print the report as JSON")
+	_ = versionCmd.Parse(args)
+
+	report := versionReport{
+		Info:            version.Get(),
+		ProtocolVersion: gossip.ProtocolVersion,
+		CryptoSchemes:   gossip.CryptoSchemes,
+		Capabilities:    append([]string{}, gossip.BuildCapabilities.Names()...),
+	}
+	for _, protocolVersion := range gossip.SupportedProtocolVersions {
+		report.SupportedProtocolVersions = append(report.SupportedProtocolVersions, int(protocolVersion))
+	}
+	for _, messageType := range api.SupportedMessageTypes() {
+		report.ApiMessageTypes = append(report.ApiMessageTypes, uint16(messageType))
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(report)
+		if err != nil {
+			zap.L().Fatal("Error writing version", zap.Error(err))
+		}
+		return
+	}
+	fmt.Printf("gossip %s\n", report.Info)
+	fmt.Printf("commit:     %s\n", orUnknown(report.Commit))
+	fmt.Printf("committed:  %s\n", orUnknown(report.CommitDate))
+	fmt.Printf("built:      %s\n", orUnknown(report.BuildDate))
+	fmt.Printf("go:         %s\n", report.GoVersion)
+	fmt.Printf("protocol:   %d (supported: %s)\n", report.ProtocolVersion, joinNumbers(report.SupportedProtocolVersions))
+	fmt.Printf("api:        message types %s\n", joinNumbers(report.ApiMessageTypes))
+	fmt.Printf("features:   %s (advertised from protocol %d)\n", gossip.BuildCapabilities, gossip.CapabilitiesVersion)
+	fmt.Println("crypto:")
+	for _, scheme := range report.CryptoSchemes {
+		fmt.Printf("  %s\n", scheme)
+	}
+}
+
+// orUnknown returns s, or "unknown" if s is empty.
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// joinNumbers returns the comma-separated decimal representation of values.
+func joinNumbers[T int | uint16](values []T) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(parts, ", ")
+}
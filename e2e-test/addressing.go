@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// addressPlan assigns the addresses of the containers within the test network, the container with index n is assigned
+// the address ending in n+2, as the first addresses belong to the network and its gateway.
+type addressPlan struct {
+	// IPv4Prefix contains the first three octets of the IPv4 subnet followed by a dot
+	IPv4Prefix string
+	// IPv6Subnet is the base address of the IPv6 subnet, nil if the network is IPv4-only
+	IPv6Subnet net.IP
+}
+
+// IPv4 returns the IPv4 address of the container with the given index.
+func (p addressPlan) IPv4(index int) string {
+	return p.IPv4Prefix + strconv.Itoa(index+2)
+}
+
+// IPv6 returns the IPv6 address of the container with the given index, empty if the network is IPv4-only.
+func (p addressPlan) IPv6(index int) string {
+	if p.IPv6Subnet == nil {
+		return ""
+	}
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, p.IPv6Subnet.To16())
+	ip[14] = byte((index + 2) >> 8)
+	ip[15] = byte(index + 2)
+	return ip.String()
+}
+
+// GossipAddress returns the UDP address of the gossip server of the container with the given index, which uses IPv6 if
+// the network is dual-stack.
+func (p addressPlan) GossipAddress(index int) string {
+	if p.IPv6Subnet != nil {
+		return net.JoinHostPort(p.IPv6(index), "7002")
+	}
+	return net.JoinHostPort(p.IPv4(index), "7002")
+}
+
+// newAddressPlan returns the address plan of the given IPv4 subnet of at least 256 addresses and optional IPv6 subnet.
+func newAddressPlan(subnet string, subnet6 string) (addressPlan, error) {
+	ip, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return addressPlan{}, err
+	}
+	ones, _ := ipNet.Mask.Size()
+	if ip.To4() == nil || ones > 24 {
+		return addressPlan{}, fmt.Errorf("subnet %s must be an IPv4 subnet of at least 256 addresses", subnet)
+	}
+	octets := strings.Split(ipNet.IP.String(), ".")
+	plan := addressPlan{IPv4Prefix: strings.Join(octets[:3], ".") + "."}
+	if subnet6 != "" {
+		ip, ipNet, err = net.ParseCIDR(subnet6)
+		if err != nil {
+			return addressPlan{}, err
+		}
+		ones, _ = ipNet.Mask.Size()
+		if ip.To4() != nil || ones > 112 {
+			return addressPlan{}, fmt.Errorf("subnet %s must be an IPv6 subnet of at least 65536 addresses", subnet6)
+		}
+		plan.IPv6Subnet = ipNet.IP
+	}
+	return plan, nil
+}
+
+// networkAddressPlan returns the address plan of an existing test network.
+func networkAddressPlan(res types.NetworkResource) (addressPlan, error) {
+	var subnet, subnet6 string
+	for _, cfg := range res.IPAM.Config {
+		ip, _, err := net.ParseCIDR(cfg.Subnet)
+		if err != nil {
+			continue
+		}
+		if ip.To4() != nil {
+			subnet = cfg.Subnet
+		} else if res.EnableIPv6 {
+			subnet6 = cfg.Subnet
+		}
+	}
+	return newAddressPlan(subnet, subnet6)
+}
+
+// resourceLimits represents the CPU and memory limits of each gossip container, zero values leave the resource unlimited.
+type resourceLimits struct {
+	CPUs        float64
+	MemoryBytes int64
+}
+
+// apply sets the limits within the host config of a container.
+func (r resourceLimits) apply(hostCfg *container.HostConfig) {
+	hostCfg.Resources.NanoCPUs = int64(r.CPUs * 1e9)
+	hostCfg.Resources.Memory = r.MemoryBytes
+}
+
+// addResourceFlags registers the flags limiting the resources of the containers, the returned function parses them once
+// the flag set has been parsed and returns an error if they are invalid.
+func addResourceFlags(flagSet *flag.FlagSet) func() (resourceLimits, error) {
+	cpus := flagSet.Float64("cpus", 0, "Number of CPUs each container may use, e.g. 0.25, 0 for no limit")
+	memory := flagSet.String("memory", "", "Memory each container may use, e.g. 64MB, empty for no limit")
+	return func() (resourceLimits, error) {
+		limits := resourceLimits{CPUs: *cpus}
+		if *memory != "" {
+			bytes, err := parseSize(*memory)
+			if err != nil {
+				return resourceLimits{}, err
+			}
+			limits.MemoryBytes = int64(bytes)
+		}
+		if limits.CPUs < 0 || (*memory != "" && limits.MemoryBytes < 6<<20) {
+			return resourceLimits{}, errors.New("cpus must not be negative and memory must be at least 6MB, the minimum of Docker")
+		}
+		return limits, nil
+	}
+}
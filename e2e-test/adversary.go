@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dockerBuildTags are the build tags of the gossip binary within the test image. The adversary tag enables the adversarial
+// behaviors, which honest nodes never use.
+const dockerBuildTags = "adversary"
+
+// adversaryBehaviors are the adversarial behaviors of the adversary key of the gossip section.
+var adversaryBehaviors = []string{"push_flood", "pull_poison", "message_spam", "silent"}
+
+// adversaryAssignment maps the indices of nodes to their adversarial behavior.
+type adversaryAssignment map[int]string
+
+// parseAdversaries parses comma-separated index=behavior pairs, e.g. 3=push_flood,5=silent. Ranges of indices are joined by
+// -, e.g. 1-4=pull_poison.
+func parseAdversaries(str string) (adversaryAssignment, error) {
+	if str == "" {
+		return nil, nil
+	}
+	assignment := make(adversaryAssignment)
+	for _, pair := range strings.Split(str, ",") {
+		indicesStr, behavior, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || !isAdversaryBehavior(behavior) {
+			return nil, fmt.Errorf("expected index=behavior with a behavior of %s, received %s", strings.Join(adversaryBehaviors, ", "), pair)
+		}
+		firstStr, lastStr, isRange := strings.Cut(indicesStr, "-")
+		if !isRange {
+			lastStr = firstStr
+		}
+		first, err := strconv.Atoi(firstStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node index within %s: %w", pair, err)
+		}
+		last, err := strconv.Atoi(lastStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node index within %s: %w", pair, err)
+		}
+		// the bootstrap node must stay honest for the other nodes to join the network at all
+		if first < 1 || last < first {
+			return nil, fmt.Errorf("the node indices of %s must be ascending and start at 1, the bootstrap node is always honest", pair)
+		}
+		for i := first; i <= last; i++ {
+			assignment[i] = behavior
+		}
+	}
+	return assignment, nil
+}
+
+// isAdversaryBehavior reports whether the given behavior is one of adversaryBehaviors.
+func isAdversaryBehavior(behavior string) bool {
+	for _, b := range adversaryBehaviors {
+		if b == behavior {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the assignment in the format parsed by parseAdversaries.
+func (a adversaryAssignment) String() string {
+	indices := make([]int, 0, len(a))
+	for i := range a {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	var pairs []string
+	for _, i := range indices {
+		pairs = append(pairs, strconv.Itoa(i)+"="+a[i])
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Message types of the API of the gossip module, see the README.
+const (
+	apiTypeAnnounce     uint16 = 500
+	apiTypeNotify       uint16 = 501
+	apiTypeNotification uint16 = 502
+	apiTypeValidation   uint16 = 503
+	apiTypeError        uint16 = 506
+)
+
+// apiNotification represents a message received from the API of a node.
+type apiNotification struct {
+	MessageID uint16
+	DataType  uint16
+	Data      []byte
+}
+
+// apiClient represents a connection to the API of a gossip node. The e2e tool is a separate module and implements the
+// few messages it needs itself, the gossip module offers a complete client within its internal api package.
+type apiClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// writeLock ensures every packet is written with a single write, as the server expects one packet per read
+	writeLock sync.Mutex
+}
+
+// dialAPI connects to the API of a gossip node.
+func dialAPI(address string) (*apiClient, error) {
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &apiClient{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Announce requests the node to spread a message with infinite hops.
+func (c *apiClient) Announce(dataType uint16, data []byte) error {
+	if 8+len(data) > 65535 {
+		return fmt.Errorf("data of %d bytes does not fit into a packet", len(data))
+	}
+	packet := binary.BigEndian.AppendUint16(nil, uint16(8+len(data)))
+	packet = binary.BigEndian.AppendUint16(packet, apiTypeAnnounce)
+	// TTL and flags
+	packet = append(packet, 0, 0)
+	packet = binary.BigEndian.AppendUint16(packet, dataType)
+	return c.write(append(packet, data...))
+}
+
+// Notify subscribes to the notifications of messages of a data type.
+func (c *apiClient) Notify(dataType uint16) error {
+	packet := binary.BigEndian.AppendUint16(nil, 8)
+	packet = binary.BigEndian.AppendUint16(packet, apiTypeNotify)
+	packet = binary.BigEndian.AppendUint16(packet, 0)
+	return c.write(binary.BigEndian.AppendUint16(packet, dataType))
+}
+
+// Validate reports the data of a notification as valid, so the node spreads the message further.
+func (c *apiClient) Validate(messageID uint16) error {
+	packet := binary.BigEndian.AppendUint16(nil, 8)
+	packet = binary.BigEndian.AppendUint16(packet, apiTypeValidation)
+	packet = binary.BigEndian.AppendUint16(packet, messageID)
+	return c.write(binary.BigEndian.AppendUint16(packet, 1))
+}
+
+// ReadNotification blocks until the next notification is received, rejections of announcements are returned as errors
+// and all other packets are skipped.
+func (c *apiClient) ReadNotification() (*apiNotification, error) {
+	for {
+		header := make([]byte, 4)
+		_, err := io.ReadFull(c.reader, header)
+		if err != nil {
+			return nil, err
+		}
+		size := binary.BigEndian.Uint16(header[:2])
+		if size < 4 {
+			return nil, fmt.Errorf("invalid packet size %d", size)
+		}
+		body := make([]byte, size-4)
+		_, err = io.ReadFull(c.reader, body)
+		if err != nil {
+			return nil, err
+		}
+		switch binary.BigEndian.Uint16(header[2:4]) {
+		case apiTypeNotification:
+			if len(body) < 4 {
+				return nil, fmt.Errorf("invalid notification size %d", size)
+			}
+			return &apiNotification{
+				MessageID: binary.BigEndian.Uint16(body[:2]),
+				DataType:  binary.BigEndian.Uint16(body[2:4]),
+				Data:      body[4:],
+			}, nil
+		case apiTypeError:
+			if len(body) < 4 {
+				return nil, fmt.Errorf("invalid error size %d", size)
+			}
+			return nil, fmt.Errorf("node rejected request with code %d: %s", binary.BigEndian.Uint16(body[2:4]), body[4:])
+		}
+	}
+}
+
+// Close closes the connection to the node.
+func (c *apiClient) Close() error {
+	return c.conn.Close()
+}
+
+// write sends a packet to the node.
+func (c *apiClient) write(packet []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.conn.Write(packet)
+	return err
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	dockerClient "github.com/docker/docker/client"
+)
+
+// runChurnCommand repeatedly stops random gossip containers and restarts them after a downtime, until the given number
+// of cycles completed or the command is interrupted, upon which all stopped containers are restarted.
+func runChurnCommand(args []string) error {
+	churnCmd := flag.NewFlagSet("churn", flag.ContinueOnError)
+	numKill := churnCmd.Int("kill", 3, "Number of containers stopped per cycle")
+	interval := churnCmd.Duration("interval", 30*time.Second, "Time in between the starts of two cycles")
+	downtime := churnCmd.Duration("downtime", 0, "Time stopped containers stay down before being restarted, defaults to half the interval")
+	cycles := churnCmd.Int("cycles", 0, "Number of cycles, 0 to churn until interrupted")
+	crash := churnCmd.Bool("crash", false, "Kill containers with SIGKILL instead of stopping them gracefully, so they cannot tell their peers they leave")
+	includeBootstrap := churnCmd.Bool("include-bootstrap", false, "Also stop the bootstrap container, whose API is exposed on the host")
+	seed := churnCmd.Int64("seed", time.Now().UnixNano(), "Seed of the random selection of containers, to reproduce a run")
+	err := churnCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *downtime == 0 {
+		*downtime = *interval / 2
+	}
+	if *numKill <= 0 || *downtime >= *interval {
+		return errors.New("kill must be positive and the downtime shorter than the interval")
+	}
+
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	containers, err := listGossipContainers(ctx, cli)
+	if err != nil {
+		return err
+	}
+	candidates := churnCandidates(containers, *includeBootstrap)
+	for _, c := range candidates {
+		if c.Netem != "" {
+			err = ensureNetHelperImage(ctx, cli)
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if len(candidates) < *numKill {
+		return fmt.Errorf("cannot stop %d of %d running candidate containers, start a larger test network", *numKill, len(candidates))
+	}
+	log.Printf("Churning %d of %d containers every %s with a downtime of %s, seed %d\n", *numKill, len(candidates), *interval, *downtime, *seed)
+	rng := rand.New(rand.NewSource(*seed))
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for cycle := 1; ; cycle++ {
+		rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+		stopped := candidates[:*numKill]
+		for i, c := range stopped {
+			err = stopContainer(ctx, cli, c, *crash)
+			if err != nil {
+				// the containers stopped so far are not left behind
+				return errors.Join(err, restartContainers(ctx, cli, stopped[:i]))
+			}
+		}
+		log.Printf("Cycle %d: stopped %d containers\n", cycle, len(stopped))
+
+		select {
+		case <-time.After(*downtime):
+		case <-interrupt:
+			err = restartContainers(ctx, cli, stopped)
+			if err != nil {
+				return err
+			}
+			log.Println("Interrupted, restarted all stopped containers")
+			return nil
+		}
+		err = restartContainers(ctx, cli, stopped)
+		if err != nil {
+			return err
+		}
+		log.Printf("Cycle %d: restarted %d containers\n", cycle, len(stopped))
+
+		if cycle == *cycles {
+			log.Println("Finished!")
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-interrupt:
+			log.Println("Interrupted")
+			return nil
+		}
+	}
+}
+
+// churnCandidates returns the running containers that may be stopped.
+func churnCandidates(containers []gossipContainer, includeBootstrap bool) []gossipContainer {
+	var candidates []gossipContainer
+	for _, c := range containers {
+		if c.Running && (includeBootstrap || !c.IsBootstrap()) {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// stopContainer stops a gossip container, either gracefully with SIGTERM or abruptly with SIGKILL.
+func stopContainer(ctx context.Context, cli *dockerClient.Client, c gossipContainer, crash bool) error {
+	var err error
+	if crash {
+		err = cli.ContainerKill(ctx, c.ID, "SIGKILL")
+	} else {
+		// the node completes its current round and sends leave packets before exiting
+		timeout := 15
+		err = cli.ContainerStop(ctx, c.ID, container.StopOptions{Timeout: &timeout})
+	}
+	if err != nil {
+		return fmt.Errorf("could not stop container %s: %w", c.Name, err)
+	}
+	log.Printf("Stopped %s\n", c.Name)
+	return nil
+}
+
+// restartContainers starts the given stopped gossip containers again.
+func restartContainers(ctx context.Context, cli *dockerClient.Client, containers []gossipContainer) error {
+	for _, c := range containers {
+		err := cli.ContainerStart(ctx, c.ID, types.ContainerStartOptions{})
+		if err != nil {
+			return fmt.Errorf("could not restart container %s: %w", c.Name, err)
+		}
+		log.Printf("Restarted %s\n", c.Name)
+		// the network namespace of the container is recreated, so the emulated network conditions are lost
+		if c.Netem != "" {
+			profile, err := parseNetemProfile(c.Netem)
+			if err != nil {
+				return err
+			}
+			err = applyNetem(ctx, cli, c, profile)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeFilePath represents the default path of the generated compose file, which is deleted by the stop subcommand.
+const composeFilePath = "docker-compose.yml"
+
+// composeFile represents the subset of the compose specification describing a test network.
+type composeFile struct {
+	Name     string                    `yaml:"name"`
+	Services map[string]composeService `yaml:"services"`
+	Networks map[string]composeNetwork `yaml:"networks"`
+}
+
+// composeService represents the container of a gossip node within a compose file.
+type composeService struct {
+	Image         string                           `yaml:"image"`
+	Build         *composeBuild                    `yaml:"build,omitempty"`
+	PullPolicy    string                           `yaml:"pull_policy,omitempty"`
+	DependsOn     []string                         `yaml:"depends_on,omitempty"`
+	ContainerName string                           `yaml:"container_name"`
+	Labels        map[string]string                `yaml:"labels"`
+	Volumes       []string                         `yaml:"volumes"`
+	Tmpfs         []string                         `yaml:"tmpfs"`
+	Ports         []string                         `yaml:"ports"`
+	Networks      map[string]composeServiceNetwork `yaml:"networks"`
+	CPUs          float64                          `yaml:"cpus,omitempty"`
+	MemLimit      int64                            `yaml:"mem_limit,omitempty"`
+}
+
+// composeBuild represents how the image of a service is built within a compose file.
+type composeBuild struct {
+	Context string            `yaml:"context"`
+	Args    map[string]string `yaml:"args,omitempty"`
+}
+
+// composeServiceNetwork represents the address of a container within a network of a compose file.
+type composeServiceNetwork struct {
+	IPv4Address string `yaml:"ipv4_address"`
+	IPv6Address string `yaml:"ipv6_address,omitempty"`
+}
+
+// composeNetwork represents the test network within a compose file.
+type composeNetwork struct {
+	Name       string             `yaml:"name"`
+	Driver     string             `yaml:"driver"`
+	EnableIPv6 bool               `yaml:"enable_ipv6,omitempty"`
+	IPAM       composeNetworkIPAM `yaml:"ipam"`
+}
+
+// composeNetworkIPAM represents the address management of the test network within a compose file.
+type composeNetworkIPAM struct {
+	Config []map[string]string `yaml:"config"`
+}
+
+// runComposeCommand generates the keys and config files of a test network like the start subcommand, but writes a compose
+// file launching it instead of driving the Docker API, for setups without Docker Engine or rootless ones.
+func runComposeCommand(args []string) error {
+	composeCmd := flag.NewFlagSet("compose", flag.ContinueOnError)
+	numNodes := composeCmd.Int("n", 10, "Number of gossip containers within the compose file")
+	subnet := composeCmd.String("subnet", "172.28.0.0/24", "IPv4 subnet of the test network, at most 253 nodes fit into it")
+	out := composeCmd.String("out", composeFilePath, "Path the compose file is written to")
+	networkOpts := addNetworkFlags(composeCmd)
+	err := composeCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	opts, err := networkOpts()
+	if err != nil {
+		return err
+	}
+	if len(opts.Netem) > 0 {
+		return errors.New("netem profiles cannot be part of a compose file")
+	}
+	plan, err := newAddressPlan(*subnet, opts.Subnet6)
+	if err != nil {
+		return err
+	}
+	if *numNodes < 1 || *numNodes > 253 {
+		return errors.New("the number of nodes must be between 1 and 253")
+	}
+
+	identities, err := generateTestKeys(*numNodes)
+	if err != nil {
+		return err
+	}
+	err = generateTestConfigs(identities, plan, opts)
+	if err != nil {
+		return err
+	}
+
+	compose := composeFile{
+		Name:     "gossiphers-test",
+		Services: make(map[string]composeService),
+		Networks: map[string]composeNetwork{dockerNetworkName: {
+			Name:   dockerNetworkName,
+			Driver: "bridge",
+			IPAM:   composeNetworkIPAM{Config: []map[string]string{{"subnet": *subnet}}},
+		}},
+	}
+	if opts.Subnet6 != "" {
+		network := compose.Networks[dockerNetworkName]
+		network.EnableIPv6 = true
+		network.IPAM.Config = append(network.IPAM.Config, map[string]string{"subnet": opts.Subnet6})
+		compose.Networks[dockerNetworkName] = network
+	}
+	for n, identity := range identities {
+		service := composeService{
+			Image:         dockerImageName,
+			ContainerName: "gossip-" + identity,
+			Labels:        containerLabels(n, identity, opts.Variant(n)),
+			Volumes: []string{
+				"./" + testConfigsDir + "/" + identity + ".ini:/config.ini",
+				"./" + filepath.ToSlash(certsDirOf(identity)) + ":/keys",
+				"./" + testKeysDir + "/" + identity + ":/nodekey.pem",
+			},
+			// the nodes write their state dumps into /state upon SIGUSR1
+			Tmpfs:    []string{stateDumpDir + ":mode=1777"},
+			Ports:    []string{"127.0.0.1::7001", "127.0.0.1::7003"},
+			Networks: map[string]composeServiceNetwork{dockerNetworkName: {IPv4Address: plan.IPv4(n), IPv6Address: plan.IPv6(n)}},
+			CPUs:     opts.Resources.CPUs,
+			MemLimit: opts.Resources.MemoryBytes,
+		}
+		if n == 0 {
+			// the image is built once for all services
+			service.Build = &composeBuild{Context: "..", Args: map[string]string{"TAGS": dockerBuildTags}}
+			service.Ports = []string{"7001:7001", "127.0.0.1::7003"}
+		} else {
+			service.PullPolicy = "never"
+			service.DependsOn = []string{"node0"}
+		}
+		compose.Services["node"+strconv.Itoa(n)] = service
+	}
+
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	err = encoder.Encode(compose)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(*out, data.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	log.Printf("Wrote the compose file of %d nodes to %s, launch it with 'docker compose -f %[2]s up -d' or 'podman-compose -f %[2]s up -d'\n", *numNodes, *out)
+	log.Println("API of container gossip-" + identities[0] + " will be available at localhost:7001")
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	dockerClient "github.com/docker/docker/client"
+)
+
+const (
+	// labelRole marks a gossip container as either the bootstrap node or a regular peer.
+	labelRole = "gossiphers.role"
+	// labelIndex holds the index of a gossip container within the test network, the bootstrap node has index 0.
+	labelIndex = "gossiphers.index"
+	// labelIdentity holds the hex-encoded identity of the node within a gossip container.
+	labelIdentity = "gossiphers.identity"
+
+	roleBootstrap = "bootstrap"
+	rolePeer      = "peer"
+)
+
+// gossipContainer represents a container of the test network running a gossip node.
+type gossipContainer struct {
+	ID       string
+	Name     string
+	Index    int
+	Identity string
+	// IP is the address of the container within the test network, empty if it is not running
+	IP string
+	// IPv6 is the IPv6 address of the container within a dual-stack test network, empty if it is IPv4-only or not running
+	IPv6    string
+	Running bool
+	// APIAddress is the address the API of the node is published at on the host, empty if it is not running
+	APIAddress string
+	// MetricsAddress is the address the debug endpoint of the node is published at on the host, empty if it is not running
+	MetricsAddress string
+	// Netem is the netem profile applied to the container, empty if the network conditions are pristine
+	Netem string
+	// Config are the config overrides of the node, empty if it uses the config template as is
+	Config string
+}
+
+// IsBootstrap reports whether the container runs the bootstrap node all other nodes initially connect to.
+func (c gossipContainer) IsBootstrap() bool {
+	return c.Index == 0
+}
+
+// podmanSockets contains the paths of the Docker-compatible API sockets of rootless and rootful Podman.
+var podmanSockets = []string{
+	filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), "podman", "podman.sock"),
+	"/run/podman/podman.sock",
+}
+
+// newDockerClient returns a client of the docker daemon configured by the environment. Without DOCKER_HOST and Docker
+// Engine socket, the client connects to the API socket of Podman if one exists.
+func newDockerClient() (*dockerClient.Client, error) {
+	log.Println("Starting docker client...")
+	opts := []dockerClient.Opt{dockerClient.FromEnv, dockerClient.WithAPIVersionNegotiation()}
+	if _, err := os.Stat("/var/run/docker.sock"); os.Getenv("DOCKER_HOST") == "" && err != nil {
+		for _, socket := range podmanSockets {
+			if _, err := os.Stat(socket); err == nil {
+				log.Println("Using the Podman socket " + socket)
+				opts = append(opts, dockerClient.WithHost("unix://"+socket))
+				break
+			}
+		}
+	}
+	return dockerClient.NewClientWithOpts(opts...)
+}
+
+// isGossipImage reports whether a container runs the test image, which Podman prefixes with the localhost registry.
+func isGossipImage(image string) bool {
+	return image == dockerImageName || image == "localhost/"+dockerImageName
+}
+
+// containerLabels returns the labels of the gossip container with the given index, identity, and config overrides.
+func containerLabels(index int, identity string, variant configVariant) map[string]string {
+	role := rolePeer
+	if index == 0 {
+		role = roleBootstrap
+	}
+	labels := map[string]string{labelRole: role, labelIndex: strconv.Itoa(index), labelIdentity: identity}
+	if len(variant) > 0 {
+		labels[labelConfig] = variant.String()
+	}
+	return labels
+}
+
+// listGossipContainers returns all containers of the test network, running or not, ordered by their index.
+func listGossipContainers(ctx context.Context, cli *dockerClient.Client) ([]gossipContainer, error) {
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: filters.NewArgs(filters.Arg("label", labelIndex))})
+	if err != nil {
+		return nil, err
+	}
+	var gossipContainers []gossipContainer
+	for _, c := range containers {
+		if !isGossipImage(c.Image) {
+			continue
+		}
+		index, err := strconv.Atoi(c.Labels[labelIndex])
+		if err != nil {
+			return nil, fmt.Errorf("container %s has an invalid index label: %w", c.ID, err)
+		}
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = c.Names[0][1:]
+		}
+		var ip, ip6 string
+		if c.NetworkSettings != nil {
+			if endpoint, ok := c.NetworkSettings.Networks[dockerNetworkName]; ok {
+				ip = endpoint.IPAddress
+				ip6 = endpoint.GlobalIPv6Address
+			}
+		}
+		gossipContainers = append(gossipContainers, gossipContainer{
+			ID:             c.ID,
+			Name:           name,
+			Index:          index,
+			Identity:       c.Labels[labelIdentity],
+			IP:             ip,
+			IPv6:           ip6,
+			Running:        c.State == "running",
+			APIAddress:     publishedAddress(c.Ports, 7001),
+			MetricsAddress: publishedAddress(c.Ports, 7003),
+			Netem:          c.Labels[labelNetem],
+			Config:         c.Labels[labelConfig],
+		})
+	}
+	sort.Slice(gossipContainers, func(i, j int) bool {
+		return gossipContainers[i].Index < gossipContainers[j].Index
+	})
+	return gossipContainers, nil
+}
+
+// publishedAddress returns the address a TCP port of a container is published at on the host, empty if it is not published.
+func publishedAddress(ports []types.Port, privatePort uint16) string {
+	for _, port := range ports {
+		if port.PrivatePort == privatePort && port.Type == "tcp" && port.PublicPort != 0 {
+			return net.JoinHostPort("localhost", strconv.Itoa(int(port.PublicPort)))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	dockerClient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// runLogsCommand collects the logs of all gossip containers into one file per container within a local directory, or
+// streams them prefixed by the container index to stdout until interrupted.
+func runLogsCommand(args []string) error {
+	logsCmd := flag.NewFlagSet("logs", flag.ContinueOnError)
+	out := logsCmd.String("out", "logs", "Directory the log files are written to, one per container")
+	follow := logsCmd.Bool("f", false, "Stream the logs of all containers to stdout until interrupted instead of writing them to files")
+	tail := logsCmd.String("tail", "all", "Number of lines to show from the end of the logs of each container")
+	index := logsCmd.Int("i", -1, "Index of the only container whose logs are shown, -1 for all containers")
+	err := logsCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	all, err := listGossipContainers(ctx, cli)
+	if err != nil {
+		return err
+	}
+	var containers []gossipContainer
+	for _, c := range all {
+		if *index < 0 || c.Index == *index {
+			containers = append(containers, c)
+		}
+	}
+	if len(containers) == 0 {
+		return errors.New("no matching gossip containers, start a test network first")
+	}
+
+	if *follow {
+		return followLogs(ctx, cli, containers, *tail)
+	}
+	err = os.MkdirAll(*out, 0755)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		path := filepath.Join(*out, fmt.Sprintf("%d-%s.log", c.Index, c.Name))
+		err = collectLogs(ctx, cli, c, *tail, path)
+		if err != nil {
+			return fmt.Errorf("could not collect the logs of %s: %w", c.Name, err)
+		}
+	}
+	log.Printf("Wrote the logs of %d containers to %s\n", len(containers), *out)
+	return nil
+}
+
+// collectLogs writes the current logs of a container, stdout and stderr interleaved, to the file at the given path.
+func collectLogs(ctx context.Context, cli *dockerClient.Client, c gossipContainer, tail string, path string) error {
+	reader, err := cli.ContainerLogs(ctx, c.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Tail: tail})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// the gossip containers run without a TTY, so the streams are multiplexed
+	_, err = stdcopy.StdCopy(file, file, reader)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// followLogs streams the logs of the containers to stdout, each line prefixed by the index of its container, until interrupted.
+func followLogs(ctx context.Context, cli *dockerClient.Client, containers []gossipContainer, tail string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	var stdoutLock sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range containers {
+		reader, err := cli.ContainerLogs(ctx, c.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Tail: tail})
+		if err != nil {
+			return fmt.Errorf("could not stream the logs of %s: %w", c.Name, err)
+		}
+		pipeReader, pipeWriter := io.Pipe()
+		go func() {
+			_, err := stdcopy.StdCopy(pipeWriter, pipeWriter, reader)
+			_ = pipeWriter.CloseWithError(err)
+			_ = reader.Close()
+		}()
+		wg.Add(1)
+		go func(prefix string) {
+			defer wg.Done()
+			scanner := bufio.NewScanner(pipeReader)
+			scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				stdoutLock.Lock()
+				fmt.Println(prefix + scanner.Text())
+				stdoutLock.Unlock()
+			}
+		}("[" + strconv.Itoa(c.Index) + "] ")
+	}
+	wg.Wait()
+	return nil
+}
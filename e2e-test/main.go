@@ -3,20 +3,15 @@ package main
 import (
 	"bufio"
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/sha256"
-	"crypto/x509"
-	"encoding/hex"
 	"encoding/json"
-	"encoding/pem"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -33,17 +28,20 @@ const (
 	dockerNetworkName = "gossip-test-network"
 	testCertsDir      = "test-data" + string(os.PathSeparator) + "testcerts"
 	testConfigsDir    = "test-data" + string(os.PathSeparator) + "testcfgs"
+	testKeysDir       = "test-data" + string(os.PathSeparator) + "testkeys"
+	testKeyCacheDir   = "test-data" + string(os.PathSeparator) + "keycache"
 	testConfigPath    = "test-data" + string(os.PathSeparator) + "test-config.ini"
-	rsaKeySize        = 4096
+	gossipBinaryPath  = "test-data" + string(os.PathSeparator) + "gossip"
 	dockerImageName   = "gossiphers:test"
 )
 
 func main() {
 	startCmd := flag.NewFlagSet("start", flag.ExitOnError)
 	numNodes := startCmd.Int("n", 10, "Number of gossip containers to spawn")
+	networkOpts := addNetworkFlags(startCmd)
 
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: test-gossip [start,stop]")
+		fmt.Println("Usage: test-gossip [start,stop,scale,status,logs,churn,partition,verify,propagate,run,compose,report,simulate]")
 		os.Exit(1)
 	}
 
@@ -57,58 +55,177 @@ func main() {
 
 	switch strings.ToLower(os.Args[1]) {
 	case "start":
-		err := startCmd.Parse(os.Args[2:])
+		err = startCmd.Parse(os.Args[2:])
 		if err != nil {
 			return
 		}
-		runStartCommand(*numNodes)
+		var opts networkOptions
+		opts, err = networkOpts()
+		if err == nil {
+			err = runStartCommand(*numNodes, opts)
+		}
 	case "stop":
-		runStopCommand()
+		err = runStopCommand()
+	case "scale":
+		err = runScaleCommand(os.Args[2:])
+	case "status":
+		err = runStatusCommand(os.Args[2:])
+	case "logs":
+		err = runLogsCommand(os.Args[2:])
+	case "churn":
+		err = runChurnCommand(os.Args[2:])
+	case "partition":
+		err = runPartitionCommand(os.Args[2:])
+	case "verify":
+		err = runVerifyCommand(os.Args[2:])
+	case "propagate":
+		err = runPropagateCommand(os.Args[2:])
+	case "run":
+		err = runScenarioCommand(os.Args[2:])
+	case "compose":
+		err = runComposeCommand(os.Args[2:])
+	case "report":
+		err = runReportCommand(os.Args[2:])
+	case "simulate":
+		err = runSimulateCommand(os.Args[2:])
 	default:
-		fmt.Println("expected 'start' or 'stop' subcommand")
+		fmt.Println("expected 'start', 'stop', 'scale', 'status', 'logs', 'churn', 'partition', 'verify', 'propagate', 'run', 'compose', 'report', or 'simulate' subcommand")
 		os.Exit(1)
 	}
-
+	// the flag sets of the subcommands already printed their usage
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		log.Fatalln(err)
+	}
 }
 
 type dockerBuildMessage struct {
 	Stream string `json:"stream"`
 }
 
-func runStartCommand(numNodes int) {
+// networkOptions represents how the nodes of a test network differ from each other.
+type networkOptions struct {
+	Netem    []netemProfile
+	Matrix   configMatrix
+	Topology topology
+	// HostkeyShare is the share of the public keys of the other nodes distributed to each node, 1 distributes all keys
+	HostkeyShare float64
+	Resources    resourceLimits
+	// Subnet6 is the IPv6 subnet of a dual-stack network whose nodes gossip over IPv6, empty for an IPv4-only network
+	Subnet6 string
+	// Adversaries are the nodes behaving adversarially, all others are honest
+	Adversaries adversaryAssignment
+}
+
+// Variant returns the config overrides of the node with the given index, its variant of the config matrix followed by its
+// adversarial behavior.
+func (o networkOptions) Variant(index int) configVariant {
+	variant := o.Matrix.Variant(index)
+	if behavior, ok := o.Adversaries[index]; ok {
+		variant = append(variant, configOverride{Key: "adversary", Value: behavior})
+	}
+	return variant
+}
+
+// NetemProfile returns the netem profile of the node with the given index, nil if the network conditions are pristine.
+func (o networkOptions) NetemProfile(index int) *netemProfile {
+	if len(o.Netem) == 0 {
+		return nil
+	}
+	return &o.Netem[index%len(o.Netem)]
+}
+
+// parseNetworkOptions parses the network options in the formats of the flags registered by addNetworkFlags.
+func parseNetworkOptions(netem string, vary string, topologyStr string, hostkeyShare float64, adversaries string) (networkOptions, error) {
+	profiles, err := parseNetemProfiles(netem)
+	if err != nil {
+		return networkOptions{}, err
+	}
+	matrix, err := parseConfigMatrix(vary)
+	if err != nil {
+		return networkOptions{}, err
+	}
+	t, err := parseTopology(topologyStr)
+	if err != nil {
+		return networkOptions{}, err
+	}
+	if hostkeyShare < 0 || hostkeyShare > 1 {
+		return networkOptions{}, fmt.Errorf("the share of distributed hostkeys must be between 0 and 1: received %v", hostkeyShare)
+	}
+	assignment, err := parseAdversaries(adversaries)
+	if err != nil {
+		return networkOptions{}, err
+	}
+	return networkOptions{Netem: profiles, Matrix: matrix, Topology: t, HostkeyShare: hostkeyShare, Adversaries: assignment}, nil
+}
+
+// addNetworkFlags registers the flags describing the nodes of a test network, the returned function parses them once the
+// flag set has been parsed and returns an error if they are invalid.
+func addNetworkFlags(flagSet *flag.FlagSet) func() (networkOptions, error) {
+	netem := flagSet.String("netem", "", "Netem profiles emulating network conditions separated by |, assigned to the containers in turn. "+
+		"A profile is a preset (none, lan, wan, mobile, lossy) or key=value pairs, e.g. delay=50ms,jitter=10ms,loss=1%")
+	vary := flagSet.String("vary", "", "Config matrix whose combinations are assigned to the nodes in turn, dimensions separated by ; "+
+		"e.g. degree=10,20;challenge_difficulty=4,8. Keys changing together are joined by +, e.g. weight_push+weight_pull=45+45,30+60")
+	topologyStr := flagSet.String("topology", "star", "Bootstrap topology, one of star, multi:K (K bootstrap nodes), chain, ring, and random:K (K random bootstrap nodes per node)")
+	hostkeys := flagSet.Float64("hostkeys", 1, "Share of the public keys of the other nodes each node knows in addition to the keys of its bootstrap nodes, 1 distributes all keys")
+	ipv6 := flagSet.Bool("ipv6", false, "Create a dual-stack network whose nodes gossip over IPv6")
+	subnet6 := flagSet.String("subnet6", "fd00:6055:1::/64", "IPv6 subnet of the dual-stack network")
+	adversaries := flagSet.String("adversary", "", "Adversarial nodes as index=behavior pairs, e.g. 3=push_flood,5-7=silent. "+
+		"Behaviors are push_flood, pull_poison, message_spam, and silent")
+	resources := addResourceFlags(flagSet)
+	return func() (networkOptions, error) {
+		opts, err := parseNetworkOptions(*netem, *vary, *topologyStr, *hostkeys, *adversaries)
+		if err != nil {
+			return networkOptions{}, err
+		}
+		opts.Resources, err = resources()
+		if err != nil {
+			return networkOptions{}, err
+		}
+		if *ipv6 {
+			opts.Subnet6 = *subnet6
+		}
+		return opts, nil
+	}
+}
+
+func runStartCommand(numNodes int, opts networkOptions) error {
 	ctx := context.Background()
 	cwd, err := os.Getwd()
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
-	log.Println("Starting docker client...")
-	cli, err := dockerClient.NewClientWithOpts(dockerClient.FromEnv, dockerClient.WithAPIVersionNegotiation())
+	cli, err := newDockerClient()
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
 	log.Println("Deleting old versions of the test image...")
 	imgList, err := cli.ImageList(ctx, types.ImageListOptions{All: true})
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 	for _, img := range imgList {
 		for _, tag := range img.RepoTags {
-			if tag == dockerImageName {
+			if isGossipImage(tag) {
 				_, err = cli.ImageRemove(ctx, img.ID, types.ImageRemoveOptions{})
 			}
 		}
 	}
 
 	log.Println("Building docker image...")
+	buildTags := dockerBuildTags
 	tar, err := archive.TarWithOptions(filepath.Dir(cwd), &archive.TarOptions{ExcludePatterns: []string{"e2e-test"}})
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
-	buildRes, err := cli.ImageBuild(ctx, tar, types.ImageBuildOptions{Tags: []string{dockerImageName}, Remove: true})
+	buildRes, err := cli.ImageBuild(ctx, tar, types.ImageBuildOptions{
+		Tags:      []string{dockerImageName},
+		Remove:    true,
+		BuildArgs: map[string]*string{"TAGS": &buildTags},
+	})
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 	scanner := bufio.NewScanner(buildRes.Body)
 	for scanner.Scan() {
@@ -121,202 +238,245 @@ func runStartCommand(numNodes int) {
 	}
 	_ = buildRes.Body.Close()
 
-	log.Println("Generating keys...")
-	err = os.Mkdir(testCertsDir, os.ModeDir)
+	identities, err := generateTestKeys(numNodes)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
-	os.Chmod(testCertsDir, os.FileMode(0755))
+	log.Println("Creating docker network...")
+	networkCreate := types.NetworkCreate{Driver: "bridge"}
+	if opts.Subnet6 != "" {
+		// the IPv4 subnet is still assigned automatically
+		networkCreate.EnableIPv6 = true
+		networkCreate.IPAM = &network.IPAM{Config: []network.IPAMConfig{{Subnet: opts.Subnet6}}}
+	}
+	networkCreateRes, err := cli.NetworkCreate(ctx, dockerNetworkName, networkCreate)
 	if err != nil {
-		log.Fatalln(err)
+		return err
+	}
+	networkInspectRes, err := cli.NetworkInspect(ctx, networkCreateRes.ID, types.NetworkInspectOptions{})
+	if err != nil {
+		return err
+	}
+	plan, err := networkAddressPlan(networkInspectRes)
+	if err != nil {
+		return err
 	}
 
-	var identities []string
-
-	for i := 0; i < numNodes; i++ {
-		privateKey, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		pubKeyBytes := x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)
-		pubKeyHash := sha256.Sum256(pubKeyBytes)
-		identityString := hex.EncodeToString(pubKeyHash[:])
-		identities = append(identities, identityString)
+	err = generateTestConfigs(identities, plan, opts)
+	if err != nil {
+		return err
+	}
 
-		pemFile, err := os.Create(testCertsDir + string(os.PathSeparator) + identityString)
+	if len(opts.Netem) > 0 {
+		err = ensureNetHelperImage(ctx, cli)
 		if err != nil {
-			log.Fatalln(err)
+			return err
 		}
+	}
 
-		// Set the file permissions
-		err = pemFile.Chmod(os.FileMode(0755))
+	log.Println("Starting containers...")
+	for n, identity := range identities {
+		err = startGossipContainer(ctx, cli, cwd, n, identity, plan, opts)
 		if err != nil {
-			log.Fatalln(err)
+			return err
 		}
 
-		pemPrivateBlock := &pem.Block{
-			Type:  "RSA PRIVATE KEY",
-			Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-		}
-		pemPublicBlock := &pem.Block{
-			Type:  "RSA PUBLIC KEY",
-			Bytes: pubKeyBytes,
+		if n == 0 {
+			// Sleep one second to wait for bootstrap container to be started
+			time.Sleep(time.Second)
 		}
+	}
 
-		err = pem.Encode(pemFile, pemPublicBlock)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		err = pem.Encode(pemFile, pemPrivateBlock)
-		if err != nil {
-			log.Fatalln(err)
-		}
+	log.Println("API of container gossip-" + identities[0] + " is available at localhost:7001")
+	log.Println("Finished!")
+	return nil
+}
 
-		err = pemFile.Close()
-		if err != nil {
-			log.Fatalln(err)
-		}
+// generateTestKeys builds the gossip binary and provisions the keys of the given number of nodes, returns their identities.
+func generateTestKeys(numNodes int) ([]string, error) {
+	log.Println("Generating keys...")
+	err := os.Mkdir(testCertsDir, os.ModeDir)
+	if err != nil {
+		return nil, err
+	}
 
+	os.Chmod(testCertsDir, os.FileMode(0755))
+	if err != nil {
+		return nil, err
 	}
 
-	log.Println("Creating docker network...")
-	networkCreateRes, err := cli.NetworkCreate(ctx, dockerNetworkName, types.NetworkCreate{Driver: "bridge"})
+	err = exec.Command("go", "build", "-o", gossipBinaryPath, "../cmd/gossip").Run()
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
-	networkInspectRes, err := cli.NetworkInspect(ctx, networkCreateRes.ID, types.NetworkInspectOptions{})
+
+	err = os.MkdirAll(testKeysDir, 0755)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
-	networkPrefix := strings.TrimSuffix(networkInspectRes.IPAM.Config[0].Gateway, "1")
+	return provisionKeys(0, numNodes)
+}
 
+// generateTestConfigs generates the config files of the nodes with the given identities, the node with index n is assigned
+// its gossip address within the address plan, the n-th variant of the config matrix, its adversarial behavior, and its
+// bootstrap nodes within the topology. Distributes the hostkeys partially if requested.
+func generateTestConfigs(identities []string, plan addressPlan, opts networkOptions) error {
 	log.Println("Generating config files...")
-	err = os.Mkdir(testConfigsDir, os.ModeDir)
+	err := os.Mkdir(testConfigsDir, os.ModeDir)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 	os.Chmod(testConfigsDir, os.FileMode(0755))
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
-	bootstrapIP := networkPrefix + "2"
 	for n, identity := range identities {
-		if n != 0 {
-			generateConfigFile(identity, networkPrefix+strconv.Itoa(n+2), &identities[0], &bootstrapIP)
-		} else {
-			generateConfigFile(identity, networkPrefix+strconv.Itoa(n+2), nil, nil)
+		var bootstrapNodes []string
+		for _, i := range opts.Topology.BootstrapIndices(n, len(identities)) {
+			bootstrapNodes = append(bootstrapNodes, identities[i]+","+plan.GossipAddress(i))
+		}
+		err = generateConfigFile(identity, plan.GossipAddress(n), bootstrapNodes, opts.Variant(n))
+		if err != nil {
+			return err
 		}
-
 	}
+	if opts.HostkeyShare < 1 {
+		return distributeHostkeys(identities, opts.Topology, opts.HostkeyShare)
+	}
+	return nil
+}
 
-	log.Println("Starting containers...")
-	for n, identity := range identities {
-		containerCfg := container.Config{
-			Image:   dockerImageName,
-			Volumes: map[string]struct{}{},
-			ExposedPorts: nat.PortSet{
-				"7001/tcp": {},
-				"7002/udp": {},
+// startGossipContainer creates and starts the container of the node with the given index and identity using its generated
+// config file and keys, limits its resources, and applies its netem profile if one is given.
+func startGossipContainer(ctx context.Context, cli *dockerClient.Client, cwd string, n int, identity string, plan addressPlan, opts networkOptions) error {
+	profile := opts.NetemProfile(n)
+	labels := containerLabels(n, identity, opts.Variant(n))
+	if profile != nil {
+		labels[labelNetem] = profile.String()
+	}
+	containerCfg := container.Config{
+		Image:   dockerImageName,
+		Labels:  labels,
+		Volumes: map[string]struct{}{},
+		ExposedPorts: nat.PortSet{
+			"7001/tcp": {},
+			"7002/udp": {},
+			"7003/tcp": {},
+		},
+	}
+	hostCfg := container.HostConfig{
+		Mounts: []mount.Mount{
+			{
+				Type:   mount.TypeBind,
+				Source: fmt.Sprintf("%v%v%v%v%v.ini", cwd, string(os.PathSeparator), testConfigsDir, string(os.PathSeparator), identity),
+				Target: "/config.ini",
 			},
-		}
-		hostCfg := container.HostConfig{
-			Mounts: []mount.Mount{
-				{
-					Type:   mount.TypeBind,
-					Source: fmt.Sprintf("%v%v%v%v%v.ini", cwd, string(os.PathSeparator), testConfigsDir, string(os.PathSeparator), identity),
-					Target: "/config.ini",
-				},
-				{
-					Type:   mount.TypeBind,
-					Source: fmt.Sprintf("%v%v%v", cwd, string(os.PathSeparator), testCertsDir),
-					Target: "/keys",
-				},
-				{
-					Type:   mount.TypeBind,
-					Source: fmt.Sprintf("%v%v%v%v%v", cwd, string(os.PathSeparator), testCertsDir, string(os.PathSeparator), identity),
-					Target: "/nodekey.pem",
-				},
+			{
+				Type:   mount.TypeBind,
+				Source: fmt.Sprintf("%v%v%v", cwd, string(os.PathSeparator), certsDirOf(identity)),
+				Target: "/keys",
 			},
-		}
-		networkCfg := network.NetworkingConfig{
-			EndpointsConfig: map[string]*network.EndpointSettings{dockerNetworkName: {IPAddress: networkPrefix + strconv.Itoa(n+2)}},
-		}
-		if n == 0 {
-			hostCfg.PortBindings = nat.PortMap{"7001/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "7001"}}}
-		}
-
-		createRes, err := cli.ContainerCreate(ctx, &containerCfg, &hostCfg, &networkCfg, nil, "gossip-"+identity)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		err = cli.ContainerStart(ctx, createRes.ID, types.ContainerStartOptions{})
-		if err != nil {
-			log.Fatalln(err)
-		}
+			{
+				Type:   mount.TypeBind,
+				Source: fmt.Sprintf("%v%v%v%v%v", cwd, string(os.PathSeparator), testKeysDir, string(os.PathSeparator), identity),
+				Target: "/nodekey.pem",
+			},
+		},
+		// the nodes write their state dumps into /state upon SIGUSR1
+		Tmpfs: map[string]string{stateDumpDir: "mode=1777"},
+	}
+	networkCfg := network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{dockerNetworkName: {IPAddress: plan.IPv4(n)}},
+	}
+	if ip6 := plan.IPv6(n); ip6 != "" {
+		endpoint := networkCfg.EndpointsConfig[dockerNetworkName]
+		endpoint.GlobalIPv6Address = ip6
+		endpoint.IPAMConfig = &network.EndpointIPAMConfig{IPv6Address: ip6}
+	}
+	opts.Resources.apply(&hostCfg)
+	if n == 0 {
+		hostCfg.PortBindings = nat.PortMap{"7001/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "7001"}}}
+	} else {
+		// the APIs of all other nodes are published on random loopback ports for the measurements of the e2e tool
+		hostCfg.PortBindings = nat.PortMap{"7001/tcp": []nat.PortBinding{{HostIP: "127.0.0.1"}}}
+	}
+	// the debug endpoints serving the metrics are scraped by the report subcommand
+	hostCfg.PortBindings["7003/tcp"] = []nat.PortBinding{{HostIP: "127.0.0.1"}}
 
-		if n == 0 {
-			// Sleep one second to wait for bootstrap container to be started
-			time.Sleep(time.Second)
-		}
+	createRes, err := cli.ContainerCreate(ctx, &containerCfg, &hostCfg, &networkCfg, nil, "gossip-"+identity)
+	if err != nil {
+		return err
 	}
 
-	log.Println("API of container gossip-" + identities[0] + " is available at localhost:7001")
-	log.Println("Finished!")
+	err = cli.ContainerStart(ctx, createRes.ID, types.ContainerStartOptions{})
+	if err != nil {
+		return err
+	}
+	if profile != nil {
+		return applyNetem(ctx, cli, gossipContainer{ID: createRes.ID, Name: "gossip-" + identity}, *profile)
+	}
+	return nil
 }
 
-func generateConfigFile(nodeIdentity string, nodeIP string, bootStrapIdentity *string, bootStrapIP *string) {
+// generateConfigFile writes the config file of a node based on the config template, followed by its bootstrap nodes in the
+// form <identity>,<address>, its gossip address, and config overrides, which take precedence over the keys of the template.
+func generateConfigFile(nodeIdentity string, gossipAddress string, bootstrapNodes []string, variant configVariant) error {
 	cfgFileIn, err := os.Open(testConfigPath)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 	cfgFileOut, err := os.Create(testConfigsDir + string(os.PathSeparator) + nodeIdentity + ".ini")
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
 	// Set the file permissions
 	err = cfgFileOut.Chmod(os.FileMode(0755))
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
 	_, err = io.Copy(cfgFileOut, cfgFileIn)
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 	_ = cfgFileIn.Close()
-	if bootStrapIdentity != nil {
-		_, err = cfgFileOut.WriteString(fmt.Sprintf("\nbootstrap_nodes = %v,%v:7002", *bootStrapIdentity, *bootStrapIP))
+	if len(bootstrapNodes) > 0 {
+		_, err = cfgFileOut.WriteString(fmt.Sprintf("\nbootstrap_nodes = %v", strings.Join(bootstrapNodes, "|")))
 	}
-	_, err = cfgFileOut.WriteString(fmt.Sprintf("\ngossip_address = %v:7002", nodeIP))
+	_, err = cfgFileOut.WriteString(fmt.Sprintf("\ngossip_address = %v", gossipAddress))
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
-	_ = cfgFileOut.Close()
+	for _, override := range variant {
+		_, err = cfgFileOut.WriteString(fmt.Sprintf("\n%v = %v", override.Key, override.Value))
+		if err != nil {
+			return err
+		}
+	}
+	return cfgFileOut.Close()
 }
 
-func runStopCommand() {
+func runStopCommand() error {
 	ctx := context.Background()
 
-	log.Println("Starting docker client...")
-	cli, err := dockerClient.NewClientWithOpts(dockerClient.FromEnv, dockerClient.WithAPIVersionNegotiation())
+	cli, err := newDockerClient()
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
 	log.Println("Deleting containers...")
 	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{All: true})
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 
 	for _, c := range containers {
-		if c.Image == dockerImageName {
+		if isGossipImage(c.Image) {
 			err = cli.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
 			if err != nil {
-				log.Fatalln(err)
+				return err
 			}
 		}
 	}
@@ -324,13 +484,13 @@ func runStopCommand() {
 	log.Println("Removing docker network...")
 	networks, err := cli.NetworkList(ctx, types.NetworkListOptions{})
 	if err != nil {
-		log.Fatalln(err)
+		return err
 	}
 	for _, n := range networks {
 		if n.Name == dockerNetworkName {
 			err = cli.NetworkRemove(ctx, n.ID)
 			if err != nil {
-				log.Fatalln(err)
+				return err
 			}
 		}
 	}
@@ -338,12 +498,29 @@ func runStopCommand() {
 	log.Println("Deleting generated files...")
 	err = os.RemoveAll(testCertsDir)
 	if err != nil {
-		log.Fatalln(err)
+		return err
+	}
+	err = os.RemoveAll(testPartialCertsDir)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(testKeysDir)
+	if err != nil {
+		return err
 	}
 	err = os.RemoveAll(testConfigsDir)
 	if err != nil {
-		log.Fatalln(err)
+		return err
+	}
+	err = os.RemoveAll(gossipBinaryPath)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(composeFilePath)
+	if err != nil {
+		return err
 	}
 
 	log.Println("Finished!")
+	return nil
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	dockerClient "github.com/docker/docker/client"
+)
+
+// labelNetem holds the netem profile of a gossip container, so it can be applied again after the container restarted.
+const labelNetem = "gossiphers.netem"
+
+// netemProfile represents the emulated network conditions of the traffic sent by a container.
+type netemProfile struct {
+	Delay  time.Duration
+	Jitter time.Duration
+	// Loss is the percentage of dropped packets
+	Loss float64
+}
+
+// netemPresets contains named profiles of typical network conditions.
+var netemPresets = map[string]netemProfile{
+	"none":   {},
+	"lan":    {Delay: time.Millisecond, Jitter: 200 * time.Microsecond},
+	"wan":    {Delay: 80 * time.Millisecond, Jitter: 20 * time.Millisecond, Loss: 0.5},
+	"mobile": {Delay: 150 * time.Millisecond, Jitter: 50 * time.Millisecond, Loss: 2},
+	"lossy":  {Delay: 20 * time.Millisecond, Loss: 10},
+}
+
+// IsPristine reports whether the profile leaves the network conditions unchanged.
+func (p netemProfile) IsPristine() bool {
+	return p == netemProfile{}
+}
+
+// String returns the profile in the form parsed by parseNetemProfile.
+func (p netemProfile) String() string {
+	if p.IsPristine() {
+		return "none"
+	}
+	var parts []string
+	if p.Delay > 0 {
+		parts = append(parts, "delay="+p.Delay.String())
+	}
+	if p.Jitter > 0 {
+		parts = append(parts, "jitter="+p.Jitter.String())
+	}
+	if p.Loss > 0 {
+		parts = append(parts, "loss="+strconv.FormatFloat(p.Loss, 'f', -1, 64)+"%")
+	}
+	return strings.Join(parts, ",")
+}
+
+// tcCommand returns the tc command applying the profile to the network interface of a container.
+func (p netemProfile) tcCommand() string {
+	if p.IsPristine() {
+		return "tc qdisc del dev eth0 root 2>/dev/null || true"
+	}
+	cmd := "tc qdisc replace dev eth0 root netem"
+	if p.Delay > 0 || p.Jitter > 0 {
+		cmd += fmt.Sprintf(" delay %dus", p.Delay.Microseconds())
+		if p.Jitter > 0 {
+			cmd += fmt.Sprintf(" %dus distribution normal", p.Jitter.Microseconds())
+		}
+	}
+	if p.Loss > 0 {
+		cmd += " loss " + strconv.FormatFloat(p.Loss, 'f', -1, 64) + "%"
+	}
+	return cmd
+}
+
+// parseNetemProfile parses either the name of a preset or comma-separated key=value pairs of delay, jitter, and loss, e.g. delay=50ms,jitter=10ms,loss=1%.
+func parseNetemProfile(str string) (netemProfile, error) {
+	str = strings.TrimSpace(str)
+	if preset, ok := netemPresets[str]; ok {
+		return preset, nil
+	}
+	profile := netemProfile{}
+	for _, pair := range strings.Split(str, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return netemProfile{}, fmt.Errorf("expected a preset or key=value pairs, received %s", pair)
+		}
+		var err error
+		switch key {
+		case "delay":
+			profile.Delay, err = time.ParseDuration(value)
+		case "jitter":
+			profile.Jitter, err = time.ParseDuration(value)
+		case "loss":
+			profile.Loss, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err == nil && (profile.Loss < 0 || profile.Loss > 100) {
+				err = fmt.Errorf("loss must be a percentage between 0 and 100")
+			}
+		default:
+			err = fmt.Errorf("unknown key, expected delay, jitter, or loss")
+		}
+		if err != nil {
+			return netemProfile{}, fmt.Errorf("invalid netem %s: %w", key, err)
+		}
+	}
+	if profile.Delay < 0 || profile.Jitter < 0 || profile.Jitter > profile.Delay {
+		return netemProfile{}, fmt.Errorf("delay and jitter must not be negative and the jitter must not exceed the delay: %s", str)
+	}
+	return profile, nil
+}
+
+// parseNetemProfiles parses profiles separated by |, which are assigned to the containers in turn.
+func parseNetemProfiles(str string) ([]netemProfile, error) {
+	if str == "" {
+		return nil, nil
+	}
+	var profiles []netemProfile
+	for _, profileStr := range strings.Split(str, "|") {
+		profile, err := parseNetemProfile(profileStr)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// applyNetem applies the netem profile to the traffic sent by the container.
+func applyNetem(ctx context.Context, cli *dockerClient.Client, c gossipContainer, profile netemProfile) error {
+	err := runInNetworkNamespace(ctx, cli, c, profile.tcCommand())
+	if err != nil {
+		return fmt.Errorf("could not apply netem profile %s to container %s: %w", profile, c.Name, err)
+	}
+	log.Printf("Applied netem profile %s to %s\n", profile, c.Name)
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	dockerClient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// netHelperImageName represents the image providing iptables and tc, which the gossip image built from scratch lacks.
+	netHelperImageName = "gossiphers-net-helper:test"
+	// netHelperDockerfile builds the netHelperImageName image.
+	netHelperDockerfile = "FROM alpine:3.18\nRUN apk add --no-cache iptables iproute2\n"
+)
+
+// ensureNetHelperImage builds the image of the network helper containers unless it exists already.
+func ensureNetHelperImage(ctx context.Context, cli *dockerClient.Client) error {
+	_, _, err := cli.ImageInspectWithRaw(ctx, netHelperImageName)
+	if err == nil {
+		return nil
+	}
+	log.Println("Building network helper image...")
+	var buf bytes.Buffer
+	archive := tar.NewWriter(&buf)
+	err = archive.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0644, Size: int64(len(netHelperDockerfile))})
+	if err != nil {
+		return err
+	}
+	_, err = archive.Write([]byte(netHelperDockerfile))
+	if err != nil {
+		return err
+	}
+	err = archive.Close()
+	if err != nil {
+		return err
+	}
+	buildRes, err := cli.ImageBuild(ctx, &buf, types.ImageBuildOptions{Tags: []string{netHelperImageName}, Remove: true})
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, buildRes.Body)
+	_ = buildRes.Body.Close()
+	_, _, err = cli.ImageInspectWithRaw(ctx, netHelperImageName)
+	if err != nil {
+		return fmt.Errorf("could not build the network helper image: %w", err)
+	}
+	return nil
+}
+
+// runInNetworkNamespace runs a shell script within the network namespace of the gossip container, e.g. to manipulate its
+// iptables rules or traffic control settings, which persist after the helper container exited.
+func runInNetworkNamespace(ctx context.Context, cli *dockerClient.Client, c gossipContainer, script string) error {
+	containerCfg := container.Config{
+		Image: netHelperImageName,
+		Cmd:   []string{"sh", "-ec", script},
+	}
+	hostCfg := container.HostConfig{
+		NetworkMode: container.NetworkMode("container:" + c.ID),
+		CapAdd:      []string{"NET_ADMIN"},
+	}
+	createRes, err := cli.ContainerCreate(ctx, &containerCfg, &hostCfg, nil, nil, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = cli.ContainerRemove(ctx, createRes.ID, types.ContainerRemoveOptions{Force: true})
+	}()
+
+	waitRes, waitErr := cli.ContainerWait(ctx, createRes.ID, container.WaitConditionNextExit)
+	err = cli.ContainerStart(ctx, createRes.ID, types.ContainerStartOptions{})
+	if err != nil {
+		return err
+	}
+	select {
+	case res := <-waitRes:
+		if res.StatusCode == 0 {
+			return nil
+		}
+		return fmt.Errorf("script failed with status %d within the network namespace of %s: %s", res.StatusCode, c.Name, helperOutput(ctx, cli, createRes.ID))
+	case err = <-waitErr:
+		return err
+	}
+}
+
+// helperOutput returns the combined output of a helper container.
+func helperOutput(ctx context.Context, cli *dockerClient.Client, containerID string) string {
+	logs, err := cli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return err.Error()
+	}
+	defer logs.Close()
+	var out bytes.Buffer
+	_, _ = stdcopy.StdCopy(&out, &out, logs)
+	return strings.TrimSpace(out.String())
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	dockerClient "github.com/docker/docker/client"
+)
+
+const (
+	// partitionChain represents the iptables chain holding the rules that isolate a container from the other groups.
+	partitionChain = "GOSSIP_PARTITION"
+	// partitionDataType represents the data type of the messages announced to verify the propagation across healed partitions.
+	partitionDataType = 4242
+	// verifyPollInterval represents the time in between two evaluations of the state dumps of all nodes.
+	verifyPollInterval = 5 * time.Second
+)
+
+// runPartitionCommand splits the test network into isolated groups, heals the partition after the given duration, and
+// verifies that the views reconverge and messages propagate across the former partition.
+func runPartitionCommand(args []string) error {
+	partitionCmd := flag.NewFlagSet("partition", flag.ContinueOnError)
+	numGroups := partitionCmd.Int("groups", 2, "Number of isolated groups the network is split into")
+	duration := partitionCmd.Duration("duration", 2*time.Minute, "Time the partition lasts before it is healed")
+	verify := partitionCmd.Bool("verify", true, "Verify that views reconverge and messages propagate after healing the partition")
+	verifyTimeout := partitionCmd.Duration("verify-timeout", 3*time.Minute, "Time the views and messages may take to reconverge after healing")
+	seed := partitionCmd.Int64("seed", time.Now().UnixNano(), "Seed of the random assignment of containers to groups, to reproduce a run")
+	err := partitionCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	containers, err := listGossipContainers(ctx, cli)
+	if err != nil {
+		return err
+	}
+	var running []gossipContainer
+	for _, c := range containers {
+		if c.Running {
+			running = append(running, c)
+		}
+	}
+	if *numGroups < 2 || len(running) < *numGroups {
+		return fmt.Errorf("cannot split %d running containers into %d groups", len(running), *numGroups)
+	}
+	err = ensureNetHelperImage(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	groups := assignGroups(running, *numGroups, rand.New(rand.NewSource(*seed)))
+	for i, group := range groups {
+		var names []string
+		for _, c := range group {
+			names = append(names, c.Name)
+		}
+		log.Printf("Group %d: %s\n", i, strings.Join(names, ", "))
+	}
+
+	log.Printf("Partitioning the network into %d groups for %s, seed %d\n", *numGroups, *duration, *seed)
+	err = applyPartition(ctx, cli, groups)
+	if err != nil {
+		// the containers partitioned so far are not left isolated
+		return errors.Join(err, healPartition(ctx, cli, running))
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	select {
+	case <-time.After(*duration):
+	case <-interrupt:
+		log.Println("Interrupted, healing the partition")
+		return healPartition(ctx, cli, running)
+	}
+	err = healPartition(ctx, cli, running)
+	if err != nil {
+		return err
+	}
+	healedAt := time.Now()
+	log.Println("Healed the partition")
+	if !*verify {
+		log.Println("Finished!")
+		return nil
+	}
+
+	groupOf := make(map[string]int)
+	for i, group := range groups {
+		for _, c := range group {
+			groupOf[c.Identity] = i
+		}
+	}
+	deadline := healedAt.Add(*verifyTimeout)
+	err = waitUntil(deadline, "views reconverged", func() (int, int) {
+		return countCrossGroupViews(ctx, cli, running, groupOf), len(running)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Views reconverged %s after healing\n", time.Since(healedAt).Round(time.Second))
+
+	dataHash, err := announceTestMessage(partitionDataType, fmt.Sprintf("partition test %d", time.Now().UnixNano()))
+	if err != nil {
+		return err
+	}
+	announcedAt := time.Now()
+	err = waitUntil(deadline, "message propagated", func() (int, int) {
+		return countMessageHolders(ctx, cli, running, dataHash), len(running)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Message propagated to all nodes %s after announcing it\n", time.Since(announcedAt).Round(time.Second))
+	log.Println("Finished!")
+	return nil
+}
+
+// assignGroups randomly distributes the containers among the given number of groups of almost equal size.
+func assignGroups(containers []gossipContainer, numGroups int, rng *rand.Rand) [][]gossipContainer {
+	shuffled := append([]gossipContainer(nil), containers...)
+	rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	groups := make([][]gossipContainer, numGroups)
+	for i, c := range shuffled {
+		groups[i%numGroups] = append(groups[i%numGroups], c)
+	}
+	return groups
+}
+
+// applyPartition drops all traffic in between containers of different groups using iptables within each container.
+func applyPartition(ctx context.Context, cli *dockerClient.Client, groups [][]gossipContainer) error {
+	for i, group := range groups {
+		var foreignIPs, foreignIPv6s []string
+		for j, other := range groups {
+			if j == i {
+				continue
+			}
+			for _, c := range other {
+				foreignIPs = append(foreignIPs, c.IP)
+				if c.IPv6 != "" {
+					foreignIPv6s = append(foreignIPv6s, c.IPv6)
+				}
+			}
+		}
+		script := partitionRules("iptables", foreignIPs)
+		if len(foreignIPv6s) > 0 {
+			script += "\n" + partitionRules("ip6tables", foreignIPv6s)
+		}
+		for _, c := range group {
+			err := runInNetworkNamespace(ctx, cli, c, script)
+			if err != nil {
+				return fmt.Errorf("could not partition container %s: %w", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// partitionRules returns the script dropping all traffic from and to the given addresses using iptables or ip6tables.
+func partitionRules(iptables string, ips []string) string {
+	return fmt.Sprintf(`%[1]s -N %[2]s 2>/dev/null || %[1]s -F %[2]s
+%[1]s -C INPUT -j %[2]s 2>/dev/null || %[1]s -I INPUT -j %[2]s
+%[1]s -C OUTPUT -j %[2]s 2>/dev/null || %[1]s -I OUTPUT -j %[2]s
+%[1]s -A %[2]s -s %[3]s -j DROP
+%[1]s -A %[2]s -d %[3]s -j DROP`, iptables, partitionChain, strings.Join(ips, ","))
+}
+
+// healPartition removes the rules isolating the containers from each other.
+func healPartition(ctx context.Context, cli *dockerClient.Client, containers []gossipContainer) error {
+	for _, c := range containers {
+		err := runInNetworkNamespace(ctx, cli, c, fmt.Sprintf("iptables -F %[1]s 2>/dev/null || true\nip6tables -F %[1]s 2>/dev/null || true", partitionChain))
+		if err != nil {
+			return fmt.Errorf("could not heal container %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// countCrossGroupViews returns the number of nodes whose main view contains at least one node of another group.
+func countCrossGroupViews(ctx context.Context, cli *dockerClient.Client, containers []gossipContainer, groupOf map[string]int) int {
+	count := 0
+	for _, c := range containers {
+		dump, err := readStateDump(ctx, cli, c)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		for _, node := range dump.MainView {
+			if group, ok := groupOf[node.Identity]; ok && group != groupOf[c.Identity] {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// countMessageHolders returns the number of nodes holding the message with the given hex-encoded data hash within their message cache.
+func countMessageHolders(ctx context.Context, cli *dockerClient.Client, containers []gossipContainer, dataHash string) int {
+	count := 0
+	for _, c := range containers {
+		dump, err := readStateDump(ctx, cli, c)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if dump.HasMessage(dataHash) {
+			count++
+		}
+	}
+	return count
+}
+
+// waitUntil evaluates the progress of a condition until all nodes fulfill it, and returns an error if the deadline passes before.
+func waitUntil(deadline time.Time, condition string, progress func() (int, int)) error {
+	for {
+		done, total := progress()
+		log.Printf("%d of %d nodes: %s\n", done, total, condition)
+		if done == total {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting until all nodes %s", condition)
+		}
+		time.Sleep(verifyPollInterval)
+	}
+}
+
+// announceTestMessage announces a message through the API of the bootstrap container exposed on the host and returns the hex-encoded hash of its data.
+func announceTestMessage(dataType int, data string) (string, error) {
+	cmd := exec.Command(gossipBinaryPath, "announce", "-api", "localhost:7001", "-type", fmt.Sprint(dataType))
+	cmd.Stdin = strings.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("could not announce test message: %w: %s", err, stderr.String())
+	}
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:]), nil
+}
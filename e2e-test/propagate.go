@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// propagationHeaderSize represents the length of the sequence number and announcement time prepended to the data of each measured message.
+const propagationHeaderSize = 16
+
+// delivery represents the reception of a measured message by a node.
+type delivery struct {
+	Sequence int
+	Latency  time.Duration
+}
+
+// propagationResult summarizes the deliveries of all measured messages.
+type propagationResult struct {
+	Messages    int
+	Subscribers int
+	Deliveries  int
+	// MinCoverage is the lowest share of subscribers a single message reached
+	MinCoverage float64
+	Latencies   []time.Duration
+}
+
+// runPropagateCommand announces messages through the API of one node, receives them through the APIs of all other nodes,
+// and reports the delivery latency percentiles and coverage.
+func runPropagateCommand(args []string) error {
+	propagateCmd := flag.NewFlagSet("propagate", flag.ContinueOnError)
+	sizeStr := propagateCmd.String("size", "1KB", "Size of the data of each message, e.g. 512B, 1KB, or 16KB (1KB = 1024 bytes)")
+	count := propagateCmd.Int("count", 100, "Number of messages announced")
+	interval := propagateCmd.Duration("interval", 100*time.Millisecond, "Time in between two announcements")
+	from := propagateCmd.Int("from", 0, "Index of the container the messages are announced through, 0 is the bootstrap node")
+	dataType := propagateCmd.Uint("type", 4244, "Data type of the messages")
+	timeout := propagateCmd.Duration("timeout", 30*time.Second, "Time to wait for outstanding deliveries after the last announcement")
+	err := propagateCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	size, err := parseSize(*sizeStr)
+	if err != nil {
+		return err
+	}
+	if size < propagationHeaderSize || *count <= 0 || *dataType > 65535 {
+		return fmt.Errorf("size must be at least %d bytes, count positive, and the data type must fit into 16 bits", propagationHeaderSize)
+	}
+
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	containers, err := listGossipContainers(ctx, cli)
+	if err != nil {
+		return err
+	}
+	var source *gossipContainer
+	var subscribers []gossipContainer
+	for _, c := range containers {
+		if !c.Running {
+			continue
+		}
+		if c.APIAddress == "" {
+			return fmt.Errorf("the API of %s is not published, restart the test network with the current version of the tool", c.Name)
+		}
+		if c.Index == *from {
+			c := c
+			source = &c
+		} else {
+			subscribers = append(subscribers, c)
+		}
+	}
+	if source == nil || len(subscribers) == 0 {
+		return fmt.Errorf("container %d must be running along with at least one other container", *from)
+	}
+
+	deliveries := make(chan delivery, 1024)
+	var wg sync.WaitGroup
+	var clients []*apiClient
+	defer func() {
+		for _, client := range clients {
+			_ = client.Close()
+		}
+		wg.Wait()
+	}()
+	for _, c := range subscribers {
+		client, err := dialAPI(c.APIAddress)
+		if err != nil {
+			return fmt.Errorf("could not connect to the API of %s: %w", c.Name, err)
+		}
+		clients = append(clients, client)
+		err = client.Notify(uint16(*dataType))
+		if err != nil {
+			return fmt.Errorf("could not subscribe at %s: %w", c.Name, err)
+		}
+		wg.Add(1)
+		go func(name string, client *apiClient) {
+			defer wg.Done()
+			receiveDeliveries(name, client, deliveries)
+		}(c.Name, client)
+	}
+	sourceClient, err := dialAPI(source.APIAddress)
+	if err != nil {
+		return fmt.Errorf("could not connect to the API of %s: %w", source.Name, err)
+	}
+	defer sourceClient.Close()
+	rejected := make(chan error, 1)
+	go func() {
+		// the source node only answers announcements it rejects
+		_, err := sourceClient.ReadNotification()
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			rejected <- fmt.Errorf("announcement failed at %s: %w", source.Name, err)
+		}
+	}()
+
+	stop := make(chan struct{})
+	results := make(chan propagationResult, 1)
+	go func() {
+		results <- collectDeliveries(deliveries, stop, *count, len(subscribers))
+	}()
+	// the collection is stopped on every return, closing a stopped collection is a no-op
+	var stopOnce sync.Once
+	stopCollection := func() { stopOnce.Do(func() { close(stop) }) }
+	defer stopCollection()
+	log.Printf("Announcing %d messages of %d bytes through %s to %d subscribers\n", *count, size, source.Name, len(subscribers))
+	// the subscriptions are processed asynchronously by the nodes
+	time.Sleep(time.Second)
+	for i := 0; i < *count; i++ {
+		select {
+		case err = <-rejected:
+			return err
+		default:
+		}
+		data := make([]byte, size)
+		binary.BigEndian.PutUint64(data, uint64(i))
+		binary.BigEndian.PutUint64(data[8:], uint64(time.Now().UnixNano()))
+		err = sourceClient.Announce(uint16(*dataType), data)
+		if err != nil {
+			return fmt.Errorf("could not announce message %d: %w", i, err)
+		}
+		time.Sleep(*interval)
+	}
+
+	var result propagationResult
+	select {
+	case result = <-results:
+	case <-time.After(*timeout):
+		log.Printf("Not all messages were delivered within %s after the last announcement\n", *timeout)
+		stopCollection()
+		result = <-results
+	case err = <-rejected:
+		return err
+	}
+	printPropagationResult(result)
+	return nil
+}
+
+// receiveDeliveries validates every notification received by a subscriber and forwards the latency of measured messages.
+func receiveDeliveries(name string, client *apiClient, deliveries chan<- delivery) {
+	for {
+		notification, err := client.ReadNotification()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("stopped receiving at %s: %v\n", name, err)
+			}
+			return
+		}
+		receivedAt := time.Now()
+		// messages are only spread further once validated
+		err = client.Validate(notification.MessageID)
+		if err != nil {
+			log.Printf("could not validate message at %s: %v\n", name, err)
+		}
+		if len(notification.Data) < propagationHeaderSize {
+			continue
+		}
+		announcedAt := time.Unix(0, int64(binary.BigEndian.Uint64(notification.Data[8:16])))
+		deliveries <- delivery{Sequence: int(binary.BigEndian.Uint64(notification.Data[:8])), Latency: receivedAt.Sub(announcedAt)}
+	}
+}
+
+// collectDeliveries gathers deliveries until every message reached every subscriber or the stop channel is closed.
+func collectDeliveries(deliveries <-chan delivery, stop <-chan struct{}, count int, numSubscribers int) propagationResult {
+	result := propagationResult{Messages: count, Subscribers: numSubscribers}
+	perMessage := make([]int, count)
+collect:
+	for result.Deliveries < count*numSubscribers {
+		select {
+		case d := <-deliveries:
+			if d.Sequence < 0 || d.Sequence >= count {
+				continue
+			}
+			perMessage[d.Sequence]++
+			result.Deliveries++
+			result.Latencies = append(result.Latencies, d.Latency)
+		case <-stop:
+			break collect
+		}
+	}
+	result.MinCoverage = 1
+	for _, n := range perMessage {
+		result.MinCoverage = math.Min(result.MinCoverage, float64(n)/float64(numSubscribers))
+	}
+	return result
+}
+
+// printPropagationResult prints the coverage and latency percentiles of the measurement.
+func printPropagationResult(result propagationResult) {
+	expected := result.Messages * result.Subscribers
+	fmt.Printf("Coverage: %d of %d deliveries (%.1f%%), the least delivered message reached %.1f%% of subscribers\n",
+		result.Deliveries, expected, 100*float64(result.Deliveries)/float64(expected), 100*result.MinCoverage)
+	if len(result.Latencies) == 0 {
+		return
+	}
+	sort.Slice(result.Latencies, func(i, j int) bool { return result.Latencies[i] < result.Latencies[j] })
+	var parts []string
+	for _, p := range []float64{50, 90, 95, 99} {
+		parts = append(parts, fmt.Sprintf("p%.0f=%s", p, percentile(result.Latencies, p).Round(time.Millisecond)))
+	}
+	parts = append(parts, fmt.Sprintf("max=%s", result.Latencies[len(result.Latencies)-1].Round(time.Millisecond)))
+	fmt.Println("Latency: " + strings.Join(parts, " "))
+}
+
+// percentile returns the p-th percentile of sorted durations using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// parseSize parses a size in bytes with an optional unit of B, KB, MB, or GB, based on 1024.
+func parseSize(str string) (int, error) {
+	str = strings.ToUpper(strings.TrimSpace(str))
+	multiplier := 1
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(str, unit.suffix) {
+			str = strings.TrimSuffix(str, unit.suffix)
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(str))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %s", str)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reportMetrics contains the counters of the gossip expvar map scraped from each node, in the order of the report columns.
+var reportMetrics = []string{"main_view_size", "packets_sent", "packets_received", "messages_stored"}
+
+// rateMetrics contains the cumulative counters that are reported as rates per second.
+var rateMetrics = map[string]bool{"packets_sent": true, "packets_received": true}
+
+// metricsSample represents the counters of a node scraped at a point in time.
+type metricsSample struct {
+	Elapsed time.Duration
+	Values  map[string]float64
+}
+
+// latencySample represents the delivery latencies of the probe messages received within a scrape interval.
+type latencySample struct {
+	Elapsed    time.Duration
+	Deliveries int
+	P50        time.Duration
+	Max        time.Duration
+}
+
+// runReportCommand scrapes the metrics endpoints of all nodes during a run and writes CSV files and an HTML report of the
+// view sizes, packet rates, cached messages, and delivery latencies of probe messages over time.
+func runReportCommand(args []string) error {
+	reportCmd := flag.NewFlagSet("report", flag.ContinueOnError)
+	duration := reportCmd.Duration("duration", 2*time.Minute, "Time the metrics are collected for, the collection stops early when interrupted")
+	interval := reportCmd.Duration("interval", 5*time.Second, "Time in between two scrapes of the metrics of all nodes")
+	out := reportCmd.String("out", "report", "Directory the CSV files and the HTML report are written to")
+	probe := reportCmd.Bool("probe", true, "Announce a probe message through the bootstrap node every interval and report its delivery latencies")
+	dataType := reportCmd.Uint("type", 4245, "Data type of the probe messages")
+	err := reportCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 || *dataType > 65535 {
+		return errors.New("the interval must be positive and the data type must fit into 16 bits")
+	}
+
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	all, err := listGossipContainers(ctx, cli)
+	if err != nil {
+		return err
+	}
+	var containers []gossipContainer
+	for _, c := range all {
+		if c.Running && c.MetricsAddress != "" {
+			containers = append(containers, c)
+		}
+	}
+	if len(containers) == 0 {
+		return errors.New("no running gossip containers with published metrics endpoints, restart the test network with the current version of the tool")
+	}
+
+	// without probe messages, the nil channels of the empty connections never receive
+	probes := &probeConnections{}
+	if *probe {
+		probes, err = startProbe(containers, uint16(*dataType))
+		if err != nil {
+			return err
+		}
+		defer probes.Close()
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	httpClient := &http.Client{Timeout: *interval}
+	samples := make(map[string][]metricsSample)
+	var latencies []latencySample
+	startedAt := time.Now()
+	log.Printf("Collecting the metrics of %d nodes every %s for %s\n", len(containers), *interval, *duration)
+collect:
+	for sequence := 0; time.Since(startedAt) < *duration; sequence++ {
+		elapsed := time.Since(startedAt)
+		for _, c := range containers {
+			values, err := scrapeMetrics(httpClient, c.MetricsAddress)
+			if err != nil {
+				log.Printf("could not scrape the metrics of %s: %v\n", c.Name, err)
+				continue
+			}
+			samples[c.Name] = append(samples[c.Name], metricsSample{Elapsed: elapsed, Values: values})
+		}
+		if *probe {
+			data := make([]byte, propagationHeaderSize)
+			binary.BigEndian.PutUint64(data, uint64(sequence))
+			binary.BigEndian.PutUint64(data[8:], uint64(time.Now().UnixNano()))
+			err := probes.source.Announce(uint16(*dataType), data)
+			if err != nil {
+				return fmt.Errorf("could not announce probe message: %w", err)
+			}
+		}
+
+		interrupted := false
+		select {
+		case <-ticker.C:
+		case <-interrupt:
+			log.Println("Interrupted, writing the report")
+			interrupted = true
+		case err = <-probes.rejected:
+			return err
+		}
+		if *probe {
+			latencies = append(latencies, summarizeLatencies(elapsed, drainDeliveries(probes.deliveries)))
+		}
+		if interrupted {
+			break collect
+		}
+	}
+
+	err = writeReport(*out, containers, samples, latencies)
+	if err != nil {
+		return err
+	}
+	log.Printf("Wrote the report to %s\n", filepath.Join(*out, "report.html"))
+	return nil
+}
+
+// probeConnections represents the connections announcing and receiving the probe messages.
+type probeConnections struct {
+	// deliveries receives the deliveries of the probe messages
+	deliveries chan delivery
+	// source is the connection to the API of the bootstrap node announcing the probe messages
+	source *apiClient
+	// rejected receives the error of an announcement rejected by the bootstrap node
+	rejected chan error
+	clients  []*apiClient
+}
+
+// Close closes the connections of the probe.
+func (p *probeConnections) Close() {
+	for _, client := range p.clients {
+		_ = client.Close()
+	}
+}
+
+// startProbe subscribes to the probe messages on all nodes except the bootstrap node, whose API announces them.
+func startProbe(containers []gossipContainer, dataType uint16) (*probeConnections, error) {
+	p := &probeConnections{deliveries: make(chan delivery, 4096), rejected: make(chan error, 1)}
+	for _, c := range containers {
+		client, err := dialAPI(c.APIAddress)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("could not connect to the API of %s: %w", c.Name, err)
+		}
+		p.clients = append(p.clients, client)
+		if c.Index == 0 {
+			p.source = client
+			go func(name string) {
+				// the source node only answers announcements it rejects
+				_, err := client.ReadNotification()
+				if err != nil && !errors.Is(err, net.ErrClosed) {
+					p.rejected <- fmt.Errorf("announcement failed at %s: %w", name, err)
+				}
+			}(c.Name)
+			continue
+		}
+		err = client.Notify(dataType)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("could not subscribe at %s: %w", c.Name, err)
+		}
+		go receiveDeliveries(c.Name, client, p.deliveries)
+	}
+	if p.source == nil {
+		p.Close()
+		return nil, errors.New("the bootstrap container must be running to announce probe messages")
+	}
+	return p, nil
+}
+
+// scrapeMetrics returns the numeric counters of the gossip expvar map served by the debug endpoint at the given address.
+func scrapeMetrics(client *http.Client, address string) (map[string]float64, error) {
+	resp, err := client.Get("http://" + address + "/debug/vars")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var vars struct {
+		Gossip map[string]json.RawMessage `json:"gossip"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&vars)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]float64)
+	for name, raw := range vars.Gossip {
+		var value float64
+		// non-numeric variables like the peer latencies are skipped
+		if json.Unmarshal(raw, &value) == nil {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+// drainDeliveries returns the latencies of all deliveries received since the channel was drained the last time.
+func drainDeliveries(deliveries <-chan delivery) []time.Duration {
+	var latencies []time.Duration
+	for {
+		select {
+		case d := <-deliveries:
+			latencies = append(latencies, d.Latency)
+		default:
+			return latencies
+		}
+	}
+}
+
+// summarizeLatencies returns the median and maximum of the latencies received within the interval ending at elapsed.
+func summarizeLatencies(elapsed time.Duration, latencies []time.Duration) latencySample {
+	sample := latencySample{Elapsed: elapsed, Deliveries: len(latencies)}
+	if len(latencies) == 0 {
+		return sample
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	sample.P50 = percentile(latencies, 50)
+	sample.Max = latencies[len(latencies)-1]
+	return sample
+}
+
+// seriesValues returns the values of a metric of a node over time.
+func seriesValues(samples []metricsSample, metric string) []point {
+	var points []point
+	for i, sample := range samples {
+		value, ok := metricValue(samples, i, metric)
+		if ok {
+			points = append(points, point{X: sample.Elapsed.Seconds(), Y: value})
+		}
+	}
+	return points
+}
+
+// writeReport writes metrics.csv, latency.csv, and report.html into the output directory.
+func writeReport(out string, containers []gossipContainer, samples map[string][]metricsSample, latencies []latencySample) error {
+	err := os.MkdirAll(out, 0755)
+	if err != nil {
+		return err
+	}
+
+	var metricsRows [][]string
+	metricsRows = append(metricsRows, append([]string{"elapsed_s", "node"}, reportMetrics...))
+	for _, c := range containers {
+		for i, sample := range samples[c.Name] {
+			row := []string{strconv.FormatFloat(sample.Elapsed.Seconds(), 'f', 1, 64), c.Name}
+			for _, metric := range reportMetrics {
+				value, ok := metricValue(samples[c.Name], i, metric)
+				cell := ""
+				if ok {
+					cell = strconv.FormatFloat(value, 'f', -1, 64)
+				}
+				row = append(row, cell)
+			}
+			metricsRows = append(metricsRows, row)
+		}
+	}
+	err = writeCSV(filepath.Join(out, "metrics.csv"), metricsRows)
+	if err != nil {
+		return err
+	}
+
+	latencyRows := [][]string{{"elapsed_s", "deliveries", "p50_ms", "max_ms"}}
+	for _, sample := range latencies {
+		latencyRows = append(latencyRows, []string{
+			strconv.FormatFloat(sample.Elapsed.Seconds(), 'f', 1, 64),
+			strconv.Itoa(sample.Deliveries),
+			strconv.FormatInt(sample.P50.Milliseconds(), 10),
+			strconv.FormatInt(sample.Max.Milliseconds(), 10),
+		})
+	}
+	err = writeCSV(filepath.Join(out, "latency.csv"), latencyRows)
+	if err != nil {
+		return err
+	}
+
+	var charts []chart
+	for _, metric := range reportMetrics {
+		title := strings.ReplaceAll(metric, "_", " ")
+		if rateMetrics[metric] {
+			title += " per second"
+		}
+		c := chart{Title: title}
+		for _, container := range containers {
+			c.Series = append(c.Series, series{Name: container.Name, Points: seriesValues(samples[container.Name], metric)})
+		}
+		charts = append(charts, c)
+	}
+	if len(latencies) > 0 {
+		p50 := series{Name: "p50"}
+		maximum := series{Name: "max"}
+		for _, sample := range latencies {
+			if sample.Deliveries > 0 {
+				p50.Points = append(p50.Points, point{X: sample.Elapsed.Seconds(), Y: float64(sample.P50.Milliseconds())})
+				maximum.Points = append(maximum.Points, point{X: sample.Elapsed.Seconds(), Y: float64(sample.Max.Milliseconds())})
+			}
+		}
+		charts = append(charts, chart{Title: "probe delivery latency in ms", Series: []series{p50, maximum}})
+	}
+
+	file, err := os.Create(filepath.Join(out, "report.html"))
+	if err != nil {
+		return err
+	}
+	err = reportTemplate.Execute(file, map[string]any{
+		"Generated": time.Now().Format(time.RFC3339),
+		"Nodes":     len(containers),
+		"Charts":    charts,
+	})
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// metricValue returns the value of a metric at the sample with the given index. Cumulative counters are converted into
+// rates per second using the previous sample, which are omitted after a restart of the node reset its counters.
+func metricValue(samples []metricsSample, i int, metric string) (float64, bool) {
+	value, ok := samples[i].Values[metric]
+	if !ok || !rateMetrics[metric] {
+		return value, ok
+	}
+	if i == 0 {
+		return 0, false
+	}
+	previous, ok := samples[i-1].Values[metric]
+	if !ok || value < previous {
+		return 0, false
+	}
+	return (value - previous) / (samples[i].Elapsed - samples[i-1].Elapsed).Seconds(), true
+}
+
+// writeCSV writes the rows to a CSV file at the given path.
+func writeCSV(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	err = writer.WriteAll(rows)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// point represents a value at a time in seconds since the start of the collection.
+type point struct {
+	X float64
+	Y float64
+}
+
+// series represents the values of a node or statistic over time.
+type series struct {
+	Name   string
+	Points []point
+}
+
+// chart represents a line chart of one metric of all nodes.
+type chart struct {
+	Title  string
+	Series []series
+}
+
+// Chart dimensions in SVG user units.
+const (
+	chartWidth   = 800.0
+	chartHeight  = 240.0
+	chartPadding = 40.0
+)
+
+// Polylines returns the SVG polyline points of every series, scaled to the common bounds of all series.
+func (c chart) Polylines() []template.HTMLAttr {
+	maxX, maxY := 1.0, 1.0
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			if p.X > maxX {
+				maxX = p.X
+			}
+			if p.Y > maxY {
+				maxY = p.Y
+			}
+		}
+	}
+	var polylines []template.HTMLAttr
+	for i, s := range c.Series {
+		var coords []string
+		for _, p := range s.Points {
+			x := chartPadding + p.X/maxX*(chartWidth-2*chartPadding)
+			y := chartHeight - chartPadding - p.Y/maxY*(chartHeight-2*chartPadding)
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		color := fmt.Sprintf("hsl(%d, 60%%, 45%%)", i*137%360)
+		polylines = append(polylines, template.HTMLAttr(fmt.Sprintf(`points="%s" stroke="%s"`, strings.Join(coords, " "), color)))
+	}
+	return polylines
+}
+
+// MaxY returns the largest value of all series, which the top of the chart corresponds to.
+func (c chart) MaxY() string {
+	maxY := 1.0
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			if p.Y > maxY {
+				maxY = p.Y
+			}
+		}
+	}
+	return strconv.FormatFloat(maxY, 'g', 4, 64)
+}
+
+// reportTemplate renders the charts as inline SVG, so the report is a single self-contained file.
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Gossip e2e report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+svg { border: 1px solid #ccc; margin-bottom: 2em; }
+polyline { fill: none; stroke-width: 1.5; }
+</style>
+</head>
+<body>
+<h1>Gossip e2e report</h1>
+<p>{{.Nodes}} nodes, generated {{.Generated}}. The raw values are contained within metrics.csv and latency.csv.</p>
+{{range .Charts}}
+<h2>{{.Title}}</h2>
+<svg width="800" height="240" viewBox="0 0 800 240">
+<line x1="40" y1="200" x2="760" y2="200" stroke="#999"/>
+<line x1="40" y1="40" x2="40" y2="200" stroke="#999"/>
+<text x="4" y="44" font-size="11">{{.MaxY}}</text>
+<text x="4" y="204" font-size="11">0</text>
+<text x="700" y="220" font-size="11">time in s</text>
+{{range .Polylines}}<polyline {{.}}/>
+{{end}}</svg>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/docker/docker/api/types"
+)
+
+// runScaleCommand adds nodes to the running test network, which join it through the current bootstrap node.
+func runScaleCommand(args []string) error {
+	scaleCmd := flag.NewFlagSet("scale", flag.ContinueOnError)
+	numAdd := scaleCmd.Int("add", 5, "Number of gossip containers to add")
+	netem := scaleCmd.String("netem", "", "Netem profiles of the added containers separated by |, assigned by their index, see the start subcommand")
+	vary := scaleCmd.String("vary", "", "Config matrix whose combinations are assigned to the added nodes by their index, see the start subcommand")
+	adversaries := scaleCmd.String("adversary", "", "Adversarial added nodes as index=behavior pairs, see the start subcommand")
+	resources := addResourceFlags(scaleCmd)
+	delay := scaleCmd.Duration("delay", 0, "Time in between the starts of two added containers, to let the network grow gradually")
+	err := scaleCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *numAdd <= 0 {
+		return errors.New("add must be positive")
+	}
+	opts, err := parseNetworkOptions(*netem, *vary, "", 1, *adversaries)
+	if err != nil {
+		return err
+	}
+	opts.Resources, err = resources()
+	if err != nil {
+		return err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	containers, err := listGossipContainers(ctx, cli)
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 || !containers[0].IsBootstrap() || !containers[0].Running {
+		return errors.New("the bootstrap container is not running, start a test network first")
+	}
+	bootstrap := containers[0]
+	nextIndex := containers[len(containers)-1].Index + 1
+
+	networkInspectRes, err := cli.NetworkInspect(ctx, dockerNetworkName, types.NetworkInspectOptions{})
+	if err != nil {
+		return err
+	}
+	plan, err := networkAddressPlan(networkInspectRes)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(gossipBinaryPath); err != nil {
+		err = exec.Command("go", "build", "-o", gossipBinaryPath, "../cmd/gossip").Run()
+		if err != nil {
+			return err
+		}
+	}
+	if len(opts.Netem) > 0 {
+		err = ensureNetHelperImage(ctx, cli)
+		if err != nil {
+			return err
+		}
+	}
+
+	identities, err := provisionKeys(nextIndex, *numAdd)
+	if err != nil {
+		return err
+	}
+	log.Printf("Adding %d containers joining through %s...\n", *numAdd, bootstrap.Name)
+	for i, identity := range identities {
+		n := nextIndex + i
+		err = generateConfigFile(identity, plan.GossipAddress(n), []string{bootstrap.Identity + "," + plan.GossipAddress(bootstrap.Index)}, opts.Variant(n))
+		if err != nil {
+			return err
+		}
+		err = startGossipContainer(ctx, cli, cwd, n, identity, plan, opts)
+		if err != nil {
+			return err
+		}
+		log.Printf("Started gossip-%s with index %d\n", identity, n)
+		if i < *numAdd-1 {
+			time.Sleep(*delay)
+		}
+	}
+	log.Printf("The test network consists of %d containers\n", len(containers)+*numAdd)
+	log.Println("Finished!")
+	return nil
+}
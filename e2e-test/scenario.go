@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scenario represents a declarative experiment on a test network, read from a YAML file.
+type scenario struct {
+	Name string `yaml:"name"`
+	// Nodes is the number of gossip containers the test network starts with
+	Nodes int `yaml:"nodes"`
+	// Netem are the netem profiles assigned to the containers, in the format of the -netem flag of the start subcommand
+	Netem string `yaml:"netem"`
+	// Vary is the config matrix assigned to the nodes, in the format of the -vary flag of the start subcommand
+	Vary string `yaml:"vary"`
+	// Adversaries are the adversarial nodes, in the format of the -adversary flag of the start subcommand
+	Adversaries string `yaml:"adversaries"`
+	// Topology is the bootstrap topology, in the format of the -topology flag of the start subcommand
+	Topology string `yaml:"topology"`
+	// Hostkeys is the share of the public keys of the other nodes distributed to each node, all keys by default
+	Hostkeys *float64 `yaml:"hostkeys"`
+	// CPUs and Memory limit the resources of each container, in the formats of the -cpus and -memory flags of the start subcommand
+	CPUs   float64 `yaml:"cpus"`
+	Memory string  `yaml:"memory"`
+	// IPv6 creates a dual-stack network whose nodes gossip over IPv6
+	IPv6   bool            `yaml:"ipv6"`
+	Events []scenarioEvent `yaml:"events"`
+	Expect *scenarioExpect `yaml:"expect"`
+	// Keep leaves the test network running after the scenario finished, instead of stopping it
+	Keep bool `yaml:"keep"`
+}
+
+// scenarioEvent represents a subcommand of the e2e tool executed at a time relative to the start of the test network.
+type scenarioEvent struct {
+	At     time.Duration `yaml:"at"`
+	Action string        `yaml:"action"`
+	// Args are the flags of the subcommand without the leading dash, e.g. kill: 3
+	Args map[string]interface{} `yaml:"args"`
+}
+
+// scenarioExpect represents the assertions evaluated once all events have been executed.
+type scenarioExpect struct {
+	// At is the earliest time relative to the start of the test network the assertions are evaluated at
+	At time.Duration `yaml:"at"`
+	// Views asserts that the main view of every node is non-empty and contains only live peers
+	Views bool `yaml:"views"`
+	// Coverage is the percentage of nodes a message announced through the bootstrap node must reach, 0 to skip the assertion
+	Coverage float64       `yaml:"coverage"`
+	Within   time.Duration `yaml:"within"`
+}
+
+// scenarioActions maps the actions of scenario events to the subcommands executing them.
+var scenarioActions = map[string]func(args []string) error{
+	"scale":     runScaleCommand,
+	"churn":     runChurnCommand,
+	"partition": runPartitionCommand,
+	"propagate": runPropagateCommand,
+	"verify":    runVerifyCommand,
+	"sleep":     func([]string) error { return nil },
+}
+
+// runScenarioCommand starts a test network, executes the timed events of a scenario file in order, evaluates its
+// assertions, and stops the network again. Returns an error if the scenario could not be executed or an assertion is
+// violated, the network is stopped either way unless the scenario keeps it.
+func runScenarioCommand(args []string) error {
+	runCmd := flag.NewFlagSet("run", flag.ContinueOnError)
+	dryRun := runCmd.Bool("dry-run", false, "Only validate the scenario file and print its events")
+	err := runCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+	if runCmd.NArg() != 1 {
+		return errors.New("usage: test-gossip run [-dry-run] scenario.yaml")
+	}
+	sc, err := readScenario(runCmd.Arg(0))
+	if err != nil {
+		return err
+	}
+	hostkeys := 1.0
+	if sc.Hostkeys != nil {
+		hostkeys = *sc.Hostkeys
+	}
+	opts, err := parseNetworkOptions(sc.Netem, sc.Vary, sc.Topology, hostkeys, sc.Adversaries)
+	if err != nil {
+		return err
+	}
+	opts.Resources.CPUs = sc.CPUs
+	if sc.Memory != "" {
+		memory, err := parseSize(sc.Memory)
+		if err != nil {
+			return err
+		}
+		opts.Resources.MemoryBytes = int64(memory)
+	}
+	if sc.IPv6 {
+		opts.Subnet6 = "fd00:6055:1::/64"
+	}
+	if *dryRun {
+		for _, event := range sc.Events {
+			fmt.Printf("%s\t%s %s\n", event.At, event.Action, strings.Join(event.flags(), " "))
+		}
+		return nil
+	}
+
+	log.Printf("Running scenario %s with %d nodes\n", sc.Name, sc.Nodes)
+	failures, err := runScenario(sc, opts)
+	if !sc.Keep {
+		// a partially started network is stopped as well
+		if stopErr := runStopCommand(); stopErr != nil {
+			log.Printf("could not stop the test network: %v\n", stopErr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("scenario %s aborted: %w", sc.Name, err)
+	}
+	if len(failures) > 0 {
+		for _, failure := range failures {
+			log.Println("FAIL: " + failure)
+		}
+		return fmt.Errorf("scenario %s failed %d assertions", sc.Name, len(failures))
+	}
+	log.Printf("Scenario %s passed\n", sc.Name)
+	return nil
+}
+
+// runScenario starts the test network of a scenario, executes its events, and returns the violated assertions. Returns an
+// error if the network could not be started or an event failed, the network is left running either way.
+func runScenario(sc *scenario, opts networkOptions) ([]string, error) {
+	err := runStartCommand(sc.Nodes, opts)
+	if err != nil {
+		return nil, err
+	}
+	startedAt := time.Now()
+	for i, event := range sc.Events {
+		time.Sleep(time.Until(startedAt.Add(event.At)))
+		log.Printf("[%s] %s %s\n", time.Since(startedAt).Round(time.Second), event.Action, strings.Join(event.flags(), " "))
+		err = scenarioActions[event.Action](event.flags())
+		if err != nil {
+			return nil, fmt.Errorf("event %d (%s) failed: %w", i, event.Action, err)
+		}
+	}
+	if sc.Expect == nil {
+		return nil, nil
+	}
+	time.Sleep(time.Until(startedAt.Add(sc.Expect.At)))
+	return evaluateExpectations(*sc.Expect), nil
+}
+
+// readScenario reads and validates the scenario file at the given path, the events are ordered by their time.
+func readScenario(path string) (*scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sc := &scenario{}
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	err = decoder.Decode(sc)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse scenario %s: %w", path, err)
+	}
+	if sc.Name == "" {
+		sc.Name = path
+	}
+	if sc.Nodes < 2 {
+		return nil, fmt.Errorf("scenario %s must start at least 2 nodes", sc.Name)
+	}
+	for i, event := range sc.Events {
+		if _, ok := scenarioActions[event.Action]; !ok {
+			return nil, fmt.Errorf("event %d of scenario %s has the unknown action %q", i, sc.Name, event.Action)
+		}
+		if event.At < 0 {
+			return nil, fmt.Errorf("event %d of scenario %s has a negative time", i, sc.Name)
+		}
+	}
+	// events scheduled at the same time keep the order of the file
+	sort.SliceStable(sc.Events, func(i, j int) bool { return sc.Events[i].At < sc.Events[j].At })
+	if sc.Expect != nil && sc.Expect.Within == 0 {
+		sc.Expect.Within = 30 * time.Second
+	}
+	return sc, nil
+}
+
+// flags returns the arguments of the event as command line flags ordered by their names.
+func (e scenarioEvent) flags() []string {
+	var flags []string
+	for name, value := range e.Args {
+		flags = append(flags, fmt.Sprintf("-%s=%v", name, value))
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// evaluateExpectations evaluates the assertions of a scenario against the running test network and returns the violated ones.
+func evaluateExpectations(expect scenarioExpect) []string {
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return []string{err.Error()}
+	}
+	containers, err := listGossipContainers(ctx, cli)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var running []gossipContainer
+	live := make(map[string]string)
+	for _, c := range containers {
+		if c.Running {
+			running = append(running, c)
+			live[c.Identity] = c.Name
+		}
+	}
+	var failures []string
+	if expect.Views {
+		failures = append(failures, verifyViews(ctx, cli, running, live)...)
+	}
+	if expect.Coverage > 0 {
+		failures = append(failures, verifyPropagation(ctx, cli, running, expect.Coverage, expect.Within)...)
+	}
+	return failures
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadScenario(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		yaml     string
+		wantErr  bool
+		validate func(t *testing.T, sc *scenario, path string)
+	}{
+		{
+			name: "events are ordered by their time, keeping the order of the file",
+			yaml: `name: ordered
+nodes: 3
+events:
+  - {at: 2m, action: verify}
+  - {at: 1m, action: scale, args: {add: 2}}
+  - {at: 1m, action: sleep}
+expect: {views: true}
+`,
+			validate: func(t *testing.T, sc *scenario, _ string) {
+				var actions []string
+				for _, event := range sc.Events {
+					actions = append(actions, event.Action)
+				}
+				if len(actions) != 3 || actions[0] != "scale" || actions[1] != "sleep" || actions[2] != "verify" {
+					t.Errorf("expected the events scale, sleep, and verify, received %v", actions)
+				}
+				if flags := sc.Events[0].flags(); len(flags) != 1 || flags[0] != "-add=2" {
+					t.Errorf("expected the flags [-add=2], received %v", flags)
+				}
+				if sc.Expect.Within != 30*time.Second {
+					t.Errorf("expected the default of 30s to reach the coverage, received %s", sc.Expect.Within)
+				}
+			},
+		},
+		{
+			name: "the name defaults to the path",
+			yaml: "nodes: 2\n",
+			validate: func(t *testing.T, sc *scenario, path string) {
+				if sc.Name != path {
+					t.Errorf("expected the name %s, received %s", path, sc.Name)
+				}
+			},
+		},
+		{name: "fewer than two nodes", yaml: "nodes: 1\n", wantErr: true},
+		{name: "unknown action", yaml: "nodes: 2\nevents:\n  - {at: 1m, action: explode}\n", wantErr: true},
+		{name: "negative time", yaml: "nodes: 2\nevents:\n  - {at: -1m, action: sleep}\n", wantErr: true},
+		{name: "unknown field", yaml: "nodes: 2\nnode_count: 3\n", wantErr: true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "scenario.yaml")
+			if err := os.WriteFile(path, []byte(test.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			sc, err := readScenario(path)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected an error: %t, received %v", test.wantErr, err)
+			}
+			if test.validate != nil {
+				test.validate(t, sc, path)
+			}
+		})
+	}
+	t.Run("shipped scenarios are valid", func(t *testing.T) {
+		t.Parallel()
+		paths, err := filepath.Glob(filepath.Join("scenarios", "*.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range paths {
+			if _, err := readScenario(path); err != nil {
+				t.Errorf("scenario %s is invalid: %v", path, err)
+			}
+		}
+	})
+}
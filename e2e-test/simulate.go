@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// runSimulateCommand runs the in-process simulation of the gossip binary with the given flags, which simulates networks far
+// larger than Docker can start on a single host, using the configuration of the test network.
+func runSimulateCommand(args []string) error {
+	// the configuration must hold a valid hostkey, the cached key of the first test node is reused
+	cacheDir := filepath.Join(testKeyCacheDir, "0")
+	_, err := cachedIdentity(cacheDir)
+	if err != nil {
+		_ = os.RemoveAll(cacheDir)
+		err = runGossipCommand("keygen", "-out", moduleRelativePath(cacheDir))
+		if err != nil {
+			return err
+		}
+	}
+	return runGossipCommand(append([]string{"dev", "-c", moduleRelativePath(testConfigPath), "-set", "hostkey=" + moduleRelativePath(filepath.Join(cacheDir, privateKeyFileName))}, args...)...)
+}
+
+// runGossipCommand runs a subcommand of the gossip binary from the root of the gossip module, whose internal packages
+// cannot be imported by this one.
+func runGossipCommand(args ...string) error {
+	cmd := exec.Command("go", append([]string{"run", "./cmd/gossip"}, args...)...)
+	cmd.Dir = ".."
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("gossip %s failed: %w", args[0], err)
+	}
+	return nil
+}
+
+// moduleRelativePath returns the given path relative to the root of the gossip module.
+func moduleRelativePath(path string) string {
+	return filepath.ToSlash(filepath.Join("e2e-test", path))
+}
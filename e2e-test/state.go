@@ -0,0 +1,95 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	dockerClient "github.com/docker/docker/client"
+)
+
+const (
+	// stateDumpDir represents the tmpfs within each container the node writes its state dumps to, configured by state_dump_file.
+	stateDumpDir = "/state"
+	// stateDumpPath represents the state dump file within each container.
+	stateDumpPath = stateDumpDir + "/state.json"
+	// stateDumpTimeout represents the time to wait for a node to write its state dump.
+	stateDumpTimeout = 5 * time.Second
+)
+
+// stateDump represents the parts of the state dump of a node the e2e tool evaluates.
+type stateDump struct {
+	Time     time.Time `json:"time"`
+	Round    int       `json:"round"`
+	Identity string    `json:"identity"`
+	MainView []struct {
+		Identity string `json:"identity"`
+	} `json:"main_view"`
+	Messages struct {
+		Entries []struct {
+			DataType uint16 `json:"data_type"`
+			DataHash string `json:"data_hash"`
+		} `json:"entries"`
+	} `json:"messages"`
+}
+
+// HasMessage reports whether the message with the given hex-encoded data hash is within the message cache of the node.
+func (d *stateDump) HasMessage(dataHash string) bool {
+	for _, entry := range d.Messages.Entries {
+		if entry.DataHash == dataHash {
+			return true
+		}
+	}
+	return false
+}
+
+// readStateDump signals the node within the container to write its state dump and returns it once written.
+func readStateDump(ctx context.Context, cli *dockerClient.Client, c gossipContainer) (*stateDump, error) {
+	requestedAt := time.Now()
+	err := cli.ContainerKill(ctx, c.ID, "SIGUSR1")
+	if err != nil {
+		return nil, fmt.Errorf("could not signal container %s: %w", c.Name, err)
+	}
+	for {
+		dump, err := copyStateDump(ctx, cli, c)
+		// the clocks of the host and containers are the same, so a dump written after the request is fresh
+		if err == nil && !dump.Time.Before(requestedAt.Truncate(time.Second)) {
+			return dump, nil
+		}
+		if time.Since(requestedAt) > stateDumpTimeout {
+			if err == nil {
+				err = fmt.Errorf("only a stale state dump of %s exists", dump.Time)
+			}
+			return nil, fmt.Errorf("container %s did not write its state dump: %w", c.Name, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// copyStateDump copies the most recent state dump out of the container.
+func copyStateDump(ctx context.Context, cli *dockerClient.Client, c gossipContainer) (*stateDump, error) {
+	reader, _, err := cli.CopyFromContainer(ctx, c.ID, stateDumpPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	// the file is wrapped within a tar archive
+	archive := tar.NewReader(reader)
+	_, err = archive.Next()
+	if err != nil {
+		return nil, err
+	}
+	dumpBytes, err := io.ReadAll(archive)
+	if err != nil {
+		return nil, err
+	}
+	dump := stateDump{}
+	err = json.Unmarshal(dumpBytes, &dump)
+	if err != nil {
+		return nil, err
+	}
+	return &dump, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// runStatusCommand prints a table of all gossip containers of the test network with their identities, addresses, and states.
+func runStatusCommand(args []string) error {
+	statusCmd := flag.NewFlagSet("status", flag.ContinueOnError)
+	all := statusCmd.Bool("a", false, "Also list stopped containers")
+	err := statusCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	containers, err := listGossipContainers(ctx, cli)
+	if err != nil {
+		return err
+	}
+	if len(containers) == 0 {
+		return errors.New("no gossip containers, start a test network first")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "INDEX\tNAME\tROLE\tSTATE\tIP\tAPI\tNETEM\tCONFIG\tIDENTITY")
+	running := 0
+	for _, c := range containers {
+		if c.Running {
+			running++
+		} else if !*all {
+			continue
+		}
+		role, state := rolePeer, "stopped"
+		if c.IsBootstrap() {
+			role = roleBootstrap
+		}
+		if c.Running {
+			state = "running"
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Index, c.Name, role, state, orDash(strings.Trim(c.IP+","+c.IPv6, ",")), orDash(c.APIAddress), orDash(c.Netem), orDash(c.Config), c.Identity)
+	}
+	_ = w.Flush()
+	fmt.Printf("%d of %d containers running\n", running, len(containers))
+	return nil
+}
+
+// orDash returns the string or a dash if it is empty, so empty columns remain recognizable.
+func orDash(str string) string {
+	if str == "" {
+		return "-"
+	}
+	return str
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// testPartialCertsDir represents the directory holding a subset of the public keys per node if the hostkeys are only
+// partially distributed, named by the identity of the node.
+var testPartialCertsDir = filepath.Join("test-data", "testcerts-partial")
+
+// topology represents how the nodes of the test network are wired to their bootstrap nodes.
+type topology struct {
+	// Kind is one of star, multi, chain, ring, and random
+	Kind string
+	// K is the number of bootstrap containers of multi, or the number of random bootstrap nodes per node of random
+	K int
+}
+
+// String returns the topology in the form parsed by parseTopology.
+func (t topology) String() string {
+	if t.Kind == "multi" || t.Kind == "random" {
+		return t.Kind + ":" + strconv.Itoa(t.K)
+	}
+	return t.Kind
+}
+
+// parseTopology parses one of star (the default), multi:K, chain, ring, and random:K.
+func parseTopology(str string) (topology, error) {
+	kind, kStr, hasK := strings.Cut(strings.TrimSpace(str), ":")
+	switch kind {
+	case "", "star":
+		return topology{Kind: "star"}, nil
+	case "chain", "ring":
+		if hasK {
+			return topology{}, fmt.Errorf("topology %s takes no parameter", kind)
+		}
+		return topology{Kind: kind}, nil
+	case "multi", "random":
+		k, err := strconv.Atoi(kStr)
+		if err != nil || k <= 0 {
+			return topology{}, fmt.Errorf("topology %s requires a positive number of bootstrap nodes, e.g. %s:3", kind, kind)
+		}
+		return topology{Kind: kind, K: k}, nil
+	}
+	return topology{}, fmt.Errorf("unknown topology %s, expected star, multi:K, chain, ring, or random:K", str)
+}
+
+// BootstrapIndices returns the indices of the bootstrap nodes of the node with the given index within a network of numNodes nodes.
+//   - star: all nodes bootstrap from node 0
+//   - multi: nodes 0 to K-1 are bootstrap nodes, which bootstrap from each other, and all other nodes bootstrap from all of them
+//   - chain: every node bootstraps from its predecessor
+//   - ring: like chain, but node 0 bootstraps from the last node
+//   - random: every node bootstraps from K other random nodes, chosen deterministically by its index
+func (t topology) BootstrapIndices(index int, numNodes int) []int {
+	var indices []int
+	switch t.Kind {
+	case "multi":
+		for i := 0; i < t.K && i < numNodes; i++ {
+			if i != index {
+				indices = append(indices, i)
+			}
+		}
+	case "chain", "ring":
+		if index > 0 {
+			indices = append(indices, index-1)
+		} else if t.Kind == "ring" && numNodes > 1 {
+			indices = append(indices, numNodes-1)
+		}
+	case "random":
+		rng := rand.New(rand.NewSource(int64(index)))
+		for _, i := range rng.Perm(numNodes) {
+			if len(indices) == t.K {
+				break
+			}
+			if i != index {
+				indices = append(indices, i)
+			}
+		}
+	default:
+		if index > 0 {
+			indices = append(indices, 0)
+		}
+	}
+	return indices
+}
+
+// distributeHostkeys copies the public keys known to each node into its own directory within testPartialCertsDir. Every
+// node knows its own key, the keys of its bootstrap nodes, and the given share of the keys of all other nodes.
+func distributeHostkeys(identities []string, t topology, share float64) error {
+	log.Printf("Distributing %.0f%% of the hostkeys to each node...\n", 100*share)
+	for n, identity := range identities {
+		known := map[int]bool{n: true}
+		for _, i := range t.BootstrapIndices(n, len(identities)) {
+			known[i] = true
+		}
+		rng := rand.New(rand.NewSource(int64(n)))
+		for i := range identities {
+			if rng.Float64() < share {
+				known[i] = true
+			}
+		}
+		dir := filepath.Join(testPartialCertsDir, identity)
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+		for i := range known {
+			err = copyFile(filepath.Join(testCertsDir, identities[i]), filepath.Join(dir, identities[i]))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// certsDirOf returns the directory holding the public keys known to the node with the given identity, relative to the
+// e2e-test directory.
+func certsDirOf(identity string) string {
+	dir := filepath.Join(testPartialCertsDir, identity)
+	if _, err := os.Stat(dir); err == nil {
+		return dir
+	}
+	return testCertsDir
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// labelConfig holds the config overrides of a gossip container within a heterogeneous test network.
+const labelConfig = "gossiphers.config"
+
+// configOverride represents a key of the gossip section set to a value different from the config template.
+type configOverride struct {
+	Key   string
+	Value string
+}
+
+// configVariant represents the config overrides of a single node.
+type configVariant []configOverride
+
+// String returns the overrides as comma-separated key=value pairs.
+func (v configVariant) String() string {
+	var pairs []string
+	for _, override := range v {
+		pairs = append(pairs, override.Key+"="+override.Value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// configDimension represents the values one or more keys of the gossip section take within a config matrix. Keys that
+// must change together, like the weights, form a single dimension whose values are tuples.
+type configDimension struct {
+	Keys   []string
+	Values [][]string
+}
+
+// configMatrix represents the parameter space of a heterogeneous test network, every node is assigned one combination
+// of the values of all dimensions.
+type configMatrix []configDimension
+
+// parseConfigMatrix parses dimensions separated by semicolons, e.g. degree=10,20;challenge_difficulty=4,8. The keys and
+// values of dimensions with multiple keys are joined by +, e.g. weight_push+weight_pull+weight_history=45+45+10,30+60+10.
+func parseConfigMatrix(str string) (configMatrix, error) {
+	if str == "" {
+		return nil, nil
+	}
+	var matrix configMatrix
+	for _, dimensionStr := range strings.Split(str, ";") {
+		keysStr, valuesStr, found := strings.Cut(strings.TrimSpace(dimensionStr), "=")
+		if !found || keysStr == "" || valuesStr == "" {
+			return nil, fmt.Errorf("expected key=value1,value2,..., received %s", dimensionStr)
+		}
+		dimension := configDimension{Keys: strings.Split(keysStr, "+")}
+		for _, valueStr := range strings.Split(valuesStr, ",") {
+			values := strings.Split(strings.TrimSpace(valueStr), "+")
+			if len(values) != len(dimension.Keys) {
+				return nil, fmt.Errorf("value %s of %s must consist of %d values joined by +", valueStr, keysStr, len(dimension.Keys))
+			}
+			dimension.Values = append(dimension.Values, values)
+		}
+		matrix = append(matrix, dimension)
+	}
+	return matrix, nil
+}
+
+// Size returns the number of combinations within the matrix.
+func (m configMatrix) Size() int {
+	size := 1
+	for _, dimension := range m {
+		size *= len(dimension.Values)
+	}
+	return size
+}
+
+// Variant returns the combination assigned to the node with the given index. The nodes cycle through all combinations,
+// with the first dimension changing fastest, so a network of Size nodes covers the whole matrix once.
+func (m configMatrix) Variant(index int) configVariant {
+	var variant configVariant
+	for _, dimension := range m {
+		values := dimension.Values[index%len(dimension.Values)]
+		index /= len(dimension.Values)
+		for i, key := range dimension.Keys {
+			variant = append(variant, configOverride{Key: key, Value: values[i]})
+		}
+	}
+	return variant
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfigMatrix(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		str     string
+		matrix  configMatrix
+		wantErr bool
+	}{
+		{"empty matrix", "", nil, false},
+		{"single dimension", "degree=10,20", configMatrix{{Keys: []string{"degree"}, Values: [][]string{{"10"}, {"20"}}}}, false},
+		{"dimensions separated by semicolons", "degree=10,20; challenge_difficulty=4", configMatrix{
+			{Keys: []string{"degree"}, Values: [][]string{{"10"}, {"20"}}},
+			{Keys: []string{"challenge_difficulty"}, Values: [][]string{{"4"}}},
+		}, false},
+		{"keys changing together", "weight_push+weight_pull=45+45,30+60", configMatrix{
+			{Keys: []string{"weight_push", "weight_pull"}, Values: [][]string{{"45", "45"}, {"30", "60"}}},
+		}, false},
+		{"missing values", "degree=", nil, true},
+		{"missing key", "=10", nil, true},
+		{"missing separator", "degree", nil, true},
+		{"tuple of the wrong length", "weight_push+weight_pull=45+45,30", nil, true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			matrix, err := parseConfigMatrix(test.str)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected an error: %t, received %v", test.wantErr, err)
+			}
+			if !reflect.DeepEqual(matrix, test.matrix) {
+				t.Errorf("expected the matrix %v, received %v", test.matrix, matrix)
+			}
+		})
+	}
+}
+
+func TestConfigMatrix_Variant(t *testing.T) {
+	t.Parallel()
+	matrix, err := parseConfigMatrix("degree=10,20;weight_push+weight_pull=45+45,30+60,20+70")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := matrix.Size(); size != 6 {
+		t.Fatalf("expected 6 combinations, received %d", size)
+	}
+	tests := []struct {
+		index   int
+		variant string
+	}{
+		{0, "degree=10,weight_push=45,weight_pull=45"},
+		{1, "degree=20,weight_push=45,weight_pull=45"},
+		{2, "degree=10,weight_push=30,weight_pull=60"},
+		{5, "degree=20,weight_push=20,weight_pull=70"},
+		// the nodes cycle through the combinations once the matrix is covered
+		{6, "degree=10,weight_push=45,weight_pull=45"},
+		{9, "degree=20,weight_push=30,weight_pull=60"},
+	}
+	for _, test := range tests {
+		if variant := matrix.Variant(test.index).String(); variant != test.variant {
+			t.Errorf("expected node %d to be assigned %s, received %s", test.index, test.variant, variant)
+		}
+	}
+	if variant := configMatrix(nil).Variant(3); len(variant) != 0 {
+		t.Errorf("expected no overrides without a matrix, received %s", variant)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	dockerClient "github.com/docker/docker/client"
+)
+
+// verifyDataType represents the data type of the messages announced to verify their propagation.
+const verifyDataType = 4243
+
+// runVerifyCommand asserts properties of the running test network based on the state dumps of all nodes and returns an
+// error if any of them is violated, so the tool exits with a non-zero status, e.g. for CI use.
+func runVerifyCommand(args []string) error {
+	verifyCmd := flag.NewFlagSet("verify", flag.ContinueOnError)
+	coverage := verifyCmd.Float64("coverage", 95, "Percentage of nodes a message announced through the bootstrap node must reach")
+	within := verifyCmd.Duration("within", 30*time.Second, "Time within which the message must reach the required percentage of nodes")
+	checkMessage := verifyCmd.Bool("message", true, "Verify the propagation of a message, otherwise only the views are verified")
+	err := verifyCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	containers, err := listGossipContainers(ctx, cli)
+	if err != nil {
+		return err
+	}
+	var running []gossipContainer
+	live := make(map[string]string)
+	for _, c := range containers {
+		if c.Running {
+			running = append(running, c)
+			live[c.Identity] = c.Name
+		}
+	}
+	if len(running) == 0 {
+		return errors.New("no running gossip containers, start a test network first")
+	}
+
+	var failures []string
+	failures = append(failures, verifyViews(ctx, cli, running, live)...)
+	if *checkMessage {
+		failures = append(failures, verifyPropagation(ctx, cli, running, *coverage, *within)...)
+	}
+	if len(failures) > 0 {
+		for _, failure := range failures {
+			log.Println("FAIL: " + failure)
+		}
+		return fmt.Errorf("%d assertions failed", len(failures))
+	}
+	log.Println("All assertions passed")
+	return nil
+}
+
+// verifyViews asserts that the main view of every node is non-empty and contains only peers whose containers are running.
+func verifyViews(ctx context.Context, cli *dockerClient.Client, running []gossipContainer, live map[string]string) []string {
+	var failures []string
+	for _, c := range running {
+		dump, err := readStateDump(ctx, cli, c)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		if len(dump.MainView) == 0 {
+			failures = append(failures, fmt.Sprintf("%s has an empty view in round %d", c.Name, dump.Round))
+			continue
+		}
+		dead := 0
+		for _, node := range dump.MainView {
+			if _, ok := live[node.Identity]; !ok {
+				dead++
+			}
+		}
+		if dead > 0 {
+			failures = append(failures, fmt.Sprintf("%s has %d of %d peers within its view that are not live in round %d", c.Name, dead, len(dump.MainView), dump.Round))
+		}
+	}
+	if len(failures) == 0 {
+		log.Printf("PASS: the views of all %d nodes contain only live peers\n", len(running))
+	}
+	return failures
+}
+
+// verifyPropagation asserts that a message announced through the bootstrap node reaches the given percentage of nodes in time.
+func verifyPropagation(ctx context.Context, cli *dockerClient.Client, running []gossipContainer, coverage float64, within time.Duration) []string {
+	dataHash, err := announceTestMessage(verifyDataType, fmt.Sprintf("verify %d", time.Now().UnixNano()))
+	if err != nil {
+		return []string{err.Error()}
+	}
+	announcedAt := time.Now()
+	required := int(coverage / 100 * float64(len(running)))
+	if float64(required) < coverage/100*float64(len(running)) {
+		required++
+	}
+	for {
+		holders := countMessageHolders(ctx, cli, running, dataHash)
+		elapsed := time.Since(announcedAt)
+		if holders >= required {
+			log.Printf("PASS: message reached %d of %d nodes within %s\n", holders, len(running), elapsed.Round(time.Millisecond))
+			return nil
+		}
+		if elapsed > within {
+			return []string{fmt.Sprintf("message reached only %d of %d nodes within %s, required are %.1f%%", holders, len(running), within, coverage)}
+		}
+		time.Sleep(time.Second)
+	}
+}
@@ -1,10 +1,17 @@
 module gossiphers
 
-go 1.20
+go 1.24
 
 require (
+	github.com/dchest/siphash v1.2.3
+	github.com/zeebo/blake3 v0.2.3
+	go.uber.org/zap v1.24.0
+	gopkg.in/ini.v1 v1.67.0
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
+)
+
+require (
+	github.com/klauspost/cpuid/v2 v2.0.12 // indirect
 	go.uber.org/atomic v1.7.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
-	go.uber.org/zap v1.24.0 // indirect
-	gopkg.in/ini.v1 v1.67.0 // indirect
 )
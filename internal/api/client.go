@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// supportedClientMessageTypes are the message types a Client handles, all others sent by the server are skipped.
+var supportedClientMessageTypes = []MessageType{MessageTypeGossipNotification, MessageTypeGossipError, MessageTypeGossipStatus, MessageTypeGossipLatency, MessageTypeGossipPeerStats, MessageTypeGossipAnnounceResult, MessageTypeGossipQuarantine}
+
+// Client represents a connection to the API of a gossip node, e.g. for command line tools and tests.
+// Packets can be sent concurrently, but ReadNotification and ReadPacket must not be called concurrently.
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// writeLock ensures every packet is written with a single write, as the server expects one packet per read
+	writeLock sync.Mutex
+}
+
+// Dial connects to the API of a gossip node at the given TCP address.
+func Dial(address string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the API at %s: %w", address, err)
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient returns a Client communicating over an established connection.
+func NewClient(conn net.Conn) *Client {
+	return &Client{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+// Announce requests the node to spread a message.
+func (c *Client) Announce(ttl uint8, dataType uint16, data []byte, highPriority bool) error {
+	packet, err := NewGossipAnnounce(ttl, dataType, data, highPriority)
+	if err != nil {
+		return err
+	}
+	return c.write(packet)
+}
+
+// AnnounceBatch requests the node to spread several messages with a single request, answered by a *GossipAnnounceResult holding
+// whether each message was accepted.
+func (c *Client) AnnounceBatch(messages []AnnouncedMessage) error {
+	packet, err := NewGossipAnnounceBatch(messages)
+	if err != nil {
+		return err
+	}
+	return c.write(packet)
+}
+
+// Notify subscribes the client to the notifications of messages of a data type. If replay is set, the node first sends the
+// notifications of the data type it buffered shortly before the subscription.
+func (c *Client) Notify(dataType uint16, replay bool) error {
+	return c.write(NewGossipNotify(dataType, replay))
+}
+
+// Validate reports whether the data of a received notification is valid, and thereby whether the node should spread the message further.
+func (c *Client) Validate(messageID uint16, isValid bool) error {
+	return c.write(NewGossipValidation(messageID, isValid))
+}
+
+// QueryStatus requests the delivery status of a message previously announced through this node, answered by a *GossipStatus.
+func (c *Client) QueryStatus(dataType uint16, dataHash []byte) error {
+	packet, err := NewGossipStatusQuery(dataType, dataHash)
+	if err != nil {
+		return err
+	}
+	return c.write(packet)
+}
+
+// QueryLatency requests the round trip times the node measured to its peers, answered by a *GossipLatency.
+func (c *Client) QueryLatency() error {
+	return c.write(NewGossipLatencyQuery())
+}
+
+// QueryPeerStats requests the protocol statistics the node recorded for each of its peers, answered by a *GossipPeerStats.
+func (c *Client) QueryPeerStats() error {
+	return c.write(NewGossipPeerStatsQuery())
+}
+
+// QueryQuarantine requests the addresses whose packets the node currently drops, answered by a *GossipQuarantine.
+func (c *Client) QueryQuarantine() error {
+	return c.write(NewGossipQuarantineQuery())
+}
+
+// ReadNotification blocks until the next notification is received. Packets of other types are skipped, except for
+// rejections of previous requests, which are returned as a *GossipError.
+func (c *Client) ReadNotification() (*GossipNotification, error) {
+	for {
+		packet, err := c.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+		if notification, ok := packet.(*GossipNotification); ok {
+			return notification, nil
+		}
+	}
+}
+
+// ReadPacket blocks until the next packet is received, which is either a *GossipNotification, a *GossipStatus, a *GossipLatency, a *GossipPeerStats,
+// a *GossipAnnounceResult, or a *GossipQuarantine.
+// Packets of unknown types are skipped, rejections of previous requests are returned as a *GossipError.
+func (c *Client) ReadPacket() (ParseablePacket, error) {
+	for {
+		headerBytes := make([]byte, 4)
+		_, err := io.ReadFull(c.reader, headerBytes)
+		if err != nil {
+			return nil, err
+		}
+		size := binary.BigEndian.Uint16(headerBytes[:2])
+		if size < 4 {
+			return nil, ErrParsePacketHeaderInvalidSize
+		}
+		packetBytes := make([]byte, size)
+		copy(packetBytes, headerBytes)
+		_, err = io.ReadFull(c.reader, packetBytes[4:])
+		if err != nil {
+			return nil, err
+		}
+
+		header, err := parsePacketHeader(headerBytes, supportedClientMessageTypes)
+		if err != nil {
+			continue
+		}
+		var packet ParseablePacket
+		switch header.Type {
+		case MessageTypeGossipNotification:
+			packet = &GossipNotification{}
+		case MessageTypeGossipError:
+			packet = &GossipError{}
+		case MessageTypeGossipStatus:
+			packet = &GossipStatus{}
+		case MessageTypeGossipLatency:
+			packet = &GossipLatency{}
+		case MessageTypeGossipPeerStats:
+			packet = &GossipPeerStats{}
+		case MessageTypeGossipAnnounceResult:
+			packet = &GossipAnnounceResult{}
+		case MessageTypeGossipQuarantine:
+			packet = &GossipQuarantine{}
+		}
+		err = packet.Parse(header, bufio.NewReader(bytes.NewReader(packetBytes)))
+		if err != nil {
+			return nil, err
+		}
+		if gossipError, ok := packet.(*GossipError); ok {
+			return nil, gossipError
+		}
+		return packet, nil
+	}
+}
+
+// SetReadDeadline sets the deadline for ReadNotification, see net.Conn.
+func (c *Client) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// Close closes the connection to the node.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// write sends a packet to the node.
+func (c *Client) write(packet WritablePacket) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	_, err := c.conn.Write(packet.ToBytes())
+	return err
+}
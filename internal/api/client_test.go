@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"gossiphers/internal/clock"
+	"gossiphers/internal/config"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClient_Announce(t *testing.T) {
+	t.Parallel()
+	t.Run("announces a message to the server", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{MaxMessageDataSize: 16}, clock.Real())
+		announced := make(chan GossipAnnounce, 1)
+		server.RegisterGossipAnnounceHandler(func(ttl uint8, dataType uint16, data []byte, highPriority bool) {
+			packet, _ := NewGossipAnnounce(ttl, dataType, data, highPriority)
+			announced <- *packet
+		})
+		serverConn, clientConn := net.Pipe()
+		go server.handleRequests(serverConn)
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		err := client.Announce(3, 42, []byte("data"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet := <-announced
+		if packet.TTL != 3 || packet.DataType != 42 || string(packet.Data) != "data" || packet.Flags != AnnounceFlagHighPriority {
+			t.Errorf("unexpected announce %+v", packet)
+		}
+	})
+	t.Run("receives the rejection of too large data", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{MaxMessageDataSize: 2}, clock.Real())
+		serverConn, clientConn := net.Pipe()
+		go server.handleRequests(serverConn)
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		err := client.Announce(0, 42, []byte("data"), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.ReadNotification()
+		var gossipError *GossipError
+		if !errors.As(err, &gossipError) {
+			t.Fatalf("expected a GossipError, received %v", err)
+		}
+		if gossipError.RequestType != MessageTypeGossipAnnounce || gossipError.Code != ErrorCodeDataTooLarge {
+			t.Errorf("unexpected error %+v", gossipError)
+		}
+	})
+	t.Run("announces a batch of messages and receives the result of each", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{MaxMessageDataSize: 4}, clock.Real())
+		announced := make(chan AnnouncedMessage, 2)
+		server.RegisterGossipAnnounceHandler(func(ttl uint8, dataType uint16, data []byte, highPriority bool) {
+			message := AnnouncedMessage{TTL: ttl, DataType: dataType, Data: data}
+			if highPriority {
+				message.Flags = AnnounceFlagHighPriority
+			}
+			announced <- message
+		})
+		serverConn, clientConn := net.Pipe()
+		go server.handleRequests(serverConn)
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		err := client.AnnounceBatch([]AnnouncedMessage{
+			{TTL: 3, DataType: 42, Data: []byte("data")},
+			{TTL: 3, DataType: 42, Data: []byte("too large")},
+			{Flags: AnnounceFlagHighPriority, DataType: 43, Data: []byte("warn")},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet, err := client.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, ok := packet.(*GossipAnnounceResult)
+		if !ok {
+			t.Fatalf("expected a GossipAnnounceResult, received %T", packet)
+		}
+		if !reflect.DeepEqual(result.Codes, []ErrorCode{ErrorCodeNone, ErrorCodeDataTooLarge, ErrorCodeNone}) {
+			t.Errorf("unexpected result codes %v", result.Codes)
+		}
+		received := map[uint16]AnnouncedMessage{}
+		for i := 0; i < 2; i++ {
+			message := <-announced
+			received[message.DataType] = message
+		}
+		if string(received[42].Data) != "data" || received[42].TTL != 3 || received[43].Flags != AnnounceFlagHighPriority {
+			t.Errorf("unexpected announced messages %+v", received)
+		}
+	})
+	t.Run("announces messages with the ttl enforced by the ttl policy", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{MaxMessageDataSize: 100, ApiTTLPolicies: map[uint16]config.TTLPolicy{42: {MaxTTL: 8, DefaultTTL: 5}}}, clock.Real())
+		ttls := make(chan uint8, 4)
+		server.RegisterGossipAnnounceHandler(func(ttl uint8, dataType uint16, data []byte, highPriority bool) {
+			ttls <- ttl
+		})
+		serverConn, clientConn := net.Pipe()
+		go server.handleRequests(serverConn)
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		for _, ttl := range []uint8{0, 20, 3} {
+			if err := client.Announce(ttl, 42, []byte("data"), false); err != nil {
+				t.Fatal(err)
+			}
+			if enforced, expected := <-ttls, map[uint8]uint8{0: 5, 20: 8, 3: 3}[ttl]; enforced != expected {
+				t.Errorf("expected a ttl of %d for the announced ttl %d, received %d", expected, ttl, enforced)
+			}
+		}
+		if err := client.Announce(0, 43, []byte("data"), false); err != nil {
+			t.Fatal(err)
+		}
+		if ttl := <-ttls; ttl != 0 {
+			t.Errorf("expected the infinite ttl of a data type without policy to be kept, received %d", ttl)
+		}
+	})
+}
+
+func TestClient_ReadNotification(t *testing.T) {
+	t.Parallel()
+	t.Run("skips other packets and parses notifications", func(t *testing.T) {
+		t.Parallel()
+		serverConn, clientConn := net.Pipe()
+		defer serverConn.Close()
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		notification, err := NewGossipNotification(7, []byte("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			_, _ = serverConn.Write(NewGossipStatus(7, make([]byte, DataHashSize), true, 1).ToBytes())
+			_, _ = serverConn.Write(notification.ToBytes())
+		}()
+
+		received, err := client.ReadNotification()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if received.MessageID != notification.MessageID || received.DataType != 7 || string(received.Data) != "hello" {
+			t.Errorf("unexpected notification %+v", received)
+		}
+	})
+}
+
+func TestClient_Validate(t *testing.T) {
+	t.Parallel()
+	t.Run("sends a parseable validation", func(t *testing.T) {
+		t.Parallel()
+		serverConn, clientConn := net.Pipe()
+		defer serverConn.Close()
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		go func() { _ = client.Validate(9, true) }()
+		buf := make([]byte, 8)
+		_, err := serverConn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, err := ParsePacketHeader(buf[:4])
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet := GossipValidation{}
+		err = packet.Parse(header, bufio.NewReader(bytes.NewReader(buf)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if packet.MessageID != 9 || !packet.IsValid {
+			t.Errorf("unexpected validation %+v", packet)
+		}
+	})
+}
+
+func TestClient_QueryStatus(t *testing.T) {
+	t.Parallel()
+	t.Run("sends a parseable status query", func(t *testing.T) {
+		t.Parallel()
+		serverConn, clientConn := net.Pipe()
+		defer serverConn.Close()
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		hash := bytes.Repeat([]byte{0xab}, DataHashSize)
+		go func() { _ = client.QueryStatus(7, hash) }()
+		buf := make([]byte, 8+DataHashSize)
+		_, err := serverConn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, err := ParsePacketHeader(buf[:4])
+		if err != nil {
+			t.Fatal(err)
+		}
+		packet := GossipStatusQuery{}
+		err = packet.Parse(header, bufio.NewReader(bytes.NewReader(buf)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if packet.DataType != 7 || !bytes.Equal(packet.DataHash, hash) {
+			t.Errorf("unexpected status query %+v", packet)
+		}
+	})
+	t.Run("rejects hashes of invalid size", func(t *testing.T) {
+		t.Parallel()
+		client := NewClient(nil)
+		if err := client.QueryStatus(7, []byte{1}); err == nil {
+			t.Error("expected an error for a short hash")
+		}
+	})
+}
+
+func TestClient_ReadPacket(t *testing.T) {
+	t.Parallel()
+	t.Run("parses status and latency replies", func(t *testing.T) {
+		t.Parallel()
+		serverConn, clientConn := net.Pipe()
+		defer serverConn.Close()
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		hash := bytes.Repeat([]byte{0xab}, DataHashSize)
+		peer := PeerLatency{Identity: bytes.Repeat([]byte{1}, IdentitySize), PingSamples: 3, PingMedian: 2 * time.Millisecond, PullP90: 5 * time.Millisecond}
+		latency, err := NewGossipLatency([]PeerLatency{peer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			_, _ = serverConn.Write(NewGossipStatus(7, hash, true, 4).ToBytes())
+			_, _ = serverConn.Write(latency.ToBytes())
+		}()
+
+		packet, err := client.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, ok := packet.(*GossipStatus)
+		if !ok || status.DataType != 7 || !status.IsKnown || status.Peers != 4 || !bytes.Equal(status.DataHash, hash) {
+			t.Errorf("unexpected status %+v", packet)
+		}
+		packet, err = client.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		received, ok := packet.(*GossipLatency)
+		if !ok || !reflect.DeepEqual(received.Peers, []PeerLatency{peer}) {
+			t.Errorf("unexpected latency %+v", packet)
+		}
+	})
+}
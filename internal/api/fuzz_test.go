@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+)
+
+// fuzzPacket represents a packet that can be both parsed and serialized.
+type fuzzPacket interface {
+	ParseablePacket
+	WritablePacket
+}
+
+// newFuzzPacket returns an empty packet of the given type, in the same way the server and client dispatch received packets.
+func newFuzzPacket(messageType MessageType) fuzzPacket {
+	switch messageType {
+	case MessageTypeGossipAnnounce:
+		return &GossipAnnounce{}
+	case MessageTypeGossipNotify:
+		return &GossipNotify{}
+	case MessageTypeGossipValidation:
+		return &GossipValidation{}
+	case MessageTypeGossipStatusQuery:
+		return &GossipStatusQuery{}
+	case MessageTypeGossipLatencyQuery:
+		return &GossipLatencyQuery{}
+	case MessageTypeGossipNotification:
+		return &GossipNotification{}
+	case MessageTypeGossipError:
+		return &GossipError{}
+	case MessageTypeGossipStatus:
+		return &GossipStatus{}
+	case MessageTypeGossipLatency:
+		return &GossipLatency{}
+	case MessageTypeGossipPeerStatsQuery:
+		return &GossipPeerStatsQuery{}
+	case MessageTypeGossipPeerStats:
+		return &GossipPeerStats{}
+	case MessageTypeGossipAnnounceBatch:
+		return &GossipAnnounceBatch{}
+	case MessageTypeGossipAnnounceResult:
+		return &GossipAnnounceResult{}
+	case MessageTypeGossipQuarantineQuery:
+		return &GossipQuarantineQuery{}
+	case MessageTypeGossipQuarantine:
+		return &GossipQuarantine{}
+	}
+	return nil
+}
+
+func FuzzParsePacket(f *testing.F) {
+	announce, err := NewGossipAnnounce(4, 1337, []byte("gossip"), true)
+	if err != nil {
+		f.Fatal(err)
+	}
+	statusQuery, err := NewGossipStatusQuery(1337, bytes.Repeat([]byte{0x12}, DataHashSize))
+	if err != nil {
+		f.Fatal(err)
+	}
+	notification, err := NewGossipNotification(1337, []byte("gossip"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	gossipError, err := NewGossipError(MessageTypeGossipAnnounce, ErrorCodeDataTooLarge, "data too large")
+	if err != nil {
+		f.Fatal(err)
+	}
+	latency, err := NewGossipLatency([]PeerLatency{{Identity: bytes.Repeat([]byte{0x34}, IdentitySize), PingSamples: 3, PingMedian: time.Millisecond}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	peerStats, err := NewGossipPeerStats([]PeerStats{{Identity: bytes.Repeat([]byte{0x56}, IdentitySize), BytesSent: 1024, Packets: []PacketCounts{{PacketType: 0x30, Received: 2, Sent: 3}}}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	announceBatch, err := NewGossipAnnounceBatch([]AnnouncedMessage{{TTL: 4, DataType: 1337, Data: []byte("gossip")}, {Flags: AnnounceFlagHighPriority, DataType: 1338, Data: []byte{}}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	announceResult, err := NewGossipAnnounceResult([]ErrorCode{ErrorCodeNone, ErrorCodeDataTooLarge})
+	if err != nil {
+		f.Fatal(err)
+	}
+	quarantine, err := NewGossipQuarantine([]QuarantinedPeer{{Address: "10.0.0.7:7001", Identity: bytes.Repeat([]byte{0x78}, IdentitySize), Remaining: time.Minute, Failures: 3}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, packet := range []WritablePacket{announce, NewGossipNotify(1337, true), NewGossipValidation(7, true), statusQuery, NewGossipLatencyQuery(),
+		NewGossipPeerStatsQuery(), notification, gossipError, NewGossipStatus(1337, bytes.Repeat([]byte{0x12}, DataHashSize), true, 4), latency, peerStats,
+		announceBatch, announceResult, NewGossipQuarantineQuery(), quarantine} {
+		f.Add(packet.ToBytes())
+	}
+
+	supportedTypes := append(append([]MessageType(nil), supportedIncomingMessageTypes...), supportedClientMessageTypes...)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) < 4 {
+			return
+		}
+		header, err := parsePacketHeader(data[:4], supportedTypes)
+		if err != nil {
+			return
+		}
+		packet := newFuzzPacket(header.Type)
+		if packet == nil {
+			t.Fatalf("no packet for supported type %#x", header.Type)
+		}
+		err = packet.Parse(header, bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return
+		}
+		if int(header.Size) != len(data) {
+			t.Fatalf("parsed packet of %d bytes with a size of %d in its header", len(data), header.Size)
+		}
+
+		// parsing drops reserved bits, but a serialized packet must parse to itself
+		serialized := packet.ToBytes()
+		reparsed := newFuzzPacket(header.Type)
+		err = reparsed.Parse(header, bufio.NewReader(bytes.NewReader(serialized)))
+		if err != nil {
+			t.Fatalf("serialized packet %x could not be parsed: %v", serialized, err)
+		}
+		if !bytes.Equal(reparsed.ToBytes(), serialized) {
+			t.Errorf("serialized packet changed when parsed again: %x, now %x", serialized, reparsed.ToBytes())
+		}
+	})
+}
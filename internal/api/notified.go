@@ -0,0 +1,51 @@
+package api
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// notifiedKey identifies a notified message by the hash of its data type and data.
+type notifiedKey [sha256.Size]byte
+
+// newNotifiedKey returns the key of a message with the given data type and data.
+func newNotifiedKey(dataType uint16, data []byte) notifiedKey {
+	hashFunc := sha256.New()
+	_ = binary.Write(hashFunc, binary.BigEndian, dataType)
+	hashFunc.Write(data)
+	var key notifiedKey
+	copy(key[:], hashFunc.Sum(nil))
+	return key
+}
+
+// notifiedHistory remembers the most recently notified messages of the subscribers of a single data type, forgetting the oldest ones once its capacity is exceeded.
+// The history is not safe for concurrent use.
+type notifiedHistory struct {
+	capacity int
+	// order contains notifiedKey values, oldest first
+	order *list.List
+	index map[notifiedKey]struct{}
+}
+
+// newNotifiedHistory returns a new instance of notifiedHistory.
+func newNotifiedHistory(capacity int) *notifiedHistory {
+	return &notifiedHistory{
+		capacity: capacity,
+		order:    list.New(),
+		index:    make(map[notifiedKey]struct{}),
+	}
+}
+
+// Add remembers a message. Returns false if the message is already remembered.
+func (h *notifiedHistory) Add(key notifiedKey) bool {
+	if _, ok := h.index[key]; ok {
+		return false
+	}
+	h.index[key] = struct{}{}
+	h.order.PushBack(key)
+	for h.order.Len() > h.capacity {
+		delete(h.index, h.order.Remove(h.order.Front()).(notifiedKey))
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package api
+
+import "testing"
+
+func TestNotifiedHistory_Add(t *testing.T) {
+	t.Parallel()
+	t.Run("rejects remembered messages", func(t *testing.T) {
+		t.Parallel()
+		history := newNotifiedHistory(10)
+		if !history.Add(newNotifiedKey(1, []byte("a"))) {
+			t.Error("expected new message to be added")
+		}
+		if history.Add(newNotifiedKey(1, []byte("a"))) {
+			t.Error("expected remembered message to be rejected")
+		}
+		if !history.Add(newNotifiedKey(2, []byte("a"))) {
+			t.Error("expected message of other data type to be added")
+		}
+	})
+	t.Run("forgets the oldest messages beyond its capacity", func(t *testing.T) {
+		t.Parallel()
+		history := newNotifiedHistory(2)
+		history.Add(newNotifiedKey(1, []byte("a")))
+		history.Add(newNotifiedKey(1, []byte("b")))
+		history.Add(newNotifiedKey(1, []byte("c")))
+		if !history.Add(newNotifiedKey(1, []byte("a"))) {
+			t.Error("expected oldest message to be forgotten")
+		}
+		if history.Add(newNotifiedKey(1, []byte("c"))) {
+			t.Error("expected recent message to be remembered")
+		}
+	})
+}
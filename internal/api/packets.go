@@ -1,20 +1,97 @@
 package api
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
 
 // MessageType represents the different types of messages existing within the API specification.
 type MessageType uint16
 
 const (
-	MessageTypeGossipAnnounce     MessageType = 500
-	MessageTypeGossipNotify       MessageType = 501
-	MessageTypeGossipNotification MessageType = 502
-	MessageTypeGossipValidation   MessageType = 503
+	MessageTypeGossipAnnounce        MessageType = 500
+	MessageTypeGossipNotify          MessageType = 501
+	MessageTypeGossipNotification    MessageType = 502
+	MessageTypeGossipValidation      MessageType = 503
+	MessageTypeGossipStatusQuery     MessageType = 504
+	MessageTypeGossipStatus          MessageType = 505
+	MessageTypeGossipError           MessageType = 506
+	MessageTypeGossipLatencyQuery    MessageType = 507
+	MessageTypeGossipLatency         MessageType = 508
+	MessageTypeGossipPeerStatsQuery  MessageType = 509
+	MessageTypeGossipPeerStats       MessageType = 510
+	MessageTypeGossipAnnounceBatch   MessageType = 511
+	MessageTypeGossipAnnounceResult  MessageType = 512
+	MessageTypeGossipQuarantineQuery MessageType = 513
+	MessageTypeGossipQuarantine      MessageType = 514
+
+	// AnnounceFlagHighPriority marks an announced message for the high-priority lane, e.g. revocations or operator alerts, which is spread before bulk messages.
+	AnnounceFlagHighPriority uint8 = 0x01
+	// NotifyFlagReplay requests the notifications of the subscribed data type that were buffered shortly before the subscription, so restarting clients do not miss bursts.
+	NotifyFlagReplay uint16 = 0x0001
+
+	// DataHashSize represents the length of the SHA-256 hash identifying the data of a gossip message in bytes.
+	DataHashSize = 32
+	// IdentitySize represents the length of the identity of a peer in bytes.
+	IdentitySize = 32
+	// LatencyEntrySize represents the length of a single peer within a GossipLatency packet in bytes.
+	// 32 bytes for the identity, 2 bytes each for the number of ping and pull samples, and 4 bytes each for the median and 90th percentile of both.
+	LatencyEntrySize = IdentitySize + 2 + 2 + 4*4
+	// MaxLatencyEntries represents the maximum number of peers fitting into a single GossipLatency packet.
+	MaxLatencyEntries = (65535 - 4) / LatencyEntrySize
+	// PeerStatsEntrySize represents the length of a single peer within a GossipPeerStats packet in bytes, without its packet counts.
+	// 32 bytes for the identity, 8 bytes each for the time of the last activity and the bytes received and sent, 4 bytes each for
+	// the send failures and invalid packets, and 2 bytes for the number of packet types that follow.
+	PeerStatsEntrySize = IdentitySize + 3*8 + 2*4 + 2
+	// PacketCountsEntrySize represents the length of the counts of a single packet type within a GossipPeerStats packet in bytes.
+	// 2 bytes for the packet type and 4 bytes each for the number of packets received and sent.
+	PacketCountsEntrySize = 2 + 2*4
+	// AnnouncedMessageHeaderSize represents the length of a single message within a GossipAnnounceBatch packet in bytes, without its data.
+	// 1 byte each for the TTL and the flags, 2 bytes each for the data type and the size of the data that follows.
+	AnnouncedMessageHeaderSize = 1 + 1 + 2 + 2
+	// QuarantineEntryHeaderSize represents the length of a single address within a GossipQuarantine packet in bytes, without the address itself.
+	// 1 byte for the size of the address that follows, 32 bytes for the identity, 4 bytes for the remaining time, and 2 bytes for the failures.
+	QuarantineEntryHeaderSize = 1 + IdentitySize + 4 + 2
+)
+
+// SupportedMessageTypes returns all message types of the API implemented by the server, requests and replies.
+func SupportedMessageTypes() []MessageType {
+	return []MessageType{
+		MessageTypeGossipAnnounce,
+		MessageTypeGossipNotify,
+		MessageTypeGossipNotification,
+		MessageTypeGossipValidation,
+		MessageTypeGossipStatusQuery,
+		MessageTypeGossipStatus,
+		MessageTypeGossipError,
+		MessageTypeGossipLatencyQuery,
+		MessageTypeGossipLatency,
+		MessageTypeGossipPeerStatsQuery,
+		MessageTypeGossipPeerStats,
+		MessageTypeGossipAnnounceBatch,
+		MessageTypeGossipAnnounceResult,
+		MessageTypeGossipQuarantineQuery,
+		MessageTypeGossipQuarantine,
+	}
+}
+
+// ErrorCode represents the reason a request of an API client was rejected.
+type ErrorCode uint16
+
+const (
+	// ErrorCodeNone indicates that a request was accepted, it is only used within the results of batched requests.
+	ErrorCodeNone ErrorCode = 0
+	// ErrorCodeDataTooLarge indicates that the data of an announced message exceeds the configured maximum size.
+	ErrorCodeDataTooLarge ErrorCode = 1
 )
 
 var (
-	ErrCreatePacketSizeExceeded         = errors.New("packet could not be created, maximum size exceeded")
-	consecutiveOutgoingMessageID uint16 = 0
+	ErrCreatePacketSizeExceeded = errors.New("packet could not be created, maximum size exceeded")
+	// consecutiveOutgoingMessageID counts the notifications created so far, messages received concurrently are notified concurrently
+	consecutiveOutgoingMessageID atomic.Uint32
 )
 
 // PacketHeader represents the header component of each packet.
@@ -28,7 +105,8 @@ type PacketHeader struct {
 type GossipAnnounce struct {
 	PacketHeader
 	TTL uint8
-	/* reserved, 8 bits */
+	// Flags holds AnnounceFlagHighPriority, the remaining bits are reserved
+	Flags    uint8
 	DataType uint16
 	Data     []byte
 }
@@ -38,7 +116,8 @@ type GossipAnnounce struct {
 // when a Gossip message of a certain type is received by the local peer
 type GossipNotify struct {
 	PacketHeader
-	/* reserved, 16 bits */
+	// Flags holds NotifyFlagReplay, the remaining bits are reserved
+	Flags    uint16
 	DataType uint16
 }
 
@@ -60,14 +139,414 @@ type GossipValidation struct {
 	IsValid bool
 }
 
+// GossipStatusQuery
+// From client to server, requests the delivery status of a Gossip message previously announced by the local peer
+type GossipStatusQuery struct {
+	PacketHeader
+	/* reserved, 16 bits */
+	DataType uint16
+	DataHash []byte
+}
+
+// GossipStatus
+// From server to client, reports the number of distinct peers known to have received an announced Gossip message
+type GossipStatus struct {
+	PacketHeader
+	DataType uint16
+	/* reserved, 15 bits */
+	IsKnown  bool
+	Peers    uint32
+	DataHash []byte
+}
+
+// NewGossipStatusQuery creates a new Gossip Status Query packet.
+func NewGossipStatusQuery(dataType uint16, dataHash []byte) (*GossipStatusQuery, error) {
+	if len(dataHash) != DataHashSize {
+		return nil, fmt.Errorf("invalid data hash size: expected %d, received %d", DataHashSize, len(dataHash))
+	}
+	return &GossipStatusQuery{
+		PacketHeader: PacketHeader{
+			Size: uint16(8 + DataHashSize), // 4B PacketHeader + 2B reserved + 2B DataType
+			Type: MessageTypeGossipStatusQuery,
+		},
+		DataType: dataType,
+		DataHash: dataHash,
+	}, nil
+}
+
+// NewGossipStatus creates a new Gossip Status packet.
+func NewGossipStatus(dataType uint16, dataHash []byte, isKnown bool, peers uint32) *GossipStatus {
+	return &GossipStatus{
+		PacketHeader: PacketHeader{
+			Size: uint16(12 + DataHashSize), // 4B PacketHeader + 2B DataType + 2B reserved/IsKnown + 4B Peers
+			Type: MessageTypeGossipStatus,
+		},
+		DataType: dataType,
+		IsKnown:  isKnown,
+		Peers:    peers,
+		DataHash: dataHash,
+	}
+}
+
+// GossipLatencyQuery
+// From client to server, requests the round trip times measured to the peers of the local peer
+type GossipLatencyQuery struct {
+	PacketHeader
+}
+
+// NewGossipLatencyQuery creates a new Gossip Latency Query packet.
+func NewGossipLatencyQuery() *GossipLatencyQuery {
+	return &GossipLatencyQuery{
+		PacketHeader: PacketHeader{
+			Size: 4,
+			Type: MessageTypeGossipLatencyQuery,
+		},
+	}
+}
+
+// PeerLatency represents the round trip times measured to a single peer. Times are transmitted in microseconds.
+type PeerLatency struct {
+	Identity    []byte
+	PingSamples uint16
+	PullSamples uint16
+	PingMedian  time.Duration
+	PingP90     time.Duration
+	PullMedian  time.Duration
+	PullP90     time.Duration
+}
+
+// GossipLatency
+// From server to client, reports the round trip times of pings and pull requests measured to each peer
+type GossipLatency struct {
+	PacketHeader
+	Peers []PeerLatency
+}
+
+// NewGossipLatency creates a new Gossip Latency packet.
+func NewGossipLatency(peers []PeerLatency) (*GossipLatency, error) {
+	if len(peers) > MaxLatencyEntries {
+		return nil, ErrCreatePacketSizeExceeded
+	}
+	for _, peer := range peers {
+		if len(peer.Identity) != IdentitySize {
+			return nil, fmt.Errorf("invalid identity size: expected %d, received %d", IdentitySize, len(peer.Identity))
+		}
+	}
+	return &GossipLatency{
+		PacketHeader: PacketHeader{
+			Size: uint16(4 + len(peers)*LatencyEntrySize),
+			Type: MessageTypeGossipLatency,
+		},
+		Peers: peers,
+	}, nil
+}
+
+// GossipPeerStatsQuery
+// From client to server, requests the protocol statistics the local peer recorded for each of its peers
+type GossipPeerStatsQuery struct {
+	PacketHeader
+}
+
+// NewGossipPeerStatsQuery creates a new Gossip Peer Stats Query packet.
+func NewGossipPeerStatsQuery() *GossipPeerStatsQuery {
+	return &GossipPeerStatsQuery{
+		PacketHeader: PacketHeader{
+			Size: 4,
+			Type: MessageTypeGossipPeerStatsQuery,
+		},
+	}
+}
+
+// PacketCounts represents the number of gossip packets of a single type exchanged with a peer.
+type PacketCounts struct {
+	PacketType uint16
+	Received   uint32
+	Sent       uint32
+}
+
+// PeerStats represents the protocol statistics recorded for a single peer. The last activity is transmitted in unix milliseconds,
+// 0 if there was none.
+type PeerStats struct {
+	Identity       []byte
+	LastActivity   time.Time
+	BytesReceived  uint64
+	BytesSent      uint64
+	SendFailures   uint32
+	InvalidPackets uint32
+	Packets        []PacketCounts
+}
+
+// size returns the length of the peer within a GossipPeerStats packet in bytes.
+func (p *PeerStats) size() int {
+	return PeerStatsEntrySize + len(p.Packets)*PacketCountsEntrySize
+}
+
+// GossipPeerStats
+// From server to client, reports the packets, bytes, last activity, and failures recorded for each peer
+type GossipPeerStats struct {
+	PacketHeader
+	Peers []PeerStats
+}
+
+// NewGossipPeerStats creates a new Gossip Peer Stats packet.
+func NewGossipPeerStats(peers []PeerStats) (*GossipPeerStats, error) {
+	size := 4
+	for _, peer := range peers {
+		if len(peer.Identity) != IdentitySize {
+			return nil, fmt.Errorf("invalid identity size: expected %d, received %d", IdentitySize, len(peer.Identity))
+		}
+		if len(peer.Packets) > math.MaxUint16 {
+			return nil, ErrCreatePacketSizeExceeded
+		}
+		size += peer.size()
+	}
+	if size > 65535 {
+		return nil, ErrCreatePacketSizeExceeded
+	}
+	return &GossipPeerStats{
+		PacketHeader: PacketHeader{
+			Size: uint16(size),
+			Type: MessageTypeGossipPeerStats,
+		},
+		Peers: peers,
+	}, nil
+}
+
+// FitPeerStats returns the leading peers fitting into a single GossipPeerStats packet.
+func FitPeerStats(peers []PeerStats) []PeerStats {
+	size := 4
+	for i := range peers {
+		size += peers[i].size()
+		if size > 65535 {
+			return peers[:i]
+		}
+	}
+	return peers
+}
+
+// AnnouncedMessage represents a single message within a GossipAnnounceBatch packet, with the fields of a GossipAnnounce packet.
+type AnnouncedMessage struct {
+	TTL uint8
+	// Flags holds AnnounceFlagHighPriority, the remaining bits are reserved
+	Flags    uint8
+	DataType uint16
+	Data     []byte
+}
+
+// GossipAnnounceBatch
+// From client to server, requests the local peer to distribute several messages at once, answered by a single GossipAnnounceResult
+type GossipAnnounceBatch struct {
+	PacketHeader
+	Messages []AnnouncedMessage
+}
+
+// NewGossipAnnounceBatch creates a new Gossip Announce Batch packet.
+func NewGossipAnnounceBatch(messages []AnnouncedMessage) (*GossipAnnounceBatch, error) {
+	size := 4
+	for _, message := range messages {
+		if len(message.Data) > math.MaxUint16 {
+			return nil, ErrCreatePacketSizeExceeded
+		}
+		size += AnnouncedMessageHeaderSize + len(message.Data)
+	}
+	if size > 65535 {
+		return nil, ErrCreatePacketSizeExceeded
+	}
+	return &GossipAnnounceBatch{
+		PacketHeader: PacketHeader{
+			Size: uint16(size),
+			Type: MessageTypeGossipAnnounceBatch,
+		},
+		Messages: messages,
+	}, nil
+}
+
+// GossipAnnounceResult
+// From server to client, reports whether each message of a GossipAnnounceBatch was accepted, ErrorCodeNone, or rejected
+type GossipAnnounceResult struct {
+	PacketHeader
+	// Codes holds the result of each message of the batch in the order of the batch
+	Codes []ErrorCode
+}
+
+// NewGossipAnnounceResult creates a new Gossip Announce Result packet.
+func NewGossipAnnounceResult(codes []ErrorCode) (*GossipAnnounceResult, error) {
+	size := 4 + 2*len(codes)
+	if size > 65535 {
+		return nil, ErrCreatePacketSizeExceeded
+	}
+	return &GossipAnnounceResult{
+		PacketHeader: PacketHeader{
+			Size: uint16(size),
+			Type: MessageTypeGossipAnnounceResult,
+		},
+		Codes: codes,
+	}, nil
+}
+
+// GossipQuarantineQuery
+// From client to server, requests the addresses whose packets the local peer currently drops
+type GossipQuarantineQuery struct {
+	PacketHeader
+}
+
+// NewGossipQuarantineQuery creates a new Gossip Quarantine Query packet.
+func NewGossipQuarantineQuery() *GossipQuarantineQuery {
+	return &GossipQuarantineQuery{
+		PacketHeader: PacketHeader{
+			Size: 4,
+			Type: MessageTypeGossipQuarantineQuery,
+		},
+	}
+}
+
+// QuarantinedPeer represents an address whose packets are dropped after repeated invalid signatures or failed challenges,
+// along with the identity claimed by its latest failed packet. The remaining time is transmitted in milliseconds.
+type QuarantinedPeer struct {
+	Address   string
+	Identity  []byte
+	Remaining time.Duration
+	Failures  uint16
+}
+
+// size returns the length of the peer within a GossipQuarantine packet in bytes.
+func (p *QuarantinedPeer) size() int {
+	return QuarantineEntryHeaderSize + len(p.Address)
+}
+
+// GossipQuarantine
+// From server to client, reports the quarantined addresses and the remaining time of their quarantine
+type GossipQuarantine struct {
+	PacketHeader
+	Peers []QuarantinedPeer
+}
+
+// NewGossipQuarantine creates a new Gossip Quarantine packet.
+func NewGossipQuarantine(peers []QuarantinedPeer) (*GossipQuarantine, error) {
+	size := 4
+	for _, peer := range peers {
+		if len(peer.Identity) != IdentitySize {
+			return nil, fmt.Errorf("invalid identity size: expected %d, received %d", IdentitySize, len(peer.Identity))
+		}
+		if len(peer.Address) > math.MaxUint8 {
+			return nil, fmt.Errorf("address exceeds %d bytes: %q", math.MaxUint8, peer.Address)
+		}
+		size += peer.size()
+	}
+	if size > 65535 {
+		return nil, ErrCreatePacketSizeExceeded
+	}
+	return &GossipQuarantine{
+		PacketHeader: PacketHeader{
+			Size: uint16(size),
+			Type: MessageTypeGossipQuarantine,
+		},
+		Peers: peers,
+	}, nil
+}
+
+// FitQuarantinedPeers returns the leading peers fitting into a single GossipQuarantine packet.
+func FitQuarantinedPeers(peers []QuarantinedPeer) []QuarantinedPeer {
+	size := 4
+	for i := range peers {
+		size += peers[i].size()
+		if size > 65535 {
+			return peers[:i]
+		}
+	}
+	return peers
+}
+
+// GossipError
+// From server to client, reports that a request of the client was rejected
+type GossipError struct {
+	PacketHeader
+	RequestType MessageType
+	Code        ErrorCode
+	// Reason is a human-readable UTF-8 description of the error
+	Reason string
+}
+
+// Error returns the reason of the rejection, making the packet usable as an error on the client side.
+func (p *GossipError) Error() string {
+	return fmt.Sprintf("request of type %d rejected with code %d: %s", p.RequestType, p.Code, p.Reason)
+}
+
+// NewGossipError creates a new Gossip Error packet.
+func NewGossipError(requestType MessageType, code ErrorCode, reason string) (*GossipError, error) {
+	size := 8 + len(reason) // 4B PacketHeader + 2B RequestType + 2B Code
+	if size > 65535 {
+		return nil, ErrCreatePacketSizeExceeded
+	}
+	return &GossipError{
+		PacketHeader: PacketHeader{
+			Size: uint16(size),
+			Type: MessageTypeGossipError,
+		},
+		RequestType: requestType,
+		Code:        code,
+		Reason:      reason,
+	}, nil
+}
+
+// NewGossipAnnounce creates a new Gossip Announce packet.
+func NewGossipAnnounce(ttl uint8, dataType uint16, data []byte, highPriority bool) (*GossipAnnounce, error) {
+	size := 8 + len(data) // 4B PacketHeader + 1B TTL + 1B Flags + 2B DataType
+	if size > 65535 {
+		return nil, ErrCreatePacketSizeExceeded
+	}
+	var flags uint8
+	if highPriority {
+		flags |= AnnounceFlagHighPriority
+	}
+	return &GossipAnnounce{
+		PacketHeader: PacketHeader{
+			Size: uint16(size),
+			Type: MessageTypeGossipAnnounce,
+		},
+		TTL:      ttl,
+		Flags:    flags,
+		DataType: dataType,
+		Data:     data,
+	}, nil
+}
+
+// NewGossipNotify creates a new Gossip Notify packet, requesting the replay of recently buffered notifications if replay is set.
+func NewGossipNotify(dataType uint16, replay bool) *GossipNotify {
+	var flags uint16
+	if replay {
+		flags |= NotifyFlagReplay
+	}
+	return &GossipNotify{
+		PacketHeader: PacketHeader{
+			Size: 8, // 4B PacketHeader + 2B Flags + 2B DataType
+			Type: MessageTypeGossipNotify,
+		},
+		Flags:    flags,
+		DataType: dataType,
+	}
+}
+
+// NewGossipValidation creates a new Gossip Validation packet.
+func NewGossipValidation(messageID uint16, isValid bool) *GossipValidation {
+	return &GossipValidation{
+		PacketHeader: PacketHeader{
+			Size: 8, // 4B PacketHeader + 2B MessageID + 2B reserved/IsValid
+			Type: MessageTypeGossipValidation,
+		},
+		MessageID: messageID,
+		IsValid:   isValid,
+	}
+}
+
 // NewGossipNotification creates a new Gossip Notification packet.
 func NewGossipNotification(dataType uint16, data []byte) (*GossipNotification, error) {
 	size := 8 + len(data) // 4B PacketHeader + 2B MessageID + 2B DataType
 	if size > 65535 {
 		return nil, ErrCreatePacketSizeExceeded
 	}
-	messageID := consecutiveOutgoingMessageID
-	consecutiveOutgoingMessageID++
+	// the message IDs wrap around like the 16 bit counter they are taken from
+	messageID := uint16(consecutiveOutgoingMessageID.Add(1) - 1)
 	return &GossipNotification{
 		PacketHeader: PacketHeader{
 			Size: uint16(size),
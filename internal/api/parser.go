@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"encoding/binary"
 	"errors"
+	"io"
+	"time"
 )
 
 var (
@@ -11,7 +13,7 @@ var (
 	ErrParsePacketHeaderInvalidType = errors.New("packet could not be parsed, type not implemented")
 	ErrParsePacketInvalidSize       = errors.New("packet could not be parsed, size in header does not match received data")
 
-	supportedIncomingMessageTypes = []MessageType{MessageTypeGossipAnnounce, MessageTypeGossipNotify, MessageTypeGossipValidation}
+	supportedIncomingMessageTypes = []MessageType{MessageTypeGossipAnnounce, MessageTypeGossipNotify, MessageTypeGossipValidation, MessageTypeGossipStatusQuery, MessageTypeGossipLatencyQuery, MessageTypeGossipPeerStatsQuery, MessageTypeGossipAnnounceBatch, MessageTypeGossipQuarantineQuery}
 )
 
 // ParseablePacket represents the ability to parse this particular packet.
@@ -19,8 +21,13 @@ type ParseablePacket interface {
 	Parse(header *PacketHeader, reader *bufio.Reader) error
 }
 
-// ParsePacketHeader attempts to parse the packet header.
+// ParsePacketHeader attempts to parse the header of a packet sent by a client to the server.
 func ParsePacketHeader(data []byte) (*PacketHeader, error) {
+	return parsePacketHeader(data, supportedIncomingMessageTypes)
+}
+
+// parsePacketHeader attempts to parse the packet header, accepting only the given message types.
+func parsePacketHeader(data []byte, supportedTypes []MessageType) (*PacketHeader, error) {
 	if len(data) != 4 {
 		return nil, ErrParsePacketHeaderInvalidSize
 	}
@@ -28,7 +35,7 @@ func ParsePacketHeader(data []byte) (*PacketHeader, error) {
 	messageType := MessageType(binary.BigEndian.Uint16(data[2:4]))
 
 	isSupported := false
-	for _, mt := range supportedIncomingMessageTypes {
+	for _, mt := range supportedTypes {
 		if messageType == mt {
 			isSupported = true
 		}
@@ -58,8 +65,7 @@ func (p *GossipAnnounce) Parse(header *PacketHeader, reader *bufio.Reader) error
 		return err
 	}
 
-	// discard reserved byte
-	_, err = reader.Discard(1)
+	err = binary.Read(reader, binary.BigEndian, &p.Flags)
 	if err != nil {
 		return err
 	}
@@ -69,13 +75,13 @@ func (p *GossipAnnounce) Parse(header *PacketHeader, reader *bufio.Reader) error
 		return err
 	}
 
-	// Read data bytes, limited to the given size minus the already read bytes
+	// Read data bytes, limited to the given size minus the already read bytes, which may exceed the buffered ones
+	if header.Size < 8 {
+		return ErrParsePacketInvalidSize
+	}
 	p.Data = make([]byte, header.Size-8)
-	n, err := reader.Read(p.Data)
+	_, err = io.ReadFull(reader, p.Data)
 	if err != nil {
-		return err
-	}
-	if n != int(header.Size-8) {
 		return ErrParsePacketInvalidSize
 	}
 
@@ -99,8 +105,7 @@ func (p *GossipNotify) Parse(header *PacketHeader, reader *bufio.Reader) error {
 	}
 	p.PacketHeader = *header
 
-	// discard reserved bytes
-	_, err = reader.Discard(2)
+	err = binary.Read(reader, binary.BigEndian, &p.Flags)
 	if err != nil {
 		return err
 	}
@@ -155,3 +160,459 @@ func (p *GossipValidation) Parse(header *PacketHeader, reader *bufio.Reader) err
 	}
 	return nil
 }
+
+// Parse parses the Gossip Status Query packet.
+func (p *GossipStatusQuery) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(8 + DataHashSize); err != nil || header.Size != uint16(8+DataHashSize) {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	// discard reserved bytes
+	_, err = reader.Discard(2)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Read(reader, binary.BigEndian, &p.DataType)
+	if err != nil {
+		return err
+	}
+
+	p.DataHash = make([]byte, DataHashSize)
+	_, err = io.ReadFull(reader, p.DataHash)
+	if err != nil {
+		return err
+	}
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses a GossipLatencyQuery packet, which consists of the header only.
+func (p *GossipLatencyQuery) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(4); err != nil || header.Size != 4 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses the Gossip Notification packet.
+func (p *GossipNotification) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(8); err != nil || header.Size < 8 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	err = binary.Read(reader, binary.BigEndian, &p.MessageID)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Read(reader, binary.BigEndian, &p.DataType)
+	if err != nil {
+		return err
+	}
+
+	p.Data = make([]byte, header.Size-8)
+	_, err = io.ReadFull(reader, p.Data)
+	if err != nil {
+		return ErrParsePacketInvalidSize
+	}
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses the Gossip Error packet.
+func (p *GossipError) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(8); err != nil || header.Size < 8 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	err = binary.Read(reader, binary.BigEndian, &p.RequestType)
+	if err != nil {
+		return err
+	}
+
+	err = binary.Read(reader, binary.BigEndian, &p.Code)
+	if err != nil {
+		return err
+	}
+
+	reason := make([]byte, header.Size-8)
+	_, err = io.ReadFull(reader, reason)
+	if err != nil {
+		return ErrParsePacketInvalidSize
+	}
+	p.Reason = string(reason)
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses the Gossip Status packet.
+func (p *GossipStatus) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(12 + DataHashSize); err != nil || header.Size != uint16(12+DataHashSize) {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	err = binary.Read(reader, binary.BigEndian, &p.DataType)
+	if err != nil {
+		return err
+	}
+
+	// the reserved bits are followed by the IsKnown flag
+	var flags uint16
+	err = binary.Read(reader, binary.BigEndian, &flags)
+	if err != nil {
+		return err
+	}
+	p.IsKnown = flags&1 == 1
+
+	err = binary.Read(reader, binary.BigEndian, &p.Peers)
+	if err != nil {
+		return err
+	}
+
+	p.DataHash = make([]byte, DataHashSize)
+	_, err = io.ReadFull(reader, p.DataHash)
+	if err != nil {
+		return err
+	}
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses the Gossip Latency packet.
+func (p *GossipLatency) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(4); err != nil || header.Size < 4 || (header.Size-4)%LatencyEntrySize != 0 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	numPeers := int(header.Size-4) / LatencyEntrySize
+	p.Peers = make([]PeerLatency, 0, numPeers)
+	for i := 0; i < numPeers; i++ {
+		peer := PeerLatency{Identity: make([]byte, IdentitySize)}
+		_, err = io.ReadFull(reader, peer.Identity)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		err = binary.Read(reader, binary.BigEndian, &peer.PingSamples)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		err = binary.Read(reader, binary.BigEndian, &peer.PullSamples)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		var micros [4]uint32
+		err = binary.Read(reader, binary.BigEndian, &micros)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		peer.PingMedian = time.Duration(micros[0]) * time.Microsecond
+		peer.PingP90 = time.Duration(micros[1]) * time.Microsecond
+		peer.PullMedian = time.Duration(micros[2]) * time.Microsecond
+		peer.PullP90 = time.Duration(micros[3]) * time.Microsecond
+		p.Peers = append(p.Peers, peer)
+	}
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses a GossipPeerStatsQuery packet, which consists of the header only.
+func (p *GossipPeerStatsQuery) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(4); err != nil || header.Size != 4 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses the Gossip Peer Stats packet.
+func (p *GossipPeerStats) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(4); err != nil || header.Size < 4 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	p.Peers = nil
+	remaining := int(header.Size) - 4
+	for remaining > 0 {
+		if remaining < PeerStatsEntrySize {
+			return ErrParsePacketInvalidSize
+		}
+		peer := PeerStats{Identity: make([]byte, IdentitySize)}
+		_, err = io.ReadFull(reader, peer.Identity)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		var fields struct {
+			LastActivity   uint64
+			BytesReceived  uint64
+			BytesSent      uint64
+			SendFailures   uint32
+			InvalidPackets uint32
+			NumPackets     uint16
+		}
+		err = binary.Read(reader, binary.BigEndian, &fields)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		if fields.LastActivity != 0 {
+			peer.LastActivity = time.UnixMilli(int64(fields.LastActivity))
+		}
+		peer.BytesReceived, peer.BytesSent = fields.BytesReceived, fields.BytesSent
+		peer.SendFailures, peer.InvalidPackets = fields.SendFailures, fields.InvalidPackets
+		remaining -= PeerStatsEntrySize
+		if int(fields.NumPackets)*PacketCountsEntrySize > remaining {
+			return ErrParsePacketInvalidSize
+		}
+		peer.Packets = make([]PacketCounts, fields.NumPackets)
+		err = binary.Read(reader, binary.BigEndian, peer.Packets)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		remaining -= int(fields.NumPackets) * PacketCountsEntrySize
+		p.Peers = append(p.Peers, peer)
+	}
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses the Gossip Announce Batch packet.
+func (p *GossipAnnounceBatch) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(4); err != nil || header.Size < 4 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	p.Messages = nil
+	remaining := int(header.Size) - 4
+	for remaining > 0 {
+		if remaining < AnnouncedMessageHeaderSize {
+			return ErrParsePacketInvalidSize
+		}
+		var fields struct {
+			TTL      uint8
+			Flags    uint8
+			DataType uint16
+			DataSize uint16
+		}
+		err = binary.Read(reader, binary.BigEndian, &fields)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		remaining -= AnnouncedMessageHeaderSize
+		if int(fields.DataSize) > remaining {
+			return ErrParsePacketInvalidSize
+		}
+		message := AnnouncedMessage{TTL: fields.TTL, Flags: fields.Flags, DataType: fields.DataType, Data: make([]byte, fields.DataSize)}
+		_, err = io.ReadFull(reader, message.Data)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		remaining -= int(fields.DataSize)
+		p.Messages = append(p.Messages, message)
+	}
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses the Gossip Announce Result packet.
+func (p *GossipAnnounceResult) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(4); err != nil || header.Size < 4 || header.Size%2 != 0 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	p.Codes = make([]ErrorCode, (header.Size-4)/2)
+	err = binary.Read(reader, binary.BigEndian, p.Codes)
+	if err != nil {
+		return ErrParsePacketInvalidSize
+	}
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses a GossipQuarantineQuery packet, which consists of the header only.
+func (p *GossipQuarantineQuery) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(4); err != nil || header.Size != 4 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
+
+// Parse parses the Gossip Quarantine packet.
+func (p *GossipQuarantine) Parse(header *PacketHeader, reader *bufio.Reader) error {
+	if _, err := reader.Peek(4); err != nil || header.Size < 4 {
+		return ErrParsePacketInvalidSize
+	}
+
+	// discard header, already parsed
+	_, err := reader.Discard(4)
+	if err != nil {
+		return err
+	}
+	p.PacketHeader = *header
+
+	p.Peers = nil
+	remaining := int(header.Size) - 4
+	for remaining > 0 {
+		if remaining < QuarantineEntryHeaderSize {
+			return ErrParsePacketInvalidSize
+		}
+		addressSize, err := reader.ReadByte()
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		remaining -= QuarantineEntryHeaderSize
+		if int(addressSize) > remaining {
+			return ErrParsePacketInvalidSize
+		}
+		address := make([]byte, addressSize)
+		_, err = io.ReadFull(reader, address)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		remaining -= int(addressSize)
+		peer := QuarantinedPeer{Address: string(address), Identity: make([]byte, IdentitySize)}
+		_, err = io.ReadFull(reader, peer.Identity)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		var remainingMs uint32
+		err = binary.Read(reader, binary.BigEndian, &remainingMs)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		peer.Remaining = time.Duration(remainingMs) * time.Millisecond
+		err = binary.Read(reader, binary.BigEndian, &peer.Failures)
+		if err != nil {
+			return ErrParsePacketInvalidSize
+		}
+		p.Peers = append(p.Peers, peer)
+	}
+
+	// Any leftover bytes are larger than specified in the header
+	if _, err := reader.Peek(1); err == nil {
+		return ErrParsePacketInvalidSize
+	}
+	return nil
+}
@@ -83,7 +83,7 @@ func TestGossipAnnounce_Parse(t *testing.T) {
 			t.Error(err)
 			return
 		}
-		if packet.TTL != 24 || packet.DataType != 1234 || !bytes.Equal(packet.Data, []byte{0x01, 0x23, 0x45, 0x67}) {
+		if packet.TTL != 24 || packet.Flags != 0xFF || packet.DataType != 1234 || !bytes.Equal(packet.Data, []byte{0x01, 0x23, 0x45, 0x67}) {
 			t.Error("Packet parsed wrong values", packet)
 			return
 		}
@@ -137,7 +137,7 @@ func TestGossipNotify_Parse(t *testing.T) {
 			t.Error(err)
 			return
 		}
-		if packet.DataType != 1234 {
+		if packet.DataType != 1234 || packet.Flags&NotifyFlagReplay == 0 {
 			t.Error("Packet parsed wrong values", packet)
 			return
 		}
@@ -209,3 +209,50 @@ func TestGossipValidation_Parse(t *testing.T) {
 		}
 	})
 }
+
+func TestGossipStatusQuery_Parse(t *testing.T) {
+	t.Parallel()
+	dataHash := bytes.Repeat([]byte{0xAB}, DataHashSize)
+	t.Run("correct packet is parsed successfully", func(t *testing.T) {
+		reader := bufio.NewReader(bytes.NewReader(append([]byte{0x00, 0x28, 0x01, 0xF8, 0x00, 0x00, 0x04, 0xD2}, dataHash...)))
+		packet := GossipStatusQuery{}
+		err := packet.Parse(&PacketHeader{Size: 40, Type: MessageTypeGossipStatusQuery}, reader)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if packet.DataType != 1234 || !bytes.Equal(packet.DataHash, dataHash) {
+			t.Error("Packet parsed wrong values", packet)
+		}
+	})
+
+	t.Run("returns error on packet with invalid amount of bytes", func(t *testing.T) {
+		reader := bufio.NewReader(bytes.NewReader(append([]byte{0x00, 0x27, 0x01, 0xF8, 0x00, 0x00, 0x04, 0xD2}, dataHash[1:]...)))
+		packet := GossipStatusQuery{}
+		err := packet.Parse(&PacketHeader{Size: 39, Type: MessageTypeGossipStatusQuery}, reader)
+		if !errors.Is(err, ErrParsePacketInvalidSize) {
+			t.Error("Invalid packet size was accepted", err)
+		}
+	})
+}
+
+func TestGossipLatencyQuery_Parse(t *testing.T) {
+	t.Parallel()
+	t.Run("correct packet is parsed successfully", func(t *testing.T) {
+		reader := bufio.NewReader(bytes.NewReader([]byte{0x00, 0x04, 0x01, 0xFB}))
+		packet := GossipLatencyQuery{}
+		err := packet.Parse(&PacketHeader{Size: 4, Type: MessageTypeGossipLatencyQuery}, reader)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("returns error on packet with invalid amount of bytes", func(t *testing.T) {
+		reader := bufio.NewReader(bytes.NewReader([]byte{0x00, 0x05, 0x01, 0xFB, 0x00}))
+		packet := GossipLatencyQuery{}
+		err := packet.Parse(&PacketHeader{Size: 5, Type: MessageTypeGossipLatencyQuery}, reader)
+		if !errors.Is(err, ErrParsePacketInvalidSize) {
+			t.Error("Invalid packet size was accepted", err)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package api
+
+import "time"
+
+// bufferedNotification represents a notification along with the time it was sent to the subscribed clients.
+type bufferedNotification struct {
+	notification GossipNotification
+	sentAt       time.Time
+}
+
+// replayBuffer holds the most recent notifications per data type for a limited time, so clients subscribing shortly after a
+// burst of messages arrived, e.g. after a restart, can be notified of them as well.
+// The buffer is not safe for concurrent use.
+type replayBuffer struct {
+	size   int
+	window time.Duration
+	// notifications contains the buffered notifications per data type, oldest first
+	notifications map[uint16][]bufferedNotification
+}
+
+// newReplayBuffer returns a buffer holding up to size notifications per data type for the given time.
+func newReplayBuffer(size int, window time.Duration) *replayBuffer {
+	return &replayBuffer{
+		size:          size,
+		window:        window,
+		notifications: make(map[uint16][]bufferedNotification),
+	}
+}
+
+// Add buffers a notification sent at the given time, forgetting the oldest notification of its data type once the buffer of
+// the data type is full.
+func (rb *replayBuffer) Add(notification GossipNotification, now time.Time) {
+	buffered := append(rb.expire(notification.DataType, now), bufferedNotification{notification: notification, sentAt: now})
+	if len(buffered) > rb.size {
+		buffered = buffered[len(buffered)-rb.size:]
+	}
+	rb.notifications[notification.DataType] = buffered
+}
+
+// Recent returns the buffered notifications of a data type that were sent within the buffering time, oldest first.
+func (rb *replayBuffer) Recent(dataType uint16, now time.Time) []GossipNotification {
+	buffered := rb.expire(dataType, now)
+	notifications := make([]GossipNotification, 0, len(buffered))
+	for _, entry := range buffered {
+		notifications = append(notifications, entry.notification)
+	}
+	return notifications
+}
+
+// expire forgets the notifications of a data type sent before the buffering time and returns the remaining ones.
+func (rb *replayBuffer) expire(dataType uint16, now time.Time) []bufferedNotification {
+	buffered := rb.notifications[dataType]
+	expired := 0
+	for expired < len(buffered) && now.Sub(buffered[expired].sentAt) > rb.window {
+		expired++
+	}
+	if expired == len(buffered) {
+		delete(rb.notifications, dataType)
+		return nil
+	}
+	buffered = buffered[expired:]
+	rb.notifications[dataType] = buffered
+	return buffered
+}
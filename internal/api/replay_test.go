@@ -0,0 +1,48 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplayBuffer(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1000, 0)
+	notification := func(dataType uint16, data string) GossipNotification {
+		n, err := NewGossipNotification(dataType, []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *n
+	}
+	t.Run("keeps the most recent notifications per data type in order", func(t *testing.T) {
+		t.Parallel()
+		rb := newReplayBuffer(2, time.Minute)
+		rb.Add(notification(1, "a"), now)
+		rb.Add(notification(2, "b"), now)
+		rb.Add(notification(1, "c"), now)
+		rb.Add(notification(1, "d"), now)
+		recent := rb.Recent(1, now)
+		if len(recent) != 2 || string(recent[0].Data) != "c" || string(recent[1].Data) != "d" {
+			t.Errorf("expected c and d, received %v", recent)
+		}
+		if recent := rb.Recent(2, now); len(recent) != 1 || string(recent[0].Data) != "b" {
+			t.Errorf("expected b, received %v", recent)
+		}
+	})
+	t.Run("forgets notifications after the buffering time", func(t *testing.T) {
+		t.Parallel()
+		rb := newReplayBuffer(10, time.Minute)
+		rb.Add(notification(1, "a"), now)
+		rb.Add(notification(1, "b"), now.Add(30*time.Second))
+		if recent := rb.Recent(1, now.Add(61*time.Second)); len(recent) != 1 || string(recent[0].Data) != "b" {
+			t.Errorf("expected b, received %v", recent)
+		}
+		if recent := rb.Recent(1, now.Add(2*time.Minute)); len(recent) != 0 {
+			t.Errorf("expected no notifications, received %v", recent)
+		}
+		if _, ok := rb.notifications[1]; ok {
+			t.Error("expected the expired data type to be forgotten")
+		}
+	})
+}
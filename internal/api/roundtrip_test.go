@@ -0,0 +1,203 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// roundTripIterations is the number of random packets generated per packet type.
+const roundTripIterations = 200
+
+// randomBytes returns n random bytes, an empty but non-nil slice for n = 0 like the parsers return.
+func randomBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	r.Read(b)
+	return b
+}
+
+// randomDataSize returns the size of random message data, mostly small but occasionally larger than the buffer of a bufio.Reader.
+func randomDataSize(r *rand.Rand) int {
+	if r.Intn(10) == 0 {
+		return 4096 + r.Intn(8192)
+	}
+	return r.Intn(256)
+}
+
+// randomDuration returns a random duration of whole microseconds fitting into the uint32 the API transmits.
+func randomDuration(r *rand.Rand) time.Duration {
+	return time.Duration(r.Uint32()) * time.Microsecond
+}
+
+// packetGenerators returns a generator of random valid packets of every API message type.
+func packetGenerators(t *testing.T) map[MessageType]func(r *rand.Rand) fuzzPacket {
+	return map[MessageType]func(r *rand.Rand) fuzzPacket{
+		MessageTypeGossipAnnounce: func(r *rand.Rand) fuzzPacket {
+			p, err := NewGossipAnnounce(uint8(r.Intn(256)), uint16(r.Intn(65536)), randomBytes(r, randomDataSize(r)), r.Intn(2) == 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipNotify: func(r *rand.Rand) fuzzPacket {
+			return NewGossipNotify(uint16(r.Intn(65536)), r.Intn(2) == 0)
+		},
+		MessageTypeGossipValidation: func(r *rand.Rand) fuzzPacket {
+			return NewGossipValidation(uint16(r.Intn(65536)), r.Intn(2) == 0)
+		},
+		MessageTypeGossipStatusQuery: func(r *rand.Rand) fuzzPacket {
+			p, err := NewGossipStatusQuery(uint16(r.Intn(65536)), randomBytes(r, DataHashSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipLatencyQuery: func(r *rand.Rand) fuzzPacket {
+			return NewGossipLatencyQuery()
+		},
+		MessageTypeGossipPeerStatsQuery: func(r *rand.Rand) fuzzPacket {
+			return NewGossipPeerStatsQuery()
+		},
+		MessageTypeGossipNotification: func(r *rand.Rand) fuzzPacket {
+			p, err := NewGossipNotification(uint16(r.Intn(65536)), randomBytes(r, randomDataSize(r)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.MessageID = uint16(r.Intn(65536))
+			return p
+		},
+		MessageTypeGossipError: func(r *rand.Rand) fuzzPacket {
+			p, err := NewGossipError(MessageType(r.Intn(65536)), ErrorCode(r.Intn(65536)), string(randomBytes(r, r.Intn(128))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipStatus: func(r *rand.Rand) fuzzPacket {
+			return NewGossipStatus(uint16(r.Intn(65536)), randomBytes(r, DataHashSize), r.Intn(2) == 0, r.Uint32())
+		},
+		MessageTypeGossipLatency: func(r *rand.Rand) fuzzPacket {
+			peers := make([]PeerLatency, r.Intn(MaxLatencyEntries+1))
+			for i := range peers {
+				peers[i] = PeerLatency{
+					Identity:    randomBytes(r, IdentitySize),
+					PingSamples: uint16(r.Intn(65536)),
+					PullSamples: uint16(r.Intn(65536)),
+					PingMedian:  randomDuration(r),
+					PingP90:     randomDuration(r),
+					PullMedian:  randomDuration(r),
+					PullP90:     randomDuration(r),
+				}
+			}
+			p, err := NewGossipLatency(peers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipPeerStats: func(r *rand.Rand) fuzzPacket {
+			var peers []PeerStats
+			for i := r.Intn(64); i > 0; i-- {
+				peer := PeerStats{
+					Identity:       randomBytes(r, IdentitySize),
+					BytesReceived:  r.Uint64(),
+					BytesSent:      r.Uint64(),
+					SendFailures:   r.Uint32(),
+					InvalidPackets: r.Uint32(),
+					Packets:        make([]PacketCounts, r.Intn(16)),
+				}
+				if r.Intn(2) == 0 {
+					peer.LastActivity = time.UnixMilli(r.Int63n(1 << 42))
+				}
+				for j := range peer.Packets {
+					peer.Packets[j] = PacketCounts{PacketType: uint16(r.Intn(65536)), Received: r.Uint32(), Sent: r.Uint32()}
+				}
+				peers = append(peers, peer)
+			}
+			p, err := NewGossipPeerStats(peers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipAnnounceBatch: func(r *rand.Rand) fuzzPacket {
+			var messages []AnnouncedMessage
+			for i := r.Intn(8); i > 0; i-- {
+				messages = append(messages, AnnouncedMessage{
+					TTL:      uint8(r.Intn(256)),
+					Flags:    uint8(r.Intn(256)),
+					DataType: uint16(r.Intn(65536)),
+					Data:     randomBytes(r, r.Intn(1024)),
+				})
+			}
+			p, err := NewGossipAnnounceBatch(messages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipQuarantineQuery: func(r *rand.Rand) fuzzPacket {
+			return NewGossipQuarantineQuery()
+		},
+		MessageTypeGossipQuarantine: func(r *rand.Rand) fuzzPacket {
+			var peers []QuarantinedPeer
+			for i := r.Intn(64); i > 0; i-- {
+				peers = append(peers, QuarantinedPeer{
+					Address:   string(randomBytes(r, r.Intn(256))),
+					Identity:  randomBytes(r, IdentitySize),
+					Remaining: time.Duration(r.Uint32()) * time.Millisecond,
+					Failures:  uint16(r.Intn(65536)),
+				})
+			}
+			p, err := NewGossipQuarantine(peers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipAnnounceResult: func(r *rand.Rand) fuzzPacket {
+			codes := make([]ErrorCode, r.Intn(16))
+			for i := range codes {
+				codes[i] = ErrorCode(r.Intn(3))
+			}
+			p, err := NewGossipAnnounceResult(codes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+	}
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	t.Parallel()
+	for messageType, generate := range packetGenerators(t) {
+		messageType, generate := messageType, generate
+		t.Run(reflect.TypeOf(generate(rand.New(rand.NewSource(0)))).Elem().Name(), func(t *testing.T) {
+			t.Parallel()
+			r := rand.New(rand.NewSource(int64(messageType)))
+			for i := 0; i < roundTripIterations; i++ {
+				packet := generate(r)
+				packetBytes := packet.ToBytes()
+				header, err := parsePacketHeader(packetBytes[:4], []MessageType{messageType})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if int(header.Size) != len(packetBytes) {
+					t.Fatalf("size in header %d does not match the serialized size %d of %+v", header.Size, len(packetBytes), packet)
+				}
+				parsed := newFuzzPacket(messageType)
+				err = parsed.Parse(header, bufio.NewReader(bytes.NewReader(packetBytes)))
+				if err != nil {
+					t.Fatalf("could not parse serialized packet of %d bytes: %v", len(packetBytes), err)
+				}
+				if !reflect.DeepEqual(parsed, packet) {
+					t.Fatalf("parsed packet differs from the serialized one: expected %+v, received %+v", packet, parsed)
+				}
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
+	"gossiphers/internal/clock"
 	"gossiphers/internal/config"
+	"gossiphers/internal/logging"
 	"io"
 	"net"
 	"sync"
@@ -19,16 +22,40 @@ type Server struct {
 	listener                  net.Listener
 	dataTypeToRegisteredConns map[uint16][]net.Conn
 	gossipAnnounceHandlers    []GossipAnnounceHandler
-	gossipValidationHandlers  []GossipValidationHandler
-	gossipNotificationLock    sync.Mutex
+	// gossipValidationHandlers are guarded by gossipNotificationLock
+	gossipValidationHandlers []*GossipValidationHandler
+	gossipStatusHandlers     []GossipStatusHandler
+	gossipLatencyHandlers    []GossipLatencyHandler
+	gossipPeerStatsHandlers  []GossipPeerStatsHandler
+	gossipQuarantineHandlers []GossipQuarantineHandler
+	gossipNotificationLock   sync.Mutex
+	// notified maps data types to the messages their subscribers were notified of, guarded by gossipNotificationLock. The
+	// history outlives the connections, so clients reconnecting and subscribing again are not notified of the same messages.
+	notified map[uint16]*notifiedHistory
+	// replay buffers the most recent notifications for clients subscribing with NotifyFlagReplay, guarded by gossipNotificationLock
+	// and nil if the replay is disabled
+	replay *replayBuffer
+	// clock expires the pending validations and the buffered notifications
+	clock clock.Clock
 }
 
-// NewServer returns a new instance of Server.
-func NewServer(cfg *config.GossipConfig) *Server {
-	return &Server{
+// logger returns the logger of the api subsystem.
+func logger() *zap.Logger {
+	return logging.L(logging.API)
+}
+
+// NewServer returns a new instance of Server, expiring pending validations and buffered notifications by the given clock.
+func NewServer(cfg *config.GossipConfig, c clock.Clock) *Server {
+	server := &Server{
 		cfg:                       cfg,
+		clock:                     c,
 		dataTypeToRegisteredConns: make(map[uint16][]net.Conn),
+		notified:                  make(map[uint16]*notifiedHistory),
+	}
+	if cfg.ApiNotificationReplaySize > 0 {
+		server.replay = newReplayBuffer(cfg.ApiNotificationReplaySize, time.Millisecond*time.Duration(cfg.ApiNotificationReplayMs))
 	}
+	return server
 }
 
 // Start starts listening for tcp connections.
@@ -39,19 +66,30 @@ func (s *Server) Start() error {
 	}
 	s.listener = listener
 
-	zap.L().Info("API Server listening", zap.String("address", s.cfg.ApiAddress))
+	logger().Info("API Server listening", zap.String("address", s.cfg.ApiAddress))
 
 	go s.listenForConnections()
 	return nil
 }
 
+// Close stops accepting connections. Established connections are closed once their clients disconnect.
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
 // listenForConnections accepts network connection requests and forwards them to handlers.
 func (s *Server) listenForConnections() {
 	defer s.listener.Close()
 	for {
 		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
 		if err != nil {
-			zap.L().Warn("Error accepting API connection", zap.Error(err))
+			logger().Warn("Error accepting API connection", zap.Error(err))
 			continue
 		}
 
@@ -61,9 +99,10 @@ func (s *Server) listenForConnections() {
 
 // handleRequests determines the request type of the connection by means of the header and handles the packet accordingly.
 func (s *Server) handleRequests(conn net.Conn) {
-	zap.L().Info("New API Client connected", zap.String("client_address", conn.RemoteAddr().String()))
+	logger().Info("New API Client connected", zap.String("client_address", conn.RemoteAddr().String()))
 	defer func() {
 		// deregister connection from data type mappings
+		s.gossipNotificationLock.Lock()
 		for dt, clients := range s.dataTypeToRegisteredConns {
 			var newClients []net.Conn
 			for _, c := range clients {
@@ -73,8 +112,23 @@ func (s *Server) handleRequests(conn net.Conn) {
 			}
 			s.dataTypeToRegisteredConns[dt] = newClients
 		}
+		// pending validations no longer wait for the verdict of the connection
+		var decidedHandlers []*GossipValidationHandler
+		var results []bool
+		for _, handler := range s.gossipValidationHandlers {
+			if decided, result := handler.forget(conn); decided {
+				decidedHandlers = append(decidedHandlers, handler)
+				results = append(results, result)
+			}
+		}
+		s.gossipNotificationLock.Unlock()
+		for i, handler := range decidedHandlers {
+			logger().Debug("Decided validation of gossip notification after API client disconnected", zap.Uint16("message_id", handler.messageID),
+				zap.Bool("valid", results[i]), zap.String("policy", s.cfg.ApiValidationPolicy))
+			handler.callback(results[i])
+		}
 		_ = conn.Close()
-		zap.L().Info("API Client disconnected", zap.String("client_address", conn.RemoteAddr().String()))
+		logger().Info("API Client disconnected", zap.String("client_address", conn.RemoteAddr().String()))
 	}()
 
 	buf := make([]byte, 65535)
@@ -86,7 +140,7 @@ func (s *Server) handleRequests(conn net.Conn) {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			zap.L().Warn("Error reading packet from API connection", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+			logger().Warn("Error reading packet from API connection", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
 			break
 		}
 
@@ -94,12 +148,12 @@ func (s *Server) handleRequests(conn net.Conn) {
 
 		headerBytes, err := reader.Peek(4)
 		if err != nil {
-			zap.L().Warn("Received invalid packet from API Client. Incomplete Header", zap.String("client_address", conn.RemoteAddr().String()))
+			logger().Warn("Received invalid packet from API Client. Incomplete Header", zap.String("client_address", conn.RemoteAddr().String()))
 			continue
 		}
 		header, err := ParsePacketHeader(headerBytes)
 		if err != nil {
-			zap.L().Warn("Received invalid packet from API Client. Invalid Header", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+			logger().Warn("Received invalid packet from API Client. Invalid Header", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
 			continue
 		}
 
@@ -108,76 +162,329 @@ func (s *Server) handleRequests(conn net.Conn) {
 			packet := GossipAnnounce{}
 			err := packet.Parse(header, reader)
 			if err != nil {
-				zap.L().Warn("Could not parse GossipAnnounce packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+				logger().Warn("Could not parse GossipAnnounce packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
 				continue
 			}
+			if len(packet.Data) > s.cfg.MaxMessageDataSize {
+				logger().Info("Rejected GossipAnnounce packet with too large data", zap.String("client_address", conn.RemoteAddr().String()), zap.Int("data_size", len(packet.Data)))
+				s.sendGossipError(conn, MessageTypeGossipAnnounce, ErrorCodeDataTooLarge, fmt.Sprintf("data of %d bytes exceeds the maximum size of %d bytes", len(packet.Data), s.cfg.MaxMessageDataSize))
+				continue
+			}
+			ttl := s.enforceTTLPolicy(conn, packet.DataType, packet.TTL)
 			for _, handler := range s.gossipAnnounceHandlers {
-				go handler(packet.TTL, packet.DataType, packet.Data)
+				go handler(ttl, packet.DataType, packet.Data, packet.Flags&AnnounceFlagHighPriority != 0)
 			}
+		case MessageTypeGossipAnnounceBatch:
+			packet := GossipAnnounceBatch{}
+			err := packet.Parse(header, reader)
+			if err != nil {
+				logger().Warn("Could not parse GossipAnnounceBatch packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+				continue
+			}
+			s.announceBatch(conn, packet)
 		case MessageTypeGossipNotify:
 			packet := GossipNotify{}
 			err := packet.Parse(header, reader)
 			if err != nil {
-				zap.L().Warn("Could not parse GossipNotify packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+				logger().Warn("Could not parse GossipNotify packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
 				continue
 			}
-			// Register connection to receive notifications for given data type
-			if clients, ok := s.dataTypeToRegisteredConns[packet.DataType]; ok {
-				s.dataTypeToRegisteredConns[packet.DataType] = append(clients, conn)
-			} else {
-				s.dataTypeToRegisteredConns[packet.DataType] = []net.Conn{conn}
-			}
+			s.subscribe(conn, packet)
 		case MessageTypeGossipValidation:
 			packet := GossipValidation{}
 			err := packet.Parse(header, reader)
 			if err != nil {
-				zap.L().Warn("Could not parse GossipValidation packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+				logger().Warn("Could not parse GossipValidation packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+				continue
+			}
+			s.validate(conn, packet)
+		case MessageTypeGossipStatusQuery:
+			packet := GossipStatusQuery{}
+			err := packet.Parse(header, reader)
+			if err != nil {
+				logger().Warn("Could not parse GossipStatusQuery packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+				continue
+			}
+			s.sendGossipStatus(conn, packet)
+		case MessageTypeGossipLatencyQuery:
+			packet := GossipLatencyQuery{}
+			err := packet.Parse(header, reader)
+			if err != nil {
+				logger().Warn("Could not parse GossipLatencyQuery packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+				continue
+			}
+			s.sendGossipLatency(conn)
+		case MessageTypeGossipPeerStatsQuery:
+			packet := GossipPeerStatsQuery{}
+			err := packet.Parse(header, reader)
+			if err != nil {
+				logger().Warn("Could not parse GossipPeerStatsQuery packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
 				continue
 			}
+			s.sendGossipPeerStats(conn)
+		case MessageTypeGossipQuarantineQuery:
+			packet := GossipQuarantineQuery{}
+			err := packet.Parse(header, reader)
+			if err != nil {
+				logger().Warn("Could not parse GossipQuarantineQuery packet.", zap.String("client_address", conn.RemoteAddr().String()), zap.Error(err))
+				continue
+			}
+			s.sendGossipQuarantine(conn)
+		}
+	}
+}
 
-			for _, handler := range s.gossipValidationHandlers {
-				if handler.messageID == packet.MessageID {
-					handler.callback(packet.IsValid)
-				}
+// subscribe registers a connection to receive notifications for the data type, once even if it subscribes repeatedly, and
+// notifies it of the buffered notifications of the data type if requested. Both happen under the notification lock, so the
+// connection receives the replayed and the following notifications in the order they were sent, without gaps in between.
+func (s *Server) subscribe(conn net.Conn, packet GossipNotify) {
+	s.gossipNotificationLock.Lock()
+	defer s.gossipNotificationLock.Unlock()
+	if clients, ok := s.dataTypeToRegisteredConns[packet.DataType]; ok {
+		if !containsConn(clients, conn) {
+			s.dataTypeToRegisteredConns[packet.DataType] = append(clients, conn)
+		}
+	} else {
+		s.dataTypeToRegisteredConns[packet.DataType] = []net.Conn{conn}
+	}
+	if packet.Flags&NotifyFlagReplay == 0 || s.replay == nil {
+		return
+	}
+	replayed := 0
+	for _, notification := range s.replay.Recent(packet.DataType, s.clock.Now()) {
+		if !s.markNotified(notification.DataType, newNotifiedKey(notification.DataType, notification.Data)) {
+			continue
+		}
+		_, err := conn.Write(notification.ToBytes())
+		if err != nil {
+			logger().Warn("Could not replay gossip notification to API client", zap.Error(err), zap.String("client_address", conn.RemoteAddr().String()))
+			return
+		}
+		for _, handler := range s.gossipValidationHandlers {
+			if handler.messageID == notification.MessageID {
+				handler.notify(conn)
 			}
 		}
+		replayed++
 	}
+	logger().Debug("Replayed buffered gossip notifications", zap.Uint16("data_type", packet.DataType), zap.Int("notifications", replayed), zap.String("client_address", conn.RemoteAddr().String()))
 }
 
 // GossipAnnounceHandler represents a handler for the Gossip Announce message.
-type GossipAnnounceHandler func(ttl uint8, dataType uint16, data []byte)
+type GossipAnnounceHandler func(ttl uint8, dataType uint16, data []byte, highPriority bool)
 
 // RegisterGossipAnnounceHandler registers a GossipAnnounceHandler.
 func (s *Server) RegisterGossipAnnounceHandler(fn GossipAnnounceHandler) {
 	s.gossipAnnounceHandlers = append(s.gossipAnnounceHandlers, fn)
 }
 
-// GossipValidationHandler represents a handler for the Gossip Validation message.
-type GossipValidationHandler struct {
-	callback    func(valid bool)
-	messageID   uint16
-	timeCreated time.Time
+// enforceTTLPolicy returns the hop TTL of a message announced by a client after applying the ttl policy of its data type.
+func (s *Server) enforceTTLPolicy(conn net.Conn, dataType uint16, ttl uint8) uint8 {
+	policy, ok := s.cfg.ApiTTLPolicies[dataType]
+	if !ok {
+		return ttl
+	}
+	enforced := policy.Apply(ttl)
+	if enforced != ttl {
+		logger().Debug("Adjusted TTL of announced message to the ttl policy of its data type", zap.String("client_address", conn.RemoteAddr().String()), zap.Uint16("data_type", dataType), zap.Uint8("ttl", ttl), zap.Uint8("enforced_ttl", enforced))
+	}
+	return enforced
+}
+
+// announceBatch passes each message of a batch that is not too large to the announce handlers, like a separately announced
+// message, and answers the batch with the result of each message.
+func (s *Server) announceBatch(conn net.Conn, batch GossipAnnounceBatch) {
+	codes := make([]ErrorCode, len(batch.Messages))
+	for i, message := range batch.Messages {
+		if len(message.Data) > s.cfg.MaxMessageDataSize {
+			logger().Info("Rejected message of GossipAnnounceBatch packet with too large data", zap.String("client_address", conn.RemoteAddr().String()), zap.Int("data_size", len(message.Data)))
+			codes[i] = ErrorCodeDataTooLarge
+			continue
+		}
+		ttl := s.enforceTTLPolicy(conn, message.DataType, message.TTL)
+		for _, handler := range s.gossipAnnounceHandlers {
+			go handler(ttl, message.DataType, message.Data, message.Flags&AnnounceFlagHighPriority != 0)
+		}
+	}
+	result, err := NewGossipAnnounceResult(codes)
+	if err != nil {
+		logger().Error("Error building API gossip announce result packet", zap.Error(err))
+		return
+	}
+
+	// prevent multiple goroutines accessing connection writers at the same time
+	s.gossipNotificationLock.Lock()
+	defer s.gossipNotificationLock.Unlock()
+	_, err = conn.Write(result.ToBytes())
+	if err != nil {
+		logger().Warn("Could not send gossip announce result to API client", zap.Error(err), zap.String("client_address", conn.RemoteAddr().String()))
+	}
+}
+
+// GossipStatusHandler represents a handler for the Gossip Status Query message.
+// It returns the number of distinct peers known to have received the message and false if the message is unknown.
+type GossipStatusHandler func(dataType uint16, dataHash []byte) (peers int, isKnown bool)
+
+// RegisterGossipStatusHandler registers a GossipStatusHandler.
+func (s *Server) RegisterGossipStatusHandler(fn GossipStatusHandler) {
+	s.gossipStatusHandlers = append(s.gossipStatusHandlers, fn)
+}
+
+// sendGossipStatus answers a status query with the result of the first handler knowing the queried message.
+func (s *Server) sendGossipStatus(conn net.Conn, query GossipStatusQuery) {
+	var peers int
+	var isKnown bool
+	for _, handler := range s.gossipStatusHandlers {
+		if peers, isKnown = handler(query.DataType, query.DataHash); isKnown {
+			break
+		}
+	}
+	status := NewGossipStatus(query.DataType, query.DataHash, isKnown, uint32(peers))
+
+	// prevent multiple goroutines accessing connection writers at the same time
+	s.gossipNotificationLock.Lock()
+	defer s.gossipNotificationLock.Unlock()
+	_, err := conn.Write(status.ToBytes())
+	if err != nil {
+		logger().Warn("Could not send gossip status to API client", zap.Error(err), zap.String("client_address", conn.RemoteAddr().String()))
+	}
+}
+
+// GossipLatencyHandler represents a handler for the Gossip Latency Query message, returning the round trip times measured to each peer.
+type GossipLatencyHandler func() []PeerLatency
+
+// RegisterGossipLatencyHandler registers a GossipLatencyHandler.
+func (s *Server) RegisterGossipLatencyHandler(fn GossipLatencyHandler) {
+	s.gossipLatencyHandlers = append(s.gossipLatencyHandlers, fn)
+}
+
+// sendGossipLatency answers a latency query with the peers of all handlers, truncated to the peers fitting into a single packet.
+func (s *Server) sendGossipLatency(conn net.Conn) {
+	var peers []PeerLatency
+	for _, handler := range s.gossipLatencyHandlers {
+		peers = append(peers, handler()...)
+	}
+	if len(peers) > MaxLatencyEntries {
+		peers = peers[:MaxLatencyEntries]
+	}
+	latency, err := NewGossipLatency(peers)
+	if err != nil {
+		logger().Error("Error building API gossip latency packet", zap.Error(err))
+		return
+	}
+
+	// prevent multiple goroutines accessing connection writers at the same time
+	s.gossipNotificationLock.Lock()
+	defer s.gossipNotificationLock.Unlock()
+	_, err = conn.Write(latency.ToBytes())
+	if err != nil {
+		logger().Warn("Could not send gossip latency to API client", zap.Error(err), zap.String("client_address", conn.RemoteAddr().String()))
+	}
+}
+
+// GossipPeerStatsHandler represents a handler for the Gossip Peer Stats Query message, returning the protocol statistics recorded for each peer.
+type GossipPeerStatsHandler func() []PeerStats
+
+// RegisterGossipPeerStatsHandler registers a GossipPeerStatsHandler.
+func (s *Server) RegisterGossipPeerStatsHandler(fn GossipPeerStatsHandler) {
+	s.gossipPeerStatsHandlers = append(s.gossipPeerStatsHandlers, fn)
+}
+
+// sendGossipPeerStats answers a peer stats query with the peers of all handlers, truncated to the peers fitting into a single packet.
+func (s *Server) sendGossipPeerStats(conn net.Conn) {
+	var peers []PeerStats
+	for _, handler := range s.gossipPeerStatsHandlers {
+		peers = append(peers, handler()...)
+	}
+	stats, err := NewGossipPeerStats(FitPeerStats(peers))
+	if err != nil {
+		logger().Error("Error building API gossip peer stats packet", zap.Error(err))
+		return
+	}
+
+	// prevent multiple goroutines accessing connection writers at the same time
+	s.gossipNotificationLock.Lock()
+	defer s.gossipNotificationLock.Unlock()
+	_, err = conn.Write(stats.ToBytes())
+	if err != nil {
+		logger().Warn("Could not send gossip peer stats to API client", zap.Error(err), zap.String("client_address", conn.RemoteAddr().String()))
+	}
+}
+
+// GossipQuarantineHandler represents a handler for the Gossip Quarantine Query message, returning the quarantined addresses.
+type GossipQuarantineHandler func() []QuarantinedPeer
+
+// RegisterGossipQuarantineHandler registers a GossipQuarantineHandler.
+func (s *Server) RegisterGossipQuarantineHandler(fn GossipQuarantineHandler) {
+	s.gossipQuarantineHandlers = append(s.gossipQuarantineHandlers, fn)
+}
+
+// sendGossipQuarantine answers a quarantine query with the addresses of all handlers, truncated to the addresses fitting into a single packet.
+func (s *Server) sendGossipQuarantine(conn net.Conn) {
+	var peers []QuarantinedPeer
+	for _, handler := range s.gossipQuarantineHandlers {
+		peers = append(peers, handler()...)
+	}
+	quarantine, err := NewGossipQuarantine(FitQuarantinedPeers(peers))
+	if err != nil {
+		logger().Error("Error building API gossip quarantine packet", zap.Error(err))
+		return
+	}
+
+	// prevent multiple goroutines accessing connection writers at the same time
+	s.gossipNotificationLock.Lock()
+	defer s.gossipNotificationLock.Unlock()
+	_, err = conn.Write(quarantine.ToBytes())
+	if err != nil {
+		logger().Warn("Could not send gossip quarantine to API client", zap.Error(err), zap.String("client_address", conn.RemoteAddr().String()))
+	}
+}
+
+// sendGossipError informs a client that its request was rejected.
+func (s *Server) sendGossipError(conn net.Conn, requestType MessageType, code ErrorCode, reason string) {
+	errorPacket, err := NewGossipError(requestType, code, reason)
+	if err != nil {
+		logger().Error("Error building API gossip error packet", zap.Error(err))
+		return
+	}
+
+	// prevent multiple goroutines accessing connection writers at the same time
+	s.gossipNotificationLock.Lock()
+	defer s.gossipNotificationLock.Unlock()
+	_, err = conn.Write(errorPacket.ToBytes())
+	if err != nil {
+		logger().Warn("Could not send gossip error to API client", zap.Error(err), zap.String("client_address", conn.RemoteAddr().String()))
+	}
 }
 
 // SendGossipNotifications sends notification messages to all subscribed connections for that particular data type.
+// The notification is buffered for clients subscribing with NotifyFlagReplay shortly after, whose validation is handled like
+// the validation of the clients notified right away.
 func (s *Server) SendGossipNotifications(notification GossipNotification, validationCallback func(valid bool)) {
+	// prevent multiple goroutines accessing connection writers at the same time, and keep subscriptions from interleaving
+	s.gossipNotificationLock.Lock()
+	defer s.gossipNotificationLock.Unlock()
+	if s.replay != nil {
+		s.replay.Add(notification, s.clock.Now())
+	}
 	connections, ok := s.dataTypeToRegisteredConns[notification.DataType]
-	if !ok {
+	if !ok && s.replay == nil {
 		// No connections have registered this data type
-		zap.L().Info("Could not distribute GossipNotifications, no API client registered for this data type.", zap.Uint16("data_type", notification.DataType))
+		logger().Info("Could not distribute GossipNotifications, no API client registered for this data type.", zap.Uint16("data_type", notification.DataType))
 		return
 	}
-
-	validationHandler := GossipValidationHandler{
-		callback:    validationCallback,
-		messageID:   notification.MessageID,
-		timeCreated: time.Now(),
+	// messages are only remembered once delivered to a subscriber, so undelivered messages can still be replayed
+	if len(connections) > 0 && !s.markNotified(notification.DataType, newNotifiedKey(notification.DataType, notification.Data)) {
+		logger().Debug("Suppressed duplicate gossip notification", zap.Uint16("data_type", notification.DataType))
+		return
 	}
+
+	validationHandler := newGossipValidationHandler(notification.MessageID, s.cfg.ApiValidationPolicy, validationCallback, s.clock.Now())
 	s.gossipValidationHandlers = append(s.gossipValidationHandlers, validationHandler)
 
 	//Remove old validation handlers
 	for len(s.gossipValidationHandlers) > 1 {
-		if s.gossipValidationHandlers[0].timeCreated.Before(time.Now().Add(-10 * time.Second)) {
+		if s.gossipValidationHandlers[0].timeCreated.Before(s.clock.Now().Add(-10 * time.Second)) {
 			s.gossipValidationHandlers = s.gossipValidationHandlers[1:]
 		} else {
 			break
@@ -185,14 +492,60 @@ func (s *Server) SendGossipNotifications(notification GossipNotification, valida
 	}
 
 	packetBytes := notification.ToBytes()
-
-	// Send messages, prevent multiple goroutines accessing connection writers at the same time
-	s.gossipNotificationLock.Lock()
 	for _, conn := range connections {
 		_, err := conn.Write(packetBytes)
 		if err != nil {
-			zap.L().Warn("Could not send gossip notification to API client", zap.Error(err), zap.String("client_address", conn.RemoteAddr().String()))
+			logger().Warn("Could not send gossip notification to API client", zap.Error(err), zap.String("client_address", conn.RemoteAddr().String()))
+			continue
+		}
+		validationHandler.notify(conn)
+	}
+}
+
+// validate records the verdict of a connection on a notification and invokes the validation callback of the notification
+// once the verdicts decide it according to the validation policy.
+func (s *Server) validate(conn net.Conn, packet GossipValidation) {
+	s.gossipNotificationLock.Lock()
+	var decidedHandler *GossipValidationHandler
+	var valid bool
+	for _, handler := range s.gossipValidationHandlers {
+		if handler.messageID != packet.MessageID {
+			continue
+		}
+		if decided, result := handler.validate(conn, packet.IsValid); decided {
+			decidedHandler, valid = handler, result
+			break
 		}
 	}
 	s.gossipNotificationLock.Unlock()
+	// the callback is invoked without holding the lock, since it may take the locks of the gossip server
+	if decidedHandler != nil {
+		logger().Debug("Decided validation of gossip notification", zap.Uint16("message_id", packet.MessageID), zap.Bool("valid", valid),
+			zap.String("policy", s.cfg.ApiValidationPolicy))
+		decidedHandler.callback(valid)
+	}
+}
+
+// markNotified remembers that the subscribers of a data type are notified of a message, the caller must hold the notification lock.
+// Returns false if the subscribers of the data type were already notified of the message.
+func (s *Server) markNotified(dataType uint16, key notifiedKey) bool {
+	if s.cfg.ApiNotificationHistorySize == 0 {
+		return true
+	}
+	history, ok := s.notified[dataType]
+	if !ok {
+		history = newNotifiedHistory(s.cfg.ApiNotificationHistorySize)
+		s.notified[dataType] = history
+	}
+	return history.Add(key)
+}
+
+// containsConn checks whether a connection is part of the given connections.
+func containsConn(conns []net.Conn, conn net.Conn) bool {
+	for _, c := range conns {
+		if c == conn {
+			return true
+		}
+	}
+	return false
 }
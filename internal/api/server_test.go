@@ -0,0 +1,271 @@
+package api
+
+import (
+	"gossiphers/internal/clock"
+	"gossiphers/internal/config"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServer_SendGossipNotifications(t *testing.T) {
+	t.Parallel()
+	t.Run("notifies each connection of a message once", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{ApiNotificationHistorySize: 10}, clock.Real())
+		serverConn, clientConn := net.Pipe()
+		defer serverConn.Close()
+		server.dataTypeToRegisteredConns[1] = []net.Conn{serverConn}
+
+		received := make(chan int)
+		go func() {
+			data, _ := io.ReadAll(clientConn)
+			received <- len(data)
+		}()
+
+		first, err := NewGossipNotification(1, []byte("data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := NewGossipNotification(1, []byte("data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.SendGossipNotifications(*first, func(bool) {})
+		server.SendGossipNotifications(*second, func(bool) {})
+		_ = serverConn.Close()
+
+		if size := <-received; size != len(first.ToBytes()) {
+			t.Errorf("expected a single notification of %d bytes, received %d bytes", len(first.ToBytes()), size)
+		}
+	})
+	t.Run("does not notify a reconnecting client of the same message again", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{ApiNotificationHistorySize: 10}, clock.Real())
+		// subscribed waits until the given number of connections is subscribed to the data type
+		subscribed := func(conns int) {
+			for {
+				server.gossipNotificationLock.Lock()
+				done := len(server.dataTypeToRegisteredConns[1]) == conns
+				server.gossipNotificationLock.Unlock()
+				if done {
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		for _, data := range []string{"first", "second"} {
+			serverConn, clientConn := net.Pipe()
+			go server.handleRequests(serverConn)
+			client := NewClient(clientConn)
+			if err := client.Notify(1, false); err != nil {
+				t.Fatal(err)
+			}
+			subscribed(1)
+			received := make(chan string, 2)
+			go func() {
+				defer close(received)
+				for {
+					notification, err := client.ReadNotification()
+					if err != nil {
+						return
+					}
+					received <- string(notification.Data)
+				}
+			}()
+			for _, sent := range []string{"first", data} {
+				notification, err := NewGossipNotification(1, []byte(sent))
+				if err != nil {
+					t.Fatal(err)
+				}
+				server.SendGossipNotifications(*notification, func(bool) {})
+			}
+			_ = client.Close()
+			subscribed(0)
+			var notifications []string
+			for notification := range received {
+				notifications = append(notifications, notification)
+			}
+			if len(notifications) != 1 || notifications[0] != data {
+				t.Errorf("expected the notification of %s only, received %v", data, notifications)
+			}
+		}
+	})
+	t.Run("replays buffered notifications to late subscribers in order", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{ApiNotificationHistorySize: 10, ApiNotificationReplaySize: 10, ApiNotificationReplayMs: 60000}, clock.Real())
+		for _, data := range []string{"first", "second"} {
+			notification, err := NewGossipNotification(1, []byte(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			server.SendGossipNotifications(*notification, func(bool) {})
+		}
+		serverConn, clientConn := net.Pipe()
+		go server.handleRequests(serverConn)
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		if err := client.Notify(1, true); err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range []string{"first", "second"} {
+			notification, err := client.ReadNotification()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(notification.Data) != expected {
+				t.Errorf("expected the notification of %s, received %s", expected, notification.Data)
+			}
+		}
+	})
+	t.Run("does not replay to subscribers without the replay flag", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{ApiNotificationReplaySize: 10, ApiNotificationReplayMs: 60000}, clock.Real())
+		buffered, err := NewGossipNotification(1, []byte("buffered"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.SendGossipNotifications(*buffered, func(bool) {})
+		serverConn, clientConn := net.Pipe()
+		go server.handleRequests(serverConn)
+		client := NewClient(clientConn)
+		defer client.Close()
+
+		if err := client.Notify(1, false); err != nil {
+			t.Fatal(err)
+		}
+		// wait for the subscription, a late notification is sent to the subscribed client only
+		for {
+			server.gossipNotificationLock.Lock()
+			subscribed := len(server.dataTypeToRegisteredConns[1]) == 1
+			server.gossipNotificationLock.Unlock()
+			if subscribed {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		live, err := NewGossipNotification(1, []byte("live"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		go server.SendGossipNotifications(*live, func(bool) {})
+		notification, err := client.ReadNotification()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(notification.Data) != "live" {
+			t.Errorf("expected the live notification, received %s", notification.Data)
+		}
+	})
+}
+
+func TestServer_validate(t *testing.T) {
+	t.Parallel()
+	t.Run("decides a message once all connections validated it", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{ApiValidationPolicy: config.ValidationPolicyAll}, clock.Real())
+		conns := make([]net.Conn, 2)
+		for i := range conns {
+			serverConn, clientConn := net.Pipe()
+			defer serverConn.Close()
+			go func() { _, _ = io.Copy(io.Discard, clientConn) }()
+			conns[i] = serverConn
+		}
+		server.dataTypeToRegisteredConns[1] = conns
+
+		notification, err := NewGossipNotification(1, []byte("data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		verdicts := make(chan bool, 2)
+		server.SendGossipNotifications(*notification, func(valid bool) { verdicts <- valid })
+		server.validate(conns[0], GossipValidation{MessageID: notification.MessageID, IsValid: true})
+		select {
+		case valid := <-verdicts:
+			t.Fatalf("expected the message to be undecided after a single verdict, received %t", valid)
+		default:
+		}
+		server.validate(conns[1], GossipValidation{MessageID: notification.MessageID, IsValid: true})
+		server.validate(conns[1], GossipValidation{MessageID: notification.MessageID, IsValid: false})
+		if valid := <-verdicts; !valid {
+			t.Error("expected the message to be decided valid by both connections")
+		}
+		if len(verdicts) != 0 {
+			t.Error("expected the callback to be invoked once")
+		}
+	})
+	t.Run("ignores verdicts on messages whose validation expired", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(time.Unix(1000, 0))
+		server := NewServer(&config.GossipConfig{ApiValidationPolicy: config.ValidationPolicyFirstWins}, fakeClock)
+		serverConn, clientConn := net.Pipe()
+		defer serverConn.Close()
+		go func() { _, _ = io.Copy(io.Discard, clientConn) }()
+		server.dataTypeToRegisteredConns[1] = []net.Conn{serverConn}
+
+		expired, err := NewGossipNotification(1, []byte("expired"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		verdicts := make(chan bool, 1)
+		server.SendGossipNotifications(*expired, func(valid bool) { verdicts <- valid })
+		fakeClock.Advance(10*time.Second + time.Millisecond)
+		// pending validations are expired when the next message is notified
+		next, err := NewGossipNotification(1, []byte("next"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.SendGossipNotifications(*next, func(bool) {})
+		server.validate(serverConn, GossipValidation{MessageID: expired.MessageID, IsValid: true})
+		select {
+		case valid := <-verdicts:
+			t.Errorf("expected the expired message to remain undecided, received %t", valid)
+		default:
+		}
+	})
+}
+
+func TestServer_handleRequests(t *testing.T) {
+	t.Parallel()
+	t.Run("disconnecting clients no longer hold back the validation", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer(&config.GossipConfig{ApiValidationPolicy: config.ValidationPolicyAll}, clock.Real())
+		validating, validatingClient := net.Pipe()
+		defer validating.Close()
+		go func() { _, _ = io.Copy(io.Discard, validatingClient) }()
+		disconnecting, disconnectingClient := net.Pipe()
+		server.dataTypeToRegisteredConns[1] = []net.Conn{validating, disconnecting}
+		done := make(chan struct{})
+		go func() {
+			server.handleRequests(disconnecting)
+			close(done)
+		}()
+
+		notification, err := NewGossipNotification(1, []byte("data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		verdicts := make(chan bool, 2)
+		go func() { _, _ = io.ReadFull(disconnectingClient, make([]byte, len(notification.ToBytes()))) }()
+		server.SendGossipNotifications(*notification, func(valid bool) { verdicts <- valid })
+		server.validate(validating, GossipValidation{MessageID: notification.MessageID, IsValid: true})
+		select {
+		case valid := <-verdicts:
+			t.Fatalf("expected the message to wait for the verdict of the second client, received %t", valid)
+		default:
+		}
+		_ = disconnectingClient.Close()
+		<-done
+
+		select {
+		case valid := <-verdicts:
+			if !valid {
+				t.Error("expected the message to be decided valid by the remaining client")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the disconnect to decide the message")
+		}
+	})
+}
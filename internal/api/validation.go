@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net"
+	"time"
+
+	"gossiphers/internal/config"
+)
+
+// GossipValidationHandler represents a handler for the Gossip Validation message, combining the verdicts of the connections
+// notified of a message by the validation policy until the message is decided.
+type GossipValidationHandler struct {
+	callback    func(valid bool)
+	messageID   uint16
+	timeCreated time.Time
+	policy      string
+	// notified holds the connections notified of the message, verdicts the verdict of each connection that validated it
+	notified map[net.Conn]struct{}
+	verdicts map[net.Conn]bool
+	decided  bool
+}
+
+// newGossipValidationHandler returns a handler created at the given time, invoking the callback once the verdicts on the message
+// are decided by the policy.
+func newGossipValidationHandler(messageID uint16, policy string, callback func(valid bool), now time.Time) *GossipValidationHandler {
+	return &GossipValidationHandler{
+		callback:    callback,
+		messageID:   messageID,
+		timeCreated: now,
+		policy:      policy,
+		notified:    make(map[net.Conn]struct{}),
+		verdicts:    make(map[net.Conn]bool),
+	}
+}
+
+// notify adds a connection notified of the message, unless the message is already decided.
+func (h *GossipValidationHandler) notify(conn net.Conn) {
+	if !h.decided {
+		h.notified[conn] = struct{}{}
+	}
+}
+
+// validate records the verdict of a connection and returns whether it decided the message along with the decision.
+// Verdicts of connections that were not notified of the message, repeated verdicts, and verdicts on decided messages are ignored.
+func (h *GossipValidationHandler) validate(conn net.Conn, valid bool) (bool, bool) {
+	if h.decided {
+		return false, false
+	}
+	if _, ok := h.notified[conn]; !ok {
+		return false, false
+	}
+	if _, ok := h.verdicts[conn]; ok {
+		return false, false
+	}
+	h.verdicts[conn] = valid
+	decided, result := decideValidation(h.policy, len(h.notified), h.verdicts)
+	h.decided = decided
+	return decided, result
+}
+
+// forget removes a disconnected connection from the notified connections, so it is not waited for anymore, and returns
+// whether the remaining verdicts decide the message along with the decision.
+func (h *GossipValidationHandler) forget(conn net.Conn) (bool, bool) {
+	if h.decided {
+		return false, false
+	}
+	if _, ok := h.notified[conn]; !ok {
+		return false, false
+	}
+	delete(h.notified, conn)
+	delete(h.verdicts, conn)
+	if len(h.notified) == 0 {
+		return false, false
+	}
+	decided, result := decideValidation(h.policy, len(h.notified), h.verdicts)
+	h.decided = decided
+	return decided, result
+}
+
+// decideValidation combines the verdicts of the notified connections by the policy and returns whether they decide the
+// message along with the decision.
+func decideValidation(policy string, notified int, verdicts map[net.Conn]bool) (bool, bool) {
+	validVerdicts, invalidVerdicts := 0, 0
+	for _, valid := range verdicts {
+		if valid {
+			validVerdicts++
+		} else {
+			invalidVerdicts++
+		}
+	}
+	switch policy {
+	case config.ValidationPolicyFirstWins:
+		return true, validVerdicts > 0
+	case config.ValidationPolicyQuorum:
+		if 2*validVerdicts > notified {
+			return true, true
+		}
+		// the remaining connections cannot outvote the invalid verdicts anymore
+		if 2*invalidVerdicts >= notified {
+			return true, false
+		}
+		return false, false
+	default:
+		if invalidVerdicts > 0 {
+			return true, false
+		}
+		return validVerdicts == notified, true
+	}
+}
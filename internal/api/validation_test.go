@@ -0,0 +1,60 @@
+package api
+
+import (
+	"gossiphers/internal/config"
+	"net"
+	"testing"
+	"time"
+)
+
+// verdict represents the verdict of one of several notified connections.
+type verdict struct {
+	conn  int
+	valid bool
+}
+
+func TestGossipValidationHandler_validate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		policy   string
+		verdicts []verdict
+		// decidedAt is the index of the verdict deciding the message, -1 if none does
+		decidedAt int
+		valid     bool
+	}{
+		{"first wins decides on the first verdict", config.ValidationPolicyFirstWins, []verdict{{0, false}, {1, true}}, 0, false},
+		{"all decides valid once every connection validated", config.ValidationPolicyAll, []verdict{{0, true}, {1, true}, {2, true}}, 2, true},
+		{"all decides invalid on the first invalid verdict", config.ValidationPolicyAll, []verdict{{0, true}, {1, false}}, 1, false},
+		{"quorum decides valid on a majority", config.ValidationPolicyQuorum, []verdict{{0, true}, {1, false}, {2, true}}, 2, true},
+		{"quorum decides invalid once no majority is reachable", config.ValidationPolicyQuorum, []verdict{{0, false}, {1, true}, {2, false}}, 2, false},
+		{"repeated verdicts count once", config.ValidationPolicyQuorum, []verdict{{0, true}, {0, true}}, -1, false},
+		{"verdicts of connections not notified are ignored", config.ValidationPolicyFirstWins, []verdict{{3, true}}, -1, false},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			conns := make([]net.Conn, 4)
+			for i := range conns {
+				serverConn, clientConn := net.Pipe()
+				defer serverConn.Close()
+				defer clientConn.Close()
+				conns[i] = serverConn
+			}
+			handler := newGossipValidationHandler(1, test.policy, func(bool) {}, time.Now())
+			for _, conn := range conns[:3] {
+				handler.notify(conn)
+			}
+			for i, v := range test.verdicts {
+				decided, valid := handler.validate(conns[v.conn], v.valid)
+				if decided != (i == test.decidedAt) {
+					t.Fatalf("expected verdict %d to decide the message: %t, received %t", i, i == test.decidedAt, decided)
+				}
+				if decided && valid != test.valid {
+					t.Errorf("expected the message to be decided valid: %t, received %t", test.valid, valid)
+				}
+			}
+		})
+	}
+}
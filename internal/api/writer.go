@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/binary"
+	"math"
+	"time"
 )
 
 // WritablePacket represents a packet struct that can be converted to a slice of bytes.
@@ -9,6 +11,45 @@ type WritablePacket interface {
 	ToBytes() []byte
 }
 
+// ToBytes converts the GossipAnnounce struct to a slice of bytes.
+func (p *GossipAnnounce) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	bytes = append(bytes, p.TTL, p.Flags)
+	bytes = binary.BigEndian.AppendUint16(bytes, p.DataType)
+	bytes = append(bytes, p.Data...)
+
+	return bytes
+}
+
+// ToBytes converts the GossipNotify struct to a slice of bytes.
+func (p *GossipNotify) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Flags)
+	bytes = binary.BigEndian.AppendUint16(bytes, p.DataType)
+
+	return bytes
+}
+
+// ToBytes converts the GossipValidation struct to a slice of bytes.
+func (p *GossipValidation) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	bytes = binary.BigEndian.AppendUint16(bytes, p.MessageID)
+	// the reserved bits are followed by the IsValid flag
+	var flags uint16
+	if p.IsValid {
+		flags = 1
+	}
+	bytes = binary.BigEndian.AppendUint16(bytes, flags)
+
+	return bytes
+}
+
 // ToBytes converts the GossipNotification struct to a slice of bytes.
 func (p *GossipNotification) ToBytes() []byte {
 	var bytes []byte
@@ -20,3 +61,181 @@ func (p *GossipNotification) ToBytes() []byte {
 
 	return bytes
 }
+
+// ToBytes converts the GossipStatusQuery struct to a slice of bytes.
+func (p *GossipStatusQuery) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	// reserved
+	bytes = binary.BigEndian.AppendUint16(bytes, 0)
+	bytes = binary.BigEndian.AppendUint16(bytes, p.DataType)
+	bytes = append(bytes, p.DataHash...)
+
+	return bytes
+}
+
+// ToBytes converts the GossipLatencyQuery struct to a slice of bytes.
+func (p *GossipLatencyQuery) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+
+	return bytes
+}
+
+// ToBytes converts the GossipStatus struct to a slice of bytes.
+func (p *GossipStatus) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	bytes = binary.BigEndian.AppendUint16(bytes, p.DataType)
+	// the reserved bits are followed by the IsKnown flag
+	var flags uint16
+	if p.IsKnown {
+		flags = 1
+	}
+	bytes = binary.BigEndian.AppendUint16(bytes, flags)
+	bytes = binary.BigEndian.AppendUint32(bytes, p.Peers)
+	bytes = append(bytes, p.DataHash...)
+
+	return bytes
+}
+
+// ToBytes converts the GossipError struct to a slice of bytes.
+func (p *GossipError) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.RequestType))
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Code))
+	bytes = append(bytes, p.Reason...)
+
+	return bytes
+}
+
+// ToBytes converts the GossipLatency struct to a slice of bytes.
+func (p *GossipLatency) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	for _, peer := range p.Peers {
+		bytes = append(bytes, peer.Identity...)
+		bytes = binary.BigEndian.AppendUint16(bytes, peer.PingSamples)
+		bytes = binary.BigEndian.AppendUint16(bytes, peer.PullSamples)
+		bytes = binary.BigEndian.AppendUint32(bytes, toMicroseconds(peer.PingMedian))
+		bytes = binary.BigEndian.AppendUint32(bytes, toMicroseconds(peer.PingP90))
+		bytes = binary.BigEndian.AppendUint32(bytes, toMicroseconds(peer.PullMedian))
+		bytes = binary.BigEndian.AppendUint32(bytes, toMicroseconds(peer.PullP90))
+	}
+
+	return bytes
+}
+
+// ToBytes converts the GossipPeerStatsQuery struct to a slice of bytes.
+func (p *GossipPeerStatsQuery) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+
+	return bytes
+}
+
+// ToBytes converts the GossipPeerStats struct to a slice of bytes.
+func (p *GossipPeerStats) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	for _, peer := range p.Peers {
+		bytes = append(bytes, peer.Identity...)
+		bytes = binary.BigEndian.AppendUint64(bytes, toUnixMilli(peer.LastActivity))
+		bytes = binary.BigEndian.AppendUint64(bytes, peer.BytesReceived)
+		bytes = binary.BigEndian.AppendUint64(bytes, peer.BytesSent)
+		bytes = binary.BigEndian.AppendUint32(bytes, peer.SendFailures)
+		bytes = binary.BigEndian.AppendUint32(bytes, peer.InvalidPackets)
+		bytes = binary.BigEndian.AppendUint16(bytes, uint16(len(peer.Packets)))
+		for _, counts := range peer.Packets {
+			bytes = binary.BigEndian.AppendUint16(bytes, counts.PacketType)
+			bytes = binary.BigEndian.AppendUint32(bytes, counts.Received)
+			bytes = binary.BigEndian.AppendUint32(bytes, counts.Sent)
+		}
+	}
+
+	return bytes
+}
+
+// ToBytes converts the GossipAnnounceBatch struct to a slice of bytes.
+func (p *GossipAnnounceBatch) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	for _, message := range p.Messages {
+		bytes = append(bytes, message.TTL, message.Flags)
+		bytes = binary.BigEndian.AppendUint16(bytes, message.DataType)
+		bytes = binary.BigEndian.AppendUint16(bytes, uint16(len(message.Data)))
+		bytes = append(bytes, message.Data...)
+	}
+
+	return bytes
+}
+
+// ToBytes converts the GossipAnnounceResult struct to a slice of bytes.
+func (p *GossipAnnounceResult) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	for _, code := range p.Codes {
+		bytes = binary.BigEndian.AppendUint16(bytes, uint16(code))
+	}
+
+	return bytes
+}
+
+// ToBytes converts the GossipQuarantineQuery struct to a slice of bytes.
+func (p *GossipQuarantineQuery) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+
+	return bytes
+}
+
+// ToBytes converts the GossipQuarantine struct to a slice of bytes.
+func (p *GossipQuarantine) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
+	for _, peer := range p.Peers {
+		bytes = append(bytes, uint8(len(peer.Address)))
+		bytes = append(bytes, peer.Address...)
+		bytes = append(bytes, peer.Identity...)
+		bytes = binary.BigEndian.AppendUint32(bytes, toMilliseconds(peer.Remaining))
+		bytes = binary.BigEndian.AppendUint16(bytes, peer.Failures)
+	}
+
+	return bytes
+}
+
+// toUnixMilli converts a time to unix milliseconds, 0 for the zero time.
+func toUnixMilli(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixMilli())
+}
+
+// toMicroseconds converts a duration to microseconds, saturating at the maximum value of an uint32.
+func toMicroseconds(d time.Duration) uint32 {
+	if d.Microseconds() > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(d.Microseconds())
+}
+
+// toMilliseconds converts a duration to milliseconds, saturating at the maximum value of an uint32.
+func toMilliseconds(d time.Duration) uint32 {
+	if d.Milliseconds() > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(d.Milliseconds())
+}
@@ -2,7 +2,10 @@ package api
 
 import (
 	"bytes"
+	"math"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGossipNotification_ToBytes(t *testing.T) {
@@ -22,3 +25,125 @@ func TestGossipNotification_ToBytes(t *testing.T) {
 		}
 	})
 }
+
+func TestGossipStatus_ToBytes(t *testing.T) {
+	t.Run("check correctness of bytes", func(t *testing.T) {
+		dataHash := bytes.Repeat([]byte{0xAB}, DataHashSize)
+		packet := NewGossipStatus(321, dataHash, true, 7)
+		packetBytes := packet.ToBytes()
+		expected := append([]byte{0x00, 0x2C, 0x01, 0xF9, 0x01, 0x41, 0x00, 0x01, 0x00, 0x00, 0x00, 0x07}, dataHash...)
+		if !bytes.Equal(packetBytes, expected) {
+			t.Error("Generated packet bytes not correct", packetBytes)
+		}
+	})
+}
+
+func TestGossipError_ToBytes(t *testing.T) {
+	t.Run("check correctness of bytes", func(t *testing.T) {
+		packet, err := NewGossipError(MessageTypeGossipAnnounce, ErrorCodeDataTooLarge, "too large")
+		if err != nil {
+			t.Fatal(err)
+		}
+		packetBytes := packet.ToBytes()
+		expected := append([]byte{0x00, 0x11, 0x01, 0xFA, 0x01, 0xF4, 0x00, 0x01}, []byte("too large")...)
+		if !bytes.Equal(packetBytes, expected) {
+			t.Error("Generated packet bytes not correct", packetBytes)
+		}
+	})
+}
+
+func TestGossipLatency_ToBytes(t *testing.T) {
+	t.Run("check correctness of bytes", func(t *testing.T) {
+		identity := bytes.Repeat([]byte{0xCD}, IdentitySize)
+		packet, err := NewGossipLatency([]PeerLatency{{
+			Identity:    identity,
+			PingSamples: 3,
+			PullSamples: 1,
+			PingMedian:  1500 * time.Microsecond,
+			PingP90:     2 * time.Millisecond,
+			PullMedian:  10 * time.Millisecond,
+			PullP90:     time.Duration(math.MaxInt64),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		packetBytes := packet.ToBytes()
+		expected := append([]byte{0x00, 0x38, 0x01, 0xFC}, identity...)
+		expected = append(expected, 0x00, 0x03, 0x00, 0x01, 0x00, 0x00, 0x05, 0xDC, 0x00, 0x00, 0x07, 0xD0, 0x00, 0x00, 0x27, 0x10, 0xFF, 0xFF, 0xFF, 0xFF)
+		if !bytes.Equal(packetBytes, expected) {
+			t.Error("Generated packet bytes not correct", packetBytes)
+		}
+	})
+	t.Run("rejects invalid identities", func(t *testing.T) {
+		_, err := NewGossipLatency([]PeerLatency{{Identity: []byte{0x01}}})
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+}
+
+func TestGossipPeerStats_ToBytes(t *testing.T) {
+	t.Run("check correctness of bytes", func(t *testing.T) {
+		identity := bytes.Repeat([]byte{0xCD}, IdentitySize)
+		packet, err := NewGossipPeerStats([]PeerStats{{
+			Identity:       identity,
+			LastActivity:   time.UnixMilli(0x0102),
+			BytesReceived:  0x0304,
+			BytesSent:      0x0506,
+			SendFailures:   7,
+			InvalidPackets: 8,
+			Packets:        []PacketCounts{{PacketType: 0x0030, Received: 9, Sent: 10}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		packetBytes := packet.ToBytes()
+		expected := append([]byte{0x00, 0x50, 0x01, 0xFE}, identity...)
+		expected = append(expected, 0, 0, 0, 0, 0, 0, 0x01, 0x02, 0, 0, 0, 0, 0, 0, 0x03, 0x04, 0, 0, 0, 0, 0, 0, 0x05, 0x06,
+			0, 0, 0, 7, 0, 0, 0, 8, 0, 1, 0x00, 0x30, 0, 0, 0, 9, 0, 0, 0, 10)
+		if !bytes.Equal(packetBytes, expected) {
+			t.Error("Generated packet bytes not correct", packetBytes)
+		}
+	})
+	t.Run("rejects invalid identities", func(t *testing.T) {
+		_, err := NewGossipPeerStats([]PeerStats{{Identity: []byte{0x01}}})
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+	t.Run("fits the leading peers into a single packet", func(t *testing.T) {
+		peers := make([]PeerStats, 1000)
+		for i := range peers {
+			peers[i] = PeerStats{Identity: bytes.Repeat([]byte{0x01}, IdentitySize), Packets: make([]PacketCounts, 4)}
+		}
+		fitting := FitPeerStats(peers)
+		if _, err := NewGossipPeerStats(fitting); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewGossipPeerStats(peers[:len(fitting)+1]); err == nil {
+			t.Error("expected one more peer to exceed the maximum packet size")
+		}
+	})
+}
+
+func TestGossipQuarantine_ToBytes(t *testing.T) {
+	t.Run("check correctness of bytes", func(t *testing.T) {
+		identity := bytes.Repeat([]byte{0xCD}, IdentitySize)
+		packet, err := NewGossipQuarantine([]QuarantinedPeer{{Address: "a:1", Identity: identity, Remaining: 0x0102 * time.Millisecond, Failures: 3}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		packetBytes := packet.ToBytes()
+		expected := append([]byte{0x00, 0x2E, 0x02, 0x02, 3, 'a', ':', '1'}, identity...)
+		expected = append(expected, 0, 0, 0x01, 0x02, 0, 3)
+		if !bytes.Equal(packetBytes, expected) {
+			t.Error("Generated packet bytes not correct", packetBytes)
+		}
+	})
+	t.Run("rejects addresses exceeding 255 bytes", func(t *testing.T) {
+		_, err := NewGossipQuarantine([]QuarantinedPeer{{Address: strings.Repeat("a", 256), Identity: bytes.Repeat([]byte{0x01}, IdentitySize)}})
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+}
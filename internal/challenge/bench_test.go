@@ -0,0 +1,56 @@
+package challenge
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func BenchmarkSolveChallenge(b *testing.B) {
+	for _, difficulty := range []int{4, 8, 12, 16, 20} {
+		difficulty := difficulty
+		b.Run(fmt.Sprintf("difficulty=%d", difficulty), func(b *testing.B) {
+			// the number of hashes varies widely per challenge, so every iteration solves a fresh one
+			challenges := make([][]byte, b.N)
+			for i := range challenges {
+				challenges[i] = make([]byte, ChallengeSize)
+				_, err := rand.Read(challenges[i])
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := SolveChallenge(challenges[i], difficulty, context.Background())
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkChallenger_IsSolvedCorrectly(b *testing.B) {
+	ch, err := NewChallenger(time.Hour, 3)
+	if err != nil {
+		b.Fatal(err)
+	}
+	identity := make([]byte, 32)
+	challenge, err := ch.NewChallenge(identity)
+	if err != nil {
+		b.Fatal(err)
+	}
+	nonce, err := SolveChallenge(challenge, 8, context.Background())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		solved, err := ch.IsSolvedCorrectly(challenge, nonce, identity, 8)
+		if err != nil || !solved {
+			b.Fatalf("expected the challenge to be solved, received %v and %v", solved, err)
+		}
+	}
+}
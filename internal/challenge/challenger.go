@@ -5,6 +5,10 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"errors"
+	"gossiphers/internal/clock"
+	"gossiphers/internal/logging"
+	"io"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -17,51 +21,92 @@ var (
 	ErrInvalidDifficulty = errors.New("invalid difficulty level")
 )
 
+// logger returns the logger of the challenge subsystem.
+func logger() *zap.Logger {
+	return logging.L(logging.Challenge)
+}
+
 // The Challenger remains a list of 64B keys that are regularly rotated in the given interval.
 // When generating a challenge the newest key in the rotation is used, for verification all keys in the rotation are valid.
 type Challenger struct {
-	keyRotation [][]byte
-	r           int
+	keyRotation      [][]byte
+	mutexKeyRotation sync.RWMutex
+	r                int
+	clock            clock.Clock
+	// random is the source of randomness of the keys
+	random io.Reader
+}
+
+// Option represents a functional option for the Challenger's constructor.
+type Option func(*Challenger)
+
+// WithClock sets the clock driving the key rotation instead of the wall clock.
+func WithClock(c clock.Clock) Option {
+	return func(ch *Challenger) {
+		ch.clock = c
+	}
+}
+
+// WithRandom sets the source of randomness the keys are generated with instead of crypto/rand.
+func WithRandom(random io.Reader) Option {
+	return func(ch *Challenger) {
+		ch.random = random
+	}
 }
 
 // NewChallenger Generates a Challenger that accepts solved challenges generated in the timeframe [now-iv*(r+1), now-iv*r]
 // iv describes the interval in which a key rotation occurs, r is the number of keys that stays valid
 // A reasonable default could be iv=15s and r=4
-func NewChallenger(iv time.Duration, r int) (*Challenger, error) {
+func NewChallenger(iv time.Duration, r int, options ...Option) (*Challenger, error) {
+	ch := Challenger{
+		r:      r,
+		clock:  clock.Real(),
+		random: rand.Reader,
+	}
+	for _, option := range options {
+		option(&ch)
+	}
 	firstKey := make([]byte, 64)
-	_, err := rand.Read(firstKey)
+	_, err := io.ReadFull(ch.random, firstKey)
 	if err != nil {
 		return nil, err
 	}
-	ch := Challenger{
-		keyRotation: [][]byte{firstKey},
-		r:           r,
-	}
+	ch.keyRotation = [][]byte{firstKey}
 	ch.startTicker(iv)
 	return &ch, nil
 }
 
 // This ticker takes care of the actual key rotation in regular intervals iv
 func (ch *Challenger) startTicker(iv time.Duration) {
+	ticker := ch.clock.NewTicker(iv)
 	go func() {
-		for range time.NewTicker(iv).C {
-			newKey := make([]byte, 64)
-			_, err := rand.Read(newKey)
-			if err != nil {
-				zap.L().Panic("Could not generate new key for Challenger", zap.Error(err))
-			}
-			if len(ch.keyRotation) < ch.r {
-				ch.keyRotation = append(ch.keyRotation, newKey)
-			} else {
-				ch.keyRotation = append(ch.keyRotation[1:], newKey)
-			}
+		for range ticker.C() {
+			ch.rotateKey()
 		}
 	}()
 }
 
+// rotateKey adds a new key to the rotation, dropping the oldest key once r keys are in rotation.
+func (ch *Challenger) rotateKey() {
+	newKey := make([]byte, 64)
+	_, err := io.ReadFull(ch.random, newKey)
+	if err != nil {
+		logger().Panic("Could not generate new key for Challenger", zap.Error(err))
+	}
+	ch.mutexKeyRotation.Lock()
+	defer ch.mutexKeyRotation.Unlock()
+	if len(ch.keyRotation) < ch.r {
+		ch.keyRotation = append(ch.keyRotation, newKey)
+	} else {
+		ch.keyRotation = append(ch.keyRotation[1:], newKey)
+	}
+}
+
 // NewChallenge returns the 32B hash generated by concatenating the current key rotation with the client address.
 // These bytes can later be generated again deterministically to check whether a given challenge was generated with one of the keys in rotation
 func (ch *Challenger) NewChallenge(identity []byte) ([]byte, error) {
+	ch.mutexKeyRotation.RLock()
+	defer ch.mutexKeyRotation.RUnlock()
 	hashFunc := sha256.New()
 	hashFunc.Write(append(ch.keyRotation[len(ch.keyRotation)-1], identity...))
 
@@ -76,7 +121,7 @@ func (ch *Challenger) IsSolvedCorrectly(challenge []byte, nonce []byte, identity
 	checkHash := hashFun.Sum(nil)
 
 	if difficulty >= len(checkHash)*8 || difficulty < 0 {
-		zap.L().Error("Difficulty is not valid for utilized hash function", zap.Int("difficulty", difficulty))
+		logger().Error("Difficulty is not valid for utilized hash function", zap.Int("difficulty", difficulty))
 		return false, ErrInvalidDifficulty
 	}
 
@@ -84,6 +129,8 @@ func (ch *Challenger) IsSolvedCorrectly(challenge []byte, nonce []byte, identity
 		return false, nil
 	}
 
+	ch.mutexKeyRotation.RLock()
+	defer ch.mutexKeyRotation.RUnlock()
 	challengeValid := false
 	for i := len(ch.keyRotation) - 1; i >= 0; i-- {
 		hashFun.Reset()
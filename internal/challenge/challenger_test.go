@@ -2,7 +2,10 @@ package challenge
 
 import (
 	"bytes"
+	"gossiphers/internal/clock"
+	"math/rand"
 	"testing"
+	"time"
 )
 
 var testIdentity = []byte{0x00, 0x01, 0x02, 0x03}
@@ -89,3 +92,66 @@ func TestChallenger_IsSolvedCorrectly(t *testing.T) {
 		}
 	})
 }
+
+// waitForKeys waits until the newest key in rotation of the challenger differs from the given one and returns it.
+func waitForKeys(t *testing.T, ch *Challenger, previous []byte) []byte {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		ch.mutexKeyRotation.RLock()
+		newest := ch.keyRotation[len(ch.keyRotation)-1]
+		ch.mutexKeyRotation.RUnlock()
+		if !bytes.Equal(newest, previous) {
+			return newest
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("key was not rotated")
+	return nil
+}
+
+func TestChallenger_keyRotation(t *testing.T) {
+	t.Parallel()
+	t.Run("challenges are accepted until r keys were rotated in after them", func(t *testing.T) {
+		fakeClock := clock.NewFake(time.Unix(0, 0))
+		ch, err := NewChallenger(time.Second, 2, WithClock(fakeClock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		challenge, err := ch.NewChallenge(testIdentity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := ch.keyRotation[0]
+		for rotation, expected := range []bool{true, false} {
+			fakeClock.Advance(time.Second)
+			key = waitForKeys(t, ch, key)
+			correct, err := ch.IsSolvedCorrectly(challenge, make([]byte, NonceSize), testIdentity, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if correct != expected {
+				t.Errorf("expected the challenge to be accepted %v after %d rotations", expected, rotation+1)
+			}
+		}
+	})
+}
+
+func TestChallenger_WithRandom(t *testing.T) {
+	t.Parallel()
+	t.Run("keys are reproducible with a seeded source of randomness", func(t *testing.T) {
+		var challenges [2][]byte
+		for i := range challenges {
+			ch, err := NewChallenger(time.Hour, 2, WithRandom(rand.New(rand.NewSource(11))))
+			if err != nil {
+				t.Fatal(err)
+			}
+			challenges[i], err = ch.NewChallenge(testIdentity)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(challenges[0], challenges[1]) {
+			t.Error("expected the same challenge from the same seed")
+		}
+	})
+}
@@ -0,0 +1,198 @@
+// Package clock abstracts the wall clock, so timeouts, rounds, and key rotations can be driven by a fake clock in tests.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock represents a source of the current time and of timers, which allows replacing the wall clock in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel receiving the current time once the duration elapsed.
+	After(d time.Duration) <-chan time.Time
+	// NewTicker returns a Ticker sending the current time on its channel after each period.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker represents a running ticker of a Clock.
+type Ticker interface {
+	// C returns the channel the ticks are delivered on.
+	C() <-chan time.Time
+	// Stop turns off the ticker, no more ticks are sent afterwards.
+	Stop()
+}
+
+// realClock implements Clock with the wall clock of the time package.
+type realClock struct{}
+
+// Real returns the wall clock.
+func Real() Clock {
+	return realClock{}
+}
+
+// Now returns the current wall clock time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After waits for the duration to elapse and then sends the current time on the returned channel.
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTicker returns a ticker of the time package.
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// realTicker implements Ticker with a ticker of the time package.
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+// C returns the channel the ticks are delivered on.
+func (t realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+// Stop turns off the ticker.
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// Or returns the given clock, or the wall clock if it is nil.
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real()
+	}
+	return c
+}
+
+// Fake is a Clock whose time only moves when advanced explicitly, so tests run deterministically without sleeping.
+// Timers and tickers fire synchronously within Advance once their time is reached.
+type Fake struct {
+	now     time.Time
+	waiters []*fakeWaiter
+	mutex   sync.Mutex
+	// changed is signalled whenever a waiter is added, used by BlockUntil
+	changed *sync.Cond
+}
+
+// fakeWaiter represents a pending timer or ticker of a Fake clock.
+type fakeWaiter struct {
+	at time.Time
+	// period is the interval of a ticker, 0 for a timer firing once
+	period time.Duration
+	c      chan time.Time
+}
+
+// NewFake returns a Fake clock starting at the given time.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.changed = sync.NewCond(&f.mutex)
+	return f
+}
+
+// Now returns the current time of the fake clock.
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+// After returns a channel receiving the time of the fake clock once it was advanced by at least the duration.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.addWaiter(d, 0).c
+}
+
+// NewTicker returns a ticker firing whenever the fake clock was advanced by another period.
+// Like a ticker of the time package, ticks are dropped while the receiver has not consumed the previous one.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for clock.Fake.NewTicker")
+	}
+	return &fakeTicker{clock: f, waiter: f.addWaiter(d, d)}
+}
+
+// addWaiter registers a timer or ticker firing after the duration.
+func (f *Fake) addWaiter(d time.Duration, period time.Duration) *fakeWaiter {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	w := &fakeWaiter{at: f.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	if d <= 0 && period == 0 {
+		w.c <- f.now
+		return w
+	}
+	f.waiters = append(f.waiters, w)
+	f.changed.Broadcast()
+	return w
+}
+
+// removeWaiter unregisters a timer or ticker.
+func (f *Fake) removeWaiter(w *fakeWaiter) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for i, waiter := range f.waiters {
+		if waiter == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Advance moves the fake clock forward by the duration and fires all timers and tickers that are due, in the order of their time.
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	end := f.now.Add(d)
+	for {
+		sort.SliceStable(f.waiters, func(i, j int) bool {
+			return f.waiters[i].at.Before(f.waiters[j].at)
+		})
+		if len(f.waiters) == 0 || f.waiters[0].at.After(end) {
+			break
+		}
+		w := f.waiters[0]
+		if w.at.After(f.now) {
+			f.now = w.at
+		}
+		select {
+		case w.c <- f.now:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.waiters = f.waiters[1:]
+		}
+	}
+	f.now = end
+}
+
+// BlockUntil blocks until at least n timers and tickers are pending, so a test can advance the clock once the code under test is waiting on it.
+func (f *Fake) BlockUntil(n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for len(f.waiters) < n {
+		f.changed.Wait()
+	}
+}
+
+// fakeTicker implements Ticker for a Fake clock.
+type fakeTicker struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+// C returns the channel the ticks are delivered on.
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.waiter.c
+}
+
+// Stop turns off the ticker.
+func (t *fakeTicker) Stop() {
+	t.clock.removeWaiter(t.waiter)
+}
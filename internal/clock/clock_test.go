@@ -0,0 +1,77 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// fired checks whether a value is ready on the channel without blocking.
+func fired(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case now := <-c:
+		return now, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFake(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1000, 0)
+	t.Run("timers fire once the clock was advanced past them", func(t *testing.T) {
+		t.Parallel()
+		f := NewFake(start)
+		c := f.After(2 * time.Second)
+		f.Advance(time.Second)
+		if _, ok := fired(c); ok {
+			t.Fatal("timer fired early")
+		}
+		f.Advance(3 * time.Second)
+		now, ok := fired(c)
+		if !ok {
+			t.Fatal("timer did not fire")
+		}
+		if !now.Equal(start.Add(2 * time.Second)) {
+			t.Errorf("expected the timer to fire at its due time, received %s", now)
+		}
+		if !f.Now().Equal(start.Add(4 * time.Second)) {
+			t.Errorf("expected the clock to be advanced by 4s, received %s", f.Now())
+		}
+	})
+	t.Run("timers without duration fire immediately", func(t *testing.T) {
+		t.Parallel()
+		f := NewFake(start)
+		if _, ok := fired(f.After(0)); !ok {
+			t.Error("timer did not fire")
+		}
+	})
+	t.Run("tickers fire every period until stopped", func(t *testing.T) {
+		t.Parallel()
+		f := NewFake(start)
+		ticker := f.NewTicker(time.Second)
+		for i := 1; i <= 3; i++ {
+			f.Advance(time.Second)
+			now, ok := fired(ticker.C())
+			if !ok || !now.Equal(start.Add(time.Duration(i)*time.Second)) {
+				t.Fatalf("expected tick %d at %s, received %s (%v)", i, start.Add(time.Duration(i)*time.Second), now, ok)
+			}
+		}
+		ticker.Stop()
+		f.Advance(time.Second)
+		if _, ok := fired(ticker.C()); ok {
+			t.Error("stopped ticker fired")
+		}
+	})
+	t.Run("blocks until the code under test waits on the clock", func(t *testing.T) {
+		t.Parallel()
+		f := NewFake(start)
+		done := make(chan struct{})
+		go func() {
+			<-f.After(time.Minute)
+			close(done)
+		}()
+		f.BlockUntil(1)
+		f.Advance(time.Minute)
+		<-done
+	})
+}
@@ -0,0 +1,22 @@
+package config
+
+const (
+	// AdversaryPushFlood pushes the own identity to every known peer each round, ignoring alpha.
+	AdversaryPushFlood = "push_flood"
+	// AdversaryPullPoison answers pull requests with fabricated identities of unreachable peers instead of the main view.
+	AdversaryPullPoison = "pull_poison"
+	// AdversaryMessageSpam spreads a burst of random messages each round.
+	AdversaryMessageSpam = "message_spam"
+	// AdversarySilent never answers pings, pull requests, push requests, or digests, but keeps pushing and pulling itself.
+	AdversarySilent = "silent"
+)
+
+// IsValidAdversary checks whether behavior is one of the supported adversarial behaviors, an empty behavior is valid and honest.
+func IsValidAdversary(behavior string) bool {
+	switch behavior {
+	case "", AdversaryPushFlood, AdversaryPullPoison, AdversaryMessageSpam, AdversarySilent:
+		return true
+	default:
+		return false
+	}
+}
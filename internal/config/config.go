@@ -2,73 +2,319 @@ package config
 
 import (
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"gossiphers/internal/clock"
+	"gossiphers/internal/transport"
+	"io"
 	"math"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"go.uber.org/zap"
 	"gopkg.in/ini.v1"
 )
 
 // RSAPrivateKey represents the format the PEM expects within the preamble.
+// HybridPrivateKey represents the type of the PEM block holding the hybrid private key, which follows the hostkey within its file.
 const (
 	RSAPrivateKey            = "RSA PRIVATE KEY"
+	HybridPrivateKey         = "GOSSIP HYBRID KEM PRIVATE KEY"
 	float64EqualityThreshold = 1e-3
 )
 
+// MaxMessageDataSizeLimit represents the largest data size in bytes fitting into a single gossip message packet.
+// 65535 bytes at most per packet, minus 44 bytes for the packet header, 4 bytes for the message fields, and 512 bytes for the signature.
+const MaxMessageDataSizeLimit = 65535 - 44 - 4 - 512
+
+var (
+	ErrMissingHostkey     = errors.New("no hostkey path within the root section of the configuration, set hostkey = <path to PEM file>")
+	ErrPrivateKeyNotFound = errors.New("no RSA private key found")
+)
+
 var defaultConfig = GossipConfig{
 	ViewSize:    30,
 	SamplerSize: 30,
-	Alpha:       .45,
-	Beta:        .45,
-	Gamma:       .1,
+	// Pull responses of all pulled nodes within a single round are collected, so the bound on the push and pull views is considerably larger than the view size.
+	PushPullViewMaxSize: 1000,
+	Alpha:               .45,
+	Beta:                .45,
+	Gamma:               .1,
 	/* BootstrapNodesStr doesn't have a default value */
+	RoundIntervalMs: 1000,
 	// A value of 8 suggests ~8 seconds between health checks.
-	RoundsBetweenPings:  8,
-	ApiAddress:          "localhost:7001",
-	HostkeysPath:        "./hostkeys/",
-	GossipAddress:       "localhost:7002",
-	ChallengeDifficulty: 19,
-	ChallengeMaxSolveMs: 300,
-	weightPull:          45,
-	weightPush:          45,
-	weightHistory:       10,
+	RoundsBetweenPings:             8,
+	ApiAddress:                     "localhost:7001",
+	ApiNotificationHistorySize:     10000,
+	ApiNotificationReplaySize:      64,
+	ApiNotificationReplayMs:        30000,
+	ApiValidationPolicy:            ValidationPolicyFirstWins,
+	Participation:                  ParticipationFull,
+	LatencyWindowSize:              64,
+	ReadinessPeerWindowMs:          30000,
+	HostkeysPath:                   "./hostkeys/",
+	GossipAddress:                  "localhost:7002",
+	ChallengeDifficulty:            19,
+	ChallengeMaxSolveMs:            300,
+	ChallengeMaxAcceptedDifficulty: 24,
+	// A challenge key is rotated every 15 seconds, solutions are accepted for the 4 most recent keys.
+	ChallengeRotationIntervalMs: 15000,
+	ChallengeRotationKeys:       4,
+	PingTimeoutMs:               500,
+	PushChallengeTimeoutMs:      500,
+	PullTimeoutMs:               500,
+	PullResponseStrategy:        PullResponseAll,
+	ProtocolVersion:             3,
+	KeyWrap:                     KeyWrapRSAOAEP,
+	SamplerStaleMs:              60000,
+	PeerFailureThreshold:        3,
+	SamplerHash:                 "sha256",
+	DNSRefreshIntervalMs:        60000,
+	MessageLocalTTLFloor:        -24,
+	MessageFloodThreshold:       50,
+	MessageFloodAction:          FloodActionDrop,
+	MaxMessageDataSize:          MaxMessageDataSizeLimit,
+	InfiniteTTLMaxRounds:        255,
+	InfiniteTTLMaxStorePercent:  100,
+	MessageStoreMaxMessages:     5000,
+	MessageStoreMaxBytes:        32 << 20,
+	MessageBatchMaxSize:         8192,
+	SendQueueSize:               1024,
+	SendFailureThreshold:        5,
+	SendCircuitCooldownMs:       30000,
+	QuarantineThreshold:         3,
+	QuarantineMs:                60000,
+	PeerDBExpiryMs:              7 * 24 * 60 * 60 * 1000,
+	ClockSkewWarnMs:             4000,
+	SeenFilterCapacity:          100000,
+	AntiEntropyIntervalRounds:   10,
+	HighPriorityLaneBudget:      32,
+	LogLevel:                    "info",
+	LogEncoding:                 "json",
+	LogFileMaxSizeMB:            100,
+	LogFileMaxBackups:           3,
+	weightPull:                  45,
+	weightPush:                  45,
+	weightHistory:               10,
 }
 
 // GossipConfig represents all of the values needed for the functioning of the gossip protocol.
 type GossipConfig struct {
 	ViewSize    int
 	SamplerSize int
-	Alpha       float64
-	Beta        float64
-	Gamma       float64
-	ApiAddress  string
+	// PushPullViewMaxSize represents the maximum number of nodes collected within the push and pull views during a round, further nodes replace random ones.
+	PushPullViewMaxSize int
+	Alpha               float64
+	Beta                float64
+	Gamma               float64
+	ApiAddress          string
+	// ApiNotificationHistorySize represents the number of most recently notified messages remembered per data type to never notify the subscribers of a data type of the same message twice, even across reconnects. A value of 0 disables the suppression of duplicate notifications.
+	ApiNotificationHistorySize int
+	// ApiNotificationReplaySize represents the number of most recent notifications buffered per data type for clients subscribing with the replay flag. A value of 0 disables the replay.
+	ApiNotificationReplaySize int
+	// ApiNotificationReplayMs represents the time in milliseconds a notification is buffered for clients subscribing with the replay flag.
+	ApiNotificationReplayMs int
+	// ApiValidationPolicy represents how the verdicts of several API clients notified of the same message are combined, one of first_wins, all, and quorum. Messages validated as invalid are not spread further.
+	ApiValidationPolicy string
+	// ApiTTLPolicies maps data types to the hop TTLs enforced on the messages announced by local API clients, messages of data types without policy keep their TTL.
+	ApiTTLPolicies map[uint16]TTLPolicy
+	// DebugAddress represents an optional TCP address of an HTTP listener exposing net/http/pprof profiles and expvar counters. Empty disables the listener.
+	DebugAddress string
+	// StateDumpFile represents an optional file the runtime state of the node is dumped to as JSON upon receiving SIGUSR1. Empty disables state dumps.
+	StateDumpFile string
+	// HealthAddress represents an optional TCP address of an HTTP listener serving the /healthz and /readyz probes. Empty disables the listener.
+	HealthAddress string
+	// LatencyWindowSize represents the number of most recent round trip times of pings and pull requests kept per peer.
+	LatencyWindowSize int
+	// ReadinessPeerWindowMs represents the time in milliseconds within which a valid packet must have been received from any peer for the node to be ready.
+	ReadinessPeerWindowMs int
 	// BootstrapNodesStr is a list of node components in the following form --> nodes = <addr1>,<id1>|<addr2>,<id2>|...|<addrn>,<idn>|
 	BootstrapNodesStr string
+	// Participation represents how the node takes part in the gossip protocol, either full or outbound_only. Outbound-only nodes pull views and receive messages, but never answer pull requests, issue push challenges, or push their identity. They announce their messages to the peers they pull from instead.
+	Participation string
+	// PinnedNodesStr is a list of nodes in the same form as BootstrapNodesStr that are always kept in the main view and always offered in pull responses.
+	PinnedNodesStr string
+	// BootstrapNodesFile is an optional path to a file containing one <id>,<addr> node per line, used in addition to BootstrapNodesStr.
+	BootstrapNodesFile string
+	// BootstrapNodesFileReloadRounds represents the number of rounds in between checking the bootstrap nodes file for changes. A value of 0 disables reloading.
+	BootstrapNodesFileReloadRounds int
+	// RoundIntervalMs represents the time in milliseconds a round waits for the responses to its push and pull requests before computing the next view.
+	RoundIntervalMs int
 	// RoundsBetweenPings represents the number of rounds in between sending out health checks to peers existing within all of the samplers to see whether they are still alive.
 	RoundsBetweenPings int
 	// HostkeysPath represents the path to the folder in which all of the hostkeys exist. (i.e. Identity (file name) --> Public Key (file content))
 	HostkeysPath string
+	// PeerPublicKeys represents public keys of peers that are known in addition to the ones within HostkeysPath.
+	PeerPublicKeys []*rsa.PublicKey
+	// PeerHybridPublicKeys maps hex-encoded identities of peers to their encoded hybrid public keys, which are known in addition to the ones within HostkeysPath.
+	PeerHybridPublicKeys map[string][]byte
 	// PrivateKey represents the private key of the node.
-	PrivateKey          *rsa.PrivateKey
+	PrivateKey *rsa.PrivateKey
+	// PrivateKeyPath represents the path to the PEM file the private key was read from.
+	PrivateKeyPath string
+	// HybridPrivateKey represents the encoded hybrid private key of the node read from the hostkey file, nil if the file holds none.
+	// It is only required by the hybrid key wrap.
+	HybridPrivateKey []byte
+	// ExpectedIdentity represents the optional hex-encoded identity the private key must derive. Empty skips the check.
+	ExpectedIdentity    string
 	GossipAddress       string
 	ChallengeDifficulty int
+	// ChallengeMaxSolveMs represents the maximum time in milliseconds spent on solving a push challenge received from a peer.
 	ChallengeMaxSolveMs int
-	weightPull          int
-	weightPush          int
-	weightHistory       int
+	// ChallengeMaxAcceptedDifficulty represents the maximum number of leading zero bits a peer may demand in the solution of its push challenge, harder challenges are refused without being solved. A value of 0 accepts any difficulty.
+	ChallengeMaxAcceptedDifficulty int
+	// ChallengeRotationIntervalMs represents the interval in milliseconds in which the key used to generate push challenges is rotated.
+	ChallengeRotationIntervalMs int
+	// ChallengeRotationKeys represents the number of most recent challenge keys for which solved challenges are still accepted.
+	ChallengeRotationKeys int
+	// PingTimeoutMs represents the time in milliseconds to wait for a pong before a sampled node is considered offline.
+	PingTimeoutMs int
+	// PushChallengeTimeoutMs represents the time in milliseconds within which a peer must answer our push request with a challenge. Later challenges are ignored.
+	PushChallengeTimeoutMs int
+	// PullTimeoutMs represents the time in milliseconds within which a peer must answer our pull request. Later responses are ignored.
+	PullTimeoutMs int
+	// PullResponseStrategy represents the selection of the main view nodes pull requests are answered with, one of all, random, and freshest.
+	PullResponseStrategy string
+	// PullResponseSize represents the number of nodes the random and freshest strategies answer pull requests with. A value of 0 answers with all nodes, in random or freshest-first order.
+	PullResponseSize int
+	// PullResponseExclude lists the nodes left out of pull responses, any of requester and unverified.
+	PullResponseExclude []string
+	// ProtocolVersion represents the version of the wire format spoken with peers, either 2 (sign-then-encrypt), 3 (encrypt-then-sign), 4 (version 3 allowing the hybrid post-quantum key wrap), 5 (version 4 advertising the capabilities of nodes), or 6 (version 5 carrying the origin timestamps of messages). All peers of a network must speak the same version.
+	ProtocolVersion int
+	// KeyWrap represents how the packet keys are wrapped for the receiver, either rsa-oaep or hybrid, which requires protocol version 4 or later. All peers of a network must use the same key wrap.
+	KeyWrap string
+	// SamplerStaleMs represents the time in milliseconds after which a sampler whose node was neither captured nor validated by a ping is reinitialized. A value of 0 disables aging.
+	SamplerStaleMs int
+	// PeerFailureThreshold represents the number of consecutive rounds in which a peer fails to answer our pings and pull requests after which it is evicted from the main view. A value of 0 disables eviction.
+	PeerFailureThreshold int
+	// SamplerHash represents the min-wise independent hash function of the samplers, one of sha256, blake3, and siphash.
+	SamplerHash string
+	// SamplerStateFile represents an optional file the sampler state is persisted to after every round and restored from on start.
+	SamplerStateFile string
+	// PeerDBFile represents an optional file the database of all peers that ever responded is persisted to after every round and restored from on start, seeding the main view after a restart.
+	PeerDBFile string
+	// PeerDBExpiryMs represents the time in milliseconds after which a peer that did not respond is forgotten by the peer database.
+	PeerDBExpiryMs int
+	// DNSRefreshIntervalMs represents the time in milliseconds after which a resolved hostname of a peer address is resolved again.
+	DNSRefreshIntervalMs int
+	// MessageLocalTTLFloor represents the local TTL at which a message that is no longer spread is evicted from the message cache. Until then, it is remembered to prevent receiving it multiple times.
+	MessageLocalTTLFloor int
+	// MessageFloodThreshold represents the maximum number of concurrently stored messages originating from the same peer. Further messages from that peer are ignored.
+	MessageFloodThreshold int
+	// MessageFloodAction represents the handling of messages from peers exceeding the flood threshold, one of drop, delay, and deprioritize. Data types may override it within their policy.
+	MessageFloodAction string
+	// MaxMessageDataSize represents the maximum size in bytes of the data of a gossip message. Larger messages are rejected when announced by API clients and ignored when received from peers.
+	MaxMessageDataSize int
+	// InfiniteTTLMaxRounds represents the number of rounds messages with infinite hops (TTL 0) are spread by this peer.
+	InfiniteTTLMaxRounds int
+	// InfiniteTTLMaxAgeMs represents the time in milliseconds after which messages with infinite hops are evicted from the message cache. A value of 0 disables the expiry.
+	InfiniteTTLMaxAgeMs int
+	// InfiniteTTLMaxStorePercent represents the maximum share of message_store_max_messages in percent that messages with infinite hops may occupy.
+	InfiniteTTLMaxStorePercent int
+	// MessageExpiryMs represents the time in milliseconds after its origin announced a message at which it stops being spread, regardless of its hop TTL. Protocol versions before 6 do not carry the announcement time and count from the first receipt instead. A value of 0 disables the expiry, data types may override it within their policy.
+	MessageExpiryMs int
+	// MessageStoreMaxMessages represents the maximum number of messages within the message cache. Once exceeded, messages that are no longer spread are evicted first, least recently received first.
+	MessageStoreMaxMessages int
+	// MessageStoreMaxBytes represents the maximum total size of the data of all messages within the message cache.
+	MessageStoreMaxBytes int
+	// MessageBatchMaxSize represents the maximum size in bytes of a packet combining several gossip messages sent to the same peer. A value of 0 sends each message in a separate packet.
+	MessageBatchMaxSize int
+	// SendWorkers represents the number of workers signing, encrypting, and sending outbound packets. A value of 0 starts one worker per CPU.
+	SendWorkers int
+	// SendQueueSize represents the number of outbound packets queued for the send workers, further packets wait until the queue has room.
+	SendQueueSize int
+	// SendFailureThreshold represents the number of consecutive failures to resolve or write to an address after which the circuit of the address opens and no packets are sent to it for SendCircuitCooldownMs. A value of 0 disables the circuit breaker.
+	SendFailureThreshold int
+	// SendCircuitCooldownMs represents the time in milliseconds the circuit of an unreachable address stays open before a single packet probes the address again.
+	SendCircuitCooldownMs int
+	// QuarantineThreshold represents the number of packets with invalid signatures or failed push challenges from an address after which the packets of the address are dropped before decryption for QuarantineMs. A value of 0 disables the quarantine.
+	QuarantineThreshold int
+	// QuarantineMs represents the time in milliseconds the packets of a quarantined address are dropped. Failures further apart are not counted towards QuarantineThreshold.
+	QuarantineMs int
+	// ClockSkewWarnMs represents the estimated offset in milliseconds between the local clock and the clocks of the peers beyond which a warning is raised. A value of 0 disables the warning.
+	ClockSkewWarnMs int
+	// MessageStoreFile represents an optional file the message cache is persisted to after every round and restored from on start.
+	MessageStoreFile string
+	// DataTypePolicies maps data types to their storage limits and spread priority, data types without policy are only limited by the message cache bounds.
+	DataTypePolicies map[uint16]DataTypePolicy
+	// SeenFilterCapacity represents the number of accepted messages per generation of the filter remembering messages after their eviction from the message cache. A value of 0 disables the filter.
+	SeenFilterCapacity int
+	// AntiEntropyIntervalRounds represents the number of rounds in between reconciling the message cache with a random peer of the view. A value of 0 disables the reconciliation.
+	AntiEntropyIntervalRounds int
+	// EagerFanout represents the number of random peers of the view receiving full messages per round, all other peers only receive announcements and request missing messages on demand. A value of 0 sends full messages to all peers.
+	EagerFanout int
+	// MessageFanout represents the number of random peers of the view the spreadable messages are announced to per round, independent of push and pull requests. A value of 0 only spreads messages along push and pull requests.
+	MessageFanout int
+	// HighPriorityLaneBudget represents the maximum number of high-priority messages sent or announced to a peer at once. A value of 0 means unlimited.
+	HighPriorityLaneBudget int
+	// BulkLaneBudget represents the maximum number of bulk messages, i.e. messages without high priority, sent or announced to a peer at once. A value of 0 means unlimited.
+	BulkLaneBudget int
+	// Adversary represents a deliberately misbehaving node validating the Byzantine resistance of its peers in tests, one of push_flood, pull_poison, message_spam, and silent.
+	// Empty behaves honestly. Only builds with the adversary tag support adversarial behaviors.
+	Adversary string
+	// Clock represents the source of time of the challenger, the gossip rounds, and the ping timeouts. The wall clock is used if nil, tests may pass in a clock.Fake.
+	Clock clock.Clock
+	// Network represents the network the gossip server listens on. UDP sockets are used if nil, tests may pass in a transport.Loopback.
+	Network transport.Network
+	// Random represents the source of randomness of the peer selection, the sampler biases, and the challenge keys, which must be safe for concurrent use.
+	// crypto/rand is used if nil, tests and simulations may pass in a seeded source for reproducible runs. Packets are always encrypted and signed with crypto/rand.
+	Random io.Reader
+	// LogLevel represents the global zap log level (debug, info, warn, error).
+	LogLevel string
+	// LogEncoding represents the encoding of log entries, either json or console.
+	LogEncoding string
+	// SubsystemLogLevels maps the subsystems gossip, api, challenge, and crypto to their log level. Subsystems without a level use LogLevel.
+	SubsystemLogLevels map[string]string
+	// LogFile represents an optional file logs are written to instead of stderr.
+	LogFile string
+	// LogFileMaxSizeMB represents the size in megabytes at which the log file is rotated.
+	LogFileMaxSizeMB int
+	// LogFileMaxBackups represents the number of rotated log files that are kept.
+	LogFileMaxBackups int
+	// LogFileMaxAgeDays represents the number of days after which rotated log files are deleted. A value of 0 keeps them regardless of their age.
+	LogFileMaxAgeDays int
+	weightPull        int
+	weightPush        int
+	weightHistory     int
 }
 
 // ReadConfig reads the values in from a .ini file through a specified path and returns a populated config.
 func ReadConfig(path string) (*GossipConfig, error) {
-	iniData, err := ini.Load(path)
+	return ReadConfigWithOverrides(path, nil)
+}
+
+// ReadConfigWithOverrides reads the values in from a .ini file through a specified path and returns a populated config.
+// Values within the file are overridden by GOSSIP_<KEY> environment variables, which are in turn overridden by the passed-in key-value pairs.
+func ReadConfigWithOverrides(path string, overrides map[string]string) (*GossipConfig, error) {
+	paths, err := resolveIncludes(path, nil)
 	if err != nil {
 		zap.L().Error("Could not parse provided configuration.", zap.String("path", path), zap.Error(err))
 		return nil, err
 	}
+	// later files take precedence over the files they include
+	sources := make([]interface{}, 0, len(paths))
+	for _, p := range paths[1:] {
+		sources = append(sources, p)
+	}
+	iniData, err := ini.Load(paths[0], sources...)
+	if err != nil {
+		zap.L().Error("Could not parse provided configuration.", zap.String("path", path), zap.Error(err))
+		return nil, fmt.Errorf("could not load configuration file %s: %w", path, err)
+	}
+
+	err = applyOverrides(iniData, envOverrides())
+	if err != nil {
+		return nil, err
+	}
+	err = applyOverrides(iniData, overrides)
+	if err != nil {
+		return nil, err
+	}
 
 	gossipSection := iniData.Section("gossip")
 	if gossipSection == nil {
@@ -76,34 +322,355 @@ func ReadConfig(path string) (*GossipConfig, error) {
 		return &defaultConfig, nil
 	}
 
+	// keep going after a problem, so all of them are reported at once
+	var problems []error
 	alpha, beta, gamma, err := alphaBetaGamma(gossipSection)
 	if err != nil {
 		zap.L().Error("Could not retrieve alpha, beta, and gamma values", zap.Error(err))
-		return nil, err
+		problems = append(problems, err)
+		alpha, beta, gamma = defaultConfig.Alpha, defaultConfig.Beta, defaultConfig.Gamma
+	}
+
+	dataTypePolicies, err := ParseDataTypePolicies(gossipSection.Key("data_type_policies").Value())
+	if err != nil {
+		problems = append(problems, err)
+	}
+
+	apiTTLPolicies, err := ParseTTLPolicies(gossipSection.Key("api_ttl_policies").Value())
+	if err != nil {
+		problems = append(problems, err)
 	}
 
 	// empty quotations denote the root section.
-	privKey := getPrivateKey(iniData.Section(""))
-
-	return &GossipConfig{
-		ViewSize:            getIntOrDefault(gossipSection.Key("degree"), defaultConfig.ViewSize, true),
-		SamplerSize:         getIntOrDefault(gossipSection.Key("l2"), defaultConfig.SamplerSize, true),
-		Alpha:               alpha,
-		Beta:                beta,
-		Gamma:               gamma,
-		BootstrapNodesStr:   gossipSection.Key("bootstrap_nodes").Value(),
-		RoundsBetweenPings:  getIntOrDefault(gossipSection.Key("rounds_between_pings"), defaultConfig.RoundsBetweenPings, false),
-		ApiAddress:          getStringOrDefault(gossipSection.Key("api_address"), defaultConfig.ApiAddress, false),
-		HostkeysPath:        getStringOrDefault(gossipSection.Key("hostkeys_path"), defaultConfig.HostkeysPath, true),
-		PrivateKey:          privKey,
-		GossipAddress:       getStringOrDefault(gossipSection.Key("gossip_address"), defaultConfig.GossipAddress, false),
-		ChallengeDifficulty: getIntOrDefault(gossipSection.Key("challenge_difficulty"), defaultConfig.ChallengeDifficulty, false),
-		ChallengeMaxSolveMs: getIntOrDefault(gossipSection.Key("challenge_max_solve_ms"), defaultConfig.ChallengeMaxSolveMs, false),
-	}, nil
+	privKey, err := getPrivateKey(iniData.Section(""))
+	if err != nil {
+		zap.L().Error("Could not retrieve the private key", zap.Error(err))
+		problems = append(problems, err)
+	}
+	var hybridKey []byte
+	if privKey != nil {
+		hybridKey, err = ReadHybridPrivateKey(iniData.Section("").Key("hostkey").Value())
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	cfg := &GossipConfig{
+		ViewSize:                       getIntOrDefault(gossipSection.Key("degree"), defaultConfig.ViewSize, true),
+		SamplerSize:                    getIntOrDefault(gossipSection.Key("l2"), defaultConfig.SamplerSize, true),
+		PushPullViewMaxSize:            getIntOrDefault(gossipSection.Key("push_pull_view_max_size"), defaultConfig.PushPullViewMaxSize, false),
+		Alpha:                          alpha,
+		Beta:                           beta,
+		Gamma:                          gamma,
+		BootstrapNodesStr:              gossipSection.Key("bootstrap_nodes").Value(),
+		PinnedNodesStr:                 gossipSection.Key("pinned_nodes").Value(),
+		Participation:                  getStringOrDefault(gossipSection.Key("participation"), defaultConfig.Participation, false),
+		BootstrapNodesFile:             gossipSection.Key("bootstrap_nodes_file").Value(),
+		BootstrapNodesFileReloadRounds: getIntOrDefault(gossipSection.Key("bootstrap_nodes_file_reload_rounds"), defaultConfig.BootstrapNodesFileReloadRounds, false),
+		RoundIntervalMs:                getIntOrDefault(gossipSection.Key("round_interval_ms"), defaultConfig.RoundIntervalMs, false),
+		RoundsBetweenPings:             getIntOrDefault(gossipSection.Key("rounds_between_pings"), defaultConfig.RoundsBetweenPings, false),
+		ApiAddress:                     getStringOrDefault(gossipSection.Key("api_address"), defaultConfig.ApiAddress, false),
+		ApiNotificationHistorySize:     getIntOrDefault(gossipSection.Key("api_notification_history_size"), defaultConfig.ApiNotificationHistorySize, false),
+		ApiNotificationReplaySize:      getIntOrDefault(gossipSection.Key("api_notification_replay_size"), defaultConfig.ApiNotificationReplaySize, false),
+		ApiNotificationReplayMs:        getIntOrDefault(gossipSection.Key("api_notification_replay_ms"), defaultConfig.ApiNotificationReplayMs, false),
+		ApiValidationPolicy:            getStringOrDefault(gossipSection.Key("api_validation_policy"), defaultConfig.ApiValidationPolicy, false),
+		ApiTTLPolicies:                 apiTTLPolicies,
+		DebugAddress:                   gossipSection.Key("debug_address").Value(),
+		StateDumpFile:                  gossipSection.Key("state_dump_file").Value(),
+		HealthAddress:                  gossipSection.Key("health_address").Value(),
+		LatencyWindowSize:              getIntOrDefault(gossipSection.Key("latency_window_size"), defaultConfig.LatencyWindowSize, false),
+		ReadinessPeerWindowMs:          getIntOrDefault(gossipSection.Key("readiness_peer_window_ms"), defaultConfig.ReadinessPeerWindowMs, false),
+		HostkeysPath:                   getStringOrDefault(gossipSection.Key("hostkeys_path"), defaultConfig.HostkeysPath, true),
+		PrivateKey:                     privKey,
+		PrivateKeyPath:                 iniData.Section("").Key("hostkey").Value(),
+		HybridPrivateKey:               hybridKey,
+		ExpectedIdentity:               iniData.Section("").Key("identity").Value(),
+		GossipAddress:                  getStringOrDefault(gossipSection.Key("gossip_address"), defaultConfig.GossipAddress, false),
+		ChallengeDifficulty:            getIntOrDefault(gossipSection.Key("challenge_difficulty"), defaultConfig.ChallengeDifficulty, false),
+		ChallengeMaxSolveMs:            getIntOrDefault(gossipSection.Key("challenge_max_solve_ms"), defaultConfig.ChallengeMaxSolveMs, false),
+		ChallengeMaxAcceptedDifficulty: getIntOrDefault(gossipSection.Key("challenge_max_accepted_difficulty"), defaultConfig.ChallengeMaxAcceptedDifficulty, false),
+		ChallengeRotationIntervalMs:    getIntOrDefault(gossipSection.Key("challenge_rotation_interval_ms"), defaultConfig.ChallengeRotationIntervalMs, false),
+		ChallengeRotationKeys:          getIntOrDefault(gossipSection.Key("challenge_rotation_keys"), defaultConfig.ChallengeRotationKeys, false),
+		PingTimeoutMs:                  getIntOrDefault(gossipSection.Key("ping_timeout_ms"), defaultConfig.PingTimeoutMs, false),
+		PushChallengeTimeoutMs:         getIntOrDefault(gossipSection.Key("push_challenge_timeout_ms"), defaultConfig.PushChallengeTimeoutMs, false),
+		PullTimeoutMs:                  getIntOrDefault(gossipSection.Key("pull_timeout_ms"), defaultConfig.PullTimeoutMs, false),
+		PullResponseStrategy:           getStringOrDefault(gossipSection.Key("pull_response_strategy"), defaultConfig.PullResponseStrategy, false),
+		PullResponseSize:               getIntOrDefault(gossipSection.Key("pull_response_size"), defaultConfig.PullResponseSize, false),
+		PullResponseExclude:            parseList(gossipSection.Key("pull_response_exclude").Value()),
+		ProtocolVersion:                getIntOrDefault(gossipSection.Key("protocol_version"), defaultConfig.ProtocolVersion, false),
+		KeyWrap:                        getStringOrDefault(gossipSection.Key("key_wrap"), defaultConfig.KeyWrap, false),
+		SamplerStaleMs:                 getIntOrDefault(gossipSection.Key("sampler_stale_ms"), defaultConfig.SamplerStaleMs, false),
+		PeerFailureThreshold:           getIntOrDefault(gossipSection.Key("peer_failure_threshold"), defaultConfig.PeerFailureThreshold, false),
+		SamplerHash:                    getStringOrDefault(gossipSection.Key("sampler_hash"), defaultConfig.SamplerHash, false),
+		SamplerStateFile:               gossipSection.Key("sampler_state_file").Value(),
+		PeerDBFile:                     gossipSection.Key("peer_db_file").Value(),
+		PeerDBExpiryMs:                 getIntOrDefault(gossipSection.Key("peer_db_expiry_ms"), defaultConfig.PeerDBExpiryMs, false),
+		DNSRefreshIntervalMs:           getIntOrDefault(gossipSection.Key("dns_refresh_interval_ms"), defaultConfig.DNSRefreshIntervalMs, false),
+		MessageLocalTTLFloor:           getIntOrDefault(gossipSection.Key("message_local_ttl_floor"), defaultConfig.MessageLocalTTLFloor, false),
+		MessageFloodThreshold:          getIntOrDefault(gossipSection.Key("message_flood_threshold"), defaultConfig.MessageFloodThreshold, false),
+		MessageFloodAction:             getStringOrDefault(gossipSection.Key("message_flood_action"), defaultConfig.MessageFloodAction, false),
+		MaxMessageDataSize:             getIntOrDefault(gossipSection.Key("max_message_data_size"), defaultConfig.MaxMessageDataSize, false),
+		InfiniteTTLMaxRounds:           getIntOrDefault(gossipSection.Key("infinite_ttl_max_rounds"), defaultConfig.InfiniteTTLMaxRounds, false),
+		InfiniteTTLMaxAgeMs:            getIntOrDefault(gossipSection.Key("infinite_ttl_max_age_ms"), defaultConfig.InfiniteTTLMaxAgeMs, false),
+		InfiniteTTLMaxStorePercent:     getIntOrDefault(gossipSection.Key("infinite_ttl_max_store_percent"), defaultConfig.InfiniteTTLMaxStorePercent, false),
+		MessageExpiryMs:                getIntOrDefault(gossipSection.Key("message_expiry_ms"), defaultConfig.MessageExpiryMs, false),
+		MessageStoreMaxMessages:        getIntOrDefault(gossipSection.Key("message_store_max_messages"), defaultConfig.MessageStoreMaxMessages, false),
+		MessageStoreMaxBytes:           getIntOrDefault(gossipSection.Key("message_store_max_bytes"), defaultConfig.MessageStoreMaxBytes, false),
+		MessageBatchMaxSize:            getIntOrDefault(gossipSection.Key("message_batch_max_size"), defaultConfig.MessageBatchMaxSize, false),
+		SendWorkers:                    getIntOrDefault(gossipSection.Key("send_workers"), defaultConfig.SendWorkers, false),
+		SendQueueSize:                  getIntOrDefault(gossipSection.Key("send_queue_size"), defaultConfig.SendQueueSize, false),
+		SendFailureThreshold:           getIntOrDefault(gossipSection.Key("send_failure_threshold"), defaultConfig.SendFailureThreshold, false),
+		SendCircuitCooldownMs:          getIntOrDefault(gossipSection.Key("send_circuit_cooldown_ms"), defaultConfig.SendCircuitCooldownMs, false),
+		QuarantineThreshold:            getIntOrDefault(gossipSection.Key("quarantine_threshold"), defaultConfig.QuarantineThreshold, false),
+		QuarantineMs:                   getIntOrDefault(gossipSection.Key("quarantine_ms"), defaultConfig.QuarantineMs, false),
+		ClockSkewWarnMs:                getIntOrDefault(gossipSection.Key("clock_skew_warn_ms"), defaultConfig.ClockSkewWarnMs, false),
+		MessageStoreFile:               gossipSection.Key("message_store_file").Value(),
+		DataTypePolicies:               dataTypePolicies,
+		SeenFilterCapacity:             getIntOrDefault(gossipSection.Key("seen_filter_capacity"), defaultConfig.SeenFilterCapacity, false),
+		AntiEntropyIntervalRounds:      getIntOrDefault(gossipSection.Key("anti_entropy_interval_rounds"), defaultConfig.AntiEntropyIntervalRounds, false),
+		EagerFanout:                    getIntOrDefault(gossipSection.Key("eager_fanout"), defaultConfig.EagerFanout, false),
+		MessageFanout:                  getIntOrDefault(gossipSection.Key("message_fanout"), defaultConfig.MessageFanout, false),
+		HighPriorityLaneBudget:         getIntOrDefault(gossipSection.Key("high_priority_lane_budget"), defaultConfig.HighPriorityLaneBudget, false),
+		BulkLaneBudget:                 getIntOrDefault(gossipSection.Key("bulk_lane_budget"), defaultConfig.BulkLaneBudget, false),
+		Adversary:                      gossipSection.Key("adversary").Value(),
+		LogLevel:                       getStringOrDefault(gossipSection.Key("log_level"), defaultConfig.LogLevel, false),
+		LogEncoding:                    getStringOrDefault(gossipSection.Key("log_encoding"), defaultConfig.LogEncoding, false),
+		SubsystemLogLevels: map[string]string{
+			"gossip":    gossipSection.Key("log_level_gossip").Value(),
+			"api":       gossipSection.Key("log_level_api").Value(),
+			"challenge": gossipSection.Key("log_level_challenge").Value(),
+			"crypto":    gossipSection.Key("log_level_crypto").Value(),
+		},
+		LogFile:           gossipSection.Key("log_file").Value(),
+		LogFileMaxSizeMB:  getIntOrDefault(gossipSection.Key("log_file_max_size_mb"), defaultConfig.LogFileMaxSizeMB, false),
+		LogFileMaxBackups: getIntOrDefault(gossipSection.Key("log_file_max_backups"), defaultConfig.LogFileMaxBackups, false),
+		LogFileMaxAgeDays: getIntOrDefault(gossipSection.Key("log_file_max_age_days"), defaultConfig.LogFileMaxAgeDays, false),
+	}
+	problems = append(problems, cfg.rangeProblems()...)
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	return cfg, nil
+}
+
+// resolveIncludes returns the paths of all files the configuration file at path (transitively) includes via the include key of its root section, followed by path itself.
+// Included paths may be comma-separated and are relative to the directory of the including file.
+func resolveIncludes(path string, includedBy []string) ([]string, error) {
+	for _, p := range includedBy {
+		if p == path {
+			return nil, fmt.Errorf("configuration file %s includes itself: %s", path, strings.Join(append(includedBy, path), " -> "))
+		}
+	}
+	iniData, err := ini.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load configuration file %s: %w", path, err)
+	}
+
+	var paths []string
+	for _, include := range iniData.Section("").Key("include").Strings(",") {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		includedPaths, err := resolveIncludes(include, append(includedBy, path))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, includedPaths...)
+	}
+	return append(paths, path), nil
+}
+
+// Validate checks that a private key is set and all values of the config are within their valid ranges.
+// All problems found are reported within the returned error, one per line.
+func (cfg *GossipConfig) Validate() error {
+	problems := cfg.rangeProblems()
+	if cfg.PrivateKey == nil {
+		problems = append([]error{errors.New("a private key is required")}, problems...)
+	}
+	return errors.Join(problems...)
+}
+
+// rangeProblems returns a problem for each value of the config that is not within its valid range.
+func (cfg *GossipConfig) rangeProblems() []error {
+	var problems []error
+	if cfg.ViewSize <= 0 || cfg.SamplerSize <= 0 {
+		problems = append(problems, fmt.Errorf("degree (%d) and l2 (%d) must be greater than 0", cfg.ViewSize, cfg.SamplerSize))
+	}
+	if cfg.PushPullViewMaxSize <= cfg.ViewSize {
+		problems = append(problems, fmt.Errorf("push_pull_view_max_size (%d) must be greater than degree (%d), otherwise push floods cannot be detected", cfg.PushPullViewMaxSize, cfg.ViewSize))
+	}
+	if cfg.Alpha <= 0 || cfg.Beta <= 0 || cfg.Gamma <= 0 || !almostEqual(cfg.Alpha+cfg.Beta+cfg.Gamma, 1.0) {
+		problems = append(problems, fmt.Errorf("alpha, beta, and gamma must be greater than 0 and add up to 1.0 -- alpha=%.3f, beta=%.3f, gamma=%.3f", cfg.Alpha, cfg.Beta, cfg.Gamma))
+	}
+	if cfg.RoundIntervalMs <= 0 {
+		problems = append(problems, fmt.Errorf("round_interval_ms must be greater than 0: received %d", cfg.RoundIntervalMs))
+	}
+	if cfg.RoundsBetweenPings <= 0 {
+		problems = append(problems, fmt.Errorf("rounds_between_pings must be greater than 0: received %d", cfg.RoundsBetweenPings))
+	}
+	if cfg.ApiNotificationHistorySize < 0 {
+		problems = append(problems, fmt.Errorf("api_notification_history_size must not be negative: received %d", cfg.ApiNotificationHistorySize))
+	}
+	if cfg.ApiNotificationReplaySize < 0 {
+		problems = append(problems, fmt.Errorf("api_notification_replay_size must not be negative: received %d", cfg.ApiNotificationReplaySize))
+	}
+	if cfg.ApiNotificationReplayMs <= 0 {
+		problems = append(problems, fmt.Errorf("api_notification_replay_ms must be greater than 0: received %d", cfg.ApiNotificationReplayMs))
+	}
+	if !IsValidParticipation(cfg.Participation) {
+		problems = append(problems, fmt.Errorf("participation must be one of full and outbound_only: received %s", cfg.Participation))
+	}
+	if !IsValidValidationPolicy(cfg.ApiValidationPolicy) {
+		problems = append(problems, fmt.Errorf("api_validation_policy must be one of first_wins, all, and quorum: received %s", cfg.ApiValidationPolicy))
+	}
+	if cfg.LatencyWindowSize <= 0 || cfg.LatencyWindowSize > 65535 {
+		problems = append(problems, fmt.Errorf("latency_window_size must be between 1 and 65535: received %d", cfg.LatencyWindowSize))
+	}
+	if cfg.ReadinessPeerWindowMs <= 0 {
+		problems = append(problems, fmt.Errorf("readiness_peer_window_ms must be greater than 0: received %d", cfg.ReadinessPeerWindowMs))
+	}
+	if cfg.ChallengeDifficulty < 0 || cfg.ChallengeDifficulty >= 256 {
+		problems = append(problems, fmt.Errorf("challenge_difficulty must be between 0 and 255: received %d", cfg.ChallengeDifficulty))
+	}
+	if cfg.PingTimeoutMs <= 0 {
+		problems = append(problems, fmt.Errorf("ping_timeout_ms must be greater than 0: received %d", cfg.PingTimeoutMs))
+	}
+	if cfg.PushChallengeTimeoutMs <= 0 {
+		problems = append(problems, fmt.Errorf("push_challenge_timeout_ms must be greater than 0: received %d", cfg.PushChallengeTimeoutMs))
+	}
+	if cfg.PullTimeoutMs <= 0 {
+		problems = append(problems, fmt.Errorf("pull_timeout_ms must be greater than 0: received %d", cfg.PullTimeoutMs))
+	}
+	if !IsValidPullResponseStrategy(cfg.PullResponseStrategy) {
+		problems = append(problems, fmt.Errorf("pull_response_strategy must be one of all, random, and freshest: received %s", cfg.PullResponseStrategy))
+	}
+	if cfg.PullResponseSize < 0 {
+		problems = append(problems, fmt.Errorf("pull_response_size must not be negative: received %d", cfg.PullResponseSize))
+	}
+	for _, exclusion := range cfg.PullResponseExclude {
+		if !IsValidPullResponseExclusion(exclusion) {
+			problems = append(problems, fmt.Errorf("pull_response_exclude must only contain requester and unverified: received %s", exclusion))
+		}
+	}
+	if cfg.ExpectedIdentity != "" {
+		if decoded, err := hex.DecodeString(cfg.ExpectedIdentity); err != nil || len(decoded) != sha256.Size {
+			problems = append(problems, fmt.Errorf("identity must consist of %d hex-encoded bytes: received %s", sha256.Size, cfg.ExpectedIdentity))
+		}
+	}
+	if cfg.ProtocolVersion < 2 || cfg.ProtocolVersion > 6 {
+		problems = append(problems, fmt.Errorf("protocol_version must be one of 2, 3, 4, 5, and 6: received %d", cfg.ProtocolVersion))
+	}
+	if !IsValidKeyWrap(cfg.KeyWrap) {
+		problems = append(problems, fmt.Errorf("key_wrap must be one of rsa-oaep and hybrid: received %s", cfg.KeyWrap))
+	} else if cfg.KeyWrap == KeyWrapHybrid && cfg.ProtocolVersion < 4 {
+		problems = append(problems, fmt.Errorf("key_wrap hybrid requires protocol_version 4 or later: received %d", cfg.ProtocolVersion))
+	} else if cfg.KeyWrap == KeyWrapHybrid && cfg.HybridPrivateKey == nil {
+		problems = append(problems, errors.New("key_wrap hybrid requires a hybrid private key within the hostkey file, generated by gossip keygen"))
+	}
+	if cfg.SamplerStaleMs < 0 {
+		problems = append(problems, fmt.Errorf("sampler_stale_ms must not be negative: received %d", cfg.SamplerStaleMs))
+	}
+	if cfg.PeerFailureThreshold < 0 {
+		problems = append(problems, fmt.Errorf("peer_failure_threshold must not be negative: received %d", cfg.PeerFailureThreshold))
+	}
+	if cfg.SamplerHash != "sha256" && cfg.SamplerHash != "blake3" && cfg.SamplerHash != "siphash" {
+		problems = append(problems, fmt.Errorf("sampler_hash must be one of sha256, blake3, and siphash: received %s", cfg.SamplerHash))
+	}
+	if cfg.DNSRefreshIntervalMs < 0 {
+		problems = append(problems, fmt.Errorf("dns_refresh_interval_ms must not be negative: received %d", cfg.DNSRefreshIntervalMs))
+	}
+	if cfg.ChallengeMaxSolveMs <= 0 {
+		problems = append(problems, fmt.Errorf("challenge_max_solve_ms must be greater than 0: received %d", cfg.ChallengeMaxSolveMs))
+	}
+	if cfg.ChallengeMaxAcceptedDifficulty < 0 || cfg.ChallengeMaxAcceptedDifficulty >= 256 {
+		problems = append(problems, fmt.Errorf("challenge_max_accepted_difficulty must be between 0 and 255: received %d", cfg.ChallengeMaxAcceptedDifficulty))
+	} else if cfg.ChallengeMaxAcceptedDifficulty > 0 && cfg.ChallengeMaxAcceptedDifficulty < cfg.ChallengeDifficulty {
+		// peers sharing the configuration would refuse our own challenges
+		problems = append(problems, fmt.Errorf("challenge_max_accepted_difficulty must not be below challenge_difficulty %d: received %d", cfg.ChallengeDifficulty, cfg.ChallengeMaxAcceptedDifficulty))
+	}
+	if cfg.ChallengeRotationIntervalMs <= 0 || cfg.ChallengeRotationKeys <= 0 {
+		problems = append(problems, fmt.Errorf("challenge_rotation_interval_ms (%d) and challenge_rotation_keys (%d) must be greater than 0", cfg.ChallengeRotationIntervalMs, cfg.ChallengeRotationKeys))
+	}
+	if cfg.MessageLocalTTLFloor > 0 {
+		problems = append(problems, fmt.Errorf("message_local_ttl_floor must not be greater than 0: received %d", cfg.MessageLocalTTLFloor))
+	}
+	if cfg.MessageFloodThreshold <= 0 {
+		problems = append(problems, fmt.Errorf("message_flood_threshold must be greater than 0: received %d", cfg.MessageFloodThreshold))
+	}
+	if !IsValidFloodAction(cfg.MessageFloodAction) {
+		problems = append(problems, fmt.Errorf("message_flood_action must be one of drop, delay, and deprioritize: received %s", cfg.MessageFloodAction))
+	}
+	if cfg.MaxMessageDataSize <= 0 || cfg.MaxMessageDataSize > MaxMessageDataSizeLimit {
+		problems = append(problems, fmt.Errorf("max_message_data_size must be between 1 and %d: received %d", MaxMessageDataSizeLimit, cfg.MaxMessageDataSize))
+	}
+	if cfg.InfiniteTTLMaxRounds <= 0 || cfg.InfiniteTTLMaxAgeMs < 0 {
+		problems = append(problems, fmt.Errorf("infinite_ttl_max_rounds (%d) must be greater than 0 and infinite_ttl_max_age_ms (%d) must not be negative", cfg.InfiniteTTLMaxRounds, cfg.InfiniteTTLMaxAgeMs))
+	}
+	if cfg.InfiniteTTLMaxStorePercent <= 0 || cfg.InfiniteTTLMaxStorePercent > 100 {
+		problems = append(problems, fmt.Errorf("infinite_ttl_max_store_percent must be between 1 and 100: received %d", cfg.InfiniteTTLMaxStorePercent))
+	}
+	if cfg.MessageExpiryMs < 0 {
+		problems = append(problems, fmt.Errorf("message_expiry_ms must not be negative: received %d", cfg.MessageExpiryMs))
+	}
+	if cfg.MessageStoreMaxMessages <= 0 || cfg.MessageStoreMaxBytes <= 0 {
+		problems = append(problems, fmt.Errorf("message_store_max_messages (%d) and message_store_max_bytes (%d) must be greater than 0", cfg.MessageStoreMaxMessages, cfg.MessageStoreMaxBytes))
+	}
+	if cfg.MessageBatchMaxSize < 0 || cfg.MessageBatchMaxSize > 65535 {
+		problems = append(problems, fmt.Errorf("message_batch_max_size must be between 0 and 65535: received %d", cfg.MessageBatchMaxSize))
+	}
+	if cfg.SendWorkers < 0 {
+		problems = append(problems, fmt.Errorf("send_workers must not be negative: received %d", cfg.SendWorkers))
+	}
+	if cfg.SendQueueSize <= 0 {
+		problems = append(problems, fmt.Errorf("send_queue_size must be greater than 0: received %d", cfg.SendQueueSize))
+	}
+	if cfg.SendFailureThreshold < 0 {
+		problems = append(problems, fmt.Errorf("send_failure_threshold must not be negative: received %d", cfg.SendFailureThreshold))
+	}
+	if cfg.SendCircuitCooldownMs <= 0 {
+		problems = append(problems, fmt.Errorf("send_circuit_cooldown_ms must be greater than 0: received %d", cfg.SendCircuitCooldownMs))
+	}
+	if cfg.QuarantineThreshold < 0 {
+		problems = append(problems, fmt.Errorf("quarantine_threshold must not be negative: received %d", cfg.QuarantineThreshold))
+	}
+	if cfg.QuarantineMs <= 0 {
+		problems = append(problems, fmt.Errorf("quarantine_ms must be greater than 0: received %d", cfg.QuarantineMs))
+	}
+	if cfg.PeerDBExpiryMs <= 0 {
+		problems = append(problems, fmt.Errorf("peer_db_expiry_ms must be greater than 0: received %d", cfg.PeerDBExpiryMs))
+	}
+	if cfg.ClockSkewWarnMs < 0 {
+		problems = append(problems, fmt.Errorf("clock_skew_warn_ms must not be negative: received %d", cfg.ClockSkewWarnMs))
+	}
+	if cfg.SeenFilterCapacity < 0 {
+		problems = append(problems, fmt.Errorf("seen_filter_capacity must not be negative: received %d", cfg.SeenFilterCapacity))
+	}
+	if cfg.AntiEntropyIntervalRounds < 0 {
+		problems = append(problems, fmt.Errorf("anti_entropy_interval_rounds must not be negative: received %d", cfg.AntiEntropyIntervalRounds))
+	}
+	if cfg.EagerFanout < 0 {
+		problems = append(problems, fmt.Errorf("eager_fanout must not be negative: received %d", cfg.EagerFanout))
+	}
+	if cfg.MessageFanout < 0 {
+		problems = append(problems, fmt.Errorf("message_fanout must not be negative: received %d", cfg.MessageFanout))
+	}
+	if cfg.HighPriorityLaneBudget < 0 || cfg.BulkLaneBudget < 0 {
+		problems = append(problems, fmt.Errorf("high_priority_lane_budget (%d) and bulk_lane_budget (%d) must not be negative", cfg.HighPriorityLaneBudget, cfg.BulkLaneBudget))
+	}
+	if !IsValidAdversary(cfg.Adversary) {
+		problems = append(problems, fmt.Errorf("adversary must be empty or one of push_flood, pull_poison, message_spam, and silent: received %s", cfg.Adversary))
+	}
+	return problems
 }
 
-// alphaBetaGamma retrieves the alpha, beta, and gamma values from the config. Note that weightPush, weightPull, and weightHistory must add up to 100.
+// alphaBetaGamma retrieves the alpha, beta, and gamma values from the config.
+// If any of the alpha, beta, and gamma keys is present, they are used directly and take precedence over the weights.
+// Otherwise they are derived from weightPush, weightPull, and weightHistory, which must add up to 100.
 func alphaBetaGamma(gossipSection *ini.Section) (alpha float64, beta float64, gamma float64, err error) {
+	if gossipSection.HasKey("alpha") || gossipSection.HasKey("beta") || gossipSection.HasKey("gamma") {
+		return directAlphaBetaGamma(gossipSection)
+	}
+
 	weightPush := getIntOrDefault(gossipSection.Key("weight_push"), defaultConfig.weightPush, true)
 	weightPull := getIntOrDefault(gossipSection.Key("weight_pull"), defaultConfig.weightPull, true)
 	weightHistory := getIntOrDefault(gossipSection.Key("weight_history"), defaultConfig.weightHistory, true)
@@ -127,20 +694,59 @@ func alphaBetaGamma(gossipSection *ini.Section) (alpha float64, beta float64, ga
 	return
 }
 
+// directAlphaBetaGamma retrieves the alpha, beta, and gamma values directly from their float keys. All three must be set, greater than 0, and add up to 1.0.
+func directAlphaBetaGamma(gossipSection *ini.Section) (alpha float64, beta float64, gamma float64, err error) {
+	if !gossipSection.HasKey("alpha") || !gossipSection.HasKey("beta") || !gossipSection.HasKey("gamma") {
+		err = errors.New("alpha, beta, and gamma must either all be set or none of them")
+		return
+	}
+	if gossipSection.HasKey("weight_push") || gossipSection.HasKey("weight_pull") || gossipSection.HasKey("weight_history") {
+		zap.L().Warn("Both alpha, beta, gamma and weights are configured, the weights are ignored")
+	}
+
+	values := make([]float64, 3)
+	for i, name := range []string{"alpha", "beta", "gamma"} {
+		values[i], err = gossipSection.Key(name).Float64()
+		if err != nil {
+			err = fmt.Errorf("%s must be a floating point number: %w", name, err)
+			return
+		}
+	}
+	alpha, beta, gamma = values[0], values[1], values[2]
+	if alpha <= 0 || beta <= 0 || gamma <= 0 {
+		err = fmt.Errorf("alpha, beta, and gamma must be greater than 0 -- alpha=%.3f, beta=%.3f, gamma=%.3f", alpha, beta, gamma)
+		return
+	}
+	if !almostEqual(alpha+beta+gamma, 1.0) {
+		err = fmt.Errorf("alpha + beta + gamma must equal 1.0 -- alpha=%.3f, beta=%.3f, gamma=%.3f", alpha, beta, gamma)
+		return
+	}
+	return
+}
+
 // almostEqual checks whether two floats are equal within a certain tolerance
 func almostEqual(a, b float64) bool {
 	return math.Abs(a-b) <= float64EqualityThreshold
 }
 
-// getPrivateKey will either successfully retrieve the private key found at the value object of the hostkey key within the ini file, or it will panic.
-func getPrivateKey(rootSection *ini.Section) *rsa.PrivateKey {
+// getPrivateKey retrieves the private key found at the value object of the hostkey key within the ini file.
+func getPrivateKey(rootSection *ini.Section) (*rsa.PrivateKey, error) {
 	hostkeyPath := rootSection.Key("hostkey").Value()
 	if len(hostkeyPath) == 0 {
-		panic("no hostkey path within the specified .ini file")
+		return nil, ErrMissingHostkey
+	}
+	key, err := ReadPrivateKey(hostkeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not load hostkey: %w", err)
 	}
-	pemData, err := os.ReadFile(hostkeyPath)
+	return key, nil
+}
+
+// ReadPrivateKey reads the first RSA private key found within the PEM file at the given path.
+func ReadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	pemData, err := os.ReadFile(path)
 	if err != nil {
-		panic(fmt.Errorf("could not read file: filepath %s", hostkeyPath))
+		return nil, fmt.Errorf("could not read private key file %s: %w", path, err)
 	}
 
 	for {
@@ -152,15 +758,34 @@ func getPrivateKey(rootSection *ini.Section) *rsa.PrivateKey {
 		if block.Type == RSAPrivateKey {
 			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
 			if err != nil {
-				panic(errors.New("could not parse the private key"))
+				return nil, fmt.Errorf("could not parse the private key in %s, expected PKCS#1 encoding: %w", path, err)
 			}
-			return key
+			return key, nil
 		}
 
 		pemData = rest
 	}
 
-	panic("Could not find the private key. Is it within the PEM file?")
+	return nil, fmt.Errorf("%w: file %s does not contain a PEM block of type %q", ErrPrivateKeyNotFound, path, RSAPrivateKey)
+}
+
+// ReadHybridPrivateKey reads the encoded hybrid private key of the first PEM block of type HybridPrivateKey within the file at the
+// given path. Returns nil if the file holds no hybrid private key.
+func ReadHybridPrivateKey(path string) ([]byte, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read private key file %s: %w", path, err)
+	}
+	for {
+		block, rest := pem.Decode(pemData)
+		if block == nil {
+			return nil, nil
+		}
+		if block.Type == HybridPrivateKey {
+			return block.Bytes, nil
+		}
+		pemData = rest
+	}
 }
 
 // getIntOrDefault retrieves the int value saved within the config file or falls back to a default if no such key exists.
@@ -0,0 +1,427 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/ini.v1"
+)
+
+// writeTestFile writes the content to a file within a temporary directory and returns its path.
+func writeTestFile(t *testing.T, name string, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, content, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeTestKey writes a newly generated RSA private key to a PEM file within a temporary directory and returns its path.
+func writeTestKey(t *testing.T) string {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeTestFile(t, "hostkey.pem", pem.EncodeToMemory(&pem.Block{Type: RSAPrivateKey, Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}))
+}
+
+func TestConfig_ReadPrivateKey(t *testing.T) {
+	t.Parallel()
+	t.Run("reads a valid private key", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pemBytes := pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)})
+		pemBytes = append(pemBytes, pem.EncodeToMemory(&pem.Block{Type: RSAPrivateKey, Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})...)
+		path := writeTestFile(t, "hostkey.pem", pemBytes)
+
+		key, err := ReadPrivateKey(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !key.Equal(privateKey) {
+			t.Error("read private key does not match the written one")
+		}
+	})
+	t.Run("returns error on missing file", func(t *testing.T) {
+		_, err := ReadPrivateKey(filepath.Join(t.TempDir(), "missing.pem"))
+		if err == nil {
+			t.Fatal("expecting error")
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Error("Unexpected error type", err)
+		}
+	})
+	t.Run("returns error on file without PEM blocks", func(t *testing.T) {
+		path := writeTestFile(t, "hostkey.pem", []byte("this is not a PEM file"))
+		_, err := ReadPrivateKey(path)
+		if !errors.Is(err, ErrPrivateKeyNotFound) {
+			t.Error("Unexpected error type", err)
+		}
+	})
+	t.Run("returns error on malformed private key block", func(t *testing.T) {
+		path := writeTestFile(t, "hostkey.pem", pem.EncodeToMemory(&pem.Block{Type: RSAPrivateKey, Bytes: []byte{0x01, 0x02, 0x03}}))
+		_, err := ReadPrivateKey(path)
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+}
+
+func TestConfig_ReadConfig(t *testing.T) {
+	t.Parallel()
+	t.Run("returns error on missing configuration file", func(t *testing.T) {
+		_, err := ReadConfig(filepath.Join(t.TempDir(), "missing.ini"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+	t.Run("returns error on missing hostkey", func(t *testing.T) {
+		path := writeTestFile(t, "config.ini", []byte("[gossip]\ndegree = 30\n"))
+		_, err := ReadConfig(path)
+		if !errors.Is(err, ErrMissingHostkey) {
+			t.Error("Unexpected error type", err)
+		}
+	})
+	t.Run("returns error on missing hostkey file", func(t *testing.T) {
+		path := writeTestFile(t, "config.ini", []byte("hostkey = "+filepath.Join(t.TempDir(), "missing.pem")+"\n[gossip]\ndegree = 30\n"))
+		_, err := ReadConfig(path)
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Error("Unexpected error type", err)
+		}
+	})
+	t.Run("reports all problems at once", func(t *testing.T) {
+		path := writeTestFile(t, "config.ini", []byte("[gossip]\ndegree = 30\nping_timeout_ms = 0\nsampler_hash = md5\nadversary = byzantine\n"))
+		_, err := ReadConfig(path)
+		if !errors.Is(err, ErrMissingHostkey) {
+			t.Error("Unexpected error type", err)
+		}
+		for _, key := range []string{"ping_timeout_ms", "sampler_hash", "adversary"} {
+			if err == nil || !strings.Contains(err.Error(), key) {
+				t.Errorf("expected a problem with %s, received %v", key, err)
+			}
+		}
+	})
+}
+
+func TestConfig_ReadConfigWithOverrides(t *testing.T) {
+	t.Run("overrides take precedence over environment variables and the file", func(t *testing.T) {
+		t.Setenv("GOSSIP_DEGREE", "20")
+		t.Setenv("GOSSIP_API_ADDRESS", "localhost:8001")
+		path := writeTestFile(t, "config.ini", []byte("hostkey = "+writeTestKey(t)+"\n[gossip]\ndegree = 10\nl2 = 10\napi_address = localhost:9001\n"))
+
+		cfg, err := ReadConfigWithOverrides(path, map[string]string{"api_address": "localhost:7777"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.SamplerSize != 10 {
+			t.Errorf("expected value of the file, received %d", cfg.SamplerSize)
+		}
+		if cfg.ViewSize != 20 {
+			t.Errorf("expected value of the environment variable, received %d", cfg.ViewSize)
+		}
+		if cfg.ApiAddress != "localhost:7777" {
+			t.Errorf("expected value of the override, received %s", cfg.ApiAddress)
+		}
+	})
+	t.Run("lists are split at commas", func(t *testing.T) {
+		path := writeTestFile(t, "config.ini", []byte("hostkey = "+writeTestKey(t)+"\n[gossip]\n"))
+		cfg, err := ReadConfigWithOverrides(path, map[string]string{"pull_response_exclude": "requester, unverified,"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.PullResponseExclude) != 2 || cfg.PullResponseExclude[0] != "requester" || cfg.PullResponseExclude[1] != "unverified" {
+			t.Errorf("expected requester and unverified, received %v", cfg.PullResponseExclude)
+		}
+	})
+	t.Run("returns error on unknown key", func(t *testing.T) {
+		path := writeTestFile(t, "config.ini", []byte("hostkey = "+writeTestKey(t)+"\n[gossip]\n"))
+		_, err := ReadConfigWithOverrides(path, map[string]string{"unknown_key": "1"})
+		if !errors.Is(err, ErrUnknownKey) {
+			t.Error("Unexpected error type", err)
+		}
+	})
+}
+
+func TestConfig_ChangedKeys(t *testing.T) {
+	t.Parallel()
+	t.Run("reports keys with differing values", func(t *testing.T) {
+		old := defaultConfig
+		changed := defaultConfig
+		changed.ViewSize++
+		changed.LogLevel = "debug"
+		keys := ChangedKeys(&old, &changed)
+		if len(keys) != 2 || keys[0] != "degree" || keys[1] != "log_level" {
+			t.Errorf("expected degree and log_level, received %v", keys)
+		}
+		if keys = ChangedKeys(&old, &old); len(keys) != 0 {
+			t.Errorf("expected no changes, received %v", keys)
+		}
+	})
+}
+
+func TestConfig_WriteDefaultConfig(t *testing.T) {
+	t.Parallel()
+	t.Run("generated configuration is read back as the default configuration", func(t *testing.T) {
+		var sb strings.Builder
+		err := WriteDefaultConfig(&sb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyPath := writeTestKey(t)
+		path := writeTestFile(t, "config.ini", []byte(strings.Replace(sb.String(), "hostkey = hostkey.pem", "hostkey = "+keyPath, 1)))
+
+		cfg, err := ReadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := defaultConfig
+		expected.PrivateKeyPath = keyPath
+		if cfg.String() != expected.String() {
+			t.Errorf("read configuration differs from the default configuration:\n%s\n%s", cfg.String(), expected.String())
+		}
+	})
+}
+
+func TestConfig_alphaBetaGamma(t *testing.T) {
+	t.Parallel()
+	section := func(t *testing.T, content string) *ini.Section {
+		t.Helper()
+		iniData, err := ini.Load([]byte("[gossip]\n" + content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return iniData.Section("gossip")
+	}
+
+	t.Run("derives values from weights", func(t *testing.T) {
+		alpha, beta, gamma, err := alphaBetaGamma(section(t, "weight_push = 40\nweight_pull = 40\nweight_history = 20\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !almostEqual(alpha, .4) || !almostEqual(beta, .4) || !almostEqual(gamma, .2) {
+			t.Errorf("unexpected values: alpha=%f, beta=%f, gamma=%f", alpha, beta, gamma)
+		}
+	})
+	t.Run("direct values take precedence over weights", func(t *testing.T) {
+		alpha, beta, gamma, err := alphaBetaGamma(section(t, "weight_push = 40\nweight_pull = 40\nweight_history = 20\nalpha = 0.5\nbeta = 0.3\ngamma = 0.2\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !almostEqual(alpha, .5) || !almostEqual(beta, .3) || !almostEqual(gamma, .2) {
+			t.Errorf("unexpected values: alpha=%f, beta=%f, gamma=%f", alpha, beta, gamma)
+		}
+	})
+	t.Run("returns error if only some direct values are set", func(t *testing.T) {
+		_, _, _, err := alphaBetaGamma(section(t, "alpha = 0.5\nbeta = 0.5\n"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+	t.Run("returns error if direct values do not add up to 1.0", func(t *testing.T) {
+		_, _, _, err := alphaBetaGamma(section(t, "alpha = 0.5\nbeta = 0.5\ngamma = 0.5\n"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+	t.Run("returns error on non-positive or malformed direct values", func(t *testing.T) {
+		_, _, _, err := alphaBetaGamma(section(t, "alpha = 1.0\nbeta = 0\ngamma = 0\n"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, _, _, err = alphaBetaGamma(section(t, "alpha = half\nbeta = 0.3\ngamma = 0.2\n"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+}
+
+func TestConfig_New(t *testing.T) {
+	t.Parallel()
+	t.Run("builds a validated config from options", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := New(WithPrivateKey(privateKey), WithViewSize(10), WithAlphaBetaGamma(.4, .4, .2), WithGossipAddress("localhost:9002"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.ViewSize != 10 || cfg.Alpha != .4 || cfg.GossipAddress != "localhost:9002" || cfg.SamplerSize != defaultConfig.SamplerSize {
+			t.Errorf("unexpected config values: %s", cfg.String())
+		}
+		if cfg.HostkeysPath != "" {
+			t.Errorf("expected no hostkeys folder, received %s", cfg.HostkeysPath)
+		}
+	})
+	t.Run("returns error without private key", func(t *testing.T) {
+		_, err := New()
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+	t.Run("returns error on invalid values", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = New(WithPrivateKey(privateKey), WithAlphaBetaGamma(.5, .5, .5))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithRoundsBetweenPings(0))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithSamplerStaleMs(-1))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithPeerFailureThreshold(-1))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithPushChallengeTimeoutMs(0))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithPullTimeoutMs(0))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithProtocolVersion(1))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithProtocolVersion(7))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithKeyWrap("rsa-pkcs1"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithProtocolVersion(3), WithKeyWrap(KeyWrapHybrid), WithHybridPrivateKey(make([]byte, 96)))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithKeyWrap(KeyWrapHybrid))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithPullResponseStrategy("oldest", 0))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithPullResponseStrategy(PullResponseRandom, -1))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithPullResponseExclude(PullResponseExcludeRequester, "strangers"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithSendQueueSize(0))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithSendFailureThreshold(-1))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithSendCircuitCooldownMs(0))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithQuarantineThreshold(-1))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithQuarantineMs(0))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithPeerDBExpiryMs(0))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithParticipation("inbound_only"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithApiValidationPolicy("majority"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithApiNotificationReplay(-1, 1000))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithApiNotificationReplay(16, 0))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithClockSkewWarnMs(-1))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithExpectedIdentity("abcd"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+		_, err = New(WithPrivateKey(privateKey), WithChallengeDifficulty(19), WithChallengeMaxAcceptedDifficulty(18))
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+}
+
+func TestConfig_ReadConfigInclude(t *testing.T) {
+	t.Parallel()
+	t.Run("values of the including file take precedence over included ones", func(t *testing.T) {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "common.ini"), []byte("hostkey = "+writeTestKey(t)+"\n[gossip]\ndegree = 10\nl2 = 10\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodePath := filepath.Join(dir, "node.ini")
+		err = os.WriteFile(nodePath, []byte("include = common.ini\n[gossip]\ndegree = 20\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := ReadConfig(nodePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.ViewSize != 20 || cfg.SamplerSize != 10 {
+			t.Errorf("unexpected values: degree=%d, l2=%d", cfg.ViewSize, cfg.SamplerSize)
+		}
+	})
+	t.Run("returns error on include cycle", func(t *testing.T) {
+		dir := t.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "a.ini"), []byte("include = b.ini\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(dir, "b.ini"), []byte("include = a.ini\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ReadConfig(filepath.Join(dir, "a.ini"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+}
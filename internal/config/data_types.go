@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FloodActionDrop ignores messages of peers exceeding the flood threshold.
+	FloodActionDrop = "drop"
+	// FloodActionDelay holds back messages of peers exceeding the flood threshold until the peer falls below the threshold again.
+	FloodActionDelay = "delay"
+	// FloodActionDeprioritize accepts messages of peers exceeding the flood threshold, but spreads them after all other messages.
+	FloodActionDeprioritize = "deprioritize"
+)
+
+// IsValidFloodAction checks whether action is one of the supported flood actions.
+func IsValidFloodAction(action string) bool {
+	return action == FloodActionDrop || action == FloodActionDelay || action == FloodActionDeprioritize
+}
+
+// DataTypePolicy represents the limits and the spread priority of the messages of a single data type.
+type DataTypePolicy struct {
+	// MaxMessages represents the maximum number of stored messages of the data type, 0 means unlimited.
+	MaxMessages int
+	// MaxBytes represents the maximum total size of the data of stored messages of the data type, 0 means unlimited.
+	MaxBytes int
+	// Priority determines the order in which messages are spread to peers, higher priorities are spread first. Data types without policy have priority 0.
+	Priority int
+	// FloodThreshold represents the maximum number of concurrently stored messages of the data type originating from the same peer.
+	// 0 applies the global message flood threshold to all messages of the peer instead.
+	FloodThreshold int
+	// FloodAction represents the handling of messages exceeding the flood threshold, one of drop, delay, and deprioritize. Empty uses the global message flood action.
+	FloodAction string
+	// ExpiryMs represents the time in milliseconds after which messages of the data type stop being spread, regardless of their hop TTL. 0 uses the global message expiry.
+	ExpiryMs int
+}
+
+// ParseDataTypePolicies parses policies of the form <type1>,<max_messages1>,<max_bytes1>,<priority1>[,<flood_threshold1>,<flood_action1>[,<expiry_ms1>]]|<type2>,...
+func ParseDataTypePolicies(policiesStr string) (map[uint16]DataTypePolicy, error) {
+	policies := make(map[uint16]DataTypePolicy)
+	for _, policyStr := range strings.Split(policiesStr, "|") {
+		policyStr = strings.TrimSpace(policyStr)
+		if policyStr == "" {
+			continue
+		}
+		parts := strings.Split(policyStr, ",")
+		if len(parts) != 4 && len(parts) != 6 && len(parts) != 7 {
+			return nil, fmt.Errorf("data type policy must be of the form <type>,<max_messages>,<max_bytes>,<priority>[,<flood_threshold>,<flood_action>[,<expiry_ms>]]: received %s", policyStr)
+		}
+		values := make([]int, 7)
+		for i, part := range parts {
+			// the optional flood action is the only non-numeric value
+			if i == 5 {
+				continue
+			}
+			value, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("invalid value within data type policy %s: %w", policyStr, err)
+			}
+			values[i] = value
+		}
+		if values[0] < 0 || values[0] > 65535 {
+			return nil, fmt.Errorf("data type must be between 0 and 65535: received %d", values[0])
+		}
+		if values[1] < 0 || values[2] < 0 || values[4] < 0 || values[6] < 0 {
+			return nil, fmt.Errorf("limits of data type %d must not be negative", values[0])
+		}
+		var floodAction string
+		if len(parts) >= 6 {
+			floodAction = strings.TrimSpace(parts[5])
+			if floodAction != "" && !IsValidFloodAction(floodAction) {
+				return nil, fmt.Errorf("flood action of data type %d must be one of drop, delay, and deprioritize: received %s", values[0], floodAction)
+			}
+		}
+		dataType := uint16(values[0])
+		if _, ok := policies[dataType]; ok {
+			return nil, fmt.Errorf("duplicate policy for data type %d", dataType)
+		}
+		policies[dataType] = DataTypePolicy{MaxMessages: values[1], MaxBytes: values[2], Priority: values[3], FloodThreshold: values[4], FloodAction: floodAction, ExpiryMs: values[6]}
+	}
+	return policies, nil
+}
+
+// FormatDataTypePolicies formats policies in the form read by ParseDataTypePolicies, ordered by data type.
+func FormatDataTypePolicies(policies map[uint16]DataTypePolicy) string {
+	dataTypes := make([]int, 0, len(policies))
+	for dataType := range policies {
+		dataTypes = append(dataTypes, int(dataType))
+	}
+	sort.Ints(dataTypes)
+	parts := make([]string, 0, len(dataTypes))
+	for _, dataType := range dataTypes {
+		policy := policies[uint16(dataType)]
+		part := fmt.Sprintf("%d,%d,%d,%d", dataType, policy.MaxMessages, policy.MaxBytes, policy.Priority)
+		if policy.FloodThreshold != 0 || policy.FloodAction != "" || policy.ExpiryMs != 0 {
+			part += fmt.Sprintf(",%d,%s", policy.FloodThreshold, policy.FloodAction)
+		}
+		if policy.ExpiryMs != 0 {
+			part += fmt.Sprintf(",%d", policy.ExpiryMs)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "|")
+}
+
+// TTLPolicy represents the hop TTLs enforced on the messages of a single data type announced by local API clients.
+type TTLPolicy struct {
+	// MaxTTL represents the maximum hop TTL of announced messages, larger and infinite TTLs are lowered to it. 0 means unlimited.
+	MaxTTL int
+	// DefaultTTL represents the hop TTL replacing the infinite TTL 0 of announced messages. 0 keeps the infinite TTL, unless limited by MaxTTL.
+	DefaultTTL int
+}
+
+// Apply returns the hop TTL of an announced message after enforcing the policy.
+func (p TTLPolicy) Apply(ttl uint8) uint8 {
+	if ttl == 0 {
+		ttl = uint8(p.DefaultTTL)
+	}
+	if p.MaxTTL > 0 && (ttl == 0 || int(ttl) > p.MaxTTL) {
+		ttl = uint8(p.MaxTTL)
+	}
+	return ttl
+}
+
+// ParseTTLPolicies parses policies of the form <type1>,<max_ttl1>,<default_ttl1>|<type2>,...
+func ParseTTLPolicies(policiesStr string) (map[uint16]TTLPolicy, error) {
+	policies := make(map[uint16]TTLPolicy)
+	for _, policyStr := range strings.Split(policiesStr, "|") {
+		policyStr = strings.TrimSpace(policyStr)
+		if policyStr == "" {
+			continue
+		}
+		parts := strings.Split(policyStr, ",")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("ttl policy must be of the form <type>,<max_ttl>,<default_ttl>: received %s", policyStr)
+		}
+		values := make([]int, 3)
+		for i, part := range parts {
+			value, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("invalid value within ttl policy %s: %w", policyStr, err)
+			}
+			values[i] = value
+		}
+		if values[0] < 0 || values[0] > 65535 {
+			return nil, fmt.Errorf("data type must be between 0 and 65535: received %d", values[0])
+		}
+		if values[1] < 0 || values[1] > 255 || values[2] < 0 || values[2] > 255 {
+			return nil, fmt.Errorf("ttls of data type %d must be between 0 and 255", values[0])
+		}
+		if values[1] > 0 && values[2] > values[1] {
+			return nil, fmt.Errorf("default ttl of data type %d must not exceed its max ttl of %d: received %d", values[0], values[1], values[2])
+		}
+		dataType := uint16(values[0])
+		if _, ok := policies[dataType]; ok {
+			return nil, fmt.Errorf("duplicate ttl policy for data type %d", dataType)
+		}
+		policies[dataType] = TTLPolicy{MaxTTL: values[1], DefaultTTL: values[2]}
+	}
+	return policies, nil
+}
+
+// FormatTTLPolicies formats policies in the form read by ParseTTLPolicies, ordered by data type.
+func FormatTTLPolicies(policies map[uint16]TTLPolicy) string {
+	dataTypes := make([]int, 0, len(policies))
+	for dataType := range policies {
+		dataTypes = append(dataTypes, int(dataType))
+	}
+	sort.Ints(dataTypes)
+	parts := make([]string, 0, len(dataTypes))
+	for _, dataType := range dataTypes {
+		policy := policies[uint16(dataType)]
+		parts = append(parts, fmt.Sprintf("%d,%d,%d", dataType, policy.MaxTTL, policy.DefaultTTL))
+	}
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestConfig_ParseDataTypePolicies(t *testing.T) {
+	t.Parallel()
+	t.Run("parses and formats policies", func(t *testing.T) {
+		policies, err := ParseDataTypePolicies("2, 500, 0, 0| 1,100,65536,10|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(policies) != 2 || policies[1] != (DataTypePolicy{MaxMessages: 100, MaxBytes: 65536, Priority: 10}) || policies[2].MaxMessages != 500 {
+			t.Errorf("unexpected policies: %v", policies)
+		}
+		if formatted := FormatDataTypePolicies(policies); formatted != "1,100,65536,10|2,500,0,0" {
+			t.Errorf("unexpected formatted policies: %s", formatted)
+		}
+	})
+	t.Run("parses and formats flood handling", func(t *testing.T) {
+		policies, err := ParseDataTypePolicies("1,100,0,0,20,delay|2,0,0,0,0,")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if policies[1].FloodThreshold != 20 || policies[1].FloodAction != FloodActionDelay || policies[2] != (DataTypePolicy{}) {
+			t.Errorf("unexpected policies: %v", policies)
+		}
+		if formatted := FormatDataTypePolicies(policies); formatted != "1,100,0,0,20,delay|2,0,0,0" {
+			t.Errorf("unexpected formatted policies: %s", formatted)
+		}
+	})
+	t.Run("parses and formats expiry", func(t *testing.T) {
+		policies, err := ParseDataTypePolicies("1,0,0,0,0,,60000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if policies[1] != (DataTypePolicy{ExpiryMs: 60000}) {
+			t.Errorf("unexpected policies: %v", policies)
+		}
+		if formatted := FormatDataTypePolicies(policies); formatted != "1,0,0,0,0,,60000" {
+			t.Errorf("unexpected formatted policies: %s", formatted)
+		}
+	})
+	t.Run("returns error on malformed policies", func(t *testing.T) {
+		for _, policiesStr := range []string{"1,100,0", "1,a,0,0", "70000,1,1,1", "1,-1,0,0", "1,1,1,1|1,2,2,2", "1,1,1,1,1", "1,1,1,1,-1,drop", "1,1,1,1,1,ignore", "1,1,1,1,1,drop,-1", "1,1,1,1,1,drop,1,1"} {
+			_, err := ParseDataTypePolicies(policiesStr)
+			if err == nil {
+				t.Errorf("expecting error for %s", policiesStr)
+			}
+		}
+	})
+}
+
+func TestConfig_ParseTTLPolicies(t *testing.T) {
+	t.Parallel()
+	t.Run("parses and formats policies", func(t *testing.T) {
+		policies, err := ParseTTLPolicies("2, 0, 10| 1,8,5|")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(policies) != 2 || policies[1] != (TTLPolicy{MaxTTL: 8, DefaultTTL: 5}) || policies[2] != (TTLPolicy{DefaultTTL: 10}) {
+			t.Errorf("unexpected policies: %v", policies)
+		}
+		if formatted := FormatTTLPolicies(policies); formatted != "1,8,5|2,0,10" {
+			t.Errorf("unexpected formatted policies: %s", formatted)
+		}
+	})
+	t.Run("returns error on malformed policies", func(t *testing.T) {
+		for _, policiesStr := range []string{"1,8", "1,a,5", "70000,8,5", "1,-1,0", "1,256,0", "1,8,9", "1,8,5|1,4,2"} {
+			_, err := ParseTTLPolicies(policiesStr)
+			if err == nil {
+				t.Errorf("expecting error for %s", policiesStr)
+			}
+		}
+	})
+}
+
+func TestTTLPolicy_Apply(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		policy   TTLPolicy
+		ttl      uint8
+		expected uint8
+	}{
+		{TTLPolicy{}, 0, 0},
+		{TTLPolicy{DefaultTTL: 5}, 0, 5},
+		{TTLPolicy{DefaultTTL: 5}, 12, 12},
+		{TTLPolicy{MaxTTL: 8}, 0, 8},
+		{TTLPolicy{MaxTTL: 8}, 20, 8},
+		{TTLPolicy{MaxTTL: 8, DefaultTTL: 5}, 0, 5},
+		{TTLPolicy{MaxTTL: 8, DefaultTTL: 5}, 3, 3},
+	} {
+		if ttl := tc.policy.Apply(tc.ttl); ttl != tc.expected {
+			t.Errorf("expected %+v to enforce a ttl of %d on %d, received %d", tc.policy, tc.expected, tc.ttl, ttl)
+		}
+	}
+}
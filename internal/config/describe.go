@@ -0,0 +1,235 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// envPrefix represents the prefix of environment variables overriding configuration keys, e.g. GOSSIP_DEGREE=20.
+const envPrefix = "GOSSIP_"
+
+var (
+	ErrUnknownKey = errors.New("unknown configuration key")
+)
+
+// keyDescription documents a single configuration key.
+type keyDescription struct {
+	name        string
+	description string
+	// value returns the value of the key within the given config.
+	value func(cfg *GossipConfig) string
+}
+
+// rootKeys contains all keys of the root section of the configuration file.
+var rootKeys = []keyDescription{
+	{"hostkey", "Path to the PEM file containing the RSA private key of this node, followed by its hybrid private key if the hybrid key wrap is used.", func(cfg *GossipConfig) string { return cfg.PrivateKeyPath }},
+	{"identity", "Expected hex-encoded identity of the hostkey, the node refuses to start if the hostkey derives a different one. Empty skips the check.", func(cfg *GossipConfig) string { return cfg.ExpectedIdentity }},
+}
+
+// gossipKeys contains all keys of the [gossip] section of the configuration file.
+var gossipKeys = []keyDescription{
+	{"degree", "Size of the main view.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ViewSize) }},
+	{"l2", "Number of samplers.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.SamplerSize) }},
+	{"push_pull_view_max_size", "Maximum number of nodes collected within the push and pull views during a round, must be greater than degree.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.PushPullViewMaxSize) }},
+	{"weight_push", "Weight of pushed nodes in the next view, all weights must add up to 100.", func(cfg *GossipConfig) string { return weightStr(cfg.Alpha) }},
+	{"weight_pull", "Weight of pulled nodes in the next view, all weights must add up to 100.", func(cfg *GossipConfig) string { return weightStr(cfg.Beta) }},
+	{"weight_history", "Weight of sampled nodes in the next view, all weights must add up to 100.", func(cfg *GossipConfig) string { return weightStr(cfg.Gamma) }},
+	{"alpha", "Fraction of pushed nodes in the next view, takes precedence over the weights if alpha, beta, and gamma are set.", func(cfg *GossipConfig) string { return floatStr(cfg.Alpha) }},
+	{"beta", "Fraction of pulled nodes in the next view, alpha, beta, and gamma must add up to 1.0.", func(cfg *GossipConfig) string { return floatStr(cfg.Beta) }},
+	{"gamma", "Fraction of sampled nodes in the next view, alpha, beta, and gamma must add up to 1.0.", func(cfg *GossipConfig) string { return floatStr(cfg.Gamma) }},
+	{"bootstrap_nodes", "Bootstrap nodes in the form <id1>,<addr1>|<id2>,<addr2>|...", func(cfg *GossipConfig) string { return cfg.BootstrapNodesStr }},
+	{"participation", "Participation in the gossip protocol, full or outbound_only, which pulls views and receives messages but never answers pull requests, issues push challenges, or pushes, and announces its messages to the peers it pulls from.", func(cfg *GossipConfig) string { return cfg.Participation }},
+	{"pinned_nodes", "Nodes always kept in the main view and always offered in pull responses, in the form of bootstrap_nodes.", func(cfg *GossipConfig) string { return cfg.PinnedNodesStr }},
+	{"bootstrap_nodes_file", "File containing one <id>,<addr> bootstrap node per line.", func(cfg *GossipConfig) string { return cfg.BootstrapNodesFile }},
+	{"bootstrap_nodes_file_reload_rounds", "Number of rounds in between checking the bootstrap nodes file for new nodes, 0 disables reloading.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.BootstrapNodesFileReloadRounds) }},
+	{"round_interval_ms", "Time a round waits for the responses to its push and pull requests before computing the next view.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.RoundIntervalMs) }},
+	{"rounds_between_pings", "Number of rounds in between health-checks of each sampled node.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.RoundsBetweenPings) }},
+	{"ping_timeout_ms", "Time to wait for a pong before a sampled node is considered offline.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.PingTimeoutMs) }},
+	{"push_challenge_timeout_ms", "Time within which a peer must answer our push request with a challenge, later challenges are ignored.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.PushChallengeTimeoutMs) }},
+	{"pull_timeout_ms", "Time within which a peer must answer our pull request, later responses are ignored.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.PullTimeoutMs) }},
+	{"pull_response_strategy", "Selection of the main view nodes pull requests are answered with: all, a random subset per request, or the freshest nodes first.", func(cfg *GossipConfig) string { return cfg.PullResponseStrategy }},
+	{"pull_response_size", "Number of nodes the random and freshest strategies answer pull requests with, 0 answers with all nodes.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.PullResponseSize) }},
+	{"pull_response_exclude", "Comma-separated nodes left out of pull responses, any of requester and unverified, i.e. nodes that never contacted us.", func(cfg *GossipConfig) string { return strings.Join(cfg.PullResponseExclude, ",") }},
+	{"protocol_version", "Version of the wire format spoken with peers, 2 signs then encrypts packets, 3 encrypts then signs them, 4 additionally allows the hybrid key wrap, 5 additionally advertises the capabilities of nodes, 6 additionally carries the time messages were announced at by their origin.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ProtocolVersion) }},
+	{"key_wrap", "Wrap of the packet keys for the receiver, rsa-oaep or hybrid, which combines X25519 and ML-KEM-768 and requires protocol version 4 or later and a hybrid private key within the hostkey file.", func(cfg *GossipConfig) string { return cfg.KeyWrap }},
+	{"sampler_stale_ms", "Time after which a sampler whose node was neither captured nor validated by a ping is reinitialized, 0 disables aging.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.SamplerStaleMs) }},
+	{"peer_failure_threshold", "Number of consecutive rounds in which a peer fails to answer our pings and pull requests after which it is evicted from the main view, 0 disables eviction.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.PeerFailureThreshold) }},
+	{"sampler_hash", "Min-wise independent hash function of the samplers, one of sha256, blake3, and siphash.", func(cfg *GossipConfig) string { return cfg.SamplerHash }},
+	{"sampler_state_file", "File the sampler state is persisted to after every round and restored from on start, empty disables persistence.", func(cfg *GossipConfig) string { return cfg.SamplerStateFile }},
+	{"peer_db_file", "File the database of all peers that ever responded is persisted to after every round and restored from on start, empty disables the database.", func(cfg *GossipConfig) string { return cfg.PeerDBFile }},
+	{"peer_db_expiry_ms", "Time after which a peer that did not respond is forgotten by the peer database.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.PeerDBExpiryMs) }},
+	{"api_address", "TCP address of the API server.", func(cfg *GossipConfig) string { return cfg.ApiAddress }},
+	{"api_notification_history_size", "Number of most recently notified messages remembered per data type to never notify the subscribers of a data type of the same message twice, 0 disables the suppression.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ApiNotificationHistorySize) }},
+	{"api_notification_replay_size", "Number of most recent notifications buffered per data type and sent to clients subscribing with the replay flag, 0 disables the replay.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ApiNotificationReplaySize) }},
+	{"api_notification_replay_ms", "Time a notification is buffered for clients subscribing with the replay flag.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ApiNotificationReplayMs) }},
+	{"api_validation_policy", "Combination of the verdicts of several API clients notified of the same message, one of first_wins, all, and quorum.", func(cfg *GossipConfig) string { return cfg.ApiValidationPolicy }},
+	{"api_ttl_policies", "Maximum and default hop TTL of messages announced by API clients per data type in the form <type1>,<max_ttl1>,<default_ttl1>|... Larger and infinite TTLs are lowered to the maximum, the default replaces the infinite TTL 0, 0 disables either.", func(cfg *GossipConfig) string { return FormatTTLPolicies(cfg.ApiTTLPolicies) }},
+	{"debug_address", "TCP address of the HTTP listener exposing pprof profiles and expvar counters, empty disables the listener.", func(cfg *GossipConfig) string { return cfg.DebugAddress }},
+	{"state_dump_file", "File the runtime state of the node is dumped to as JSON upon receiving SIGUSR1, empty disables state dumps.", func(cfg *GossipConfig) string { return cfg.StateDumpFile }},
+	{"health_address", "TCP address of the HTTP listener serving the /healthz and /readyz probes, empty disables the listener.", func(cfg *GossipConfig) string { return cfg.HealthAddress }},
+	{"latency_window_size", "Number of most recent round trip times of pings and pull requests kept per peer.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.LatencyWindowSize) }},
+	{"readiness_peer_window_ms", "Time in milliseconds within which a valid packet must have been received from any peer for the node to be ready.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ReadinessPeerWindowMs) }},
+	{"gossip_address", "UDP address of the gossip server.", func(cfg *GossipConfig) string { return cfg.GossipAddress }},
+	{"dns_refresh_interval_ms", "Time after which hostnames within peer addresses are resolved again, 0 resolves them on every send.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.DNSRefreshIntervalMs) }},
+	{"hostkeys_path", "Directory containing the public keys of all peers, named by their hex-encoded identity.", func(cfg *GossipConfig) string { return cfg.HostkeysPath }},
+	{"challenge_difficulty", "Number of leading zero bits required in push challenge solutions.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ChallengeDifficulty) }},
+	{"challenge_max_solve_ms", "Maximum time spent on solving a push challenge received from a peer.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ChallengeMaxSolveMs) }},
+	{"challenge_max_accepted_difficulty", "Maximum number of leading zero bits a peer may demand in the solution of its push challenge, harder challenges are refused, 0 accepts any difficulty.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ChallengeMaxAcceptedDifficulty) }},
+	{"challenge_rotation_interval_ms", "Interval in which the key used to generate push challenges is rotated.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ChallengeRotationIntervalMs) }},
+	{"challenge_rotation_keys", "Number of most recent challenge keys for which solutions are still accepted.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ChallengeRotationKeys) }},
+	{"message_local_ttl_floor", "Local TTL at which a message that is no longer spread is evicted from the message cache.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.MessageLocalTTLFloor) }},
+	{"message_flood_threshold", "Maximum number of concurrently stored messages originating from the same peer.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.MessageFloodThreshold) }},
+	{"message_flood_action", "Handling of messages from peers exceeding the flood threshold, one of drop, delay, and deprioritize.", func(cfg *GossipConfig) string { return cfg.MessageFloodAction }},
+	{"max_message_data_size", "Maximum size in bytes of the data of a gossip message.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.MaxMessageDataSize) }},
+	{"infinite_ttl_max_rounds", "Number of rounds messages with infinite hops (TTL 0) are spread by this peer.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.InfiniteTTLMaxRounds) }},
+	{"infinite_ttl_max_age_ms", "Time after which messages with infinite hops are evicted from the message cache, 0 disables the expiry.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.InfiniteTTLMaxAgeMs) }},
+	{"infinite_ttl_max_store_percent", "Maximum share of message_store_max_messages in percent that messages with infinite hops may occupy.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.InfiniteTTLMaxStorePercent) }},
+	{"message_expiry_ms", "Time after its origin announced a message at which it stops being spread regardless of its hop TTL, counted from the first receipt before protocol version 6, 0 disables the expiry.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.MessageExpiryMs) }},
+	{"message_store_max_messages", "Maximum number of messages within the message cache, messages that are no longer spread are evicted first.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.MessageStoreMaxMessages) }},
+	{"message_store_max_bytes", "Maximum total size in bytes of the data of all messages within the message cache.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.MessageStoreMaxBytes) }},
+	{"message_batch_max_size", "Maximum size in bytes of a packet combining several gossip messages sent to the same peer, 0 sends each message in a separate packet.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.MessageBatchMaxSize) }},
+	{"send_workers", "Number of workers signing, encrypting, and sending outbound packets, 0 starts one worker per CPU.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.SendWorkers) }},
+	{"send_queue_size", "Number of outbound packets queued for the send workers, further packets wait until the queue has room.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.SendQueueSize) }},
+	{"send_failure_threshold", "Number of consecutive failures to resolve or write to an address after which no packets are sent to it for send_circuit_cooldown_ms, 0 disables the circuit breaker.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.SendFailureThreshold) }},
+	{"send_circuit_cooldown_ms", "Time the circuit of an unreachable address stays open before a single packet probes the address again.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.SendCircuitCooldownMs) }},
+	{"quarantine_threshold", "Number of packets with invalid signatures or failed push challenges from an address after which its packets are dropped before decryption for quarantine_ms, 0 disables the quarantine.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.QuarantineThreshold) }},
+	{"quarantine_ms", "Time the packets of a quarantined address are dropped, failures further apart are not counted as repeated.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.QuarantineMs) }},
+	{"clock_skew_warn_ms", "Estimated offset between the local clock and the clocks of the peers beyond which a warning is logged and the clock_skew_warning gauge is set, 0 disables the warning.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.ClockSkewWarnMs) }},
+	{"data_type_policies", "Storage limits, spread priority, and optionally flood handling and expiry per data type in the form <type1>,<max_messages1>,<max_bytes1>,<priority1>[,<flood_threshold1>,<flood_action1>[,<expiry_ms1>]]|... A limit of 0 means unlimited, higher priorities are spread first.", func(cfg *GossipConfig) string { return FormatDataTypePolicies(cfg.DataTypePolicies) }},
+	{"message_store_file", "File the message cache is persisted to after every round and restored from on start, empty disables persistence.", func(cfg *GossipConfig) string { return cfg.MessageStoreFile }},
+	{"seen_filter_capacity", "Number of accepted messages per generation of the filter remembering messages after their eviction from the message cache, 0 disables the filter.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.SeenFilterCapacity) }},
+	{"anti_entropy_interval_rounds", "Number of rounds in between reconciling the message cache with a random peer of the view, 0 disables the reconciliation.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.AntiEntropyIntervalRounds) }},
+	{"eager_fanout", "Number of random peers of the view receiving full messages per round, all other peers only receive announcements and request missing messages on demand. 0 sends full messages to all peers.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.EagerFanout) }},
+	{"message_fanout", "Number of random peers of the view the spreadable messages are announced to per round, independent of push and pull requests. 0 only spreads messages along push and pull requests.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.MessageFanout) }},
+	{"high_priority_lane_budget", "Maximum number of high-priority messages sent or announced to a peer at once, 0 means unlimited.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.HighPriorityLaneBudget) }},
+	{"bulk_lane_budget", "Maximum number of bulk messages sent or announced to a peer at once, 0 means unlimited.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.BulkLaneBudget) }},
+	{"adversary", "Deliberately misbehave to test the Byzantine resistance of the peers, one of push_flood, pull_poison, message_spam, and silent. Empty behaves honestly, only builds with the adversary tag support it.", func(cfg *GossipConfig) string { return cfg.Adversary }},
+	{"log_level", "Global log level (debug, info, warn, error).", func(cfg *GossipConfig) string { return cfg.LogLevel }},
+	{"log_encoding", "Encoding of log entries, either json or console.", func(cfg *GossipConfig) string { return cfg.LogEncoding }},
+	{"log_level_gossip", "Log level of the gossip subsystem, empty to use the global log level.", func(cfg *GossipConfig) string { return cfg.SubsystemLogLevels["gossip"] }},
+	{"log_level_api", "Log level of the api subsystem, empty to use the global log level.", func(cfg *GossipConfig) string { return cfg.SubsystemLogLevels["api"] }},
+	{"log_level_challenge", "Log level of the challenge subsystem, empty to use the global log level.", func(cfg *GossipConfig) string { return cfg.SubsystemLogLevels["challenge"] }},
+	{"log_level_crypto", "Log level of the crypto subsystem, empty to use the global log level.", func(cfg *GossipConfig) string { return cfg.SubsystemLogLevels["crypto"] }},
+	{"log_file", "File logs are written to instead of stderr.", func(cfg *GossipConfig) string { return cfg.LogFile }},
+	{"log_file_max_size_mb", "Size in megabytes at which the log file is rotated.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.LogFileMaxSizeMB) }},
+	{"log_file_max_backups", "Number of rotated log files that are kept.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.LogFileMaxBackups) }},
+	{"log_file_max_age_days", "Number of days after which rotated log files are deleted, 0 keeps them regardless of their age.", func(cfg *GossipConfig) string { return strconv.Itoa(cfg.LogFileMaxAgeDays) }},
+}
+
+// optionalKeys contains the names of keys that are commented out within the generated default configuration.
+var optionalKeys = map[string]struct{}{"alpha": {}, "beta": {}, "gamma": {}}
+
+// allKeys returns the descriptions of the keys of all sections.
+func allKeys() []keyDescription {
+	keys := make([]keyDescription, 0, len(rootKeys)+len(gossipKeys))
+	keys = append(keys, rootKeys...)
+	return append(keys, gossipKeys...)
+}
+
+// ChangedKeys returns the names of all documented keys whose value differs between the given configurations.
+func ChangedKeys(old, new *GossipConfig) []string {
+	var changed []string
+	for _, key := range allKeys() {
+		if key.value(old) != key.value(new) {
+			changed = append(changed, key.name)
+		}
+	}
+	return changed
+}
+
+// weightStr converts a weight fraction back into its integer percentage representation.
+func weightStr(fraction float64) string {
+	return strconv.Itoa(int(math.Round(fraction * 100)))
+}
+
+// floatStr converts a float into its shortest string representation.
+func floatStr(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// WriteDefaultConfig writes a fully commented configuration file containing the default values of all keys.
+func WriteDefaultConfig(w io.Writer) error {
+	defaults := defaultConfig
+	defaults.PrivateKeyPath = "hostkey.pem"
+	return writeConfig(w, &defaults, "Default configuration of the gossip module", true)
+}
+
+// WriteEffectiveConfig writes the given configuration in the format of a configuration file.
+// The private key itself is never written, only the path it was read from.
+func WriteEffectiveConfig(w io.Writer, cfg *GossipConfig) error {
+	return writeConfig(w, cfg, "Effective configuration of the gossip module (file + environment + flags), secrets are redacted", false)
+}
+
+// writeConfig writes all documented keys with their value within the given config, optional keys are commented out if requested.
+func writeConfig(w io.Writer, cfg *GossipConfig, title string, commentOptional bool) error {
+	var sb strings.Builder
+	sb.WriteString("# " + title + "\n\n")
+	writeKey := func(key keyDescription) {
+		prefix := ""
+		if _, optional := optionalKeys[key.name]; optional && commentOptional {
+			prefix = "# "
+		}
+		sb.WriteString(fmt.Sprintf("# %s\n%s%s = %s\n\n", key.description, prefix, key.name, key.value(cfg)))
+	}
+	for _, key := range rootKeys {
+		writeKey(key)
+	}
+	sb.WriteString("[gossip]\n")
+	for _, key := range gossipKeys {
+		writeKey(key)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// String returns a single-line representation of all documented keys within the config, the private key is never included.
+func (cfg *GossipConfig) String() string {
+	var parts []string
+	for _, key := range allKeys() {
+		parts = append(parts, key.name+"="+key.value(cfg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// envOverrides collects the values of all environment variables named GOSSIP_<KEY> for known configuration keys.
+func envOverrides() map[string]string {
+	overrides := make(map[string]string)
+	for _, key := range allKeys() {
+		if val, ok := os.LookupEnv(envPrefix + strings.ToUpper(key.name)); ok {
+			overrides[key.name] = val
+		}
+	}
+	return overrides
+}
+
+// applyOverrides sets the given key-value pairs within their respective section of the loaded configuration file.
+func applyOverrides(iniData *ini.File, overrides map[string]string) error {
+	for name, value := range overrides {
+		switch {
+		case isKnownKey(rootKeys, name):
+			iniData.Section("").Key(name).SetValue(value)
+		case isKnownKey(gossipKeys, name):
+			iniData.Section("gossip").Key(name).SetValue(value)
+		default:
+			return fmt.Errorf("%w: %s", ErrUnknownKey, name)
+		}
+	}
+	return nil
+}
+
+// isKnownKey checks whether a key with the given name is contained in keys.
+func isKnownKey(keys []keyDescription, name string) bool {
+	for _, key := range keys {
+		if key.name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package config
+
+const (
+	// KeyWrapRSAOAEP wraps the packet keys with RSA-OAEP for the RSA public key of the receiver.
+	KeyWrapRSAOAEP = "rsa-oaep"
+	// KeyWrapHybrid wraps the packet keys with the hybrid X25519 and ML-KEM-768 KEM for the hybrid public key of the receiver,
+	// so recorded packets stay confidential even once quantum computers break RSA and X25519. It requires protocol version 4.
+	KeyWrapHybrid = "hybrid"
+)
+
+// IsValidKeyWrap checks whether keyWrap is one of the supported key wraps.
+func IsValidKeyWrap(keyWrap string) bool {
+	return keyWrap == KeyWrapRSAOAEP || keyWrap == KeyWrapHybrid
+}
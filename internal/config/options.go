@@ -0,0 +1,377 @@
+package config
+
+import (
+	"crypto/rsa"
+	"gossiphers/internal/clock"
+	"gossiphers/internal/transport"
+	"io"
+)
+
+// Option represents a functional option for the GossipConfig's 'constructor'.
+type Option func(*GossipConfig)
+
+// New returns a validated GossipConfig built from the default values and the given options.
+// In contrast to ReadConfig no files are read, keys are passed in directly. No hostkeys folder is used unless WithHostkeysPath is passed in.
+func New(options ...Option) (*GossipConfig, error) {
+	cfg := defaultConfig
+	cfg.HostkeysPath = ""
+	for _, option := range options {
+		option(&cfg)
+	}
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// WithPrivateKey sets the private key of the node.
+func WithPrivateKey(key *rsa.PrivateKey) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PrivateKey = key
+	}
+}
+
+// WithHybridPrivateKey sets the encoded hybrid private key of the node, which the hybrid key wrap unwraps the packet keys with.
+func WithHybridPrivateKey(key []byte) Option {
+	return func(cfg *GossipConfig) {
+		cfg.HybridPrivateKey = key
+	}
+}
+
+// WithPeerPublicKeys adds public keys of peers the node is able to communicate with.
+func WithPeerPublicKeys(keys ...*rsa.PublicKey) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PeerPublicKeys = append(cfg.PeerPublicKeys, keys...)
+	}
+}
+
+// WithPeerHybridPublicKey adds the encoded hybrid public key of the peer with the given hex-encoded identity, which the hybrid key
+// wrap wraps the packet keys for.
+func WithPeerHybridPublicKey(identity string, key []byte) Option {
+	return func(cfg *GossipConfig) {
+		if cfg.PeerHybridPublicKeys == nil {
+			cfg.PeerHybridPublicKeys = make(map[string][]byte)
+		}
+		cfg.PeerHybridPublicKeys[identity] = key
+	}
+}
+
+// WithExpectedIdentity sets the hex-encoded identity the private key must derive.
+func WithExpectedIdentity(identity string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ExpectedIdentity = identity
+	}
+}
+
+// WithHostkeysPath sets the folder public keys of peers are read from in addition to the ones passed in via WithPeerPublicKeys.
+func WithHostkeysPath(path string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.HostkeysPath = path
+	}
+}
+
+// WithViewSize sets the size of the main view.
+func WithViewSize(size int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ViewSize = size
+	}
+}
+
+// WithSamplerSize sets the number of samplers.
+func WithSamplerSize(size int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.SamplerSize = size
+	}
+}
+
+// WithAlphaBetaGamma sets the fractions of pushed, pulled, and sampled nodes in the next view, which must add up to 1.0.
+func WithAlphaBetaGamma(alpha float64, beta float64, gamma float64) Option {
+	return func(cfg *GossipConfig) {
+		cfg.Alpha = alpha
+		cfg.Beta = beta
+		cfg.Gamma = gamma
+	}
+}
+
+// WithBootstrapNodes sets the bootstrap nodes in the form <id1>,<addr1>|<id2>,<addr2>|...
+func WithBootstrapNodes(nodesStr string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.BootstrapNodesStr = nodesStr
+	}
+}
+
+// WithParticipation sets how the node takes part in the gossip protocol, either full or outbound_only.
+func WithParticipation(participation string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.Participation = participation
+	}
+}
+
+// WithPinnedNodes sets the nodes always kept in the main view and offered in pull responses, in the form <id1>,<addr1>|<id2>,<addr2>|...
+func WithPinnedNodes(nodesStr string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PinnedNodesStr = nodesStr
+	}
+}
+
+// WithApiAddress sets the TCP address of the API server.
+func WithApiAddress(address string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ApiAddress = address
+	}
+}
+
+// WithApiValidationPolicy sets how the verdicts of several API clients notified of the same message are combined.
+func WithApiValidationPolicy(policy string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ApiValidationPolicy = policy
+	}
+}
+
+// WithApiNotificationReplay sets the number of most recent notifications buffered per data type and the time they are buffered
+// for clients subscribing with the replay flag, a size of 0 disables the replay.
+func WithApiNotificationReplay(size int, ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ApiNotificationReplaySize = size
+		cfg.ApiNotificationReplayMs = ms
+	}
+}
+
+// WithGossipAddress sets the UDP address of the gossip server.
+func WithGossipAddress(address string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.GossipAddress = address
+	}
+}
+
+// WithChallengeDifficulty sets the number of leading zero bits required in push challenge solutions.
+func WithChallengeDifficulty(difficulty int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ChallengeDifficulty = difficulty
+	}
+}
+
+// WithChallengeMaxSolveMs sets the maximum time spent on solving a push challenge received from a peer.
+func WithChallengeMaxSolveMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ChallengeMaxSolveMs = ms
+	}
+}
+
+// WithChallengeMaxAcceptedDifficulty sets the maximum number of leading zero bits a peer may demand in the solution of its push challenge.
+func WithChallengeMaxAcceptedDifficulty(difficulty int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ChallengeMaxAcceptedDifficulty = difficulty
+	}
+}
+
+// WithRoundsBetweenPings sets the number of rounds in between health-checking the sampled nodes.
+func WithRoundsBetweenPings(rounds int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.RoundsBetweenPings = rounds
+	}
+}
+
+// WithPingTimeoutMs sets the time to wait for a pong before a sampled node is considered offline.
+func WithPingTimeoutMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PingTimeoutMs = ms
+	}
+}
+
+// WithPushChallengeTimeoutMs sets the time within which a peer must answer our push request with a challenge.
+func WithPushChallengeTimeoutMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PushChallengeTimeoutMs = ms
+	}
+}
+
+// WithPullTimeoutMs sets the time within which a peer must answer our pull request.
+func WithPullTimeoutMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PullTimeoutMs = ms
+	}
+}
+
+// WithPullResponseStrategy sets the selection of the nodes pull requests are answered with, one of all, random, and freshest.
+func WithPullResponseStrategy(strategy string, size int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PullResponseStrategy = strategy
+		cfg.PullResponseSize = size
+	}
+}
+
+// WithPullResponseExclude sets the nodes left out of pull responses, any of requester and unverified.
+func WithPullResponseExclude(exclusions ...string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PullResponseExclude = exclusions
+	}
+}
+
+// WithMessageExpiryMs sets the time after its origin announced a message at which it stops being spread.
+func WithMessageExpiryMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.MessageExpiryMs = ms
+	}
+}
+
+// WithProtocolVersion sets the version of the wire format spoken with peers.
+func WithProtocolVersion(version int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ProtocolVersion = version
+	}
+}
+
+// WithKeyWrap sets how the packet keys are wrapped for the receiver, either rsa-oaep or hybrid.
+func WithKeyWrap(keyWrap string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.KeyWrap = keyWrap
+	}
+}
+
+// WithSamplerStaleMs sets the time after which a sampler whose node was not validated is reinitialized.
+func WithSamplerStaleMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.SamplerStaleMs = ms
+	}
+}
+
+// WithPeerFailureThreshold sets the number of consecutive rounds without a response after which a peer is evicted from the main view.
+func WithPeerFailureThreshold(rounds int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PeerFailureThreshold = rounds
+	}
+}
+
+// WithSamplerStateFile sets the file the sampler state is persisted to and restored from.
+func WithSamplerStateFile(path string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.SamplerStateFile = path
+	}
+}
+
+// WithPeerDBFile sets the file the peer database is persisted to and restored from.
+func WithPeerDBFile(path string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PeerDBFile = path
+	}
+}
+
+// WithPeerDBExpiryMs sets the time after which a peer that did not respond is forgotten by the peer database.
+func WithPeerDBExpiryMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.PeerDBExpiryMs = ms
+	}
+}
+
+// WithSamplerHash sets the min-wise independent hash function of the samplers, one of sha256, blake3, and siphash.
+func WithSamplerHash(name string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.SamplerHash = name
+	}
+}
+
+// WithMessageStoreFile sets the file the message cache is persisted to and restored from.
+func WithMessageStoreFile(path string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.MessageStoreFile = path
+	}
+}
+
+// WithApiTTLPolicy sets the hop TTLs enforced on the messages of a data type announced by API clients.
+func WithApiTTLPolicy(dataType uint16, policy TTLPolicy) Option {
+	return func(cfg *GossipConfig) {
+		if cfg.ApiTTLPolicies == nil {
+			cfg.ApiTTLPolicies = make(map[uint16]TTLPolicy)
+		}
+		cfg.ApiTTLPolicies[dataType] = policy
+	}
+}
+
+// WithDataTypePolicy sets the storage limits and spread priority of a data type.
+func WithDataTypePolicy(dataType uint16, policy DataTypePolicy) Option {
+	return func(cfg *GossipConfig) {
+		if cfg.DataTypePolicies == nil {
+			cfg.DataTypePolicies = make(map[uint16]DataTypePolicy)
+		}
+		cfg.DataTypePolicies[dataType] = policy
+	}
+}
+
+// WithSendWorkers sets the number of workers signing, encrypting, and sending outbound packets.
+func WithSendWorkers(workers int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.SendWorkers = workers
+	}
+}
+
+// WithSendQueueSize sets the number of outbound packets queued for the send workers.
+func WithSendQueueSize(size int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.SendQueueSize = size
+	}
+}
+
+// WithSendFailureThreshold sets the number of consecutive send failures after which the circuit of an address opens.
+func WithSendFailureThreshold(failures int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.SendFailureThreshold = failures
+	}
+}
+
+// WithSendCircuitCooldownMs sets the time the circuit of an unreachable address stays open.
+func WithSendCircuitCooldownMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.SendCircuitCooldownMs = ms
+	}
+}
+
+// WithQuarantineThreshold sets the number of invalid signatures or failed challenges after which an address is quarantined.
+func WithQuarantineThreshold(failures int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.QuarantineThreshold = failures
+	}
+}
+
+// WithQuarantineMs sets the time the packets of a quarantined address are dropped.
+func WithQuarantineMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.QuarantineMs = ms
+	}
+}
+
+// WithClockSkewWarnMs sets the estimated clock offset to the peers beyond which a warning is raised, 0 disables the warning.
+func WithClockSkewWarnMs(ms int) Option {
+	return func(cfg *GossipConfig) {
+		cfg.ClockSkewWarnMs = ms
+	}
+}
+
+// WithAdversary sets the adversarial behavior of the node, one of push_flood, pull_poison, message_spam, and silent.
+func WithAdversary(behavior string) Option {
+	return func(cfg *GossipConfig) {
+		cfg.Adversary = behavior
+	}
+}
+
+// WithClock sets the clock driving the challenger, the gossip rounds, and the ping timeouts instead of the wall clock.
+func WithClock(c clock.Clock) Option {
+	return func(cfg *GossipConfig) {
+		cfg.Clock = c
+	}
+}
+
+// WithNetwork sets the network the gossip server listens on instead of UDP sockets.
+func WithNetwork(network transport.Network) Option {
+	return func(cfg *GossipConfig) {
+		cfg.Network = network
+	}
+}
+
+// WithRandom sets the source of randomness of the peer selection, the sampler biases, and the challenge keys instead of crypto/rand.
+func WithRandom(random io.Reader) Option {
+	return func(cfg *GossipConfig) {
+		cfg.Random = random
+	}
+}
@@ -0,0 +1,14 @@
+package config
+
+const (
+	// ParticipationFull takes part in the gossip protocol with both incoming and outgoing requests.
+	ParticipationFull = "full"
+	// ParticipationOutboundOnly only sends requests like a client: the node pulls views and receives messages, but never answers
+	// pull requests, never issues push challenges, and never pushes its own identity, e.g. for edge devices behind strict firewalls.
+	ParticipationOutboundOnly = "outbound_only"
+)
+
+// IsValidParticipation checks whether participation is one of the supported participation modes.
+func IsValidParticipation(participation string) bool {
+	return participation == ParticipationFull || participation == ParticipationOutboundOnly
+}
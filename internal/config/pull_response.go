@@ -0,0 +1,40 @@
+package config
+
+import "strings"
+
+const (
+	// PullResponseAll answers pull requests with the whole main view.
+	PullResponseAll = "all"
+	// PullResponseRandom answers each pull request with a different random subset of pull_response_size nodes of the main view.
+	PullResponseRandom = "random"
+	// PullResponseFreshest answers pull requests with the pull_response_size nodes of the main view that contacted us most recently.
+	PullResponseFreshest = "freshest"
+)
+
+const (
+	// PullResponseExcludeRequester leaves the requesting peer out of the pull response, which it already knows.
+	PullResponseExcludeRequester = "requester"
+	// PullResponseExcludeUnverified leaves nodes that never contacted us by a push or a pong out of pull responses.
+	PullResponseExcludeUnverified = "unverified"
+)
+
+// IsValidPullResponseStrategy checks whether strategy is one of the supported pull response strategies.
+func IsValidPullResponseStrategy(strategy string) bool {
+	return strategy == PullResponseAll || strategy == PullResponseRandom || strategy == PullResponseFreshest
+}
+
+// IsValidPullResponseExclusion checks whether exclusion is one of the supported pull response exclusions.
+func IsValidPullResponseExclusion(exclusion string) bool {
+	return exclusion == PullResponseExcludeRequester || exclusion == PullResponseExcludeUnverified
+}
+
+// parseList splits a comma-separated list into its trimmed, non-empty elements.
+func parseList(value string) []string {
+	var elements []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
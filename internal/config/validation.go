@@ -0,0 +1,17 @@
+package config
+
+const (
+	// ValidationPolicyFirstWins accepts the verdict of the first API client validating a notification.
+	ValidationPolicyFirstWins = "first_wins"
+	// ValidationPolicyAll considers a notification valid once every notified API client validated it as valid, and invalid as
+	// soon as any of them validated it as invalid.
+	ValidationPolicyAll = "all"
+	// ValidationPolicyQuorum considers a notification valid once more than half of the notified API clients validated it as
+	// valid, and invalid once that majority cannot be reached anymore.
+	ValidationPolicyQuorum = "quorum"
+)
+
+// IsValidValidationPolicy checks whether policy is one of the supported validation policies.
+func IsValidValidationPolicy(policy string) bool {
+	return policy == ValidationPolicyFirstWins || policy == ValidationPolicyAll || policy == ValidationPolicyQuorum
+}
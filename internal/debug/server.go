@@ -0,0 +1,65 @@
+// Package debug provides an opt-in HTTP endpoint exposing profiles and expvar counters of a running node.
+package debug
+
+import (
+	"errors"
+	"expvar"
+	"gossiphers/internal/logging"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"go.uber.org/zap"
+)
+
+// Server represents the debug HTTP listener serving net/http/pprof below /debug/pprof/ and expvar counters at /debug/vars.
+type Server struct {
+	address  string
+	listener net.Listener
+}
+
+// logger returns the logger of the gossip subsystem, to which the debug endpoint belongs.
+func logger() *zap.Logger {
+	return logging.L(logging.Gossip)
+}
+
+// NewServer returns a new instance of Server listening at the given address once started.
+func NewServer(address string) *Server {
+	return &Server{address: address}
+}
+
+// Start starts listening for HTTP requests.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	logger().Info("Debug Server listening", zap.String("address", listener.Addr().String()))
+	go func() {
+		err := http.Serve(listener, mux)
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			logger().Warn("Debug Server stopped", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the server is listening at, which differs from the configured one if port 0 was given.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops listening for HTTP requests.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
@@ -0,0 +1,43 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestServer_Start(t *testing.T) {
+	t.Parallel()
+	t.Run("serves expvar counters and profiles", func(t *testing.T) {
+		t.Parallel()
+		server := NewServer("127.0.0.1:0")
+		err := server.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Close()
+
+		resp, err := http.Get("http://" + server.Addr().String() + "/debug/vars")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var vars map[string]json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&vars)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := vars["memstats"]; !ok {
+			t.Error("expected memstats within expvar counters")
+		}
+
+		resp, err = http.Get("http://" + server.Addr().String() + "/debug/pprof/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status 200 for the profile index, received %d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package gossip
+
+import (
+	"errors"
+	"fmt"
+	"gossiphers/internal/config"
+	"io"
+
+	"go.uber.org/zap"
+)
+
+// ErrAdversaryUnavailable is returned if an adversarial behavior is configured within a build without the adversary tag.
+var ErrAdversaryUnavailable = errors.New("adversarial behaviors are only supported by builds with the adversary tag")
+
+const (
+	// adversaryFabricatedNodes represents the number of fabricated nodes within the pull responses of a pull poisoning node.
+	adversaryFabricatedNodes = 64
+	// adversarySpamMessages represents the number of random messages spread per round by a spamming node.
+	adversarySpamMessages = 64
+	// adversarySpamDataType and adversarySpamDataSize represent the data type and size of the spammed messages.
+	adversarySpamDataType = 65535
+	adversarySpamDataSize = 256
+)
+
+// FabricateNodes returns n nodes with identities drawn from the source of randomness, crypto/rand if nil, at addresses within
+// the documentation range 192.0.2.0/24, which no peer can reach or verify packets of.
+func FabricateNodes(random io.Reader, n int) ([]Node, error) {
+	nodes := make([]Node, 0, n)
+	for i := 0; i < n; i++ {
+		identity := make([]byte, IdentitySize)
+		_, err := io.ReadFull(randomOr(random), identity)
+		if err != nil {
+			return nil, err
+		}
+		node, err := NewNode(identity, fmt.Sprintf("192.0.2.%d:7002", i%254+1))
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, *node)
+	}
+	return nodes, nil
+}
+
+// pullResponseNodes returns the nodes pull requests are answered with during the round, which is the main view unless the node poisons pulls.
+func (g *Gossip) pullResponseNodes(mainViewNodes []Node) []Node {
+	if g.cfg.Adversary != config.AdversaryPullPoison {
+		return mainViewNodes
+	}
+	nodes, err := FabricateNodes(g.random, adversaryFabricatedNodes)
+	if err != nil {
+		g.logger().Error("Error fabricating nodes", zap.Error(err))
+		return mainViewNodes
+	}
+	return nodes
+}
+
+// pushTargets returns the nodes pushed to during the round, which are the randomly selected ones unless the node floods
+// pushes, in which case every node of the main view and the samplers is pushed to.
+func (g *Gossip) pushTargets(selected []*Node, mainViewNodes []Node) []*Node {
+	if g.cfg.Adversary != config.AdversaryPushFlood {
+		return selected
+	}
+	var targets []*Node
+	unique := make(map[Identity]struct{})
+	for _, node := range append(selectedFrom(mainViewNodes), g.samplerGroup.SampleAll()...) {
+		if _, ok := unique[node.Identity]; !ok {
+			unique[node.Identity] = struct{}{}
+			targets = append(targets, node)
+		}
+	}
+	return targets
+}
+
+// selectedFrom returns pointers to all given nodes.
+func selectedFrom(nodes []Node) []*Node {
+	pointers := make([]*Node, 0, len(nodes))
+	for i := range nodes {
+		pointers = append(pointers, &nodes[i])
+	}
+	return pointers
+}
+
+// spamMessages spreads a burst of random messages with infinite hops if the node spams messages.
+func (g *Gossip) spamMessages() {
+	if g.cfg.Adversary != config.AdversaryMessageSpam {
+		return
+	}
+	for i := 0; i < adversarySpamMessages; i++ {
+		data := make([]byte, adversarySpamDataSize)
+		_, err := io.ReadFull(randomOr(g.random), data)
+		if err != nil {
+			g.logger().Error("Error generating spam", zap.Error(err))
+			return
+		}
+		g.gossipServer.spreadMessage(0, adversarySpamDataType, data, false)
+	}
+}
+
+// ignoresRequests reports whether the server leaves the requests of peers unanswered, because the node is silent.
+// Responses to its own requests are still handled, so a silent node keeps joining the views of its peers.
+func (s *Server) ignoresRequests(messageType MessageType) bool {
+	if s.cfg.Adversary != config.AdversarySilent {
+		return false
+	}
+	switch messageType {
+	case MessageTypeGossipPing, MessageTypeGossipPullRequest, MessageTypeGossipPushRequest, MessageTypeGossipDigest, MessageTypeGossipDigestRequest:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,6 @@
+//go:build adversary
+
+package gossip
+
+// adversaryBuild reports whether this build supports adversarial behaviors.
+const adversaryBuild = true
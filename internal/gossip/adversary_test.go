@@ -0,0 +1,125 @@
+package gossip
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"gossiphers/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestFabricateNodes(t *testing.T) {
+	t.Parallel()
+	t.Run("fabricates distinct unreachable nodes", func(t *testing.T) {
+		t.Parallel()
+		nodes, err := FabricateNodes(nil, 300)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unique := make(map[Identity]struct{})
+		for _, node := range nodes {
+			unique[node.Identity] = struct{}{}
+			if !strings.HasPrefix(node.Address, "192.0.2.") {
+				t.Errorf("expected an address within the documentation range, received %s", node.Address)
+			}
+		}
+		if len(unique) != 300 {
+			t.Errorf("expected 300 distinct identities, received %d", len(unique))
+		}
+	})
+}
+
+func TestGossip_adversary(t *testing.T) {
+	t.Parallel()
+	t.Run("refuses to start as an adversary without the adversary tag", func(t *testing.T) {
+		t.Parallel()
+		if adversaryBuild {
+			t.Skip("built with the adversary tag")
+		}
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithAdversary(config.AdversarySilent))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewGossip(cfg)
+		if !errors.Is(err, ErrAdversaryUnavailable) {
+			t.Errorf("expected ErrAdversaryUnavailable, received %v", err)
+		}
+	})
+	t.Run("honest nodes keep the selected push targets and pull responses", func(t *testing.T) {
+		t.Parallel()
+		nodes, err := createNodes(5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := &Gossip{cfg: &config.GossipConfig{}}
+		selected := []*Node{&nodes[0]}
+		if targets := g.pushTargets(selected, nodes); len(targets) != 1 || targets[0] != &nodes[0] {
+			t.Errorf("expected the selected push target, received %v", targets)
+		}
+		if responseNodes := g.pullResponseNodes(nodes); len(responseNodes) != 5 {
+			t.Errorf("expected the main view as pull response, received %v", responseNodes)
+		}
+	})
+	t.Run("push flooding nodes push to all known nodes", func(t *testing.T) {
+		t.Parallel()
+		nodes, err := createNodes(8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samplers, err := NewSamplerGroup(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samplers.Update(nodes[3:])
+		g := &Gossip{cfg: &config.GossipConfig{Adversary: config.AdversaryPushFlood}, samplerGroup: samplers}
+		targets := g.pushTargets([]*Node{&nodes[0]}, nodes[:4])
+		unique := make(map[Identity]struct{})
+		for _, target := range targets {
+			unique[target.Identity] = struct{}{}
+		}
+		if len(unique) != len(targets) || len(targets) < 5 {
+			t.Errorf("expected the main view and the sampled nodes without duplicates, received %d targets", len(targets))
+		}
+	})
+	t.Run("pull poisoning nodes answer with fabricated nodes", func(t *testing.T) {
+		t.Parallel()
+		nodes, err := createNodes(5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := &Gossip{cfg: &config.GossipConfig{Adversary: config.AdversaryPullPoison}}
+		responseNodes := g.pullResponseNodes(nodes)
+		if len(responseNodes) != adversaryFabricatedNodes {
+			t.Fatalf("expected %d fabricated nodes, received %d", adversaryFabricatedNodes, len(responseNodes))
+		}
+		for _, node := range responseNodes {
+			for _, known := range nodes {
+				if node.Identity == known.Identity {
+					t.Fatalf("expected only fabricated nodes, received %s", node.String())
+				}
+			}
+		}
+	})
+	t.Run("silent nodes only ignore requests", func(t *testing.T) {
+		t.Parallel()
+		s := &Server{cfg: &config.GossipConfig{Adversary: config.AdversarySilent}}
+		for _, messageType := range []MessageType{MessageTypeGossipPing, MessageTypeGossipPullRequest, MessageTypeGossipPushRequest} {
+			if !s.ignoresRequests(messageType) {
+				t.Errorf("expected silent nodes to ignore message type %#x", messageType)
+			}
+		}
+		for _, messageType := range []MessageType{MessageTypeGossipPong, MessageTypeGossipPullResponse, MessageTypeGossipPushChallenge} {
+			if s.ignoresRequests(messageType) {
+				t.Errorf("expected silent nodes to handle message type %#x", messageType)
+			}
+		}
+		if (&Server{cfg: &config.GossipConfig{}}).ignoresRequests(MessageTypeGossipPing) {
+			t.Error("expected honest nodes to answer pings")
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package gossip
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"fmt"
+	"gossiphers/internal/api"
+	"gossiphers/internal/clock"
+	"gossiphers/internal/config"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchmarkPacketSizes are the sizes of the packets encrypted and signed by the benchmarks, from a ping up to a large message.
+var benchmarkPacketSizes = []int{PacketHeaderSize + PingNonceSize, 1024, 8192, MaxPacketSize - SignatureSize}
+
+var (
+	benchmarkKeysOnce sync.Once
+	benchmarkKeys     [2]*rsa.PrivateKey
+)
+
+// newBenchmarkCryptos returns the Crypto instances of a sender and a receiver knowing each other's public key, using
+// hostkeys of the size used in production. The keys are generated once for all benchmarks.
+func newBenchmarkCryptos(b *testing.B) (*Crypto, *Crypto) {
+	benchmarkKeysOnce.Do(func() {
+		for i := range benchmarkKeys {
+			key, err := rsa.GenerateKey(rand.Reader, RSAKeySize)
+			if err != nil {
+				b.Fatal(err)
+			}
+			benchmarkKeys[i] = key
+		}
+	})
+	var cryptos [2]*Crypto
+	for i, key := range benchmarkKeys {
+		cfg, err := config.New(config.WithPrivateKey(key), config.WithPeerPublicKeys(&benchmarkKeys[1-i].PublicKey))
+		if err != nil {
+			b.Fatal(err)
+		}
+		cryptos[i], err = NewCrypto(cfg)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	return cryptos[0], cryptos[1]
+}
+
+// identityOf returns the identity of the node owning the Crypto instance.
+func identityOf(b *testing.B, c *Crypto) Identity {
+	id, err := generateIdentity(&c.cfg.PrivateKey.PublicKey)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return *id
+}
+
+func BenchmarkCrypto_EncryptPacket(b *testing.B) {
+	sender, receiver := newBenchmarkCryptos(b)
+	receiverID := identityOf(b, receiver)
+	for _, size := range benchmarkPacketSizes {
+		msg := make([]byte, size)
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				_, err := sender.EncryptPacket(msg, receiverID, 0)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCrypto_DecryptPacket(b *testing.B) {
+	sender, receiver := newBenchmarkCryptos(b)
+	receiverID := identityOf(b, receiver)
+	for _, size := range benchmarkPacketSizes {
+		ciphertext, err := sender.EncryptPacket(make([]byte, size), receiverID, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				_, err := receiver.DecryptPacket(ciphertext)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCrypto_Sign(b *testing.B) {
+	sender, _ := newBenchmarkCryptos(b)
+	for _, size := range benchmarkPacketSizes {
+		data := make([]byte, size)
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				_, err := sender.Sign(data)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCrypto_VerifySignature(b *testing.B) {
+	sender, receiver := newBenchmarkCryptos(b)
+	senderID := identityOf(b, sender)
+	for _, size := range benchmarkPacketSizes {
+		data := make([]byte, size)
+		sig, err := sender.Sign(data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				err := receiver.VerifySignature(data, sig, senderID)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkServer_handleIncomingBytes measures the whole pipeline of a received packet: signature verification, decryption,
+// parsing, and handling. A pong is only parsed, a ping is additionally answered with a signed and encrypted pong.
+func BenchmarkServer_handleIncomingBytes(b *testing.B) {
+	sender, receiver := newBenchmarkCryptos(b)
+	senderID := identityOf(b, sender)
+	challenger, err := NewChallenger(receiver.cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	server, err := NewServer(receiver.cfg, make(chan Node), make(chan Node), receiver, challenger, api.NewServer(receiver.cfg, clock.Real()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	server.listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer server.listener.Close()
+	// the answers to pings are sent to a socket that is never read, the kernel drops them once its buffer is full
+	peer, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer peer.Close()
+
+	// encryptedPacket returns a signed and encrypted ping or pong of the sender, which is only accepted for a few seconds
+	encryptedPacket := func(header PacketHeader) []byte {
+		header.Timestamp = uint64(time.Now().UnixMilli())
+		packetBytes := binary.BigEndian.AppendUint64(header.ToBytes(), 42)
+		ciphertext, err := server.protection.seal(sender, packetBytes, senderID, identityOf(b, receiver), 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return ciphertext
+	}
+
+	for _, bc := range []struct {
+		name        string
+		messageType MessageType
+	}{{"pong", MessageTypeGossipPong}, {"ping", MessageTypeGossipPing}} {
+		header := PacketHeader{Size: uint16(PacketHeaderSize + PingNonceSize + SignatureSize), Type: bc.messageType, SenderIdentity: senderID}
+		b.Run(bc.name, func(b *testing.B) {
+			packet, created := encryptedPacket(header), time.Now()
+			server.lastPeerContact.Store(0)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if time.Since(created) > 4*time.Second {
+					b.StopTimer()
+					packet, created = encryptedPacket(header), time.Now()
+					b.StartTimer()
+				}
+				server.handleIncomingBytes(packet, peer.LocalAddr())
+			}
+			b.StopTimer()
+			if server.lastPeerContact.Load() == 0 {
+				b.Fatal("expected the packets to be accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package gossip
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// parseBootstrapNodesFile reads a file containing one <id>,<addr> node per line and parses it into a slice of nodes.
+// Empty lines and lines starting with '#' are ignored.
+func parseBootstrapNodesFile(path string) ([]Node, error) {
+	fileBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read bootstrap nodes file %s: %w", path, err)
+	}
+
+	var nodes []Node
+	scanner := bufio.NewScanner(bytes.NewReader(fileBytes))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lineNodes, err := parseBootstrapNodesStr(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node in bootstrap nodes file %s on line %d: %w", path, lineNumber, err)
+		}
+		nodes = append(nodes, lineNodes...)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// loadBootstrapNodes combines the nodes given inline in the configuration with those of the bootstrap nodes file, if one is configured.
+func loadBootstrapNodes(nodesStr string, nodesFile string) ([]Node, error) {
+	nodes, err := parseBootstrapNodesStr(nodesStr)
+	if err != nil {
+		return nil, err
+	}
+	if nodesFile == "" {
+		return nodes, nil
+	}
+	fileNodes, err := parseBootstrapNodesFile(nodesFile)
+	if err != nil {
+		return nil, err
+	}
+	return append(nodes, fileNodes...), nil
+}
+
+// bootstrapFileWatcher keeps track of the bootstrap nodes file and reports nodes that were added since the last check.
+type bootstrapFileWatcher struct {
+	path         string
+	lastModTime  time.Time
+	knownNodes   map[string]struct{}
+	reloadRounds int
+}
+
+// newBootstrapFileWatcher returns a new instance of bootstrapFileWatcher, treating the given nodes as already known.
+func newBootstrapFileWatcher(path string, reloadRounds int, knownNodes []Node) *bootstrapFileWatcher {
+	w := &bootstrapFileWatcher{
+		path:         path,
+		knownNodes:   make(map[string]struct{}),
+		reloadRounds: reloadRounds,
+	}
+	if info, err := os.Stat(path); err == nil {
+		w.lastModTime = info.ModTime()
+	}
+	for _, node := range knownNodes {
+		w.knownNodes[node.String()] = struct{}{}
+	}
+	return w
+}
+
+// newNodes checks whether the bootstrap nodes file was modified (only every reloadRounds rounds) and returns all nodes that were not known before.
+func (w *bootstrapFileWatcher) newNodes(round int) []Node {
+	if w.path == "" || w.reloadRounds <= 0 || round%w.reloadRounds != 0 {
+		return nil
+	}
+	info, err := os.Stat(w.path)
+	if err != nil {
+		logger().Warn("Could not access bootstrap nodes file", zap.String("path", w.path), zap.Error(err))
+		return nil
+	}
+	if !info.ModTime().After(w.lastModTime) {
+		return nil
+	}
+	w.lastModTime = info.ModTime()
+
+	nodes, err := parseBootstrapNodesFile(w.path)
+	if err != nil {
+		logger().Warn("Could not reload bootstrap nodes file", zap.String("path", w.path), zap.Error(err))
+		return nil
+	}
+	var added []Node
+	for _, node := range nodes {
+		if _, ok := w.knownNodes[node.String()]; ok {
+			continue
+		}
+		w.knownNodes[node.String()] = struct{}{}
+		added = append(added, node)
+	}
+	return added
+}
@@ -0,0 +1,99 @@
+package gossip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBootstrap_parseBootstrapNodesFile(t *testing.T) {
+	t.Parallel()
+	t.Run("parses one node per line and skips comments and empty lines", func(t *testing.T) {
+		nodes, err := createNodes(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := "# bootstrap peers\n" + nodes[0].Identity.String() + "," + nodes[0].Address + "\n\n" + nodes[1].Identity.String() + "," + nodes[1].Address + "\n"
+		path := filepath.Join(t.TempDir(), "peers.txt")
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := parseBootstrapNodesFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parsed) != 2 {
+			t.Fatalf("expected 2 nodes, received %d", len(parsed))
+		}
+		if parsed[0].String() != nodes[0].String() || parsed[1].String() != nodes[1].String() {
+			t.Errorf("parsed nodes do not match: %v", parsed)
+		}
+		if parsed[0].Source != SourceBootstrap || parsed[0].Source.String() != "bootstrap" {
+			t.Errorf("parsed nodes should be marked as bootstrap nodes, received %s", parsed[0].Source)
+		}
+	})
+	t.Run("returns error on malformed line", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "peers.txt")
+		err := os.WriteFile(path, []byte("not-a-node\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = parseBootstrapNodesFile(path)
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+	t.Run("returns error on missing file", func(t *testing.T) {
+		_, err := parseBootstrapNodesFile(filepath.Join(t.TempDir(), "missing.txt"))
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+}
+
+func TestBootstrap_bootstrapFileWatcher(t *testing.T) {
+	t.Parallel()
+	t.Run("reports only nodes added after a file change", func(t *testing.T) {
+		nodes, err := createNodes(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "peers.txt")
+		err = os.WriteFile(path, []byte(nodes[0].Identity.String()+","+nodes[0].Address+"\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		watcher := newBootstrapFileWatcher(path, 2, nodes[:1])
+		if added := watcher.newNodes(2); len(added) != 0 {
+			t.Errorf("expected no new nodes for an unchanged file, received %d", len(added))
+		}
+
+		content := ""
+		for _, node := range nodes {
+			content += node.Identity.String() + "," + node.Address + "\n"
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		future := time.Now().Add(time.Minute)
+		err = os.Chtimes(path, future, future)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if added := watcher.newNodes(3); len(added) != 0 {
+			t.Errorf("expected the file to be checked only every 2 rounds, received %d nodes", len(added))
+		}
+		added := watcher.newNodes(4)
+		if len(added) != 2 {
+			t.Fatalf("expected 2 new nodes, received %d", len(added))
+		}
+		if added[0].String() != nodes[1].String() || added[1].String() != nodes[2].String() {
+			t.Errorf("unexpected new nodes: %v", added)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package gossip
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gossiphers/internal/config"
+	"gossiphers/internal/transport"
+	"net"
+	"path/filepath"
+	"strings"
+)
+
+// CheckConfig checks whether a node can run with the given valid config, beyond the ranges checked by config.GossipConfig.Validate.
+// It verifies the private key, its identity, and the hostkeys folder, resolves all configured addresses, and ensures the public
+// keys of all bootstrap nodes are known. Returns all problems found, none if the node is expected to start.
+func CheckConfig(cfg *config.GossipConfig) []error {
+	problems := hostkeyProblems(cfg)
+
+	gCrypto, err := NewCrypto(cfg)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("hostkeys_path %s cannot be loaded: %w", cfg.HostkeysPath, err))
+	} else if len(gCrypto.idToPub) == 0 {
+		problems = append(problems, fmt.Errorf("hostkeys_path %s does not contain any public keys, no peer can be contacted", cfg.HostkeysPath))
+	}
+
+	if _, err := net.ResolveUDPAddr("udp", cfg.GossipAddress); err != nil {
+		problems = append(problems, fmt.Errorf("gossip_address %s cannot be resolved: %w", cfg.GossipAddress, err))
+	}
+	tcpAddresses := []struct {
+		key     string
+		address string
+	}{
+		{"api_address", cfg.ApiAddress},
+		{"debug_address", cfg.DebugAddress},
+		{"health_address", cfg.HealthAddress},
+	}
+	for _, tcpAddress := range tcpAddresses {
+		if tcpAddress.address == "" {
+			continue
+		}
+		if _, err := net.ResolveTCPAddr("tcp", tcpAddress.address); err != nil {
+			problems = append(problems, fmt.Errorf("%s %s cannot be resolved: %w", tcpAddress.key, tcpAddress.address, err))
+		}
+	}
+
+	bootstrapNodes, err := loadBootstrapNodes(cfg.BootstrapNodesStr, cfg.BootstrapNodesFile)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("bootstrap nodes cannot be loaded: %w", err))
+	}
+	pinnedNodes, err := parseBootstrapNodesStr(cfg.PinnedNodesStr)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("pinned nodes cannot be parsed: %w", err))
+	}
+	for i, node := range append(bootstrapNodes, pinnedNodes...) {
+		kind := "bootstrap"
+		if i >= len(bootstrapNodes) {
+			kind = "pinned"
+		}
+		if _, err := net.ResolveUDPAddr("udp", node.Address); err != nil {
+			problems = append(problems, fmt.Errorf("address of %s node %s cannot be resolved: %w", kind, node.String(), err))
+		}
+		if gCrypto == nil {
+			continue
+		}
+		if _, ok := gCrypto.idToPub[node.Identity]; !ok {
+			problems = append(problems, fmt.Errorf("public key of %s node %s is missing from hostkeys_path %s", kind, node.String(), cfg.HostkeysPath))
+		}
+	}
+	return problems
+}
+
+// SelfCheck verifies the key material and the gossip address of a node about to start, so a misconfigured node fails right away
+// instead of mid-round once a peer rejects its packets or the listener cannot be bound. It checks the private key and the
+// identity it derives, the public keys of the hostkeys folder, and that the gossip address can be bound.
+// Returns all problems found joined into a single error.
+func SelfCheck(cfg *config.GossipConfig) error {
+	problems := hostkeyProblems(cfg)
+	if _, err := NewCrypto(cfg); err != nil {
+		problems = append(problems, fmt.Errorf("hostkeys_path %s cannot be loaded: %w", cfg.HostkeysPath, err))
+	}
+	listener, err := transport.Or(cfg.Network).Listen(cfg.GossipAddress)
+	if err != nil {
+		problems = append(problems, fmt.Errorf("gossip_address %s cannot be bound: %w", cfg.GossipAddress, err))
+	} else {
+		_ = listener.Close()
+	}
+	return errors.Join(problems...)
+}
+
+// hostkeyProblems checks that the private key is a valid key of HostkeyBits bits, and that the identity it derives matches both
+// the configured identity and an identity the name of the hostkey file consists of, e.g. after keys were mixed up between nodes.
+func hostkeyProblems(cfg *config.GossipConfig) []error {
+	if err := cfg.PrivateKey.Validate(); err != nil {
+		return []error{fmt.Errorf("hostkey is invalid: %w", err)}
+	}
+	var problems []error
+	if bits := cfg.PrivateKey.N.BitLen(); bits != HostkeyBits {
+		problems = append(problems, fmt.Errorf("hostkey has %d bits, but signatures require %d bits", bits, HostkeyBits))
+	}
+	identity, err := IdentityOf(cfg.PrivateKey)
+	if err != nil {
+		return append(problems, fmt.Errorf("identity of the hostkey cannot be derived: %w", err))
+	}
+	if cfg.ExpectedIdentity != "" && !strings.EqualFold(cfg.ExpectedIdentity, identity.String()) {
+		problems = append(problems, fmt.Errorf("hostkey derives identity %s, but identity is set to %s", identity.String(), cfg.ExpectedIdentity))
+	}
+	if named, ok := identityOfFileName(cfg.PrivateKeyPath); ok && named != *identity {
+		problems = append(problems, fmt.Errorf("hostkey %s is named after identity %s, but derives identity %s", cfg.PrivateKeyPath, named.String(), identity.String()))
+	}
+	return problems
+}
+
+// identityOfFileName returns the identity the name of the file at the given path consists of, ignoring its extension.
+// Returns false if the name is not a hex-encoded identity.
+func identityOfFileName(path string) (Identity, bool) {
+	name := filepath.Base(path)
+	hash, err := hex.DecodeString(strings.TrimSuffix(name, filepath.Ext(name)))
+	if err != nil {
+		return Identity{}, false
+	}
+	identity, err := NewIdentity(hash)
+	if err != nil {
+		return Identity{}, false
+	}
+	return *identity, true
+}
@@ -0,0 +1,148 @@
+package gossip
+
+import (
+	"gossiphers/internal/config"
+	"gossiphers/internal/transport"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	t.Parallel()
+	privateKey, err := GenerateHostkey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerKey, err := GenerateHostkey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerIdentity, err := IdentityOf(peerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostkeysPath := t.TempDir()
+
+	t.Run("passes a runnable config", func(t *testing.T) {
+		t.Parallel()
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithPeerPublicKeys(&peerKey.PublicKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.BootstrapNodesStr = peerIdentity.String() + ",127.0.0.1:7002"
+		if problems := CheckConfig(cfg); len(problems) != 0 {
+			t.Errorf("unexpected problems %v", problems)
+		}
+	})
+	t.Run("reports all problems", func(t *testing.T) {
+		t.Parallel()
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithHostkeysPath(hostkeysPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.GossipAddress = "127.0.0.1:notaport"
+		cfg.BootstrapNodesStr = peerIdentity.String() + ",127.0.0.1:7002"
+		problems := CheckConfig(cfg)
+		expected := []string{"does not contain any public keys", "gossip_address", "is missing from hostkeys_path"}
+		if len(problems) != len(expected) {
+			t.Fatalf("expected %d problems, received %v", len(expected), problems)
+		}
+		for i, problem := range problems {
+			if !strings.Contains(problem.Error(), expected[i]) {
+				t.Errorf("expected problem containing %q, received %v", expected[i], problem)
+			}
+		}
+	})
+}
+
+func TestSelfCheck(t *testing.T) {
+	t.Parallel()
+	privateKey, err := GenerateHostkey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := IdentityOf(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := GenerateHostkey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherIdentity, err := IdentityOf(otherKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("passes matching key material and a free address", func(t *testing.T) {
+		t.Parallel()
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithExpectedIdentity(strings.ToUpper(identity.String())), config.WithNetwork(transport.NewLoopback()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.GossipAddress = "127.0.0.1:7002"
+		cfg.PrivateKeyPath = filepath.Join("keys", identity.String()+".pem")
+		if err := SelfCheck(cfg); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+	t.Run("reports a mismatching identity", func(t *testing.T) {
+		t.Parallel()
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithExpectedIdentity(otherIdentity.String()), config.WithNetwork(transport.NewLoopback()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.GossipAddress = "127.0.0.1:7002"
+		cfg.PrivateKeyPath = otherIdentity.String()
+		err = SelfCheck(cfg)
+		if err == nil || !strings.Contains(err.Error(), "but identity is set to "+otherIdentity.String()) {
+			t.Errorf("expected the configured identity to be reported, received %v", err)
+		}
+		if err == nil || !strings.Contains(err.Error(), "is named after identity "+otherIdentity.String()) {
+			t.Errorf("expected the file name to be reported, received %v", err)
+		}
+	})
+	t.Run("reports a public key named after the wrong identity", func(t *testing.T) {
+		t.Parallel()
+		hostkeysPath := t.TempDir()
+		hybridKey, err := GenerateHybridKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := WriteHostkey(hostkeysPath, otherKey, hybridKey); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(filepath.Join(hostkeysPath, otherIdentity.String()), filepath.Join(hostkeysPath, identity.String())); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithHostkeysPath(hostkeysPath), config.WithNetwork(transport.NewLoopback()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.GossipAddress = "127.0.0.1:7002"
+		err = SelfCheck(cfg)
+		if err == nil || !strings.Contains(err.Error(), "cannot be loaded") {
+			t.Errorf("expected the hostkeys_path to be reported, received %v", err)
+		}
+	})
+	t.Run("reports an address that cannot be bound", func(t *testing.T) {
+		t.Parallel()
+		network := transport.NewLoopback()
+		listener, err := network.Listen("127.0.0.1:7002")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithNetwork(network))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.GossipAddress = "127.0.0.1:7002"
+		err = SelfCheck(cfg)
+		if err == nil || !strings.Contains(err.Error(), "gossip_address 127.0.0.1:7002 cannot be bound") {
+			t.Errorf("expected the gossip_address to be reported, received %v", err)
+		}
+	})
+}
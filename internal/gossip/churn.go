@@ -0,0 +1,62 @@
+package gossip
+
+import (
+	"sync"
+	"time"
+)
+
+// RoundStats represents the membership churn and timing of a single gossip round.
+type RoundStats struct {
+	Round int `json:"round"`
+	// Added and Removed are the numbers of nodes that joined and left the main view at the end of the round
+	Added    int           `json:"added"`
+	Removed  int           `json:"removed"`
+	Duration time.Duration `json:"duration_ns"`
+	// FirstResponse is the time from the start of the round until the first response of a peer to a ping, pull, or push request, zero if no peer responded
+	FirstResponse time.Duration `json:"first_response_ns"`
+}
+
+// ChurnStats summarizes the membership churn and timing of all rounds so far.
+type ChurnStats struct {
+	Rounds  int `json:"rounds"`
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	// UnresponsiveRounds is the number of rounds in which no peer responded
+	UnresponsiveRounds   int           `json:"unresponsive_rounds"`
+	AverageDuration      time.Duration `json:"average_duration_ns"`
+	AverageFirstResponse time.Duration `json:"average_first_response_ns"`
+	Last                 RoundStats    `json:"last"`
+}
+
+// churnTracker accumulates the statistics of completed rounds. It is safe for concurrent use.
+type churnTracker struct {
+	mu                 sync.Mutex
+	stats              ChurnStats
+	totalDuration      time.Duration
+	totalFirstResponse time.Duration
+}
+
+// Record adds the statistics of a completed round.
+func (ct *churnTracker) Record(round RoundStats) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.stats.Rounds++
+	ct.stats.Added += round.Added
+	ct.stats.Removed += round.Removed
+	ct.stats.Last = round
+	ct.totalDuration += round.Duration
+	ct.stats.AverageDuration = ct.totalDuration / time.Duration(ct.stats.Rounds)
+	if round.FirstResponse == 0 {
+		ct.stats.UnresponsiveRounds++
+		return
+	}
+	ct.totalFirstResponse += round.FirstResponse
+	ct.stats.AverageFirstResponse = ct.totalFirstResponse / time.Duration(ct.stats.Rounds-ct.stats.UnresponsiveRounds)
+}
+
+// Snapshot returns the statistics of all rounds recorded so far.
+func (ct *churnTracker) Snapshot() ChurnStats {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	return ct.stats
+}
@@ -0,0 +1,28 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChurnTracker_Record(t *testing.T) {
+	t.Parallel()
+	t.Run("accumulates churn and averages timings", func(t *testing.T) {
+		t.Parallel()
+		var tracker churnTracker
+		tracker.Record(RoundStats{Round: 1, Added: 3, Duration: 2 * time.Second, FirstResponse: 100 * time.Millisecond})
+		tracker.Record(RoundStats{Round: 2, Added: 1, Removed: 2, Duration: 4 * time.Second})
+		tracker.Record(RoundStats{Round: 3, Removed: 1, Duration: 3 * time.Second, FirstResponse: 300 * time.Millisecond})
+
+		stats := tracker.Snapshot()
+		if stats.Rounds != 3 || stats.Added != 4 || stats.Removed != 3 || stats.UnresponsiveRounds != 1 {
+			t.Errorf("unexpected totals: %+v", stats)
+		}
+		if stats.AverageDuration != 3*time.Second || stats.AverageFirstResponse != 200*time.Millisecond {
+			t.Errorf("unexpected averages: %+v", stats)
+		}
+		if stats.Last.Round != 3 {
+			t.Errorf("unexpected last round: %+v", stats.Last)
+		}
+	})
+}
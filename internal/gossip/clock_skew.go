@@ -0,0 +1,98 @@
+package gossip
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// clockSkewRetention represents how long the clock offset of a peer counts towards the estimate after its last packet.
+	clockSkewRetention = 10 * time.Minute
+	// clockSkewMinPeers represents the number of peers with a recent clock offset required for an estimate, so a single peer
+	// with a wrong clock is not mistaken for a wrong local clock.
+	clockSkewMinPeers = 3
+)
+
+// clockOffset represents the difference between the timestamp of the latest packet of a peer and the local time it was
+// received at.
+type clockOffset struct {
+	offset     time.Duration
+	receivedAt time.Time
+}
+
+// clockSkewEstimator estimates the offset of the local clock relative to the clocks of the peers from the timestamps within the
+// headers of their authenticated packets. Each peer contributes the offset of its latest packet, and the local clock is
+// considered off by the negated median of these offsets, which neither a minority of peers with wrong clocks nor Byzantine peers
+// forging timestamps can move. The offsets include the one-way delay of the packets, which lets the local clock appear ahead by
+// a few milliseconds, negligible compared to skews endangering the freshness checks.
+// It is safe for concurrent use.
+type clockSkewEstimator struct {
+	mu      sync.Mutex
+	offsets map[Identity]clockOffset
+}
+
+// newClockSkewEstimator returns an estimator without recorded offsets.
+func newClockSkewEstimator() *clockSkewEstimator {
+	return &clockSkewEstimator{offsets: make(map[Identity]clockOffset)}
+}
+
+// Record records the timestamp in milliseconds of an authenticated packet of the peer received at the given time.
+func (ce *clockSkewEstimator) Record(identity Identity, timestamp uint64, now time.Time) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.offsets[identity] = clockOffset{offset: time.UnixMilli(int64(timestamp)).Sub(now), receivedAt: now}
+}
+
+// Estimate forgets the offsets of peers without a packet since the retention period and returns the estimated offset of the
+// local clock, positive if it is ahead of the peers, along with the number of peers it is based on. Returns false if fewer than
+// clockSkewMinPeers peers sent a packet recently.
+func (ce *clockSkewEstimator) Estimate(now time.Time) (time.Duration, int, bool) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	offsets := make([]time.Duration, 0, len(ce.offsets))
+	for identity, offset := range ce.offsets {
+		if now.Sub(offset.receivedAt) > clockSkewRetention {
+			delete(ce.offsets, identity)
+			continue
+		}
+		offsets = append(offsets, offset.offset)
+	}
+	if len(offsets) < clockSkewMinPeers {
+		return 0, len(offsets), false
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	median := offsets[len(offsets)/2]
+	if len(offsets)%2 == 0 {
+		median = (offsets[len(offsets)/2-1] + median) / 2
+	}
+	return -median, len(offsets), true
+}
+
+// updateClockSkew publishes the estimated offset of the local clock relative to the peers and warns once the offset exceeds
+// the configured threshold, since packets timestamped too far apart from the local time are rejected as stale by the peers or
+// by ourselves, cutting the node off from the network.
+func (s *Server) updateClockSkew() {
+	skew, peers, ok := s.clockSkew.Estimate(s.clock.Now())
+	setGauge(metricClockSkewPeers, peers)
+	if !ok {
+		return
+	}
+	setGauge(metricClockSkewMs, int(skew.Milliseconds()))
+	threshold := time.Millisecond * time.Duration(s.cfg.ClockSkewWarnMs)
+	skewed := threshold > 0 && (skew > threshold || skew < -threshold)
+	if skewed == s.clockSkewed {
+		return
+	}
+	s.clockSkewed = skewed
+	if skewed {
+		setGauge(metricClockSkewWarning, 1)
+		s.logger().Warn("Local clock appears to be off relative to the peers, check the time synchronization of the host",
+			zap.Int64("skew_ms", skew.Milliseconds()), zap.Int("peers", peers), zap.Int("warn_ms", s.cfg.ClockSkewWarnMs))
+		return
+	}
+	setGauge(metricClockSkewWarning, 0)
+	s.logger().Info("Local clock agrees with the peers again", zap.Int64("skew_ms", skew.Milliseconds()), zap.Int("peers", peers))
+}
@@ -0,0 +1,90 @@
+package gossip
+
+import (
+	"gossiphers/internal/clock"
+	"gossiphers/internal/config"
+	"testing"
+	"time"
+)
+
+// timestampAt returns the packet timestamp of the given time.
+func timestampAt(t time.Time) uint64 {
+	return uint64(t.UnixMilli())
+}
+
+func TestClockSkewEstimator(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1000, 0)
+	t.Run("no estimate without enough peers", func(t *testing.T) {
+		t.Parallel()
+		ce := newClockSkewEstimator()
+		ce.Record(testIdentity("peer1"), timestampAt(now.Add(time.Minute)), now)
+		ce.Record(testIdentity("peer2"), timestampAt(now.Add(time.Minute)), now)
+		if _, peers, ok := ce.Estimate(now); ok || peers != 2 {
+			t.Errorf("expected no estimate based on 2 peers, received %t and %d peers", ok, peers)
+		}
+	})
+	t.Run("local clock behind the peers is estimated negative", func(t *testing.T) {
+		t.Parallel()
+		ce := newClockSkewEstimator()
+		ce.Record(testIdentity("peer1"), timestampAt(now.Add(5*time.Second)), now)
+		ce.Record(testIdentity("peer2"), timestampAt(now.Add(6*time.Second)), now)
+		ce.Record(testIdentity("peer3"), timestampAt(now.Add(7*time.Second)), now)
+		if skew, peers, ok := ce.Estimate(now); !ok || peers != 3 || skew != -6*time.Second {
+			t.Errorf("expected a skew of -6s based on 3 peers, received %s based on %d peers", skew, peers)
+		}
+	})
+	t.Run("a minority of skewed peers does not move the estimate", func(t *testing.T) {
+		t.Parallel()
+		ce := newClockSkewEstimator()
+		ce.Record(testIdentity("peer1"), timestampAt(now), now)
+		ce.Record(testIdentity("peer2"), timestampAt(now), now)
+		ce.Record(testIdentity("peer3"), timestampAt(now.Add(-time.Second)), now)
+		ce.Record(testIdentity("peer4"), timestampAt(now.Add(time.Hour)), now)
+		ce.Record(testIdentity("peer5"), timestampAt(now.Add(time.Hour)), now)
+		if skew, _, ok := ce.Estimate(now); !ok || skew != 0 {
+			t.Errorf("expected a skew of 0, received %s", skew)
+		}
+	})
+	t.Run("the latest packet of a peer counts", func(t *testing.T) {
+		t.Parallel()
+		ce := newClockSkewEstimator()
+		ce.Record(testIdentity("peer1"), timestampAt(now.Add(-time.Hour)), now)
+		ce.Record(testIdentity("peer1"), timestampAt(now.Add(-2*time.Second)), now)
+		ce.Record(testIdentity("peer2"), timestampAt(now.Add(-2*time.Second)), now)
+		ce.Record(testIdentity("peer3"), timestampAt(now.Add(-2*time.Second)), now)
+		if skew, _, ok := ce.Estimate(now); !ok || skew != 2*time.Second {
+			t.Errorf("expected a skew of 2s, received %s", skew)
+		}
+	})
+	t.Run("offsets of silent peers expire", func(t *testing.T) {
+		t.Parallel()
+		ce := newClockSkewEstimator()
+		ce.Record(testIdentity("peer1"), timestampAt(now), now)
+		ce.Record(testIdentity("peer2"), timestampAt(now), now)
+		ce.Record(testIdentity("peer3"), timestampAt(now), now.Add(clockSkewRetention))
+		if _, peers, ok := ce.Estimate(now.Add(clockSkewRetention + time.Second)); ok || peers != 1 {
+			t.Errorf("expected the offsets of peer1 and peer2 to expire, received an estimate based on %d peers", peers)
+		}
+	})
+}
+
+func TestServer_updateClockSkew(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1000, 0)
+	s := &Server{cfg: &config.GossipConfig{ClockSkewWarnMs: 4000}, clock: clock.NewFake(now), clockSkew: newClockSkewEstimator()}
+	for _, peer := range []Identity{testIdentity("peer1"), testIdentity("peer2"), testIdentity("peer3")} {
+		s.clockSkew.Record(peer, timestampAt(now.Add(-5*time.Second)), now)
+	}
+	s.updateClockSkew()
+	if !s.clockSkewed {
+		t.Fatal("expected a warning for a local clock 5s ahead")
+	}
+	for _, peer := range []Identity{testIdentity("peer1"), testIdentity("peer2"), testIdentity("peer3")} {
+		s.clockSkew.Record(peer, timestampAt(now.Add(-time.Second)), now)
+	}
+	s.updateClockSkew()
+	if s.clockSkewed {
+		t.Error("expected the warning to be lifted for a local clock 1s ahead")
+	}
+}
@@ -13,8 +13,11 @@ import (
 	"errors"
 	"fmt"
 	"gossiphers/internal/config"
+	"gossiphers/internal/logging"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -22,25 +25,97 @@ import (
 const (
 	PacketKeySize = 32
 	gcmNonceSize  = 12
+	gcmTagSize    = 16
 )
 
+// CryptoSchemes describes the cryptographic schemes used to sign and encrypt packets, e.g. to audit the compatibility of builds.
+var CryptoSchemes = []string{
+	"signature: RSA-4096 PKCS#1 v1.5 with SHA-256",
+	"encryption: AES-256-GCM with the key wrapped by RSA-OAEP with SHA-256",
+	"encryption (key wrap hybrid): AES-256-GCM with the key derived from X25519 and ML-KEM-768 by HKDF-SHA256",
+	"identity: SHA-256 of the PKCS#1 encoded RSA public key",
+}
+
+// PacketCrypto encrypts, decrypts, signs, and verifies the packets exchanged with peers. Crypto implements it, tests may
+// replace it with a cheap stub.
+type PacketCrypto interface {
+	// EncryptPacket encrypts a packet for the peer with the given identity, which advertised the given capabilities.
+	EncryptPacket(msg []byte, id Identity, capabilities Capabilities) ([]byte, error)
+	// DecryptPacket decrypts a packet of at least Overhead bytes that was encrypted for us.
+	DecryptPacket(ciphertext []byte) ([]byte, error)
+	// Overhead returns the largest number of bytes encryption adds to a packet.
+	Overhead() int
+	// Sign returns the signature of data, which consists of SignatureSize bytes.
+	Sign(data []byte) ([]byte, error)
+	// VerifySignature checks that sig is a valid signature of the message by the peer with the given identity.
+	VerifySignature(message []byte, sig []byte, id Identity) error
+}
+
 // Crypto represents a container for all of the cryptographic functionality within the gossip protocol.
 type Crypto struct {
 	cfg *config.GossipConfig
 	// idToPub represents the mapping of Identities to RSA public keys.
 	idToPub map[Identity]rsa.PublicKey
+	// idToHybrid represents the mapping of Identities to hybrid public keys, which not all peers may have published.
+	idToHybrid map[Identity]*hybridPublicKey
+	// hybrid represents our own hybrid private key, nil if the hostkey file contains none. Packets wrapped with the hybrid
+	// KEM are only decrypted with it.
+	hybrid *hybridPrivateKey
+}
+
+// cryptoLogger returns the logger of the crypto subsystem.
+func cryptoLogger() *zap.Logger {
+	return logging.L(logging.Crypto)
 }
 
 // NewCrypto creates a new Crypto instance.
 func NewCrypto(cfg *config.GossipConfig) (*Crypto, error) {
+	c := &Crypto{
+		cfg:        cfg,
+		idToPub:    make(map[Identity]rsa.PublicKey),
+		idToHybrid: make(map[Identity]*hybridPublicKey),
+	}
+	for _, publicKey := range cfg.PeerPublicKeys {
+		id, err := generateIdentity(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		c.idToPub[*id] = *publicKey
+	}
+	for idStr, encoded := range cfg.PeerHybridPublicKeys {
+		hash, err := hex.DecodeString(idStr)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode identity of hybrid public key: %w", err)
+		}
+		id, err := NewIdentity(hash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity of hybrid public key: %w", err)
+		}
+		hybridKey, err := parseHybridPublicKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hybrid public key of identity %s: %w", idStr, err)
+		}
+		c.idToHybrid[*id] = hybridKey
+	}
+	if cfg.HybridPrivateKey != nil {
+		hybridKey, err := parseHybridPrivateKey(cfg.HybridPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse hybrid private key: %w", err)
+		}
+		c.hybrid = hybridKey
+	}
+	// A programmatically created config may not use a hostkeys folder at all
+	if cfg.HostkeysPath == "" {
+		return c, c.missingHybridKeys()
+	}
+
 	// List files in the folder
 	dirEntries, err := os.ReadDir(cfg.HostkeysPath)
 	if err != nil {
-		zap.L().Error("could not read folder", zap.Error(err))
+		cryptoLogger().Error("could not read folder", zap.Error(err))
 		return nil, err
 	}
 
-	idToPub := make(map[Identity]rsa.PublicKey)
 	// Loop through the files
 	for _, dirEntry := range dirEntries {
 		if dirEntry.IsDir() {
@@ -64,41 +139,64 @@ func NewCrypto(cfg *config.GossipConfig) (*Crypto, error) {
 			return nil, err
 		}
 
-		// Decode PEM blocks
-		pemBlock, _ := pem.Decode(fileBytes)
+		// Decode PEM blocks, the RSA public key may be followed by the hybrid public key
+		pemBlock, rest := pem.Decode(fileBytes)
 		if pemBlock == nil {
 			return nil, fmt.Errorf("no PEM block found within the file: filepath %s", filePath)
 		}
+		for ; pemBlock != nil; pemBlock, rest = pem.Decode(rest) {
+			// Check the PEM block type
+			switch pemBlock.Type {
+			case rsaPublicKey:
+				// Decode public key
+				publicKey, err := x509.ParsePKCS1PublicKey(pemBlock.Bytes)
+				if err != nil {
+					return nil, err
+				}
 
-		// Check the PEM block type
-		switch pemBlock.Type {
-		case "RSA PUBLIC KEY":
-			// Decode public key
-			publicKey, err := x509.ParsePKCS1PublicKey(pemBlock.Bytes)
-			if err != nil {
-				return nil, err
-			}
+				// Verify whether the public key actually belongs to the identity.
+				genID, err := generateIdentity(publicKey)
+				if err != nil {
+					return nil, err
+				}
+				if *genID != *id {
+					return nil, fmt.Errorf("mapping from public key to identity is incorrect: id %s, genID %s", id.String(), genID.String())
+				}
+				c.idToPub[*id] = *publicKey
 
-			// Verify whether the public key actually belongs to the identity.
-			genID, err := generateIdentity(publicKey)
-			if err != nil {
-				return nil, err
-			}
-			if genID.String() != id.String() {
-				return nil, fmt.Errorf("mapping from public key to identity is incorrect: id %s, genID %s", id.String(), genID.String())
+			case hybridPublicKeyBlock:
+				// The hybrid public key is trusted like the RSA public key within the same file named by the identity
+				hybridKey, err := parseHybridPublicKey(pemBlock.Bytes)
+				if err != nil {
+					return nil, fmt.Errorf("invalid hybrid public key within the file %s: %w", filePath, err)
+				}
+				c.idToHybrid[*id] = hybridKey
+
+			default:
+				cryptoLogger().Error("unsupported PEM block type, skipping", zap.String("block type", pemBlock.Type))
 			}
-			idToPub[*id] = *publicKey
+		}
+	}
+	return c, c.missingHybridKeys()
+}
 
-		default:
-			zap.L().Error("unsupported PEM block type, skipping", zap.String("block type", pemBlock.Type))
-			continue
+// missingHybridKeys returns an error naming the peers without hybrid public key if packet keys are wrapped with the hybrid
+// KEM, so a node is not switched to the hybrid key wrap before the hybrid public keys of all its peers were distributed.
+func (c *Crypto) missingHybridKeys() error {
+	if c.cfg.KeyWrap != config.KeyWrapHybrid {
+		return nil
+	}
+	var missing []string
+	for id := range c.idToPub {
+		if _, ok := c.idToHybrid[id]; !ok {
+			missing = append(missing, id.String())
 		}
 	}
-	c := Crypto{
-		cfg,
-		idToPub,
+	if len(missing) == 0 {
+		return nil
 	}
-	return &c, nil
+	sort.Strings(missing)
+	return fmt.Errorf("key_wrap hybrid requires the hybrid public keys of all peers, missing for %s", strings.Join(missing, ", "))
 }
 
 // generateIdentity generates an Identity from a public key.
@@ -117,60 +215,83 @@ func generateIdentity(pubKey *rsa.PublicKey) (*Identity, error) {
 
 // DecryptPacket decrypts a packet.
 // The first bytes of the packet (equivalent to the size of the peers private key)
-// contain the RSA-OAEP-encrypted 32B AES-GCM key and 12B nonce, which are used to then decrypt the rest of the packet
+// contain the RSA-OAEP-encrypted 32B AES-GCM key and 12B nonce, which are used to then decrypt the rest of the packet.
+// With the hybrid key wrap, the first bytes contain the wrapped key the AES-GCM key and nonce are derived from instead.
+// Since the sender chooses the key wrap per receiver, packets are decrypted with the hybrid key wrap first if we have a
+// hybrid private key, and with RSA-OAEP if that fails.
 func (c *Crypto) DecryptPacket(ciphertext []byte) ([]byte, error) {
-	aesKeyAndNonceBytes, err := rsa.DecryptOAEP(sha256.New(), nil, c.cfg.PrivateKey, ciphertext[:c.cfg.PrivateKey.Size()], nil)
+	if c.hybrid != nil {
+		decryptedBytes, err := openWrapped(ciphertext, hybridWrapSize, func(wrapped []byte) ([]byte, error) {
+			return unwrapHybrid(c.hybrid, wrapped)
+		})
+		if err == nil {
+			return decryptedBytes, nil
+		}
+	}
+	decryptedBytes, err := openWrapped(ciphertext, c.cfg.PrivateKey.Size(), func(wrapped []byte) ([]byte, error) {
+		return rsa.DecryptOAEP(sha256.New(), nil, c.cfg.PrivateKey, wrapped, nil)
+	})
 	if err != nil {
-		zap.L().Error("unable to decrypt packet key", zap.Error(err))
+		cryptoLogger().Warn("unable to decrypt packet", zap.Error(err))
 		return nil, err
 	}
+	return decryptedBytes, nil
+}
+
+// openWrapped decrypts a packet whose AES-GCM key and nonce are wrapped within its first wrapSize bytes, which unwrap recovers.
+func openWrapped(ciphertext []byte, wrapSize int, unwrap func(wrapped []byte) ([]byte, error)) ([]byte, error) {
+	if len(ciphertext) < wrapSize+gcmTagSize {
+		return nil, fmt.Errorf("packet of %d bytes is too short for a wrapped key of %d bytes", len(ciphertext), wrapSize)
+	}
+	aesKeyAndNonceBytes, err := unwrap(ciphertext[:wrapSize])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt packet key: %w", err)
+	}
 	aesBlock, err := aes.NewCipher(aesKeyAndNonceBytes[:PacketKeySize])
 	if err != nil {
-		zap.L().Error("unable to import packet aes key", zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("unable to import packet aes key: %w", err)
 	}
-
 	aesGCM, err := cipher.NewGCM(aesBlock)
 	if err != nil {
-		zap.L().Error("unable to create GCM for decryption", zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("unable to create GCM for decryption: %w", err)
 	}
-
-	decryptedBytes := make([]byte, 0)
-	decryptedBytes, err = aesGCM.Open(decryptedBytes, aesKeyAndNonceBytes[PacketKeySize:], ciphertext[c.cfg.PrivateKey.Size():], nil)
+	decryptedBytes, err := aesGCM.Open(nil, aesKeyAndNonceBytes[PacketKeySize:], ciphertext[wrapSize:], nil)
 	if err != nil {
-		zap.L().Warn("unable to decrypt message with aes gcm", zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("unable to decrypt message with aes gcm: %w", err)
 	}
-
 	return decryptedBytes, nil
 }
 
 // EncryptPacket encrypts a packet, by randomly generating an AES-GCM key and nonce to encrypt the message.
 // The key and nonce are then RSA-OAEP encrypted with the receivers public key and prepended to the message.
-func (c *Crypto) EncryptPacket(msg []byte, id Identity) ([]byte, error) {
+// With the hybrid key wrap, the key and nonce are derived from secrets encapsulated for the receivers hybrid public key
+// instead, as long as the receiver has one and advertised CapabilityHybridKeyWrap, and RSA-OAEP otherwise.
+func (c *Crypto) EncryptPacket(msg []byte, id Identity, capabilities Capabilities) ([]byte, error) {
+	if c.wrapsHybrid(id, capabilities) {
+		return c.encryptHybrid(msg, id)
+	}
 	pub, exists := c.idToPub[id]
 	if !exists {
-		zap.L().Error("identity to public key mapping does not exist", zap.String("id", id.String()))
+		cryptoLogger().Error("identity to public key mapping does not exist", zap.String("id", id.String()))
 		return nil, fmt.Errorf("identity to public key mapping does not exist: id %s", id.String())
 	}
 
 	aesKeyAndNonceBytes := make([]byte, PacketKeySize+gcmNonceSize)
 	_, err := rand.Read(aesKeyAndNonceBytes)
 	if err != nil {
-		zap.L().Error("could not generate aes key and iv", zap.Error(err))
+		cryptoLogger().Error("could not generate aes key and iv", zap.Error(err))
 		return nil, err
 	}
 
 	aesBlock, err := aes.NewCipher(aesKeyAndNonceBytes[:PacketKeySize])
 	if err != nil {
-		zap.L().Error("unable to initialize generated aes key", zap.Error(err))
+		cryptoLogger().Error("unable to initialize generated aes key", zap.Error(err))
 		return nil, err
 	}
 
 	encryptedAesKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &pub, aesKeyAndNonceBytes, nil)
 	if err != nil {
-		zap.L().Error("unable to encrypt aes key", zap.Error(err))
+		cryptoLogger().Error("unable to encrypt aes key", zap.Error(err))
 		return nil, err
 	}
 
@@ -186,6 +307,51 @@ func (c *Crypto) EncryptPacket(msg []byte, id Identity) ([]byte, error) {
 	return ciphertext, nil
 }
 
+// encryptHybrid encrypts a packet with an AES-GCM key and nonce derived from secrets encapsulated for the receivers hybrid
+// public key, prepending the wrapped key to the message.
+func (c *Crypto) encryptHybrid(msg []byte, id Identity) ([]byte, error) {
+	pub, exists := c.idToHybrid[id]
+	if !exists {
+		cryptoLogger().Error("identity to hybrid public key mapping does not exist", zap.String("id", id.String()))
+		return nil, fmt.Errorf("identity to hybrid public key mapping does not exist: id %s", id.String())
+	}
+	aesKeyAndNonceBytes, wrapped, err := wrapHybrid(pub)
+	if err != nil {
+		cryptoLogger().Error("unable to wrap aes key", zap.Error(err))
+		return nil, err
+	}
+	aesBlock, err := aes.NewCipher(aesKeyAndNonceBytes[:PacketKeySize])
+	if err != nil {
+		cryptoLogger().Error("unable to initialize derived aes key", zap.Error(err))
+		return nil, err
+	}
+	aesGCM, err := cipher.NewGCM(aesBlock)
+	if err != nil {
+		return nil, err
+	}
+	return aesGCM.Seal(wrapped, aesKeyAndNonceBytes[PacketKeySize:], msg, nil), nil
+}
+
+// wrapsHybrid checks whether the packet key of packets for the peer with the given identity and capabilities is wrapped with
+// the hybrid KEM, which requires the hybrid key wrap to be configured, the peer to advertise it, and the hybrid public key of the peer.
+func (c *Crypto) wrapsHybrid(id Identity, capabilities Capabilities) bool {
+	if c.cfg.KeyWrap != config.KeyWrapHybrid || !capabilities.Has(CapabilityHybridKeyWrap) {
+		return false
+	}
+	_, ok := c.idToHybrid[id]
+	return ok
+}
+
+// Overhead returns the largest number of bytes encryption adds to a packet: the wrapped AES-GCM key and nonce, which are as
+// long as the RSA key or hybridWrapSize with the hybrid key wrap, and the authentication tag.
+func (c *Crypto) Overhead() int {
+	wrapSize := c.cfg.PrivateKey.Size()
+	if (c.hybrid != nil || c.cfg.KeyWrap == config.KeyWrapHybrid) && hybridWrapSize > wrapSize {
+		wrapSize = hybridWrapSize
+	}
+	return wrapSize + gcmTagSize
+}
+
 // Sign signs data with rsa-sha256.
 func (c *Crypto) Sign(data []byte) ([]byte, error) {
 	h := sha256.Sum256(data)
@@ -196,7 +362,7 @@ func (c *Crypto) Sign(data []byte) ([]byte, error) {
 func (c *Crypto) VerifySignature(message []byte, sig []byte, id Identity) error {
 	pub, exists := c.idToPub[id]
 	if !exists {
-		zap.L().Error("identity to public key mapping does not exist", zap.String("id", id.String()))
+		cryptoLogger().Error("identity to public key mapping does not exist", zap.String("id", id.String()))
 		return fmt.Errorf("identity to public key mapping does not exist: id %s", id.String())
 	}
 	h := sha256.Sum256(message)
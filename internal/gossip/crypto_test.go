@@ -104,12 +104,12 @@ func TestCrypto_GenerateIdentity(t *testing.T) {
 		}
 
 		// Ensure the Identity has the correct size
-		if len(string(*identity)) != sha256.Size {
+		if len(identity.ToBytes()) != sha256.Size {
 			t.Fatalf("Identity has the wrong size: expected %d, received %d", sha256.Size, len(*identity))
 		}
 
 		// Ensure the String representation of the Identity is correct
-		expectedString := hex.EncodeToString([]byte(*identity))
+		expectedString := hex.EncodeToString(identity.ToBytes())
 		if identity.String() != expectedString {
 			t.Fatalf("String representation of Identity is incorrect: expected %s, received %s", expectedString, identity.String())
 		}
@@ -135,14 +135,14 @@ func TestCrypto_EncryptPacket(t *testing.T) {
 				PrivateKey: privateKey,
 			},
 			idToPub: map[Identity]rsa.PublicKey{
-				"test_identity": otherPeerPrivateKey.PublicKey,
+				testIdentity("test_identity"): otherPeerPrivateKey.PublicKey,
 			},
 		}
 
 		// Data to encrypt
 		data := []byte("Hello, World!")
 
-		ciphertext, err := c.EncryptPacket(data, "test_identity")
+		ciphertext, err := c.EncryptPacket(data, testIdentity("test_identity"), 0)
 		if err != nil {
 			t.Fatal("Error encrypting data:", err)
 		}
@@ -182,14 +182,14 @@ func TestCrypto_DecryptPacket(t *testing.T) {
 				PrivateKey: privateKey,
 			},
 			idToPub: map[Identity]rsa.PublicKey{
-				"test_identity": otherPeerPrivateKey.PublicKey,
+				testIdentity("test_identity"): otherPeerPrivateKey.PublicKey,
 			},
 		}
 
 		// Data to encrypt
 		data := []byte("Hello, World!")
 
-		ciphertext, err := c.EncryptPacket(data, "test_identity")
+		ciphertext, err := c.EncryptPacket(data, testIdentity("test_identity"), 0)
 		if err != nil {
 			t.Fatal("Error encrypting data:", err)
 		}
@@ -249,7 +249,7 @@ func TestCrypto_VerifySignature(t *testing.T) {
 				PrivateKey: privateKey,
 			},
 			idToPub: map[Identity]rsa.PublicKey{
-				"test_identity": privateKey.PublicKey,
+				testIdentity("test_identity"): privateKey.PublicKey,
 			},
 		}
 
@@ -261,20 +261,20 @@ func TestCrypto_VerifySignature(t *testing.T) {
 		}
 
 		// Test case 1: Valid signature
-		err = c.VerifySignature(message, signature, "test_identity")
+		err = c.VerifySignature(message, signature, testIdentity("test_identity"))
 		if err != nil {
 			t.Fatal("Valid signature verification failed:", err)
 		}
 
 		// Test case 2: Invalid signature
 		invalidSignature := []byte("InvalidSignature")
-		err = c.VerifySignature(message, invalidSignature, "test_identity")
+		err = c.VerifySignature(message, invalidSignature, testIdentity("test_identity"))
 		if err == nil {
 			t.Fatal("Invalid signature verification should fail but didn't.")
 		}
 
 		// Test case 3: Identity not found
-		err = c.VerifySignature(message, signature, "non_existent_identity")
+		err = c.VerifySignature(message, signature, testIdentity("non_existent_identity"))
 		if err == nil {
 			t.Fatal("Identity not found verification should fail but didn't.")
 		}
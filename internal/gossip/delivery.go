@@ -0,0 +1,77 @@
+package gossip
+
+import (
+	"encoding/hex"
+	"sort"
+)
+
+// deliveryTracker tracks the distinct peers known to have received the messages announced by local API clients.
+// A peer is known to have received a message once it lists the message within a digest or announcement, or requests it from us.
+// The tracker is not safe for concurrent use.
+type deliveryTracker struct {
+	peers map[messageKey]map[Identity]struct{}
+}
+
+// DeliveryCount represents the number of distinct peers known to have received an announced message.
+type DeliveryCount struct {
+	DataType uint16 `json:"data_type"`
+	// DataHash is the hex-encoded SHA-256 hash of the message's data
+	DataHash string `json:"data_hash"`
+	Peers    int    `json:"peers"`
+}
+
+// newDeliveryTracker returns a new instance of deliveryTracker.
+func newDeliveryTracker() *deliveryTracker {
+	return &deliveryTracker{
+		peers: make(map[messageKey]map[Identity]struct{}),
+	}
+}
+
+// Track starts tracking the delivery of the message with the given key, unless it is already tracked.
+func (dt *deliveryTracker) Track(key messageKey) {
+	if _, ok := dt.peers[key]; !ok {
+		dt.peers[key] = make(map[Identity]struct{})
+	}
+}
+
+// Record records that the given peer received the message with the given key. Returns false if the message is not tracked.
+func (dt *deliveryTracker) Record(key messageKey, peer Identity) bool {
+	peers, ok := dt.peers[key]
+	if ok {
+		peers[peer] = struct{}{}
+	}
+	return ok
+}
+
+// Count returns the number of distinct peers known to have received the message with the given key. Returns false if the message is not tracked.
+func (dt *deliveryTracker) Count(key messageKey) (int, bool) {
+	peers, ok := dt.peers[key]
+	return len(peers), ok
+}
+
+// Prune stops tracking all messages for which keep returns false and returns their number.
+func (dt *deliveryTracker) Prune(keep func(key messageKey) bool) int {
+	pruned := 0
+	for key := range dt.peers {
+		if !keep(key) {
+			delete(dt.peers, key)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// Counts returns the delivery counts of all tracked messages, ordered by data type and data hash.
+func (dt *deliveryTracker) Counts() []DeliveryCount {
+	counts := make([]DeliveryCount, 0, len(dt.peers))
+	for key, peers := range dt.peers {
+		counts = append(counts, DeliveryCount{DataType: key.dataType, DataHash: hex.EncodeToString([]byte(key.dataHash)), Peers: len(peers)})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].DataType != counts[j].DataType {
+			return counts[i].DataType < counts[j].DataType
+		}
+		return counts[i].DataHash < counts[j].DataHash
+	})
+	return counts
+}
@@ -0,0 +1,45 @@
+package gossip
+
+import (
+	"testing"
+)
+
+func TestDeliveryTracker(t *testing.T) {
+	t.Parallel()
+	t.Run("counts distinct peers of tracked messages only", func(t *testing.T) {
+		tracker := newDeliveryTracker()
+		tracked := messageKey{1, "tracked"}
+		tracker.Track(tracked)
+		tracker.Record(tracked, testIdentity("peer1"))
+		tracker.Record(tracked, testIdentity("peer1"))
+		tracker.Record(tracked, testIdentity("peer2"))
+		if tracker.Record(messageKey{1, "untracked"}, testIdentity("peer1")) {
+			t.Error("delivery of an untracked message was recorded")
+		}
+
+		// tracking a message again keeps its deliveries
+		tracker.Track(tracked)
+		if peers, ok := tracker.Count(tracked); !ok || peers != 2 {
+			t.Errorf("expected 2 peers, got %d", peers)
+		}
+		if _, ok := tracker.Count(messageKey{1, "untracked"}); ok {
+			t.Error("untracked message is known")
+		}
+	})
+	t.Run("prunes messages and lists counts", func(t *testing.T) {
+		tracker := newDeliveryTracker()
+		tracker.Track(messageKey{2, "\x01"})
+		tracker.Track(messageKey{1, "\x02"})
+		tracker.Track(messageKey{1, "\x03"})
+		tracker.Record(messageKey{1, "\x02"}, testIdentity("peer1"))
+
+		if pruned := tracker.Prune(func(key messageKey) bool { return key.dataHash != "\x03" }); pruned != 1 {
+			t.Errorf("expected 1 pruned message, got %d", pruned)
+		}
+		counts := tracker.Counts()
+		expected := []DeliveryCount{{DataType: 1, DataHash: "02", Peers: 1}, {DataType: 2, DataHash: "01", Peers: 0}}
+		if len(counts) != len(expected) || counts[0] != expected[0] || counts[1] != expected[1] {
+			t.Errorf("expected %v, got %v", expected, counts)
+		}
+	})
+}
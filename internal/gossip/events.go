@@ -0,0 +1,122 @@
+package gossip
+
+import "sync"
+
+// Event represents an event published on the EventBus. Subscribers distinguish the concrete event types with a type switch.
+type Event interface {
+	isEvent()
+}
+
+// PeerAddedEvent is published whenever a node joins the main view.
+type PeerAddedEvent struct {
+	Round int
+	Node  Node
+}
+
+// PeerLeftEvent is published whenever a peer announces that it shuts down gracefully.
+type PeerLeftEvent struct {
+	Identity Identity
+}
+
+// PeerEvictedEvent is published whenever a peer is evicted from the main view after failing to answer our pings and pull requests for too many consecutive rounds.
+type PeerEvictedEvent struct {
+	Round    int
+	Identity Identity
+}
+
+// RejectReason represents the reason a received packet was rejected.
+type RejectReason string
+
+const (
+	RejectReasonInvalidLength    RejectReason = "invalid_length"
+	RejectReasonDecryption       RejectReason = "decryption_failed"
+	RejectReasonInvalidHeader    RejectReason = "invalid_header"
+	RejectReasonSelfAddressed    RejectReason = "self_addressed"
+	RejectReasonStale            RejectReason = "stale_timestamp"
+	RejectReasonInvalidSignature RejectReason = "invalid_signature"
+	RejectReasonQuarantined      RejectReason = "quarantined"
+)
+
+// PacketRejectedEvent is published whenever a received packet is rejected before being handled.
+type PacketRejectedEvent struct {
+	// FromAddress is the address the packet was received from
+	FromAddress string
+	Reason      RejectReason
+}
+
+// MessageSpreadEvent is published whenever a new gossip message is stored for spreading, either announced by a local API client or received from a peer.
+type MessageSpreadEvent struct {
+	DataType uint16
+	DataHash []byte
+	TTL      uint8
+	// Source is the identity of the peer the message was received from, our own identity for announced messages
+	Source Identity
+}
+
+// ChallengeFailedEvent is published whenever a peer pushes itself with an incorrectly solved challenge.
+type ChallengeFailedEvent struct {
+	Identity Identity
+	Address  string
+}
+
+// RoundCompletedEvent is published at the end of every gossip round, e.g. to signal liveness to a supervisor.
+type RoundCompletedEvent struct {
+	Stats RoundStats
+	// ViewSize is the number of nodes within the main view of the next round
+	ViewSize int
+}
+
+func (PeerAddedEvent) isEvent()       {}
+func (PeerLeftEvent) isEvent()        {}
+func (PeerEvictedEvent) isEvent()     {}
+func (PacketRejectedEvent) isEvent()  {}
+func (MessageSpreadEvent) isEvent()   {}
+func (ChallengeFailedEvent) isEvent() {}
+func (RoundCompletedEvent) isEvent()  {}
+
+// EventHandler represents a subscriber of the EventBus.
+// Handlers are called synchronously from the goroutine publishing the event and should therefore return quickly.
+type EventHandler func(event Event)
+
+// EventBus distributes events of the gossip protocol to all subscribers, e.g. metrics, audit logging, or embedding applications.
+// It is safe for concurrent use, publishing on a nil EventBus does nothing.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[int]EventHandler
+	nextID   int
+}
+
+// NewEventBus returns a new instance of EventBus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: make(map[int]EventHandler)}
+}
+
+// Subscribe registers an EventHandler for all events and returns a function removing the subscription again.
+func (b *EventBus) Subscribe(fn EventHandler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish calls all subscribers with the given event.
+func (b *EventBus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	handlers := make([]EventHandler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,32 @@
+package gossip
+
+import "testing"
+
+func TestEventBus(t *testing.T) {
+	t.Parallel()
+	t.Run("delivers events to subscribers until they unsubscribe", func(t *testing.T) {
+		t.Parallel()
+		bus := NewEventBus()
+		var events []Event
+		unsubscribe := bus.Subscribe(func(event Event) {
+			events = append(events, event)
+		})
+
+		bus.Publish(PacketRejectedEvent{FromAddress: "localhost:7002", Reason: RejectReasonStale})
+		unsubscribe()
+		bus.Publish(PacketRejectedEvent{FromAddress: "localhost:7002", Reason: RejectReasonInvalidSignature})
+
+		if len(events) != 1 {
+			t.Fatalf("expected 1 event, received %d", len(events))
+		}
+		rejected, ok := events[0].(PacketRejectedEvent)
+		if !ok || rejected.Reason != RejectReasonStale {
+			t.Errorf("unexpected event: %v", events[0])
+		}
+	})
+	t.Run("publishing on a nil bus does nothing", func(t *testing.T) {
+		t.Parallel()
+		var bus *EventBus
+		bus.Publish(PeerAddedEvent{})
+	})
+}
@@ -0,0 +1,215 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"gossiphers/internal/challenge"
+	"testing"
+	"time"
+)
+
+// fuzzPacket represents a packet that can be both parsed and serialized.
+type fuzzPacket interface {
+	ParseablePacket
+	WritablePacket
+}
+
+// newFuzzPacket returns an empty packet of the given type, in the same way the server dispatches received packets.
+func newFuzzPacket(messageType MessageType) fuzzPacket {
+	switch messageType {
+	case MessageTypeGossipPing:
+		return &PacketPing{}
+	case MessageTypeGossipPong:
+		return &PacketPong{}
+	case MessageTypeGossipPullRequest:
+		return &PacketPullRequest{}
+	case MessageTypeGossipPullResponse:
+		return &PacketPullResponse{}
+	case MessageTypeGossipPushRequest:
+		return &PacketPushRequest{}
+	case MessageTypeGossipPushChallenge:
+		return &PacketPushChallenge{}
+	case MessageTypeGossipPush:
+		return &PacketPush{}
+	case MessageTypeGossipMessage:
+		return &PacketMessage{}
+	case MessageTypeGossipMessageBatch:
+		return &PacketMessageBatch{}
+	case MessageTypeGossipDigest:
+		return &PacketDigest{}
+	case MessageTypeGossipDigestRequest:
+		return &PacketDigestRequest{}
+	case MessageTypeGossipIHave:
+		return &PacketIHave{}
+	case MessageTypeGossipLeave:
+		return &PacketLeave{}
+	}
+	return nil
+}
+
+// fuzzSeedPackets returns one signed packet of every supported type, serialized by ToBytes.
+func fuzzSeedPackets(f *testing.F) [][]byte {
+	temp := sha256.Sum256(nil)
+	sender, err := NewIdentity(temp[:])
+	if err != nil {
+		f.Fatal(err)
+	}
+	nodes, err := createNodes(3)
+	if err != nil {
+		f.Fatal(err)
+	}
+	hash := sliceRepeat(sha256.Size, byte(0x12))
+	entries := []DigestEntry{{DataType: 1, DataHash: hash}, {DataType: 2, DataHash: hash}}
+	// a fixed timestamp keeps the seed corpus stable
+	now := time.UnixMilli(0x0102030405)
+
+	ping, err := NewPacketPing(*sender, 0x0123456789abcdef, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	pong, err := NewPacketPong(*sender, 0x0123456789abcdef, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	pullRequest, err := NewPacketPullRequest(*sender, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	pullResponse, err := NewPacketPullResponse(*sender, nodes, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	pushRequest, err := NewPacketPushRequest(*sender, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	pushChallenge, err := NewPacketPushChallenge(*sender, 8, sliceRepeat(challenge.ChallengeSize, byte(0x34)), now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	push, err := NewPacketPush(*sender, sliceRepeat(challenge.ChallengeSize, byte(0x34)), sliceRepeat(challenge.NonceSize, byte(0x56)), nodes[0], now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	message, err := NewPacketMessage(*sender, 4, 1337, 1700000000000, []byte("gossip"), now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	batch, err := NewPacketMessageBatch(*sender, []MessageBatchEntry{{TTL: 1, DataType: 1, Data: []byte("a")}, {TTL: 2, Flags: MessageFlagHighPriority | MessageFlagOriginTimestamp, DataType: 2, OriginTimestamp: 1700000000000}}, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	digest, err := NewPacketDigest(*sender, entries, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	digestRequest, err := NewPacketDigestRequest(*sender, entries, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	iHave, err := NewPacketIHave(*sender, entries, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+	leave, err := NewPacketLeave(*sender, now)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, footer := range []*PacketFooter{&ping.PacketFooter, &pong.PacketFooter, &pullRequest.PacketFooter, &pullResponse.PacketFooter,
+		&pushRequest.PacketFooter, &pushChallenge.PacketFooter, &push.PacketFooter, &message.PacketFooter, &batch.PacketFooter,
+		&digest.PacketFooter, &digestRequest.PacketFooter, &iHave.PacketFooter, &leave.PacketFooter} {
+		footer.Signature = createMockSignature()
+	}
+	var seeds [][]byte
+	for _, packet := range []WritablePacket{ping, pong, pullRequest, pullResponse, pushRequest, pushChallenge, push, message, batch, digest, digestRequest, iHave, leave} {
+		seeds = append(seeds, packet.ToBytes())
+	}
+	return seeds
+}
+
+func FuzzParsePacketHeader(f *testing.F) {
+	for _, seed := range fuzzSeedPackets(f) {
+		f.Add(seed[:PacketHeaderSize])
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		header, err := ParsePacketHeader(data)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(header.ToBytes(), data) {
+			t.Errorf("header does not serialize to the parsed bytes: parsed %x, serialized %x", data, header.ToBytes())
+		}
+	})
+}
+
+func FuzzParsePacket(f *testing.F) {
+	for _, seed := range fuzzSeedPackets(f) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) < PacketHeaderSize {
+			return
+		}
+		header, err := ParsePacketHeader(data[:PacketHeaderSize])
+		if err != nil {
+			return
+		}
+		packet := newFuzzPacket(header.Type)
+		if packet == nil {
+			t.Fatalf("no packet for supported type %#x", header.Type)
+		}
+		err = packet.Parse(header, bytes.NewReader(data[PacketHeaderSize:]))
+		if err != nil {
+			return
+		}
+
+		// parsing may normalize invalid UTF-8 within addresses, but a serialized packet must parse to itself
+		serialized := packet.ToBytes()
+		reparsed := newFuzzPacket(header.Type)
+		err = reparsed.Parse(header, bytes.NewReader(serialized[PacketHeaderSize:]))
+		if err != nil {
+			t.Fatalf("serialized packet %x could not be parsed: %v", serialized, err)
+		}
+		if !bytes.Equal(reparsed.ToBytes(), serialized) {
+			t.Errorf("serialized packet changed when parsed again: %x, now %x", serialized, reparsed.ToBytes())
+		}
+	})
+}
+
+func FuzzParseNodes(f *testing.F) {
+	nodes, err := createNodes(3)
+	if err != nil {
+		f.Fatal(err)
+	}
+	var all []byte
+	for _, node := range nodes {
+		f.Add(node.ToBytes())
+		all = append(all, node.ToBytes()...)
+	}
+	f.Add(all)
+	nodes[0].Capabilities = CapabilityCompression | CapabilityRelay
+	f.Add(nodes[0].ToBytes())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parsed, err := parseNodes(data)
+		if err != nil {
+			return
+		}
+		var serialized []byte
+		for _, node := range parsed {
+			serialized = append(serialized, node.ToBytes()...)
+		}
+		reparsed, err := parseNodes(serialized)
+		if err != nil {
+			t.Fatalf("serialized nodes %x could not be parsed: %v", serialized, err)
+		}
+		if len(reparsed) != len(parsed) {
+			t.Fatalf("expected %d nodes after parsing the serialized nodes again, received %d", len(parsed), len(reparsed))
+		}
+		for i := range parsed {
+			if reparsed[i].Identity.String() != parsed[i].Identity.String() || reparsed[i].Address != parsed[i].Address {
+				t.Errorf("node %s changed to %s when parsed again", parsed[i].String(), reparsed[i].String())
+			}
+		}
+	})
+}
@@ -1,15 +1,23 @@
 package gossip
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"expvar"
 	"fmt"
 	"gossiphers/internal/api"
+	"gossiphers/internal/clock"
 	"gossiphers/internal/config"
+	"gossiphers/internal/logging"
+	"io"
 	"math"
 	"math/big"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -25,60 +33,217 @@ type Gossip struct {
 	pullView     *View
 	pullNodes    chan Node
 	mainView     *View
+	// mainViewMu guards the replacement of mainView at the end of a round against concurrent readers such as DumpState
+	mainViewMu   sync.RWMutex
 	samplerGroup *SamplerGroup
+	// ownIdentity is excluded from all views and samplers
+	ownIdentity Identity
+	// bootstrapWatcher picks up nodes added to the bootstrap nodes file while running
+	bootstrapWatcher *bootstrapFileWatcher
+	// viewPolicy decides on the next main view at the end of each round
+	viewPolicy ViewUpdatePolicy
+	// viewChangeHandlers are notified whenever the main view is replaced with different nodes
+	viewChangeHandlers []ViewChangeHandler
+	// events distributes the events of the gossip protocol to subscribers
+	events *EventBus
+	// listening is set once the API and gossip listeners are up
+	listening atomic.Bool
+	// round holds the number of the current round
+	round atomic.Int64
+	// roundMetrics attributes the changes of the counters to the rounds they happened in
+	roundMetrics *roundMetrics
+	// churn accumulates the membership churn and timing of completed rounds
+	churn churnTracker
+	// liveness counts the rounds peers failed to respond in and decides on their eviction from the main view
+	liveness *livenessTracker
+	// peerDB remembers all peers that ever responded, nil if no peer database file is configured
+	peerDB *peerDB
+	// clock drives the rounds and the readiness window
+	clock clock.Clock
+	// random is the source of randomness of the peer selection
+	random io.Reader
+	// stop is closed to end the gossip rounds, done is closed once Start returned
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// logger returns the logger of the gossip subsystem.
+func logger() *zap.Logger {
+	return logging.L(logging.Gossip)
+}
+
+// logger returns the logger of the gossip subsystem annotated with the ID of the current round.
+func (g *Gossip) logger() *zap.Logger {
+	if g.gossipServer == nil {
+		return logger()
+	}
+	return g.gossipServer.logger()
+}
+
+// peerID returns the log field of a peer's identity, shortened to tell peers apart without cluttering the logs.
+func peerID(identity Identity) zap.Field {
+	return zap.String("peer_id", identity.Short())
+}
+
+// peerAddr returns the log field of a peer's address.
+func peerAddr(address string) zap.Field {
+	return zap.String("peer_addr", address)
 }
 
 // NewGossip returns a new instance of Gossip
 func NewGossip(cfg *config.GossipConfig) (*Gossip, error) {
-	apiServer := api.NewServer(cfg)
+	if cfg.Adversary != "" {
+		if !adversaryBuild {
+			return nil, ErrAdversaryUnavailable
+		}
+		logger().Warn("Running as an adversary, this node deliberately misbehaves towards its peers", zap.String("adversary", cfg.Adversary))
+	}
+	apiServer := api.NewServer(cfg, clock.Or(cfg.Clock))
 
 	pushNodes := make(chan Node)
 	pullNodes := make(chan Node)
 	gCrypto, err := NewCrypto(cfg)
 	if err != nil {
-		zap.L().Error("Error initializing crypto")
+		logger().Error("Error initializing crypto")
+		return nil, err
+	}
+	challenger, err := NewChallenger(cfg)
+	if err != nil {
+		logger().Error("Error initializing challenger")
 		return nil, err
 	}
-	gossipServer, err := NewServer(cfg, pushNodes, pullNodes, gCrypto, apiServer)
+	gossipServer, err := NewServer(cfg, pushNodes, pullNodes, gCrypto, challenger, apiServer)
 	if err != nil {
-		zap.L().Error("Error initializing gossip server")
+		logger().Error("Error initializing gossip server")
 		return nil, err
 	}
+	events := NewEventBus()
+	gossipServer.events = events
 
-	pushView := NewView()
-	pullView := NewView()
+	ownIdentity := gossipServer.ownNode.Identity
+	random := randomOr(cfg.Random)
+	pushView := NewView(WithMaxSize(cfg.PushPullViewMaxSize), WithExcludedIdentity(ownIdentity), WithRandom(random), WithClock(gossipServer.clock))
+	pullView := NewView(WithMaxSize(cfg.PushPullViewMaxSize), WithExcludedIdentity(ownIdentity), WithRandom(random), WithClock(gossipServer.clock))
 
-	samplerGroup, err := NewSamplerGroup(cfg.SamplerSize)
+	samplerHash, err := SamplerHashByName(cfg.SamplerHash)
 	if err != nil {
 		return nil, err
 	}
+	samplerGroup, err := NewSamplerGroup(cfg.SamplerSize, WithSamplerExcludedIdentity(ownIdentity), WithSamplerHash(samplerHash), WithSamplerRandom(random), WithSamplerClock(gossipServer.clock))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SamplerStateFile != "" {
+		err = samplerGroup.Load(cfg.SamplerStateFile)
+		if errors.Is(err, os.ErrNotExist) {
+			logger().Info("No sampler state to restore", zap.String("path", cfg.SamplerStateFile))
+		} else if err != nil {
+			logger().Warn("Could not restore sampler state, starting with empty samplers", zap.String("path", cfg.SamplerStateFile), zap.Error(err))
+		} else {
+			logger().Info("Restored sampler state", zap.String("path", cfg.SamplerStateFile), zap.Int("samples", len(samplerGroup.SampleAll())))
+		}
+	}
 
-	bootstrapNodes, err := parseBootstrapNodesStr(cfg.BootstrapNodesStr)
+	bootstrapNodes, err := loadBootstrapNodes(cfg.BootstrapNodesStr, cfg.BootstrapNodesFile)
 	if err != nil {
 		return nil, err
 	}
 
-	mainView := NewView(WithBootstrapNodes(bootstrapNodes))
+	pinnedNodes := gossipServer.pinnedNodes
+	if len(pinnedNodes) >= cfg.ViewSize {
+		return nil, fmt.Errorf("%d pinned nodes leave no room for other nodes within the main view of size %d", len(pinnedNodes), cfg.ViewSize)
+	}
+	mainView := NewView(WithBootstrapNodes(bootstrapNodes), WithPinnedNodes(pinnedNodes), WithMaxSize(cfg.ViewSize), WithExcludedIdentity(ownIdentity), WithRandom(random), WithClock(gossipServer.clock))
 
 	samplerGroup.Update(bootstrapNodes)
 
-	return &Gossip{
-		cfg:          cfg,
-		apiServer:    apiServer,
-		gossipServer: gossipServer,
-		pushView:     pushView,
-		pushNodes:    pushNodes,
-		pullView:     pullView,
-		pullNodes:    pullNodes,
-		mainView:     mainView,
-		samplerGroup: samplerGroup,
-	}, nil
+	var db *peerDB
+	if cfg.PeerDBFile != "" {
+		db = newPeerDB(time.Duration(cfg.PeerDBExpiryMs) * time.Millisecond)
+		err = db.Load(cfg.PeerDBFile)
+		if errors.Is(err, os.ErrNotExist) {
+			logger().Info("No peer database to restore", zap.String("path", cfg.PeerDBFile))
+		} else if err != nil {
+			logger().Warn("Could not restore peer database, starting with an empty database", zap.String("path", cfg.PeerDBFile), zap.Error(err))
+		} else {
+			seedNodes := db.SeedView(mainView, gossipServer.clock.Now())
+			samplerGroup.Update(seedNodes)
+			logger().Info("Restored peer database", zap.String("path", cfg.PeerDBFile), zap.Int("peers", db.Len()), zap.Int("seeded", len(seedNodes)))
+		}
+	}
+
+	g := &Gossip{
+		cfg:              cfg,
+		apiServer:        apiServer,
+		gossipServer:     gossipServer,
+		pushView:         pushView,
+		pushNodes:        pushNodes,
+		pullView:         pullView,
+		pullNodes:        pullNodes,
+		mainView:         mainView,
+		samplerGroup:     samplerGroup,
+		ownIdentity:      ownIdentity,
+		viewPolicy:       BrahmsViewUpdatePolicy{},
+		events:           events,
+		clock:            gossipServer.clock,
+		random:           random,
+		liveness:         newLivenessTracker(cfg.PeerFailureThreshold),
+		peerDB:           db,
+		roundMetrics:     newRoundMetrics(),
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+		bootstrapWatcher: newBootstrapFileWatcher(cfg.BootstrapNodesFile, cfg.BootstrapNodesFileReloadRounds, bootstrapNodes),
+	}
+	if cfg.Participation == config.ParticipationOutboundOnly {
+		g.viewPolicy = OutboundOnlyViewUpdatePolicy{}
+	}
+	metrics.Set(metricChurn, expvar.Func(func() any {
+		return g.ChurnStats()
+	}))
+	metrics.Set(metricRoundMetrics, expvar.Func(func() any {
+		return g.roundMetrics.Snapshot()
+	}))
+	return g, nil
 }
 
-// Start starts the gossip protocol.
+// Events returns the EventBus the events of the gossip protocol are published on.
+func (g *Gossip) Events() *EventBus {
+	return g.events
+}
+
+// ChurnStats returns the membership churn and timing of all rounds completed so far.
+func (g *Gossip) ChurnStats() ChurnStats {
+	return g.churn.Snapshot()
+}
+
+// Ready returns nil once the API and gossip listeners are up and a valid packet was received from any peer within the configured readiness window.
+// Otherwise, it returns an error describing why the node is not ready.
+func (g *Gossip) Ready() error {
+	if !g.listening.Load() {
+		return errors.New("listeners are not up")
+	}
+	window := time.Millisecond * time.Duration(g.cfg.ReadinessPeerWindowMs)
+	lastContact := g.gossipServer.LastPeerContact()
+	if lastContact.IsZero() {
+		return errors.New("no valid packet received from any peer yet")
+	}
+	if since := g.clock.Now().Sub(lastContact); since > window {
+		return fmt.Errorf("no valid packet received from any peer within %s, last one %s ago", window, since.Round(time.Second))
+	}
+	return nil
+}
+
+// SetViewUpdatePolicy replaces the policy deciding on the next main view, which defaults to BrahmsViewUpdatePolicy. It must be called before the gossip protocol is started.
+func (g *Gossip) SetViewUpdatePolicy(policy ViewUpdatePolicy) {
+	g.viewPolicy = policy
+}
+
+// Start starts the gossip protocol. It runs the gossip rounds until an error occurs or Shutdown is called, in which case nil is returned.
 func (g *Gossip) Start() error {
+	defer close(g.done)
 	round := 1
-	zap.L().Info("starting the gossip protocol", zap.Int("round", round))
+	g.logger().Info("starting the gossip protocol", zap.Int("round", round))
 
 	// Start API server
 	err := g.apiServer.Start()
@@ -91,6 +256,7 @@ func (g *Gossip) Start() error {
 	if err != nil {
 		return err
 	}
+	g.listening.Store(true)
 
 	go func() {
 		for {
@@ -111,48 +277,91 @@ func (g *Gossip) Start() error {
 	}()
 
 	for {
+		select {
+		case <-g.stop:
+			g.logger().Info("Stopped the gossip protocol", zap.Int("round", round))
+			return nil
+		default:
+		}
+		roundStart := g.clock.Now()
+		g.round.Store(int64(round))
+		g.gossipServer.startRound(round)
+		// stop contacting peers that left gracefully
+		for _, identity := range g.gossipServer.TakeLeftPeers() {
+			g.mainView.Remove(identity)
+			err := g.samplerGroup.Invalidate(identity)
+			if err != nil {
+				g.logger().Error("Error reinitializing sampler", zap.Error(err))
+			}
+		}
+		// add nodes that have been added to the bootstrap nodes file in the meantime
+		if newNodes := g.bootstrapWatcher.newNodes(round); len(newNodes) > 0 {
+			g.logger().Info("Bootstrap nodes file reloaded", zap.Int("new_nodes", len(newNodes)))
+			for _, node := range newNodes {
+				g.mainView.Append(node)
+			}
+			g.samplerGroup.Update(newNodes)
+		}
+
+		metrics.Add(metricRounds, 1)
 		g.gossipServer.ResetPeerStates()
 		g.pushView.Clear()
 		g.pullView.Clear()
 		mainViewNodes := g.mainView.GetAll()
-		g.gossipServer.UpdatePullResponseNodes(mainViewNodes)
+		g.gossipServer.UpdatePullResponseNodes(g.pullResponseNodes(mainViewNodes))
+		if g.cfg.EagerFanout > 0 {
+			eagerNodes, err := randSubset(g.random, mainViewNodes, g.cfg.EagerFanout)
+			if err != nil {
+				return err
+			}
+			g.gossipServer.SetEagerPeers(eagerNodes)
+		}
 
-		// periodically health-check (ping) nodes within the samplers.
+		// drop sampled nodes which have not been confirmed for too long
+		if g.cfg.SamplerStaleMs > 0 {
+			reinitialized, err := g.samplerGroup.ReinitStale(time.Millisecond * time.Duration(g.cfg.SamplerStaleMs))
+			if err != nil {
+				return err
+			}
+			if reinitialized > 0 {
+				g.logger().Info("Reinitialized samplers with stale nodes", zap.Int("samplers", reinitialized))
+			}
+		}
+
+		// health-check (ping) the nodes within the samplers that are due in this round.
+		// The results are applied to the samplers once all pings finished.
 		var samplerWaitGroup sync.WaitGroup
-		alreadySampled := map[string]struct{}{}
-		if round%g.cfg.RoundsBetweenPings == 0 {
-			for _, sampler := range g.samplerGroup.samplers {
-				if sampler.Sample() == nil {
-					continue
-				}
-				if _, wasSampled := alreadySampled[sampler.Sample().String()]; wasSampled {
-					continue
+		pingedView := g.mainView
+		pingedNodes, err := g.samplerGroup.DuePings(round, g.cfg.RoundsBetweenPings)
+		if err != nil {
+			return err
+		}
+		pingResults := make([]bool, len(pingedNodes))
+		for i, node := range pingedNodes {
+			samplerWaitGroup.Add(1)
+			go func(i int, node *Node) {
+				defer samplerWaitGroup.Done()
+				rtt, online := g.gossipServer.Ping(node, time.Millisecond*time.Duration(g.cfg.PingTimeoutMs))
+				if online {
+					pingedView.RecordPing(node.Identity, rtt)
 				}
-				alreadySampled[sampler.Sample().String()] = struct{}{}
-				samplerWaitGroup.Add(1)
-				movedSampler := sampler
-				go func() {
-					defer samplerWaitGroup.Done()
-					if !g.gossipServer.Ping(movedSampler.Sample(), time.Millisecond*500) {
-						zap.L().Info("Sampler node offline, reinitializing sampler...", zap.String("node", movedSampler.Sample().String()))
-						err = movedSampler.Init()
-						if err != nil {
-							zap.L().Error("Error reinitializing sampler", zap.Error(err))
-						}
-					}
-				}()
-			}
+				pingResults[i] = online
+			}(i, node)
 		}
 
-		pushToNodes, err := randSubset(mainViewNodes, g.AlphaL1())
+		pushToNodes, err := randSubset(g.random, mainViewNodes, g.AlphaL1())
 		if err != nil {
 			return err
 		}
-		for _, node := range pushToNodes {
-			g.gossipServer.SendPushRequest(node)
+		// outbound-only nodes do not push themselves, peers would add them to their views but could not reach them
+		if g.cfg.Participation != config.ParticipationOutboundOnly {
+			for _, node := range g.pushTargets(pushToNodes, mainViewNodes) {
+				g.gossipServer.SendPushRequest(node)
+			}
 		}
+		g.spamMessages()
 
-		pullFromNodes, err := randSubset(mainViewNodes, g.BetaL1())
+		pullFromNodes, err := randSubset(g.random, mainViewNodes, g.BetaL1())
 		if err != nil {
 			return err
 		}
@@ -160,35 +369,148 @@ func (g *Gossip) Start() error {
 			g.gossipServer.SendPullRequest(node)
 		}
 
-		// pause execution for a second while waiting for responses.
-		time.Sleep(1 * time.Second)
-
-		pushViewNodes := g.pushView.GetAll()
-		pullViewNodes := g.pullView.GetAll()
-		if len(pushViewNodes) <= g.AlphaL1() && len(pushViewNodes) > 0 && (len(pullViewNodes) > 0 || len(pullFromNodes) == 0) {
-			randPushViewNodesSubset, err := randSubset(pushViewNodes, g.AlphaL1())
+		// announce messages to additional random peers, independent of alpha and beta
+		var spreadToNodes []*Node
+		if g.cfg.MessageFanout > 0 {
+			spreadToNodes, err = randSubset(g.random, mainViewNodes, g.cfg.MessageFanout)
 			if err != nil {
 				return err
 			}
-			randPullViewNodesSubset, err := randSubset(pullViewNodes, g.BetaL1())
+		}
+		for _, node := range g.spreadTargets(spreadToNodes, pullFromNodes) {
+			g.gossipServer.SpreadMessages(node)
+		}
+
+		// periodically reconcile the message cache with a random peer to catch up on missed messages
+		if g.cfg.AntiEntropyIntervalRounds > 0 && round%g.cfg.AntiEntropyIntervalRounds == 0 {
+			reconcileWithNodes, err := randSubset(g.random, mainViewNodes, 1)
 			if err != nil {
 				return err
 			}
-			randSamplerNodesSubset, err := g.samplerGroup.RandomNodeSubset(g.GammaL1())
-			if err != nil {
-				return err
+			for _, node := range reconcileWithNodes {
+				g.gossipServer.SendDigest(node)
 			}
+		}
 
-			nodes := g.trimDuplicates(randPullViewNodesSubset, randPushViewNodesSubset, randSamplerNodesSubset)
-			g.mainView = NewView(WithBootstrapNodes(nodes))
+		// pause execution while waiting for responses, the round is completed even if the protocol is stopped meanwhile.
+		select {
+		case <-g.clock.After(time.Duration(g.cfg.RoundIntervalMs) * time.Millisecond):
+		case <-g.stop:
 		}
+
 		samplerWaitGroup.Wait()
+		pushViewNodes := g.pushView.GetAll()
+		pullViewNodes := g.pullView.GetAll()
+		responses := g.roundResponses(pingedNodes, pingResults, pullFromNodes, pushViewNodes)
+		evicted := g.liveness.Record(responses)
+		g.recordPeers(responses, pingedNodes, pullFromNodes, pushViewNodes)
+		nodes, update, err := g.viewPolicy.NextView(ViewUpdateInput{
+			PushNodes:    pushViewNodes,
+			PullNodes:    pullViewNodes,
+			PullRequests: len(pullFromNodes),
+			Samplers:     g.samplerGroup,
+			AlphaL1:      g.AlphaL1(),
+			BetaL1:       g.BetaL1(),
+			GammaL1:      g.GammaL1(),
+			Random:       g.random,
+		})
+		if err != nil {
+			return err
+		}
+		roundStats := RoundStats{Round: round}
+		if update {
+			mainView := NewView(WithBootstrapNodes(nodes), WithPinnedNodes(g.gossipServer.pinnedNodes), WithMaxSize(g.cfg.ViewSize), WithExcludedIdentity(g.ownIdentity), WithRandom(g.random), WithClock(g.clock))
+			// peers staying within the view keep their measured round trip times and the time they were last seen
+			mainView.MergeMetadata(g.mainView.GetAll())
+			g.mainViewMu.Lock()
+			g.mainView = mainView
+			g.mainViewMu.Unlock()
+		} else {
+			g.logger().Debug("Keeping main view", zap.Int("pushed_nodes", len(pushViewNodes)), zap.Int("pulled_nodes", len(pullViewNodes)))
+		}
+		// evicted peers are removed even if they were just selected again, e.g. from stale pull responses
+		g.evict(round, evicted)
+		added, removed := g.notifyViewChange(round, mainViewNodes, g.mainView.GetAll())
+		roundStats.Added, roundStats.Removed = len(added), len(removed)
+		for i, node := range pingedNodes {
+			if pingResults[i] {
+				g.samplerGroup.MarkValidated(node.Identity)
+				continue
+			}
+			g.logger().Info("Sampler node offline, reinitializing sampler...", peerID(node.Identity), peerAddr(node.Address))
+			err = g.samplerGroup.Invalidate(node.Identity)
+			if err != nil {
+				g.logger().Error("Error reinitializing sampler", zap.Error(err))
+			}
+		}
 		g.samplerGroup.Update(pushViewNodes)
 		g.samplerGroup.Update(pullViewNodes)
+		g.persistState()
+
+		roundStats.Duration = g.clock.Now().Sub(roundStart)
+		roundStats.FirstResponse = g.gossipServer.FirstResponseDelay()
+		g.churn.Record(roundStats)
+		g.roundMetrics.Record(round)
+		g.events.Publish(RoundCompletedEvent{Stats: roundStats, ViewSize: g.mainView.NodeCount()})
+		g.logger().Debug("Round completed", zap.Int("round", round), zap.Int("added", roundStats.Added), zap.Int("removed", roundStats.Removed), zap.Duration("duration", roundStats.Duration), zap.Duration("first_response", roundStats.FirstResponse))
 
 		// increment round
 		round++
-		zap.L().Info("new round starting", zap.Int("round", round), zap.Int("current_view_size", g.mainView.NodeCount()))
+		setGauge(metricMainViewSize, g.mainView.NodeCount())
+		g.logger().Info("new round starting", zap.Int("round", round), zap.Int("current_view_size", g.mainView.NodeCount()))
+	}
+}
+
+// Shutdown stops the gossip protocol gracefully after the current round: it tells the nodes of the main view that we leave,
+// persists the sampler and message state, and closes the API and gossip listeners. It must only be called after Start.
+// If the context expires before the current round completes, only the listeners are closed and the context's error is returned.
+func (g *Gossip) Shutdown(ctx context.Context) error {
+	g.stopOnce.Do(func() { close(g.stop) })
+	var err error
+	select {
+	case <-g.done:
+		mainViewNodes := g.mainView.GetAll()
+		for i := range mainViewNodes {
+			if sendErr := g.gossipServer.SendLeave(&mainViewNodes[i]); sendErr != nil {
+				g.logger().Warn("Could not send leave packet", peerID(mainViewNodes[i].Identity), peerAddr(mainViewNodes[i].Address), zap.Error(sendErr))
+			}
+		}
+		g.logger().Info("Sent leave packets", zap.Int("nodes", len(mainViewNodes)))
+		g.persistState()
+	case <-ctx.Done():
+		err = ctx.Err()
+		g.logger().Warn("Gossip round did not complete in time, shutting down without leaving", zap.Error(err))
+	}
+
+	g.listening.Store(false)
+	if closeErr := g.apiServer.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if closeErr := g.gossipServer.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// persistState saves the sampler and message state and the peer database to their files, if configured.
+func (g *Gossip) persistState() {
+	if g.cfg.SamplerStateFile != "" {
+		err := g.samplerGroup.Save(g.cfg.SamplerStateFile)
+		if err != nil {
+			g.logger().Warn("Could not persist sampler state", zap.String("path", g.cfg.SamplerStateFile), zap.Error(err))
+		}
+	}
+	if g.cfg.MessageStoreFile != "" {
+		err := g.gossipServer.SaveMessages(g.cfg.MessageStoreFile)
+		if err != nil {
+			g.logger().Warn("Could not persist messages", zap.String("path", g.cfg.MessageStoreFile), zap.Error(err))
+		}
+	}
+	if g.peerDB != nil {
+		err := g.peerDB.Save(g.cfg.PeerDBFile, g.clock.Now())
+		if err != nil {
+			g.logger().Warn("Could not persist peer database", zap.String("path", g.cfg.PeerDBFile), zap.Error(err))
+		}
 	}
 }
 
@@ -208,7 +530,7 @@ func (g *Gossip) GammaL1() int {
 }
 
 // trimDuplicates combines slices of nodes while trimming the duplicates.
-func (g *Gossip) trimDuplicates(listNodes ...[]*Node) []Node {
+func trimDuplicates(listNodes ...[]*Node) []Node {
 	unique := make(map[string]bool)
 	result := make([]Node, 0)
 	for _, nodes := range listNodes {
@@ -231,29 +553,36 @@ func parseBootstrapNodesStr(nodesStr string) ([]Node, error) {
 		if nodePair == "" {
 			continue
 		}
-		parts := strings.Split(nodePair, ",")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("node list encoding incorrect: not able to identify the identity and address of the node: received %s and decoded it into %v", nodePair, parts)
-		}
-		identity, err := hex.DecodeString(parts[0])
-		if err != nil {
-			return nil, err
-		}
-		node, err := NewNode(identity, parts[1])
+		node, err := ParseNode(nodePair)
 		if err != nil {
 			return nil, err
 		}
+		node.Source = SourceBootstrap
 		nodes = append(nodes, *node)
 	}
 	return nodes, nil
 }
 
+// ParseNode parses a node of the form <identity>,<address>, where the identity is hex-encoded.
+func ParseNode(nodeStr string) (*Node, error) {
+	parts := strings.Split(nodeStr, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("node list encoding incorrect: not able to identify the identity and address of the node: received %s and decoded it into %v", nodeStr, parts)
+	}
+	identity, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	return NewNode(identity, parts[1])
+}
+
 // RandomSubset returns a random subset of up to length n of the nodes. If n is greater then len(nodes), only a random subset of len(nodes) will be returned.
-func randSubset(nodes []Node, desiredNum int) ([]*Node, error) {
+// The subset is drawn from the given source of randomness, crypto/rand if it is nil.
+func randSubset(random io.Reader, nodes []Node, desiredNum int) ([]*Node, error) {
 	if desiredNum == 0 {
 		return []*Node{}, nil
 	} else if desiredNum > len(nodes) {
-		return randSubset(nodes, len(nodes))
+		return randSubset(random, nodes, len(nodes))
 	} else if desiredNum < 0 {
 		return nil, fmt.Errorf("desiredNum cannot be negative: received %d", desiredNum)
 	}
@@ -269,7 +598,7 @@ func randSubset(nodes []Node, desiredNum int) ([]*Node, error) {
 	// Iterate through the slice in reverse order
 	for ii := n - 1; ii > 0; ii-- {
 		// Generate a random index between 0 and i (inclusive)
-		j, err := rand.Int(rand.Reader, big.NewInt(int64(ii+1)))
+		j, err := rand.Int(randomOr(random), big.NewInt(int64(ii+1)))
 		if err != nil {
 			panic(err)
 		}
@@ -1,17 +1,26 @@
 package gossip
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"gossiphers/internal/clock"
+	"gossiphers/internal/config"
+	"gossiphers/internal/transport"
+	"net"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_randSubset(t *testing.T) {
 	t.Parallel()
 	t.Run("with an empty slice and n == 0", func(t *testing.T) {
 		emptySlice := []Node{}
-		res, err := randSubset(emptySlice, 0)
+		res, err := randSubset(nil, emptySlice, 0)
 		if err != nil {
 			t.Error(err)
 		}
@@ -25,7 +34,7 @@ func Test_randSubset(t *testing.T) {
 			t.Error(err)
 		}
 		singleElementSlice := []Node{nodes[0]}
-		resPtrs, err := randSubset(singleElementSlice, 1)
+		resPtrs, err := randSubset(nil, singleElementSlice, 1)
 		if err != nil {
 			t.Error(err)
 		}
@@ -42,7 +51,7 @@ func Test_randSubset(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		originalSlicePtrs, err := randSubset(uniqueSlice, 5)
+		originalSlicePtrs, err := randSubset(nil, uniqueSlice, 5)
 		originalSlice := dereferenceSlice(originalSlicePtrs)
 		if err != nil {
 			t.Error(err)
@@ -59,7 +68,7 @@ func Test_randSubset(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		originalLargeSlicePtrs, err := randSubset(largeSlice, 40)
+		originalLargeSlicePtrs, err := randSubset(nil, largeSlice, 40)
 		if err != nil {
 			t.Error(err)
 		}
@@ -76,7 +85,7 @@ func Test_randSubset(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		originalRepeatedSlicePtrs, err := randSubset(repeatedSlice, 5)
+		originalRepeatedSlicePtrs, err := randSubset(nil, repeatedSlice, 5)
 		if err != nil {
 			t.Error(err)
 		}
@@ -89,7 +98,7 @@ func Test_randSubset(t *testing.T) {
 		if err != nil {
 			t.Error(err)
 		}
-		originalRepeatedSlicePtrs, err := randSubset(repeatedSlice, 0)
+		originalRepeatedSlicePtrs, err := randSubset(nil, repeatedSlice, 0)
 		if err != nil {
 			t.Error(err)
 		}
@@ -100,12 +109,29 @@ func Test_randSubset(t *testing.T) {
 			t.Error("expecting slice of length 0")
 		}
 	})
+	t.Run("subsets are reproducible with a seeded source of randomness", func(t *testing.T) {
+		s, err := createNodes(20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, err := randSubset(NewSeededRandom(3), s, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := randSubset(NewSeededRandom(3), s, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(first, second) {
+			t.Error("expected the same subset from the same seed")
+		}
+	})
 	t.Run("with n < 0", func(t *testing.T) {
 		s, err := createNodes(10)
 		if err != nil {
 			t.Error(err)
 		}
-		_, err = randSubset(s, -3)
+		_, err = randSubset(nil, s, -3)
 		if err == nil {
 			t.Error("expecting errror")
 		}
@@ -137,3 +163,176 @@ func createNodes(n int) ([]Node, error) {
 	}
 	return nodes, nil
 }
+
+func TestGossip_NewGossip(t *testing.T) {
+	t.Parallel()
+	t.Run("creates gossip from a programmatically built config", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes, err := createNodes(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bootstrapNodesStr := nodes[0].Identity.String() + "," + nodes[0].Address + "|" + nodes[1].Identity.String() + "," + nodes[1].Address
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithPeerPublicKeys(&privateKey.PublicKey), config.WithBootstrapNodes(bootstrapNodesStr))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := NewGossip(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.mainView.NodeCount() != 2 {
+			t.Errorf("expected the bootstrap nodes in the main view, received %d nodes", g.mainView.NodeCount())
+		}
+		if len(g.samplerGroup.SampleAll()) != cfg.SamplerSize {
+			t.Errorf("expected all samplers to be filled with bootstrap nodes")
+		}
+	})
+	t.Run("own node is excluded from bootstrap nodes and pull responses", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ownIdentity, err := generateIdentity(&privateKey.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes, err := createNodes(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bootstrapNodesStr := ownIdentity.String() + ",localhost:7002|" + nodes[0].Identity.String() + "," + nodes[0].Address
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithPeerPublicKeys(&privateKey.PublicKey), config.WithBootstrapNodes(bootstrapNodesStr))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		g, err := NewGossip(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.mainView.Contains(*ownIdentity) || g.mainView.NodeCount() != 1 {
+			t.Errorf("own node must not be part of the main view")
+		}
+		for _, sample := range g.samplerGroup.SampleAll() {
+			if sample.Identity == *ownIdentity {
+				t.Fatalf("own node must not be sampled")
+			}
+		}
+		g.gossipServer.UpdatePullResponseNodes([]Node{{Identity: *ownIdentity, Address: "localhost:7002"}, nodes[0]})
+		if len(g.gossipServer.pullResponseNodes) != 1 || g.gossipServer.pullResponseNodes[0].Identity != nodes[0].Identity {
+			t.Errorf("own node must not be part of pull responses: %v", g.gossipServer.pullResponseNodes)
+		}
+	})
+}
+
+func TestGossip_Shutdown(t *testing.T) {
+	t.Parallel()
+	t.Run("stops the rounds and closes the listeners", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithPeerPublicKeys(&privateKey.PublicKey),
+			config.WithGossipAddress("127.0.0.1:0"), config.WithApiAddress("127.0.0.1:0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := NewGossip(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		started := make(chan error, 1)
+		go func() { started <- g.Start() }()
+		for !g.listening.Load() {
+			time.Sleep(10 * time.Millisecond)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err = g.Shutdown(ctx); err != nil {
+			t.Fatalf("expected a graceful shutdown, received %v", err)
+		}
+		if err = <-started; err != nil {
+			t.Errorf("expected Start to return nil after Shutdown, received %v", err)
+		}
+		if g.listening.Load() {
+			t.Error("gossip is still listening after Shutdown")
+		}
+		if _, err = g.gossipServer.listener.WriteTo([]byte{0}, g.gossipServer.listener.LocalAddr()); !errors.Is(err, net.ErrClosed) {
+			t.Errorf("expected the gossip listener to be closed, received %v", err)
+		}
+	})
+}
+
+func TestGossip_Start(t *testing.T) {
+	t.Parallel()
+	t.Run("rounds advance with the clock", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, RSAKeySize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fakeClock := clock.NewFake(time.Unix(1700000000, 0))
+		cfg, err := config.New(config.WithPrivateKey(privateKey), config.WithPeerPublicKeys(&privateKey.PublicKey),
+			config.WithGossipAddress("127.0.0.1:7001"), config.WithApiAddress("127.0.0.1:0"),
+			config.WithClock(fakeClock), config.WithNetwork(transport.NewLoopback()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := NewGossip(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		started := make(chan error, 1)
+		go func() { started <- g.Start() }()
+		interval := time.Duration(cfg.RoundIntervalMs) * time.Millisecond
+		for rounds := 1; rounds <= 3; rounds++ {
+			// the challenger's key rotation ticker and the timer of the current round
+			fakeClock.BlockUntil(2)
+			fakeClock.Advance(interval)
+			fakeClock.BlockUntil(2)
+			stats := g.ChurnStats()
+			if stats.Rounds != rounds || stats.Last.Duration != interval {
+				t.Fatalf("expected %d rounds of %s, received %d rounds, the last one of %s", rounds, interval, stats.Rounds, stats.Last.Duration)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err = g.Shutdown(ctx); err != nil {
+			t.Fatalf("expected a graceful shutdown, received %v", err)
+		}
+		if err = <-started; err != nil {
+			t.Errorf("expected Start to return nil after Shutdown, received %v", err)
+		}
+	})
+}
+
+func TestParseNode(t *testing.T) {
+	t.Parallel()
+	t.Run("parses identity and address", func(t *testing.T) {
+		t.Parallel()
+		identity := "fc392157e4a730749ae31476fdbdfa5881587ef46b2d53c8d25b7490c06dad7c"
+		node, err := ParseNode(identity + ",127.0.0.1:7002")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Identity.String() != identity || node.Address != "127.0.0.1:7002" {
+			t.Errorf("unexpected node %s", node)
+		}
+	})
+	t.Run("rejects malformed nodes", func(t *testing.T) {
+		t.Parallel()
+		for _, nodeStr := range []string{"", "127.0.0.1:7002", "zz,127.0.0.1:7002", "abcd,127.0.0.1:7002", "a,b,c"} {
+			_, err := ParseNode(nodeStr)
+			if err == nil {
+				t.Errorf("expected an error for %q", nodeStr)
+			}
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package gossip
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gossiphers/internal/config"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// HostkeyBits represents the size of the RSA keys in bits, determined by the fixed signature size of the packets.
+	HostkeyBits = SignatureSize * 8
+	// HostkeyFileName represents the name of the file the private key is written to by WriteHostkey.
+	HostkeyFileName = "hostkey.pem"
+	// rsaPublicKey represents the type of the PEM blocks holding public keys within the hostkeys folder.
+	rsaPublicKey = "RSA PUBLIC KEY"
+	// pkixPublicKey represents the type of PEM blocks holding public keys in PKIX encoding, as written by OpenSSL.
+	pkixPublicKey = "PUBLIC KEY"
+)
+
+var (
+	ErrNoKeyFound = errors.New("no RSA key found")
+)
+
+// GenerateHostkey generates a new RSA private key usable as the hostkey of a node.
+func GenerateHostkey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, HostkeyBits)
+}
+
+// IdentityOf returns the identity derived from the public key of a hostkey.
+func IdentityOf(privateKey *rsa.PrivateKey) (*Identity, error) {
+	return generateIdentity(&privateKey.PublicKey)
+}
+
+// IdentityOfPublicKey returns the identity derived from a public key, the SHA-256 hash of its PKCS#1 encoding.
+func IdentityOfPublicKey(publicKey *rsa.PublicKey) (*Identity, error) {
+	return generateIdentity(publicKey)
+}
+
+// WriteHostkey writes the private key PEM followed by the hybrid private key PEM to HostkeyFileName and the public key PEM followed by the hybrid public key PEM to a file named by the hex-encoded identity within dir, creating dir if necessary.
+// The public key file is meant to be copied into the hostkeys folder of the peers. Returns the identity of the key.
+func WriteHostkey(dir string, privateKey *rsa.PrivateKey, hybridKey []byte) (*Identity, error) {
+	identity, err := IdentityOf(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	hybridPEM, err := HybridPublicKeyPEM(hybridKey)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("could not create key directory: %w", err)
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: config.RSAPrivateKey, Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	err = writeNewFile(filepath.Join(dir, HostkeyFileName), append(privatePEM, HybridPrivateKeyPEM(hybridKey)...), 0600)
+	if err != nil {
+		return nil, err
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: rsaPublicKey, Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)})
+	err = writeNewFile(filepath.Join(dir, identity.String()), append(publicPEM, hybridPEM...), 0644)
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// AppendHybridKey generates a new hybrid private key and appends its PEM block to the hostkey file at the given path, which must
+// not hold a hybrid private key yet. Returns the PEM block of the hybrid public key, which belongs into the public key file of
+// the node within the hostkeys folders of its peers.
+func AppendHybridKey(path string) ([]byte, error) {
+	existing, err := config.ReadHybridPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("hostkey file %s already holds a hybrid private key", path)
+	}
+	hybridKey, err := GenerateHybridKey()
+	if err != nil {
+		return nil, err
+	}
+	hybridPEM, err := HybridPublicKeyPEM(hybridKey)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not open key file: %w", err)
+	}
+	_, err = file.Write(HybridPrivateKeyPEM(hybridKey))
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("could not write key file %s: %w", path, err)
+	}
+	return hybridPEM, file.Close()
+}
+
+// writeNewFile writes data to a file that must not exist yet, so existing keys are never overwritten.
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return fmt.Errorf("could not create key file: %w", err)
+	}
+	_, err = file.Write(data)
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("could not write key file %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// ReadPublicKey reads the RSA public key of the first key found within the PEM file at the given path.
+// Both private and public keys are accepted, public keys may be PKCS#1 or PKIX encoded.
+func ReadPublicKey(path string) (*rsa.PublicKey, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file %s: %w", path, err)
+	}
+	for {
+		block, rest := pem.Decode(pemData)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case config.RSAPrivateKey:
+			privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the private key in %s: %w", path, err)
+			}
+			return &privateKey.PublicKey, nil
+		case rsaPublicKey:
+			publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the public key in %s: %w", path, err)
+			}
+			return publicKey, nil
+		case pkixPublicKey:
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse the public key in %s: %w", path, err)
+			}
+			publicKey, ok := key.(*rsa.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("%w: the public key in %s is of type %T", ErrNoKeyFound, path, key)
+			}
+			return publicKey, nil
+		}
+		pemData = rest
+	}
+	return nil, fmt.Errorf("%w: file %s does not contain an RSA key", ErrNoKeyFound, path)
+}
+
+// Fingerprint returns the SHA-256 fingerprint of a public key, the unpadded base64 encoding of the hash of its PKIX (DER) encoding prefixed with "SHA256:".
+// Unlike the identity, it matches the hash of the key as exported by common tools such as `openssl pkey -pubout -outform DER`.
+func Fingerprint(publicKey *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(der)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,190 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"gossiphers/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteHostkey(t *testing.T) {
+	t.Parallel()
+	privateKey, err := GenerateHostkey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hybridKey, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("writes keys readable by the configuration and crypto", func(t *testing.T) {
+		t.Parallel()
+		dir := filepath.Join(t.TempDir(), "keys")
+		identity, err := WriteHostkey(dir, privateKey, hybridKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		readKey, err := config.ReadPrivateKey(filepath.Join(dir, HostkeyFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !readKey.Equal(privateKey) {
+			t.Error("read private key differs from the written one")
+		}
+		readHybridKey, err := config.ReadHybridPrivateKey(filepath.Join(dir, HostkeyFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(readHybridKey, hybridKey) {
+			t.Error("read hybrid private key differs from the written one")
+		}
+
+		hostkeysPath := t.TempDir()
+		err = os.Rename(filepath.Join(dir, identity.String()), filepath.Join(hostkeysPath, identity.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewCrypto(&config.GossipConfig{HostkeysPath: hostkeysPath})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := c.idToPub[*identity]; !ok {
+			t.Errorf("public key of identity %s not loaded", identity)
+		}
+		if _, ok := c.idToHybrid[*identity]; !ok {
+			t.Errorf("hybrid public key of identity %s not loaded", identity)
+		}
+	})
+
+	t.Run("appends a hybrid key to a hostkey without one", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), HostkeyFileName)
+		privatePEM := pem.EncodeToMemory(&pem.Block{Type: config.RSAPrivateKey, Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+		if err := os.WriteFile(path, privatePEM, 0600); err != nil {
+			t.Fatal(err)
+		}
+		hybridPEM, err := AppendHybridKey(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		readHybridKey, err := config.ReadHybridPrivateKey(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := HybridPublicKeyPEM(readHybridKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(hybridPEM, expected) {
+			t.Error("expected the hybrid public key of the appended hybrid private key")
+		}
+		if readKey, err := config.ReadPrivateKey(path); err != nil || !readKey.Equal(privateKey) {
+			t.Errorf("expected the hostkey to be kept, received %v", err)
+		}
+		if _, err = AppendHybridKey(path); err == nil {
+			t.Error("expected an error for a hostkey already holding a hybrid key")
+		}
+	})
+
+	t.Run("does not overwrite existing keys", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		_, err := WriteHostkey(dir, privateKey, hybridKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = WriteHostkey(dir, privateKey, hybridKey)
+		if !errors.Is(err, os.ErrExist) {
+			t.Errorf("expected error %v, received %v", os.ErrExist, err)
+		}
+	})
+}
+
+func TestReadPublicKey(t *testing.T) {
+	t.Parallel()
+	privateKey, err := GenerateHostkey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hybridKey, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	identity, err := WriteHostkey(dir, privateKey, hybridKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkixDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkixPath := filepath.Join(dir, "pkix.pem")
+	err = os.WriteFile(pkixPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkixDER}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emptyPath := filepath.Join(dir, "empty.pem")
+	err = os.WriteFile(emptyPath, []byte("no key here"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, path := range map[string]string{
+		"private key":       filepath.Join(dir, HostkeyFileName),
+		"PKCS#1 public key": filepath.Join(dir, identity.String()),
+		"PKIX public key":   pkixPath,
+	} {
+		path := path
+		t.Run("reads the public key of a "+name, func(t *testing.T) {
+			t.Parallel()
+			publicKey, err := ReadPublicKey(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !publicKey.Equal(&privateKey.PublicKey) {
+				t.Error("read public key differs from the written one")
+			}
+		})
+	}
+
+	t.Run("fails without a key", func(t *testing.T) {
+		t.Parallel()
+		_, err := ReadPublicKey(emptyPath)
+		if !errors.Is(err, ErrNoKeyFound) {
+			t.Errorf("expected error %v, received %v", ErrNoKeyFound, err)
+		}
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+	privateKey, err := GenerateHostkey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("hashes the PKIX encoding", func(t *testing.T) {
+		t.Parallel()
+		fingerprint, err := Fingerprint(&privateKey.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := sha256.Sum256(der)
+		expected := "SHA256:" + base64.RawStdEncoding.EncodeToString(hash[:])
+		if fingerprint != expected {
+			t.Errorf("expected %s, received %s", expected, fingerprint)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package gossip
+
+import (
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/mlkem"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"gossiphers/internal/config"
+)
+
+const (
+	// HybridKeyWrapVersion represents the first protocol version allowing the packet keys to be wrapped with the hybrid KEM
+	// instead of RSA-OAEP, which is chosen by the configured key wrap independently of the version.
+	HybridKeyWrapVersion uint8 = 4
+	// x25519KeySize represents the size of X25519 public keys.
+	x25519KeySize = 32
+	// HybridPublicKeySize represents the size of an encoded hybrid public key: the X25519 public key followed by the ML-KEM-768
+	// encapsulation key.
+	HybridPublicKeySize = x25519KeySize + mlkem.EncapsulationKeySize768
+	// hybridPrivateKeySize represents the size of an encoded hybrid private key: the X25519 private key followed by the seed of
+	// the ML-KEM-768 decapsulation key.
+	hybridPrivateKeySize = x25519KeySize + mlkem.SeedSize
+	// hybridWrapSize represents the size of a wrapped packet key: the ephemeral X25519 public key of the sender followed by the
+	// ML-KEM-768 ciphertext.
+	hybridWrapSize = x25519KeySize + mlkem.CiphertextSize768
+	// hybridPublicKeyBlock represents the type of the PEM blocks holding hybrid public keys, which follow the RSA public key
+	// within the files of the hostkeys folder.
+	hybridPublicKeyBlock = "GOSSIP HYBRID KEM PUBLIC KEY"
+	// hybridWrapLabel binds the packet keys to their use by the hybrid key wrap.
+	hybridWrapLabel = "gossiphers hybrid key wrap"
+)
+
+// hybridPrivateKey represents the X25519 and ML-KEM-768 private keys unwrapping the packet keys if the hybrid key wrap is
+// configured. A packet key is only recovered by breaking both, so the confidentiality of recorded packets survives
+// the advent of quantum computers breaking X25519 as well as a flaw within the younger ML-KEM.
+type hybridPrivateKey struct {
+	x25519 *ecdh.PrivateKey
+	mlkem  *mlkem.DecapsulationKey768
+	// public represents the encoded hybrid public key
+	public []byte
+}
+
+// hybridPublicKey represents the X25519 and ML-KEM-768 public keys of a peer the packet keys are wrapped for.
+type hybridPublicKey struct {
+	x25519 *ecdh.PublicKey
+	mlkem  *mlkem.EncapsulationKey768
+	// encoded represents the encoded hybrid public key
+	encoded []byte
+}
+
+// GenerateHybridKey generates a new encoded hybrid private key, independently of the hostkey, so breaking either of them
+// reveals nothing about the other. It is written to the hostkey file by WriteHostkey.
+func GenerateHybridKey() ([]byte, error) {
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate x25519 key: %w", err)
+	}
+	mlkemKey, err := mlkem.GenerateKey768()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate ml-kem key: %w", err)
+	}
+	return append(x25519Key.Bytes(), mlkemKey.Bytes()...), nil
+}
+
+// parseHybridPrivateKey parses an encoded hybrid private key.
+func parseHybridPrivateKey(encoded []byte) (*hybridPrivateKey, error) {
+	if len(encoded) != hybridPrivateKeySize {
+		return nil, fmt.Errorf("hybrid private key must consist of %d bytes: received %d bytes", hybridPrivateKeySize, len(encoded))
+	}
+	x25519Key, err := ecdh.X25519().NewPrivateKey(encoded[:x25519KeySize])
+	if err != nil {
+		return nil, fmt.Errorf("invalid x25519 private key: %w", err)
+	}
+	mlkemKey, err := mlkem.NewDecapsulationKey768(encoded[x25519KeySize:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ml-kem seed: %w", err)
+	}
+	public := append(x25519Key.PublicKey().Bytes(), mlkemKey.EncapsulationKey().Bytes()...)
+	return &hybridPrivateKey{x25519: x25519Key, mlkem: mlkemKey, public: public}, nil
+}
+
+// parseHybridPublicKey parses an encoded hybrid public key.
+func parseHybridPublicKey(encoded []byte) (*hybridPublicKey, error) {
+	if len(encoded) != HybridPublicKeySize {
+		return nil, fmt.Errorf("hybrid public key must consist of %d bytes: received %d bytes", HybridPublicKeySize, len(encoded))
+	}
+	x25519Key, err := ecdh.X25519().NewPublicKey(encoded[:x25519KeySize])
+	if err != nil {
+		return nil, fmt.Errorf("invalid x25519 public key: %w", err)
+	}
+	mlkemKey, err := mlkem.NewEncapsulationKey768(encoded[x25519KeySize:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ml-kem encapsulation key: %w", err)
+	}
+	return &hybridPublicKey{x25519: x25519Key, mlkem: mlkemKey, encoded: encoded}, nil
+}
+
+// HybridPublicKey returns the encoded hybrid public key of an encoded hybrid private key.
+func HybridPublicKey(privateKey []byte) ([]byte, error) {
+	hybridKey, err := parseHybridPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return hybridKey.public, nil
+}
+
+// HybridPublicKeyPEM returns the PEM block of the hybrid public key of an encoded hybrid private key, which belongs into the
+// public key file of the node within the hostkeys folders of its peers.
+func HybridPublicKeyPEM(privateKey []byte) ([]byte, error) {
+	public, err := HybridPublicKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: hybridPublicKeyBlock, Bytes: public}), nil
+}
+
+// HybridPrivateKeyPEM returns the PEM block of an encoded hybrid private key, which follows the hostkey within the hostkey file.
+func HybridPrivateKeyPEM(privateKey []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: config.HybridPrivateKey, Bytes: privateKey})
+}
+
+// wrapHybrid generates a fresh packet key and nonce for the peer owning the public key and returns them along with the wrapped
+// key, which the peer unwraps by unwrapHybrid.
+func wrapHybrid(public *hybridPublicKey) (keyAndNonce []byte, wrapped []byte, err error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate ephemeral x25519 key: %w", err)
+	}
+	x25519Secret, err := ephemeral.ECDH(public.x25519)
+	if err != nil {
+		return nil, nil, err
+	}
+	mlkemSecret, ciphertext := public.mlkem.Encapsulate()
+	wrapped = append(ephemeral.PublicKey().Bytes(), ciphertext...)
+	keyAndNonce, err = deriveHybridPacketKey(mlkemSecret, x25519Secret, wrapped, public.encoded)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyAndNonce, wrapped, nil
+}
+
+// unwrapHybrid recovers the packet key and nonce from a key of hybridWrapSize bytes wrapped for the private key.
+func unwrapHybrid(private *hybridPrivateKey, wrapped []byte) ([]byte, error) {
+	ephemeral, err := ecdh.X25519().NewPublicKey(wrapped[:x25519KeySize])
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral x25519 key: %w", err)
+	}
+	x25519Secret, err := private.x25519.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	mlkemSecret, err := private.mlkem.Decapsulate(wrapped[x25519KeySize:hybridWrapSize])
+	if err != nil {
+		return nil, err
+	}
+	return deriveHybridPacketKey(mlkemSecret, x25519Secret, wrapped[:hybridWrapSize], private.public)
+}
+
+// deriveHybridPacketKey derives the packet key and nonce from both shared secrets, bound to the wrapped key and the public key
+// of the receiver, so the packet key stays secret as long as either of the secrets does.
+func deriveHybridPacketKey(mlkemSecret []byte, x25519Secret []byte, wrapped []byte, receiver []byte) ([]byte, error) {
+	secret := append(append([]byte{}, mlkemSecret...), x25519Secret...)
+	info := append(append([]byte(hybridWrapLabel), wrapped...), receiver...)
+	return hkdf.Key(sha256.New, secret, nil, string(info), PacketKeySize+gcmNonceSize)
+}
@@ -0,0 +1,148 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"gossiphers/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestCrypto_HybridKeyWrap(t *testing.T) {
+	t.Parallel()
+	senderKey, err := rsa.GenerateKey(rand.Reader, RSAKeySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiverKey, err := rsa.GenerateKey(rand.Reader, RSAKeySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiverIdentity, err := IdentityOf(receiverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	senderIdentity, err := IdentityOf(senderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	senderHybridPrivateKey, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiverHybridPrivateKey, err := GenerateHybridKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiverHybridKey, err := HybridPublicKey(receiverHybridPrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newConfig := func(t *testing.T, options ...config.Option) *config.GossipConfig {
+		cfg, err := config.New(append([]config.Option{config.WithProtocolVersion(int(HybridKeyWrapVersion)), config.WithKeyWrap(config.KeyWrapHybrid)}, options...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	newCrypto := func(t *testing.T, options ...config.Option) *Crypto {
+		c, err := NewCrypto(newConfig(t, options...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	sender := newCrypto(t, config.WithPrivateKey(senderKey), config.WithHybridPrivateKey(senderHybridPrivateKey), config.WithPeerPublicKeys(&receiverKey.PublicKey),
+		config.WithPeerHybridPublicKey(receiverIdentity.String(), receiverHybridKey))
+	// the receiver still wraps packet keys with RSA-OAEP and does not know the hybrid public key of the sender yet, as during
+	// the switch of a network to the hybrid key wrap
+	receiver := newCrypto(t, config.WithKeyWrap(config.KeyWrapRSAOAEP), config.WithPrivateKey(receiverKey), config.WithHybridPrivateKey(receiverHybridPrivateKey),
+		config.WithPeerPublicKeys(&senderKey.PublicKey))
+
+	t.Run("encrypted packets are decrypted by the receiver", func(t *testing.T) {
+		t.Parallel()
+		data := []byte("Hello, World!")
+		ciphertext, err := sender.EncryptPacket(data, *receiverIdentity, BuildCapabilities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ciphertext) != len(data)+sender.Overhead() {
+			t.Errorf("expected an overhead of %d bytes, received %d bytes", sender.Overhead(), len(ciphertext)-len(data))
+		}
+		decrypted, err := receiver.DecryptPacket(ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, decrypted) {
+			t.Errorf("encrypted and decrypted data do not match\n%x != %x", data, decrypted)
+		}
+	})
+	t.Run("packets with a tampered wrapped key are not decrypted", func(t *testing.T) {
+		t.Parallel()
+		ciphertext, err := sender.EncryptPacket([]byte("Hello, World!"), *receiverIdentity, BuildCapabilities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// flip a bit of the ML-KEM ciphertext, the X25519 secret alone must not recover the packet key
+		ciphertext[x25519KeySize] ^= 1
+		if _, err = receiver.DecryptPacket(ciphertext); err == nil {
+			t.Error("expected the tampered packet not to be decrypted")
+		}
+	})
+	t.Run("packets for other peers are not decrypted", func(t *testing.T) {
+		t.Parallel()
+		ciphertext, err := sender.EncryptPacket([]byte("Hello, World!"), *receiverIdentity, BuildCapabilities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = sender.DecryptPacket(ciphertext); err == nil {
+			t.Error("expected the packet for another peer not to be decrypted")
+		}
+	})
+	t.Run("packets wrapped with RSA-OAEP are decrypted by nodes with a hybrid private key", func(t *testing.T) {
+		t.Parallel()
+		data := []byte("Hello, World!")
+		ciphertext, err := receiver.EncryptPacket(data, *senderIdentity, BuildCapabilities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ciphertext) != len(data)+senderKey.Size()+gcmTagSize {
+			t.Errorf("expected the packet key to be wrapped with RSA-OAEP, received an overhead of %d bytes", len(ciphertext)-len(data))
+		}
+		decrypted, err := sender.DecryptPacket(ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, decrypted) {
+			t.Errorf("encrypted and decrypted data do not match\n%x != %x", data, decrypted)
+		}
+	})
+	t.Run("configured peers without hybrid public key are rejected", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewCrypto(newConfig(t, config.WithPrivateKey(receiverKey), config.WithHybridPrivateKey(receiverHybridPrivateKey), config.WithPeerPublicKeys(&senderKey.PublicKey)))
+		if err == nil || !strings.Contains(err.Error(), senderIdentity.String()) {
+			t.Errorf("expected an error naming the peer %s without hybrid public key, received %v", senderIdentity, err)
+		}
+	})
+	t.Run("hybrid keys are generated independently", func(t *testing.T) {
+		t.Parallel()
+		other, err := GenerateHybridKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		otherPublic, err := HybridPublicKey(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(otherPublic, receiverHybridKey) || len(otherPublic) != HybridPublicKeySize {
+			t.Error("expected a different hybrid public key for each generated hybrid key")
+		}
+	})
+	t.Run("malformed hybrid private keys are rejected", func(t *testing.T) {
+		t.Parallel()
+		if _, err := HybridPublicKey(receiverHybridPrivateKey[1:]); err == nil {
+			t.Error("expected an error for a truncated hybrid private key")
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package gossip
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyBucketBounds are the upper bounds of the buckets of round trip time histograms, the last bucket is unbounded.
+var latencyBucketBounds = []time.Duration{
+	time.Millisecond, 2 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 200 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second,
+}
+
+// latencyRetention represents how long the round trip times of a peer are kept after its last sample.
+const latencyRetention = 10 * time.Minute
+
+// LatencyStats summarizes the most recent round trip times of a single kind measured to a peer.
+type LatencyStats struct {
+	Samples int           `json:"samples"`
+	Median  time.Duration `json:"median_ns"`
+	P90     time.Duration `json:"p90_ns"`
+	// Histogram holds the number of samples per bucket, whose upper bounds are 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, and 2000 ms, followed by an unbounded bucket
+	Histogram []int `json:"histogram"`
+}
+
+// PeerLatency represents the round trip times of pings and of pull requests until their response measured to a peer.
+type PeerLatency struct {
+	Identity Identity     `json:"-"`
+	Ping     LatencyStats `json:"ping"`
+	Pull     LatencyStats `json:"pull"`
+}
+
+// rttWindow holds the most recent round trip times of a single kind, overwriting the oldest one once full.
+type rttWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+// add adds a round trip time, keeping at most size samples.
+func (w *rttWindow) add(rtt time.Duration, size int) {
+	if len(w.samples) < size {
+		w.samples = append(w.samples, rtt)
+		return
+	}
+	w.samples[w.next] = rtt
+	w.next = (w.next + 1) % size
+}
+
+// stats summarizes the samples of the window.
+func (w *rttWindow) stats() LatencyStats {
+	stats := LatencyStats{Samples: len(w.samples), Histogram: make([]int, len(latencyBucketBounds)+1)}
+	if len(w.samples) == 0 {
+		return stats
+	}
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats.Median = sorted[(len(sorted)-1)/2]
+	stats.P90 = sorted[(len(sorted)-1)*9/10]
+	for _, rtt := range sorted {
+		bucket := sort.Search(len(latencyBucketBounds), func(i int) bool { return rtt <= latencyBucketBounds[i] })
+		stats.Histogram[bucket]++
+	}
+	return stats
+}
+
+// peerRTT holds the round trip times measured to a single peer.
+type peerRTT struct {
+	ping       rttWindow
+	pull       rttWindow
+	lastSample time.Time
+}
+
+// latencyTable measures the round trip times of pings and pull requests per peer, keeping a rolling window of the most recent samples.
+// It is safe for concurrent use.
+type latencyTable struct {
+	mu         sync.Mutex
+	windowSize int
+	peers      map[Identity]*peerRTT
+	// pendingPulls holds the time pull requests were sent to peers which did not respond yet
+	pendingPulls map[Identity]time.Time
+}
+
+// newLatencyTable returns a new instance of latencyTable keeping windowSize samples per peer and kind.
+func newLatencyTable(windowSize int) *latencyTable {
+	return &latencyTable{
+		windowSize:   windowSize,
+		peers:        make(map[Identity]*peerRTT),
+		pendingPulls: make(map[Identity]time.Time),
+	}
+}
+
+// RecordPing adds the round trip time of a successful ping to a peer, whose pong was received at the given time.
+func (lt *latencyTable) RecordPing(identity Identity, rtt time.Duration, receivedAt time.Time) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	peer := lt.peer(identity)
+	peer.ping.add(rtt, lt.windowSize)
+	peer.lastSample = receivedAt
+}
+
+// StartPull remembers that a pull request was sent to a peer at the given time.
+func (lt *latencyTable) StartPull(identity Identity, sentAt time.Time) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.pendingPulls[identity] = sentAt
+}
+
+// FinishPull adds the round trip time of the pending pull request to a peer, which responded at the given time.
+// Returns false if no pull request to the peer is pending.
+func (lt *latencyTable) FinishPull(identity Identity, receivedAt time.Time) bool {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	sentAt, ok := lt.pendingPulls[identity]
+	if !ok {
+		return false
+	}
+	delete(lt.pendingPulls, identity)
+	peer := lt.peer(identity)
+	peer.pull.add(receivedAt.Sub(sentAt), lt.windowSize)
+	peer.lastSample = receivedAt
+	return true
+}
+
+// Prune forgets pending pull requests, which can no longer be answered, and peers without samples since the retention period.
+func (lt *latencyTable) Prune(now time.Time) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.pendingPulls = make(map[Identity]time.Time)
+	for identity, peer := range lt.peers {
+		if now.Sub(peer.lastSample) > latencyRetention {
+			delete(lt.peers, identity)
+		}
+	}
+}
+
+// Snapshot returns the round trip time statistics of all peers, ordered by identity.
+func (lt *latencyTable) Snapshot() []PeerLatency {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	latencies := make([]PeerLatency, 0, len(lt.peers))
+	for identity, peer := range lt.peers {
+		latencies = append(latencies, PeerLatency{Identity: identity, Ping: peer.ping.stats(), Pull: peer.pull.stats()})
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i].Identity.Compare(latencies[j].Identity) < 0 })
+	return latencies
+}
+
+// peer returns the round trip times of a peer, creating them if necessary. The caller must hold the mutex.
+func (lt *latencyTable) peer(identity Identity) *peerRTT {
+	peer, ok := lt.peers[identity]
+	if !ok {
+		peer = &peerRTT{}
+		lt.peers[identity] = peer
+	}
+	return peer
+}
@@ -0,0 +1,65 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyTable(t *testing.T) {
+	t.Parallel()
+	t.Run("summarizes the most recent pings", func(t *testing.T) {
+		t.Parallel()
+		table := newLatencyTable(3)
+		for _, rtt := range []time.Duration{time.Second, time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond} {
+			table.RecordPing(testIdentity("peer1"), rtt, time.Now())
+		}
+		latencies := table.Snapshot()
+		if len(latencies) != 1 {
+			t.Fatalf("expected 1 peer, received %d", len(latencies))
+		}
+		ping := latencies[0].Ping
+		if ping.Samples != 3 || ping.Median != 3*time.Millisecond || ping.P90 != 3*time.Millisecond {
+			t.Errorf("unexpected ping statistics: %+v", ping)
+		}
+		// 1 ms falls into the first bucket, 3 and 4 ms into the bucket up to 5 ms
+		if ping.Histogram[0] != 1 || ping.Histogram[2] != 2 {
+			t.Errorf("unexpected histogram: %v", ping.Histogram)
+		}
+		if latencies[0].Pull.Samples != 0 {
+			t.Errorf("unexpected pull samples: %+v", latencies[0].Pull)
+		}
+	})
+	t.Run("measures pull requests until their response", func(t *testing.T) {
+		t.Parallel()
+		table := newLatencyTable(3)
+		sentAt := time.Now()
+		if table.FinishPull(testIdentity("peer1"), sentAt) {
+			t.Error("finished pull request that was never started")
+		}
+		table.StartPull(testIdentity("peer1"), sentAt)
+		if !table.FinishPull(testIdentity("peer1"), sentAt.Add(20*time.Millisecond)) {
+			t.Error("pending pull request not finished")
+		}
+		if table.FinishPull(testIdentity("peer1"), sentAt.Add(30*time.Millisecond)) {
+			t.Error("pull request finished twice")
+		}
+		pull := table.Snapshot()[0].Pull
+		if pull.Samples != 1 || pull.Median != 20*time.Millisecond {
+			t.Errorf("unexpected pull statistics: %+v", pull)
+		}
+	})
+	t.Run("forgets pending pull requests and idle peers", func(t *testing.T) {
+		t.Parallel()
+		table := newLatencyTable(3)
+		table.RecordPing(testIdentity("peer1"), time.Millisecond, time.Now())
+		table.StartPull(testIdentity("peer2"), time.Now())
+		table.Prune(time.Now())
+		if table.FinishPull(testIdentity("peer2"), time.Now()) || len(table.Snapshot()) != 1 {
+			t.Error("expected pending pull requests to be forgotten")
+		}
+		table.Prune(time.Now().Add(2 * latencyRetention))
+		if len(table.Snapshot()) != 0 {
+			t.Error("expected idle peer to be forgotten")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package gossip
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// livenessTracker counts the consecutive rounds in which a peer failed to answer our pings and pull requests, so peers that
+// stopped responding are evicted from the main view instead of occupying push and pull slots every round.
+// It is only used by the goroutine running the gossip rounds and therefore not safe for concurrent use.
+type livenessTracker struct {
+	// threshold is the number of consecutive failures after which a peer is evicted, 0 disables eviction
+	threshold int
+	failures  map[Identity]int
+}
+
+// newLivenessTracker returns a tracker evicting peers after the given number of consecutive failures, 0 never evicts.
+func newLivenessTracker(threshold int) *livenessTracker {
+	return &livenessTracker{threshold: threshold, failures: make(map[Identity]int)}
+}
+
+// Record takes whether each peer contacted during a round responded and returns the peers that reached the failure threshold.
+// A response resets the failures of a peer. If no peer responded at all, we most likely lost connectivity ourselves, so the
+// round is not held against any peer. The failures of evicted peers are forgotten, giving them the full number of attempts
+// again once they return to the view.
+func (lt *livenessTracker) Record(responded map[Identity]bool) []Identity {
+	if lt.threshold <= 0 {
+		return nil
+	}
+	anyResponse := false
+	for _, ok := range responded {
+		anyResponse = anyResponse || ok
+	}
+	if !anyResponse {
+		return nil
+	}
+	var evicted []Identity
+	for identity, ok := range responded {
+		if ok {
+			delete(lt.failures, identity)
+			continue
+		}
+		lt.failures[identity]++
+		if lt.failures[identity] >= lt.threshold {
+			delete(lt.failures, identity)
+			evicted = append(evicted, identity)
+		}
+	}
+	sort.Slice(evicted, func(i, j int) bool { return evicted[i].Compare(evicted[j]) < 0 })
+	return evicted
+}
+
+// Failures returns the number of consecutive failures of a peer.
+func (lt *livenessTracker) Failures(identity Identity) int {
+	return lt.failures[identity]
+}
+
+// roundResponses returns whether each peer we pinged or sent a pull request to within the round responded. Peers that pushed
+// their identity to us responded as well.
+func (g *Gossip) roundResponses(pingedNodes []*Node, pingResults []bool, pulledNodes []*Node, pushedNodes []Node) map[Identity]bool {
+	responded := make(map[Identity]bool)
+	for i, node := range pingedNodes {
+		responded[node.Identity] = responded[node.Identity] || pingResults[i]
+	}
+	for _, node := range pulledNodes {
+		responded[node.Identity] = responded[node.Identity] || g.gossipServer.AnsweredPull(node.Identity)
+	}
+	for _, node := range pushedNodes {
+		responded[node.Identity] = true
+	}
+	return responded
+}
+
+// evict removes peers that stopped responding from the main view and reinitializes the samplers holding them, pinned peers are kept.
+func (g *Gossip) evict(round int, identities []Identity) {
+	for _, identity := range identities {
+		if g.mainView.IsPinned(identity) {
+			g.logger().Debug("Keeping unresponsive pinned peer in the main view", peerID(identity), zap.Int("failed_rounds", g.cfg.PeerFailureThreshold))
+			continue
+		}
+		g.logger().Info("Evicting unresponsive peer from the main view", peerID(identity), zap.Int("failed_rounds", g.cfg.PeerFailureThreshold))
+		g.mainView.Remove(identity)
+		if err := g.samplerGroup.Invalidate(identity); err != nil {
+			g.logger().Error("Error reinitializing sampler", zap.Error(err))
+		}
+		metrics.Add(metricPeersEvicted, 1)
+		g.events.Publish(PeerEvictedEvent{Round: round, Identity: identity})
+	}
+}
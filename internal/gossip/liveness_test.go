@@ -0,0 +1,56 @@
+package gossip
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLivenessTracker_Record(t *testing.T) {
+	t.Parallel()
+	t.Run("peers are evicted after the threshold of consecutive failures", func(t *testing.T) {
+		t.Parallel()
+		lt := newLivenessTracker(3)
+		for round := 1; round <= 2; round++ {
+			if evicted := lt.Record(map[Identity]bool{testIdentity("alive"): true, testIdentity("dead"): false}); len(evicted) != 0 {
+				t.Fatalf("expected no eviction in round %d, received %v", round, evicted)
+			}
+		}
+		if evicted := lt.Record(map[Identity]bool{testIdentity("alive"): true, testIdentity("dead"): false}); !reflect.DeepEqual(evicted, []Identity{testIdentity("dead")}) {
+			t.Errorf("expected the dead peer to be evicted, received %v", evicted)
+		}
+		if lt.Failures(testIdentity("dead")) != 0 {
+			t.Errorf("expected the failures of an evicted peer to be forgotten, received %d", lt.Failures(testIdentity("dead")))
+		}
+	})
+	t.Run("a response resets the failures", func(t *testing.T) {
+		t.Parallel()
+		lt := newLivenessTracker(2)
+		lt.Record(map[Identity]bool{testIdentity("alive"): true, testIdentity("flaky"): false})
+		lt.Record(map[Identity]bool{testIdentity("alive"): true, testIdentity("flaky"): true})
+		if evicted := lt.Record(map[Identity]bool{testIdentity("alive"): true, testIdentity("flaky"): false}); len(evicted) != 0 {
+			t.Errorf("expected the failures to be reset by the response, received the eviction of %v", evicted)
+		}
+		if lt.Failures(testIdentity("flaky")) != 1 {
+			t.Errorf("expected 1 failure, received %d", lt.Failures(testIdentity("flaky")))
+		}
+	})
+	t.Run("rounds without any response are not counted", func(t *testing.T) {
+		t.Parallel()
+		lt := newLivenessTracker(1)
+		if evicted := lt.Record(map[Identity]bool{testIdentity("peer1"): false, testIdentity("peer2"): false}); len(evicted) != 0 {
+			t.Errorf("expected no eviction while no peer responds, received %v", evicted)
+		}
+		if lt.Failures(testIdentity("peer1")) != 0 {
+			t.Errorf("expected no failure to be counted, received %d", lt.Failures(testIdentity("peer1")))
+		}
+	})
+	t.Run("a threshold of 0 never evicts", func(t *testing.T) {
+		t.Parallel()
+		lt := newLivenessTracker(0)
+		for round := 0; round < 10; round++ {
+			if evicted := lt.Record(map[Identity]bool{testIdentity("alive"): true, testIdentity("dead"): false}); len(evicted) != 0 {
+				t.Fatalf("expected no eviction, received %v", evicted)
+			}
+		}
+	})
+}
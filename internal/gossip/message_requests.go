@@ -0,0 +1,65 @@
+package gossip
+
+import (
+	"sync"
+)
+
+// messageRequestTracker tracks the messages we requested from peers within the current round after they announced them in a
+// digest or IHave. A peer we did not otherwise allow to send messages may only send the requested messages, each of them once,
+// so announcing messages never opens a window for arbitrary messages. The number of outstanding requests per peer is bounded.
+// It is safe for concurrent use.
+type messageRequestTracker struct {
+	// maxPerPeer is the maximum number of messages outstanding per peer
+	maxPerPeer int
+	peers      map[Identity]map[messageKey]struct{}
+	mu         sync.Mutex
+}
+
+// newMessageRequestTracker returns a tracker without outstanding requests, which allows maxPerPeer outstanding messages per peer.
+func newMessageRequestTracker(maxPerPeer int) *messageRequestTracker {
+	return &messageRequestTracker{maxPerPeer: maxPerPeer, peers: make(map[Identity]map[messageKey]struct{})}
+}
+
+// Request records the messages of the entries as requested from the peer and returns the entries to request. Entries already
+// outstanding and entries exceeding the bound of outstanding messages of the peer are left out.
+func (mt *messageRequestTracker) Request(identity Identity, entries []DigestEntry) []DigestEntry {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	requested, ok := mt.peers[identity]
+	if !ok {
+		requested = make(map[messageKey]struct{})
+		mt.peers[identity] = requested
+	}
+	var requests []DigestEntry
+	for _, entry := range entries {
+		if len(requested) >= mt.maxPerPeer {
+			break
+		}
+		key := messageKey{entry.DataType, string(entry.DataHash)}
+		if _, ok := requested[key]; ok {
+			continue
+		}
+		requested[key] = struct{}{}
+		requests = append(requests, entry)
+	}
+	return requests
+}
+
+// Receive records that the peer sent the message with the given key and returns whether we requested it from the peer.
+// Each request is only answered once.
+func (mt *messageRequestTracker) Receive(identity Identity, key messageKey) bool {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if _, ok := mt.peers[identity][key]; !ok {
+		return false
+	}
+	delete(mt.peers[identity], key)
+	return true
+}
+
+// Reset forgets all requests at the start of a round.
+func (mt *messageRequestTracker) Reset() {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	mt.peers = make(map[Identity]map[messageKey]struct{})
+}
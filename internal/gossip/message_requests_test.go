@@ -0,0 +1,50 @@
+package gossip
+
+import (
+	"testing"
+)
+
+func TestMessageRequestTracker(t *testing.T) {
+	t.Parallel()
+	entries := []DigestEntry{{DataType: 1, DataHash: []byte{1}}, {DataType: 1, DataHash: []byte{2}}, {DataType: 2, DataHash: []byte{1}}}
+	t.Run("requested messages are received once", func(t *testing.T) {
+		t.Parallel()
+		mt := newMessageRequestTracker(10)
+		if requests := mt.Request(testIdentity("peer1"), entries[:1]); len(requests) != 1 {
+			t.Fatalf("expected the entry to be requested, received %v", requests)
+		}
+		if mt.Receive(testIdentity("peer2"), messageKey{1, "\x01"}) {
+			t.Error("message requested from another peer was received")
+		}
+		if !mt.Receive(testIdentity("peer1"), messageKey{1, "\x01"}) {
+			t.Error("requested message was not received")
+		}
+		if mt.Receive(testIdentity("peer1"), messageKey{1, "\x01"}) {
+			t.Error("requested message was received twice")
+		}
+	})
+	t.Run("outstanding requests are bounded per peer", func(t *testing.T) {
+		t.Parallel()
+		mt := newMessageRequestTracker(2)
+		mt.Request(testIdentity("peer1"), entries[:1])
+		requests := mt.Request(testIdentity("peer1"), entries)
+		if len(requests) != 1 || requests[0].DataHash[0] != 2 {
+			t.Fatalf("expected only the second entry to be requested, received %v", requests)
+		}
+		if mt.Receive(testIdentity("peer1"), messageKey{2, "\x01"}) {
+			t.Error("message exceeding the bound was received")
+		}
+		if requests = mt.Request(testIdentity("peer2"), entries); len(requests) != 2 {
+			t.Errorf("expected the bound to apply per peer, received %v", requests)
+		}
+	})
+	t.Run("requests are forgotten with the round", func(t *testing.T) {
+		t.Parallel()
+		mt := newMessageRequestTracker(10)
+		mt.Request(testIdentity("peer1"), entries)
+		mt.Reset()
+		if mt.Receive(testIdentity("peer1"), messageKey{1, "\x01"}) {
+			t.Error("message requested within the previous round was received")
+		}
+	})
+}
@@ -0,0 +1,283 @@
+package gossip
+
+import (
+	"container/list"
+	"gossiphers/internal/config"
+	"math"
+	"sort"
+	"time"
+)
+
+// messageKey identifies a gossip message by its data type and the hash of its data.
+type messageKey struct {
+	dataType uint16
+	dataHash string
+}
+
+// sourceTypeKey identifies the messages of a single data type originating from the same peer.
+type sourceTypeKey struct {
+	source   Identity
+	dataType uint16
+}
+
+// messageStore holds the gossip messages that are currently spread or remembered to prevent receiving them again.
+// It is bounded by a maximum number of messages and a maximum number of data bytes. Once a bound is exceeded,
+// messages that are no longer spread are evicted first, least recently received ones first, followed by the least recently received spreadable messages.
+// Data types may be limited further by their policy, in which case only messages of the same data type are evicted once the limit of a data type is exceeded.
+// Likewise, the number of messages with infinite hops (TTL 0) may be limited, in which case only those are evicted once their limit is exceeded.
+// The store is not safe for concurrent use.
+type messageStore struct {
+	maxMessages int
+	maxBytes    int
+	policies    map[uint16]config.DataTypePolicy
+	// maxInfiniteMessages represents the maximum number of stored messages with infinite hops, 0 means unlimited
+	maxInfiniteMessages int
+
+	// order contains *spreadableMessage values, least recently received first
+	order             *list.List
+	index             map[messageKey]*list.Element
+	countBySource     map[Identity]int
+	countBySourceType map[sourceTypeKey]int
+	countByType       map[uint16]int
+	bytesByType       map[uint16]int
+	bytes             int
+	infiniteCount     int
+}
+
+// newMessageStore returns a new instance of messageStore.
+func newMessageStore(maxMessages int, maxBytes int, policies map[uint16]config.DataTypePolicy) *messageStore {
+	return &messageStore{
+		maxMessages:       maxMessages,
+		maxBytes:          maxBytes,
+		policies:          policies,
+		order:             list.New(),
+		index:             make(map[messageKey]*list.Element),
+		countBySource:     make(map[Identity]int),
+		countBySourceType: make(map[sourceTypeKey]int),
+		countByType:       make(map[uint16]int),
+		bytesByType:       make(map[uint16]int),
+	}
+}
+
+// Contains checks whether a message with the given data type and data hash is stored.
+func (ms *messageStore) Contains(dataType uint16, dataHash []byte) bool {
+	_, ok := ms.index[messageKey{dataType, string(dataHash)}]
+	return ok
+}
+
+// Touch marks the message with the given data type and data hash as recently received. Returns false if no such message is stored.
+func (ms *messageStore) Touch(dataType uint16, dataHash []byte) bool {
+	element, ok := ms.index[messageKey{dataType, string(dataHash)}]
+	if ok {
+		ms.order.MoveToBack(element)
+	}
+	return ok
+}
+
+// CountFromSource returns the number of stored messages originating from the given peer.
+func (ms *messageStore) CountFromSource(source Identity) int {
+	return ms.countBySource[source]
+}
+
+// CountFromSourceOfType returns the number of stored messages of the given data type originating from the given peer.
+func (ms *messageStore) CountFromSourceOfType(source Identity, dataType uint16) int {
+	return ms.countBySourceType[sourceTypeKey{source, dataType}]
+}
+
+// Add stores a message, evicting other messages if a bound is exceeded.
+// Returns false if the message is already known, in which case it is marked as recently received, if it exceeds the byte bound on its own,
+// or if it was evicted right away to restore a bound, e.g. a message that is no longer spread within a full data type.
+func (ms *messageStore) Add(msg spreadableMessage) bool {
+	key := messageKey{msg.DataType, string(msg.DataHash)}
+	if element, ok := ms.index[key]; ok {
+		ms.order.MoveToBack(element)
+		return false
+	}
+	policy := ms.policies[msg.DataType]
+	if (ms.maxBytes > 0 && len(msg.Data) > ms.maxBytes) || (policy.MaxBytes > 0 && len(msg.Data) > policy.MaxBytes) {
+		return false
+	}
+
+	ms.index[key] = ms.order.PushBack(&msg)
+	ms.countBySource[msg.SourceIdentity]++
+	ms.countBySourceType[sourceTypeKey{msg.SourceIdentity, msg.DataType}]++
+	ms.countByType[msg.DataType]++
+	ms.bytesByType[msg.DataType] += len(msg.Data)
+	ms.bytes += len(msg.Data)
+	if msg.TTL == 0 {
+		ms.infiniteCount++
+	}
+	for (policy.MaxMessages > 0 && ms.countByType[msg.DataType] > policy.MaxMessages) || (policy.MaxBytes > 0 && ms.bytesByType[msg.DataType] > policy.MaxBytes) {
+		ms.evictOne(func(other *spreadableMessage) bool { return other.DataType == msg.DataType })
+	}
+	for ms.maxInfiniteMessages > 0 && ms.infiniteCount > ms.maxInfiniteMessages {
+		ms.evictOne(func(other *spreadableMessage) bool { return other.TTL == 0 })
+	}
+	for (ms.maxMessages > 0 && ms.order.Len() > ms.maxMessages) || (ms.maxBytes > 0 && ms.bytes > ms.maxBytes) {
+		ms.evictOne(nil)
+	}
+	_, stored := ms.index[key]
+	return stored
+}
+
+// Remove deletes the message with the given data type and data hash. Returns false if no such message is stored.
+func (ms *messageStore) Remove(dataType uint16, dataHash []byte) bool {
+	element, ok := ms.index[messageKey{dataType, string(dataHash)}]
+	if !ok {
+		return false
+	}
+	ms.removeElement(element)
+	return true
+}
+
+// Decay decreases the local TTL of all messages and evicts those whose local TTL reached the floor. Returns the number of evicted messages.
+func (ms *messageStore) Decay(floor int) int {
+	evicted := 0
+	for element := ms.order.Front(); element != nil; {
+		next := element.Next()
+		msg := element.Value.(*spreadableMessage)
+		msg.LocalTTL--
+		if msg.LocalTTL <= floor {
+			ms.removeElement(element)
+			evicted++
+		}
+		element = next
+	}
+	return evicted
+}
+
+// Expire stops spreading all messages that passed their expiry time by setting their local TTL to 0. Returns the number of newly expired messages.
+// Expired messages are remembered until their local TTL reaches the floor, like all messages that are no longer spread.
+func (ms *messageStore) Expire(now time.Time) int {
+	expired := 0
+	for element := ms.order.Front(); element != nil; element = element.Next() {
+		msg := element.Value.(*spreadableMessage)
+		if msg.LocalTTL > 0 && msg.isExpired(now) {
+			msg.LocalTTL = 0
+			expired++
+		}
+	}
+	return expired
+}
+
+// EvictInfiniteOlderThan evicts the messages with infinite hops that were received more than maxAge ago. Returns the number of evicted messages.
+func (ms *messageStore) EvictInfiniteOlderThan(maxAge time.Duration, now time.Time) int {
+	evicted := 0
+	for element := ms.order.Front(); element != nil; {
+		next := element.Next()
+		msg := element.Value.(*spreadableMessage)
+		if msg.TTL == 0 && now.Sub(msg.ReceivedAt) > maxAge {
+			ms.removeElement(element)
+			evicted++
+		}
+		element = next
+	}
+	return evicted
+}
+
+// Spreadable returns copies of all messages that are still spread, i.e. have a local TTL greater than 0 and did not expire before now.
+// Messages of the high-priority lane come first. Within each lane, messages are ordered by the priority of their data type, highest first, and least recently received first within the same priority.
+// Deprioritized messages are spread after all other messages.
+func (ms *messageStore) Spreadable(now time.Time) []spreadableMessage {
+	var messages []spreadableMessage
+	for element := ms.order.Front(); element != nil; element = element.Next() {
+		msg := element.Value.(*spreadableMessage)
+		if msg.LocalTTL > 0 && !msg.isExpired(now) {
+			messages = append(messages, *msg)
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		if messages[i].inHighPriorityLane() != messages[j].inHighPriorityLane() {
+			return messages[i].inHighPriorityLane()
+		}
+		return ms.priority(messages[i]) > ms.priority(messages[j])
+	})
+	return messages
+}
+
+// priority returns the spread priority of a message.
+func (ms *messageStore) priority(msg spreadableMessage) int {
+	if msg.Deprioritized {
+		return math.MinInt
+	}
+	return ms.policies[msg.DataType].Priority
+}
+
+// Get returns a copy of the message with the given data type and data hash. Returns false if no such message is stored.
+func (ms *messageStore) Get(dataType uint16, dataHash []byte) (spreadableMessage, bool) {
+	element, ok := ms.index[messageKey{dataType, string(dataHash)}]
+	if !ok {
+		return spreadableMessage{}, false
+	}
+	return *element.Value.(*spreadableMessage), true
+}
+
+// Digest returns the digest entries of up to limit stored messages, most recently received first.
+// Messages that are no longer spread are included as well, so peers that missed them can still catch up.
+func (ms *messageStore) Digest(limit int) []DigestEntry {
+	var entries []DigestEntry
+	for element := ms.order.Back(); element != nil && len(entries) < limit; element = element.Prev() {
+		msg := element.Value.(*spreadableMessage)
+		entries = append(entries, DigestEntry{DataType: msg.DataType, DataHash: msg.DataHash})
+	}
+	return entries
+}
+
+// Len returns the number of stored messages.
+func (ms *messageStore) Len() int {
+	return ms.order.Len()
+}
+
+// Bytes returns the total size of the data of all stored messages.
+func (ms *messageStore) Bytes() int {
+	return ms.bytes
+}
+
+// evictOne evicts the least recently received message that is no longer spread, or the least recently received message if all are still spread.
+// If match is given, only messages it matches are considered.
+func (ms *messageStore) evictOne(match func(msg *spreadableMessage) bool) {
+	var oldest *list.Element
+	for element := ms.order.Front(); element != nil; element = element.Next() {
+		msg := element.Value.(*spreadableMessage)
+		if match != nil && !match(msg) {
+			continue
+		}
+		if msg.LocalTTL <= 0 {
+			ms.removeElement(element)
+			return
+		}
+		if oldest == nil {
+			oldest = element
+		}
+	}
+	if oldest != nil {
+		ms.removeElement(oldest)
+	}
+}
+
+// removeElement removes a message from all internal data structures.
+func (ms *messageStore) removeElement(element *list.Element) {
+	msg := ms.order.Remove(element).(*spreadableMessage)
+	delete(ms.index, messageKey{msg.DataType, string(msg.DataHash)})
+	ms.countBySource[msg.SourceIdentity]--
+	if ms.countBySource[msg.SourceIdentity] <= 0 {
+		delete(ms.countBySource, msg.SourceIdentity)
+	}
+	sourceType := sourceTypeKey{msg.SourceIdentity, msg.DataType}
+	ms.countBySourceType[sourceType]--
+	if ms.countBySourceType[sourceType] <= 0 {
+		delete(ms.countBySourceType, sourceType)
+	}
+	ms.countByType[msg.DataType]--
+	if ms.countByType[msg.DataType] <= 0 {
+		delete(ms.countByType, msg.DataType)
+	}
+	ms.bytesByType[msg.DataType] -= len(msg.Data)
+	if ms.bytesByType[msg.DataType] <= 0 {
+		delete(ms.bytesByType, msg.DataType)
+	}
+	ms.bytes -= len(msg.Data)
+	if msg.TTL == 0 {
+		ms.infiniteCount--
+	}
+}
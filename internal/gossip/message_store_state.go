@@ -0,0 +1,100 @@
+package gossip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// storedMessageState represents the persisted state of a single message within the message store.
+type storedMessageState struct {
+	LocalTTL       int       `json:"local_ttl"`
+	TTL            uint8     `json:"ttl"`
+	DataType       uint16    `json:"data_type"`
+	Data           []byte    `json:"data"`
+	SourceIdentity string    `json:"source_identity"`
+	ReceivedAt     time.Time `json:"received_at"`
+	OriginAt       time.Time `json:"origin_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Deprioritized  bool      `json:"deprioritized,omitempty"`
+	HighPriority   bool      `json:"high_priority,omitempty"`
+}
+
+// Save writes all messages with their remaining TTLs to the file at path, least recently received first.
+func (ms *messageStore) Save(path string) error {
+	states := make([]storedMessageState, 0, ms.order.Len())
+	for element := ms.order.Front(); element != nil; element = element.Next() {
+		msg := element.Value.(*spreadableMessage)
+		states = append(states, storedMessageState{
+			LocalTTL:       msg.LocalTTL,
+			TTL:            msg.TTL,
+			DataType:       msg.DataType,
+			Data:           msg.Data,
+			SourceIdentity: msg.SourceIdentity.String(),
+			ReceivedAt:     msg.ReceivedAt,
+			OriginAt:       msg.OriginAt,
+			ExpiresAt:      msg.ExpiresAt,
+			Deprioritized:  msg.Deprioritized,
+			HighPriority:   msg.HighPriority,
+		})
+	}
+	stateBytes, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, stateBytes); err != nil {
+		return fmt.Errorf("could not write message store file: %w", err)
+	}
+	return nil
+}
+
+// Load adds the messages of the file at path, which was written by Save, to the store and returns them. Messages of files
+// without receive times count as received at now. Data hashes are recomputed, so a tampered file cannot inject messages under a wrong hash.
+func (ms *messageStore) Load(path string, now time.Time) ([]spreadableMessage, error) {
+	stateBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read message store file: %w", err)
+	}
+	var states []storedMessageState
+	err = json.Unmarshal(stateBytes, &states)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse message store file %s: %w", path, err)
+	}
+
+	var loaded []spreadableMessage
+	for i, state := range states {
+		sourceIdentity, err := hex.DecodeString(state.SourceIdentity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source identity of message %d in message store file %s: %w", i, path, err)
+		}
+		source, err := NewIdentity(sourceIdentity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source identity of message %d in message store file %s: %w", i, path, err)
+		}
+		dataHash := sha256.Sum256(state.Data)
+		// files written before the receive time was persisted count as received on load
+		if state.ReceivedAt.IsZero() {
+			state.ReceivedAt = now
+		}
+		msg := spreadableMessage{
+			LocalTTL:       state.LocalTTL,
+			TTL:            state.TTL,
+			DataType:       state.DataType,
+			Data:           state.Data,
+			DataHash:       dataHash[:],
+			SourceIdentity: *source,
+			ReceivedAt:     state.ReceivedAt,
+			OriginAt:       state.OriginAt,
+			ExpiresAt:      state.ExpiresAt,
+			Deprioritized:  state.Deprioritized,
+			HighPriority:   state.HighPriority,
+		}
+		if ms.Add(msg) {
+			loaded = append(loaded, msg)
+		}
+	}
+	return loaded, nil
+}
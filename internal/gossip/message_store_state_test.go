@@ -0,0 +1,60 @@
+package gossip
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMessageStore_SaveLoad(t *testing.T) {
+	t.Parallel()
+	t.Run("restores messages with their remaining TTLs", func(t *testing.T) {
+		nodes, err := createNodes(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := newMessageStore(10, 1024, nil)
+		store.Add(newTestMessage("first", 3, nodes[0].Identity))
+		store.Add(newTestMessage("second", -2, nodes[0].Identity))
+		path := filepath.Join(t.TempDir(), "messages.json")
+		err = store.Save(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restored := newMessageStore(10, 1024, nil)
+		loaded, err := restored.Load(path, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(loaded) != 2 || restored.Len() != 2 || restored.CountFromSource(nodes[0].Identity) != 2 {
+			t.Fatalf("expected 2 restored messages, received %d", restored.Len())
+		}
+		spreadable := restored.Spreadable(time.Now())
+		if len(spreadable) != 1 || spreadable[0].LocalTTL != 3 || string(spreadable[0].Data) != "first" {
+			t.Errorf("unexpected spreadable messages: %v", spreadable)
+		}
+		if !bytes.Equal(loaded[1].DataHash, newTestMessage("second", 0, testIdentity("")).DataHash) {
+			t.Errorf("data hash was not recomputed")
+		}
+	})
+	t.Run("returns error on missing or malformed files", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		_, err := store.Load(filepath.Join(t.TempDir(), "missing.json"), time.Now())
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected not exist error, received %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "messages.json")
+		err = os.WriteFile(path, []byte(`[{"source_identity":"abcd"}]`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.Load(path, time.Now())
+		if err == nil {
+			t.Error("expected error on invalid source identity")
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"gossiphers/internal/config"
+	"testing"
+	"time"
+)
+
+// newTestMessage returns a spreadable message with the given data, local TTL, and source.
+func newTestMessage(data string, localTTL int, source Identity) spreadableMessage {
+	hash := sha256.Sum256([]byte(data))
+	return spreadableMessage{
+		LocalTTL:       localTTL,
+		TTL:            uint8(localTTL),
+		DataType:       1,
+		Data:           []byte(data),
+		DataHash:       hash[:],
+		SourceIdentity: source,
+	}
+}
+
+func TestMessageStore_Add(t *testing.T) {
+	t.Parallel()
+	t.Run("deduplicates by data type and hash", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		msg := newTestMessage("hello", 5, testIdentity("peer1"))
+		if !store.Add(msg) {
+			t.Fatal("new message was not added")
+		}
+		if store.Add(msg) {
+			t.Error("known message was added again")
+		}
+		otherType := msg
+		otherType.DataType = 2
+		if !store.Add(otherType) {
+			t.Error("message of another data type was not added")
+		}
+		if !store.Contains(1, msg.DataHash) || store.Len() != 2 || store.Bytes() != 10 || store.CountFromSource(testIdentity("peer1")) != 2 {
+			t.Errorf("unexpected store state: %d messages, %d bytes", store.Len(), store.Bytes())
+		}
+	})
+	t.Run("evicts messages that are no longer spread first", func(t *testing.T) {
+		store := newMessageStore(3, 1024, nil)
+		store.Add(newTestMessage("spread1", 5, testIdentity("peer1")))
+		store.Add(newTestMessage("expired", -3, testIdentity("peer1")))
+		store.Add(newTestMessage("spread2", 5, testIdentity("peer1")))
+		store.Add(newTestMessage("spread3", 5, testIdentity("peer2")))
+
+		expired := newTestMessage("expired", 0, testIdentity(""))
+		if store.Contains(1, expired.DataHash) {
+			t.Error("expired message was not evicted")
+		}
+		if store.Len() != 3 || store.CountFromSource(testIdentity("peer1")) != 2 {
+			t.Errorf("unexpected store state: %d messages", store.Len())
+		}
+
+		// without expired messages the least recently received one is evicted
+		store.Touch(1, newTestMessage("spread1", 0, testIdentity("")).DataHash)
+		store.Add(newTestMessage("spread4", 5, testIdentity("peer2")))
+		if store.Contains(1, newTestMessage("spread2", 0, testIdentity("")).DataHash) || !store.Contains(1, newTestMessage("spread1", 0, testIdentity("")).DataHash) {
+			t.Error("least recently received message was not evicted")
+		}
+	})
+	t.Run("enforces the byte bound", func(t *testing.T) {
+		store := newMessageStore(100, 10, nil)
+		if store.Add(newTestMessage("this is too large", 5, testIdentity("peer1"))) {
+			t.Error("message exceeding the byte bound on its own was added")
+		}
+		for i := 0; i < 5; i++ {
+			store.Add(newTestMessage(fmt.Sprintf("msg%d", i), 5, testIdentity("peer1")))
+		}
+		if store.Bytes() > 10 || store.Len() != 2 {
+			t.Errorf("byte bound exceeded: %d messages, %d bytes", store.Len(), store.Bytes())
+		}
+	})
+}
+
+func TestMessageStore_AddEvicted(t *testing.T) {
+	t.Parallel()
+	store := newMessageStore(10, 1024, map[uint16]config.DataTypePolicy{1: {MaxMessages: 2}})
+	store.Add(newTestMessage("spread1", 5, testIdentity("peer1")))
+	store.Add(newTestMessage("spread2", 5, testIdentity("peer1")))
+	// a message that is no longer spread is the first to be evicted from the full data type, even if it was just received
+	victim := newTestMessage("not spread", 0, testIdentity("peer2"))
+	if store.Add(victim) {
+		t.Error("expected a message evicted right away not to be reported as added")
+	}
+	if store.Contains(1, victim.DataHash) || store.Len() != 2 || store.CountFromSource(testIdentity("peer2")) != 0 {
+		t.Errorf("unexpected store state: %d messages", store.Len())
+	}
+}
+
+func TestMessageStore_DataTypePolicies(t *testing.T) {
+	t.Parallel()
+	t.Run("a data type exceeding its limits only evicts its own messages", func(t *testing.T) {
+		store := newMessageStore(100, 1024, map[uint16]config.DataTypePolicy{2: {MaxMessages: 2}})
+		store.Add(newTestMessage("type1", 5, testIdentity("peer1")))
+		for i := 0; i < 5; i++ {
+			msg := newTestMessage(fmt.Sprintf("type2-%d", i), 5, testIdentity("peer2"))
+			msg.DataType = 2
+			store.Add(msg)
+		}
+		if store.Len() != 3 || !store.Contains(1, newTestMessage("type1", 0, testIdentity("")).DataHash) {
+			t.Errorf("unexpected store state: %d messages", store.Len())
+		}
+		newest := newTestMessage("type2-4", 0, testIdentity(""))
+		if !store.Contains(2, newest.DataHash) {
+			t.Error("the most recent message of the limited data type was evicted")
+		}
+	})
+	t.Run("rejects messages exceeding the byte limit of their data type", func(t *testing.T) {
+		store := newMessageStore(100, 1024, map[uint16]config.DataTypePolicy{1: {MaxBytes: 4}})
+		if store.Add(newTestMessage("too large", 5, testIdentity("peer1"))) {
+			t.Error("message exceeding the byte limit of its data type was added")
+		}
+	})
+	t.Run("spreads messages of higher priority first", func(t *testing.T) {
+		store := newMessageStore(100, 1024, map[uint16]config.DataTypePolicy{3: {Priority: 10}, 4: {Priority: -1}})
+		for i, dataType := range []uint16{4, 1, 3, 1, 3} {
+			msg := newTestMessage(fmt.Sprintf("msg%d", i), 5, testIdentity("peer1"))
+			msg.DataType = dataType
+			store.Add(msg)
+		}
+		var order []uint16
+		var data []string
+		for _, msg := range store.Spreadable(time.Now()) {
+			order = append(order, msg.DataType)
+			data = append(data, string(msg.Data))
+		}
+		if fmt.Sprint(order) != "[3 3 1 1 4]" || data[0] != "msg2" || data[2] != "msg1" {
+			t.Errorf("unexpected spread order: %v %v", order, data)
+		}
+	})
+}
+
+func TestMessageStore_Decay(t *testing.T) {
+	t.Parallel()
+	t.Run("decays local TTLs and evicts messages at the floor", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		store.Add(newTestMessage("short", 1, testIdentity("peer1")))
+		store.Add(newTestMessage("long", 3, testIdentity("peer1")))
+
+		if evicted := store.Decay(-1); evicted != 0 {
+			t.Fatalf("unexpected eviction of %d messages", evicted)
+		}
+		spreadable := store.Spreadable(time.Now())
+		if len(spreadable) != 1 || string(spreadable[0].Data) != "long" {
+			t.Errorf("unexpected spreadable messages: %v", spreadable)
+		}
+		if evicted := store.Decay(-1); evicted != 1 || store.Len() != 1 || store.CountFromSource(testIdentity("peer1")) != 1 {
+			t.Errorf("expected the short message to be evicted, %d evicted", evicted)
+		}
+	})
+	t.Run("removes messages by data type and hash", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		msg := newTestMessage("hello", 5, testIdentity("peer1"))
+		store.Add(msg)
+		if !store.Remove(1, msg.DataHash) || store.Remove(1, msg.DataHash) || store.Len() != 0 || store.Bytes() != 0 || store.CountFromSource(testIdentity("peer1")) != 0 {
+			t.Error("message was not removed correctly")
+		}
+	})
+}
+
+func TestMessageStore_Digest(t *testing.T) {
+	t.Parallel()
+	t.Run("lists most recently received messages first up to the limit", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		store.Add(newTestMessage("first", 5, testIdentity("peer1")))
+		store.Add(newTestMessage("expired", -3, testIdentity("peer1")))
+		store.Add(newTestMessage("last", 5, testIdentity("peer1")))
+
+		entries := store.Digest(10)
+		if len(entries) != 3 {
+			t.Fatalf("expected 3 entries including the expired message, got %d", len(entries))
+		}
+		if !bytes.Equal(entries[0].DataHash, newTestMessage("last", 0, testIdentity("")).DataHash) {
+			t.Error("most recently received message is not listed first")
+		}
+		if len(store.Digest(2)) != 2 {
+			t.Error("digest exceeds the limit")
+		}
+	})
+	t.Run("returns stored messages by key", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		msg := newTestMessage("hello", 5, testIdentity("peer1"))
+		store.Add(msg)
+		got, ok := store.Get(msg.DataType, msg.DataHash)
+		if !ok || !bytes.Equal(got.Data, msg.Data) {
+			t.Errorf("stored message not returned: %v", got)
+		}
+		if _, ok := store.Get(2, msg.DataHash); ok {
+			t.Error("message of another data type returned")
+		}
+	})
+}
+
+func TestMessageStore_Deprioritized(t *testing.T) {
+	t.Parallel()
+	t.Run("counts messages per source and data type", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		store.Add(newTestMessage("one", 5, testIdentity("peer1")))
+		otherType := newTestMessage("two", 5, testIdentity("peer1"))
+		otherType.DataType = 2
+		store.Add(otherType)
+		if store.CountFromSourceOfType(testIdentity("peer1"), 1) != 1 || store.CountFromSourceOfType(testIdentity("peer1"), 2) != 1 || store.CountFromSource(testIdentity("peer1")) != 2 {
+			t.Errorf("unexpected counts: %d, %d", store.CountFromSourceOfType(testIdentity("peer1"), 1), store.CountFromSourceOfType(testIdentity("peer1"), 2))
+		}
+		store.Remove(2, otherType.DataHash)
+		if store.CountFromSourceOfType(testIdentity("peer1"), 2) != 0 {
+			t.Error("count of removed message not decreased")
+		}
+	})
+	t.Run("spreads deprioritized messages last", func(t *testing.T) {
+		store := newMessageStore(10, 1024, map[uint16]config.DataTypePolicy{1: {Priority: -5}})
+		deprioritized := newTestMessage("flood", 5, testIdentity("peer1"))
+		deprioritized.DataType = 2
+		deprioritized.Deprioritized = true
+		store.Add(deprioritized)
+		store.Add(newTestMessage("normal", 5, testIdentity("peer2")))
+
+		spreadable := store.Spreadable(time.Now())
+		if len(spreadable) != 2 || !spreadable[1].Deprioritized {
+			t.Errorf("deprioritized message not spread last: %v", spreadable)
+		}
+	})
+}
+
+func TestMessageStore_HighPriority(t *testing.T) {
+	t.Parallel()
+	t.Run("spreads high-priority messages first", func(t *testing.T) {
+		store := newMessageStore(10, 1024, map[uint16]config.DataTypePolicy{1: {Priority: 10}})
+		store.Add(newTestMessage("bulk", 5, testIdentity("peer1")))
+		highPriority := newTestMessage("alert", 5, testIdentity("peer2"))
+		highPriority.DataType = 2
+		highPriority.HighPriority = true
+		store.Add(highPriority)
+		deprioritized := newTestMessage("flood", 5, testIdentity("peer3"))
+		deprioritized.HighPriority = true
+		deprioritized.Deprioritized = true
+		store.Add(deprioritized)
+
+		spreadable := store.Spreadable(time.Now())
+		if len(spreadable) != 3 || string(spreadable[0].Data) != "alert" || string(spreadable[1].Data) != "bulk" || string(spreadable[2].Data) != "flood" {
+			t.Errorf("unexpected spread order: %v", spreadable)
+		}
+	})
+}
+
+func TestMessageStore_InfiniteTTL(t *testing.T) {
+	t.Parallel()
+	// newInfiniteMessage returns a spreadable message with infinite hops received at the given time.
+	newInfiniteMessage := func(data string, receivedAt time.Time) spreadableMessage {
+		msg := newTestMessage(data, 255, testIdentity("peer1"))
+		msg.TTL = 0
+		msg.ReceivedAt = receivedAt
+		return msg
+	}
+	t.Run("bounds the number of messages with infinite hops", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		store.maxInfiniteMessages = 2
+		store.Add(newTestMessage("limited", 5, testIdentity("peer1")))
+		first := newInfiniteMessage("first", time.Now())
+		store.Add(first)
+		store.Add(newInfiniteMessage("second", time.Now()))
+		store.Add(newInfiniteMessage("third", time.Now()))
+
+		if store.Contains(1, first.DataHash) {
+			t.Error("least recently received message with infinite hops was not evicted")
+		}
+		if store.Len() != 3 || !store.Contains(1, newTestMessage("limited", 0, testIdentity("")).DataHash) {
+			t.Errorf("unexpected store state: %d messages", store.Len())
+		}
+	})
+	t.Run("evicts messages with infinite hops by age", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		now := time.Now()
+		old := newInfiniteMessage("old", now.Add(-time.Hour))
+		store.Add(old)
+		store.Add(newInfiniteMessage("new", now))
+		oldLimited := newTestMessage("limited", 5, testIdentity("peer1"))
+		oldLimited.ReceivedAt = now.Add(-time.Hour)
+		store.Add(oldLimited)
+
+		if evicted := store.EvictInfiniteOlderThan(time.Minute, now); evicted != 1 || store.Contains(1, old.DataHash) {
+			t.Errorf("expected only the old message with infinite hops to be evicted, evicted %d", evicted)
+		}
+		if store.Len() != 2 || store.infiniteCount != 1 {
+			t.Errorf("unexpected store state: %d messages, %d with infinite hops", store.Len(), store.infiniteCount)
+		}
+	})
+}
+
+func TestMessageStore_Expire(t *testing.T) {
+	t.Parallel()
+	t.Run("stops spreading expired messages", func(t *testing.T) {
+		store := newMessageStore(10, 1024, nil)
+		now := time.Now()
+		expired := newTestMessage("expired", 5, testIdentity("peer1"))
+		expired.ExpiresAt = now.Add(-time.Second)
+		store.Add(expired)
+		valid := newTestMessage("valid", 5, testIdentity("peer1"))
+		valid.ExpiresAt = now.Add(time.Second)
+		store.Add(valid)
+		store.Add(newTestMessage("never", 5, testIdentity("peer1")))
+
+		if len(store.Spreadable(now)) != 2 {
+			t.Error("expired message is spread before it was removed")
+		}
+		if n := store.Expire(now); n != 1 {
+			t.Errorf("expected 1 expired message, got %d", n)
+		}
+		if len(store.Spreadable(now)) != 2 || !store.Contains(1, expired.DataHash) {
+			t.Error("expired message is still spread or was not remembered")
+		}
+		if n := store.Expire(now); n != 0 {
+			t.Errorf("message expired twice")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package gossip
+
+import (
+	"expvar"
+	"time"
+)
+
+// metrics holds the counters of the gossip subsystem, published as the expvar variable "gossip" on the debug endpoint.
+var metrics = expvar.NewMap("gossip")
+
+// Names of the counters within metrics.
+const (
+	metricRounds             = "rounds"
+	metricPacketsReceived    = "packets_received"
+	metricPacketsRejected    = "packets_rejected"
+	metricPacketsSent        = "packets_sent"
+	metricPacketsSendFailed  = "packets_send_failed"
+	metricMessagesStored     = "messages_stored"
+	metricMessageStoreBytes  = "message_store_bytes"
+	metricMainViewSize       = "main_view_size"
+	metricPeersEvicted       = "peers_evicted"
+	metricDecryptNanoseconds = "decrypt_ns"
+	metricEncryptNanoseconds = "encrypt_ns"
+	metricSignNanoseconds    = "sign_ns"
+	metricVerifyNanoseconds  = "verify_ns"
+	metricPeerLatencies      = "peer_latencies"
+	metricPeerStats          = "peer_stats"
+	metricDeliveries         = "deliveries"
+	metricChurn              = "churn"
+	metricRoundID            = "round_id"
+	metricRoundMetrics       = "round_metrics"
+
+	// transitions of our pushes to peers
+	metricPushesRequested        = "pushes_requested"
+	metricPushChallengesAccepted = "push_challenges_accepted"
+	metricPushChallengesRefused  = "push_challenges_refused"
+	metricPushesSent             = "pushes_sent"
+	metricPushesAborted          = "pushes_aborted"
+	metricPushesExpired          = "pushes_expired"
+
+	// challenges of requested peers demanding more work than we accept, refused without solving them
+	metricPushChallengesTooDifficult = "push_challenges_too_difficult"
+
+	// outcomes of the pushes of peers to us
+	metricPushChallengesIssued = "push_challenges_issued"
+	metricPushesAccepted       = "pushes_accepted"
+	metricPushesRejected       = "pushes_rejected"
+
+	// transitions of our pull requests to peers
+	metricPullsRequested        = "pulls_requested"
+	metricPullResponsesAccepted = "pull_responses_accepted"
+	metricPullResponsesRefused  = "pull_responses_refused"
+	metricPullsTimedOut         = "pulls_timed_out"
+
+	// packets to ourselves, dropped before sending
+	metricPacketsSelfAddressed = "packets_self_addressed"
+
+	// circuits of addresses we repeatedly failed to send to, and the packets dropped while they are open
+	metricSendCircuitsOpened = "send_circuits_opened"
+	metricSendCircuitsOpen   = "send_circuits_open"
+	metricPacketsCircuitOpen = "packets_circuit_open"
+
+	// addresses quarantined for repeated invalid signatures or failed challenges, and their packets dropped before decryption
+	metricPeersQuarantined   = "peers_quarantined"
+	metricQuarantinedPeers   = "quarantined_peers"
+	metricPacketsQuarantined = "packets_quarantined"
+	// digests left unanswered since their sender already sent a digest within the current round
+	metricDigestsRejected = "digests_rejected"
+	// pull and push requests left unanswered by an outbound-only node
+	metricRequestsRefusedOutboundOnly = "requests_refused_outbound_only"
+	// peers recorded within the peer database
+	metricPeerDBPeers = "peer_db_peers"
+
+	// offset of the local clock relative to the peers estimated from the timestamps of their packets
+	metricClockSkewMs      = "clock_skew_ms"
+	metricClockSkewPeers   = "clock_skew_peers"
+	metricClockSkewWarning = "clock_skew_warning"
+)
+
+// addDuration adds the time elapsed since start to the counter with the given name.
+func addDuration(name string, start time.Time) {
+	metrics.Add(name, time.Since(start).Nanoseconds())
+}
+
+// setGauge sets the counter with the given name to a value.
+func setGauge(name string, value int) {
+	gauge := new(expvar.Int)
+	gauge.Set(int64(value))
+	metrics.Set(name, gauge)
+}
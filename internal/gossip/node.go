@@ -1,35 +1,171 @@
 package gossip
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"gossiphers/internal/config"
+	"strings"
+	"time"
 )
 
 // IdentitySize represents the size of the Node's Identity attribute, which is the 32 byte result of the SHA256 hash of the Node's respective public key.
 const IdentitySize int = sha256.Size // 32
 
-// Identity represents a SHA256 hash of a public key.
-type Identity string
+// shortIdentityLength is the number of hex characters of an identity rendered by Identity.Short.
+const shortIdentityLength = 12
+
+// Identity represents a SHA256 hash of a public key. It is a comparable value used as map key directly, so the raw hash is
+// never confused with its hex encoding.
+type Identity [IdentitySize]byte
 
 // NewIdentity generates a new Identity given a string if it is of the correct size.
 func NewIdentity(hash []byte) (*Identity, error) {
 	if len(hash) != IdentitySize {
 		return nil, fmt.Errorf("id of wrong size: expected %d, received %d", sha256.Size, len(hash))
 	}
-	id := Identity(hash[:])
+	id := Identity(hash)
 	return &id, nil
 }
 
 // String represents the Identity as an uppercase hex-encoded string.
 func (id Identity) String() string {
-	return hex.EncodeToString([]byte(id))
+	return hex.EncodeToString(id[:])
+}
+
+// Compare returns -1, 0, or 1 if the Identity orders before, equal to, or after the other Identity, comparing the hashes
+// byte by byte.
+func (id Identity) Compare(other Identity) int {
+	return bytes.Compare(id[:], other[:])
+}
+
+// Short represents the Identity as the first hex-encoded characters, which tell peers apart in logs.
+func (id Identity) Short() string {
+	return id.String()[:shortIdentityLength]
+}
+
+// NodeSource describes how a node was learned.
+type NodeSource int
+
+const (
+	SourceUnknown NodeSource = iota
+	SourceBootstrap
+	SourcePush
+	SourcePull
+	SourcePeerDB
+	SourcePinned
+)
+
+// String returns the name of the node source.
+func (s NodeSource) String() string {
+	switch s {
+	case SourceBootstrap:
+		return "bootstrap"
+	case SourcePush:
+		return "push"
+	case SourcePull:
+		return "pull"
+	case SourcePeerDB:
+		return "peer_db"
+	case SourcePinned:
+		return "pinned"
+	default:
+		return "unknown"
+	}
+}
+
+// Capabilities represents a bitmask of the optional features a node supports, so peers running different builds can negotiate
+// features per peer. Unknown bits are kept, they may be features of newer builds.
+type Capabilities uint16
+
+const (
+	// CapabilityCompression marks nodes able to receive compressed packets.
+	CapabilityCompression Capabilities = 1 << iota
+	// CapabilityFragmentation marks nodes able to reassemble packets fragmented beyond the maximum packet size.
+	CapabilityFragmentation
+	// CapabilityEd25519 marks nodes able to verify packets signed with Ed25519 hostkeys.
+	CapabilityEd25519
+	// CapabilityRelay marks nodes willing to relay packets to peers that are not reachable directly.
+	CapabilityRelay
+	// CapabilityMessageBatch marks nodes able to receive several gossip messages within a single batch packet.
+	CapabilityMessageBatch
+	// CapabilityHybridKeyWrap marks nodes wrapping the packet keys with the hybrid KEM, see config.KeyWrapHybrid.
+	CapabilityHybridKeyWrap
+)
+
+// CapabilitiesVersion represents the first protocol version advertising the capabilities of nodes in pushes. Peers speaking
+// older versions would take the capabilities for part of the address, so they are not sent to them.
+// Capabilities are only taken from the pushes the nodes signed themselves, never from pull responses listing other nodes.
+const CapabilitiesVersion uint8 = 5
+
+// BuildCapabilities represents the capabilities implemented by this build, compression, fragmentation, Ed25519, and relay
+// are not implemented yet.
+const BuildCapabilities = CapabilityMessageBatch | CapabilityHybridKeyWrap
+
+// localCapabilities returns the capabilities advertised by a node with the given configuration.
+func localCapabilities(cfg *config.GossipConfig) Capabilities {
+	capabilities := CapabilityMessageBatch
+	if cfg.KeyWrap == config.KeyWrapHybrid {
+		capabilities |= CapabilityHybridKeyWrap
+	}
+	return capabilities
+}
+
+// capabilityNames maps each known capability to its name.
+var capabilityNames = []struct {
+	capability Capabilities
+	name       string
+}{
+	{CapabilityCompression, "compression"},
+	{CapabilityFragmentation, "fragmentation"},
+	{CapabilityEd25519, "ed25519"},
+	{CapabilityRelay, "relay"},
+	{CapabilityMessageBatch, "message_batch"},
+	{CapabilityHybridKeyWrap, "hybrid_key_wrap"},
+}
+
+// Has checks whether all of the given capabilities are set.
+func (c Capabilities) Has(capabilities Capabilities) bool {
+	return c&capabilities == capabilities
+}
+
+// Names returns the names of the set capabilities, unknown ones hex-encoded.
+func (c Capabilities) Names() []string {
+	var names []string
+	for _, known := range capabilityNames {
+		if c.Has(known.capability) {
+			names = append(names, known.name)
+			c &^= known.capability
+		}
+	}
+	if c != 0 {
+		names = append(names, fmt.Sprintf("%#04x", uint16(c)))
+	}
+	return names
+}
+
+// String returns the comma-separated names of the set capabilities, none if no capability is set.
+func (c Capabilities) String() string {
+	if c == 0 {
+		return "none"
+	}
+	return strings.Join(c.Names(), ",")
 }
 
 // Node represents a peer within the Gossip network.
+// Only the identity, the address, and the capabilities are exchanged with other peers, the remaining fields are local metadata.
 type Node struct {
 	Identity Identity
 	Address  string
+	// Capabilities are the optional features the node advertised within its own push, zero if it never pushed to us.
+	Capabilities Capabilities
+	// LastSeen is the last time the peer itself contacted us (push or pong), zero if it never did.
+	LastSeen time.Time
+	// RTT is the round trip time measured by the latest successful ping, zero if the node was never pinged.
+	RTT time.Duration
+	// Source describes how the node was learned.
+	Source NodeSource
 }
 
 // NewNode returns a new instance of Node.
@@ -45,6 +181,19 @@ func NewNode(identity []byte, address string) (*Node, error) {
 	}, nil
 }
 
+// mergeMetadata takes over the newer metadata of another instance of the same node.
+func (n *Node) mergeMetadata(other Node) {
+	if other.LastSeen.After(n.LastSeen) {
+		n.LastSeen = other.LastSeen
+	}
+	if other.RTT != 0 {
+		n.RTT = other.RTT
+	}
+	if other.Capabilities != 0 {
+		n.Capabilities = other.Capabilities
+	}
+}
+
 // String returns the string representation of a node.
 func (n *Node) String() string {
 	return n.Identity.String() + "@" + n.Address
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/hex"
+	"gossiphers/internal/config"
 	"testing"
 )
 
@@ -50,6 +51,31 @@ func TestIdentity_String(t *testing.T) {
 	})
 }
 
+func TestIdentity_Short(t *testing.T) {
+	t.Parallel()
+	t.Run("full identity is truncated", func(t *testing.T) {
+		t.Parallel()
+		id := Identity(sliceRepeat(IdentitySize, byte(0xab)))
+		if id.Short() != "abababababab" {
+			t.Errorf("Identity.Short() returned unexpected result, expected: abababababab, got: %s", id.Short())
+		}
+	})
+}
+
+func TestIdentity_Compare(t *testing.T) {
+	t.Parallel()
+	if testIdentity("peer1").Compare(testIdentity("peer2")) >= 0 || testIdentity("peer2").Compare(testIdentity("peer1")) <= 0 || testIdentity("peer1").Compare(testIdentity("peer1")) != 0 {
+		t.Error("expected identities to be ordered byte by byte")
+	}
+}
+
+// testIdentity returns the identity consisting of the given name padded with zero bytes, which tells peers apart in tests.
+func testIdentity(name string) Identity {
+	var id Identity
+	copy(id[:], name)
+	return id
+}
+
 func TestNode_NewNode(t *testing.T) {
 	t.Parallel()
 	t.Run("with invalid identity (e.g., empty identity)", func(t *testing.T) {
@@ -114,3 +140,41 @@ func TestNode_String(t *testing.T) {
 		}
 	})
 }
+
+func TestCapabilities_String(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		capabilities Capabilities
+		expected     string
+	}{
+		{0, "none"},
+		{CapabilityCompression | CapabilityRelay, "compression,relay"},
+		{CapabilityEd25519 | 0x0100, "ed25519,0x0100"},
+		{CapabilityMessageBatch | CapabilityHybridKeyWrap, "message_batch,hybrid_key_wrap"},
+	} {
+		if s := tc.capabilities.String(); s != tc.expected {
+			t.Errorf("expected %s, received %s", tc.expected, s)
+		}
+	}
+	if !(CapabilityCompression | CapabilityRelay).Has(CapabilityRelay) || CapabilityRelay.Has(CapabilityCompression|CapabilityRelay) {
+		t.Error("expected Has to check all of the given capabilities")
+	}
+}
+
+func TestLocalCapabilities(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		keyWrap  string
+		expected Capabilities
+	}{
+		{config.KeyWrapRSAOAEP, CapabilityMessageBatch},
+		{config.KeyWrapHybrid, CapabilityMessageBatch | CapabilityHybridKeyWrap},
+	} {
+		if capabilities := localCapabilities(&config.GossipConfig{KeyWrap: tc.keyWrap}); capabilities != tc.expected {
+			t.Errorf("expected the key wrap %s to advertise %s, received %s", tc.keyWrap, tc.expected, capabilities)
+		}
+		if !BuildCapabilities.Has(tc.expected) {
+			t.Errorf("expected the build to implement the advertised capabilities %s", tc.expected)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package gossip
+
+import "gossiphers/internal/config"
+
+// refusesRequest reports whether the server leaves a request of a peer unanswered because the node participates outbound-only.
+// Pull and push requests are refused, so the node neither reveals its view nor issues push challenges. Pings are still answered,
+// since they only reach the node if its firewall lets them through.
+func (s *Server) refusesRequest(messageType MessageType) bool {
+	if s.cfg.Participation != config.ParticipationOutboundOnly {
+		return false
+	}
+	if messageType != MessageTypeGossipPullRequest && messageType != MessageTypeGossipPushRequest {
+		return false
+	}
+	metrics.Add(metricRequestsRefusedOutboundOnly, 1)
+	return true
+}
+
+// spreadTargets returns the nodes the spreadable messages are announced to in addition to push and pull requests, which are
+// the randomly selected ones. Outbound-only nodes neither push nor answer pull requests, so the messages announced through
+// their API would never leave them. They additionally announce their messages to the nodes pulled from during the round,
+// which request the missing ones in reply.
+func (g *Gossip) spreadTargets(selected []*Node, pullFromNodes []*Node) []*Node {
+	if g.cfg.Participation != config.ParticipationOutboundOnly {
+		return selected
+	}
+	var targets []*Node
+	unique := make(map[Identity]struct{})
+	for _, node := range append(append([]*Node(nil), selected...), pullFromNodes...) {
+		if _, ok := unique[node.Identity]; !ok {
+			unique[node.Identity] = struct{}{}
+			targets = append(targets, node)
+		}
+	}
+	return targets
+}
@@ -0,0 +1,47 @@
+package gossip
+
+import (
+	"gossiphers/internal/config"
+	"testing"
+)
+
+func TestServer_refusesRequest(t *testing.T) {
+	t.Parallel()
+	outboundOnly := &Server{cfg: &config.GossipConfig{Participation: config.ParticipationOutboundOnly}}
+	for _, messageType := range []MessageType{MessageTypeGossipPullRequest, MessageTypeGossipPushRequest} {
+		if !outboundOnly.refusesRequest(messageType) {
+			t.Errorf("expected an outbound-only node to refuse packets of type %#04x", messageType)
+		}
+	}
+	for _, messageType := range []MessageType{MessageTypeGossipPing, MessageTypeGossipPullResponse, MessageTypeGossipPushChallenge, MessageTypeGossipIHave} {
+		if outboundOnly.refusesRequest(messageType) {
+			t.Errorf("expected an outbound-only node to handle packets of type %#04x", messageType)
+		}
+	}
+	full := &Server{cfg: &config.GossipConfig{Participation: config.ParticipationFull}}
+	if full.refusesRequest(MessageTypeGossipPullRequest) {
+		t.Error("expected a fully participating node to answer pull requests")
+	}
+}
+
+func TestGossip_spreadTargets(t *testing.T) {
+	t.Parallel()
+	nodes, err := createNodes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selected := []*Node{&nodes[0], &nodes[1]}
+	pulled := []*Node{&nodes[1], &nodes[2]}
+	full := &Gossip{cfg: &config.GossipConfig{Participation: config.ParticipationFull}}
+	if targets := full.spreadTargets(selected, pulled); len(targets) != 2 {
+		t.Errorf("expected a fully participating node to announce to the selected nodes only, received %d targets", len(targets))
+	}
+	outboundOnly := &Gossip{cfg: &config.GossipConfig{Participation: config.ParticipationOutboundOnly}}
+	targets := outboundOnly.spreadTargets(selected, pulled)
+	if len(targets) != 3 {
+		t.Fatalf("expected an outbound-only node to announce to the selected and pulled nodes without duplicates, received %d targets", len(targets))
+	}
+	if targets := outboundOnly.spreadTargets(nil, pulled); len(targets) != 2 {
+		t.Errorf("expected an outbound-only node without message fanout to announce to the pulled nodes, received %d targets", len(targets))
+	}
+}
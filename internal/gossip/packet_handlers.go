@@ -0,0 +1,74 @@
+package gossip
+
+import (
+	"bytes"
+	"net"
+)
+
+// PacketHandler handles a received packet of a single type, whose header was authenticated already.
+// The body holds the bytes following the header. An error is returned if the body could not be parsed.
+type PacketHandler func(fromAddr net.Addr, header *PacketHeader, body *bytes.Reader) error
+
+// PacketMiddleware wraps the handler of a packet type, e.g. to rate limit or count the packets of the type.
+// It is called once for every registered packet type, so it can decide per type whether to wrap the handler at all.
+type PacketMiddleware func(messageType MessageType, next PacketHandler) PacketHandler
+
+// packetParser is implemented by pointers to packet types, which parse their body following the header.
+type packetParser[P any] interface {
+	*P
+	Parse(header *PacketHeader, reader *bytes.Reader) error
+}
+
+// handlePacket returns a PacketHandler parsing the body into a packet of type P before passing it to handle.
+func handlePacket[P any, PP packetParser[P]](handle func(net.Addr, P)) PacketHandler {
+	return func(fromAddr net.Addr, header *PacketHeader, body *bytes.Reader) error {
+		var packet P
+		if err := PP(&packet).Parse(header, body); err != nil {
+			return err
+		}
+		handle(fromAddr, packet)
+		return nil
+	}
+}
+
+// registerCoreHandlers registers the handlers of the packet types of the gossip protocol.
+func (s *Server) registerCoreHandlers() {
+	s.RegisterPacketHandler(MessageTypeGossipPing, handlePacket[PacketPing](s.handlePing))
+	s.RegisterPacketHandler(MessageTypeGossipPong, handlePacket[PacketPong](s.handlePong))
+	s.RegisterPacketHandler(MessageTypeGossipPullRequest, handlePacket[PacketPullRequest](s.handlePullRequest))
+	s.RegisterPacketHandler(MessageTypeGossipPullResponse, handlePacket[PacketPullResponse](s.handlePullResponse))
+	s.RegisterPacketHandler(MessageTypeGossipPushRequest, handlePacket[PacketPushRequest](s.handlePushRequest))
+	s.RegisterPacketHandler(MessageTypeGossipPushChallenge, handlePacket[PacketPushChallenge](s.handlePushChallenge))
+	s.RegisterPacketHandler(MessageTypeGossipPush, handlePacket[PacketPush](s.handlePush))
+	s.RegisterPacketHandler(MessageTypeGossipMessage, handlePacket[PacketMessage](s.handleMessage))
+	s.RegisterPacketHandler(MessageTypeGossipMessageBatch, handlePacket[PacketMessageBatch](s.handleMessageBatch))
+	s.RegisterPacketHandler(MessageTypeGossipDigest, handlePacket[PacketDigest](s.handleDigest))
+	s.RegisterPacketHandler(MessageTypeGossipDigestRequest, handlePacket[PacketDigestRequest](s.handleDigestRequest))
+	s.RegisterPacketHandler(MessageTypeGossipIHave, handlePacket[PacketIHave](s.handleIHave))
+	s.RegisterPacketHandler(MessageTypeGossipLeave, handlePacket[PacketLeave](s.handleLeave))
+}
+
+// RegisterPacketHandler registers the handler of received packets of the given type, replacing the handler registered before.
+// It must be called before the server is started.
+func (s *Server) RegisterPacketHandler(messageType MessageType, handler PacketHandler) {
+	s.packetHandlers[messageType] = handler
+	s.wrapPacketHandlers()
+}
+
+// UsePacketMiddleware wraps the handlers of all packet types, including the ones registered later, with middleware.
+// Middleware used first runs first. It must be called before the server is started.
+func (s *Server) UsePacketMiddleware(middleware PacketMiddleware) {
+	s.packetMiddlewares = append(s.packetMiddlewares, middleware)
+	s.wrapPacketHandlers()
+}
+
+// wrapPacketHandlers rebuilds the handlers packets are dispatched to from the registered handlers and middleware.
+func (s *Server) wrapPacketHandlers() {
+	s.dispatchHandlers = make(map[MessageType]PacketHandler, len(s.packetHandlers))
+	for messageType, handler := range s.packetHandlers {
+		for i := len(s.packetMiddlewares) - 1; i >= 0; i-- {
+			handler = s.packetMiddlewares[i](messageType, handler)
+		}
+		s.dispatchHandlers[messageType] = handler
+	}
+}
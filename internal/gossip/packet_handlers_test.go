@@ -0,0 +1,57 @@
+package gossip
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestServer_RegisterPacketHandler(t *testing.T) {
+	t.Parallel()
+	const messageTypeTest MessageType = 0x00ff
+	t.Run("middleware wraps handlers registered before and after in order", func(t *testing.T) {
+		t.Parallel()
+		s := &Server{packetHandlers: make(map[MessageType]PacketHandler)}
+		var calls []string
+		s.RegisterPacketHandler(messageTypeTest, func(net.Addr, *PacketHeader, *bytes.Reader) error {
+			calls = append(calls, "test")
+			return nil
+		})
+		for _, name := range []string{"first", "second"} {
+			name := name
+			s.UsePacketMiddleware(func(messageType MessageType, next PacketHandler) PacketHandler {
+				return func(fromAddr net.Addr, header *PacketHeader, body *bytes.Reader) error {
+					calls = append(calls, fmt.Sprintf("%s/%#04x", name, uint16(messageType)))
+					return next(fromAddr, header, body)
+				}
+			})
+		}
+		s.RegisterPacketHandler(MessageTypeGossipLeave, func(net.Addr, *PacketHeader, *bytes.Reader) error {
+			calls = append(calls, "leave")
+			return nil
+		})
+
+		for _, messageType := range []MessageType{messageTypeTest, MessageTypeGossipLeave} {
+			if err := s.dispatchHandlers[messageType](nil, &PacketHeader{Type: messageType}, bytes.NewReader(nil)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expected := []string{"first/0x00ff", "second/0x00ff", "test", "first/0x0080", "second/0x0080", "leave"}
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("expected calls %v, received %v", expected, calls)
+		}
+	})
+	t.Run("parse errors are returned without calling the handler", func(t *testing.T) {
+		t.Parallel()
+		handled := false
+		handler := handlePacket[PacketPing](func(net.Addr, PacketPing) { handled = true })
+		if err := handler(nil, &PacketHeader{Type: MessageTypeGossipPing}, bytes.NewReader([]byte{0x01})); err == nil {
+			t.Error("expected an error parsing a truncated ping")
+		}
+		if handled {
+			t.Error("handler was called with a packet that could not be parsed")
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package gossip
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// packetProtection represents the order in which packets are signed and encrypted, which is part of the wire format and
+// therefore selected by the protocol version. Peers only understand each other if they protect packets in the same order.
+type packetProtection int
+
+const (
+	// protectionSignThenEncrypt signs the plaintext packet and encrypts it along with the signature, so eavesdroppers learn
+	// neither the content nor the sender of a packet. Receivers have to decrypt every packet before they can authenticate it.
+	//
+	//	encrypt(packet | sign(packet))
+	protectionSignThenEncrypt packetProtection = iota
+	// protectionEncryptThenSign encrypts the packet and signs the ciphertext along with the sender's identity, which is
+	// appended in the clear. Receivers reject forged packets by the cheap signature verification before the expensive
+	// decryption, at the cost of revealing the sender to eavesdroppers.
+	//
+	//	encrypt(packet) | sender | sign(encrypt(packet) | sender)
+	protectionEncryptThenSign
+)
+
+// protectionOf returns the packet protection of a protocol version.
+func protectionOf(version uint8) (packetProtection, error) {
+	switch version {
+	case 2:
+		return protectionSignThenEncrypt, nil
+	case 3, HybridKeyWrapVersion, CapabilitiesVersion, OriginTimestampVersion:
+		// the hybrid key wrap only changes the encryption, see Crypto, the capabilities the encoding of nodes, and the origin
+		// timestamps the encoding of messages
+		return protectionEncryptThenSign, nil
+	default:
+		return 0, fmt.Errorf("unsupported protocol version %d, supported are %v", version, SupportedProtocolVersions)
+	}
+}
+
+// String returns the name of a packet protection.
+func (p packetProtection) String() string {
+	switch p {
+	case protectionSignThenEncrypt:
+		return "sign_then_encrypt"
+	case protectionEncryptThenSign:
+		return "encrypt_then_sign"
+	default:
+		return "unknown"
+	}
+}
+
+// overhead returns the largest number of bytes the protection adds to a packet encrypted by c.
+func (p packetProtection) overhead(c PacketCrypto) int {
+	return c.Overhead() + p.framing()
+}
+
+// framing returns the number of bytes the protection adds to a packet besides the encryption, i.e. the signature and the
+// identity of the sender sent in the clear.
+func (p packetProtection) framing() int {
+	if p == protectionEncryptThenSign {
+		return IdentitySize + SignatureSize
+	}
+	return SignatureSize
+}
+
+// seal signs and encrypts a packet of the sender for the receiver with the given capabilities in the order of the protection.
+func (p packetProtection) seal(c PacketCrypto, packetBytes []byte, sender Identity, receiver Identity, capabilities Capabilities) ([]byte, error) {
+	sign := func(data []byte) ([]byte, error) {
+		signStart := time.Now()
+		defer addDuration(metricSignNanoseconds, signStart)
+		signature, err := c.Sign(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not sign packet: %w", err)
+		}
+		return signature, nil
+	}
+	encrypt := func(data []byte) ([]byte, error) {
+		encryptStart := time.Now()
+		defer addDuration(metricEncryptNanoseconds, encryptStart)
+		ciphertext, err := c.EncryptPacket(data, receiver, capabilities)
+		if err != nil {
+			return nil, fmt.Errorf("could not encrypt packet: %w", err)
+		}
+		return ciphertext, nil
+	}
+
+	if p == protectionEncryptThenSign {
+		ciphertext, err := encrypt(packetBytes)
+		if err != nil {
+			return nil, err
+		}
+		signedBytes := append(ciphertext, sender.ToBytes()...)
+		signature, err := sign(signedBytes)
+		if err != nil {
+			return nil, err
+		}
+		return append(signedBytes, signature...), nil
+	}
+	signature, err := sign(packetBytes)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(append(packetBytes, signature...))
+}
+
+// openPacket authenticates and decrypts a received packet in the order of the server's protection. It returns the plaintext
+// packet followed by its signature, which the packet parsers expect, along with the parsed header. Packets that fail any check
+// are rejected and false is returned.
+func (s *Server) openPacket(packetBytes []byte, fromAddr net.Addr) ([]byte, *PacketHeader, bool) {
+	// packets of quarantined addresses are dropped before any cryptographic operation
+	if s.quarantine.Quarantined(fromAddr.String(), s.clock.Now()) {
+		metrics.Add(metricPacketsQuarantined, 1)
+		s.rejectPacket(fromAddr, RejectReasonQuarantined)
+		return nil, nil, false
+	}
+	// the encryption overhead depends on the key wrap chosen by the sender, so only the crypto can check it
+	if len(packetBytes) < PacketHeaderSize+s.protection.framing() {
+		s.logger().Info("Received gossip packet with invalid length")
+		s.rejectPacket(fromAddr, RejectReasonInvalidLength)
+		return nil, nil, false
+	}
+	if s.protection == protectionEncryptThenSign {
+		return s.openEncryptedThenSigned(packetBytes, fromAddr)
+	}
+	return s.openSignedThenEncrypted(packetBytes, fromAddr)
+}
+
+// openSignedThenEncrypted decrypts a packet protected by protectionSignThenEncrypt and verifies the signature of the plaintext.
+func (s *Server) openSignedThenEncrypted(packetBytes []byte, fromAddr net.Addr) ([]byte, *PacketHeader, bool) {
+	decryptedBytes, ok := s.decrypt(packetBytes, fromAddr)
+	if !ok {
+		return nil, nil, false
+	}
+	header, ok := s.parseHeader(decryptedBytes, fromAddr)
+	if !ok {
+		return nil, nil, false
+	}
+	signedLength := len(decryptedBytes) - SignatureSize
+	if !s.verify(decryptedBytes[:signedLength], decryptedBytes[signedLength:], header.SenderIdentity, fromAddr) {
+		return nil, nil, false
+	}
+	if !s.checkTimestamp(header, fromAddr) {
+		return nil, nil, false
+	}
+	return decryptedBytes, header, true
+}
+
+// openEncryptedThenSigned verifies the signature of a packet protected by protectionEncryptThenSign before decrypting it.
+func (s *Server) openEncryptedThenSigned(packetBytes []byte, fromAddr net.Addr) ([]byte, *PacketHeader, bool) {
+	signedLength := len(packetBytes) - SignatureSize
+	ciphertextLength := signedLength - IdentitySize
+	sender := Identity(packetBytes[ciphertextLength:signedLength])
+	if sender == s.ownNode.Identity {
+		s.logger().Debug("Received and ignored gossip packet sent by ourselves", peerAddr(fromAddr.String()))
+		s.rejectPacket(fromAddr, RejectReasonSelfAddressed)
+		return nil, nil, false
+	}
+	if !s.verify(packetBytes[:signedLength], packetBytes[signedLength:], sender, fromAddr) {
+		return nil, nil, false
+	}
+	decryptedBytes, ok := s.decrypt(packetBytes[:ciphertextLength], fromAddr)
+	if !ok {
+		return nil, nil, false
+	}
+	// the packet parsers expect the signature to follow the packet
+	decryptedBytes = append(decryptedBytes, packetBytes[signedLength:]...)
+	header, ok := s.parseHeader(decryptedBytes, fromAddr)
+	if !ok {
+		return nil, nil, false
+	}
+	if header.SenderIdentity != sender {
+		s.logger().Info("Received gossip packet whose header names another sender than the signature", peerID(sender), peerAddr(fromAddr.String()))
+		s.rejectPacket(fromAddr, RejectReasonInvalidHeader)
+		return nil, nil, false
+	}
+	if !s.checkTimestamp(header, fromAddr) {
+		return nil, nil, false
+	}
+	return decryptedBytes, header, true
+}
+
+// decrypt decrypts a received packet, rejecting it if that fails.
+func (s *Server) decrypt(ciphertext []byte, fromAddr net.Addr) ([]byte, bool) {
+	decryptStart := time.Now()
+	decryptedBytes, err := s.crypto.DecryptPacket(ciphertext)
+	addDuration(metricDecryptNanoseconds, decryptStart)
+	if err != nil {
+		s.logger().Warn("Could not decrypt received gossip packet", zap.Error(err))
+		s.rejectPacket(fromAddr, RejectReasonDecryption)
+		return nil, false
+	}
+	return decryptedBytes, true
+}
+
+// parseHeader parses the header of a decrypted packet, rejecting packets with an invalid header and packets sent by ourselves.
+func (s *Server) parseHeader(decryptedBytes []byte, fromAddr net.Addr) (*PacketHeader, bool) {
+	if len(decryptedBytes) < PacketHeaderSize+SignatureSize {
+		s.logger().Info("Received gossip packet with invalid length")
+		s.rejectPacket(fromAddr, RejectReasonInvalidLength)
+		return nil, false
+	}
+	header, err := ParsePacketHeader(decryptedBytes[:PacketHeaderSize])
+	if err != nil {
+		s.logger().Info("Received gossip packet with invalid header", zap.Error(err))
+		s.rejectPacket(fromAddr, RejectReasonInvalidHeader)
+		return nil, false
+	}
+	// our own packets, e.g. sent to our own address leaked into a view, are never handled
+	if header.SenderIdentity == s.ownNode.Identity {
+		s.logger().Debug("Received and ignored gossip packet sent by ourselves", peerAddr(fromAddr.String()))
+		s.rejectPacket(fromAddr, RejectReasonSelfAddressed)
+		return nil, false
+	}
+	return header, true
+}
+
+// checkTimestamp records the clock offset of the sender of an authenticated packet and rejects the packet if it was timestamped
+// too long ago. The offset is recorded first, so the peers whose packets are rejected as stale still count towards the estimate.
+func (s *Server) checkTimestamp(header *PacketHeader, fromAddr net.Addr) bool {
+	now := s.clock.Now()
+	s.clockSkew.Record(header.SenderIdentity, header.Timestamp, now)
+	if time.UnixMilli(int64(header.Timestamp)).Add(time.Second * 8).Before(now) {
+		s.logger().Info("Received and ignored gossip packet with old timestamp", zap.Uint64("packet_time", header.Timestamp), zap.Int64("local_time", now.UnixMilli()))
+		s.rejectPacket(fromAddr, RejectReasonStale)
+		return false
+	}
+	return true
+}
+
+// verify verifies the signature of a received packet by the sender, rejecting the packet if it is invalid.
+func (s *Server) verify(signedBytes []byte, signature []byte, sender Identity, fromAddr net.Addr) bool {
+	verifyStart := time.Now()
+	err := s.crypto.VerifySignature(signedBytes, signature, sender)
+	addDuration(metricVerifyNanoseconds, verifyStart)
+	if err != nil {
+		s.logger().Info("Signature on received gossip packet could not be validated", zap.Error(err), peerAddr(fromAddr.String()))
+		s.rejectPacket(fromAddr, RejectReasonInvalidSignature)
+		s.recordPeerFailure(fromAddr, sender)
+		return false
+	}
+	return true
+}
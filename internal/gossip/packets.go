@@ -1,6 +1,7 @@
 package gossip
 
 import (
+	"crypto/sha256"
 	"errors"
 	challengeModule "gossiphers/internal/challenge"
 	"time"
@@ -20,7 +21,14 @@ const (
 	MessageTypeGossipPushChallenge MessageType = 0x0051
 	MessageTypeGossipPush          MessageType = 0x0052
 
-	MessageTypeGossipMessage MessageType = 0x0060
+	MessageTypeGossipMessage      MessageType = 0x0060
+	MessageTypeGossipMessageBatch MessageType = 0x0061
+
+	MessageTypeGossipDigest        MessageType = 0x0070
+	MessageTypeGossipDigestRequest MessageType = 0x0071
+	MessageTypeGossipIHave         MessageType = 0x0072
+
+	MessageTypeGossipLeave MessageType = 0x0080
 
 	// PacketHeaderSize represents the length of the PacketHeader in bytes.
 	// 2 bytes for the size field, 2 bytes for the Message Type, and 32 bytes for the Sender Identity.
@@ -28,10 +36,46 @@ const (
 	// SignatureSize represents the length of the signature in bytes.
 	SignatureSize    int = 512
 	PeerIdentitySize int = 32
+	// PingNonceSize represents the length of the nonce of a ping, which is echoed by the pong answering it, in bytes.
+	PingNonceSize = 8
 
 	MaxPacketSize = 65535
+
+	// MessageBatchEntryHeaderSize represents the length of the fields preceding the data of each message within a batch in bytes.
+	// 1 byte for the TTL, 1 byte for the flags, 2 bytes for the data type, and 2 bytes for the data size.
+	MessageBatchEntryHeaderSize = 6
+
+	// MessageFlagHighPriority marks a message of the high-priority lane, e.g. revocations or operator alerts, within the flags byte of a message.
+	MessageFlagHighPriority uint8 = 0x01
+	// MessageFlagOriginTimestamp marks a message carrying the time its origin announced it at, see OriginTimestampVersion.
+	// The timestamp precedes the data of the message.
+	MessageFlagOriginTimestamp uint8 = 0x02
+	// OriginTimestampSize represents the length of the origin timestamp of a message in bytes.
+	OriginTimestampSize = 8
+
+	// DigestEntrySize represents the length of a single digest entry in bytes.
+	// 2 bytes for the data type and 32 bytes for the SHA-256 hash of the data.
+	DigestEntrySize = 2 + sha256.Size
+	// MaxDigestEntries represents the maximum number of digest entries fitting into a single packet.
+	MaxDigestEntries = (MaxPacketSize - PacketHeaderSize - SignatureSize) / DigestEntrySize
 )
 
+// ProtocolVersion represents the version of the wire format of the gossip packets spoken by this build.
+// Nodes only communicate with peers supporting a common version, see SupportedProtocolVersions.
+// Version 2 added the nonce to pings and pongs, version 3 encrypts packets before signing them instead of signing them
+// before encrypting them, see packetProtection, and version 4 allows wrapping the packet keys with the hybrid KEM instead of
+// RSA-OAEP, see HybridKeyWrapVersion, and version 5 advertises the capabilities of nodes along with their addresses, see
+// CapabilitiesVersion, and version 6 carries the origin timestamps of messages, see OriginTimestampVersion.
+// The version spoken is configured, this is the newest one.
+const ProtocolVersion uint8 = 6
+
+// OriginTimestampVersion represents the first protocol version carrying the time a message was announced at by its origin
+// within message packets, so every node expires the message at the same time instead of a lifetime after receiving it.
+const OriginTimestampVersion uint8 = 6
+
+// SupportedProtocolVersions lists the versions of the wire format this build is able to speak, oldest first.
+var SupportedProtocolVersions = []uint8{2, 3, HybridKeyWrapVersion, CapabilitiesVersion, ProtocolVersion}
+
 var (
 	ErrCreatePacketInvalidComponentSize = errors.New("packet could not be created, component of invalid size or maximum size exceeded")
 )
@@ -52,19 +96,40 @@ type PacketFooter struct {
 // PacketPing represents a probe sent from one node, n1, to the other node, n2, to check if n2 is still alive.
 type PacketPing struct {
 	PacketHeader
+	// Nonce identifies the ping, n2 echoes it in its pong
+	Nonce uint64
 	PacketFooter
 }
 
-// NewPacketPing returns a new instance of PacketPing.
-func NewPacketPing(senderID Identity) (*PacketPing, error) {
-	if len(senderID) != PeerIdentitySize {
-		return nil, ErrCreatePacketInvalidComponentSize
-	}
+// NewPacketPing returns a new instance of PacketPing timestamped with now.
+func NewPacketPing(senderID Identity, nonce uint64, now time.Time) (*PacketPing, error) {
 	return &PacketPing{
 		PacketHeader: PacketHeader{
-			Size:           uint16(PacketHeaderSize + SignatureSize),
+			Size:           uint16(PacketHeaderSize + PingNonceSize + SignatureSize),
 			Type:           MessageTypeGossipPing,
-			Timestamp:      uint64(time.Now().UnixMilli()),
+			Timestamp:      uint64(now.UnixMilli()),
+			SenderIdentity: senderID,
+		},
+		Nonce: nonce,
+		PacketFooter: PacketFooter{
+			Signature: nil,
+		},
+	}, nil
+}
+
+// PacketLeave represents the announcement of a node shutting down gracefully, so its peers stop contacting it right away.
+type PacketLeave struct {
+	PacketHeader
+	PacketFooter
+}
+
+// NewPacketLeave returns a new instance of PacketLeave timestamped with now.
+func NewPacketLeave(senderID Identity, now time.Time) (*PacketLeave, error) {
+	return &PacketLeave{
+		PacketHeader: PacketHeader{
+			Size:           uint16(PacketHeaderSize + SignatureSize),
+			Type:           MessageTypeGossipLeave,
+			Timestamp:      uint64(now.UnixMilli()),
 			SenderIdentity: senderID,
 		},
 		PacketFooter: PacketFooter{
@@ -76,21 +141,21 @@ func NewPacketPing(senderID Identity) (*PacketPing, error) {
 // PacketPong represents a reply to the ping indicating that n2 is alive.
 type PacketPong struct {
 	PacketHeader
+	// Nonce is the nonce of the answered ping
+	Nonce uint64
 	PacketFooter
 }
 
-// NewPacketPong returns a new instance of PacketPong.
-func NewPacketPong(senderID Identity) (*PacketPing, error) {
-	if len(senderID) != PeerIdentitySize {
-		return nil, ErrCreatePacketInvalidComponentSize
-	}
-	return &PacketPing{
+// NewPacketPong returns a new instance of PacketPong answering the ping with the given nonce, timestamped with now.
+func NewPacketPong(senderID Identity, nonce uint64, now time.Time) (*PacketPong, error) {
+	return &PacketPong{
 		PacketHeader: PacketHeader{
-			Size:           uint16(PacketHeaderSize + SignatureSize),
+			Size:           uint16(PacketHeaderSize + PingNonceSize + SignatureSize),
 			Type:           MessageTypeGossipPong,
-			Timestamp:      uint64(time.Now().UnixMilli()),
+			Timestamp:      uint64(now.UnixMilli()),
 			SenderIdentity: senderID,
 		},
+		Nonce: nonce,
 		PacketFooter: PacketFooter{
 			Signature: nil,
 		},
@@ -103,16 +168,13 @@ type PacketPullRequest struct {
 	PacketFooter
 }
 
-// NewPacketPullRequest returns a new instance of PacketPullRequest.
-func NewPacketPullRequest(senderID Identity) (*PacketPullRequest, error) {
-	if len(senderID) != PeerIdentitySize {
-		return nil, ErrCreatePacketInvalidComponentSize
-	}
+// NewPacketPullRequest returns a new instance of PacketPullRequest timestamped with now.
+func NewPacketPullRequest(senderID Identity, now time.Time) (*PacketPullRequest, error) {
 	return &PacketPullRequest{
 		PacketHeader: PacketHeader{
 			Size:           uint16(PacketHeaderSize + SignatureSize),
 			Type:           MessageTypeGossipPullRequest,
-			Timestamp:      uint64(time.Now().UnixMilli()),
+			Timestamp:      uint64(now.UnixMilli()),
 			SenderIdentity: senderID,
 		},
 		PacketFooter: PacketFooter{
@@ -128,20 +190,20 @@ type PacketPullResponse struct {
 	PacketFooter
 }
 
-// NewPacketPullResponse returns a new instance of PacketPullResponse.
-func NewPacketPullResponse(senderID Identity, nodes []Node) (*PacketPullResponse, error) {
+// NewPacketPullResponse returns a new instance of PacketPullResponse timestamped with now.
+func NewPacketPullResponse(senderID Identity, nodes []Node, now time.Time) (*PacketPullResponse, error) {
 	packetSize := PacketHeaderSize + SignatureSize
 	for _, node := range nodes {
 		packetSize += len(node.ToBytes())
 	}
-	if len(senderID) != PeerIdentitySize || packetSize > MaxPacketSize {
+	if packetSize > MaxPacketSize {
 		return nil, ErrCreatePacketInvalidComponentSize
 	}
 	return &PacketPullResponse{
 		PacketHeader: PacketHeader{
 			Size:           uint16(packetSize),
 			Type:           MessageTypeGossipPullResponse,
-			Timestamp:      uint64(time.Now().UnixMilli()),
+			Timestamp:      uint64(now.UnixMilli()),
 			SenderIdentity: senderID,
 		},
 		Nodes: nodes,
@@ -157,16 +219,13 @@ type PacketPushRequest struct {
 	PacketFooter
 }
 
-// NewPacketPushRequest returns a new instance of PacketPushRequest.
-func NewPacketPushRequest(senderID Identity) (*PacketPushRequest, error) {
-	if len(senderID) != PeerIdentitySize {
-		return nil, ErrCreatePacketInvalidComponentSize
-	}
+// NewPacketPushRequest returns a new instance of PacketPushRequest timestamped with now.
+func NewPacketPushRequest(senderID Identity, now time.Time) (*PacketPushRequest, error) {
 	return &PacketPushRequest{
 		PacketHeader: PacketHeader{
 			Size:           uint16(PacketHeaderSize + SignatureSize),
 			Type:           MessageTypeGossipPushRequest,
-			Timestamp:      uint64(time.Now().UnixMilli()),
+			Timestamp:      uint64(now.UnixMilli()),
 			SenderIdentity: senderID,
 		},
 		PacketFooter: PacketFooter{
@@ -183,16 +242,16 @@ type PacketPushChallenge struct {
 	PacketFooter
 }
 
-// NewPacketPushChallenge returns a new instance of PacketPushChallenge.
-func NewPacketPushChallenge(senderID Identity, difficulty uint32, challenge []byte) (*PacketPushChallenge, error) {
-	if len(senderID) != PeerIdentitySize || len(challenge) != challengeModule.ChallengeSize {
+// NewPacketPushChallenge returns a new instance of PacketPushChallenge timestamped with now.
+func NewPacketPushChallenge(senderID Identity, difficulty uint32, challenge []byte, now time.Time) (*PacketPushChallenge, error) {
+	if len(challenge) != challengeModule.ChallengeSize {
 		return nil, ErrCreatePacketInvalidComponentSize
 	}
 	return &PacketPushChallenge{
 		PacketHeader: PacketHeader{
 			Size:           uint16(PacketHeaderSize+SignatureSize+challengeModule.ChallengeSize) + 4, // difficulty = 4
 			Type:           MessageTypeGossipPushChallenge,
-			Timestamp:      uint64(time.Now().UnixMilli()),
+			Timestamp:      uint64(now.UnixMilli()),
 			SenderIdentity: senderID,
 		},
 		Difficulty: difficulty,
@@ -212,17 +271,17 @@ type PacketPush struct {
 	PacketFooter
 }
 
-// NewPacketPush returns a new instance of PacketPush.
-func NewPacketPush(senderID Identity, challenge []byte, nonce []byte, node Node) (*PacketPush, error) {
+// NewPacketPush returns a new instance of PacketPush timestamped with now.
+func NewPacketPush(senderID Identity, challenge []byte, nonce []byte, node Node, now time.Time) (*PacketPush, error) {
 	packetSize := PacketHeaderSize + SignatureSize + challengeModule.ChallengeSize + challengeModule.NonceSize + len(node.ToBytes())
-	if len(senderID) != PeerIdentitySize || len(challenge) != challengeModule.ChallengeSize || len(nonce) != challengeModule.NonceSize || packetSize > MaxPacketSize {
+	if len(challenge) != challengeModule.ChallengeSize || len(nonce) != challengeModule.NonceSize || packetSize > MaxPacketSize {
 		return nil, ErrCreatePacketInvalidComponentSize
 	}
 	return &PacketPush{
 		PacketHeader: PacketHeader{
 			Size:           uint16(packetSize),
 			Type:           MessageTypeGossipPush,
-			Timestamp:      uint64(time.Now().UnixMilli()),
+			Timestamp:      uint64(now.UnixMilli()),
 			SenderIdentity: senderID,
 		},
 		Challenge: challenge,
@@ -238,30 +297,194 @@ func NewPacketPush(senderID Identity, challenge []byte, nonce []byte, node Node)
 type PacketMessage struct {
 	PacketHeader
 	TTL uint8
-	/* reserved 8 bits */
+	// Flags holds MessageFlagHighPriority and MessageFlagOriginTimestamp, the remaining bits are reserved
+	Flags    uint8
 	DataType uint16
-	Data     []byte
+	// OriginTimestamp is the time in Unix milliseconds the origin announced the message at, only sent if Flags holds MessageFlagOriginTimestamp
+	OriginTimestamp uint64
+	Data            []byte
 	PacketFooter
 }
 
-// NewPacketMessage returns a new instance of PacketMessage.
-func NewPacketMessage(senderID Identity, ttl uint8, dataType uint16, data []byte) (*PacketMessage, error) {
-	packetSize := PacketHeaderSize + SignatureSize + 1 + 1 + 2 + len(data) // ttl = 1, reserved = 1, dataType = 2
-	if len(senderID) != PeerIdentitySize || packetSize > MaxPacketSize {
+// NewPacketMessage returns a new instance of PacketMessage timestamped with now.
+// An origin timestamp of 0 is omitted, otherwise MessageFlagOriginTimestamp is set.
+func NewPacketMessage(senderID Identity, ttl uint8, dataType uint16, originTimestamp uint64, data []byte, now time.Time) (*PacketMessage, error) {
+	packetSize := PacketHeaderSize + SignatureSize + 1 + 1 + 2 + len(data) // ttl = 1, flags = 1, dataType = 2
+	var flags uint8
+	if originTimestamp != 0 {
+		packetSize += OriginTimestampSize
+		flags = MessageFlagOriginTimestamp
+	}
+	if packetSize > MaxPacketSize {
 		return nil, ErrCreatePacketInvalidComponentSize
 	}
 	return &PacketMessage{
 		PacketHeader: PacketHeader{
 			Size:           uint16(packetSize),
 			Type:           MessageTypeGossipMessage,
-			Timestamp:      uint64(time.Now().UnixMilli()),
+			Timestamp:      uint64(now.UnixMilli()),
 			SenderIdentity: senderID,
 		},
-		TTL:      ttl,
-		DataType: dataType,
-		Data:     data,
+		TTL:             ttl,
+		Flags:           flags,
+		DataType:        dataType,
+		OriginTimestamp: originTimestamp,
+		Data:            data,
 		PacketFooter: PacketFooter{
 			Signature: nil,
 		},
 	}, nil
 }
+
+// MessageBatchEntry represents a single gossip message within a PacketMessageBatch.
+type MessageBatchEntry struct {
+	TTL uint8
+	// Flags holds MessageFlagHighPriority and MessageFlagOriginTimestamp, the remaining bits are reserved
+	Flags    uint8
+	DataType uint16
+	// OriginTimestamp is the time in Unix milliseconds the origin announced the message at, only sent if Flags holds MessageFlagOriginTimestamp
+	OriginTimestamp uint64
+	Data            []byte
+}
+
+// Size returns the length of the serialized entry in bytes.
+func (e *MessageBatchEntry) Size() int {
+	size := MessageBatchEntryHeaderSize + len(e.Data)
+	if e.Flags&MessageFlagOriginTimestamp != 0 {
+		size += OriginTimestampSize
+	}
+	return size
+}
+
+// PacketMessageBatch represents several gossip messages sent under a single header and signature, amortizing the cost of signing and encrypting small messages.
+// Each message is handled as if it was received within a separate PacketMessage.
+type PacketMessageBatch struct {
+	PacketHeader
+	Messages []MessageBatchEntry
+	PacketFooter
+}
+
+// NewPacketMessageBatch returns a new instance of PacketMessageBatch timestamped with now.
+func NewPacketMessageBatch(senderID Identity, messages []MessageBatchEntry, now time.Time) (*PacketMessageBatch, error) {
+	packetSize := PacketHeaderSize + SignatureSize
+	for _, msg := range messages {
+		packetSize += msg.Size()
+	}
+	if len(messages) == 0 || packetSize > MaxPacketSize {
+		return nil, ErrCreatePacketInvalidComponentSize
+	}
+	return &PacketMessageBatch{
+		PacketHeader: PacketHeader{
+			Size:           uint16(packetSize),
+			Type:           MessageTypeGossipMessageBatch,
+			Timestamp:      uint64(now.UnixMilli()),
+			SenderIdentity: senderID,
+		},
+		Messages: messages,
+		PacketFooter: PacketFooter{
+			Signature: nil,
+		},
+	}, nil
+}
+
+// DigestEntry identifies a gossip message by its data type and the SHA-256 hash of its data.
+type DigestEntry struct {
+	DataType uint16
+	DataHash []byte
+}
+
+// PacketDigest represents a summary of the messages stored by a node, sent to a random peer to reconcile both message stores.
+// The peer replies with the messages missing from the digest and requests the messages it is missing itself.
+type PacketDigest struct {
+	PacketHeader
+	Entries []DigestEntry
+	PacketFooter
+}
+
+// NewPacketDigest returns a new instance of PacketDigest timestamped with now.
+func NewPacketDigest(senderID Identity, entries []DigestEntry, now time.Time) (*PacketDigest, error) {
+	packetSize, err := digestPacketSize(senderID, entries)
+	if err != nil {
+		return nil, err
+	}
+	return &PacketDigest{
+		PacketHeader: PacketHeader{
+			Size:           uint16(packetSize),
+			Type:           MessageTypeGossipDigest,
+			Timestamp:      uint64(now.UnixMilli()),
+			SenderIdentity: senderID,
+		},
+		Entries: entries,
+		PacketFooter: PacketFooter{
+			Signature: nil,
+		},
+	}, nil
+}
+
+// PacketDigestRequest represents the reply to a digest, requesting the messages of the digest which the replying node is missing.
+type PacketDigestRequest struct {
+	PacketHeader
+	Entries []DigestEntry
+	PacketFooter
+}
+
+// NewPacketDigestRequest returns a new instance of PacketDigestRequest timestamped with now.
+func NewPacketDigestRequest(senderID Identity, entries []DigestEntry, now time.Time) (*PacketDigestRequest, error) {
+	packetSize, err := digestPacketSize(senderID, entries)
+	if err != nil {
+		return nil, err
+	}
+	return &PacketDigestRequest{
+		PacketHeader: PacketHeader{
+			Size:           uint16(packetSize),
+			Type:           MessageTypeGossipDigestRequest,
+			Timestamp:      uint64(now.UnixMilli()),
+			SenderIdentity: senderID,
+		},
+		Entries: entries,
+		PacketFooter: PacketFooter{
+			Signature: nil,
+		},
+	}, nil
+}
+
+// PacketIHave represents a lightweight announcement of the messages a node currently spreads, sent instead of the full messages to peers outside of the eager subset.
+// The peer requests the messages it is missing with a PacketDigestRequest.
+type PacketIHave struct {
+	PacketHeader
+	Entries []DigestEntry
+	PacketFooter
+}
+
+// NewPacketIHave returns a new instance of PacketIHave timestamped with now.
+func NewPacketIHave(senderID Identity, entries []DigestEntry, now time.Time) (*PacketIHave, error) {
+	packetSize, err := digestPacketSize(senderID, entries)
+	if err != nil {
+		return nil, err
+	}
+	return &PacketIHave{
+		PacketHeader: PacketHeader{
+			Size:           uint16(packetSize),
+			Type:           MessageTypeGossipIHave,
+			Timestamp:      uint64(now.UnixMilli()),
+			SenderIdentity: senderID,
+		},
+		Entries: entries,
+		PacketFooter: PacketFooter{
+			Signature: nil,
+		},
+	}, nil
+}
+
+// digestPacketSize validates the components of a digest packet and returns its size.
+func digestPacketSize(senderID Identity, entries []DigestEntry) (int, error) {
+	if len(entries) > MaxDigestEntries {
+		return 0, ErrCreatePacketInvalidComponentSize
+	}
+	for _, entry := range entries {
+		if len(entry.DataHash) != sha256.Size {
+			return 0, ErrCreatePacketInvalidComponentSize
+		}
+	}
+	return PacketHeaderSize + SignatureSize + len(entries)*DigestEntrySize, nil
+}
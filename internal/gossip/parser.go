@@ -2,10 +2,12 @@ package gossip
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"gossiphers/internal/challenge"
+	"strconv"
 	"strings"
 )
 
@@ -14,7 +16,7 @@ var (
 	ErrParsePacketHeaderInvalidType = errors.New("packet could not be parsed, type not implemented")
 	ErrParsePacketInvalidSize       = errors.New("packet could not be parsed, size in header does not match received data")
 
-	supportedIncomingMessageTypes = []MessageType{MessageTypeGossipPing, MessageTypeGossipPong, MessageTypeGossipPullRequest, MessageTypeGossipPullResponse, MessageTypeGossipPush, MessageTypeGossipPushChallenge, MessageTypeGossipPushRequest, MessageTypeGossipMessage}
+	supportedIncomingMessageTypes = []MessageType{MessageTypeGossipPing, MessageTypeGossipPong, MessageTypeGossipPullRequest, MessageTypeGossipPullResponse, MessageTypeGossipPush, MessageTypeGossipPushChallenge, MessageTypeGossipPushRequest, MessageTypeGossipMessage, MessageTypeGossipMessageBatch, MessageTypeGossipDigest, MessageTypeGossipDigestRequest, MessageTypeGossipIHave, MessageTypeGossipLeave}
 )
 
 // ParseablePacket represents the ability to parse this particular packet.
@@ -66,8 +68,37 @@ func parseSignature(reader *bytes.Reader) ([]byte, error) {
 	return sig, nil
 }
 
+// parsePingNonce reads the nonce of a ping or pong from the reader.
+func parsePingNonce(reader *bytes.Reader) (uint64, error) {
+	if reader.Len() < PingNonceSize {
+		return 0, fmt.Errorf("packet too short to contain the ping nonce: %d bytes remaining", reader.Len())
+	}
+	var nonce uint64
+	err := binary.Read(reader, binary.BigEndian, &nonce)
+	return nonce, err
+}
+
 // Parse parses the Ping packet assuming that the packet has already been decrypted.
 func (p *PacketPing) Parse(header *PacketHeader, reader *bytes.Reader) error {
+	// Assuming the header has already been read and that the reader is now on the first byte of the data.
+	nonce, err := parsePingNonce(reader)
+	if err != nil {
+		return err
+	}
+	sig, err := parseSignature(reader)
+	if err != nil {
+		return err
+	}
+
+	p.PacketHeader = *header
+	p.Nonce = nonce
+	p.Signature = sig
+
+	return nil
+}
+
+// Parse parses the Leave packet assuming that the packet has already been decrypted.
+func (p *PacketLeave) Parse(header *PacketHeader, reader *bytes.Reader) error {
 	// Assuming the header has already been read and that the reader is now on the first byte of the data.
 	sig, err := parseSignature(reader)
 	if err != nil {
@@ -83,12 +114,17 @@ func (p *PacketPing) Parse(header *PacketHeader, reader *bytes.Reader) error {
 // Parse parses the Pong packet assuming that the packet has already been decrypted.
 func (p *PacketPong) Parse(header *PacketHeader, reader *bytes.Reader) error {
 	// Assuming the header has already been read and that the reader is now on the first byte of the data.
+	nonce, err := parsePingNonce(reader)
+	if err != nil {
+		return err
+	}
 	sig, err := parseSignature(reader)
 	if err != nil {
 		return err
 	}
 
 	p.PacketHeader = *header
+	p.Nonce = nonce
 	p.Signature = sig
 	return nil
 }
@@ -107,12 +143,13 @@ func (p *PacketPullRequest) Parse(header *PacketHeader, reader *bytes.Reader) er
 }
 
 // parseNodes takes a string of the form <identity1>\t<address1>\n<identity2>\t<address2>\n<identity3>\t<address3>\n... and parses it into a slice of nodes.
+// Each address may be followed by \t<capabilities> with the capabilities as 4 hex digits. Trailing bytes too short to hold another node are rejected.
 func parseNodes(nodeBytes []byte) ([]Node, error) {
 	reader := bytes.NewReader(nodeBytes)
 	var nodes []Node
-	for {
+	for reader.Len() > 0 {
 		if reader.Len() < IdentitySize+3 {
-			break
+			return nil, fmt.Errorf("%d trailing bytes in node list are too short to contain a node", reader.Len())
 		}
 		nodeIdentity := make([]byte, IdentitySize)
 		_, err := reader.Read(nodeIdentity)
@@ -132,13 +169,20 @@ func parseNodes(nodeBytes []byte) ([]Node, error) {
 			rest = append(rest, readRune)
 		}
 		if !strings.HasPrefix(string(rest), "\t") {
-			return nil, fmt.Errorf("expected a \\t separator in node list, found %v", rest[0])
+			return nil, fmt.Errorf("expected a \\t separator in node list, found %q", string(rest))
 		}
-		address := strings.TrimPrefix(string(rest), "\t")
+		address, capabilitiesStr, hasCapabilities := strings.Cut(strings.TrimPrefix(string(rest), "\t"), "\t")
 		newNode, err := NewNode(nodeIdentity, address)
 		if err != nil {
 			return nil, err
 		}
+		if hasCapabilities {
+			capabilities, err := strconv.ParseUint(capabilitiesStr, 16, 16)
+			if err != nil || len(capabilitiesStr) != 4 {
+				return nil, fmt.Errorf("expected capabilities of 4 hex digits in node list, found %q", capabilitiesStr)
+			}
+			newNode.Capabilities = Capabilities(capabilities)
+		}
 
 		nodes = append(nodes, *newNode)
 	}
@@ -251,7 +295,7 @@ func (p *PacketPush) Parse(header *PacketHeader, reader *bytes.Reader) error {
 	// read <identity>\t<address>\n
 	nodeTotalSize := reader.Len() - SignatureSize
 	// IdentitySize + 2 + 1 gives you the size of the Identity, the length of \n and \t assuming UTF-8 encoding, and the minimum address size.
-	if nodeTotalSize <= IdentitySize+2+1 {
+	if nodeTotalSize < IdentitySize+2+1 {
 		return errors.New("missing <identity>\\t<address>\\n component of PUSH packet")
 	}
 	nodeBytes := make([]byte, nodeTotalSize)
@@ -284,7 +328,7 @@ func (p *PacketPush) Parse(header *PacketHeader, reader *bytes.Reader) error {
 // Parse parses the Message packet assuming that the packet has already been decrypted.
 func (p *PacketMessage) Parse(header *PacketHeader, reader *bytes.Reader) error {
 	// Assuming the header has already been read and that the reader is now on the first byte of the data.
-	// mineRequiredSize is derived from adding all of the non-header fields' byte size requirements together with the exclusion of the data field. 1 (TTL, uint8) + 1 (reserved byte) + 2 (DataType, uint16) + SignatureSize.
+	// mineRequiredSize is derived from adding all of the non-header fields' byte size requirements together with the exclusion of the data field. 1 (TTL, uint8) + 1 (flags byte) + 2 (DataType, uint16) + SignatureSize.
 	minRequiredSize := 1 + 1 + 2 + SignatureSize
 	if reader.Len() < minRequiredSize {
 		return fmt.Errorf("packet length excluding the header is less than the minimum required size: minimum required size: %d, actual size: %d", minRequiredSize, reader.Len())
@@ -293,15 +337,24 @@ func (p *PacketMessage) Parse(header *PacketHeader, reader *bytes.Reader) error
 	// Read TTL
 	binary.Read(reader, binary.BigEndian, &p.TTL)
 
-	// skip over reserved byte
-	_, err := reader.ReadByte()
+	// Read flags
+	flags, err := reader.ReadByte()
 	if err != nil {
 		return err
 	}
+	p.Flags = flags
 
 	// Read DataType
 	binary.Read(reader, binary.BigEndian, &p.DataType)
 
+	// Read the origin timestamp if present
+	if flags&MessageFlagOriginTimestamp != 0 {
+		if reader.Len() < OriginTimestampSize+SignatureSize {
+			return fmt.Errorf("insufficient space for origin timestamp and signature in packet")
+		}
+		binary.Read(reader, binary.BigEndian, &p.OriginTimestamp)
+	}
+
 	// Read Data
 	dataLen := reader.Len() - SignatureSize
 	if dataLen < 0 {
@@ -328,3 +381,139 @@ func (p *PacketMessage) Parse(header *PacketHeader, reader *bytes.Reader) error
 	p.Signature = sig
 	return nil
 }
+
+// Parse parses the MessageBatch packet assuming that the packet has already been decrypted.
+func (p *PacketMessageBatch) Parse(header *PacketHeader, reader *bytes.Reader) error {
+	// Assuming the header has already been read and that the reader is now on the first byte of the data.
+	var messages []MessageBatchEntry
+	for reader.Len() > SignatureSize {
+		if reader.Len() < MessageBatchEntryHeaderSize+SignatureSize {
+			return fmt.Errorf("insufficient space for message batch entry and signature in packet")
+		}
+		var msg MessageBatchEntry
+		var dataSize uint16
+		binary.Read(reader, binary.BigEndian, &msg.TTL)
+		flags, err := reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		msg.Flags = flags
+		binary.Read(reader, binary.BigEndian, &msg.DataType)
+		binary.Read(reader, binary.BigEndian, &dataSize)
+		if flags&MessageFlagOriginTimestamp != 0 {
+			if reader.Len() < OriginTimestampSize+SignatureSize {
+				return fmt.Errorf("insufficient space for origin timestamp and signature in packet")
+			}
+			binary.Read(reader, binary.BigEndian, &msg.OriginTimestamp)
+		}
+		if reader.Len() < int(dataSize)+SignatureSize {
+			return fmt.Errorf("insufficient space for message data of %d bytes and signature in packet", dataSize)
+		}
+		// technically, data could be 0 bytes.
+		if dataSize != 0 {
+			msg.Data = make([]byte, dataSize)
+			_, err = reader.Read(msg.Data)
+			if err != nil {
+				return err
+			}
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) == 0 {
+		return errors.New("expecting messages, but no messages have been included within the MessageBatch packet")
+	}
+
+	// read signature
+	sig, err := parseSignature(reader)
+	if err != nil {
+		return err
+	}
+
+	p.PacketHeader = *header
+	p.Messages = messages
+	p.Signature = sig
+	return nil
+}
+
+// parseDigestEntries reads the digest entries preceding the signature.
+func parseDigestEntries(reader *bytes.Reader) ([]DigestEntry, error) {
+	entriesTotalSize := reader.Len() - SignatureSize
+	if entriesTotalSize < 0 || entriesTotalSize%DigestEntrySize != 0 {
+		return nil, fmt.Errorf("packet length excluding the header and signature is not a multiple of the digest entry size: %d bytes", entriesTotalSize)
+	}
+	entries := make([]DigestEntry, 0, entriesTotalSize/DigestEntrySize)
+	for i := 0; i < entriesTotalSize/DigestEntrySize; i++ {
+		var entry DigestEntry
+		err := binary.Read(reader, binary.BigEndian, &entry.DataType)
+		if err != nil {
+			return nil, err
+		}
+		entry.DataHash = make([]byte, sha256.Size)
+		n, err := reader.Read(entry.DataHash)
+		if err != nil {
+			return nil, err
+		}
+		if n != sha256.Size {
+			return nil, fmt.Errorf("data hash improperly read: only %d bytes read", n)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Parse parses the Digest packet assuming that the packet has already been decrypted.
+func (p *PacketDigest) Parse(header *PacketHeader, reader *bytes.Reader) error {
+	// Assuming the header has already been read and that the reader is now on the first byte of the data.
+	entries, err := parseDigestEntries(reader)
+	if err != nil {
+		return err
+	}
+
+	sig, err := parseSignature(reader)
+	if err != nil {
+		return err
+	}
+
+	p.PacketHeader = *header
+	p.Entries = entries
+	p.Signature = sig
+	return nil
+}
+
+// Parse parses the DigestRequest packet assuming that the packet has already been decrypted.
+func (p *PacketDigestRequest) Parse(header *PacketHeader, reader *bytes.Reader) error {
+	// Assuming the header has already been read and that the reader is now on the first byte of the data.
+	entries, err := parseDigestEntries(reader)
+	if err != nil {
+		return err
+	}
+
+	sig, err := parseSignature(reader)
+	if err != nil {
+		return err
+	}
+
+	p.PacketHeader = *header
+	p.Entries = entries
+	p.Signature = sig
+	return nil
+}
+
+// Parse parses the IHave packet assuming that the packet has already been decrypted.
+func (p *PacketIHave) Parse(header *PacketHeader, reader *bytes.Reader) error {
+	// Assuming the header has already been read and that the reader is now on the first byte of the data.
+	entries, err := parseDigestEntries(reader)
+	if err != nil {
+		return err
+	}
+
+	sig, err := parseSignature(reader)
+	if err != nil {
+		return err
+	}
+
+	p.PacketHeader = *header
+	p.Entries = entries
+	p.Signature = sig
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"gossiphers/internal/challenge"
 	"io"
 	"testing"
+	"time"
 )
 
 func TestParsePacketHeader(t *testing.T) {
@@ -110,20 +111,21 @@ func TestParsePacketPing(t *testing.T) {
 		}
 		p := PacketPing{
 			PacketHeader: ph,
+			Nonce:        0x0123456789abcdef,
 			PacketFooter: pf,
 		}
 
 		reader := bytes.NewReader(p.ToBytes())
 
-		if reader.Len() != 556 {
-			t.Errorf("expecting 556, got %d", reader.Len())
+		if reader.Len() != 564 {
+			t.Errorf("expecting 564, got %d", reader.Len())
 		}
 		_, err = reader.Seek(int64(PacketHeaderSize), io.SeekStart)
 		if err != nil {
 			t.Error(err)
 		}
-		if reader.Len() != 512 {
-			t.Errorf("expecting 512, got %d", reader.Len())
+		if reader.Len() != 520 {
+			t.Errorf("expecting 520, got %d", reader.Len())
 		}
 
 		var pingPacket PacketPing
@@ -141,6 +143,9 @@ func TestParsePacketPing(t *testing.T) {
 		if !bytes.Equal(pingPacket.SenderIdentity.ToBytes(), mockSenderIdentity.ToBytes()) {
 			t.Errorf("pingPacket.SenderIdentity incorrect: expected %v, received %v", mockSenderIdentity, pingPacket.SenderIdentity)
 		}
+		if pingPacket.Nonce != 0x0123456789abcdef {
+			t.Errorf("pingPacket.Nonce incorrect: expected 0x0123456789abcdef, received %#x", pingPacket.Nonce)
+		}
 		if !bytes.Equal(pingPacket.Signature, mockSignature) {
 			t.Errorf("pingPacket.Signature incorrect: expected %v, received %v", mockSignature, pingPacket.Signature)
 		}
@@ -168,19 +173,20 @@ func TestParsePacketPong(t *testing.T) {
 		}
 		p := PacketPong{
 			PacketHeader: ph,
+			Nonce:        0x0123456789abcdef,
 			PacketFooter: pf,
 		}
 
 		reader := bytes.NewReader(p.ToBytes())
-		if reader.Len() != 556 {
-			t.Errorf("expecting 556, got %d", reader.Len())
+		if reader.Len() != 564 {
+			t.Errorf("expecting 564, got %d", reader.Len())
 		}
 		_, err = reader.Seek(int64(PacketHeaderSize), io.SeekStart)
 		if err != nil {
 			t.Error(err)
 		}
-		if reader.Len() != 512 {
-			t.Errorf("expecting 512, got %d", reader.Len())
+		if reader.Len() != 520 {
+			t.Errorf("expecting 520, got %d", reader.Len())
 		}
 
 		var pongPacket PacketPong
@@ -198,6 +204,9 @@ func TestParsePacketPong(t *testing.T) {
 		if !bytes.Equal(pongPacket.SenderIdentity.ToBytes(), mockSenderIdentity.ToBytes()) {
 			t.Errorf("SenderIdentity attribute incorrect: expected %v, received %v", mockSenderIdentity, pongPacket.SenderIdentity)
 		}
+		if pongPacket.Nonce != 0x0123456789abcdef {
+			t.Errorf("Nonce attribute incorrect: expected 0x0123456789abcdef, received %#x", pongPacket.Nonce)
+		}
 		if !bytes.Equal(pongPacket.Signature, mockSignature) {
 			t.Errorf("Signature attribute incorrect: expected %v, received %v", mockSignature, pongPacket.Signature)
 		}
@@ -325,6 +334,28 @@ func TestParseNodes(t *testing.T) {
 			t.Errorf("nodes[0].Address incorrect: expected %s, received %s", mockAddr1, nodes[0].Address)
 		}
 	})
+	t.Run("capabilities are parsed along with the address", func(t *testing.T) {
+		var mockNodes []byte
+		mockNodes = append(mockNodes, sliceRepeat(IdentitySize, byte(0x01))...)
+		mockNodes = append(mockNodes, []byte("\t1.2.3.4:5678\t0009\n")...)
+		nodes, err := parseNodes(mockNodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nodes[0].Address != "1.2.3.4:5678" || nodes[0].Capabilities != CapabilityCompression|CapabilityRelay {
+			t.Errorf("expected 1.2.3.4:5678 with compression and relay, received %s with %s", nodes[0].Address, nodes[0].Capabilities)
+		}
+	})
+	t.Run("malformed capabilities fail", func(t *testing.T) {
+		for _, capabilities := range []string{"", "9", "00009", "zzzz", "0009\t"} {
+			var mockNodes []byte
+			mockNodes = append(mockNodes, sliceRepeat(IdentitySize, byte(0x01))...)
+			mockNodes = append(mockNodes, []byte("\t1.2.3.4:5678\t"+capabilities+"\n")...)
+			if _, err := parseNodes(mockNodes); err == nil {
+				t.Errorf("expected capabilities %q to fail", capabilities)
+			}
+		}
+	})
 }
 
 func TestParsePacketPullResponse(t *testing.T) {
@@ -624,7 +655,7 @@ func TestParsePacketMessage(t *testing.T) {
 			t.Error(err)
 		}
 		mockSignature := createMockSignature()
-		// 1 --> TTL, 1 --> reserved, 2 --> DataType
+		// 1 --> TTL, 1 --> flags, 2 --> DataType
 		expectedSize := PacketHeaderSize + 1 + 1 + 2 + len(mockData) + SignatureSize
 		ph := PacketHeader{
 			Size:           uint16(expectedSize),
@@ -637,6 +668,7 @@ func TestParsePacketMessage(t *testing.T) {
 		p := PacketMessage{
 			PacketHeader: ph,
 			TTL:          uint8(mockTTL),
+			Flags:        MessageFlagHighPriority,
 			DataType:     uint16(0x1234),
 			Data:         mockData,
 			PacketFooter: pf,
@@ -674,6 +706,9 @@ func TestParsePacketMessage(t *testing.T) {
 		if message.TTL != uint8(mockTTL) {
 			t.Errorf("TTL attribute incorrect: expected %d, received %d", mockTTL, message.TTL)
 		}
+		if message.Flags != MessageFlagHighPriority {
+			t.Errorf("Flags attribute incorrect: expected %d, received %d", MessageFlagHighPriority, message.Flags)
+		}
 		if message.DataType != uint16(0x1234) {
 			t.Errorf("DataType attribute incorrect: expected %d, received %d", uint16(0x1234), message.DataType)
 		}
@@ -682,3 +717,180 @@ func TestParsePacketMessage(t *testing.T) {
 		}
 	})
 }
+
+func TestParsePacketDigest(t *testing.T) {
+	t.Parallel()
+	temp := sha256.Sum256(nil)
+	mockSenderIdentity, err := NewIdentity(temp[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockEntries := []DigestEntry{
+		{DataType: 0x1234, DataHash: sliceRepeat(sha256.Size, byte(0x24))},
+		{DataType: 0x4321, DataHash: sliceRepeat(sha256.Size, byte(0x42))},
+	}
+	t.Run("packet digest is parsed successfully", func(t *testing.T) {
+		p, err := NewPacketDigest(*mockSenderIdentity, mockEntries, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Signature = createMockSignature()
+		reader := bytes.NewReader(p.ToBytes())
+		if reader.Len() != int(p.Size) {
+			t.Errorf("expecting %d, got %d", p.Size, reader.Len())
+		}
+		_, err = reader.Seek(int64(PacketHeaderSize), io.SeekStart)
+		if err != nil {
+			t.Error(err)
+		}
+
+		var digest PacketDigest
+		err = digest.Parse(&p.PacketHeader, reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(digest.Entries) != len(mockEntries) {
+			t.Fatalf("Entries attribute incorrect: expected %d entries, received %d", len(mockEntries), len(digest.Entries))
+		}
+		for i, entry := range digest.Entries {
+			if entry.DataType != mockEntries[i].DataType || !bytes.Equal(entry.DataHash, mockEntries[i].DataHash) {
+				t.Errorf("entry %d incorrect: expected %v, received %v", i, mockEntries[i], entry)
+			}
+		}
+		if !bytes.Equal(digest.Signature, p.Signature) {
+			t.Errorf("Signature attribute incorrect: expected %v, received %v", p.Signature, digest.Signature)
+		}
+	})
+	t.Run("empty packet digest request is parsed successfully", func(t *testing.T) {
+		p, err := NewPacketDigestRequest(*mockSenderIdentity, nil, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Signature = createMockSignature()
+		var request PacketDigestRequest
+		err = request.Parse(&p.PacketHeader, bytes.NewReader(p.ToBytes()[PacketHeaderSize:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(request.Entries) != 0 {
+			t.Errorf("expected no entries, received %d", len(request.Entries))
+		}
+	})
+	t.Run("truncated entry fails", func(t *testing.T) {
+		b := append(sliceRepeat(DigestEntrySize-1, byte(0x01)), createMockSignature()...)
+		var digest PacketDigest
+		err := digest.Parse(&PacketHeader{}, bytes.NewReader(b))
+		if err == nil {
+			t.Error("expected an error for a truncated digest entry")
+		}
+	})
+}
+
+func TestParsePacketIHave(t *testing.T) {
+	t.Parallel()
+	t.Run("packet ihave is parsed successfully", func(t *testing.T) {
+		temp := sha256.Sum256(nil)
+		mockEntries := []DigestEntry{{DataType: 0x1234, DataHash: sliceRepeat(sha256.Size, byte(0x24))}}
+		p, err := NewPacketIHave(Identity(temp[:]), mockEntries, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Signature = createMockSignature()
+
+		var iHave PacketIHave
+		err = iHave.Parse(&p.PacketHeader, bytes.NewReader(p.ToBytes()[PacketHeaderSize:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if iHave.Type != MessageTypeGossipIHave {
+			t.Errorf("Type attribute incorrect: expected 0x0072, received %x", iHave.Type)
+		}
+		if len(iHave.Entries) != 1 || iHave.Entries[0].DataType != 0x1234 || !bytes.Equal(iHave.Entries[0].DataHash, mockEntries[0].DataHash) {
+			t.Errorf("Entries attribute incorrect: expected %v, received %v", mockEntries, iHave.Entries)
+		}
+	})
+}
+
+func TestParsePacketMessageBatch(t *testing.T) {
+	t.Parallel()
+	temp := sha256.Sum256(nil)
+	t.Run("packet message batch is parsed successfully", func(t *testing.T) {
+		mockMessages := []MessageBatchEntry{
+			{TTL: 5, DataType: 0x1234, Data: []byte("hello world!!")},
+			{TTL: 0, Flags: MessageFlagHighPriority, DataType: 0x4321, Data: nil},
+			{TTL: 1, DataType: 0x1234, Data: []byte("bye")},
+		}
+		p, err := NewPacketMessageBatch(Identity(temp[:]), mockMessages, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Signature = createMockSignature()
+		b := p.ToBytes()
+		if len(b) != int(p.Size) {
+			t.Errorf("expecting %d, got %d", p.Size, len(b))
+		}
+
+		var batch PacketMessageBatch
+		err = batch.Parse(&p.PacketHeader, bytes.NewReader(b[PacketHeaderSize:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(batch.Messages) != len(mockMessages) {
+			t.Fatalf("Messages attribute incorrect: expected %d messages, received %d", len(mockMessages), len(batch.Messages))
+		}
+		for i, msg := range batch.Messages {
+			if msg.TTL != mockMessages[i].TTL || msg.Flags != mockMessages[i].Flags || msg.DataType != mockMessages[i].DataType || !bytes.Equal(msg.Data, mockMessages[i].Data) {
+				t.Errorf("message %d incorrect: expected %v, received %v", i, mockMessages[i], msg)
+			}
+		}
+		if !bytes.Equal(batch.Signature, p.Signature) {
+			t.Errorf("Signature attribute incorrect: expected %v, received %v", p.Signature, batch.Signature)
+		}
+	})
+	t.Run("data size exceeding the packet fails", func(t *testing.T) {
+		entry := MessageBatchEntry{TTL: 5, DataType: 1, Data: []byte("hello")}
+		b := entry.ToBytes()
+		// claim more data than contained within the packet
+		b[5] = 0xff
+		var batch PacketMessageBatch
+		err := batch.Parse(&PacketHeader{}, bytes.NewReader(append(b, createMockSignature()...)))
+		if err == nil {
+			t.Error("expected an error for a truncated message")
+		}
+	})
+	t.Run("empty batch fails", func(t *testing.T) {
+		var batch PacketMessageBatch
+		err := batch.Parse(&PacketHeader{}, bytes.NewReader(createMockSignature()))
+		if err == nil {
+			t.Error("expected an error for a batch without messages")
+		}
+		if _, err := NewPacketMessageBatch(Identity(temp[:]), nil, time.Now()); err != ErrCreatePacketInvalidComponentSize {
+			t.Errorf("expected ErrCreatePacketInvalidComponentSize, received %v", err)
+		}
+	})
+}
+
+func TestParsePacketLeave(t *testing.T) {
+	t.Parallel()
+	t.Run("packet leave is parsed successfully", func(t *testing.T) {
+		temp := sha256.Sum256(nil)
+		p, err := NewPacketLeave(Identity(temp[:]), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Signature = createMockSignature()
+		b := p.ToBytes()
+
+		var leave PacketLeave
+		err = leave.Parse(&p.PacketHeader, bytes.NewReader(b[PacketHeaderSize:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if leave.Type != MessageTypeGossipLeave || leave.SenderIdentity != p.SenderIdentity {
+			t.Errorf("header incorrect: expected %v, received %v", p.PacketHeader, leave.PacketHeader)
+		}
+		if !bytes.Equal(leave.Signature, p.Signature) {
+			t.Errorf("Signature attribute incorrect: expected %v, received %v", p.Signature, leave.Signature)
+		}
+	})
+}
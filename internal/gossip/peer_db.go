@@ -0,0 +1,288 @@
+package gossip
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// peerDBHalfLife represents the time after which the reputation of a peer halves without new responses or failures.
+	peerDBHalfLife = 24 * time.Hour
+	// peerDBMaxAddresses represents the number of most recent addresses kept per peer.
+	peerDBMaxAddresses = 4
+)
+
+// PeerRecord represents a peer that responded to us at least once, as kept within the peer database.
+type PeerRecord struct {
+	Identity Identity
+	// Addresses lists the addresses the peer responded at, most recent first
+	Addresses []string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Reputation grows with every round the peer responded in and shrinks with every round it failed to, decaying towards 0
+	// with a half-life of peerDBHalfLife
+	Reputation float64
+	// DecayedAt is the time the reputation was last decayed to
+	DecayedAt time.Time
+}
+
+// peerRecordJSON represents the persisted form of a PeerRecord, with the identity hex-encoded.
+type peerRecordJSON struct {
+	Identity   string    `json:"identity"`
+	Addresses  []string  `json:"addresses"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	Reputation float64   `json:"reputation"`
+	DecayedAt  time.Time `json:"decayed_at"`
+}
+
+// MarshalJSON encodes the record with a hex-encoded identity, the format of the peer database file.
+func (r PeerRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(peerRecordJSON{
+		Identity:   r.Identity.String(),
+		Addresses:  r.Addresses,
+		FirstSeen:  r.FirstSeen,
+		LastSeen:   r.LastSeen,
+		Reputation: r.Reputation,
+		DecayedAt:  r.DecayedAt,
+	})
+}
+
+// UnmarshalJSON decodes a record written by MarshalJSON.
+func (r *PeerRecord) UnmarshalJSON(data []byte) error {
+	var record peerRecordJSON
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	identityBytes, err := hex.DecodeString(record.Identity)
+	if err != nil {
+		return fmt.Errorf("invalid identity %s: %w", record.Identity, err)
+	}
+	identity, err := NewIdentity(identityBytes)
+	if err != nil {
+		return fmt.Errorf("invalid identity %s: %w", record.Identity, err)
+	}
+	*r = PeerRecord{
+		Identity:   *identity,
+		Addresses:  record.Addresses,
+		FirstSeen:  record.FirstSeen,
+		LastSeen:   record.LastSeen,
+		Reputation: record.Reputation,
+		DecayedAt:  record.DecayedAt,
+	}
+	return nil
+}
+
+// ReputationAt returns the reputation of the record decayed to the given time.
+func (r PeerRecord) ReputationAt(now time.Time) float64 {
+	elapsed := now.Sub(r.DecayedAt)
+	if elapsed <= 0 {
+		return r.Reputation
+	}
+	return r.Reputation * math.Exp2(-float64(elapsed)/float64(peerDBHalfLife))
+}
+
+// peerDB remembers every peer that ever responded to us independently of the views, which only hold a small and changing
+// sample of the network. It outlives restarts, seeding the main view of a restarted node with peers known to have been
+// reachable, and serves as an inventory of the peers of a fleet. Peers that were not seen for the expiry are forgotten.
+// It is safe for concurrent use.
+type peerDB struct {
+	expiry time.Duration
+	peers  map[Identity]*PeerRecord
+	mu     sync.Mutex
+}
+
+// newPeerDB returns an empty database forgetting peers that were not seen for the expiry.
+func newPeerDB(expiry time.Duration) *peerDB {
+	return &peerDB{expiry: expiry, peers: make(map[Identity]*PeerRecord)}
+}
+
+// Responded records that the node responded at its address within a round.
+func (db *peerDB) Responded(node Node, now time.Time) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	record, ok := db.peers[node.Identity]
+	if !ok {
+		record = &PeerRecord{Identity: node.Identity, FirstSeen: now, DecayedAt: now}
+		db.peers[node.Identity] = record
+	}
+	record.Reputation = record.ReputationAt(now) + 1
+	record.DecayedAt = now
+	record.LastSeen = now
+	if node.Address != "" {
+		addresses := []string{node.Address}
+		for _, address := range record.Addresses {
+			if address != node.Address && len(addresses) < peerDBMaxAddresses {
+				addresses = append(addresses, address)
+			}
+		}
+		record.Addresses = addresses
+	}
+}
+
+// Failed records that a known peer failed to respond within a round, peers that never responded are not recorded.
+func (db *peerDB) Failed(identity Identity, now time.Time) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if record, ok := db.peers[identity]; ok {
+		record.Reputation = record.ReputationAt(now) - 1
+		record.DecayedAt = now
+	}
+}
+
+// Expire forgets the peers that were not seen for the expiry and returns their number.
+func (db *peerDB) Expire(now time.Time) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	expired := 0
+	for identity, record := range db.peers {
+		if now.Sub(record.LastSeen) > db.expiry {
+			delete(db.peers, identity)
+			expired++
+		}
+	}
+	return expired
+}
+
+// Snapshot returns the records of all peers with their reputation decayed to the given time, the most reputable first.
+func (db *peerDB) Snapshot(now time.Time) []PeerRecord {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	records := make([]PeerRecord, 0, len(db.peers))
+	for _, record := range db.peers {
+		decayed := *record
+		decayed.Addresses = append([]string(nil), record.Addresses...)
+		decayed.Reputation, decayed.DecayedAt = record.ReputationAt(now), now
+		records = append(records, decayed)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Reputation != records[j].Reputation {
+			return records[i].Reputation > records[j].Reputation
+		}
+		return records[i].Identity.Compare(records[j].Identity) < 0
+	})
+	return records
+}
+
+// SeedNodes returns the peers whose reputation is positive at their most recent address, the most reputable first.
+func (db *peerDB) SeedNodes(now time.Time) []Node {
+	var nodes []Node
+	for _, record := range db.Snapshot(now) {
+		if record.Reputation <= 0 {
+			break
+		}
+		if len(record.Addresses) == 0 || now.Sub(record.LastSeen) > db.expiry {
+			continue
+		}
+		nodes = append(nodes, Node{Identity: record.Identity, Address: record.Addresses[0], Source: SourcePeerDB})
+	}
+	return nodes
+}
+
+// SeedView fills the free slots of the view with the most reputable peers, without evicting any of its nodes, and returns
+// the added nodes.
+func (db *peerDB) SeedView(view *View, now time.Time) []Node {
+	var added []Node
+	for _, node := range db.SeedNodes(now) {
+		if view.maxSize > 0 && view.NodeCount() >= view.maxSize {
+			break
+		}
+		if view.isExcluded(node.Identity) || view.Contains(node.Identity) {
+			continue
+		}
+		view.Append(node)
+		added = append(added, node)
+	}
+	return added
+}
+
+// Len returns the number of peers within the database.
+func (db *peerDB) Len() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return len(db.peers)
+}
+
+// Save writes the records of all peers to the file at path.
+func (db *peerDB) Save(path string, now time.Time) error {
+	recordBytes, err := json.MarshalIndent(db.Snapshot(now), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, recordBytes); err != nil {
+		return fmt.Errorf("could not write peer database file: %w", err)
+	}
+	return nil
+}
+
+// Load restores the records of the file at path, which was written by Save, replacing the records of the database.
+func (db *peerDB) Load(path string) error {
+	records, err := ReadPeerDB(path)
+	if err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.peers = make(map[Identity]*PeerRecord, len(records))
+	for i := range records {
+		db.peers[records[i].Identity] = &records[i]
+	}
+	return nil
+}
+
+// ReadPeerDB reads the records of a peer database file, e.g. to export the inventory of the peers known to a node.
+func ReadPeerDB(path string) ([]PeerRecord, error) {
+	recordBytes, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("could not read peer database file: %w", err)
+	}
+	var records []PeerRecord
+	err = json.Unmarshal(recordBytes, &records)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse peer database file %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// recordPeers records the peers that responded within the round and the known peers that failed to in the peer database,
+// if configured, and forgets the expired peers.
+func (g *Gossip) recordPeers(responses map[Identity]bool, pingedNodes []*Node, pulledNodes []*Node, pushedNodes []Node) {
+	if g.peerDB == nil {
+		return
+	}
+	now := g.clock.Now()
+	// the addresses we reached peers at take precedence over the addresses they pushed
+	addresses := make(map[Identity]string, len(responses))
+	for _, node := range pushedNodes {
+		addresses[node.Identity] = node.Address
+	}
+	for _, node := range pingedNodes {
+		addresses[node.Identity] = node.Address
+	}
+	for _, node := range pulledNodes {
+		addresses[node.Identity] = node.Address
+	}
+	for identity, responded := range responses {
+		if responded {
+			g.peerDB.Responded(Node{Identity: identity, Address: addresses[identity]}, now)
+		} else {
+			g.peerDB.Failed(identity, now)
+		}
+	}
+	if expired := g.peerDB.Expire(now); expired > 0 {
+		g.logger().Debug("Forgot expired peers", zap.Int("peers", expired))
+	}
+	setGauge(metricPeerDBPeers, g.peerDB.Len())
+}
@@ -0,0 +1,101 @@
+package gossip
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPeerDB(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1000, 0)
+	t.Run("responses and failures change the reputation", func(t *testing.T) {
+		t.Parallel()
+		db := newPeerDB(time.Hour)
+		db.Responded(Node{Identity: testIdentity("peer1"), Address: "127.0.0.1:7001"}, start)
+		db.Responded(Node{Identity: testIdentity("peer1"), Address: "127.0.0.1:7001"}, start)
+		db.Failed(testIdentity("peer1"), start)
+		db.Failed(testIdentity("peer2"), start)
+		records := db.Snapshot(start)
+		if len(records) != 1 || records[0].Reputation != 1 {
+			t.Fatalf("expected only peer1 with a reputation of 1, received %v", records)
+		}
+	})
+	t.Run("reputations decay with the half-life", func(t *testing.T) {
+		t.Parallel()
+		db := newPeerDB(7 * peerDBHalfLife)
+		db.Responded(Node{Identity: testIdentity("peer1"), Address: "127.0.0.1:7001"}, start)
+		db.Responded(Node{Identity: testIdentity("peer1"), Address: "127.0.0.1:7001"}, start)
+		if reputation := db.Snapshot(start.Add(peerDBHalfLife))[0].Reputation; reputation != 1 {
+			t.Errorf("expected the reputation of 2 to halve after the half-life, received %f", reputation)
+		}
+	})
+	t.Run("recent addresses come first", func(t *testing.T) {
+		t.Parallel()
+		db := newPeerDB(time.Hour)
+		for i, address := range []string{"127.0.0.1:7001", "127.0.0.1:7002", "127.0.0.1:7003", "127.0.0.1:7004", "127.0.0.1:7001", "127.0.0.1:7005"} {
+			db.Responded(Node{Identity: testIdentity("peer1"), Address: address}, start.Add(time.Duration(i)*time.Second))
+		}
+		addresses := db.Snapshot(start)[0].Addresses
+		expected := []string{"127.0.0.1:7005", "127.0.0.1:7001", "127.0.0.1:7004", "127.0.0.1:7003"}
+		if len(addresses) != len(expected) {
+			t.Fatalf("expected addresses %v, received %v", expected, addresses)
+		}
+		for i := range expected {
+			if addresses[i] != expected[i] {
+				t.Fatalf("expected addresses %v, received %v", expected, addresses)
+			}
+		}
+	})
+	t.Run("peers not seen for the expiry are forgotten", func(t *testing.T) {
+		t.Parallel()
+		db := newPeerDB(time.Hour)
+		db.Responded(Node{Identity: testIdentity("peer1"), Address: "127.0.0.1:7001"}, start)
+		db.Responded(Node{Identity: testIdentity("peer2"), Address: "127.0.0.1:7002"}, start.Add(time.Minute))
+		if expired := db.Expire(start.Add(time.Hour + time.Second)); expired != 1 || db.Len() != 1 {
+			t.Errorf("expected peer1 to expire, received %d expired and %d remaining peers", expired, db.Len())
+		}
+	})
+	t.Run("views are seeded with the most reputable peers", func(t *testing.T) {
+		t.Parallel()
+		db := newPeerDB(time.Hour)
+		for i, name := range []string{"peer1", "peer2", "peer3", "peer4", "own"} {
+			for j := 0; j <= i; j++ {
+				db.Responded(Node{Identity: testIdentity(name), Address: "127.0.0.1:7001"}, start)
+			}
+		}
+		db.Responded(Node{Identity: testIdentity("peer5"), Address: "127.0.0.1:7005"}, start)
+		db.Failed(testIdentity("peer5"), start)
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("peer4"), Address: "127.0.0.1:7004"}, {Identity: testIdentity("bootstrap")}}),
+			WithMaxSize(4), WithExcludedIdentity(testIdentity("own")))
+		added := db.SeedView(view, start)
+		if len(added) != 2 || added[0].Identity != testIdentity("peer3") || added[1].Identity != testIdentity("peer2") {
+			t.Fatalf("expected peer3 and peer2 to be added, received %v", added)
+		}
+		if !view.Contains(testIdentity("bootstrap")) || view.NodeCount() != 4 {
+			t.Errorf("expected the bootstrap nodes to be kept, received %v", view.GetAll())
+		}
+	})
+	t.Run("peers are restored from the file", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "peers.json")
+		db := newPeerDB(time.Hour)
+		db.Responded(Node{Identity: testIdentity("peer1"), Address: "127.0.0.1:7001"}, start)
+		db.Responded(Node{Identity: testIdentity("peer2"), Address: "127.0.0.1:7002"}, start)
+		db.Responded(Node{Identity: testIdentity("peer2"), Address: "127.0.0.1:7002"}, start)
+		if err := db.Save(path, start); err != nil {
+			t.Fatal(err)
+		}
+		restored := newPeerDB(time.Hour)
+		if err := restored.Load(path); err != nil {
+			t.Fatal(err)
+		}
+		records := restored.Snapshot(start)
+		if len(records) != 2 || records[0].Identity != testIdentity("peer2") || records[0].Reputation != 2 || records[0].Addresses[0] != "127.0.0.1:7002" {
+			t.Fatalf("expected peer2 and peer1 to be restored, received %v", records)
+		}
+		if !records[1].FirstSeen.Equal(start) || !records[1].LastSeen.Equal(start) {
+			t.Errorf("expected peer1 to be first and last seen at %s, received %v", start, records[1])
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package gossip
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// peerStatsRetention represents how long the statistics of a peer are kept after its last activity.
+const peerStatsRetention = 10 * time.Minute
+
+// PacketCounts represents the number of packets of a single type exchanged with a peer.
+type PacketCounts struct {
+	Received int `json:"received"`
+	Sent     int `json:"sent"`
+}
+
+// PeerStats represents the protocol activity of a single peer: the authenticated packets received from it and the packets sent
+// to it per packet type, their total size, the time of the last activity, and the failures attributed to the peer.
+type PeerStats struct {
+	Identity Identity                     `json:"-"`
+	Packets  map[MessageType]PacketCounts `json:"packets"`
+	// BytesReceived and BytesSent count the bytes of the packets as they were transmitted, i.e. signed and encrypted
+	BytesReceived uint64 `json:"bytes_received"`
+	BytesSent     uint64 `json:"bytes_sent"`
+	// LastActivity is the time a packet of the peer was last received, sent or failed to be sent to it, or found invalid
+	LastActivity time.Time `json:"last_activity"`
+	// SendFailures counts the packets to the peer that could not be signed, encrypted, resolved, or written
+	SendFailures int `json:"send_failures"`
+	// InvalidPackets counts the authenticated packets of the peer whose content could not be handled
+	InvalidPackets int `json:"invalid_packets"`
+}
+
+// peerStatsTable records the protocol activity per peer, answering which peer is doing what on a live node.
+// It is safe for concurrent use.
+type peerStatsTable struct {
+	mu    sync.Mutex
+	peers map[Identity]*PeerStats
+}
+
+// newPeerStatsTable returns a table without recorded activity.
+func newPeerStatsTable() *peerStatsTable {
+	return &peerStatsTable{peers: make(map[Identity]*PeerStats)}
+}
+
+// Received records an authenticated packet of the given type and transmitted size received from the peer at the given time.
+func (pt *peerStatsTable) Received(identity Identity, packetType MessageType, size int, now time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	peer := pt.peer(identity)
+	counts := peer.Packets[packetType]
+	counts.Received++
+	peer.Packets[packetType] = counts
+	peer.BytesReceived += uint64(size)
+	peer.LastActivity = now
+}
+
+// Sent records a packet of the given type and transmitted size sent to the peer at the given time.
+func (pt *peerStatsTable) Sent(identity Identity, packetType MessageType, size int, now time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	peer := pt.peer(identity)
+	counts := peer.Packets[packetType]
+	counts.Sent++
+	peer.Packets[packetType] = counts
+	peer.BytesSent += uint64(size)
+	peer.LastActivity = now
+}
+
+// SendFailed records a packet that could not be sent to the peer at the given time.
+func (pt *peerStatsTable) SendFailed(identity Identity, now time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	peer := pt.peer(identity)
+	peer.SendFailures++
+	peer.LastActivity = now
+}
+
+// Invalid records an authenticated packet of the peer whose content could not be handled at the given time.
+func (pt *peerStatsTable) Invalid(identity Identity, now time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	peer := pt.peer(identity)
+	peer.InvalidPackets++
+	peer.LastActivity = now
+}
+
+// Prune forgets the peers without activity since the retention period.
+func (pt *peerStatsTable) Prune(now time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	for identity, peer := range pt.peers {
+		if now.Sub(peer.LastActivity) > peerStatsRetention {
+			delete(pt.peers, identity)
+		}
+	}
+}
+
+// Snapshot returns a copy of the statistics of all peers, ordered by identity.
+func (pt *peerStatsTable) Snapshot() []PeerStats {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	stats := make([]PeerStats, 0, len(pt.peers))
+	for _, peer := range pt.peers {
+		snapshot := *peer
+		snapshot.Packets = make(map[MessageType]PacketCounts, len(peer.Packets))
+		for packetType, counts := range peer.Packets {
+			snapshot.Packets[packetType] = counts
+		}
+		stats = append(stats, snapshot)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Identity.Compare(stats[j].Identity) < 0 })
+	return stats
+}
+
+// peer returns the statistics of a peer, creating them if necessary. The caller must hold the mutex.
+func (pt *peerStatsTable) peer(identity Identity) *PeerStats {
+	peer, ok := pt.peers[identity]
+	if !ok {
+		peer = &PeerStats{Identity: identity, Packets: make(map[MessageType]PacketCounts)}
+		pt.peers[identity] = peer
+	}
+	return peer
+}
@@ -0,0 +1,73 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerStatsTable(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1000, 0)
+	t.Run("packets are counted per peer and type", func(t *testing.T) {
+		t.Parallel()
+		pt := newPeerStatsTable()
+		pt.Received(testIdentity("peer1"), MessageTypeGossipPing, 600, start)
+		pt.Received(testIdentity("peer1"), MessageTypeGossipPing, 600, start)
+		pt.Sent(testIdentity("peer1"), MessageTypeGossipPong, 650, start.Add(time.Second))
+		pt.SendFailed(testIdentity("peer1"), start.Add(time.Second))
+		pt.Invalid(testIdentity("peer2"), start)
+		stats := pt.Snapshot()
+		if len(stats) != 2 || stats[0].Identity != testIdentity("peer1") || stats[1].Identity != testIdentity("peer2") {
+			t.Fatalf("expected the stats of peer1 and peer2, received %v", stats)
+		}
+		peer := stats[0]
+		if peer.Packets[MessageTypeGossipPing] != (PacketCounts{Received: 2}) || peer.Packets[MessageTypeGossipPong] != (PacketCounts{Sent: 1}) {
+			t.Errorf("unexpected packet counts %v", peer.Packets)
+		}
+		if peer.BytesReceived != 1200 || peer.BytesSent != 650 || peer.SendFailures != 1 {
+			t.Errorf("unexpected stats %+v", peer)
+		}
+		if !peer.LastActivity.Equal(start.Add(time.Second)) {
+			t.Errorf("expected the last activity at the time of the pong, received %s", peer.LastActivity)
+		}
+		if stats[1].InvalidPackets != 1 {
+			t.Errorf("expected an invalid packet of peer2, received %+v", stats[1])
+		}
+	})
+	t.Run("snapshots are not changed by later activity", func(t *testing.T) {
+		t.Parallel()
+		pt := newPeerStatsTable()
+		pt.Received(testIdentity("peer1"), MessageTypeGossipPing, 600, start)
+		stats := pt.Snapshot()
+		pt.Received(testIdentity("peer1"), MessageTypeGossipPing, 600, start)
+		if stats[0].Packets[MessageTypeGossipPing].Received != 1 {
+			t.Errorf("snapshot changed by later activity: %v", stats[0].Packets)
+		}
+	})
+	t.Run("inactive peers are pruned", func(t *testing.T) {
+		t.Parallel()
+		pt := newPeerStatsTable()
+		pt.Received(testIdentity("peer1"), MessageTypeGossipPing, 600, start)
+		pt.Received(testIdentity("peer2"), MessageTypeGossipPing, 600, start.Add(peerStatsRetention))
+		pt.Prune(start.Add(peerStatsRetention + time.Second))
+		if stats := pt.Snapshot(); len(stats) != 1 || stats[0].Identity != testIdentity("peer2") {
+			t.Errorf("expected only the stats of peer2, received %v", stats)
+		}
+	})
+	t.Run("failures keep misbehaving peers from being pruned", func(t *testing.T) {
+		t.Parallel()
+		pt := newPeerStatsTable()
+		pt.Received(testIdentity("peer1"), MessageTypeGossipPing, 600, start)
+		pt.Received(testIdentity("peer2"), MessageTypeGossipPing, 600, start)
+		pt.SendFailed(testIdentity("peer1"), start.Add(peerStatsRetention))
+		pt.Invalid(testIdentity("peer2"), start.Add(peerStatsRetention))
+		pt.Prune(start.Add(peerStatsRetention + time.Second))
+		stats := pt.Snapshot()
+		if len(stats) != 2 || stats[0].SendFailures != 1 || stats[1].InvalidPackets != 1 {
+			t.Fatalf("expected the stats of peer1 and peer2 to be kept, received %v", stats)
+		}
+		if !stats[0].LastActivity.Equal(start.Add(peerStatsRetention)) {
+			t.Errorf("expected the last activity at the time of the send failure, received %s", stats[0].LastActivity)
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package gossip
+
+// loadPinnedNodes parses the nodes that are always kept in the main view and offered in pull responses, given in the form of
+// the bootstrap nodes. Our own node is left out, so the same list can be configured on all nodes of a deployment.
+func loadPinnedNodes(nodesStr string, ownIdentity Identity) ([]Node, error) {
+	nodes, err := parseBootstrapNodesStr(nodesStr)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Identity == ownIdentity {
+			continue
+		}
+		node.Source = SourcePinned
+		pinned = append(pinned, node)
+	}
+	return pinned, nil
+}
+
+// isPinned checks whether the node with the given identity is pinned.
+func (s *Server) isPinned(identity Identity) bool {
+	for _, node := range s.pinnedNodes {
+		if node.Identity == identity {
+			return true
+		}
+	}
+	return false
+}
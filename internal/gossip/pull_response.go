@@ -0,0 +1,92 @@
+package gossip
+
+import (
+	"gossiphers/internal/config"
+	"sort"
+)
+
+// pullResponseNodesFor returns the nodes a pull request of the requester is answered with: the pinned nodes, and a selection of
+// the nodes of the last UpdatePullResponseNodes call by the configured strategy after leaving out the configured exclusions.
+// Answering with fewer nodes saves bandwidth and reveals less of our view to a single peer, at the cost of slower mixing.
+func (s *Server) pullResponseNodesFor(requester Identity) ([]Node, error) {
+	pinned := make([]Node, 0, len(s.pinnedNodes))
+	for _, node := range s.pinnedNodes {
+		if node.Identity != requester {
+			pinned = append(pinned, node)
+		}
+	}
+	s.mutexPullResponseNodes.RLock()
+	nodes := make([]Node, 0, len(s.pullResponseNodes))
+	for _, node := range s.pullResponseNodes {
+		if s.isPinned(node.Identity) {
+			// offer the metadata of the main view instead of the configured node
+			for i := range pinned {
+				if pinned[i].Identity == node.Identity {
+					pinned[i] = node
+				}
+			}
+			continue
+		}
+		if s.excludedFromPullResponse(node, requester) {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	s.mutexPullResponseNodes.RUnlock()
+
+	size := s.cfg.PullResponseSize - len(pinned)
+	if s.cfg.PullResponseSize == 0 || size > len(nodes) {
+		size = len(nodes)
+	} else if size < 0 {
+		size = 0
+	}
+	selected, err := s.selectPullResponseNodes(nodes, size)
+	if err != nil {
+		return nil, err
+	}
+	selected = append(pinned, selected...)
+	// requesters only take capabilities from the pushes of the nodes themselves
+	for i := range selected {
+		selected[i].Capabilities = 0
+	}
+	return selected, nil
+}
+
+// selectPullResponseNodes selects size of the nodes by the configured pull response strategy.
+func (s *Server) selectPullResponseNodes(nodes []Node, size int) ([]Node, error) {
+	switch s.cfg.PullResponseStrategy {
+	case config.PullResponseRandom:
+		subset, err := randSubset(s.random, nodes, size)
+		if err != nil {
+			return nil, err
+		}
+		selected := make([]Node, 0, len(subset))
+		for _, node := range subset {
+			selected = append(selected, *node)
+		}
+		return selected, nil
+	case config.PullResponseFreshest:
+		// nodes that never contacted us have a zero LastSeen and are therefore sorted last
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].LastSeen.After(nodes[j].LastSeen) })
+		return nodes[:size], nil
+	default:
+		return nodes, nil
+	}
+}
+
+// excludedFromPullResponse checks whether a node is left out of the pull response to the requester by the configured exclusions.
+func (s *Server) excludedFromPullResponse(node Node, requester Identity) bool {
+	for _, exclusion := range s.cfg.PullResponseExclude {
+		switch exclusion {
+		case config.PullResponseExcludeRequester:
+			if node.Identity == requester {
+				return true
+			}
+		case config.PullResponseExcludeUnverified:
+			if node.LastSeen.IsZero() {
+				return true
+			}
+		}
+	}
+	return false
+}
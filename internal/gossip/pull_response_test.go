@@ -0,0 +1,92 @@
+package gossip
+
+import (
+	"gossiphers/internal/config"
+	"testing"
+	"time"
+)
+
+// newPullResponseTestServer returns a server answering pull requests with the given nodes by the given strategy and exclusions.
+func newPullResponseTestServer(strategy string, size int, exclude []string, nodes []Node) *Server {
+	return &Server{
+		cfg:               &config.GossipConfig{PullResponseStrategy: strategy, PullResponseSize: size, PullResponseExclude: exclude},
+		pullResponseNodes: nodes,
+		random:            NewSeededRandom(1),
+	}
+}
+
+func TestServer_pullResponseNodesFor(t *testing.T) {
+	t.Parallel()
+	seen := time.Unix(1000, 0)
+	nodes := []Node{
+		{Identity: testIdentity("peer1"), Address: "127.0.0.1:7001"},
+		{Identity: testIdentity("peer2"), Address: "127.0.0.1:7002", LastSeen: seen},
+		{Identity: testIdentity("peer3"), Address: "127.0.0.1:7003", LastSeen: seen.Add(time.Second)},
+		{Identity: testIdentity("peer4"), Address: "127.0.0.1:7004"},
+	}
+	t.Run("all answers with every node", func(t *testing.T) {
+		t.Parallel()
+		s := newPullResponseTestServer(config.PullResponseAll, 2, nil, nodes)
+		selected, err := s.pullResponseNodesFor(testIdentity("peer1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(selected) != len(nodes) {
+			t.Errorf("expected all %d nodes, received %v", len(nodes), selected)
+		}
+	})
+	t.Run("random answers with a subset of the configured size", func(t *testing.T) {
+		t.Parallel()
+		s := newPullResponseTestServer(config.PullResponseRandom, 2, nil, nodes)
+		selected, err := s.pullResponseNodesFor(testIdentity("peer1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(selected) != 2 || selected[0].Identity == selected[1].Identity {
+			t.Errorf("expected 2 distinct nodes, received %v", selected)
+		}
+	})
+	t.Run("freshest answers with the nodes seen most recently", func(t *testing.T) {
+		t.Parallel()
+		s := newPullResponseTestServer(config.PullResponseFreshest, 2, nil, nodes)
+		selected, err := s.pullResponseNodesFor(testIdentity("peer1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(selected) != 2 || selected[0].Identity != testIdentity("peer3") || selected[1].Identity != testIdentity("peer2") {
+			t.Errorf("expected peer3 and peer2, received %v", selected)
+		}
+	})
+	t.Run("exclusions leave out the requester and unverified nodes", func(t *testing.T) {
+		t.Parallel()
+		s := newPullResponseTestServer(config.PullResponseAll, 0, []string{config.PullResponseExcludeRequester, config.PullResponseExcludeUnverified}, nodes)
+		selected, err := s.pullResponseNodesFor(testIdentity("peer2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(selected) != 1 || selected[0].Identity != testIdentity("peer3") {
+			t.Errorf("expected only peer3, received %v", selected)
+		}
+	})
+	t.Run("pinned nodes are offered in addition to the selection", func(t *testing.T) {
+		t.Parallel()
+		s := newPullResponseTestServer(config.PullResponseFreshest, 3, []string{config.PullResponseExcludeUnverified}, nodes)
+		s.pinnedNodes = []Node{{Identity: testIdentity("peer4"), Address: "127.0.0.1:7004"}, {Identity: testIdentity("hub"), Address: "127.0.0.1:7005"}}
+		selected, err := s.pullResponseNodesFor(testIdentity("peer2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(selected) != 3 || selected[0].Identity != testIdentity("peer4") || selected[1].Identity != testIdentity("hub") || selected[2].Identity != testIdentity("peer3") {
+			t.Errorf("expected peer4, hub, and peer3, received %v", selected)
+		}
+		selected, err = s.pullResponseNodesFor(testIdentity("hub"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, node := range selected {
+			if node.Identity == testIdentity("hub") {
+				t.Errorf("pinned node was offered to itself: %v", selected)
+			}
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package gossip
+
+import (
+	"sync"
+	"time"
+)
+
+// pullState represents the progress of a pull request we sent to a peer within the current round.
+type pullState int
+
+const (
+	// pullIdle means we did not send a pull request to the peer
+	pullIdle pullState = iota
+	// pullOutstanding means we sent a pull request and wait for the peer's response
+	pullOutstanding
+	// pullAnswered means the peer answered our pull request in time
+	pullAnswered
+)
+
+// String returns the name of a pull state.
+func (s pullState) String() string {
+	switch s {
+	case pullIdle:
+		return "idle"
+	case pullOutstanding:
+		return "outstanding"
+	case pullAnswered:
+		return "answered"
+	default:
+		return "unknown"
+	}
+}
+
+// pullProgress holds the state of the pull request to a single peer and the time it was entered.
+type pullProgress struct {
+	state pullState
+	since time.Time
+}
+
+// pullTracker tracks our outstanding pull requests. A pull response is only accepted from a peer we sent a pull request to
+// within the timeout, and only once per request, so late, repeated, and unsolicited responses cannot inject nodes into our
+// view. It is safe for concurrent use.
+type pullTracker struct {
+	// timeout is the time within which a peer must answer our pull request
+	timeout time.Duration
+	peers   map[Identity]pullProgress
+	mu      sync.Mutex
+}
+
+// newPullTracker returns a tracker without outstanding pulls, which accepts responses within the given time after the request.
+func newPullTracker(timeout time.Duration) *pullTracker {
+	return &pullTracker{timeout: timeout, peers: make(map[Identity]pullProgress)}
+}
+
+// Request records a pull request sent to the peer, restarting a pull that is already outstanding.
+func (pt *pullTracker) Request(identity Identity, now time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.peers[identity] = pullProgress{state: pullOutstanding, since: now}
+	metrics.Add(metricPullsRequested, 1)
+}
+
+// Respond records a pull response received from the peer and returns whether it answers an outstanding pull request.
+// Responses of peers we did not send a pull request to, repeated responses, and responses arriving after the timeout are refused.
+func (pt *pullTracker) Respond(identity Identity, now time.Time) bool {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	progress, ok := pt.peers[identity]
+	if !ok || progress.state != pullOutstanding {
+		metrics.Add(metricPullResponsesRefused, 1)
+		return false
+	}
+	if now.Sub(progress.since) > pt.timeout {
+		delete(pt.peers, identity)
+		metrics.Add(metricPullsTimedOut, 1)
+		return false
+	}
+	pt.peers[identity] = pullProgress{state: pullAnswered, since: now}
+	metrics.Add(metricPullResponsesAccepted, 1)
+	return true
+}
+
+// Reset forgets all pulls at the start of a round, counting the ones that were not answered as timed out.
+func (pt *pullTracker) Reset() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	for _, progress := range pt.peers {
+		if progress.state == pullOutstanding {
+			metrics.Add(metricPullsTimedOut, 1)
+		}
+	}
+	pt.peers = make(map[Identity]pullProgress)
+}
+
+// State returns the state of the pull request to the peer.
+func (pt *pullTracker) State(identity Identity) pullState {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.peers[identity].state
+}
+
+// States returns the state of all pull requests outstanding or answered within the current round.
+func (pt *pullTracker) States() map[Identity]pullState {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	states := make(map[Identity]pullState, len(pt.peers))
+	for identity, progress := range pt.peers {
+		states[identity] = progress.state
+	}
+	return states
+}
@@ -0,0 +1,61 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPullTracker(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1000, 0)
+	t.Run("pull requests are answered once", func(t *testing.T) {
+		t.Parallel()
+		pt := newPullTracker(time.Second)
+		pt.Request(testIdentity("peer1"), start)
+		if state := pt.State(testIdentity("peer1")); state != pullOutstanding {
+			t.Fatalf("expected the pull to be outstanding, received %s", state)
+		}
+		if !pt.Respond(testIdentity("peer1"), start.Add(100*time.Millisecond)) {
+			t.Fatal("response to the pull request was refused")
+		}
+		if state := pt.State(testIdentity("peer1")); state != pullAnswered {
+			t.Errorf("expected the pull to be answered, received %s", state)
+		}
+		if pt.Respond(testIdentity("peer1"), start.Add(200*time.Millisecond)) {
+			t.Error("repeated response was accepted")
+		}
+	})
+	t.Run("responses without pull request are refused", func(t *testing.T) {
+		t.Parallel()
+		pt := newPullTracker(time.Second)
+		if pt.Respond(testIdentity("peer1"), start) {
+			t.Error("unrequested response was accepted")
+		}
+		if state := pt.State(testIdentity("peer1")); state != pullIdle {
+			t.Errorf("expected no pull of the peer, received %s", state)
+		}
+	})
+	t.Run("responses after the timeout are refused", func(t *testing.T) {
+		t.Parallel()
+		pt := newPullTracker(time.Second)
+		pt.Request(testIdentity("peer1"), start)
+		if pt.Respond(testIdentity("peer1"), start.Add(2*time.Second)) {
+			t.Error("late response was accepted")
+		}
+		if state := pt.State(testIdentity("peer1")); state != pullIdle {
+			t.Errorf("expected the timed out pull to be forgotten, received %s", state)
+		}
+	})
+	t.Run("resets forget the pulls", func(t *testing.T) {
+		t.Parallel()
+		pt := newPullTracker(time.Second)
+		pt.Request(testIdentity("peer1"), start)
+		pt.Reset()
+		if len(pt.States()) != 0 {
+			t.Errorf("expected no pulls after the reset, received %v", pt.States())
+		}
+		if pt.Respond(testIdentity("peer1"), start) {
+			t.Error("response to a pull request of the previous round was accepted")
+		}
+	})
+}
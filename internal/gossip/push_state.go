@@ -0,0 +1,135 @@
+package gossip
+
+import (
+	"sync"
+	"time"
+)
+
+// pushState represents the progress of pushing our identity to a peer within the current round.
+type pushState int
+
+const (
+	// pushIdle means no push to the peer is in progress
+	pushIdle pushState = iota
+	// pushRequested means we sent a push request and wait for the peer's challenge
+	pushRequested
+	// pushChallenged means we received the peer's challenge and solve it
+	pushChallenged
+	// pushPushed means we sent the solved challenge along with our node
+	pushPushed
+)
+
+// String returns the name of a push state.
+func (s pushState) String() string {
+	switch s {
+	case pushIdle:
+		return "idle"
+	case pushRequested:
+		return "requested"
+	case pushChallenged:
+		return "challenged"
+	case pushPushed:
+		return "pushed"
+	default:
+		return "unknown"
+	}
+}
+
+// pushProgress holds the state of the push to a single peer and the time it was entered.
+type pushProgress struct {
+	state pushState
+	since time.Time
+}
+
+// pushTracker implements the state machine of our pushes to peers: requested → challenged → pushed.
+// A challenge is only accepted from a peer we requested to push to within the timeout, and only once per request,
+// so peers cannot make us solve challenges we did not ask for. It is safe for concurrent use.
+type pushTracker struct {
+	// timeout is the time within which a requested peer must answer with a challenge
+	timeout time.Duration
+	peers   map[Identity]pushProgress
+	mu      sync.Mutex
+}
+
+// newPushTracker returns a tracker without pushes in progress, which accepts challenges within the given time after the request.
+func newPushTracker(timeout time.Duration) *pushTracker {
+	return &pushTracker{timeout: timeout, peers: make(map[Identity]pushProgress)}
+}
+
+// Request records a push request sent to the peer, restarting a push that is already in progress.
+func (pt *pushTracker) Request(identity Identity, now time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.peers[identity] = pushProgress{state: pushRequested, since: now}
+	metrics.Add(metricPushesRequested, 1)
+}
+
+// Challenge records a challenge received from the peer and returns whether it answers an outstanding push request.
+// Challenges of peers we did not request, repeated challenges, and challenges arriving after the timeout are refused.
+func (pt *pushTracker) Challenge(identity Identity, now time.Time) bool {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	progress, ok := pt.peers[identity]
+	if !ok || progress.state != pushRequested {
+		metrics.Add(metricPushChallengesRefused, 1)
+		return false
+	}
+	if now.Sub(progress.since) > pt.timeout {
+		delete(pt.peers, identity)
+		metrics.Add(metricPushesExpired, 1)
+		return false
+	}
+	pt.peers[identity] = pushProgress{state: pushChallenged, since: now}
+	metrics.Add(metricPushChallengesAccepted, 1)
+	return true
+}
+
+// Pushed records that the solved challenge of the peer was sent, completing the push.
+func (pt *pushTracker) Pushed(identity Identity, now time.Time) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if progress, ok := pt.peers[identity]; ok && progress.state == pushChallenged {
+		pt.peers[identity] = pushProgress{state: pushPushed, since: now}
+		metrics.Add(metricPushesSent, 1)
+	}
+}
+
+// Abort gives up on the push to the peer, e.g. because its challenge could not be solved in time.
+func (pt *pushTracker) Abort(identity Identity) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	if _, ok := pt.peers[identity]; ok {
+		delete(pt.peers, identity)
+		metrics.Add(metricPushesAborted, 1)
+	}
+}
+
+// Reset forgets all pushes at the start of a round, counting the ones that did not complete as expired.
+func (pt *pushTracker) Reset() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	for _, progress := range pt.peers {
+		if progress.state != pushPushed {
+			metrics.Add(metricPushesExpired, 1)
+		}
+	}
+	pt.peers = make(map[Identity]pushProgress)
+}
+
+// State returns the state of the push to the peer.
+func (pt *pushTracker) State(identity Identity) pushState {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	return pt.peers[identity].state
+}
+
+// States returns the state of all pushes in progress or completed within the current round.
+func (pt *pushTracker) States() map[Identity]pushState {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	states := make(map[Identity]pushState, len(pt.peers))
+	for identity, progress := range pt.peers {
+		states[identity] = progress.state
+	}
+	return states
+}
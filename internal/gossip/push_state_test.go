@@ -0,0 +1,80 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPushTracker(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1000, 0)
+	t.Run("pushes run through requested, challenged, and pushed", func(t *testing.T) {
+		t.Parallel()
+		pt := newPushTracker(time.Second)
+		pt.Request(testIdentity("peer1"), start)
+		if state := pt.State(testIdentity("peer1")); state != pushRequested {
+			t.Fatalf("expected the push to be requested, received %s", state)
+		}
+		if !pt.Challenge(testIdentity("peer1"), start.Add(100*time.Millisecond)) {
+			t.Fatal("challenge answering the push request was refused")
+		}
+		if state := pt.State(testIdentity("peer1")); state != pushChallenged {
+			t.Fatalf("expected the push to be challenged, received %s", state)
+		}
+		pt.Pushed(testIdentity("peer1"), start.Add(200*time.Millisecond))
+		if state := pt.State(testIdentity("peer1")); state != pushPushed {
+			t.Errorf("expected the push to be pushed, received %s", state)
+		}
+	})
+	t.Run("challenges without push request are refused", func(t *testing.T) {
+		t.Parallel()
+		pt := newPushTracker(time.Second)
+		if pt.Challenge(testIdentity("peer1"), start) {
+			t.Error("unrequested challenge was accepted")
+		}
+		pt.Pushed(testIdentity("peer1"), start)
+		if state := pt.State(testIdentity("peer1")); state != pushIdle {
+			t.Errorf("expected no push to the peer, received %s", state)
+		}
+	})
+	t.Run("each push request accepts a single challenge", func(t *testing.T) {
+		t.Parallel()
+		pt := newPushTracker(time.Second)
+		pt.Request(testIdentity("peer1"), start)
+		if !pt.Challenge(testIdentity("peer1"), start) {
+			t.Fatal("challenge answering the push request was refused")
+		}
+		if pt.Challenge(testIdentity("peer1"), start) {
+			t.Error("repeated challenge was accepted")
+		}
+	})
+	t.Run("challenges after the timeout are refused", func(t *testing.T) {
+		t.Parallel()
+		pt := newPushTracker(time.Second)
+		pt.Request(testIdentity("peer1"), start)
+		if pt.Challenge(testIdentity("peer1"), start.Add(2*time.Second)) {
+			t.Error("late challenge was accepted")
+		}
+		if state := pt.State(testIdentity("peer1")); state != pushIdle {
+			t.Errorf("expected the expired push to be forgotten, received %s", state)
+		}
+	})
+	t.Run("aborted pushes and resets forget the pushes", func(t *testing.T) {
+		t.Parallel()
+		pt := newPushTracker(time.Second)
+		pt.Request(testIdentity("peer1"), start)
+		pt.Challenge(testIdentity("peer1"), start)
+		pt.Abort(testIdentity("peer1"))
+		if state := pt.State(testIdentity("peer1")); state != pushIdle {
+			t.Errorf("expected the aborted push to be forgotten, received %s", state)
+		}
+		pt.Request(testIdentity("peer2"), start)
+		pt.Reset()
+		if len(pt.States()) != 0 {
+			t.Errorf("expected no pushes after the reset, received %v", pt.States())
+		}
+		if pt.Challenge(testIdentity("peer2"), start) {
+			t.Error("challenge to a push request of the previous round was accepted")
+		}
+	})
+}
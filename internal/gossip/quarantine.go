@@ -0,0 +1,124 @@
+package gossip
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// QuarantinedPeer represents an address whose packets are dropped, along with the identity its latest failed packet claimed,
+// the end of the quarantine, and the failures that caused it.
+type QuarantinedPeer struct {
+	Address  string
+	Identity Identity
+	Until    time.Time
+	Failures int
+}
+
+// quarantineEntry holds the failures of a single address within the current window and the end of its quarantine, if any.
+type quarantineEntry struct {
+	identity    Identity
+	failures    int
+	lastFailure time.Time
+	until       time.Time
+}
+
+// quarantine drops the packets of addresses that repeatedly sent packets with invalid signatures or failed challenges for a
+// while, before the packets are decrypted or verified, so a misbehaving peer cannot keep the node busy with expensive
+// cryptographic operations. Failures further apart than the quarantine duration are not counted as repeated.
+// It is safe for concurrent use.
+type quarantine struct {
+	// threshold is the number of failures after which an address is quarantined, 0 disables the quarantine
+	threshold int
+	duration  time.Duration
+	entries   map[string]*quarantineEntry
+	mu        sync.Mutex
+}
+
+// newQuarantine returns a quarantine of the given duration for addresses failing the given number of times.
+func newQuarantine(threshold int, duration time.Duration) *quarantine {
+	return &quarantine{threshold: threshold, duration: duration, entries: make(map[string]*quarantineEntry)}
+}
+
+// Fail records a failure of a packet from the address claiming the identity and returns whether it quarantined the address.
+func (q *quarantine) Fail(address string, identity Identity, now time.Time) bool {
+	if q.threshold <= 0 {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[address]
+	if !ok || q.staleLocked(entry, now) {
+		// failures of sources spoofing many addresses must not accumulate
+		for other, otherEntry := range q.entries {
+			if q.staleLocked(otherEntry, now) {
+				delete(q.entries, other)
+			}
+		}
+		entry = &quarantineEntry{}
+		q.entries[address] = entry
+	}
+	entry.identity, entry.lastFailure = identity, now
+	entry.failures++
+	if entry.failures < q.threshold || now.Before(entry.until) {
+		return false
+	}
+	entry.until = now.Add(q.duration)
+	metrics.Add(metricPeersQuarantined, 1)
+	setGauge(metricQuarantinedPeers, q.activeLocked(now))
+	return true
+}
+
+// Quarantined checks whether the packets of the address are dropped, forgetting the address once its quarantine ended.
+func (q *quarantine) Quarantined(address string, now time.Time) bool {
+	if q.threshold <= 0 {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[address]
+	if !ok || entry.until.IsZero() {
+		return false
+	}
+	if now.Before(entry.until) {
+		return true
+	}
+	delete(q.entries, address)
+	setGauge(metricQuarantinedPeers, q.activeLocked(now))
+	return false
+}
+
+// Snapshot returns the quarantined addresses, ordered by the end of their quarantine.
+func (q *quarantine) Snapshot(now time.Time) []QuarantinedPeer {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	peers := make([]QuarantinedPeer, 0)
+	for address, entry := range q.entries {
+		if now.Before(entry.until) {
+			peers = append(peers, QuarantinedPeer{Address: address, Identity: entry.identity, Until: entry.until, Failures: entry.failures})
+		}
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if !peers[i].Until.Equal(peers[j].Until) {
+			return peers[i].Until.Before(peers[j].Until)
+		}
+		return peers[i].Address < peers[j].Address
+	})
+	return peers
+}
+
+// staleLocked checks whether an entry is neither quarantined nor failed recently, the caller must hold the mutex.
+func (q *quarantine) staleLocked(entry *quarantineEntry, now time.Time) bool {
+	return !now.Before(entry.until) && now.Sub(entry.lastFailure) > q.duration
+}
+
+// activeLocked returns the number of addresses currently quarantined, the caller must hold the mutex.
+func (q *quarantine) activeLocked(now time.Time) int {
+	active := 0
+	for _, entry := range q.entries {
+		if now.Before(entry.until) {
+			active++
+		}
+	}
+	return active
+}
@@ -0,0 +1,73 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuarantine(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1000, 0)
+	const address = "127.0.0.1:7001"
+	t.Run("addresses are quarantined after repeated failures", func(t *testing.T) {
+		t.Parallel()
+		q := newQuarantine(3, time.Minute)
+		for i := 0; i < 2; i++ {
+			if q.Fail(address, testIdentity("peer1"), start) {
+				t.Fatalf("address was quarantined after %d failures", i+1)
+			}
+			if q.Quarantined(address, start) {
+				t.Fatalf("packets were dropped after %d failures", i+1)
+			}
+		}
+		if !q.Fail(address, testIdentity("peer1"), start) {
+			t.Fatal("address was not quarantined after the third failure")
+		}
+		if !q.Quarantined(address, start.Add(time.Minute-time.Millisecond)) {
+			t.Error("packets were not dropped within the quarantine")
+		}
+		if q.Quarantined("127.0.0.1:7002", start) {
+			t.Error("packets of another address were dropped")
+		}
+	})
+	t.Run("quarantines end after their duration", func(t *testing.T) {
+		t.Parallel()
+		q := newQuarantine(1, time.Minute)
+		q.Fail(address, testIdentity("peer1"), start)
+		if q.Quarantined(address, start.Add(time.Minute)) {
+			t.Error("packets were dropped after the quarantine")
+		}
+		if !q.Fail(address, testIdentity("peer1"), start.Add(2*time.Minute)) {
+			t.Error("expected the failures to be counted afresh after the quarantine")
+		}
+	})
+	t.Run("failures further apart than the duration are not repeated", func(t *testing.T) {
+		t.Parallel()
+		q := newQuarantine(2, time.Minute)
+		q.Fail(address, testIdentity("peer1"), start)
+		if q.Fail(address, testIdentity("peer1"), start.Add(time.Minute+time.Millisecond)) {
+			t.Error("address was quarantined although its failures were too far apart")
+		}
+	})
+	t.Run("snapshots list the current quarantines", func(t *testing.T) {
+		t.Parallel()
+		q := newQuarantine(1, time.Minute)
+		q.Fail("127.0.0.1:7002", testIdentity("peer2"), start.Add(time.Second))
+		q.Fail(address, testIdentity("peer1"), start)
+		q.Fail("127.0.0.1:7003", testIdentity("peer3"), start.Add(-time.Minute))
+		peers := q.Snapshot(start.Add(time.Second))
+		if len(peers) != 2 || peers[0].Address != address || peers[1].Identity != testIdentity("peer2") {
+			t.Fatalf("expected the quarantines of peer1 and peer2 in this order, received %v", peers)
+		}
+		if peers[0].Failures != 1 || !peers[0].Until.Equal(start.Add(time.Minute)) {
+			t.Errorf("expected a single failure quarantining peer1 until %s, received %v", start.Add(time.Minute), peers[0])
+		}
+	})
+	t.Run("a threshold of 0 disables the quarantine", func(t *testing.T) {
+		t.Parallel()
+		q := newQuarantine(0, time.Minute)
+		if q.Fail(address, testIdentity("peer1"), start) || q.Quarantined(address, start) {
+			t.Error("address was quarantined although the quarantine is disabled")
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package gossip
+
+import (
+	"crypto/rand"
+	"io"
+	mathrand "math/rand"
+	"sync"
+)
+
+// randomOr returns the given source of randomness, or crypto/rand if it is nil.
+func randomOr(random io.Reader) io.Reader {
+	if random == nil {
+		return rand.Reader
+	}
+	return random
+}
+
+// seededRandom is a deterministic source of randomness which is safe for concurrent use.
+type seededRandom struct {
+	source *mathrand.Rand
+	mutex  sync.Mutex
+}
+
+// NewSeededRandom returns a deterministic source of randomness, which makes the peer selection, the sampler biases, and
+// the challenge keys reproducible in tests and simulations. It must never be used in production, as peers could predict
+// the choices of the node.
+func NewSeededRandom(seed int64) io.Reader {
+	return &seededRandom{source: mathrand.New(mathrand.NewSource(seed))}
+}
+
+// Read fills p with pseudo-random bytes.
+func (r *seededRandom) Read(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.source.Read(p)
+}
@@ -0,0 +1,61 @@
+package gossip
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// resolvedAddress represents a cached resolution of a node address.
+type resolvedAddress struct {
+	addr       *net.UDPAddr
+	resolvedAt time.Time
+}
+
+// addressResolver resolves node addresses, which may contain hostnames, into UDP addresses.
+// Resolutions are cached and refreshed once they are older than the refresh interval, so nodes behind dynamic DNS names can be referenced stably.
+type addressResolver struct {
+	refreshInterval time.Duration
+	lookup          func(address string) (*net.UDPAddr, error)
+
+	cache      map[string]resolvedAddress
+	mutexCache sync.Mutex
+}
+
+// newAddressResolver returns a new instance of addressResolver.
+func newAddressResolver(refreshInterval time.Duration) *addressResolver {
+	return &addressResolver{
+		refreshInterval: refreshInterval,
+		lookup: func(address string) (*net.UDPAddr, error) {
+			return net.ResolveUDPAddr("udp", address)
+		},
+		cache: make(map[string]resolvedAddress),
+	}
+}
+
+// Resolve returns the UDP address of the given <host>:<port> address, refreshing resolutions older than the refresh interval at now.
+// If a refresh fails, the previously resolved address is used until the name can be resolved again.
+func (r *addressResolver) Resolve(address string, now time.Time) (*net.UDPAddr, error) {
+	r.mutexCache.Lock()
+	cached, ok := r.cache[address]
+	r.mutexCache.Unlock()
+	if ok && now.Sub(cached.resolvedAt) < r.refreshInterval {
+		return cached.addr, nil
+	}
+
+	addr, err := r.lookup(address)
+	if err != nil {
+		if ok {
+			logger().Warn("Could not refresh address resolution, using previous result", peerAddr(address), zap.String("resolved", cached.addr.String()), zap.Error(err))
+			return cached.addr, nil
+		}
+		return nil, err
+	}
+
+	r.mutexCache.Lock()
+	r.cache[address] = resolvedAddress{addr: addr, resolvedAt: now}
+	r.mutexCache.Unlock()
+	return addr, nil
+}
@@ -0,0 +1,81 @@
+package gossip
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAddressResolver_Resolve(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1000, 0)
+	t.Run("caches resolutions until the refresh interval passed", func(t *testing.T) {
+		lookups := 0
+		resolver := newAddressResolver(time.Hour)
+		resolver.lookup = func(address string) (*net.UDPAddr, error) {
+			lookups++
+			return &net.UDPAddr{IP: net.IPv4(10, 0, 0, byte(lookups)), Port: 7002}, nil
+		}
+
+		first, err := resolver.Resolve("node.example.com:7002", start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := resolver.Resolve("node.example.com:7002", start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lookups != 1 || first.String() != second.String() {
+			t.Errorf("expected a single lookup, received %d lookups", lookups)
+		}
+
+		third, err := resolver.Resolve("node.example.com:7002", start.Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lookups != 2 || third.String() != "10.0.0.2:7002" {
+			t.Errorf("expected the address to be resolved again, received %s after %d lookups", third.String(), lookups)
+		}
+	})
+	t.Run("falls back to the previous resolution on failure", func(t *testing.T) {
+		resolver := newAddressResolver(0)
+		fail := false
+		resolver.lookup = func(address string) (*net.UDPAddr, error) {
+			if fail {
+				return nil, errors.New("lookup failed")
+			}
+			return &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 7002}, nil
+		}
+		_, err := resolver.Resolve("node.example.com:7002", start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fail = true
+		addr, err := resolver.Resolve("node.example.com:7002", start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.String() != "10.0.0.1:7002" {
+			t.Errorf("unexpected address: %s", addr.String())
+		}
+		_, err = resolver.Resolve("unknown.example.com:7002", start)
+		if err == nil {
+			t.Error("expecting error for an address that was never resolved")
+		}
+	})
+	t.Run("resolves ip addresses and hostnames", func(t *testing.T) {
+		resolver := newAddressResolver(time.Minute)
+		addr, err := resolver.Resolve("127.0.0.1:7002", start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.Port != 7002 || !addr.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+			t.Errorf("unexpected address: %s", addr.String())
+		}
+		_, err = resolver.Resolve("localhost:7002", start)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package gossip
+
+import (
+	"expvar"
+	"sync"
+)
+
+// roundMetricsHistory represents the number of most recent rounds whose counter changes are kept, which bounds the round IDs
+// published as keys of the round_metrics variable.
+const roundMetricsHistory = 16
+
+// RoundCounters represents the changes of the counters of the gossip subsystem within a single round, counters without change
+// are left out.
+type RoundCounters struct {
+	RoundID  int              `json:"round_id"`
+	Counters map[string]int64 `json:"counters"`
+}
+
+// roundMetrics attributes the changes of the counters of the gossip subsystem to the rounds they happened in, so the metrics of
+// a problematic round can be looked up by the round ID its log entries carry. Only the most recent rounds are kept.
+// It is safe for concurrent use.
+type roundMetrics struct {
+	mu sync.Mutex
+	// baseline holds the counters at the end of the previous round
+	baseline map[string]int64
+	rounds   []RoundCounters
+}
+
+// newRoundMetrics returns a tracker attributing the changes of the counters from now on.
+func newRoundMetrics() *roundMetrics {
+	return &roundMetrics{baseline: counterValues()}
+}
+
+// Record attributes the changes of the counters since the end of the previous round to the completed round.
+func (rm *roundMetrics) Record(roundID int) {
+	current := counterValues()
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	changes := make(map[string]int64)
+	for name, value := range current {
+		if delta := value - rm.baseline[name]; delta != 0 {
+			changes[name] = delta
+		}
+	}
+	rm.baseline = current
+	rm.rounds = append(rm.rounds, RoundCounters{RoundID: roundID, Counters: changes})
+	if len(rm.rounds) > roundMetricsHistory {
+		rm.rounds = append([]RoundCounters(nil), rm.rounds[len(rm.rounds)-roundMetricsHistory:]...)
+	}
+}
+
+// Snapshot returns the counter changes of the most recent rounds, oldest first.
+func (rm *roundMetrics) Snapshot() []RoundCounters {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return append([]RoundCounters(nil), rm.rounds...)
+}
+
+// counterValues returns the current values of the integer counters of the gossip subsystem.
+func counterValues() map[string]int64 {
+	values := make(map[string]int64)
+	metrics.Do(func(kv expvar.KeyValue) {
+		if counter, ok := kv.Value.(*expvar.Int); ok {
+			values[kv.Key] = counter.Value()
+		}
+	})
+	return values
+}
@@ -0,0 +1,29 @@
+package gossip
+
+import (
+	"testing"
+)
+
+func TestRoundMetrics(t *testing.T) {
+	const counter = "round_metrics_test"
+	rm := newRoundMetrics()
+	metrics.Add(counter, 3)
+	rm.Record(1)
+	rm.Record(2)
+	rounds := rm.Snapshot()
+	if len(rounds) != 2 || rounds[0].RoundID != 1 || rounds[1].RoundID != 2 {
+		t.Fatalf("expected the counters of rounds 1 and 2, received %v", rounds)
+	}
+	if changes := rounds[0].Counters[counter]; changes != 3 {
+		t.Errorf("expected the counter to change by 3 within round 1, received %d", changes)
+	}
+	if _, ok := rounds[1].Counters[counter]; ok {
+		t.Error("expected the unchanged counter to be left out of round 2")
+	}
+	for round := 3; round <= roundMetricsHistory+2; round++ {
+		rm.Record(round)
+	}
+	if rounds = rm.Snapshot(); len(rounds) != roundMetricsHistory || rounds[0].RoundID != 3 {
+		t.Errorf("expected the %d most recent rounds starting with round 3, received %d rounds", roundMetricsHistory, len(rounds))
+	}
+}
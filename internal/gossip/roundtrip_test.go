@@ -0,0 +1,219 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"gossiphers/internal/challenge"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripIterations is the number of random packets generated per packet type.
+const roundTripIterations = 200
+
+// randomBytes returns n random bytes.
+func randomBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	r.Read(b)
+	return b
+}
+
+// randomData returns random message data of up to maxSize bytes, nil instead of empty data like the parsers return.
+func randomData(r *rand.Rand, maxSize int) []byte {
+	if size := r.Intn(maxSize + 1); size > 0 {
+		return randomBytes(r, size)
+	}
+	return nil
+}
+
+// randomIdentity returns a random identity.
+func randomIdentity(t *testing.T, r *rand.Rand) Identity {
+	id, err := NewIdentity(randomBytes(r, IdentitySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *id
+}
+
+// randomNode returns a node with a random identity and a random UTF-8 address without line breaks.
+func randomNode(t *testing.T, r *rand.Rand) Node {
+	var address strings.Builder
+	for i := 0; i < 1+r.Intn(40); i++ {
+		switch r.Intn(4) {
+		case 0:
+			address.WriteRune(rune(0x80 + r.Intn(0xD000)))
+		default:
+			address.WriteByte(byte(' ' + r.Intn('~'-' '+1)))
+		}
+	}
+	node, err := NewNode(randomBytes(r, IdentitySize), address.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.Capabilities = Capabilities(r.Intn(1 << 16))
+	return *node
+}
+
+// randomDigestEntries returns up to 32 random digest entries, an empty but non-nil slice for none like the parsers return.
+func randomDigestEntries(r *rand.Rand) []DigestEntry {
+	entries := make([]DigestEntry, r.Intn(33))
+	for i := range entries {
+		entries[i] = DigestEntry{DataType: uint16(r.Intn(65536)), DataHash: randomBytes(r, sha256.Size)}
+	}
+	return entries
+}
+
+// packetGenerators returns a generator of random valid unsigned packets of every gossip message type.
+func packetGenerators(t *testing.T) map[MessageType]func(r *rand.Rand) fuzzPacket {
+	return map[MessageType]func(r *rand.Rand) fuzzPacket{
+		MessageTypeGossipPing: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketPing(randomIdentity(t, r), r.Uint64(), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipPong: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketPong(randomIdentity(t, r), r.Uint64(), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipPullRequest: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketPullRequest(randomIdentity(t, r), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipPullResponse: func(r *rand.Rand) fuzzPacket {
+			nodes := make([]Node, 1+r.Intn(30))
+			for i := range nodes {
+				nodes[i] = randomNode(t, r)
+			}
+			p, err := NewPacketPullResponse(randomIdentity(t, r), nodes, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipPushRequest: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketPushRequest(randomIdentity(t, r), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipPushChallenge: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketPushChallenge(randomIdentity(t, r), r.Uint32(), randomBytes(r, challenge.ChallengeSize), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipPush: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketPush(randomIdentity(t, r), randomBytes(r, challenge.ChallengeSize), randomBytes(r, challenge.NonceSize), randomNode(t, r), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipMessage: func(r *rand.Rand) fuzzPacket {
+			var originTimestamp uint64
+			if r.Intn(2) == 0 {
+				originTimestamp = r.Uint64() | 1
+			}
+			p, err := NewPacketMessage(randomIdentity(t, r), uint8(r.Intn(256)), uint16(r.Intn(65536)), originTimestamp, randomData(r, 8192), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the origin timestamp flag determines the size of the packet, only the remaining flags are random
+			p.Flags |= uint8(r.Intn(256)) &^ MessageFlagOriginTimestamp
+			return p
+		},
+		MessageTypeGossipMessageBatch: func(r *rand.Rand) fuzzPacket {
+			messages := make([]MessageBatchEntry, 1+r.Intn(10))
+			for i := range messages {
+				messages[i] = MessageBatchEntry{TTL: uint8(r.Intn(256)), Flags: uint8(r.Intn(256)), DataType: uint16(r.Intn(65536)), Data: randomData(r, 512)}
+				if messages[i].Flags&MessageFlagOriginTimestamp != 0 {
+					messages[i].OriginTimestamp = r.Uint64()
+				}
+			}
+			p, err := NewPacketMessageBatch(randomIdentity(t, r), messages, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipDigest: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketDigest(randomIdentity(t, r), randomDigestEntries(r), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipDigestRequest: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketDigestRequest(randomIdentity(t, r), randomDigestEntries(r), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipIHave: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketIHave(randomIdentity(t, r), randomDigestEntries(r), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+		MessageTypeGossipLeave: func(r *rand.Rand) fuzzPacket {
+			p, err := NewPacketLeave(randomIdentity(t, r), time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return p
+		},
+	}
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	t.Parallel()
+	generators := packetGenerators(t)
+	for _, messageType := range supportedIncomingMessageTypes {
+		if generators[messageType] == nil {
+			t.Fatalf("no generator for supported type %#x", messageType)
+		}
+	}
+	for messageType, generate := range generators {
+		messageType, generate := messageType, generate
+		t.Run(reflect.TypeOf(generate(rand.New(rand.NewSource(0)))).Elem().Name(), func(t *testing.T) {
+			t.Parallel()
+			r := rand.New(rand.NewSource(int64(messageType)))
+			for i := 0; i < roundTripIterations; i++ {
+				packet := generate(r)
+				// the signature is opaque to the parsers, a random one is as good as a valid one
+				reflect.ValueOf(packet).Elem().FieldByName("Signature").SetBytes(randomBytes(r, SignatureSize))
+				packetBytes := packet.ToBytes()
+				header, err := ParsePacketHeader(packetBytes[:PacketHeaderSize])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if header.Type != messageType || int(header.Size) != len(packetBytes) {
+					t.Fatalf("header %+v does not match the type %#x and serialized size %d", header, messageType, len(packetBytes))
+				}
+				parsed := newFuzzPacket(messageType)
+				err = parsed.Parse(header, bytes.NewReader(packetBytes[PacketHeaderSize:]))
+				if err != nil {
+					t.Fatalf("could not parse serialized packet of %d bytes: %v", len(packetBytes), err)
+				}
+				if !reflect.DeepEqual(parsed, packet) {
+					t.Fatalf("parsed packet differs from the serialized one: expected %+v, received %+v", packet, parsed)
+				}
+			}
+		})
+	}
+}
@@ -3,12 +3,13 @@ package gossip
 import (
 	"bytes"
 	"crypto/rand"
-	"crypto/sha256"
 	"errors"
 	"fmt"
+	"gossiphers/internal/clock"
+	"io"
 	"math/big"
-
-	"go.uber.org/zap"
+	"sync"
+	"time"
 )
 
 var (
@@ -20,6 +21,18 @@ type Sampler struct {
 	bias            []byte
 	elem            *Node
 	currentElemHash []byte
+	// hashFunc is the min-wise independent hash function, SHA-256 if unset
+	hashFunc SamplerHashFunc
+	// random is the source of randomness of the bias, crypto/rand if nil
+	random io.Reader
+	// clock stamps the capture of elements, the wall clock if nil
+	clock clock.Clock
+	// capturedAt is the time the current element was captured
+	capturedAt time.Time
+	// validatedAt is the time the current element last answered a ping
+	validatedAt time.Time
+	// nextPingRound is the round in which the current element is health-checked next, 0 if not yet scheduled
+	nextPingRound int
 }
 
 // Init creates a random bias element, which will be used in a random min-wise independent hash function.
@@ -27,23 +40,44 @@ func (s *Sampler) Init() error {
 	s.elem = nil
 	s.bias = make([]byte, 64)
 	s.currentElemHash = nil
-	_, err := rand.Read(s.bias)
+	s.capturedAt = time.Time{}
+	s.validatedAt = time.Time{}
+	s.nextPingRound = 0
+	_, err := io.ReadFull(randomOr(s.random), s.bias)
 	return err
 }
 
 // Next applies the random min-wise independent hash function to the passed-in Node, keeping whichever node has the lower hash value.
 func (s *Sampler) Next(newElem Node) {
-	hashFunc := sha256.New()
-	_, err := hashFunc.Write(append(s.bias, newElem.Identity.ToBytes()...))
-	if err != nil {
-		zap.L().Panic("Unexpected error during hash calculation", zap.Error(err))
-	}
-	newHash := hashFunc.Sum(nil)
-
+	newHash := s.hash(newElem.Identity)
 	if s.elem == nil || bytes.Compare(newHash, s.currentElemHash) < 0 {
 		s.elem = &newElem
 		s.currentElemHash = newHash
+		s.capturedAt = clock.Or(s.clock).Now()
+		s.validatedAt = time.Time{}
+		s.nextPingRound = 0
+	}
+}
+
+// hash applies the min-wise independent hash function, keyed by the sampler's bias, to an identity.
+func (s *Sampler) hash(identity Identity) []byte {
+	if s.hashFunc == nil {
+		return sha256SamplerHash(s.bias, identity.ToBytes())
 	}
+	return s.hashFunc(s.bias, identity.ToBytes())
+}
+
+// IsStale checks whether the current element was neither captured nor validated within the given window.
+// Empty samplers are never stale.
+func (s *Sampler) IsStale(window time.Duration, now time.Time) bool {
+	if s.elem == nil {
+		return false
+	}
+	lastConfirmed := s.capturedAt
+	if s.validatedAt.After(lastConfirmed) {
+		lastConfirmed = s.validatedAt
+	}
+	return now.Sub(lastConfirmed) > window
 }
 
 // Sample returns a reference to the currently stored node.
@@ -52,32 +86,81 @@ func (s *Sampler) Sample() *Node {
 }
 
 // SamplerGroup represents a collection of Samplers.
+// It is safe for concurrent use, sampled nodes are never modified after being returned.
 type SamplerGroup struct {
 	samplers []Sampler
+	// excluded is an identity, usually our own, which is never fed into the samplers
+	excluded Identity
+	hashFunc SamplerHashFunc
+	// random is the source of randomness of the biases, the ping schedule, and the sampled subsets, crypto/rand if nil
+	random io.Reader
+	// clock stamps the capture and validation of elements and decides their staleness, the wall clock if nil
+	clock clock.Clock
+	mu    sync.Mutex
+}
+
+// SamplerGroupOption represents a functional option for the SamplerGroup's 'constructor'.
+type SamplerGroupOption func(*SamplerGroup)
+
+// WithSamplerExcludedIdentity prevents nodes with the given identity, usually the identity of the local node, from being sampled.
+func WithSamplerExcludedIdentity(identity Identity) SamplerGroupOption {
+	return func(sg *SamplerGroup) {
+		sg.excluded = identity
+	}
+}
+
+// WithSamplerHash sets the min-wise independent hash function used by all samplers, SHA-256 by default.
+func WithSamplerHash(hashFunc SamplerHashFunc) SamplerGroupOption {
+	return func(sg *SamplerGroup) {
+		sg.hashFunc = hashFunc
+	}
+}
+
+// WithSamplerRandom sets the source of randomness of the sampler biases, the ping schedule, and the sampled subsets, crypto/rand by default.
+func WithSamplerRandom(random io.Reader) SamplerGroupOption {
+	return func(sg *SamplerGroup) {
+		sg.random = random
+	}
+}
+
+// WithSamplerClock sets the clock stamping the capture and validation of sampled nodes, the wall clock by default.
+func WithSamplerClock(c clock.Clock) SamplerGroupOption {
+	return func(sg *SamplerGroup) {
+		sg.clock = c
+	}
 }
 
 // NewSamplerGroup creates an initialized collection of Samplers.
-func NewSamplerGroup(size int) (*SamplerGroup, error) {
+func NewSamplerGroup(size int, options ...SamplerGroupOption) (*SamplerGroup, error) {
 	if size <= 0 {
 		return nil, ErrInvalidSamplerAmount
 	}
-	samplers := make([]Sampler, size)
-	for i, s := range samplers {
-		err := s.Init()
+	sg := &SamplerGroup{
+		samplers: make([]Sampler, size),
+	}
+	for _, option := range options {
+		option(sg)
+	}
+	for i := range sg.samplers {
+		sg.samplers[i].hashFunc = sg.hashFunc
+		sg.samplers[i].random = sg.random
+		sg.samplers[i].clock = sg.clock
+		err := sg.samplers[i].Init()
 		if err != nil {
 			return nil, err
 		}
-		samplers[i] = s
 	}
-
-	return &SamplerGroup{
-		samplers: samplers,
-	}, nil
+	return sg, nil
 }
 
 // Update invokes the min-wise indepedent hash function for each sampler with the given elements.
 func (sg *SamplerGroup) Update(newElems []Node) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
 	for _, newElem := range newElems {
+		if sg.excluded != (Identity{}) && newElem.Identity == sg.excluded {
+			continue
+		}
 		for i, s := range sg.samplers {
 			s.Next(newElem)
 			sg.samplers[i] = s
@@ -85,44 +168,125 @@ func (sg *SamplerGroup) Update(newElems []Node) {
 	}
 }
 
-// RandomSubset returns a random subset of length n of the ViewList.
-func (sg *SamplerGroup) RandomNodeSubset(n int) ([]*Node, error) {
-	if n > len(sg.samplers) || n <= 0 {
-		return nil, fmt.Errorf("RandomSubset: required size between 0 (non-inclusive) and |sg.samplers|")
+// MarkValidated records that the node with the given identity answered a ping, for all samplers currently holding it.
+func (sg *SamplerGroup) MarkValidated(identity Identity) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	now := clock.Or(sg.clock).Now()
+	for i := range sg.samplers {
+		if sg.samplers[i].elem != nil && sg.samplers[i].elem.Identity == identity {
+			sg.samplers[i].validatedAt = now
+		}
 	}
+}
 
-	// mix samplers
-	copySlice := make([]*Sampler, 0)
-	for i := 0; i < len(sg.samplers); i++ {
-		if sg.samplers[i].Sample() == nil {
-			continue
+// Invalidate reinitializes all samplers currently holding the node with the given identity.
+func (sg *SamplerGroup) Invalidate(identity Identity) error {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	for i := range sg.samplers {
+		if sg.samplers[i].elem != nil && sg.samplers[i].elem.Identity == identity {
+			err := sg.samplers[i].Init()
+			if err != nil {
+				return err
+			}
 		}
-		copySlice = append(copySlice, &sg.samplers[i])
 	}
-	for i := 0; i < len(copySlice); i++ {
-		// Generate a random index between 0 and i (inclusive)
-		bigJ, err := rand.Int(rand.Reader, big.NewInt(int64(len(copySlice))))
+	return nil
+}
+
+// ReinitStale reinitializes all samplers whose element was neither captured nor validated within the given window and returns their number.
+func (sg *SamplerGroup) ReinitStale(window time.Duration) (int, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	now := clock.Or(sg.clock).Now()
+	reinitialized := 0
+	for i := range sg.samplers {
+		if !sg.samplers[i].IsStale(window, now) {
+			continue
+		}
+		err := sg.samplers[i].Init()
 		if err != nil {
-			return nil, err
+			return reinitialized, err
 		}
-		if !bigJ.IsInt64() {
-			return nil, fmt.Errorf("j is not of type int64: %s", bigJ.String())
+		reinitialized++
+	}
+	return reinitialized, nil
+}
+
+// DuePings returns the distinct sampled nodes that should be health-checked in the given round.
+// Every sampler checks its element once every roundsBetweenPings rounds. Newly captured elements are scheduled at a random offset
+// within the interval, which spreads the pings of large sampler groups evenly across rounds instead of sending them in bursts.
+func (sg *SamplerGroup) DuePings(round int, roundsBetweenPings int) ([]*Node, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	var due []*Node
+	unique := make(map[Identity]struct{})
+	for i := range sg.samplers {
+		s := &sg.samplers[i]
+		if s.elem == nil {
+			continue
+		}
+		if s.nextPingRound == 0 {
+			offset, err := rand.Int(randomOr(sg.random), big.NewInt(int64(roundsBetweenPings)))
+			if err != nil {
+				return nil, err
+			}
+			s.nextPingRound = round + 1 + int(offset.Int64())
+		}
+		if s.nextPingRound > round {
+			continue
 		}
-		j := bigJ.Int64()
+		s.nextPingRound = round + roundsBetweenPings
+		if _, ok := unique[s.elem.Identity]; ok {
+			continue
+		}
+		unique[s.elem.Identity] = struct{}{}
+		due = append(due, s.elem)
+	}
+	return due, nil
+}
+
+// RandomNodeSubset returns up to n distinct sampled nodes. Fewer nodes are returned if the samplers hold fewer distinct nodes, n = 0 results in an empty slice.
+// Non-empty samplers are drawn uniformly at random without replacement, so the probability of a node being returned is proportional to the number of samplers holding it.
+func (sg *SamplerGroup) RandomNodeSubset(n int) ([]*Node, error) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	if n < 0 {
+		return nil, fmt.Errorf("n cannot be negative: received %d", n)
+	}
 
-		// Swap the elements at i and j
-		copySlice[i], copySlice[j] = copySlice[j], copySlice[i]
+	samples := make([]*Node, 0, len(sg.samplers))
+	for i := range sg.samplers {
+		if sample := sg.samplers[i].Sample(); sample != nil {
+			samples = append(samples, sample)
+		}
 	}
 
-	nodes := make([]*Node, 0)
-	for i := 0; i < n && i < len(copySlice); i++ {
-		nodes = append(nodes, copySlice[i].Sample())
+	nodes := make([]*Node, 0, n)
+	unique := make(map[Identity]struct{})
+	// partial Fisher-Yates shuffle, stopping as soon as n distinct nodes are drawn
+	for i := 0; i < len(samples) && len(nodes) < n; i++ {
+		bigJ, err := rand.Int(randomOr(sg.random), big.NewInt(int64(len(samples)-i)))
+		if err != nil {
+			return nil, err
+		}
+		j := i + int(bigJ.Int64())
+		samples[i], samples[j] = samples[j], samples[i]
+
+		if _, ok := unique[samples[i].Identity]; ok {
+			continue
+		}
+		unique[samples[i].Identity] = struct{}{}
+		nodes = append(nodes, samples[i])
 	}
 	return nodes, nil
 }
 
 // SampleAll samples each sampler within the collection.
 func (sg *SamplerGroup) SampleAll() []*Node {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
 	var samples []*Node
 	for _, s := range sg.samplers {
 		res := s.Sample()
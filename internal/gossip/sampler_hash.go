@@ -0,0 +1,56 @@
+package gossip
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/dchest/siphash"
+	"github.com/zeebo/blake3"
+	"go.uber.org/zap"
+)
+
+// SamplerHashFunc represents a min-wise independent hash function keyed by a sampler's bias.
+type SamplerHashFunc func(bias []byte, identity []byte) []byte
+
+// sha256SamplerHash hashes the bias concatenated with the identity using SHA-256.
+func sha256SamplerHash(bias []byte, identity []byte) []byte {
+	hashFunc := sha256.New()
+	hashFunc.Write(bias)
+	hashFunc.Write(identity)
+	return hashFunc.Sum(nil)
+}
+
+// blake3SamplerHash hashes the identity using BLAKE3 keyed by the first 32 bytes of the bias.
+func blake3SamplerHash(bias []byte, identity []byte) []byte {
+	var key [32]byte
+	copy(key[:], bias)
+	hashFunc, err := blake3.NewKeyed(key[:])
+	if err != nil {
+		logger().Panic("Unexpected error creating keyed BLAKE3 hash", zap.Error(err))
+	}
+	_, _ = hashFunc.Write(identity)
+	return hashFunc.Sum(nil)
+}
+
+// sipSamplerHash hashes the identity using SipHash-2-4 keyed by the first 16 bytes of the bias.
+func sipSamplerHash(bias []byte, identity []byte) []byte {
+	var key [16]byte
+	copy(key[:], bias)
+	sum := siphash.Hash(binary.LittleEndian.Uint64(key[:8]), binary.LittleEndian.Uint64(key[8:]), identity)
+	return binary.BigEndian.AppendUint64(nil, sum)
+}
+
+// SamplerHashByName returns the sampler hash function with the given name, one of sha256, blake3, and siphash.
+func SamplerHashByName(name string) (SamplerHashFunc, error) {
+	switch name {
+	case "", "sha256":
+		return sha256SamplerHash, nil
+	case "blake3":
+		return blake3SamplerHash, nil
+	case "siphash":
+		return sipSamplerHash, nil
+	default:
+		return nil, fmt.Errorf("unknown sampler hash function %s", name)
+	}
+}
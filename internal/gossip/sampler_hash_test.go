@@ -0,0 +1,55 @@
+package gossip
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSamplerHash_SamplerHashByName(t *testing.T) {
+	t.Parallel()
+	t.Run("hash functions are deterministic and keyed by the bias", func(t *testing.T) {
+		identity := sliceRepeat(IdentitySize, byte(0x01))
+		bias1 := sliceRepeat(64, byte(0x02))
+		bias2 := sliceRepeat(64, byte(0x03))
+		for _, name := range []string{"sha256", "blake3", "siphash"} {
+			hashFunc, err := SamplerHashByName(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(hashFunc(bias1, identity), hashFunc(bias1, identity)) {
+				t.Errorf("%s is not deterministic", name)
+			}
+			if bytes.Equal(hashFunc(bias1, identity), hashFunc(bias2, identity)) {
+				t.Errorf("%s does not depend on the bias", name)
+			}
+		}
+	})
+	t.Run("returns error on unknown hash function", func(t *testing.T) {
+		_, err := SamplerHashByName("md5")
+		if err == nil {
+			t.Error("expecting error")
+		}
+	})
+	t.Run("samplers use the configured hash function", func(t *testing.T) {
+		nodes, err := createNodes(20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg, err := NewSamplerGroup(5, WithSamplerHash(sipSamplerHash))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg.Update(nodes)
+		for _, s := range sg.samplers {
+			if len(s.currentElemHash) != 8 {
+				t.Fatalf("expected 8 byte SipHash values, received %d bytes", len(s.currentElemHash))
+			}
+			// the sampled node must be the one with the minimal hash value
+			for _, node := range nodes {
+				if bytes.Compare(sipSamplerHash(s.bias, node.Identity.ToBytes()), s.currentElemHash) < 0 {
+					t.Fatalf("sampler did not keep the node with the minimal hash value")
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package gossip
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gossiphers/internal/clock"
+	"os"
+)
+
+// samplerState represents the persisted state of a single sampler. Identity and address are empty for empty samplers.
+type samplerState struct {
+	Bias     string `json:"bias"`
+	Identity string `json:"identity,omitempty"`
+	Address  string `json:"address,omitempty"`
+}
+
+// Save writes the biases and current elements of all samplers to the file at path.
+func (sg *SamplerGroup) Save(path string) error {
+	sg.mu.Lock()
+	states := make([]samplerState, len(sg.samplers))
+	for i, s := range sg.samplers {
+		states[i].Bias = hex.EncodeToString(s.bias)
+		if s.elem != nil {
+			states[i].Identity = s.elem.Identity.String()
+			states[i].Address = s.elem.Address
+		}
+	}
+	sg.mu.Unlock()
+
+	stateBytes, err := json.Marshal(states)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, stateBytes); err != nil {
+		return fmt.Errorf("could not write sampler state file: %w", err)
+	}
+	return nil
+}
+
+// Load restores the biases and elements of the samplers from the file at path, which was written by Save.
+// If the file holds fewer samplers than the group, the remaining ones keep their state, additional ones are ignored.
+// Restored elements count as freshly captured, elements with the excluded identity are dropped.
+func (sg *SamplerGroup) Load(path string) error {
+	stateBytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read sampler state file: %w", err)
+	}
+	var states []samplerState
+	err = json.Unmarshal(stateBytes, &states)
+	if err != nil {
+		return fmt.Errorf("could not parse sampler state file %s: %w", path, err)
+	}
+
+	samplers := make([]Sampler, 0, len(states))
+	for i, state := range states {
+		bias, err := hex.DecodeString(state.Bias)
+		if err != nil || len(bias) == 0 {
+			return fmt.Errorf("invalid bias of sampler %d in sampler state file %s", i, path)
+		}
+		sampler := Sampler{bias: bias, hashFunc: sg.hashFunc, random: sg.random, clock: sg.clock}
+		if state.Identity != "" {
+			identity, err := hex.DecodeString(state.Identity)
+			if err != nil {
+				return fmt.Errorf("invalid identity of sampler %d in sampler state file %s: %w", i, path, err)
+			}
+			node, err := NewNode(identity, state.Address)
+			if err != nil {
+				return fmt.Errorf("invalid node of sampler %d in sampler state file %s: %w", i, path, err)
+			}
+			if sg.excluded == (Identity{}) || node.Identity != sg.excluded {
+				sampler.elem = node
+				sampler.currentElemHash = sampler.hash(node.Identity)
+				sampler.capturedAt = clock.Or(sg.clock).Now()
+			}
+		}
+		samplers = append(samplers, sampler)
+	}
+
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	for i := 0; i < len(samplers) && i < len(sg.samplers); i++ {
+		sg.samplers[i] = samplers[i]
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package gossip
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSamplerGroup_SaveLoad(t *testing.T) {
+	t.Parallel()
+
+	t.Run("restores biases and elements", func(t *testing.T) {
+		nodes, err := createNodes(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg, err := NewSamplerGroup(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg.Update(nodes)
+		path := filepath.Join(t.TempDir(), "samplers.json")
+		err = sg.Save(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restored, err := NewSamplerGroup(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = restored.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range sg.samplers {
+			if !bytes.Equal(sg.samplers[i].bias, restored.samplers[i].bias) || !bytes.Equal(sg.samplers[i].currentElemHash, restored.samplers[i].currentElemHash) {
+				t.Errorf("Sampler %d was not restored", i)
+			}
+			if sg.samplers[i].elem.String() != restored.samplers[i].elem.String() {
+				t.Errorf("Element of sampler %d was not restored: %s", i, restored.samplers[i].elem.String())
+			}
+		}
+
+		// restored samplers keep making the same decisions
+		more, err := createNodes(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg.Update(more)
+		restored.Update(more)
+		for i := range sg.samplers {
+			if sg.samplers[i].elem.Identity != restored.samplers[i].elem.Identity {
+				t.Errorf("Restored sampler %d diverged", i)
+			}
+		}
+	})
+
+	t.Run("tolerates a different number of samplers and drops the excluded identity", func(t *testing.T) {
+		nodes, err := createNodes(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg, err := NewSamplerGroup(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg.Update(nodes)
+		path := filepath.Join(t.TempDir(), "samplers.json")
+		err = sg.Save(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		restored, err := NewSamplerGroup(3, WithSamplerExcludedIdentity(nodes[0].Identity))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = restored.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(restored.samplers) != 3 || len(restored.SampleAll()) != 0 {
+			t.Errorf("Unexpected restored samplers: %d samplers, %d samples", len(restored.samplers), len(restored.SampleAll()))
+		}
+		if !bytes.Equal(sg.samplers[1].bias, restored.samplers[1].bias) {
+			t.Errorf("Bias was not restored")
+		}
+	})
+
+	t.Run("returns error on missing or malformed files", func(t *testing.T) {
+		sg, err := NewSamplerGroup(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sg.Load(filepath.Join(t.TempDir(), "missing.json"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Expected not exist error, received %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "samplers.json")
+		err = os.WriteFile(path, []byte(`[{"bias":"zz"}]`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sg.Load(path)
+		if err == nil {
+			t.Error("Expected error on malformed bias")
+		}
+	})
+}
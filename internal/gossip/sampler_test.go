@@ -2,7 +2,10 @@ package gossip
 
 import (
 	"bytes"
+	"gossiphers/internal/clock"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSampler_Next(t *testing.T) {
@@ -138,6 +141,24 @@ func TestSamplerGroup_NewSampler(t *testing.T) {
 			}
 		}
 	})
+	t.Run("biases are reproducible with a seeded source of randomness", func(t *testing.T) {
+		var groups [2]*SamplerGroup
+		for i := range groups {
+			sg, err := NewSamplerGroup(10, WithSamplerRandom(NewSeededRandom(7)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			groups[i] = sg
+		}
+		for i := range groups[0].samplers {
+			if !bytes.Equal(groups[0].samplers[i].bias, groups[1].samplers[i].bias) {
+				t.Fatalf("sampler %d received different biases from the same seed", i)
+			}
+			if i > 0 && bytes.Equal(groups[0].samplers[i].bias, groups[0].samplers[i-1].bias) {
+				t.Fatalf("samplers %d and %d received the same bias", i-1, i)
+			}
+		}
+	})
 }
 
 func TestSamplerGroup_Update(t *testing.T) {
@@ -197,6 +218,22 @@ func TestSamplerGroup_Update(t *testing.T) {
 			t.Error("SamplerGroup did not update second Sampler Identity")
 		}
 	})
+
+	t.Run("SamplerGroup never samples the excluded identity", func(t *testing.T) {
+		ownNode, err := NewNode(sliceRepeat(IdentitySize, byte(0x01)), "1.2.3.4:5678")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg, err := NewSamplerGroup(4, WithSamplerExcludedIdentity(ownNode.Identity))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg.Update([]Node{*ownNode})
+
+		if len(sg.SampleAll()) != 0 {
+			t.Error("SamplerGroup sampled the excluded identity")
+		}
+	})
 }
 
 func TestSamplerGroup_SampleAll(t *testing.T) {
@@ -247,6 +284,86 @@ func TestSamplerGroup_SampleAll(t *testing.T) {
 	})
 }
 
+func TestSamplerGroup_RandomNodeSubset(t *testing.T) {
+	t.Parallel()
+
+	t.Run("returns distinct non-nil nodes and tolerates n larger than the group", func(t *testing.T) {
+		nodes, err := createNodes(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg := SamplerGroup{samplers: []Sampler{{elem: &nodes[0]}, {elem: nil}, {elem: &nodes[1]}, {elem: &nodes[0]}}}
+
+		subset, err := sg.RandomNodeSubset(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(subset) != 2 {
+			t.Fatalf("Expected both distinct nodes, received %d nodes", len(subset))
+		}
+		if subset[0] == nil || subset[1] == nil || subset[0].Identity == subset[1].Identity {
+			t.Errorf("Subset contains nil or duplicate nodes: %v", subset)
+		}
+	})
+
+	t.Run("returns exactly n nodes if enough are sampled", func(t *testing.T) {
+		nodes, err := createNodes(5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg := SamplerGroup{samplers: []Sampler{{elem: &nodes[0]}, {elem: &nodes[1]}, {elem: &nodes[2]}, {elem: &nodes[3]}, {elem: &nodes[4]}}}
+
+		subset, err := sg.RandomNodeSubset(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(subset) != 3 {
+			t.Errorf("Expected 3 nodes, received %d", len(subset))
+		}
+	})
+
+	t.Run("handles empty groups, zero, and negative sizes", func(t *testing.T) {
+		sg, err := NewSamplerGroup(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subset, err := sg.RandomNodeSubset(2)
+		if err != nil || len(subset) != 0 {
+			t.Errorf("Expected no nodes from empty samplers, received %v (%v)", subset, err)
+		}
+		subset, err = sg.RandomNodeSubset(0)
+		if err != nil || len(subset) != 0 {
+			t.Errorf("Expected no nodes for n = 0, received %v (%v)", subset, err)
+		}
+		_, err = sg.RandomNodeSubset(-1)
+		if err == nil {
+			t.Error("Expected error for negative n")
+		}
+	})
+
+	t.Run("every sampled node is picked", func(t *testing.T) {
+		nodes, err := createNodes(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg := SamplerGroup{samplers: []Sampler{{elem: &nodes[0]}, {elem: &nodes[1]}, {elem: &nodes[2]}, {elem: &nodes[3]}}}
+
+		picked := make(map[Identity]int)
+		for i := 0; i < 400; i++ {
+			subset, err := sg.RandomNodeSubset(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			picked[subset[0].Identity]++
+		}
+		for _, node := range nodes {
+			if picked[node.Identity] == 0 {
+				t.Errorf("Node %s was never picked", node.String())
+			}
+		}
+	})
+}
+
 func sliceRepeat[T any](size int, v T) []T {
 	retVal := make([]T, 0, size)
 	for i := 0; i < size; i++ {
@@ -254,3 +371,162 @@ func sliceRepeat[T any](size int, v T) []T {
 	}
 	return retVal
 }
+
+func TestSamplerGroup_Aging(t *testing.T) {
+	t.Parallel()
+
+	t.Run("samplers become stale unless their node is validated", func(t *testing.T) {
+		node, err := NewNode(sliceRepeat(IdentitySize, byte(0x01)), "1.2.3.4:5678")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fakeClock := clock.NewFake(time.Unix(1000, 0))
+		sg, err := NewSamplerGroup(3, WithSamplerClock(fakeClock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg.Update([]Node{*node})
+		fakeClock.Advance(time.Hour)
+
+		if !sg.samplers[0].IsStale(time.Minute, fakeClock.Now()) {
+			t.Error("Sampler with an old element is not stale")
+		}
+		sg.MarkValidated(node.Identity)
+		if sg.samplers[0].IsStale(time.Minute, fakeClock.Now()) {
+			t.Error("Sampler with a validated element is stale")
+		}
+		reinitialized, err := sg.ReinitStale(time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reinitialized != 0 || len(sg.SampleAll()) != 3 {
+			t.Errorf("Validated samplers were reinitialized")
+		}
+
+		fakeClock.Advance(time.Minute + time.Millisecond)
+		reinitialized, err = sg.ReinitStale(time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reinitialized != 3 || len(sg.SampleAll()) != 0 {
+			t.Errorf("Stale samplers were not reinitialized, %d reinitialized", reinitialized)
+		}
+	})
+
+	t.Run("invalidate reinitializes all samplers holding the node", func(t *testing.T) {
+		node1, err := NewNode(sliceRepeat(IdentitySize, byte(0x01)), "1.2.3.4:5678")
+		if err != nil {
+			t.Fatal(err)
+		}
+		node2, err := NewNode(sliceRepeat(IdentitySize, byte(0x02)), "3.4.5.6:7890")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg := SamplerGroup{samplers: []Sampler{{elem: node1}, {elem: node2}, {elem: node1}}}
+
+		err = sg.Invalidate(node1.Identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples := sg.SampleAll()
+		if len(samples) != 1 || samples[0].Identity != node2.Identity {
+			t.Errorf("Unexpected samples after invalidation: %v", samples)
+		}
+	})
+}
+
+func TestSamplerGroup_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	t.Run("SamplerGroup can be updated and sampled concurrently", func(t *testing.T) {
+		nodes, err := createNodes(20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg, err := NewSamplerGroup(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var wg sync.WaitGroup
+		for i := range nodes {
+			wg.Add(3)
+			go func(node Node) {
+				defer wg.Done()
+				sg.Update([]Node{node})
+			}(nodes[i])
+			go func(node Node) {
+				defer wg.Done()
+				sg.MarkValidated(node.Identity)
+				_, _ = sg.ReinitStale(time.Minute)
+			}(nodes[i])
+			go func() {
+				defer wg.Done()
+				for _, sample := range sg.SampleAll() {
+					_ = sample.String()
+				}
+				_, _ = sg.RandomNodeSubset(5)
+			}()
+		}
+		wg.Wait()
+
+		if len(sg.SampleAll()) != 10 {
+			t.Errorf("Expected all samplers to be filled, received %d samples", len(sg.SampleAll()))
+		}
+	})
+}
+
+func TestSamplerGroup_DuePings(t *testing.T) {
+	t.Parallel()
+
+	t.Run("every sampled node is pinged once per interval at a spread offset", func(t *testing.T) {
+		nodes, err := createNodes(40)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samplers := make([]Sampler, len(nodes))
+		for i := range nodes {
+			samplers[i].elem = &nodes[i]
+		}
+		sg := SamplerGroup{samplers: samplers}
+
+		interval := 8
+		pingsPerNode := make(map[Identity]int)
+		busyRounds := 0
+		for round := 1; round <= 3*interval; round++ {
+			due, err := sg.DuePings(round, interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(due) > 0 {
+				busyRounds++
+			}
+			for _, node := range due {
+				pingsPerNode[node.Identity]++
+			}
+		}
+		for _, node := range nodes {
+			if pings := pingsPerNode[node.Identity]; pings < 2 || pings > 3 {
+				t.Errorf("Node %s was pinged %d times within 3 intervals", node.String(), pings)
+			}
+		}
+		if busyRounds <= interval {
+			t.Errorf("Pings were not spread across rounds, only %d rounds with pings", busyRounds)
+		}
+	})
+
+	t.Run("nodes held by multiple samplers are returned once", func(t *testing.T) {
+		nodes, err := createNodes(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg := SamplerGroup{samplers: []Sampler{{elem: &nodes[0], nextPingRound: 1}, {elem: &nodes[0], nextPingRound: 1}, {elem: nil}}}
+		due, err := sg.DuePings(1, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(due) != 1 {
+			t.Errorf("Expected a single due node, received %d", len(due))
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package gossip
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+)
+
+// seenFilterFalsePositiveRate is the false positive rate each generation of a seenFilter is dimensioned for.
+const seenFilterFalsePositiveRate = 0.001
+
+// bloomFilter is a fixed-size bloom filter over message keys.
+type bloomFilter struct {
+	bits      []uint64
+	numHashes int
+	count     int
+}
+
+// newBloomFilter returns a bloom filter dimensioned for capacity elements at the given false positive rate.
+func newBloomFilter(capacity int, falsePositiveRate float64) *bloomFilter {
+	numBits := int(math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	numHashes := int(math.Round(float64(numBits) / float64(capacity) * math.Ln2))
+	if numHashes < 1 {
+		numHashes = 1
+	}
+	return &bloomFilter{
+		bits:      make([]uint64, (numBits+63)/64),
+		numHashes: numHashes,
+	}
+}
+
+// positions derives the bit positions of a key using double hashing.
+func (bf *bloomFilter) positions(key messageKey) []uint64 {
+	hashFunc := sha256.New()
+	hashFunc.Write(binary.BigEndian.AppendUint16(nil, key.dataType))
+	hashFunc.Write([]byte(key.dataHash))
+	sum := hashFunc.Sum(nil)
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+	numBits := uint64(len(bf.bits) * 64)
+	positions := make([]uint64, bf.numHashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % numBits
+	}
+	return positions
+}
+
+// Add inserts a key into the filter.
+func (bf *bloomFilter) Add(key messageKey) {
+	for _, position := range bf.positions(key) {
+		bf.bits[position/64] |= 1 << (position % 64)
+	}
+	bf.count++
+}
+
+// Contains checks whether a key may have been inserted into the filter. False positives are possible, false negatives are not.
+func (bf *bloomFilter) Contains(key messageKey) bool {
+	for _, position := range bf.positions(key) {
+		if bf.bits[position/64]&(1<<(position%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// seenFilter remembers the keys of recently accepted messages beyond their eviction from the message store, so messages re-gossiped by lagging peers are not delivered to API clients again.
+// It consists of two bloom filter generations: once the current generation holds capacity keys, it replaces the previous one and a new generation is started.
+// Hence, between capacity and 2*capacity of the most recent keys are remembered at a constant memory footprint.
+// The filter is not safe for concurrent use.
+type seenFilter struct {
+	capacity int
+	current  *bloomFilter
+	previous *bloomFilter
+}
+
+// newSeenFilter returns a new instance of seenFilter remembering at least capacity keys.
+func newSeenFilter(capacity int) *seenFilter {
+	return &seenFilter{
+		capacity: capacity,
+		current:  newBloomFilter(capacity, seenFilterFalsePositiveRate),
+	}
+}
+
+// Add remembers a key, rotating the generations if the current one is full.
+func (sf *seenFilter) Add(key messageKey) {
+	if sf.current.count >= sf.capacity {
+		sf.previous = sf.current
+		sf.current = newBloomFilter(sf.capacity, seenFilterFalsePositiveRate)
+	}
+	sf.current.Add(key)
+}
+
+// Contains checks whether a key was (probably) seen recently.
+func (sf *seenFilter) Contains(key messageKey) bool {
+	return sf.current.Contains(key) || (sf.previous != nil && sf.previous.Contains(key))
+}
@@ -0,0 +1,55 @@
+package gossip
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSeenFilter(t *testing.T) {
+	t.Parallel()
+	t.Run("remembers added keys", func(t *testing.T) {
+		filter := newSeenFilter(1000)
+		for i := 0; i < 1000; i++ {
+			filter.Add(messageKey{1, fmt.Sprintf("hash%d", i)})
+		}
+		for i := 0; i < 1000; i++ {
+			if !filter.Contains(messageKey{1, fmt.Sprintf("hash%d", i)}) {
+				t.Fatalf("key %d was forgotten", i)
+			}
+		}
+		if filter.Contains(messageKey{2, "hash1"}) && filter.Contains(messageKey{2, "hash2"}) && filter.Contains(messageKey{2, "hash3"}) {
+			t.Error("keys of other data types are reported as seen")
+		}
+	})
+	t.Run("keeps a low false positive rate", func(t *testing.T) {
+		filter := newSeenFilter(10000)
+		for i := 0; i < 10000; i++ {
+			filter.Add(messageKey{1, fmt.Sprintf("seen%d", i)})
+		}
+		falsePositives := 0
+		for i := 0; i < 10000; i++ {
+			if filter.Contains(messageKey{1, fmt.Sprintf("unseen%d", i)}) {
+				falsePositives++
+			}
+		}
+		if falsePositives > 50 {
+			t.Errorf("too many false positives: %d of 10000", falsePositives)
+		}
+	})
+	t.Run("forgets keys after two generations", func(t *testing.T) {
+		filter := newSeenFilter(100)
+		filter.Add(messageKey{1, "old"})
+		for i := 0; i < 150; i++ {
+			filter.Add(messageKey{1, fmt.Sprintf("hash%d", i)})
+		}
+		if !filter.Contains(messageKey{1, "old"}) {
+			t.Error("key was forgotten within the previous generation")
+		}
+		for i := 150; i < 250; i++ {
+			filter.Add(messageKey{1, fmt.Sprintf("hash%d", i)})
+		}
+		if filter.Contains(messageKey{1, "old"}) {
+			t.Error("key was not forgotten after two generations")
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package gossip
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitState represents whether packets are sent to an address.
+type circuitState int
+
+const (
+	// circuitClosed means packets are sent to the address
+	circuitClosed circuitState = iota
+	// circuitOpen means sending to the address failed repeatedly and packets are dropped until the cooldown passed
+	circuitOpen
+	// circuitHalfOpen means the cooldown passed and a single packet probes whether the address is reachable again
+	circuitHalfOpen
+)
+
+// String returns the name of a circuit state.
+func (s circuitState) String() string {
+	switch s {
+	case circuitClosed:
+		return "closed"
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// sendCircuit holds the consecutive send failures of a single address, the state of its circuit, and the time it was entered.
+type sendCircuit struct {
+	failures int
+	state    circuitState
+	since    time.Time
+}
+
+// sendBreaker opens the circuit of an address after consecutive failures to resolve or write to it, so packets to unreachable
+// peers are dropped cheaply for a cooldown instead of being signed, encrypted, and sent every round. Once the cooldown passed,
+// a single packet probes the address, closing the circuit if it is sent and opening it for another cooldown otherwise.
+// It is safe for concurrent use.
+type sendBreaker struct {
+	// threshold is the number of consecutive failures after which the circuit opens, 0 disables the breaker
+	threshold int
+	cooldown  time.Duration
+	circuits  map[string]*sendCircuit
+	mu        sync.Mutex
+}
+
+// newSendBreaker returns a breaker opening circuits after the given number of consecutive failures for the given cooldown.
+func newSendBreaker(threshold int, cooldown time.Duration) *sendBreaker {
+	return &sendBreaker{threshold: threshold, cooldown: cooldown, circuits: make(map[string]*sendCircuit)}
+}
+
+// Allow checks whether a packet may be sent to the address. After the cooldown of an open circuit, one packet is allowed as a
+// probe per cooldown until its outcome is recorded.
+func (sb *sendBreaker) Allow(address string, now time.Time) bool {
+	if sb.threshold <= 0 {
+		return true
+	}
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	circuit, ok := sb.circuits[address]
+	if !ok || circuit.state == circuitClosed {
+		return true
+	}
+	if now.Sub(circuit.since) < sb.cooldown {
+		return false
+	}
+	circuit.state, circuit.since = circuitHalfOpen, now
+	return true
+}
+
+// Failure records a failure to send to the address and returns whether it opened the circuit.
+// A failed probe opens the circuit again right away.
+func (sb *sendBreaker) Failure(address string, now time.Time) bool {
+	if sb.threshold <= 0 {
+		return false
+	}
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	circuit, ok := sb.circuits[address]
+	if !ok {
+		circuit = &sendCircuit{}
+		sb.circuits[address] = circuit
+	}
+	circuit.failures++
+	switch {
+	case circuit.state == circuitHalfOpen:
+		circuit.state, circuit.since = circuitOpen, now
+		return false
+	case circuit.state == circuitClosed && circuit.failures >= sb.threshold:
+		circuit.state, circuit.since = circuitOpen, now
+		metrics.Add(metricSendCircuitsOpened, 1)
+		setGauge(metricSendCircuitsOpen, sb.openLocked())
+		return true
+	default:
+		return false
+	}
+}
+
+// Success records a packet sent to the address, which closes its circuit and forgets its failures.
+func (sb *sendBreaker) Success(address string) {
+	if sb.threshold <= 0 {
+		return
+	}
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	circuit, ok := sb.circuits[address]
+	if !ok {
+		return
+	}
+	delete(sb.circuits, address)
+	if circuit.state != circuitClosed {
+		setGauge(metricSendCircuitsOpen, sb.openLocked())
+	}
+}
+
+// State returns the state of the circuit of the address.
+func (sb *sendBreaker) State(address string) circuitState {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	if circuit, ok := sb.circuits[address]; ok {
+		return circuit.state
+	}
+	return circuitClosed
+}
+
+// openLocked returns the number of circuits that are not closed, the caller must hold the mutex.
+func (sb *sendBreaker) openLocked() int {
+	open := 0
+	for _, circuit := range sb.circuits {
+		if circuit.state != circuitClosed {
+			open++
+		}
+	}
+	return open
+}
@@ -0,0 +1,85 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSendBreaker(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1000, 0)
+	const address = "127.0.0.1:7001"
+	t.Run("circuits open after consecutive failures", func(t *testing.T) {
+		t.Parallel()
+		sb := newSendBreaker(3, time.Second)
+		for i := 0; i < 2; i++ {
+			if sb.Failure(address, start) {
+				t.Fatalf("circuit opened after %d failures", i+1)
+			}
+			if !sb.Allow(address, start) {
+				t.Fatalf("packet was refused after %d failures", i+1)
+			}
+		}
+		if !sb.Failure(address, start) {
+			t.Fatal("circuit did not open after the third failure")
+		}
+		if sb.Allow(address, start.Add(999*time.Millisecond)) {
+			t.Error("packet was allowed within the cooldown")
+		}
+		if state := sb.State(address); state != circuitOpen {
+			t.Errorf("expected the circuit to be open, received %s", state)
+		}
+	})
+	t.Run("successes reset the failures", func(t *testing.T) {
+		t.Parallel()
+		sb := newSendBreaker(2, time.Second)
+		sb.Failure(address, start)
+		sb.Success(address)
+		if sb.Failure(address, start) {
+			t.Error("circuit opened although the failures were not consecutive")
+		}
+	})
+	t.Run("a single probe is allowed after the cooldown", func(t *testing.T) {
+		t.Parallel()
+		sb := newSendBreaker(1, time.Second)
+		sb.Failure(address, start)
+		if !sb.Allow(address, start.Add(time.Second)) {
+			t.Fatal("probe was refused after the cooldown")
+		}
+		if state := sb.State(address); state != circuitHalfOpen {
+			t.Errorf("expected the circuit to be half open, received %s", state)
+		}
+		if sb.Allow(address, start.Add(time.Second)) {
+			t.Error("second packet was allowed while probing")
+		}
+		sb.Success(address)
+		if state := sb.State(address); state != circuitClosed {
+			t.Errorf("expected the successful probe to close the circuit, received %s", state)
+		}
+	})
+	t.Run("failed probes open the circuit for another cooldown", func(t *testing.T) {
+		t.Parallel()
+		sb := newSendBreaker(3, time.Second)
+		for i := 0; i < 3; i++ {
+			sb.Failure(address, start)
+		}
+		sb.Allow(address, start.Add(time.Second))
+		sb.Failure(address, start.Add(time.Second))
+		if sb.Allow(address, start.Add(1500*time.Millisecond)) {
+			t.Error("packet was allowed within the cooldown after a failed probe")
+		}
+		if !sb.Allow(address, start.Add(2*time.Second)) {
+			t.Error("probe was refused after the second cooldown")
+		}
+	})
+	t.Run("a threshold of 0 disables the breaker", func(t *testing.T) {
+		t.Parallel()
+		sb := newSendBreaker(0, time.Second)
+		for i := 0; i < 10; i++ {
+			sb.Failure(address, start)
+		}
+		if !sb.Allow(address, start) {
+			t.Error("packet was refused with the breaker disabled")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package gossip
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+)
+
+// outboundPacket represents a packet queued for the send workers.
+type outboundPacket struct {
+	packetBytes      []byte
+	address          string
+	receiverIdentity Identity
+	// result receives the outcome of sending the packet, nil if nobody waits for it
+	result chan error
+}
+
+// startSendWorkers starts the configured number of workers signing, encrypting, and sending the queued outbound packets
+// until the server is closed.
+func (s *Server) startSendWorkers() {
+	workers := s.cfg.SendWorkers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	s.outbound = make(chan outboundPacket, s.cfg.SendQueueSize)
+	s.closed = make(chan struct{})
+	for i := 0; i < workers; i++ {
+		go s.runSendWorker()
+	}
+}
+
+// runSendWorker sends queued outbound packets until the server is closed.
+func (s *Server) runSendWorker() {
+	for {
+		select {
+		case packet := <-s.outbound:
+			err := s.writePacket(packet.packetBytes, packet.address, packet.receiverIdentity)
+			if packet.result != nil {
+				packet.result <- err
+			}
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// enqueuePacket queues a packet for the send workers, waiting while the queue is full.
+// Without send workers, i.e. before the server was started, the packet is sent on the calling goroutine.
+// Packets addressed to our own identity or address are dropped with ErrSelfAddressed.
+func (s *Server) enqueuePacket(packet outboundPacket) error {
+	if packet.receiverIdentity == s.ownNode.Identity || packet.address == s.ownNode.Address {
+		s.logger().Debug("Dropped gossip packet addressed to ourselves", peerID(packet.receiverIdentity), peerAddr(packet.address))
+		metrics.Add(metricPacketsSelfAddressed, 1)
+		return ErrSelfAddressed
+	}
+	if s.outbound == nil {
+		err := s.writePacket(packet.packetBytes, packet.address, packet.receiverIdentity)
+		if packet.result != nil {
+			packet.result <- err
+		}
+		return nil
+	}
+	select {
+	case s.outbound <- packet:
+		return nil
+	case <-s.closed:
+		metrics.Add(metricPacketsSendFailed, 1)
+		return fmt.Errorf("could not queue packet to %s: %w", packet.address, net.ErrClosed)
+	}
+}
+
+// sendBytes sends a packet to a select address and waits until it was signed, encrypted, and written.
+func (s *Server) sendBytes(packetBytes []byte, address string, receiverIdentity Identity) error {
+	result := make(chan error, 1)
+	if err := s.enqueuePacket(outboundPacket{packetBytes: packetBytes, address: address, receiverIdentity: receiverIdentity, result: result}); err != nil {
+		return err
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-s.closed:
+		return fmt.Errorf("could not send packet to %s: %w", address, net.ErrClosed)
+	}
+}
+
+// queueBytes queues a packet to a select address for the send workers without waiting for it to be sent.
+// Errors are logged and counted by the workers.
+func (s *Server) queueBytes(packetBytes []byte, address string, receiverIdentity Identity) {
+	_ = s.enqueuePacket(outboundPacket{packetBytes: packetBytes, address: address, receiverIdentity: receiverIdentity})
+}
@@ -3,12 +3,23 @@ package gossip
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"expvar"
+	"fmt"
 	"gossiphers/internal/api"
 	"gossiphers/internal/challenge"
+	"gossiphers/internal/clock"
 	"gossiphers/internal/config"
+	"gossiphers/internal/transport"
+	"io"
+	"math"
 	"net"
+	"os"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -17,44 +28,114 @@ import (
 // Server represents a udp listener with handlers for gossip-related messages.
 type Server struct {
 	cfg      *config.GossipConfig
-	listener net.PacketConn
+	listener transport.Transport
 	ownNode  *Node
+	// clock drives the ping timeouts and the timestamps of sent and received packets
+	clock clock.Clock
 
 	// Channels to send nodes to the gossip implementation upon receiving valid push or pull packets
 	pushNodes chan Node
 	pullNodes chan Node
 
+	// leftPeers holds the identities of the peers that left since the last call of TakeLeftPeers
+	leftPeers      map[Identity]struct{}
+	mutexLeftPeers sync.Mutex
+
+	// events receives the events of the gossip protocol, nil if events are not published
+	events *EventBus
+	// latencies holds the round trip times of pings and pull requests per peer
+	latencies *latencyTable
+	// peerStats holds the packets, bytes, last activity, and failures per peer
+	peerStats *peerStatsTable
+	// clockSkew estimates the offset of the local clock from the timestamps of the packets of the peers
+	clockSkew *clockSkewEstimator
+	// clockSkewed holds whether the estimated clock offset exceeded ClockSkewWarnMs, only accessed by ResetPeerStates
+	clockSkewed bool
+	// lastPeerContact holds the time in unix nanoseconds of the most recent packet received from any peer with a valid signature
+	lastPeerContact atomic.Int64
+	// roundStartedAt holds the time in unix nanoseconds the current round started
+	roundStartedAt atomic.Int64
+	// firstResponseAt holds the time in unix nanoseconds of the first response of a peer within the current round, 0 if none was received yet
+	firstResponseAt atomic.Int64
+	// roundLogger is the logger annotated with the ID of the current round, nil before the first round
+	roundLogger atomic.Pointer[zap.Logger]
+
 	// List of nodes used in pull responses to other peers
-	pullResponseNodes      []Node
+	pullResponseNodes []Node
+	// pinnedNodes are offered in every pull response, in addition to a selection of the pull response nodes
+	pinnedNodes            []Node
 	mutexPullResponseNodes sync.RWMutex
 
-	// Communication state with other peers, map from string(peerID) to list of conditional states the peer currently meets
-	peerState      map[string][]peerCondition
+	// Communication state with other peers, map from peer identity to list of conditional states the peer currently meets
+	peerState      map[Identity][]peerCondition
 	mutexPeerState sync.RWMutex
+	// capabilities are the capabilities peers advertised within their latest push, see peerCapabilities
+	capabilities      map[Identity]Capabilities
+	mutexCapabilities sync.RWMutex
 
-	// Channels used internally to resolve ping calls with the corresponding pong
-	pongChannels      map[string]chan struct{}
-	mutexPongChannels sync.RWMutex
+	// Channels used internally to resolve ping calls with the pong echoing their nonce
+	pongChannels      map[outstandingPing]chan struct{}
+	mutexPongChannels sync.Mutex
+	// random generates the nonces of pings
+	random io.Reader
 
+	// pushes tracks the progress of our pushes to peers within the current round
+	pushes *pushTracker
+	// pulls tracks our pull requests to peers within the current round
+	pulls *pullTracker
+	// messageRequests tracks the messages we requested from peers within the current round after they announced them
+	messageRequests *messageRequestTracker
 	// challenger implementation to generate and verify computational puzzles
-	challenger            *challenge.Challenger
+	challenger            Challenger
 	challengeDifficulty   uint32
 	challengeMaxSolveTime time.Duration
 
 	// internal state of messages that are currently spread by this gossip module
-	messagesToSpread []spreadableMessage
-	mutexMessages    sync.RWMutex
+	messages *messageStore
+	// seen remembers accepted messages after their eviction from the message store, nil if disabled
+	seen *seenFilter
+	// delayed holds messages of peers exceeding the flood threshold whose flood action is delay, until the peer falls below the threshold again
+	delayed       map[Identity][]delayedMessage
+	mutexMessages sync.RWMutex
+
+	// deliveries tracks the propagation of messages announced by local API clients
+	deliveries      *deliveryTracker
+	mutexDeliveries sync.Mutex
 
 	apiServer *api.Server
-	crypto    *Crypto
+	crypto    PacketCrypto
+	// protection is the order packets are signed and encrypted in, selected by the configured protocol version
+	protection packetProtection
+
+	// eagerPeers are the peers receiving full messages in the current round if lazy spreading is enabled, all other peers only receive announcements
+	eagerPeers      map[Identity]struct{}
+	mutexEagerPeers sync.RWMutex
+
+	// resolver caches the resolution of peer addresses, which may be hostnames
+	resolver *addressResolver
+	// breaker stops sending to addresses we repeatedly failed to resolve or write to for a cooldown
+	breaker *sendBreaker
+	// quarantine drops the packets of addresses that repeatedly sent invalid signatures or failed challenges before decryption
+	quarantine *quarantine
+
+	// packetHandlers and packetMiddlewares are registered per packet type and for all types, dispatchHandlers combines them
+	packetHandlers    map[MessageType]PacketHandler
+	packetMiddlewares []PacketMiddleware
+	dispatchHandlers  map[MessageType]PacketHandler
+
+	// outbound queues the packets for the send workers, nil until the server was started
+	outbound chan outboundPacket
+	// closed is closed once the server is closed to stop the send workers
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
 // spreadableMessage is the internal representation for a gossip message that will be exchanged with other nodes
 // when received the TTL is decreased by 1, once it reaches 1 it is no longer forwarded
-// A TTL or 0 indicates unlimited hops.
+// A TTL or 0 indicates unlimited hops, such messages are spread for the configured InfiniteTTLMaxRounds.
 // The LocalTTL tracks for how many more cycles this peer will try and exchange this message with other nodes.
 // Messages with a LocalTTL smaller or equal to 0 will no longer be forwarded,
-// once they reach -24 they will be evicted from the local cache which also prevents them from being received multiple times.
+// once they reach the configured MessageLocalTTLFloor (-24 by default) they will be evicted from the local cache which also prevents them from being received multiple times.
 type spreadableMessage struct {
 	LocalTTL       int
 	TTL            uint8
@@ -62,104 +143,572 @@ type spreadableMessage struct {
 	Data           []byte
 	DataHash       []byte
 	SourceIdentity Identity
+	// ReceivedAt is the time the message was received from a peer or announced by a local API client
+	ReceivedAt time.Time
+	// OriginAt is the time the message was announced at by its origin, the zero time if the peer it was received from did not carry it
+	OriginAt time.Time
+	// ExpiresAt is the time at which the message stops being spread regardless of its TTL, the zero time if it never expires
+	ExpiresAt time.Time
+	// Deprioritized messages were accepted from a peer exceeding the flood threshold and are spread after all other messages
+	Deprioritized bool
+	// HighPriority messages belong to the high-priority lane, they are spread before all bulk messages and never batched
+	HighPriority bool
+}
+
+// flags returns the flags of the message as sent within message packets.
+func (m *spreadableMessage) flags() uint8 {
+	if m.HighPriority {
+		return MessageFlagHighPriority
+	}
+	return 0
+}
+
+// inHighPriorityLane checks whether the message is spread within the high-priority lane.
+// Deprioritized messages are always spread within the bulk lane, regardless of their flags.
+func (m *spreadableMessage) inHighPriorityLane() bool {
+	return m.HighPriority && !m.Deprioritized
+}
+
+// isExpired checks whether the message passed its expiry time.
+func (m *spreadableMessage) isExpired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && now.After(m.ExpiresAt)
+}
+
+// delayedMessage is a received message held back to prevent message flooding.
+type delayedMessage struct {
+	packet   PacketMessage
+	dataHash []byte
+}
+
+var (
+	ErrPingTimeout   = errors.New("no pong received")
+	ErrSelfAddressed = errors.New("packet addressed to ourselves")
+	ErrCircuitOpen   = errors.New("circuit of the address is open after repeated send failures")
+)
+
+// Challenger generates the computational puzzles peers solve before pushing their identity to us, and verifies the solutions.
+// *challenge.Challenger implements it, tests may replace it with a stub.
+type Challenger interface {
+	// NewChallenge returns a challenge for the peer with the given identity.
+	NewChallenge(identity []byte) ([]byte, error)
+	// IsSolvedCorrectly checks that the challenge was issued to the peer with the given identity and that the nonce solves it with the given difficulty.
+	IsSolvedCorrectly(challenge []byte, nonce []byte, identity []byte, difficulty int) (bool, error)
+}
+
+// NewChallenger returns a challenger rotating its keys as configured, driven by the configured clock and source of randomness.
+func NewChallenger(cfg *config.GossipConfig) (*challenge.Challenger, error) {
+	return challenge.NewChallenger(time.Millisecond*time.Duration(cfg.ChallengeRotationIntervalMs), cfg.ChallengeRotationKeys,
+		challenge.WithClock(clock.Or(cfg.Clock)), challenge.WithRandom(randomOr(cfg.Random)))
 }
 
 // A peerCondition is a flag representing a communication state with a remote peer
 type peerCondition int
 
+// outstandingPing identifies a ping waiting for its pong by the pinged peer and the nonce of the ping.
+type outstandingPing struct {
+	identity Identity
+	nonce    uint64
+}
+
 const (
-	AllowPull peerCondition = iota
-	AllowMessage
-	AllowPushChallenge
+	AllowMessage peerCondition = iota
 	DenyPush
+	AllowDigestRequest
+	// DenyDigest marks a peer whose digest was already answered within the current round
+	DenyDigest
 )
 
-// NewServer returns a new instance of Server.
-func NewServer(cfg *config.GossipConfig, pushNodes chan Node, pullNodes chan Node, gCrypto *Crypto, apiServer *api.Server) (*Server, error) {
-	challenger, err := challenge.NewChallenger(time.Second*15, 4)
-	if err != nil {
-		return nil, err
+// String returns the name of a peer condition.
+func (c peerCondition) String() string {
+	switch c {
+	case AllowMessage:
+		return "allow_message"
+	case DenyPush:
+		return "deny_push"
+	case AllowDigestRequest:
+		return "allow_digest_request"
+	case DenyDigest:
+		return "deny_digest"
+	default:
+		return "unknown"
 	}
+}
 
+// NewServer returns a new instance of Server, which encrypts and signs packets with gCrypto and challenges pushing peers with challenger.
+func NewServer(cfg *config.GossipConfig, pushNodes chan Node, pullNodes chan Node, gCrypto PacketCrypto, challenger Challenger, apiServer *api.Server) (*Server, error) {
 	ownIdentity, err := generateIdentity(&cfg.PrivateKey.PublicKey)
 	if err != nil {
 		return nil, err
 	}
-	ownNode, err := NewNode([]byte(*ownIdentity), cfg.GossipAddress)
+	ownNode, err := NewNode(ownIdentity.ToBytes(), cfg.GossipAddress)
+	if err != nil {
+		return nil, err
+	}
+	protection, err := protectionOf(uint8(cfg.ProtocolVersion))
 	if err != nil {
 		return nil, err
 	}
+	pinnedNodes, err := loadPinnedNodes(cfg.PinnedNodesStr, ownNode.Identity)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pinned nodes: %w", err)
+	}
+	if cfg.ProtocolVersion >= int(CapabilitiesVersion) {
+		ownNode.Capabilities = localCapabilities(cfg)
+	}
 
 	server := Server{
 		cfg:                   cfg,
 		ownNode:               ownNode,
+		pinnedNodes:           pinnedNodes,
+		clock:                 clock.Or(cfg.Clock),
 		pushNodes:             pushNodes,
 		pullNodes:             pullNodes,
-		peerState:             make(map[string][]peerCondition),
-		pongChannels:          make(map[string]chan struct{}),
+		peerState:             make(map[Identity][]peerCondition),
+		capabilities:          make(map[Identity]Capabilities),
+		pongChannels:          make(map[outstandingPing]chan struct{}),
+		random:                randomOr(cfg.Random),
+		leftPeers:             make(map[Identity]struct{}),
+		pushes:                newPushTracker(time.Millisecond * time.Duration(cfg.PushChallengeTimeoutMs)),
+		pulls:                 newPullTracker(time.Millisecond * time.Duration(cfg.PullTimeoutMs)),
+		messageRequests:       newMessageRequestTracker(MaxDigestEntries),
 		challenger:            challenger,
 		challengeDifficulty:   uint32(cfg.ChallengeDifficulty),
 		challengeMaxSolveTime: time.Millisecond * time.Duration(cfg.ChallengeMaxSolveMs),
 		apiServer:             apiServer,
 		crypto:                gCrypto,
+		protection:            protection,
+		resolver:              newAddressResolver(time.Millisecond * time.Duration(cfg.DNSRefreshIntervalMs)),
+		breaker:               newSendBreaker(cfg.SendFailureThreshold, time.Millisecond*time.Duration(cfg.SendCircuitCooldownMs)),
+		quarantine:            newQuarantine(cfg.QuarantineThreshold, time.Millisecond*time.Duration(cfg.QuarantineMs)),
+		messages:              newMessageStore(cfg.MessageStoreMaxMessages, cfg.MessageStoreMaxBytes, cfg.DataTypePolicies),
+		packetHandlers:        make(map[MessageType]PacketHandler),
+		delayed:               make(map[Identity][]delayedMessage),
+		deliveries:            newDeliveryTracker(),
+		latencies:             newLatencyTable(cfg.LatencyWindowSize),
+		peerStats:             newPeerStatsTable(),
+		clockSkew:             newClockSkewEstimator(),
+	}
+	server.registerCoreHandlers()
+	// bound the share of the message store that messages with infinite hops may occupy
+	server.messages.maxInfiniteMessages = cfg.MessageStoreMaxMessages * cfg.InfiniteTTLMaxStorePercent / 100
+	if cfg.SeenFilterCapacity > 0 {
+		server.seen = newSeenFilter(cfg.SeenFilterCapacity)
+	}
+	if cfg.MessageStoreFile != "" {
+		server.loadMessages(cfg.MessageStoreFile)
 	}
 
 	// Automatically spread messages given to us by API clients
-	server.apiServer.RegisterGossipAnnounceHandler(func(ttl uint8, dataType uint16, data []byte) {
-		zap.L().Info("Spreading Gossip Message from local API client", zap.Uint16("data_type", dataType), zap.Uint8("ttl", ttl))
-		server.spreadMessage(ttl, dataType, data)
+	server.apiServer.RegisterGossipAnnounceHandler(func(ttl uint8, dataType uint16, data []byte, highPriority bool) {
+		server.logger().Info("Spreading Gossip Message from local API client", zap.Uint16("data_type", dataType), zap.Uint8("ttl", ttl), zap.Bool("high_priority", highPriority))
+		server.spreadMessage(ttl, dataType, data, highPriority)
 	})
+	server.apiServer.RegisterGossipStatusHandler(server.DeliveryStatus)
+	server.apiServer.RegisterGossipLatencyHandler(server.apiLatencies)
+	server.apiServer.RegisterGossipPeerStatsHandler(server.apiPeerStats)
+	server.apiServer.RegisterGossipQuarantineHandler(server.apiQuarantine)
+	metrics.Set(metricPeerLatencies, expvar.Func(func() any {
+		latencies := make(map[string]PeerLatency)
+		for _, latency := range server.Latencies() {
+			latencies[latency.Identity.String()] = latency
+		}
+		return latencies
+	}))
+	metrics.Set(metricPeerStats, expvar.Func(func() any {
+		stats := make(map[string]PeerStats)
+		for _, peer := range server.PeerStats() {
+			stats[peer.Identity.String()] = peer
+		}
+		return stats
+	}))
+	metrics.Set(metricDeliveries, expvar.Func(func() any { return server.DeliveryCounts() }))
 
 	return &server, nil
 }
 
-// Start starts the UDP listener at the configured address
+// startRound annotates the log entries of the server with the ID of a round started by the gossip protocol, so the entries
+// logged while handling the packets of the round, e.g. from goroutines of the listener, can be attributed to it.
+func (s *Server) startRound(roundID int) {
+	s.roundLogger.Store(logger().With(zap.Int("round_id", roundID)))
+	setGauge(metricRoundID, roundID)
+}
+
+// logger returns the logger of the gossip subsystem annotated with the ID of the current round.
+func (s *Server) logger() *zap.Logger {
+	if roundLogger := s.roundLogger.Load(); roundLogger != nil {
+		return roundLogger
+	}
+	return logger()
+}
+
+// Start starts the UDP listener at the configured address, or a listener of the configured network.
 func (s *Server) Start() error {
-	listener, err := net.ListenPacket("udp", s.cfg.GossipAddress)
+	listener, err := transport.Or(s.cfg.Network).Listen(s.cfg.GossipAddress)
 	if err != nil {
 		return err
 	}
 	s.listener = listener
+	s.startSendWorkers()
 
-	zap.L().Info("Gossip Server listening", zap.String("address", s.cfg.GossipAddress))
+	s.logger().Info("Gossip Server listening", zap.String("address", s.cfg.GossipAddress))
 	go s.listenForPackets()
 	return nil
 }
 
+// Close stops listening for packets and stops the send workers, dropping the packets still queued.
+func (s *Server) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	s.closeOnce.Do(func() { close(s.closed) })
+	return s.listener.Close()
+}
+
+// SendLeave tells a node that we are shutting down, so it stops contacting us.
+func (s *Server) SendLeave(node *Node) error {
+	packet, err := NewPacketLeave(s.ownNode.Identity, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	return s.sendBytes(packet.ToBytes(), node.Address, node.Identity)
+}
+
+// TakeLeftPeers returns the identities of the peers that announced leaving since the last call and forgets them.
+func (s *Server) TakeLeftPeers() []Identity {
+	s.mutexLeftPeers.Lock()
+	defer s.mutexLeftPeers.Unlock()
+	identities := make([]Identity, 0, len(s.leftPeers))
+	for identity := range s.leftPeers {
+		identities = append(identities, identity)
+	}
+	s.leftPeers = make(map[Identity]struct{})
+	return identities
+}
+
+// LastPeerContact returns the time the most recent packet with a valid signature was received from any peer, the zero time if none was received yet.
+func (s *Server) LastPeerContact() time.Time {
+	nanos := s.lastPeerContact.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// markResponse records the arrival of a response of a peer to one of our requests, keeping only the first one of each round.
+func (s *Server) markResponse() {
+	s.firstResponseAt.CompareAndSwap(0, s.clock.Now().UnixNano())
+}
+
+// FirstResponseDelay returns the time from the start of the current round until the first response of a peer to a ping, pull, or push request, 0 if no peer responded yet.
+func (s *Server) FirstResponseDelay() time.Duration {
+	first := s.firstResponseAt.Load()
+	if first == 0 {
+		return 0
+	}
+	return time.Duration(first - s.roundStartedAt.Load())
+}
+
+// Latencies returns the round trip times of pings and pull requests measured to each peer.
+func (s *Server) Latencies() []PeerLatency {
+	return s.latencies.Snapshot()
+}
+
+// apiLatencies returns the round trip times measured to each peer in their representation within the API.
+func (s *Server) apiLatencies() []api.PeerLatency {
+	latencies := s.Latencies()
+	apiLatencies := make([]api.PeerLatency, 0, len(latencies))
+	for _, latency := range latencies {
+		apiLatencies = append(apiLatencies, api.PeerLatency{
+			Identity:    latency.Identity.ToBytes(),
+			PingSamples: uint16(latency.Ping.Samples),
+			PullSamples: uint16(latency.Pull.Samples),
+			PingMedian:  latency.Ping.Median,
+			PingP90:     latency.Ping.P90,
+			PullMedian:  latency.Pull.Median,
+			PullP90:     latency.Pull.P90,
+		})
+	}
+	return apiLatencies
+}
+
+// PeerStats returns the packets, bytes, last activity, and failures recorded per peer.
+func (s *Server) PeerStats() []PeerStats {
+	return s.peerStats.Snapshot()
+}
+
+// apiPeerStats returns the statistics recorded per peer in their representation within the API, packet types in ascending order.
+func (s *Server) apiPeerStats() []api.PeerStats {
+	stats := s.PeerStats()
+	apiStats := make([]api.PeerStats, 0, len(stats))
+	for _, peer := range stats {
+		packets := make([]api.PacketCounts, 0, len(peer.Packets))
+		for packetType, counts := range peer.Packets {
+			packets = append(packets, api.PacketCounts{PacketType: uint16(packetType), Received: saturateUint32(counts.Received), Sent: saturateUint32(counts.Sent)})
+		}
+		sort.Slice(packets, func(i, j int) bool { return packets[i].PacketType < packets[j].PacketType })
+		apiStats = append(apiStats, api.PeerStats{
+			Identity:       peer.Identity.ToBytes(),
+			LastActivity:   peer.LastActivity,
+			BytesReceived:  peer.BytesReceived,
+			BytesSent:      peer.BytesSent,
+			SendFailures:   saturateUint32(peer.SendFailures),
+			InvalidPackets: saturateUint32(peer.InvalidPackets),
+			Packets:        packets,
+		})
+	}
+	return apiStats
+}
+
+// Quarantine returns the addresses whose packets are currently dropped after repeated invalid signatures or failed challenges.
+func (s *Server) Quarantine() []QuarantinedPeer {
+	return s.quarantine.Snapshot(s.clock.Now())
+}
+
+// apiQuarantine returns the quarantined addresses in their representation within the API.
+func (s *Server) apiQuarantine() []api.QuarantinedPeer {
+	now := s.clock.Now()
+	quarantined := s.Quarantine()
+	apiQuarantined := make([]api.QuarantinedPeer, 0, len(quarantined))
+	for _, peer := range quarantined {
+		failures := peer.Failures
+		if failures > math.MaxUint16 {
+			failures = math.MaxUint16
+		}
+		apiQuarantined = append(apiQuarantined, api.QuarantinedPeer{
+			Address:   peer.Address,
+			Identity:  peer.Identity.ToBytes(),
+			Remaining: peer.Until.Sub(now),
+			Failures:  uint16(failures),
+		})
+	}
+	return apiQuarantined
+}
+
+// saturateUint32 converts a counter to an uint32, saturating at its maximum value.
+func saturateUint32(count int) uint32 {
+	if count > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(count)
+}
+
 // ResetPeerStates should be called between two gossip rounds, clearing the servers internal state for peers and decaying messages
+// Delayed messages of peers that fell below the flood threshold are accepted afterwards.
 func (s *Server) ResetPeerStates() {
+	s.roundStartedAt.Store(s.clock.Now().UnixNano())
+	s.firstResponseAt.Store(0)
 	s.mutexPeerState.Lock()
-	s.peerState = make(map[string][]peerCondition)
+	s.peerState = make(map[Identity][]peerCondition)
 	s.mutexPeerState.Unlock()
+	s.pushes.Reset()
+	s.pulls.Reset()
+	s.messageRequests.Reset()
 
-	// decay local message TTL, delete messages with TTL=0
+	// decay local message TTL, delete messages that reached the floor
+	s.mutexMessages.Lock()
+	now := s.clock.Now()
+	s.messages.Expire(now)
+	s.messages.Decay(s.cfg.MessageLocalTTLFloor)
+	if s.cfg.InfiniteTTLMaxAgeMs > 0 {
+		s.messages.EvictInfiniteOlderThan(time.Millisecond*time.Duration(s.cfg.InfiniteTTLMaxAgeMs), now)
+	}
+	released := s.releaseDelayedMessages()
+	// stop tracking the delivery of messages that were evicted
+	s.mutexDeliveries.Lock()
+	s.deliveries.Prune(func(key messageKey) bool {
+		return s.messages.Contains(key.dataType, []byte(key.dataHash))
+	})
+	s.mutexDeliveries.Unlock()
+	s.latencies.Prune(s.clock.Now())
+	s.peerStats.Prune(s.clock.Now())
+	s.updateClockSkew()
+	setGauge(metricMessagesStored, s.messages.Len())
+	setGauge(metricMessageStoreBytes, s.messages.Bytes())
+	s.mutexMessages.Unlock()
+
+	for _, msg := range released {
+		s.notifyMessage(msg.packet, msg.dataHash)
+	}
+}
+
+// expiresAt returns the expiry time of a message of the given data type announced by its origin at the given time, the zero time if such messages never expire.
+func (s *Server) expiresAt(dataType uint16, originAt time.Time) time.Time {
+	expiryMs := s.cfg.MessageExpiryMs
+	if policy := s.cfg.DataTypePolicies[dataType]; policy.ExpiryMs > 0 {
+		expiryMs = policy.ExpiryMs
+	}
+	if expiryMs == 0 {
+		return time.Time{}
+	}
+	return originAt.Add(time.Millisecond * time.Duration(expiryMs))
+}
+
+// floodPolicy returns the flood threshold and action that apply to messages of the given data type.
+// If the data type has its own threshold, only messages of that data type count towards it, otherwise all messages of a peer do.
+func (s *Server) floodPolicy(dataType uint16) (threshold int, perType bool, action string) {
+	policy := s.cfg.DataTypePolicies[dataType]
+	threshold, action = s.cfg.MessageFloodThreshold, s.cfg.MessageFloodAction
+	if policy.FloodThreshold > 0 {
+		threshold, perType = policy.FloodThreshold, true
+	}
+	if policy.FloodAction != "" {
+		action = policy.FloodAction
+	}
+	return threshold, perType, action
+}
+
+// isFlooding checks whether a peer exceeds the flood threshold for messages of the given data type and returns the flood action that applies, the caller must hold the message mutex.
+func (s *Server) isFlooding(source Identity, dataType uint16) (bool, string) {
+	threshold, perType, action := s.floodPolicy(dataType)
+	count := s.messages.CountFromSource(source)
+	if perType {
+		count = s.messages.CountFromSourceOfType(source, dataType)
+	}
+	return count > threshold, action
+}
+
+// delayMessage holds back a message until its source falls below the flood threshold, the caller must hold the message mutex.
+// At most threshold many messages are held back per peer, returns false if the message was not held back.
+func (s *Server) delayMessage(packet PacketMessage, dataHash []byte) bool {
+	threshold, _, _ := s.floodPolicy(packet.DataType)
+	queue := s.delayed[packet.SenderIdentity]
+	for _, msg := range queue {
+		if msg.packet.DataType == packet.DataType && bytes.Equal(msg.dataHash, dataHash) {
+			return true
+		}
+	}
+	if len(queue) >= threshold {
+		return false
+	}
+	s.delayed[packet.SenderIdentity] = append(queue, delayedMessage{packet: packet, dataHash: dataHash})
+	return true
+}
+
+// releaseDelayedMessages stores the delayed messages whose source no longer exceeds the flood threshold and returns them, the caller must hold the message mutex.
+// Delayed messages that became known in the meantime are discarded.
+func (s *Server) releaseDelayedMessages() []delayedMessage {
+	var released []delayedMessage
+	for source, queue := range s.delayed {
+		var remaining []delayedMessage
+		for _, msg := range queue {
+			added, floodAction := s.storeMessage(msg.packet, msg.dataHash)
+			if added {
+				released = append(released, msg)
+			} else if floodAction == config.FloodActionDelay {
+				remaining = append(remaining, msg)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(s.delayed, source)
+		} else {
+			s.delayed[source] = remaining
+		}
+	}
+	return released
+}
+
+// loadMessages restores the message store persisted at path. A missing or broken file only results in an empty store.
+func (s *Server) loadMessages(path string) {
 	s.mutexMessages.Lock()
 	defer s.mutexMessages.Unlock()
-	var newMessages []spreadableMessage
-	for _, msg := range s.messagesToSpread {
-		msg.LocalTTL--
-		if msg.LocalTTL > -24 {
-			newMessages = append(newMessages, msg)
+	loaded, err := s.messages.Load(path, s.clock.Now())
+	if errors.Is(err, os.ErrNotExist) {
+		s.logger().Info("No messages to restore", zap.String("path", path))
+		return
+	}
+	if err != nil {
+		s.logger().Warn("Could not restore messages, starting with an empty message store", zap.String("path", path), zap.Error(err))
+		return
+	}
+	if s.seen != nil {
+		for _, msg := range loaded {
+			s.seen.Add(messageKey{msg.DataType, string(msg.DataHash)})
 		}
 	}
-	s.messagesToSpread = newMessages
+	s.logger().Info("Restored messages", zap.String("path", path), zap.Int("messages", len(loaded)))
+}
+
+// DeliveryStatus returns the number of distinct peers known to have received the message with the given data type and data hash,
+// which must have been announced by a local API client. Returns false if the message is unknown or was already evicted.
+func (s *Server) DeliveryStatus(dataType uint16, dataHash []byte) (int, bool) {
+	s.mutexDeliveries.Lock()
+	defer s.mutexDeliveries.Unlock()
+	return s.deliveries.Count(messageKey{dataType, string(dataHash)})
+}
+
+// DeliveryCounts returns the delivery counts of all messages announced by local API clients that are still stored.
+func (s *Server) DeliveryCounts() []DeliveryCount {
+	s.mutexDeliveries.Lock()
+	defer s.mutexDeliveries.Unlock()
+	return s.deliveries.Counts()
+}
+
+// recordDeliveries records that a peer received the messages of the given entries, if they were announced by local API clients.
+func (s *Server) recordDeliveries(peer Identity, entries []DigestEntry) {
+	s.mutexDeliveries.Lock()
+	defer s.mutexDeliveries.Unlock()
+	for _, entry := range entries {
+		s.deliveries.Record(messageKey{entry.DataType, string(entry.DataHash)}, peer)
+	}
+}
+
+// SaveMessages persists the message store including the remaining TTLs to the file at path.
+func (s *Server) SaveMessages(path string) error {
+	s.mutexMessages.RLock()
+	defer s.mutexMessages.RUnlock()
+	return s.messages.Save(path)
 }
 
 // UpdatePullResponseNodes should be called by the gossip logic to update the nodes used in pull responses regularly
+// Our own node is never part of pull responses, peers learn about it through pushes only. Each pull request is answered with
+// a selection of these nodes according to the configured pull response strategy.
 func (s *Server) UpdatePullResponseNodes(nodes []Node) {
+	responseNodes := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Identity == s.ownNode.Identity {
+			continue
+		}
+		responseNodes = append(responseNodes, node)
+	}
 	s.mutexPullResponseNodes.Lock()
-	s.pullResponseNodes = nodes
+	s.pullResponseNodes = responseNodes
 	s.mutexPullResponseNodes.Unlock()
 }
 
+// SetEagerPeers sets the peers which receive full messages until the next call if lazy spreading is enabled.
+// All other peers only receive announcements of the spread messages and request the ones they are missing.
+func (s *Server) SetEagerPeers(nodes []*Node) {
+	eagerPeers := make(map[Identity]struct{}, len(nodes))
+	for _, node := range nodes {
+		eagerPeers[node.Identity] = struct{}{}
+	}
+	s.mutexEagerPeers.Lock()
+	s.eagerPeers = eagerPeers
+	s.mutexEagerPeers.Unlock()
+}
+
+// isEagerPeer checks whether full messages are sent to the peer with the given identity.
+func (s *Server) isEagerPeer(identity Identity) bool {
+	if s.cfg.EagerFanout == 0 {
+		return true
+	}
+	s.mutexEagerPeers.RLock()
+	defer s.mutexEagerPeers.RUnlock()
+	_, ok := s.eagerPeers[identity]
+	return ok
+}
+
 // listenForPackets accepts network packets and forwards them to handlers
 func (s *Server) listenForPackets() {
 	defer s.listener.Close()
 	for {
-		buf := make([]byte, 65535+s.cfg.PrivateKey.Size())
+		buf := make([]byte, MaxPacketSize+s.protection.overhead(s.crypto))
 		numBytes, fromAddr, err := s.listener.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
 		if err != nil {
-			zap.L().Warn("Error reading gossip packet from UDP socket", zap.Error(err))
+			s.logger().Warn("Error reading gossip packet from UDP socket", zap.Error(err))
 			continue
 		}
 		packetBytes := buf[:numBytes]
@@ -168,142 +717,107 @@ func (s *Server) listenForPackets() {
 	}
 }
 
+// rejectPacket counts a rejected packet and publishes a PacketRejectedEvent.
+func (s *Server) rejectPacket(fromAddr net.Addr, reason RejectReason) {
+	metrics.Add(metricPacketsRejected, 1)
+	s.events.Publish(PacketRejectedEvent{FromAddress: fromAddr.String(), Reason: reason})
+}
+
 // handleIncomingBytes determines the request type of the packet by means of the header and handles it accordingly.
 func (s *Server) handleIncomingBytes(packetBytes []byte, fromAddr net.Addr) {
-	if len(packetBytes) < PacketHeaderSize+SignatureSize+s.cfg.PrivateKey.Size() {
-		zap.L().Info("Received gossip packet with invalid length")
-		return
-	}
-	decryptedBytes, err := s.crypto.DecryptPacket(packetBytes)
-	if err != nil {
-		zap.L().Warn("Could not decrypt received gossip packet", zap.Error(err))
+	metrics.Add(metricPacketsReceived, 1)
+	decryptedBytes, header, ok := s.openPacket(packetBytes, fromAddr)
+	if !ok {
 		return
 	}
+	s.lastPeerContact.Store(s.clock.Now().UnixNano())
+	s.peerStats.Received(header.SenderIdentity, header.Type, len(packetBytes), s.clock.Now())
 
-	header, err := ParsePacketHeader(decryptedBytes[:PacketHeaderSize])
-	if err != nil {
-		zap.L().Info("Received gossip packet with invalid header", zap.Error(err))
+	s.logger().Debug("Received valid Gossip Packet", zap.String("type", strconv.FormatInt(int64(header.Type), 16)), peerID(header.SenderIdentity), peerAddr(fromAddr.String()))
+	if s.ignoresRequests(header.Type) || s.refusesRequest(header.Type) {
 		return
 	}
-
-	if time.UnixMilli(int64(header.Timestamp)).Add(time.Second * 8).Before(time.Now()) {
-		zap.L().Info("Received and ignored gossip packet with old timestamp", zap.Uint64("packet_time", header.Timestamp), zap.Int64("local_time", time.Now().UnixMilli()))
+	handler, ok := s.dispatchHandlers[header.Type]
+	if !ok {
+		s.logger().Debug("Received gossip packet of unknown type", zap.String("type", strconv.FormatInt(int64(header.Type), 16)), peerID(header.SenderIdentity))
 		return
 	}
-
-	err = s.crypto.VerifySignature(decryptedBytes[:len(decryptedBytes)-SignatureSize], decryptedBytes[len(decryptedBytes)-SignatureSize:], header.SenderIdentity)
-	if err != nil {
-		zap.L().Info("Signature on received gossip packet could not be validated", zap.Error(err), zap.String("sender_address", fromAddr.String()))
-		return
-	}
-
-	zap.L().Debug("Received valid Gossip Packet", zap.String("type", strconv.FormatInt(int64(header.Type), 16)), zap.String("from_identity", header.SenderIdentity.String()), zap.String("from_address", fromAddr.String()))
-	switch header.Type {
-	case MessageTypeGossipPing:
-		packet := PacketPing{}
-		err = packet.Parse(header, bytes.NewReader(decryptedBytes[PacketHeaderSize:]))
-		if err != nil {
-			break
-		}
-		s.handlePing(fromAddr, packet)
-	case MessageTypeGossipPong:
-		packet := PacketPong{}
-		err = packet.Parse(header, bytes.NewReader(decryptedBytes[PacketHeaderSize:]))
-		if err != nil {
-			break
-		}
-		s.handlePong(fromAddr, packet)
-	case MessageTypeGossipPullRequest:
-		packet := PacketPullRequest{}
-		err = packet.Parse(header, bytes.NewReader(decryptedBytes[PacketHeaderSize:]))
-		if err != nil {
-			break
-		}
-		s.handlePullRequest(fromAddr, packet)
-	case MessageTypeGossipPullResponse:
-		packet := PacketPullResponse{}
-		err = packet.Parse(header, bytes.NewReader(decryptedBytes[PacketHeaderSize:]))
-		if err != nil {
-			break
-		}
-		s.handlePullResponse(fromAddr, packet)
-	case MessageTypeGossipPushRequest:
-		packet := PacketPushRequest{}
-		err = packet.Parse(header, bytes.NewReader(decryptedBytes[PacketHeaderSize:]))
-		if err != nil {
-			break
-		}
-		s.handlePushRequest(fromAddr, packet)
-	case MessageTypeGossipPushChallenge:
-		packet := PacketPushChallenge{}
-		err = packet.Parse(header, bytes.NewReader(decryptedBytes[PacketHeaderSize:]))
-		if err != nil {
-			break
-		}
-		s.handlePushChallenge(fromAddr, packet)
-	case MessageTypeGossipPush:
-		packet := PacketPush{}
-		err = packet.Parse(header, bytes.NewReader(decryptedBytes[PacketHeaderSize:]))
-		if err != nil {
-			break
-		}
-		s.handlePush(fromAddr, packet)
-	case MessageTypeGossipMessage:
-		packet := PacketMessage{}
-		err = packet.Parse(header, bytes.NewReader(decryptedBytes[PacketHeaderSize:]))
-		if err != nil {
-			break
-		}
-		s.handleMessage(fromAddr, packet)
-	}
-	if err != nil {
-		zap.L().Info("Received gossip packet with invalid content", zap.Error(err), zap.String("source_identity", header.SenderIdentity.String()))
+	if err := handler(fromAddr, header, bytes.NewReader(decryptedBytes[PacketHeaderSize:])); err != nil {
+		s.logger().Info("Received gossip packet with invalid content", zap.Error(err), peerID(header.SenderIdentity))
+		s.peerStats.Invalid(header.SenderIdentity, s.clock.Now())
 		return
 	}
 }
 
-// sendBytes sends a packet to a select address.
-func (s *Server) sendBytes(packetBytes []byte, address string, receiverIdentity Identity) error {
-	// Sign
-	signature, err := s.crypto.Sign(packetBytes)
-	if err != nil {
-		zap.L().Warn("Error signing outgoing packet", zap.Error(err), zap.String("target_addr", address))
-		return err
+// writePacket timestamps, signs, and encrypts a packet and writes it to a select address.
+// Packets to addresses whose circuit is open are dropped with ErrCircuitOpen before they are signed.
+func (s *Server) writePacket(packetBytes []byte, address string, receiverIdentity Identity) error {
+	if !s.breaker.Allow(address, s.clock.Now()) {
+		metrics.Add(metricPacketsCircuitOpen, 1)
+		s.logger().Debug("Dropped gossip packet to peer with open send circuit", peerID(receiverIdentity), peerAddr(address))
+		return ErrCircuitOpen
 	}
-	signedBytes := append(packetBytes, signature...)
+	// Timestamp the packet with the time of the server's clock, which the receiver checks the packet's age against
+	packetBytes = append([]byte(nil), packetBytes...)
+	binary.BigEndian.PutUint64(packetBytes[4:12], uint64(s.clock.Now().UnixMilli()))
 
-	// RSA Encrypt
-	encryptedBytes, err := s.crypto.EncryptPacket(signedBytes, receiverIdentity)
+	encryptedBytes, err := s.protection.seal(s.crypto, packetBytes, s.ownNode.Identity, receiverIdentity, s.peerCapabilities(receiverIdentity))
 	if err != nil {
-		zap.L().Warn("Error encrypting outgoing packet", zap.Error(err), zap.String("target_addr", address))
+		metrics.Add(metricPacketsSendFailed, 1)
+		s.logger().Warn("Error protecting outgoing packet", zap.Error(err), peerAddr(address))
+		s.peerStats.SendFailed(receiverIdentity, s.clock.Now())
 		return err
 	}
-	addr, err := net.ResolveUDPAddr("udp", address)
+	addr, err := s.resolver.Resolve(address, s.clock.Now())
 	if err != nil {
+		metrics.Add(metricPacketsSendFailed, 1)
+		s.logger().Warn("Error resolving target address", zap.Error(err), peerAddr(address))
+		s.recordSendFailure(address, receiverIdentity)
 		return err
 	}
 	_, err = s.listener.WriteTo(encryptedBytes, addr)
 	if err != nil {
-		zap.L().Warn("Error writing outgoing packet", zap.Error(err), zap.String("target_addr", address))
+		metrics.Add(metricPacketsSendFailed, 1)
+		s.logger().Warn("Error writing outgoing packet", zap.Error(err), peerAddr(address))
+		s.recordSendFailure(address, receiverIdentity)
 		return err
 	}
+	s.breaker.Success(address)
+	s.peerStats.Sent(receiverIdentity, MessageType(binary.BigEndian.Uint16(packetBytes[2:4])), len(encryptedBytes), s.clock.Now())
+	metrics.Add(metricPacketsSent, 1)
 	return nil
 }
 
+// recordSendFailure records a failure to resolve or write to an address with the peer statistics and the circuit breaker.
+func (s *Server) recordSendFailure(address string, receiverIdentity Identity) {
+	s.peerStats.SendFailed(receiverIdentity, s.clock.Now())
+	if s.breaker.Failure(address, s.clock.Now()) {
+		s.logger().Warn("Opened send circuit of unreachable peer", peerID(receiverIdentity), peerAddr(address),
+			zap.Int("failures", s.cfg.SendFailureThreshold), zap.Int("cooldown_ms", s.cfg.SendCircuitCooldownMs))
+	}
+}
+
+// recordPeerFailure records an invalid signature or failed challenge of a packet from the address with the quarantine.
+func (s *Server) recordPeerFailure(fromAddr net.Addr, identity Identity) {
+	if s.quarantine.Fail(fromAddr.String(), identity, s.clock.Now()) {
+		s.logger().Warn("Quarantined peer after repeated invalid signatures or failed challenges", peerID(identity), peerAddr(fromAddr.String()),
+			zap.Int("failures", s.cfg.QuarantineThreshold), zap.Int("quarantine_ms", s.cfg.QuarantineMs))
+	}
+}
+
 // addPeerCondition adds a conditional state to a peer.
 func (s *Server) addPeerCondition(identity Identity, condition peerCondition) {
 	s.mutexPeerState.Lock()
 	defer s.mutexPeerState.Unlock()
-	mapKey := identity.String()
-	if allowedPackets, ok := s.peerState[mapKey]; ok {
+	if allowedPackets, ok := s.peerState[identity]; ok {
 		for _, ap := range allowedPackets {
 			if ap == condition {
 				return
 			}
 		}
-		s.peerState[mapKey] = append(allowedPackets, condition)
+		s.peerState[identity] = append(allowedPackets, condition)
 	} else {
-		s.peerState[mapKey] = []peerCondition{condition}
+		s.peerState[identity] = []peerCondition{condition}
 	}
 }
 
@@ -311,7 +825,7 @@ func (s *Server) addPeerCondition(identity Identity, condition peerCondition) {
 func (s *Server) hasPeerCondition(identity Identity, condition peerCondition) bool {
 	s.mutexPeerState.RLock()
 	defer s.mutexPeerState.RUnlock()
-	if allowedPackets, ok := s.peerState[identity.String()]; ok {
+	if allowedPackets, ok := s.peerState[identity]; ok {
 		for _, ap := range allowedPackets {
 			if ap == condition {
 				return true
@@ -321,98 +835,289 @@ func (s *Server) hasPeerCondition(identity Identity, condition peerCondition) bo
 	return false
 }
 
-// sendGossipMessage sends a gossip message to a node.
+// recordCapabilities records the capabilities a peer advertised within its own push.
+func (s *Server) recordCapabilities(identity Identity, capabilities Capabilities) {
+	s.mutexCapabilities.Lock()
+	defer s.mutexCapabilities.Unlock()
+	s.capabilities[identity] = capabilities
+}
+
+// peerCapabilities returns the capabilities the peer with the given identity advertised within its latest push, none if it never
+// pushed to us, so peers are only sent the batch packets and hybrid key wraps they announced to understand. Capabilities are
+// not advertised before CapabilitiesVersion, so all peers of older networks are assumed to share our own.
+func (s *Server) peerCapabilities(identity Identity) Capabilities {
+	if s.cfg.ProtocolVersion < int(CapabilitiesVersion) {
+		return localCapabilities(s.cfg)
+	}
+	s.mutexCapabilities.RLock()
+	defer s.mutexCapabilities.RUnlock()
+	return s.capabilities[identity]
+}
+
+// sendGossipMessage sends the spread gossip messages to a node, or only announces them if the node is not an eager peer in this round.
 // This should only be used with nodes that have previously responded with a pull response or accepted a push.
 func (s *Server) sendGossipMessages(address string, receiverIdentity Identity) {
+	messages := s.spreadableMessages()
+	if s.isEagerPeer(receiverIdentity) {
+		s.sendMessagePackets(messages, address, receiverIdentity)
+		return
+	}
+	s.sendIHave(messages, address, receiverIdentity)
+}
+
+// SpreadMessages announces the spreadable messages to a node outside of push and pull requests.
+// The node requests the messages it is missing, which it is consequently allowed to do.
+func (s *Server) SpreadMessages(node *Node) {
+	s.sendIHave(s.spreadableMessages(), node.Address, node.Identity)
+}
+
+// spreadableMessages returns the messages that are still spread and have not expired yet, limited to the budget of each lane.
+func (s *Server) spreadableMessages() []spreadableMessage {
 	s.mutexMessages.RLock()
 	defer s.mutexMessages.RUnlock()
-	for _, msg := range s.messagesToSpread {
-		if msg.LocalTTL <= 0 {
-			continue
+	return s.applyLaneBudgets(s.messages.Spreadable(s.clock.Now()))
+}
+
+// applyLaneBudgets limits the given messages to the configured budget of each lane, keeping the first messages of each lane.
+func (s *Server) applyLaneBudgets(messages []spreadableMessage) []spreadableMessage {
+	if s.cfg.HighPriorityLaneBudget == 0 && s.cfg.BulkLaneBudget == 0 {
+		return messages
+	}
+	var limited []spreadableMessage
+	highPriority, bulk := 0, 0
+	for _, msg := range messages {
+		if msg.inHighPriorityLane() {
+			if s.cfg.HighPriorityLaneBudget > 0 && highPriority >= s.cfg.HighPriorityLaneBudget {
+				continue
+			}
+			highPriority++
+		} else {
+			if s.cfg.BulkLaneBudget > 0 && bulk >= s.cfg.BulkLaneBudget {
+				continue
+			}
+			bulk++
+		}
+		limited = append(limited, msg)
+	}
+	return limited
+}
+
+// sendIHave announces the given messages to a node and allows it to request them.
+func (s *Server) sendIHave(messages []spreadableMessage, address string, receiverIdentity Identity) {
+	if len(messages) == 0 {
+		return
+	}
+
+	entries := make([]DigestEntry, 0, len(messages))
+	for _, msg := range messages {
+		if len(entries) == MaxDigestEntries {
+			break
+		}
+		entries = append(entries, DigestEntry{DataType: msg.DataType, DataHash: msg.DataHash})
+	}
+	packet, err := NewPacketIHave(s.ownNode.Identity, entries, s.clock.Now())
+	if err != nil {
+		s.logger().Error("Error creating IHavePacket", zap.Error(err))
+		return
+	}
+	s.addPeerCondition(receiverIdentity, AllowDigestRequest)
+	s.queueBytes(packet.ToBytes(), address, receiverIdentity)
+}
+
+// sendMessagePackets sends the given messages to a node.
+// Messages are combined into batch packets of up to the configured batch size, larger messages and batches of a single message are sent in separate message packets.
+// High-priority messages are never batched, so they are not held back by bulk messages.
+func (s *Server) sendMessagePackets(messages []spreadableMessage, address string, receiverIdentity Identity) {
+	// peers that did not advertise understanding batch packets receive each message in a message packet
+	batches := s.peerCapabilities(receiverIdentity).Has(CapabilityMessageBatch)
+	var batch []MessageBatchEntry
+	batchSize := PacketHeaderSize + SignatureSize
+	flush := func() {
+		defer func() {
+			batch = nil
+			batchSize = PacketHeaderSize + SignatureSize
+		}()
+		if len(batch) == 0 {
+			return
+		}
+		if len(batch) == 1 {
+			s.sendMessagePacket(batch[0], address, receiverIdentity)
+			return
 		}
-		packet, err := NewPacketMessage(s.ownNode.Identity, msg.TTL, msg.DataType, msg.Data)
+		packet, err := NewPacketMessageBatch(s.ownNode.Identity, batch, s.clock.Now())
 		if err != nil {
-			zap.L().Error("Error creating MessagePacket", zap.Error(err))
+			s.logger().Error("Error creating MessageBatchPacket", zap.Error(err))
 			return
 		}
+		s.queueBytes(packet.ToBytes(), address, receiverIdentity)
+	}
 
-		_ = s.sendBytes(packet.ToBytes(), address, receiverIdentity)
+	for _, msg := range messages {
+		entry := MessageBatchEntry{TTL: msg.TTL, Flags: msg.flags(), DataType: msg.DataType, Data: msg.Data}
+		if s.cfg.ProtocolVersion >= int(OriginTimestampVersion) && !msg.OriginAt.IsZero() {
+			entry.Flags |= MessageFlagOriginTimestamp
+			entry.OriginTimestamp = uint64(msg.OriginAt.UnixMilli())
+		}
+		entrySize := entry.Size()
+		if !batches || msg.inHighPriorityLane() || PacketHeaderSize+SignatureSize+entrySize > s.cfg.MessageBatchMaxSize {
+			s.sendMessagePacket(entry, address, receiverIdentity)
+			continue
+		}
+		if batchSize+entrySize > s.cfg.MessageBatchMaxSize {
+			flush()
+		}
+		batch = append(batch, entry)
+		batchSize += entrySize
+	}
+	flush()
+}
+
+// sendMessagePacket sends a single message to a node in a message packet.
+func (s *Server) sendMessagePacket(msg MessageBatchEntry, address string, receiverIdentity Identity) {
+	packet, err := NewPacketMessage(s.ownNode.Identity, msg.TTL, msg.DataType, msg.OriginTimestamp, msg.Data, s.clock.Now())
+	if err != nil {
+		s.logger().Error("Error creating MessagePacket", zap.Error(err))
+		return
 	}
+	packet.Flags |= msg.Flags
+	s.queueBytes(packet.ToBytes(), address, receiverIdentity)
 }
 
 // Ping sends a ping packet to a given node and waits for a reply for the specified time.
-// If a correct response is received within the timeout return true, otherwise return false.
-func (s *Server) Ping(node *Node, timeout time.Duration) bool {
+// If a correct response is received within the timeout the round trip time and true are returned, otherwise false.
+func (s *Server) Ping(node *Node, timeout time.Duration) (time.Duration, bool) {
+	rtt, err := s.Probe(node, timeout)
+	return rtt, err == nil
+}
+
+// Probe works like Ping, but returns an error describing why no response was received instead of false.
+// ErrPingTimeout is returned if the ping was sent but not answered in time.
+func (s *Server) Probe(node *Node, timeout time.Duration) (time.Duration, error) {
+	var nonce uint64
+	if err := binary.Read(s.random, binary.BigEndian, &nonce); err != nil {
+		return 0, fmt.Errorf("could not generate ping nonce: %w", err)
+	}
+	key := outstandingPing{identity: node.Identity, nonce: nonce}
 	pongChannel := make(chan struct{}, 1)
 
 	s.mutexPongChannels.Lock()
-	s.pongChannels[node.Identity.String()] = pongChannel
+	s.pongChannels[key] = pongChannel
 	s.mutexPongChannels.Unlock()
 
 	defer func() {
 		s.mutexPongChannels.Lock()
-		delete(s.pongChannels, node.Identity.String())
+		delete(s.pongChannels, key)
 		s.mutexPongChannels.Unlock()
 	}()
 
-	pingPacket, err := NewPacketPing(s.ownNode.Identity)
+	pingPacket, err := NewPacketPing(s.ownNode.Identity, nonce, s.clock.Now())
 	if err != nil {
-		zap.L().Error("Error creating PingPacket", zap.Error(err))
-		return false
+		s.logger().Error("Error creating PingPacket", zap.Error(err))
+		return 0, err
 	}
 
+	sentAt := s.clock.Now()
 	err = s.sendBytes(pingPacket.ToBytes(), node.Address, node.Identity)
 	if err != nil {
-		return false
+		return 0, fmt.Errorf("could not send ping: %w", err)
 	}
 
 	select {
 	case <-pongChannel:
-		return true
-	case <-time.After(timeout):
-		return false
+		receivedAt := s.clock.Now()
+		rtt := receivedAt.Sub(sentAt)
+		s.latencies.RecordPing(node.Identity, rtt, receivedAt)
+		return rtt, nil
+	case <-s.clock.After(timeout):
+		return 0, fmt.Errorf("%w after %s", ErrPingTimeout, timeout)
 	}
 }
 
-// SendPullRequest sends a gossip pull request to a given node and consequently allows the node to respond to it
+// AnsweredPull returns whether the peer with the given identity answered our pull request within the current round.
+func (s *Server) AnsweredPull(identity Identity) bool {
+	return s.pulls.State(identity) == pullAnswered
+}
+
+// SendPullRequest sends a gossip pull request to a given node and consequently allows the node to respond to it within the pull timeout
 func (s *Server) SendPullRequest(node *Node) {
-	zap.L().Debug("Sending Pull request", zap.String("target_identity", node.Identity.String()), zap.String("target_address", node.Address))
-	packet, err := NewPacketPullRequest(s.ownNode.Identity)
+	s.logger().Debug("Sending Pull request", peerID(node.Identity), peerAddr(node.Address))
+	packet, err := NewPacketPullRequest(s.ownNode.Identity, s.clock.Now())
 	if err != nil {
-		zap.L().Error("Error creating PullRequestPacket", zap.Error(err))
+		s.logger().Error("Error creating PullRequestPacket", zap.Error(err))
 	}
-	s.addPeerCondition(node.Identity, AllowPull)
-	_ = s.sendBytes(packet.ToBytes(), node.Address, node.Identity)
+	s.pulls.Request(node.Identity, s.clock.Now())
+	s.latencies.StartPull(node.Identity, s.clock.Now())
+	s.queueBytes(packet.ToBytes(), node.Address, node.Identity)
 }
 
 // SendPushRequest sends a gossip push request to a node.
 // The node can respond with a push challenge which is then solved and the node pushes its own identity and address
 func (s *Server) SendPushRequest(node *Node) {
-	zap.L().Debug("Sending Push request", zap.String("target_identity", node.Identity.String()), zap.String("target_address", node.Address))
-	packet, err := NewPacketPushRequest(s.ownNode.Identity)
+	s.logger().Debug("Sending Push request", peerID(node.Identity), peerAddr(node.Address))
+	packet, err := NewPacketPushRequest(s.ownNode.Identity, s.clock.Now())
 	if err != nil {
-		zap.L().Error("Error creating PushRequestPacket", zap.Error(err))
+		s.logger().Error("Error creating PushRequestPacket", zap.Error(err))
 	}
-	s.addPeerCondition(node.Identity, AllowPushChallenge)
-	_ = s.sendBytes(packet.ToBytes(), node.Address, node.Identity)
+	s.pushes.Request(node.Identity, s.clock.Now())
+	s.queueBytes(packet.ToBytes(), node.Address, node.Identity)
+}
+
+// SendDigest sends a digest of the locally stored messages to a node, which replies with the messages we are missing and requests the ones it is missing.
+// The node is consequently allowed to send messages and to request messages from the digest.
+func (s *Server) SendDigest(node *Node) {
+	s.mutexMessages.RLock()
+	entries := s.messages.Digest(MaxDigestEntries)
+	s.mutexMessages.RUnlock()
+
+	s.logger().Debug("Sending digest", peerID(node.Identity), peerAddr(node.Address), zap.Int("entries", len(entries)))
+	packet, err := NewPacketDigest(s.ownNode.Identity, entries, s.clock.Now())
+	if err != nil {
+		s.logger().Error("Error creating DigestPacket", zap.Error(err))
+		return
+	}
+	s.addPeerCondition(node.Identity, AllowMessage)
+	s.addPeerCondition(node.Identity, AllowDigestRequest)
+	s.queueBytes(packet.ToBytes(), node.Address, node.Identity)
 }
 
 // spreadMessage stores a given message into the servers internal message store, spreading it during push and pulls
-// until the TTL has decayed to 1, a TTL of 0 indicates infinite hops.
-func (s *Server) spreadMessage(ttl uint8, dataType uint16, data []byte) {
+// until the TTL has decayed to 1, a TTL of 0 indicates infinite hops which are spread for the configured InfiniteTTLMaxRounds.
+// High-priority messages are spread within the high-priority lane.
+func (s *Server) spreadMessage(ttl uint8, dataType uint16, data []byte, highPriority bool) {
 	hashFunc := sha256.New()
 	hashFunc.Write(data)
 	dataHash := hashFunc.Sum(nil)
 
 	s.mutexMessages.Lock()
-	defer s.mutexMessages.Unlock()
 
-	s.messagesToSpread = append(s.messagesToSpread, spreadableMessage{
-		LocalTTL:       int(ttl),
+	localTTL := int(ttl)
+	if ttl == 0 {
+		localTTL = s.cfg.InfiniteTTLMaxRounds
+	}
+	now := s.clock.Now()
+	added := s.messages.Add(spreadableMessage{
+		LocalTTL:       localTTL,
 		TTL:            ttl,
 		DataType:       dataType,
 		Data:           data,
 		DataHash:       dataHash,
 		SourceIdentity: s.ownNode.Identity,
+		ReceivedAt:     now,
+		OriginAt:       now,
+		ExpiresAt:      s.expiresAt(dataType, now),
+		HighPriority:   highPriority,
 	})
+	if added && s.seen != nil {
+		s.seen.Add(messageKey{dataType, string(dataHash)})
+	}
+	if added {
+		s.mutexDeliveries.Lock()
+		s.deliveries.Track(messageKey{dataType, string(dataHash)})
+		s.mutexDeliveries.Unlock()
+	}
+	s.mutexMessages.Unlock()
+
+	if added {
+		s.events.Publish(MessageSpreadEvent{DataType: dataType, DataHash: dataHash, TTL: ttl, Source: s.ownNode.Identity})
+	}
 }
@@ -1,65 +1,90 @@
 package gossip
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"gossiphers/internal/api"
 	"gossiphers/internal/challenge"
+	"gossiphers/internal/config"
 	"net"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 // handlePing handles the ping message type.
 func (s *Server) handlePing(fromAddr net.Addr, packet PacketPing) {
-	pingPacket, err := NewPacketPong(s.ownNode.Identity)
+	pongPacket, err := NewPacketPong(s.ownNode.Identity, packet.Nonce, s.clock.Now())
 	if err != nil {
-		zap.L().Error("Error creating PongPacket", zap.Error(err))
+		s.logger().Error("Error creating PongPacket", zap.Error(err))
 		return
 	}
-	_ = s.sendBytes(pingPacket.ToBytes(), fromAddr.String(), packet.SenderIdentity)
+	s.queueBytes(pongPacket.ToBytes(), fromAddr.String(), packet.SenderIdentity)
 }
 
-// handlePong handles the pong message type.
-func (s *Server) handlePong(_ net.Addr, packet PacketPong) {
-	s.mutexPongChannels.RLock()
-	if ch, ok := s.pongChannels[packet.SenderIdentity.String()]; ok {
-		ch <- struct{}{}
+// handlePong handles the pong message type, resolving the outstanding ping whose nonce it echoes.
+// Pongs not answering an outstanding ping of ours, e.g. replayed or late ones, are ignored.
+func (s *Server) handlePong(fromAddr net.Addr, packet PacketPong) {
+	key := outstandingPing{identity: packet.SenderIdentity, nonce: packet.Nonce}
+	s.mutexPongChannels.Lock()
+	ch, ok := s.pongChannels[key]
+	delete(s.pongChannels, key)
+	s.mutexPongChannels.Unlock()
+	if !ok {
+		s.logger().Debug("Ignored pong not answering an outstanding ping", peerID(packet.SenderIdentity), peerAddr(fromAddr.String()))
+		return
 	}
-	s.mutexPongChannels.RUnlock()
+	s.markResponse()
+	ch <- struct{}{}
+}
+
+// handleLeave handles the leave message type, remembering the sender until the gossip protocol removes it from its views.
+func (s *Server) handleLeave(fromAddr net.Addr, packet PacketLeave) {
+	s.logger().Info("Peer is leaving", peerID(packet.SenderIdentity), peerAddr(fromAddr.String()))
+	s.mutexLeftPeers.Lock()
+	s.leftPeers[packet.SenderIdentity] = struct{}{}
+	s.mutexLeftPeers.Unlock()
+	s.events.Publish(PeerLeftEvent{Identity: packet.SenderIdentity})
 }
 
 // handlePullRequest handles the pull request message type.
 func (s *Server) handlePullRequest(fromAddr net.Addr, packet PacketPullRequest) {
-	s.mutexPullResponseNodes.RLock()
-	// don't send push response when view is empty
-	if len(s.pullResponseNodes) == 0 {
-		s.mutexPullResponseNodes.RUnlock()
+	nodes, err := s.pullResponseNodesFor(packet.SenderIdentity)
+	if err != nil {
+		s.logger().Error("Error selecting pull response nodes", zap.Error(err))
+		return
+	}
+	// don't send pull response when no nodes are left to answer with
+	if len(nodes) == 0 {
 		return
 	}
-	responsePacket, err := NewPacketPullResponse(s.ownNode.Identity, s.pullResponseNodes)
+	responsePacket, err := NewPacketPullResponse(s.ownNode.Identity, nodes, s.clock.Now())
 	if err != nil {
-		zap.L().Warn("Error creating pull response packet", zap.Error(err))
+		s.logger().Warn("Error creating pull response packet", zap.Error(err))
 		return
 	}
-	_ = s.sendBytes(responsePacket.ToBytes(), fromAddr.String(), packet.SenderIdentity)
-	s.mutexPullResponseNodes.RUnlock()
+	s.queueBytes(responsePacket.ToBytes(), fromAddr.String(), packet.SenderIdentity)
 	s.sendGossipMessages(fromAddr.String(), packet.SenderIdentity)
 }
 
 // handlePullResponse handles the pull response message type.
 func (s *Server) handlePullResponse(_ net.Addr, packet PacketPullResponse) {
-	if !s.hasPeerCondition(packet.SenderIdentity, AllowPull) {
+	if !s.pulls.Respond(packet.SenderIdentity, s.clock.Now()) {
+		s.logger().Debug("Ignoring unrequested or late pull response", peerID(packet.SenderIdentity))
 		return
 	}
+	s.markResponse()
+	s.latencies.FinishPull(packet.SenderIdentity, s.clock.Now())
 	// Allow message exchange after pull response
 	s.addPeerCondition(packet.SenderIdentity, AllowMessage)
 	for _, node := range packet.Nodes {
-		if node.String() == s.ownNode.String() {
+		if node.Identity == s.ownNode.Identity {
 			continue
 		}
+		node.Source = SourcePull
+		// the responder may claim any capabilities on behalf of the listed nodes, they are only taken from their own pushes
+		node.Capabilities = 0
 		s.pullNodes <- node
 	}
 }
@@ -68,117 +93,198 @@ func (s *Server) handlePullResponse(_ net.Addr, packet PacketPullResponse) {
 func (s *Server) handlePushRequest(fromAddr net.Addr, packet PacketPushRequest) {
 	newChallenge, err := s.challenger.NewChallenge(packet.SenderIdentity.ToBytes())
 	if err != nil {
-		zap.L().Warn("Error generating challenge", zap.Error(err))
+		s.logger().Warn("Error generating challenge", zap.Error(err))
 		return
 	}
-	challengePacket, err := NewPacketPushChallenge(s.ownNode.Identity, s.challengeDifficulty, newChallenge)
+	challengePacket, err := NewPacketPushChallenge(s.ownNode.Identity, s.challengeDifficulty, newChallenge, s.clock.Now())
 	if err != nil {
-		zap.L().Error("Error creating PushChallengePacket", zap.Error(err))
+		s.logger().Error("Error creating PushChallengePacket", zap.Error(err))
 		return
 	}
-	_ = s.sendBytes(challengePacket.ToBytes(), fromAddr.String(), packet.SenderIdentity)
+	metrics.Add(metricPushChallengesIssued, 1)
+	s.queueBytes(challengePacket.ToBytes(), fromAddr.String(), packet.SenderIdentity)
 }
 
-// handlePushChallenge handles the push challenge message type.
+// handlePushChallenge handles the push challenge message type, which is only answered if we requested to push to the sender.
 func (s *Server) handlePushChallenge(fromAddr net.Addr, packet PacketPushChallenge) {
-	if !s.hasPeerCondition(packet.SenderIdentity, AllowPushChallenge) {
+	if !s.pushes.Challenge(packet.SenderIdentity, s.clock.Now()) {
+		s.logger().Debug("Ignored push challenge not answering an outstanding push request", peerID(packet.SenderIdentity))
+		return
+	}
+	s.markResponse()
+	if maxDifficulty := s.cfg.ChallengeMaxAcceptedDifficulty; maxDifficulty > 0 && packet.Difficulty > uint32(maxDifficulty) {
+		// every bit doubles the expected work, so the challenge is refused rather than solved until the budget runs out
+		s.logger().Warn("Refused push challenge demanding more than the accepted difficulty", peerID(packet.SenderIdentity), peerAddr(fromAddr.String()),
+			zap.Uint32("difficulty", packet.Difficulty), zap.Int("max_difficulty", maxDifficulty))
+		metrics.Add(metricPushChallengesTooDifficult, 1)
+		s.pushes.Abort(packet.SenderIdentity)
 		return
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), s.challengeMaxSolveTime)
 	defer cancel()
 	nonce, err := challenge.SolveChallenge(packet.Challenge, int(packet.Difficulty), ctx)
 	if err != nil {
-		zap.L().Warn("Error solving challenge", zap.Error(err))
+		s.logger().Warn("Error solving challenge within the solve budget", zap.Error(err), peerID(packet.SenderIdentity), peerAddr(fromAddr.String()),
+			zap.Uint32("difficulty", packet.Difficulty))
+		s.pushes.Abort(packet.SenderIdentity)
 		return
 	}
 
-	pushPacket, err := NewPacketPush(s.ownNode.Identity, packet.Challenge, nonce, *s.ownNode)
+	pushPacket, err := NewPacketPush(s.ownNode.Identity, packet.Challenge, nonce, *s.ownNode, s.clock.Now())
 	if err != nil {
-		zap.L().Error("Error creating PushPacket", zap.Error(err))
+		s.logger().Error("Error creating PushPacket", zap.Error(err))
+		s.pushes.Abort(packet.SenderIdentity)
 		return
 	}
 
-	_ = s.sendBytes(pushPacket.ToBytes(), fromAddr.String(), packet.SenderIdentity)
+	s.pushes.Pushed(packet.SenderIdentity, s.clock.Now())
+	s.queueBytes(pushPacket.ToBytes(), fromAddr.String(), packet.SenderIdentity)
 	s.sendGossipMessages(fromAddr.String(), packet.SenderIdentity)
 }
 
 // handlePush handles the push message type.
-func (s *Server) handlePush(_ net.Addr, packet PacketPush) {
+func (s *Server) handlePush(fromAddr net.Addr, packet PacketPush) {
 	// Allow only one push per node per cycle
 	if s.hasPeerCondition(packet.SenderIdentity, DenyPush) {
+		metrics.Add(metricPushesRejected, 1)
 		return
 	}
 	s.addPeerCondition(packet.SenderIdentity, DenyPush)
 
 	challengeOk, err := s.challenger.IsSolvedCorrectly(packet.Challenge, packet.Nonce, packet.SenderIdentity.ToBytes(), int(s.challengeDifficulty))
 	if err != nil {
-		zap.L().Warn("Error during challenge verification", zap.Error(err))
+		s.logger().Warn("Error during challenge verification", zap.Error(err))
 	}
 	if !challengeOk {
+		metrics.Add(metricPushesRejected, 1)
+		s.events.Publish(ChallengeFailedEvent{Identity: packet.SenderIdentity, Address: packet.Node.Address})
+		s.recordPeerFailure(fromAddr, packet.SenderIdentity)
+		return
+	}
+	if packet.SenderIdentity != packet.Node.Identity {
+		s.logger().Warn("Node tried pushing reference to a third party node, rejected.", peerID(packet.SenderIdentity))
+		metrics.Add(metricPushesRejected, 1)
 		return
 	}
-	if !bytes.Equal(packet.SenderIdentity.ToBytes(), packet.Node.Identity.ToBytes()) {
-		zap.L().Warn("Node tried pushing reference to a third party node, rejected.", zap.String("sender_identity", string(packet.SenderIdentity)))
+	if packet.Node.Identity == s.ownNode.Identity {
 		return
 	}
+	metrics.Add(metricPushesAccepted, 1)
 	// Allow message exchange after push response
 	s.addPeerCondition(packet.SenderIdentity, AllowMessage)
-	s.pushNodes <- packet.Node
+	s.recordCapabilities(packet.SenderIdentity, packet.Node.Capabilities)
+	pushedNode := packet.Node
+	pushedNode.Source = SourcePush
+	pushedNode.LastSeen = s.clock.Now()
+	s.pushNodes <- pushedNode
 }
 
 // handleMessage handles the gossip-message message type.
+// Peers may only send messages once a push or pull exchange or a digest of ours allowed them to, or the messages we requested from them.
 func (s *Server) handleMessage(fromAddr net.Addr, packet PacketMessage) {
-	if !s.hasPeerCondition(packet.SenderIdentity, AllowMessage) {
-		return
-	}
 	hashFunc := sha256.New()
 	hashFunc.Write(packet.Data)
 	dataHash := hashFunc.Sum(nil)
+	if !s.hasPeerCondition(packet.SenderIdentity, AllowMessage) && !s.messageRequests.Receive(packet.SenderIdentity, messageKey{packet.DataType, string(dataHash)}) {
+		return
+	}
+	if len(packet.Data) > s.cfg.MaxMessageDataSize {
+		s.logger().Info("Ignored gossip message exceeding the maximum data size", peerID(packet.SenderIdentity), peerAddr(fromAddr.String()), zap.Int("data_size", len(packet.Data)))
+		return
+	}
 	// Using an anonymous function here to prevent an accidental deadlock on the message mutex
 	if !func() bool {
 		s.mutexMessages.Lock()
 		defer s.mutexMessages.Unlock()
-		messagesSameSource := 0
-		for _, msg := range s.messagesToSpread {
-			// ignore messages that are already known
-			if msg.DataType == packet.DataType && bytes.Equal(msg.DataHash, dataHash) {
-				return false
-			}
-			if bytes.Equal(packet.SenderIdentity.ToBytes(), msg.SourceIdentity.ToBytes()) {
-				messagesSameSource++
+		added, floodAction := s.storeMessage(packet, dataHash)
+		switch floodAction {
+		case config.FloodActionDrop:
+			s.logger().Info("Ignored gossip message to prevent message flooding", peerID(packet.SenderIdentity), peerAddr(fromAddr.String()))
+		case config.FloodActionDelay:
+			if s.delayMessage(packet, dataHash) {
+				s.logger().Info("Delayed gossip message to prevent message flooding", peerID(packet.SenderIdentity), peerAddr(fromAddr.String()))
+			} else {
+				s.logger().Info("Ignored gossip message to prevent message flooding, too many delayed messages", peerID(packet.SenderIdentity), peerAddr(fromAddr.String()))
 			}
+		case config.FloodActionDeprioritize:
+			s.logger().Info("Deprioritized gossip message to prevent message flooding", peerID(packet.SenderIdentity), peerAddr(fromAddr.String()))
 		}
-
-		// ignore message if we have too many concurrent messages from that peer in our storage
-		if messagesSameSource > 50 {
-			zap.L().Info("Ignored gossip message to prevent message flooding", zap.String("source_identity", string(packet.SenderIdentity)), zap.String("source_address", fromAddr.String()))
-			return false
-		}
-		var newTTL uint8 = 0
-		localTTL := 255
-		if packet.TTL != 0 {
-			newTTL = packet.TTL - 1
-			localTTL = int(newTTL)
-		}
-		s.messagesToSpread = append(s.messagesToSpread, spreadableMessage{
-			LocalTTL:       localTTL,
-			TTL:            newTTL,
-			DataType:       packet.DataType,
-			Data:           packet.Data,
-			DataHash:       dataHash,
-			SourceIdentity: packet.SenderIdentity,
-		})
-		return true
+		return added
 	}() {
 		return
 	}
+	s.notifyMessage(packet, dataHash)
+}
+
+// storeMessage adds a received message to the message store unless it is already known or its source exceeds the flood threshold, the caller must hold the message mutex.
+// Returns whether the message was added and, if its source exceeds the flood threshold, the flood action that applies to it.
+func (s *Server) storeMessage(packet PacketMessage, dataHash []byte) (bool, string) {
+	// ignore messages that are already known
+	if s.messages.Touch(packet.DataType, dataHash) {
+		return false, ""
+	}
+	// ignore messages that were already evicted, but are still re-gossiped by lagging peers
+	key := messageKey{packet.DataType, string(dataHash)}
+	if s.seen != nil && s.seen.Contains(key) {
+		return false, ""
+	}
 
-	zap.L().Info("Received new gossip message for API clients", zap.Uint16("data_type", packet.DataType), zap.String("data_hash", hex.EncodeToString(dataHash)))
+	// ignore messages whose origin announced them longer than their lifetime ago, no matter how many hops they took
+	now := s.clock.Now()
+	originAt := packet.originAt(now)
+	expiresAt := s.expiresAt(packet.DataType, originAt)
+	if !expiresAt.IsZero() && now.After(expiresAt) {
+		return false, ""
+	}
+
+	// handle message according to the flood action if we have too many concurrent messages from that peer in our storage
+	flooding, floodAction := s.isFlooding(packet.SenderIdentity, packet.DataType)
+	if !flooding {
+		floodAction = ""
+	} else if floodAction != config.FloodActionDeprioritize {
+		return false, floodAction
+	}
+	// messages received with a TTL of 1 keep it, so they are not forwarded with infinite hops by the anti-entropy reconciliation
+	var newTTL uint8 = 0
+	localTTL := s.cfg.InfiniteTTLMaxRounds
+	if packet.TTL == 1 {
+		newTTL = 1
+		localTTL = 0
+	} else if packet.TTL != 0 {
+		newTTL = packet.TTL - 1
+		localTTL = int(newTTL)
+	}
+	msg := spreadableMessage{
+		LocalTTL:       localTTL,
+		TTL:            newTTL,
+		DataType:       packet.DataType,
+		Data:           packet.Data,
+		DataHash:       dataHash,
+		SourceIdentity: packet.SenderIdentity,
+		ReceivedAt:     now,
+		ExpiresAt:      expiresAt,
+		Deprioritized:  flooding,
+		HighPriority:   packet.Flags&MessageFlagHighPriority != 0,
+	}
+	if packet.Flags&MessageFlagOriginTimestamp != 0 {
+		msg.OriginAt = originAt
+	}
+	added := s.messages.Add(msg)
+	if added && s.seen != nil {
+		s.seen.Add(key)
+	}
+	return added, floodAction
+}
+
+// notifyMessage publishes a newly stored message received from a peer and forwards it to API clients, removing it from the message store if a client marks it as invalid.
+func (s *Server) notifyMessage(packet PacketMessage, dataHash []byte) {
+	s.logger().Info("Received new gossip message for API clients", zap.Uint16("data_type", packet.DataType), zap.String("data_hash", hex.EncodeToString(dataHash)))
+	s.events.Publish(MessageSpreadEvent{DataType: packet.DataType, DataHash: dataHash, TTL: packet.TTL, Source: packet.SenderIdentity})
 
 	// forward newly received message to API clients
 	apiPacket, err := api.NewGossipNotification(packet.DataType, packet.Data)
 	if err != nil {
-		zap.L().Error("Error building API gossip notification packet", zap.Error(err))
+		s.logger().Error("Error building API gossip notification packet", zap.Error(err))
 		return
 	}
 	s.apiServer.SendGossipNotifications(*apiPacket, func(valid bool) {
@@ -188,12 +294,124 @@ func (s *Server) handleMessage(fromAddr net.Addr, packet PacketMessage) {
 		// Remove invalid packet from internal state to stop it from spreading further
 		s.mutexMessages.Lock()
 		defer s.mutexMessages.Unlock()
-		var newMessages []spreadableMessage
-		for _, msg := range s.messagesToSpread {
-			if !bytes.Equal(msg.DataHash, dataHash) {
-				newMessages = append(newMessages, msg)
-			}
-		}
-		s.messagesToSpread = newMessages
+		s.messages.Remove(packet.DataType, dataHash)
 	})
 }
+
+// originAt returns the time the origin of the message announced it at, the given receive time if the message does not
+// carry its origin timestamp. Origin timestamps later than the receive time are capped, so they cannot extend the lifetime of the message.
+func (p *PacketMessage) originAt(receivedAt time.Time) time.Time {
+	if p.Flags&MessageFlagOriginTimestamp == 0 {
+		return receivedAt
+	}
+	originAt := time.UnixMilli(int64(p.OriginTimestamp))
+	if originAt.After(receivedAt) {
+		return receivedAt
+	}
+	return originAt
+}
+
+// handleMessageBatch handles the message batch type, handling each contained message like a separately received message packet.
+func (s *Server) handleMessageBatch(fromAddr net.Addr, packet PacketMessageBatch) {
+	for _, msg := range packet.Messages {
+		s.handleMessage(fromAddr, PacketMessage{
+			PacketHeader:    packet.PacketHeader,
+			TTL:             msg.TTL,
+			Flags:           msg.Flags,
+			DataType:        msg.DataType,
+			OriginTimestamp: msg.OriginTimestamp,
+			Data:            msg.Data,
+			PacketFooter:    packet.PacketFooter,
+		})
+	}
+}
+
+// handleDigest handles the digest message type.
+// Messages missing from the digest are sent to the peer, messages of the digest which are neither stored nor were seen before are requested in turn.
+// Each peer is answered once per round, so unsolicited digests cannot amplify the traffic beyond a single answer per round.
+func (s *Server) handleDigest(fromAddr net.Addr, packet PacketDigest) {
+	// Answer only one digest per node per cycle, as every answer may consist of many messages
+	if s.hasPeerCondition(packet.SenderIdentity, DenyDigest) {
+		metrics.Add(metricDigestsRejected, 1)
+		return
+	}
+	s.addPeerCondition(packet.SenderIdentity, DenyDigest)
+	s.recordDeliveries(packet.SenderIdentity, packet.Entries)
+	known := make(map[messageKey]struct{}, len(packet.Entries))
+	for _, entry := range packet.Entries {
+		known[messageKey{entry.DataType, string(entry.DataHash)}] = struct{}{}
+	}
+
+	var missingAtPeer []spreadableMessage
+	now := s.clock.Now()
+	s.mutexMessages.RLock()
+	for _, entry := range s.messages.Digest(MaxDigestEntries) {
+		if _, ok := known[messageKey{entry.DataType, string(entry.DataHash)}]; ok {
+			continue
+		}
+		if msg, ok := s.messages.Get(entry.DataType, entry.DataHash); ok && !msg.isExpired(now) {
+			missingAtPeer = append(missingAtPeer, msg)
+		}
+	}
+	missingLocally := s.missingEntries(packet.Entries)
+	s.mutexMessages.RUnlock()
+
+	s.logger().Debug("Reconciling messages with peer", peerID(packet.SenderIdentity), zap.Int("missing_at_peer", len(missingAtPeer)), zap.Int("missing_locally", len(missingLocally)))
+	s.sendMessagePackets(missingAtPeer, fromAddr.String(), packet.SenderIdentity)
+	s.requestMessages(fromAddr, packet.SenderIdentity, missingLocally)
+}
+
+// handleIHave handles the IHave message type, requesting the announced messages which are neither stored nor were seen before.
+// Like for digests, the peer may only send the requested messages.
+func (s *Server) handleIHave(fromAddr net.Addr, packet PacketIHave) {
+	s.recordDeliveries(packet.SenderIdentity, packet.Entries)
+	s.mutexMessages.RLock()
+	missing := s.missingEntries(packet.Entries)
+	s.mutexMessages.RUnlock()
+	s.requestMessages(fromAddr, packet.SenderIdentity, missing)
+}
+
+// missingEntries returns the entries whose messages are neither stored nor were seen before, the caller must hold the message mutex.
+func (s *Server) missingEntries(entries []DigestEntry) []DigestEntry {
+	var missing []DigestEntry
+	for _, entry := range entries {
+		key := messageKey{entry.DataType, string(entry.DataHash)}
+		if s.messages.Contains(entry.DataType, entry.DataHash) || (s.seen != nil && s.seen.Contains(key)) {
+			continue
+		}
+		missing = append(missing, entry)
+	}
+	return missing
+}
+
+// requestMessages requests the messages of the given entries from a peer, which is allowed to send the requested messages only.
+func (s *Server) requestMessages(fromAddr net.Addr, peerIdentity Identity, entries []DigestEntry) {
+	entries = s.messageRequests.Request(peerIdentity, entries)
+	if len(entries) == 0 {
+		return
+	}
+	requestPacket, err := NewPacketDigestRequest(s.ownNode.Identity, entries, s.clock.Now())
+	if err != nil {
+		s.logger().Error("Error creating DigestRequestPacket", zap.Error(err))
+		return
+	}
+	s.queueBytes(requestPacket.ToBytes(), fromAddr.String(), peerIdentity)
+}
+
+// handleDigestRequest handles the digest request message type.
+func (s *Server) handleDigestRequest(fromAddr net.Addr, packet PacketDigestRequest) {
+	if !s.hasPeerCondition(packet.SenderIdentity, AllowDigestRequest) {
+		return
+	}
+	var requested []spreadableMessage
+	now := s.clock.Now()
+	s.mutexMessages.RLock()
+	for _, entry := range packet.Entries {
+		if msg, ok := s.messages.Get(entry.DataType, entry.DataHash); ok && !msg.isExpired(now) {
+			requested = append(requested, msg)
+		}
+	}
+	s.mutexMessages.RUnlock()
+	s.recordDeliveries(packet.SenderIdentity, packet.Entries)
+	s.sendMessagePackets(requested, fromAddr.String(), packet.SenderIdentity)
+}
@@ -0,0 +1,756 @@
+package gossip
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"gossiphers/internal/api"
+	challengeModule "gossiphers/internal/challenge"
+	"gossiphers/internal/clock"
+	"gossiphers/internal/config"
+	"gossiphers/internal/transport"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// loopbackTestServers is the number of servers connected by newLoopbackServers.
+const loopbackTestServers = 3
+
+var (
+	loopbackTestKeysOnce   sync.Once
+	loopbackTestKeys       [loopbackTestServers]*rsa.PrivateKey
+	loopbackTestHybridKeys [loopbackTestServers][]byte
+)
+
+// plaintextCrypto implements PacketCrypto without encryption and with empty signatures, which every peer accepts.
+type plaintextCrypto struct{}
+
+func (plaintextCrypto) EncryptPacket(msg []byte, _ Identity, _ Capabilities) ([]byte, error) {
+	return msg, nil
+}
+func (plaintextCrypto) DecryptPacket(ciphertext []byte) ([]byte, error) { return ciphertext, nil }
+func (plaintextCrypto) Overhead() int                                   { return 0 }
+func (plaintextCrypto) Sign([]byte) ([]byte, error)                     { return make([]byte, SignatureSize), nil }
+func (plaintextCrypto) VerifySignature([]byte, []byte, Identity) error  { return nil }
+
+// stubChallenger implements Challenger with empty challenges, whose solutions are all accepted or all rejected.
+type stubChallenger struct {
+	accept bool
+}
+
+func (stubChallenger) NewChallenge([]byte) ([]byte, error) {
+	return make([]byte, challengeModule.ChallengeSize), nil
+}
+
+func (c stubChallenger) IsSolvedCorrectly([]byte, []byte, []byte, int) (bool, error) {
+	return c.accept, nil
+}
+
+// realDependencies returns the crypto and challenger used in production.
+func realDependencies(t *testing.T, cfg *config.GossipConfig) (PacketCrypto, Challenger) {
+	gCrypto, err := NewCrypto(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenger, err := NewChallenger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gCrypto, challenger
+}
+
+// newLoopbackServers returns started servers knowing each other's public keys, which are connected by an in-memory network and
+// driven by the given clock. The keys are generated once for all tests.
+func newLoopbackServers(t *testing.T, clocks ...clock.Clock) []*Server {
+	return newLoopbackServersWith(t, realDependencies, clocks...)
+}
+
+// newLoopbackServersWith returns servers like newLoopbackServers, which use the crypto and challenger returned by dependencies.
+func newLoopbackServersWith(t *testing.T, dependencies func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger), clocks ...clock.Clock) []*Server {
+	return newLoopbackServersConfigured(t, dependencies, nil, clocks...)
+}
+
+// newLoopbackServersConfigured returns servers like newLoopbackServersWith, whose configuration is additionally modified by options.
+func newLoopbackServersConfigured(t *testing.T, dependencies func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger), options []config.Option, clocks ...clock.Clock) []*Server {
+	servers := newUnstartedLoopbackServers(t, dependencies, options, clocks...)
+	startLoopbackServers(t, servers)
+	return servers
+}
+
+// newUnstartedLoopbackServers returns servers like newLoopbackServersConfigured that are not started yet, so tests can replace
+// fields such as the protection or the event bus before the servers handle packets. They must be started by startLoopbackServers.
+func newUnstartedLoopbackServers(t *testing.T, dependencies func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger), options []config.Option, clocks ...clock.Clock) []*Server {
+	loopbackTestKeysOnce.Do(func() {
+		for i := range loopbackTestKeys {
+			key, err := rsa.GenerateKey(rand.Reader, RSAKeySize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loopbackTestKeys[i] = key
+			hybridKey, err := GenerateHybridKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			loopbackTestHybridKeys[i] = hybridKey
+		}
+	})
+	publicKeys := make([]*rsa.PublicKey, 0, len(loopbackTestKeys))
+	var keyOptions []config.Option
+	for i, key := range loopbackTestKeys {
+		publicKeys = append(publicKeys, &key.PublicKey)
+		identity, err := IdentityOf(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hybridKey, err := HybridPublicKey(loopbackTestHybridKeys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyOptions = append(keyOptions, config.WithPeerHybridPublicKey(identity.String(), hybridKey))
+	}
+
+	network := transport.NewLoopback()
+	servers := make([]*Server, len(clocks))
+	for i, serverClock := range clocks {
+		cfg, err := config.New(append(append([]config.Option{
+			config.WithPrivateKey(loopbackTestKeys[i]),
+			config.WithHybridPrivateKey(loopbackTestHybridKeys[i]),
+			config.WithPeerPublicKeys(publicKeys...),
+			config.WithGossipAddress(fmt.Sprintf("127.0.0.1:%d", 7001+i)),
+			config.WithChallengeDifficulty(4),
+			config.WithClock(serverClock),
+			config.WithNetwork(network),
+		}, keyOptions...), options...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gCrypto, challenger := dependencies(t, cfg)
+		server, err := NewServer(cfg, make(chan Node, 16), make(chan Node, 16), gCrypto, challenger, api.NewServer(cfg, clock.Or(cfg.Clock)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		servers[i] = server
+	}
+	return servers
+}
+
+// startLoopbackServers starts servers returned by newUnstartedLoopbackServers and closes them once the test finished.
+func startLoopbackServers(t *testing.T, servers []*Server) {
+	for _, server := range servers {
+		if err := server.Start(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = server.Close() })
+	}
+}
+
+// receiveNode returns the next node sent on the channel, failing the test if none arrives within a few seconds.
+func receiveNode(t *testing.T, nodes chan Node) Node {
+	select {
+	case node := <-nodes:
+		return node
+	case <-time.After(5 * time.Second):
+		t.Fatal("no node received")
+		return Node{}
+	}
+}
+
+func TestServer_loopback(t *testing.T) {
+	t.Parallel()
+	start := time.Unix(1700000000, 0)
+	t.Run("ping is answered without the clock moving", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServers(t, fakeClock, fakeClock)
+		rtt, err := servers[0].Probe(servers[1].ownNode, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rtt != 0 {
+			t.Errorf("expected a round trip time of 0 on the fake clock, received %s", rtt)
+		}
+		if !servers[1].LastPeerContact().Equal(start) {
+			t.Errorf("expected the last peer contact at the time of the fake clock, received %s", servers[1].LastPeerContact())
+		}
+	})
+	t.Run("ping times out once the clock passed the timeout", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServers(t, fakeClock, fakeClock)
+		offline := *servers[1].ownNode
+		if err := servers[1].Close(); err != nil {
+			t.Fatal(err)
+		}
+		online := make(chan bool)
+		go func() {
+			_, ok := servers[0].Ping(&offline, time.Second)
+			online <- ok
+		}()
+		// both challengers rotate their keys with a ticker, the ping waits on a timer
+		fakeClock.BlockUntil(3)
+		fakeClock.Advance(time.Second)
+		if <-online {
+			t.Error("node that stopped listening answered the ping")
+		}
+	})
+	t.Run("pull request is answered with the pull response nodes", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServers(t, fakeClock, fakeClock, fakeClock)
+		servers[1].UpdatePullResponseNodes([]Node{*servers[2].ownNode})
+		servers[0].SendPullRequest(servers[1].ownNode)
+		if node := receiveNode(t, servers[0].pullNodes); node.Identity != servers[2].ownNode.Identity {
+			t.Errorf("expected node %s to be pulled, received %s", servers[2].ownNode, node)
+		}
+	})
+	t.Run("push challenge is solved and the pushing node accepted", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServers(t, fakeClock, fakeClock)
+		servers[0].SendPushRequest(servers[1].ownNode)
+		if node := receiveNode(t, servers[1].pushNodes); node.Identity != servers[0].ownNode.Identity {
+			t.Errorf("expected node %s to be pushed, received %s", servers[0].ownNode, node)
+		}
+	})
+	t.Run("packets timestamped too long ago by the sender's clock are rejected", func(t *testing.T) {
+		t.Parallel()
+		servers := newUnstartedLoopbackServers(t, realDependencies, nil, clock.NewFake(start), clock.NewFake(start.Add(9*time.Second)))
+		servers[1].events = NewEventBus()
+		rejected := make(chan RejectReason, 1)
+		servers[1].events.Subscribe(func(event Event) {
+			if e, ok := event.(PacketRejectedEvent); ok {
+				rejected <- e.Reason
+			}
+		})
+		startLoopbackServers(t, servers)
+		servers[0].SendPullRequest(servers[1].ownNode)
+		select {
+		case reason := <-rejected:
+			if reason != RejectReasonStale {
+				t.Errorf("expected the packet to be rejected as stale, received %v", reason)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("packet was not rejected")
+		}
+		// the skewed clock of the sender is what the clock skew estimate has to detect
+		if _, peers, _ := servers[1].clockSkew.Estimate(start.Add(9 * time.Second)); peers != 1 {
+			t.Errorf("expected the clock offset of the stale packet to be recorded, received offsets of %d peers", peers)
+		}
+	})
+	t.Run("bursts of queued packets are all sent by the send workers", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServersWith(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, fakeClock, fakeClock)
+		// every ping carries its own nonce, so each of them is answered by a pong, unlike concurrent pulls of the same peer
+		const requests = 50
+		var wg sync.WaitGroup
+		errs := make(chan error, requests)
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := servers[0].Probe(servers[1].ownNode, time.Second)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	t.Run("sending fails once the server is closed", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServersWith(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, fakeClock, fakeClock)
+		if err := servers[0].Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := servers[0].SendLeave(servers[1].ownNode); !errors.Is(err, net.ErrClosed) {
+			t.Errorf("expected net.ErrClosed, received %v", err)
+		}
+	})
+	t.Run("packets to our own identity or address are dropped", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServersWith(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, fakeClock, fakeClock)
+		leaked := &Node{Identity: servers[1].ownNode.Identity, Address: servers[0].ownNode.Address}
+		for _, node := range []*Node{servers[0].ownNode, leaked} {
+			if err := servers[0].SendLeave(node); !errors.Is(err, ErrSelfAddressed) {
+				t.Errorf("expected ErrSelfAddressed sending to %s, received %v", node, err)
+			}
+		}
+	})
+	t.Run("packets sent by ourselves are rejected", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newUnstartedLoopbackServers(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, nil, fakeClock)
+		servers[0].events = NewEventBus()
+		rejected := make(chan RejectReason, 1)
+		servers[0].events.Subscribe(func(event Event) {
+			if e, ok := event.(PacketRejectedEvent); ok {
+				rejected <- e.Reason
+			}
+		})
+		startLoopbackServers(t, servers)
+		packet, err := NewPacketLeave(servers[0].ownNode.Identity, fakeClock.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		packetBytes, err := servers[0].protection.seal(servers[0].crypto, packet.ToBytes(), servers[0].ownNode.Identity, servers[0].ownNode.Identity, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		servers[0].handleIncomingBytes(packetBytes, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7001})
+		select {
+		case reason := <-rejected:
+			if reason != RejectReasonSelfAddressed {
+				t.Errorf("expected the packet to be rejected as self-addressed, received %v", reason)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("packet was not rejected")
+		}
+		if len(servers[0].TakeLeftPeers()) != 0 {
+			t.Error("expected our own leave packet to be ignored")
+		}
+	})
+	for _, version := range SupportedProtocolVersions {
+		keyWraps := []string{config.KeyWrapRSAOAEP}
+		if version >= HybridKeyWrapVersion {
+			keyWraps = append(keyWraps, config.KeyWrapHybrid)
+		}
+		for _, keyWrap := range keyWraps {
+			version, keyWrap := version, keyWrap
+			t.Run(fmt.Sprintf("pull request is answered between servers speaking protocol version %d with key wrap %s", version, keyWrap), func(t *testing.T) {
+				t.Parallel()
+				fakeClock := clock.NewFake(start)
+				servers := newLoopbackServersConfigured(t, realDependencies, []config.Option{config.WithProtocolVersion(int(version)), config.WithKeyWrap(keyWrap)}, fakeClock, fakeClock, fakeClock)
+				servers[1].UpdatePullResponseNodes([]Node{*servers[2].ownNode})
+				servers[0].SendPullRequest(servers[1].ownNode)
+				if node := receiveNode(t, servers[0].pullNodes); node.Identity != servers[2].ownNode.Identity {
+					t.Errorf("expected node %s to be pulled, received %s", servers[2].ownNode, node)
+				}
+			})
+		}
+	}
+	for _, tc := range []struct {
+		version      uint8
+		capabilities Capabilities
+	}{
+		{HybridKeyWrapVersion, 0},
+		{CapabilitiesVersion, CapabilityMessageBatch},
+	} {
+		tc := tc
+		t.Run(fmt.Sprintf("capabilities of pushing nodes are received with protocol version %d: %s", tc.version, tc.capabilities), func(t *testing.T) {
+			t.Parallel()
+			fakeClock := clock.NewFake(start)
+			servers := newLoopbackServersConfigured(t, realDependencies, []config.Option{config.WithProtocolVersion(int(tc.version))}, fakeClock, fakeClock)
+			servers[0].SendPushRequest(servers[1].ownNode)
+			if node := receiveNode(t, servers[1].pushNodes); node.Capabilities != tc.capabilities {
+				t.Errorf("expected the capabilities %s, received %s", tc.capabilities, node.Capabilities)
+			}
+		})
+	}
+	t.Run("capabilities claimed by pull responses on behalf of other nodes are dropped", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServersConfigured(t, realDependencies, []config.Option{config.WithProtocolVersion(int(CapabilitiesVersion))}, fakeClock, fakeClock, fakeClock)
+		claimed := *servers[2].ownNode
+		claimed.Capabilities = CapabilityCompression | CapabilityRelay
+		servers[0].pulls.Request(servers[1].ownNode.Identity, fakeClock.Now())
+		servers[0].handlePullResponse(nil, PacketPullResponse{PacketHeader: PacketHeader{SenderIdentity: servers[1].ownNode.Identity}, Nodes: []Node{claimed}})
+		if node := receiveNode(t, servers[0].pullNodes); node.Capabilities != 0 {
+			t.Errorf("expected no capabilities, received %s", node.Capabilities)
+		}
+	})
+	for _, tc := range []struct {
+		capabilities Capabilities
+		packetTypes  []MessageType
+	}{
+		{BuildCapabilities, []MessageType{MessageTypeGossipMessageBatch}},
+		// a build without batch packets and the hybrid key wrap, which cannot decrypt hybrid wrapped packet keys
+		{0, []MessageType{MessageTypeGossipMessage, MessageTypeGossipMessage}},
+	} {
+		tc := tc
+		t.Run(fmt.Sprintf("messages are sent in the packets and key wraps advertised by the receiver: %s", tc.capabilities), func(t *testing.T) {
+			t.Parallel()
+			fakeClock := clock.NewFake(start)
+			servers := newUnstartedLoopbackServers(t, realDependencies, []config.Option{config.WithProtocolVersion(int(CapabilitiesVersion)), config.WithKeyWrap(config.KeyWrapHybrid)},
+				fakeClock, fakeClock)
+			servers[1].ownNode.Capabilities = tc.capabilities
+			if !tc.capabilities.Has(CapabilityHybridKeyWrap) {
+				rsaConfig := *servers[1].cfg
+				rsaConfig.KeyWrap = config.KeyWrapRSAOAEP
+				rsaConfig.HybridPrivateKey = nil
+				rsaCrypto, err := NewCrypto(&rsaConfig)
+				if err != nil {
+					t.Fatal(err)
+				}
+				servers[1].crypto = rsaCrypto
+			}
+			received := make(chan MessageType, 4)
+			servers[1].UsePacketMiddleware(func(messageType MessageType, next PacketHandler) PacketHandler {
+				return func(fromAddr net.Addr, header *PacketHeader, body *bytes.Reader) error {
+					if messageType == MessageTypeGossipMessage || messageType == MessageTypeGossipMessageBatch {
+						received <- messageType
+					}
+					return next(fromAddr, header, body)
+				}
+			})
+			startLoopbackServers(t, servers)
+			servers[1].SendPushRequest(servers[0].ownNode)
+			receiveNode(t, servers[0].pushNodes)
+			receiver := servers[1].ownNode.Identity
+			if capabilities := servers[0].peerCapabilities(receiver); capabilities != tc.capabilities {
+				t.Fatalf("expected the capabilities %s, received %s", tc.capabilities, capabilities)
+			}
+
+			servers[0].spreadMessage(5, 1, []byte("first"), false)
+			servers[0].spreadMessage(5, 1, []byte("second"), false)
+			servers[0].sendMessagePackets(servers[0].spreadableMessages(), servers[1].ownNode.Address, receiver)
+			for _, expected := range tc.packetTypes {
+				select {
+				case packetType := <-received:
+					if packetType != expected {
+						t.Errorf("expected a packet of type %#04x, received %#04x", uint16(expected), uint16(packetType))
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("no packet of type %#04x received", uint16(expected))
+				}
+			}
+		})
+	}
+	for _, tc := range []struct {
+		sender, receiver packetProtection
+		reason           RejectReason
+	}{
+		// the last bytes of the ciphertext are mistaken for the sender identity and signature appended by encrypt-then-sign
+		{protectionSignThenEncrypt, protectionEncryptThenSign, RejectReasonInvalidSignature},
+		// the appended sender identity and signature break the authentication of the ciphertext
+		{protectionEncryptThenSign, protectionSignThenEncrypt, RejectReasonDecryption},
+	} {
+		tc := tc
+		t.Run(fmt.Sprintf("packets protected by %s are rejected by servers expecting %s", tc.sender, tc.receiver), func(t *testing.T) {
+			t.Parallel()
+			fakeClock := clock.NewFake(start)
+			servers := newUnstartedLoopbackServers(t, realDependencies, nil, fakeClock, fakeClock)
+			servers[0].protection = tc.sender
+			servers[1].protection = tc.receiver
+			servers[1].events = NewEventBus()
+			rejected := make(chan RejectReason, 1)
+			servers[1].events.Subscribe(func(event Event) {
+				if e, ok := event.(PacketRejectedEvent); ok {
+					rejected <- e.Reason
+				}
+			})
+			startLoopbackServers(t, servers)
+			servers[0].SendPullRequest(servers[1].ownNode)
+			select {
+			case reason := <-rejected:
+				if reason != tc.reason {
+					t.Errorf("expected the packet to be rejected as %v, received %v", tc.reason, reason)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("packet was not rejected")
+			}
+		})
+	}
+	t.Run("packets whose header names another sender than the signature are rejected", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newUnstartedLoopbackServers(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, nil, fakeClock, fakeClock, fakeClock)
+		servers[1].protection = protectionEncryptThenSign
+		servers[1].events = NewEventBus()
+		rejected := make(chan RejectReason, 1)
+		servers[1].events.Subscribe(func(event Event) {
+			if e, ok := event.(PacketRejectedEvent); ok {
+				rejected <- e.Reason
+			}
+		})
+		startLoopbackServers(t, servers)
+		packet, err := NewPacketLeave(servers[2].ownNode.Identity, fakeClock.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		packetBytes, err := protectionEncryptThenSign.seal(servers[0].crypto, packet.ToBytes(), servers[0].ownNode.Identity, servers[1].ownNode.Identity, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		servers[1].handleIncomingBytes(packetBytes, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7001})
+		select {
+		case reason := <-rejected:
+			if reason != RejectReasonInvalidHeader {
+				t.Errorf("expected the packet to be rejected for its header, received %v", reason)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("packet was not rejected")
+		}
+		if len(servers[1].TakeLeftPeers()) != 0 {
+			t.Error("expected the leave packet naming another sender to be ignored")
+		}
+	})
+	t.Run("packets to unresolvable addresses are dropped while their circuit is open", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServersWith(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, fakeClock, fakeClock)
+		var resolvable atomic.Bool
+		lookup := servers[0].resolver.lookup
+		servers[0].resolver.lookup = func(address string) (*net.UDPAddr, error) {
+			if !resolvable.Load() {
+				return nil, errors.New("no such host")
+			}
+			return lookup(address)
+		}
+		peer := servers[1].ownNode
+		for i := 0; i < servers[0].cfg.SendFailureThreshold; i++ {
+			if err := servers[0].SendLeave(peer); err == nil || errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("expected packet %d to fail resolving the address, received %v", i, err)
+			}
+		}
+		if err := servers[0].SendLeave(peer); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected the packet to be dropped by the open circuit, received %v", err)
+		}
+		resolvable.Store(true)
+		fakeClock.Advance(time.Millisecond * time.Duration(servers[0].cfg.SendCircuitCooldownMs))
+		if err := servers[0].SendLeave(peer); err != nil {
+			t.Fatalf("expected the probe after the cooldown to be sent, received %v", err)
+		}
+		if state := servers[0].breaker.State(peer.Address); state != circuitClosed {
+			t.Errorf("expected the circuit to be closed after the probe, received %s", state)
+		}
+	})
+	t.Run("pull request is answered with stubbed crypto", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServersWith(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, fakeClock, fakeClock, fakeClock)
+		servers[1].UpdatePullResponseNodes([]Node{*servers[2].ownNode})
+		servers[0].SendPullRequest(servers[1].ownNode)
+		if node := receiveNode(t, servers[0].pullNodes); node.Identity != servers[2].ownNode.Identity {
+			t.Errorf("expected node %s to be pulled, received %s", servers[2].ownNode, node)
+		}
+	})
+	t.Run("spread messages are requested and stored by the announced peer only", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServersWith(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, fakeClock, fakeClock, fakeClock)
+		servers[0].spreadMessage(5, 1, []byte("spread"), false)
+		servers[0].SpreadMessages(servers[1].ownNode)
+		deadline := time.Now().Add(5 * time.Second)
+		for !storesMessage(servers[1], "spread") {
+			if time.Now().After(deadline) {
+				t.Fatal("announced peer did not request the spread message")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if storesMessage(servers[2], "spread") {
+			t.Error("peer that was not announced to stored the spread message")
+		}
+	})
+	t.Run("forwarded messages expire at the time their origin announced them at", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServersConfigured(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, []config.Option{config.WithProtocolVersion(int(OriginTimestampVersion)), config.WithMessageExpiryMs(60000)}, fakeClock, fakeClock, fakeClock)
+		servers[0].spreadMessage(5, 1, []byte("expiring"), false)
+		servers[0].SpreadMessages(servers[1].ownNode)
+		deadline := time.Now().Add(5 * time.Second)
+		for !storesMessage(servers[1], "expiring") {
+			if time.Now().After(deadline) {
+				t.Fatal("announced peer did not request the message")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		servers[1].SpreadMessages(servers[2].ownNode)
+		for !storesMessage(servers[2], "expiring") {
+			if time.Now().After(deadline) {
+				t.Fatal("forwarded message was not requested by the second hop")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		hash := sha256.Sum256([]byte("expiring"))
+		var expiries []time.Time
+		for _, s := range servers {
+			s.mutexMessages.RLock()
+			msg, _ := s.messages.Get(1, hash[:])
+			s.mutexMessages.RUnlock()
+			expiries = append(expiries, msg.ExpiresAt)
+		}
+		// origin timestamps are carried in milliseconds
+		for i, expiresAt := range expiries[1:] {
+			if !expiresAt.Equal(expiries[0].Truncate(time.Millisecond)) {
+				t.Errorf("expected hop %d to expire the message at %v like its origin, received %v", i+1, expiries[0], expiresAt)
+			}
+		}
+	})
+	t.Run("messages are no longer spread once the clock passed their expiry", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newUnstartedLoopbackServers(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, []config.Option{config.WithMessageExpiryMs(1000)}, fakeClock)
+		servers[0].spreadMessage(5, 1, []byte("expiring"), false)
+		if spreadable := servers[0].spreadableMessages(); len(spreadable) != 1 {
+			t.Fatalf("expected the message to be spread before its expiry, received %d messages", len(spreadable))
+		}
+		fakeClock.Advance(time.Second + time.Millisecond)
+		if spreadable := servers[0].spreadableMessages(); len(spreadable) != 0 {
+			t.Errorf("expected no message to be spread after the expiry, received %d messages", len(spreadable))
+		}
+		servers[0].ResetPeerStates()
+		hash := sha256.Sum256([]byte("expiring"))
+		servers[0].mutexMessages.RLock()
+		msg, _ := servers[0].messages.Get(1, hash[:])
+		servers[0].mutexMessages.RUnlock()
+		if msg.LocalTTL > 0 {
+			t.Errorf("expected the expired message to stop being spread at the end of the round, received a local TTL of %d", msg.LocalTTL)
+		}
+	})
+	t.Run("messages of outbound-only nodes are requested by the peers they are announced to", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newLoopbackServersConfigured(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: true}
+		}, []config.Option{config.WithParticipation(config.ParticipationOutboundOnly)}, fakeClock, fakeClock)
+		servers[0].spreadMessage(5, 1, []byte("outbound"), false)
+		servers[0].SpreadMessages(servers[1].ownNode)
+		deadline := time.Now().Add(5 * time.Second)
+		for !storesMessage(servers[1], "outbound") {
+			if time.Now().After(deadline) {
+				t.Fatal("peer did not request the message announced by the outbound-only node")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+	t.Run("push is rejected once the challenger rejects the solution", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newUnstartedLoopbackServers(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: false}
+		}, nil, fakeClock, fakeClock)
+		servers[1].events = NewEventBus()
+		failed := make(chan Identity, 1)
+		servers[1].events.Subscribe(func(event Event) {
+			if e, ok := event.(ChallengeFailedEvent); ok {
+				failed <- e.Identity
+			}
+		})
+		startLoopbackServers(t, servers)
+		servers[0].SendPushRequest(servers[1].ownNode)
+		select {
+		case identity := <-failed:
+			if identity != servers[0].ownNode.Identity {
+				t.Errorf("expected the challenge of %s to fail, received %s", servers[0].ownNode.Identity, identity)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("challenge did not fail")
+		}
+		select {
+		case node := <-servers[1].pushNodes:
+			t.Errorf("expected no node to be pushed, received %s", node)
+		default:
+		}
+	})
+	t.Run("packets of quarantined peers are dropped before decryption", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newUnstartedLoopbackServers(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+			return plaintextCrypto{}, stubChallenger{accept: false}
+		}, []config.Option{config.WithQuarantineThreshold(1)}, fakeClock, fakeClock)
+		servers[1].events = NewEventBus()
+		quarantined := make(chan string, 1)
+		servers[1].events.Subscribe(func(event Event) {
+			if e, ok := event.(PacketRejectedEvent); ok && e.Reason == RejectReasonQuarantined {
+				quarantined <- e.FromAddress
+			}
+		})
+		startLoopbackServers(t, servers)
+		servers[0].SendPushRequest(servers[1].ownNode)
+		deadline := time.Now().Add(5 * time.Second)
+		for len(servers[1].Quarantine()) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("peer failing its challenge was not quarantined")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if peer := servers[1].Quarantine()[0]; peer.Address != servers[0].ownNode.Address || peer.Identity != servers[0].ownNode.Identity {
+			t.Errorf("expected %s to be quarantined, received %s claiming %s", servers[0].ownNode.Address, peer.Address, peer.Identity)
+		}
+		if err := servers[0].SendLeave(servers[1].ownNode); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case address := <-quarantined:
+			if address != servers[0].ownNode.Address {
+				t.Errorf("expected the packet of %s to be dropped, received %s", servers[0].ownNode.Address, address)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("packet of the quarantined peer was not dropped")
+		}
+	})
+	t.Run("packets with invalid signatures quarantine their source address", func(t *testing.T) {
+		t.Parallel()
+		fakeClock := clock.NewFake(start)
+		servers := newUnstartedLoopbackServers(t, realDependencies, []config.Option{config.WithQuarantineThreshold(1)}, fakeClock, fakeClock)
+		servers[1].events = NewEventBus()
+		rejected := make(chan RejectReason, 2)
+		servers[1].events.Subscribe(func(event Event) {
+			if e, ok := event.(PacketRejectedEvent); ok {
+				rejected <- e.Reason
+			}
+		})
+		packet, err := NewPacketLeave(servers[0].ownNode.Identity, fakeClock.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		packetBytes, err := servers[0].protection.seal(servers[0].crypto, packet.ToBytes(), servers[0].ownNode.Identity, servers[1].ownNode.Identity, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forgedBytes := bytes.Clone(packetBytes)
+		forgedBytes[len(forgedBytes)-1] ^= 0xff
+		forgedAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7003}
+		servers[1].handleIncomingBytes(forgedBytes, forgedAddr)
+		quarantined := servers[1].Quarantine()
+		if len(quarantined) != 1 || quarantined[0].Address != forgedAddr.String() {
+			t.Fatalf("expected %s to be quarantined, received %v", forgedAddr, quarantined)
+		}
+		if reason := <-rejected; reason != RejectReasonInvalidSignature {
+			t.Errorf("expected the forged packet to be rejected as %s, received %s", RejectReasonInvalidSignature, reason)
+		}
+		servers[1].handleIncomingBytes(packetBytes, forgedAddr)
+		servers[1].handleIncomingBytes(packetBytes, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7004})
+		if reason := <-rejected; reason != RejectReasonQuarantined {
+			t.Errorf("expected the packet of the quarantined address to be rejected as %s, received %s", RejectReasonQuarantined, reason)
+		}
+		select {
+		case reason := <-rejected:
+			t.Errorf("expected the packet of another address to be accepted, received a rejection as %s", reason)
+		default:
+		}
+	})
+}
@@ -0,0 +1,429 @@
+package gossip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gossiphers/internal/clock"
+	"gossiphers/internal/config"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServer_isEagerPeer(t *testing.T) {
+	t.Parallel()
+	t.Run("all peers are eager without fanout", func(t *testing.T) {
+		s := &Server{cfg: &config.GossipConfig{}}
+		if !s.isEagerPeer(testIdentity("peer1")) {
+			t.Error("peer is not eager although lazy spreading is disabled")
+		}
+	})
+	t.Run("only the set peers are eager with fanout", func(t *testing.T) {
+		s := &Server{cfg: &config.GossipConfig{EagerFanout: 1}}
+		if s.isEagerPeer(testIdentity("peer1")) {
+			t.Error("peer is eager before any eager peers were set")
+		}
+		s.SetEagerPeers([]*Node{{Identity: testIdentity("peer1")}})
+		if !s.isEagerPeer(testIdentity("peer1")) {
+			t.Error("set peer is not eager")
+		}
+		if s.isEagerPeer(testIdentity("peer2")) {
+			t.Error("peer that was not set is eager")
+		}
+	})
+}
+
+func TestServer_handleLeave(t *testing.T) {
+	t.Parallel()
+	t.Run("left peers are taken once and published", func(t *testing.T) {
+		bus := NewEventBus()
+		var events []Event
+		bus.Subscribe(func(event Event) {
+			events = append(events, event)
+		})
+		s := &Server{leftPeers: make(map[Identity]struct{}), events: bus}
+		fromAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7002}
+		s.handleLeave(fromAddr, PacketLeave{PacketHeader: PacketHeader{SenderIdentity: testIdentity("peer1")}})
+		s.handleLeave(fromAddr, PacketLeave{PacketHeader: PacketHeader{SenderIdentity: testIdentity("peer1")}})
+
+		left := s.TakeLeftPeers()
+		if len(left) != 1 || left[0] != testIdentity("peer1") {
+			t.Errorf("expected peer1 to have left, received %v", left)
+		}
+		if left = s.TakeLeftPeers(); len(left) != 0 {
+			t.Errorf("expected left peers to be forgotten once taken, received %v", left)
+		}
+		if len(events) != 2 || events[0] != (PeerLeftEvent{Identity: testIdentity("peer1")}) {
+			t.Errorf("expected two PeerLeftEvents, received %v", events)
+		}
+	})
+}
+
+func TestServer_handlePong(t *testing.T) {
+	t.Parallel()
+	fromAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7002}
+	pong := func(sender Identity, nonce uint64) PacketPong {
+		return PacketPong{PacketHeader: PacketHeader{SenderIdentity: sender}, Nonce: nonce}
+	}
+	t.Run("only the pong echoing the nonce of the ping resolves it, and only once", func(t *testing.T) {
+		t.Parallel()
+		pongChannel := make(chan struct{}, 1)
+		s := &Server{clock: clock.NewFake(time.Unix(1000, 0)), pongChannels: map[outstandingPing]chan struct{}{{identity: testIdentity("peer1"), nonce: 42}: pongChannel}}
+		s.handlePong(fromAddr, pong(testIdentity("peer1"), 41))
+		s.handlePong(fromAddr, pong(testIdentity("peer2"), 42))
+		select {
+		case <-pongChannel:
+			t.Fatal("ping was resolved by a pong with another nonce or sender")
+		default:
+		}
+		s.handlePong(fromAddr, pong(testIdentity("peer1"), 42))
+		s.handlePong(fromAddr, pong(testIdentity("peer1"), 42))
+		select {
+		case <-pongChannel:
+		default:
+			t.Fatal("ping was not resolved by its pong")
+		}
+		if len(s.pongChannels) != 0 {
+			t.Errorf("expected the resolved ping to be forgotten, %d pings outstanding", len(s.pongChannels))
+		}
+		if s.firstResponseAt.Load() == 0 {
+			t.Error("expected the pong to be recorded as response")
+		}
+	})
+}
+
+func TestServer_handlePushChallenge(t *testing.T) {
+	t.Parallel()
+	t.Run("challenges beyond the accepted difficulty abort the push without being solved", func(t *testing.T) {
+		t.Parallel()
+		now := time.Unix(1000, 0)
+		s := &Server{
+			cfg:                   &config.GossipConfig{ChallengeMaxAcceptedDifficulty: 8},
+			clock:                 clock.NewFake(now),
+			pushes:                newPushTracker(time.Second),
+			challengeMaxSolveTime: time.Hour,
+		}
+		s.pushes.Request(testIdentity("peer1"), now)
+		fromAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7002}
+		// a difficulty of 255 bits would never be solved within the solve budget of an hour
+		s.handlePushChallenge(fromAddr, PacketPushChallenge{PacketHeader: PacketHeader{SenderIdentity: testIdentity("peer1")}, Difficulty: 255, Challenge: make([]byte, 8)})
+		if state := s.pushes.State(testIdentity("peer1")); state != pushIdle {
+			t.Errorf("expected the push to be aborted, received state %s", state)
+		}
+	})
+}
+
+// newFloodTestServer returns a server with an empty message store and the given flood handling, suitable for testing message storage without networking.
+func newFloodTestServer(threshold int, action string, policies map[uint16]config.DataTypePolicy) *Server {
+	return &Server{
+		cfg:      &config.GossipConfig{MessageFloodThreshold: threshold, MessageFloodAction: action, DataTypePolicies: policies},
+		clock:    clock.Real(),
+		messages: newMessageStore(100, 1<<20, policies),
+		delayed:  make(map[Identity][]delayedMessage),
+	}
+}
+
+// newTestPacketMessage returns a message packet of data type 1 sent by the given peer and the hash of its data.
+func newTestPacketMessage(data string, sender Identity) (PacketMessage, []byte) {
+	hash := sha256.Sum256([]byte(data))
+	return PacketMessage{PacketHeader: PacketHeader{SenderIdentity: sender}, TTL: 5, DataType: 1, Data: []byte(data)}, hash[:]
+}
+
+func TestServer_storeMessage(t *testing.T) {
+	t.Parallel()
+	t.Run("drops messages of flooding peers", func(t *testing.T) {
+		s := newFloodTestServer(1, config.FloodActionDrop, nil)
+		for i, data := range []string{"one", "two"} {
+			packet, hash := newTestPacketMessage(data, testIdentity("peer1"))
+			if added, _ := s.storeMessage(packet, hash); !added {
+				t.Errorf("message %d below the threshold was not added", i)
+			}
+		}
+		packet, hash := newTestPacketMessage("three", testIdentity("peer1"))
+		added, floodAction := s.storeMessage(packet, hash)
+		if added || floodAction != config.FloodActionDrop {
+			t.Errorf("flooding message was not dropped: added %v, action %s", added, floodAction)
+		}
+	})
+	t.Run("deprioritizes messages of flooding peers", func(t *testing.T) {
+		s := newFloodTestServer(0, config.FloodActionDeprioritize, nil)
+		s.storeMessage(newTestPacketMessage("one", testIdentity("peer1")))
+		packet, hash := newTestPacketMessage("two", testIdentity("peer1"))
+		added, floodAction := s.storeMessage(packet, hash)
+		msg, _ := s.messages.Get(1, hash)
+		if !added || floodAction != config.FloodActionDeprioritize || !msg.Deprioritized {
+			t.Errorf("flooding message was not deprioritized: added %v, action %s", added, floodAction)
+		}
+	})
+	t.Run("applies per data type thresholds to the data type only", func(t *testing.T) {
+		s := newFloodTestServer(100, config.FloodActionDrop, map[uint16]config.DataTypePolicy{1: {FloodThreshold: 1}})
+		other, otherHash := newTestPacketMessage("other", testIdentity("peer1"))
+		other.DataType = 2
+		s.storeMessage(other, otherHash)
+		s.storeMessage(newTestPacketMessage("one", testIdentity("peer1")))
+		if added, _ := s.storeMessage(newTestPacketMessage("two", testIdentity("peer1"))); !added {
+			t.Error("message was dropped although messages of other data types do not count towards the threshold")
+		}
+		if added, _ := s.storeMessage(newTestPacketMessage("three", testIdentity("peer1"))); added {
+			t.Error("message exceeding the data type's threshold was added")
+		}
+	})
+	t.Run("delays messages until the peer falls below the threshold", func(t *testing.T) {
+		s := newFloodTestServer(1, config.FloodActionDelay, nil)
+		first, firstHash := newTestPacketMessage("one", testIdentity("peer1"))
+		s.storeMessage(first, firstHash)
+		s.storeMessage(newTestPacketMessage("two", testIdentity("peer1")))
+		packet, hash := newTestPacketMessage("three", testIdentity("peer1"))
+		if added, floodAction := s.storeMessage(packet, hash); added || floodAction != config.FloodActionDelay {
+			t.Fatalf("flooding message was not delayed: added %v, action %s", added, floodAction)
+		}
+		if !s.delayMessage(packet, hash) || !s.delayMessage(packet, hash) {
+			t.Fatal("message was not held back")
+		}
+		if fourth, fourthHash := newTestPacketMessage("four", testIdentity("peer1")); s.delayMessage(fourth, fourthHash) {
+			t.Error("more messages than the threshold were held back")
+		}
+		if released := s.releaseDelayedMessages(); len(released) != 0 {
+			t.Errorf("released %d messages although the peer is still flooding", len(released))
+		}
+
+		s.messages.Remove(1, firstHash)
+		released := s.releaseDelayedMessages()
+		if len(released) != 1 || !s.messages.Contains(1, hash) || len(s.delayed) != 0 {
+			t.Errorf("delayed message was not released: %d released", len(released))
+		}
+	})
+}
+
+func TestServer_storeMessageTTL(t *testing.T) {
+	t.Parallel()
+	s := newFloodTestServer(100, config.FloodActionDrop, nil)
+	s.cfg.InfiniteTTLMaxRounds = 30
+	for _, tc := range []struct {
+		ttl              uint8
+		expectedTTL      uint8
+		expectedLocalTTL int
+	}{
+		{ttl: 0, expectedTTL: 0, expectedLocalTTL: 30},
+		{ttl: 1, expectedTTL: 1, expectedLocalTTL: 0},
+		{ttl: 5, expectedTTL: 4, expectedLocalTTL: 4},
+	} {
+		packet, hash := newTestPacketMessage(fmt.Sprintf("ttl %d", tc.ttl), testIdentity("peer1"))
+		packet.TTL = tc.ttl
+		s.storeMessage(packet, hash)
+		msg, ok := s.messages.Get(1, hash)
+		if !ok || msg.TTL != tc.expectedTTL || msg.LocalTTL != tc.expectedLocalTTL {
+			t.Errorf("message received with TTL %d stored with TTL %d and local TTL %d", tc.ttl, msg.TTL, msg.LocalTTL)
+		}
+	}
+}
+
+func TestServer_expiresAt(t *testing.T) {
+	t.Parallel()
+	s := newFloodTestServer(100, config.FloodActionDrop, map[uint16]config.DataTypePolicy{2: {ExpiryMs: 500}})
+	now := time.Now()
+	if expiresAt := s.expiresAt(1, now); !expiresAt.IsZero() {
+		t.Errorf("message expires without configured expiry: %v", expiresAt)
+	}
+	s.cfg.MessageExpiryMs = 1000
+	if expiresAt := s.expiresAt(1, now); !expiresAt.Equal(now.Add(time.Second)) {
+		t.Errorf("global expiry not applied: %v", expiresAt)
+	}
+	if expiresAt := s.expiresAt(2, now); !expiresAt.Equal(now.Add(500 * time.Millisecond)) {
+		t.Errorf("data type expiry not applied: %v", expiresAt)
+	}
+}
+
+func TestServer_storeMessageExpiry(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name string
+		// origin is the announcement time of the message relative to its receipt, nil if the message does not carry it
+		origin *time.Duration
+		stored bool
+		// capped origins are taken from the receipt
+		capped bool
+	}{
+		{name: "without origin timestamp", stored: true},
+		{name: "announced within the lifetime", origin: durationOf(-500 * time.Millisecond), stored: true},
+		{name: "announced before the lifetime", origin: durationOf(-2 * time.Second), stored: false},
+		{name: "announced in the future", origin: durationOf(time.Hour), stored: true, capped: true},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			s := newFloodTestServer(100, config.FloodActionDrop, nil)
+			s.cfg.MessageExpiryMs = 1000
+			packet, hash := newTestPacketMessage(tc.name, testIdentity("peer1"))
+			receivedAt := time.Now()
+			var originAt time.Time
+			if tc.origin != nil {
+				originAt = receivedAt.Add(*tc.origin)
+				packet.Flags = MessageFlagOriginTimestamp
+				packet.OriginTimestamp = uint64(originAt.UnixMilli())
+			}
+			if added, _ := s.storeMessage(packet, hash); added != tc.stored {
+				t.Fatalf("expected the message to be stored %t, received %t", tc.stored, added)
+			}
+			if !tc.stored {
+				return
+			}
+			msg, _ := s.messages.Get(1, hash)
+			switch {
+			case tc.origin == nil:
+				if !msg.OriginAt.IsZero() || msg.ExpiresAt.Before(receivedAt.Add(time.Second)) {
+					t.Errorf("expected the message to expire a second after its receipt, received origin %v and expiry %v", msg.OriginAt, msg.ExpiresAt)
+				}
+				return
+			case tc.capped:
+				if msg.OriginAt.Before(receivedAt) || msg.OriginAt.After(time.Now()) {
+					t.Errorf("expected the origin to be capped to the receipt at %v, received %v", receivedAt, msg.OriginAt)
+				}
+			default:
+				// origin timestamps are carried in milliseconds
+				if !msg.OriginAt.Equal(originAt.Truncate(time.Millisecond)) {
+					t.Errorf("expected the origin %v, received %v", originAt, msg.OriginAt)
+				}
+			}
+			if !msg.ExpiresAt.Equal(msg.OriginAt.Add(time.Second)) {
+				t.Errorf("expected the message to expire a second after its origin %v, received %v", msg.OriginAt, msg.ExpiresAt)
+			}
+		})
+	}
+}
+
+// durationOf returns a pointer to the given duration.
+func durationOf(d time.Duration) *time.Duration {
+	return &d
+}
+
+func TestServer_applyLaneBudgets(t *testing.T) {
+	t.Parallel()
+	// newLaneMessages returns the given number of high-priority messages followed by the given number of bulk messages.
+	newLaneMessages := func(highPriority int, bulk int) []spreadableMessage {
+		var messages []spreadableMessage
+		for i := 0; i < highPriority+bulk; i++ {
+			messages = append(messages, spreadableMessage{LocalTTL: 1, HighPriority: i < highPriority})
+		}
+		return messages
+	}
+	t.Run("keeps all messages without budgets", func(t *testing.T) {
+		t.Parallel()
+		s := &Server{cfg: &config.GossipConfig{}}
+		if limited := s.applyLaneBudgets(newLaneMessages(5, 5)); len(limited) != 10 {
+			t.Errorf("expected 10 messages, received %d", len(limited))
+		}
+	})
+	t.Run("limits each lane separately", func(t *testing.T) {
+		t.Parallel()
+		s := &Server{cfg: &config.GossipConfig{HighPriorityLaneBudget: 2, BulkLaneBudget: 3}}
+		limited := s.applyLaneBudgets(newLaneMessages(5, 5))
+		highPriority := 0
+		for _, msg := range limited {
+			if msg.inHighPriorityLane() {
+				highPriority++
+			}
+		}
+		if len(limited) != 5 || highPriority != 2 {
+			t.Errorf("expected 2 high-priority and 3 bulk messages, received %d of %d messages with high priority", highPriority, len(limited))
+		}
+	})
+}
+
+// TestServer_deliveryMetrics is not parallel, as every server publishes its delivery counts under the same metric.
+func TestServer_deliveryMetrics(t *testing.T) {
+	s := newLoopbackServers(t, clock.NewFake(time.Unix(1700000000, 0)))[0]
+	s.spreadMessage(5, 1, []byte("announced"), false)
+	hash := sha256.Sum256([]byte("announced"))
+	s.recordDeliveries(testIdentity("peer1"), []DigestEntry{{DataType: 1, DataHash: hash[:]}})
+
+	var counts []DeliveryCount
+	if err := json.Unmarshal([]byte(metrics.Get(metricDeliveries).String()), &counts); err != nil {
+		t.Fatal(err)
+	}
+	expected := DeliveryCount{DataType: 1, DataHash: hex.EncodeToString(hash[:]), Peers: 1}
+	if len(counts) != 1 || counts[0] != expected {
+		t.Errorf("expected the delivery count %v, received %v", expected, counts)
+	}
+}
+
+// newMessageRequestTestServers returns two started loopback servers with plaintext crypto and the address of the first one.
+func newMessageRequestTestServers(t *testing.T) ([]*Server, net.Addr) {
+	servers := newLoopbackServersWith(t, func(*testing.T, *config.GossipConfig) (PacketCrypto, Challenger) {
+		return plaintextCrypto{}, stubChallenger{accept: true}
+	}, clock.NewFake(time.Unix(1700000000, 0)), clock.NewFake(time.Unix(1700000000, 0)))
+	return servers, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7001}
+}
+
+// storesMessage returns whether the server stores the message with data type 1 and the given data.
+func storesMessage(s *Server, data string) bool {
+	hash := sha256.Sum256([]byte(data))
+	s.mutexMessages.RLock()
+	defer s.mutexMessages.RUnlock()
+	return s.messages.Contains(1, hash[:])
+}
+
+func TestServer_handleDigest(t *testing.T) {
+	t.Parallel()
+	t.Run("unsolicited digests only allow the requested messages", func(t *testing.T) {
+		t.Parallel()
+		servers, fromAddr := newMessageRequestTestServers(t)
+		sender := servers[0].ownNode.Identity
+		_, wantedHash := newTestPacketMessage("wanted", sender)
+		servers[1].handleDigest(fromAddr, PacketDigest{PacketHeader: PacketHeader{SenderIdentity: sender}, Entries: []DigestEntry{{DataType: 1, DataHash: wantedHash}}})
+		if servers[1].hasPeerCondition(sender, AllowMessage) {
+			t.Fatal("unsolicited digest allowed the peer to send any message")
+		}
+		for _, data := range []string{"unrequested", "wanted"} {
+			packet, _ := newTestPacketMessage(data, sender)
+			servers[1].handleMessage(fromAddr, packet)
+		}
+		if storesMessage(servers[1], "unrequested") {
+			t.Error("message that was not requested was stored")
+		}
+		if !storesMessage(servers[1], "wanted") {
+			t.Error("requested message was not stored")
+		}
+	})
+	t.Run("only the first digest of a peer within a round is answered", func(t *testing.T) {
+		t.Parallel()
+		servers, fromAddr := newMessageRequestTestServers(t)
+		sender := servers[0].ownNode.Identity
+		servers[1].handleDigest(fromAddr, PacketDigest{PacketHeader: PacketHeader{SenderIdentity: sender}})
+		for round, expected := range []bool{false, true} {
+			packet, hash := newTestPacketMessage(fmt.Sprintf("wanted %d", round), sender)
+			servers[1].handleDigest(fromAddr, PacketDigest{PacketHeader: PacketHeader{SenderIdentity: sender}, Entries: []DigestEntry{{DataType: 1, DataHash: hash}}})
+			servers[1].handleMessage(fromAddr, packet)
+			if stored := storesMessage(servers[1], string(packet.Data)); stored != expected {
+				t.Errorf("expected the message of the digest in round %d to be stored %t, received %t", round, expected, stored)
+			}
+			servers[1].ResetPeerStates()
+		}
+	})
+}
+
+func TestServer_handleIHave(t *testing.T) {
+	t.Parallel()
+	t.Run("unsolicited announcements do not open a message window", func(t *testing.T) {
+		t.Parallel()
+		servers, fromAddr := newMessageRequestTestServers(t)
+		sender := servers[0].ownNode.Identity
+		_, announcedHash := newTestPacketMessage("announced", sender)
+		servers[1].handleIHave(fromAddr, PacketIHave{PacketHeader: PacketHeader{SenderIdentity: sender}, Entries: []DigestEntry{{DataType: 1, DataHash: announcedHash}}})
+		if servers[1].hasPeerCondition(sender, AllowMessage) {
+			t.Fatal("unsolicited announcement allowed the peer to send any message")
+		}
+		for _, data := range []string{"unannounced", "announced", "announced"} {
+			packet, _ := newTestPacketMessage(data, sender)
+			servers[1].handleMessage(fromAddr, packet)
+		}
+		if storesMessage(servers[1], "unannounced") {
+			t.Error("message that was not announced was stored")
+		}
+		if !storesMessage(servers[1], "announced") {
+			t.Error("announced message was not stored")
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package gossip
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// StateDump represents a snapshot of the runtime state of a node for post-mortem debugging of convergence issues.
+type StateDump struct {
+	Time     time.Time   `json:"time"`
+	Round    int         `json:"round"`
+	Identity string      `json:"identity"`
+	MainView []NodeDump  `json:"main_view"`
+	PushView []NodeDump  `json:"push_view"`
+	PullView []NodeDump  `json:"pull_view"`
+	Samplers []NodeDump  `json:"samplers"`
+	Peers    []PeerDump  `json:"peers"`
+	Messages MessageDump `json:"messages"`
+}
+
+// NodeDump represents a node within a StateDump. Empty samplers are represented by an empty identity.
+type NodeDump struct {
+	Identity string    `json:"identity,omitempty"`
+	Address  string    `json:"address,omitempty"`
+	Source   string    `json:"source,omitempty"`
+	LastSeen time.Time `json:"last_seen,omitempty"`
+	RTTMs    int64     `json:"rtt_ms,omitempty"`
+	// Capabilities lists the names of the capabilities the node advertised within its own push
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// PeerDump represents the conditions, the state of our push and pull of the current round, the delayed messages, and the
+// protocol statistics of a single peer within a StateDump.
+type PeerDump struct {
+	Identity        string     `json:"identity"`
+	Conditions      []string   `json:"conditions,omitempty"`
+	Push            string     `json:"push,omitempty"`
+	Pull            string     `json:"pull,omitempty"`
+	DelayedMessages int        `json:"delayed_messages,omitempty"`
+	Stats           *PeerStats `json:"stats,omitempty"`
+}
+
+// MessageDump summarizes the message store within a StateDump, the data of the messages is omitted.
+type MessageDump struct {
+	Count      int                  `json:"count"`
+	Bytes      int                  `json:"bytes"`
+	Spreadable int                  `json:"spreadable"`
+	Entries    []MessageEntryDump   `json:"entries"`
+	ByType     map[uint16]TypeCount `json:"by_type"`
+}
+
+// TypeCount represents the number and total data size of the stored messages of a data type.
+type TypeCount struct {
+	Count int `json:"count"`
+	Bytes int `json:"bytes"`
+}
+
+// MessageEntryDump represents a single stored message within a StateDump.
+type MessageEntryDump struct {
+	DataType      uint16    `json:"data_type"`
+	DataHash      string    `json:"data_hash"`
+	Size          int       `json:"size"`
+	TTL           uint8     `json:"ttl"`
+	LocalTTL      int       `json:"local_ttl"`
+	Source        string    `json:"source"`
+	ReceivedAt    time.Time `json:"received_at"`
+	ExpiresAt     time.Time `json:"expires_at,omitempty"`
+	HighPriority  bool      `json:"high_priority,omitempty"`
+	Deprioritized bool      `json:"deprioritized,omitempty"`
+}
+
+// DumpState captures the current runtime state of the node.
+func (g *Gossip) DumpState() StateDump {
+	g.mainViewMu.RLock()
+	mainView := g.mainView
+	g.mainViewMu.RUnlock()
+	dump := StateDump{
+		Time:     g.clock.Now(),
+		Round:    int(g.round.Load()),
+		Identity: g.ownIdentity.String(),
+		MainView: dumpNodes(mainView.GetAll()),
+		PushView: dumpNodes(g.pushView.GetAll()),
+		PullView: dumpNodes(g.pullView.GetAll()),
+		Samplers: g.samplerGroup.dump(),
+	}
+	dump.Peers, dump.Messages = g.gossipServer.dump()
+	return dump
+}
+
+// WriteStateDump writes the current runtime state of the node as JSON to the file at path.
+func (g *Gossip) WriteStateDump(path string) error {
+	dumpBytes, err := json.MarshalIndent(g.DumpState(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, dumpBytes); err != nil {
+		return fmt.Errorf("could not write state dump file: %w", err)
+	}
+	return nil
+}
+
+// dumpNodes converts nodes to their representation within a StateDump.
+func dumpNodes(nodes []Node) []NodeDump {
+	dumps := make([]NodeDump, 0, len(nodes))
+	for _, node := range nodes {
+		dumps = append(dumps, dumpNode(&node))
+	}
+	return dumps
+}
+
+// dumpNode converts a node to its representation within a StateDump, an empty NodeDump if node is nil.
+func dumpNode(node *Node) NodeDump {
+	if node == nil {
+		return NodeDump{}
+	}
+	return NodeDump{
+		Identity:     node.Identity.String(),
+		Address:      node.Address,
+		Source:       node.Source.String(),
+		LastSeen:     node.LastSeen,
+		RTTMs:        node.RTT.Milliseconds(),
+		Capabilities: node.Capabilities.Names(),
+	}
+}
+
+// dump returns the elements of all samplers, including empty ones.
+func (sg *SamplerGroup) dump() []NodeDump {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	dumps := make([]NodeDump, len(sg.samplers))
+	for i := range sg.samplers {
+		dumps[i] = dumpNode(sg.samplers[i].elem)
+	}
+	return dumps
+}
+
+// dump returns the peers with conditions, pushes, pulls, delayed messages, or recorded activity and a summary of the message store.
+func (s *Server) dump() ([]PeerDump, MessageDump) {
+	peers := make(map[string]*PeerDump)
+	peer := func(identity string) *PeerDump {
+		if _, ok := peers[identity]; !ok {
+			peers[identity] = &PeerDump{Identity: identity}
+		}
+		return peers[identity]
+	}
+
+	s.mutexPeerState.Lock()
+	for identity, conditions := range s.peerState {
+		for _, condition := range conditions {
+			peer(identity.String()).Conditions = append(peer(identity.String()).Conditions, condition.String())
+		}
+	}
+	s.mutexPeerState.Unlock()
+	if s.pushes != nil {
+		for identity, state := range s.pushes.States() {
+			peer(identity.String()).Push = state.String()
+		}
+	}
+	if s.pulls != nil {
+		for identity, state := range s.pulls.States() {
+			peer(identity.String()).Pull = state.String()
+		}
+	}
+
+	if s.peerStats != nil {
+		for _, stats := range s.peerStats.Snapshot() {
+			stats := stats
+			peer(stats.Identity.String()).Stats = &stats
+		}
+	}
+
+	s.mutexMessages.RLock()
+	for identity, delayed := range s.delayed {
+		peer(identity.String()).DelayedMessages = len(delayed)
+	}
+	messages := s.messages.dump()
+	s.mutexMessages.RUnlock()
+
+	peerDumps := make([]PeerDump, 0, len(peers))
+	for _, dump := range peers {
+		peerDumps = append(peerDumps, *dump)
+	}
+	sort.Slice(peerDumps, func(i, j int) bool {
+		return peerDumps[i].Identity < peerDumps[j].Identity
+	})
+	return peerDumps, messages
+}
+
+// dump summarizes the stored messages, least recently received first.
+func (ms *messageStore) dump() MessageDump {
+	dump := MessageDump{
+		Count:   ms.order.Len(),
+		Bytes:   ms.bytes,
+		Entries: make([]MessageEntryDump, 0, ms.order.Len()),
+		ByType:  make(map[uint16]TypeCount, len(ms.countByType)),
+	}
+	for dataType, count := range ms.countByType {
+		dump.ByType[dataType] = TypeCount{Count: count, Bytes: ms.bytesByType[dataType]}
+	}
+	for element := ms.order.Front(); element != nil; element = element.Next() {
+		msg := element.Value.(*spreadableMessage)
+		if msg.LocalTTL > 0 {
+			dump.Spreadable++
+		}
+		dump.Entries = append(dump.Entries, MessageEntryDump{
+			DataType:      msg.DataType,
+			DataHash:      hex.EncodeToString(msg.DataHash),
+			Size:          len(msg.Data),
+			TTL:           msg.TTL,
+			LocalTTL:      msg.LocalTTL,
+			Source:        msg.SourceIdentity.String(),
+			ReceivedAt:    msg.ReceivedAt,
+			ExpiresAt:     msg.ExpiresAt,
+			HighPriority:  msg.HighPriority,
+			Deprioritized: msg.Deprioritized,
+		})
+	}
+	return dump
+}
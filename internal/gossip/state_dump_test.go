@@ -0,0 +1,43 @@
+package gossip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServer_dump(t *testing.T) {
+	t.Parallel()
+	t.Run("summarizes peers and messages", func(t *testing.T) {
+		t.Parallel()
+		s := &Server{
+			messages:  newMessageStore(10, 1024, nil),
+			delayed:   map[Identity][]delayedMessage{testIdentity("peer2"): {{}, {}}},
+			peerState: make(map[Identity][]peerCondition),
+			pushes:    newPushTracker(time.Second),
+			pulls:     newPullTracker(time.Second),
+		}
+		s.addPeerCondition(testIdentity("peer1"), AllowDigestRequest)
+		s.addPeerCondition(testIdentity("peer1"), AllowMessage)
+		s.pushes.Request(testIdentity("peer2"), time.Unix(1000, 0))
+		s.pulls.Request(testIdentity("peer1"), time.Unix(1000, 0))
+		s.messages.Add(newTestMessage("spread", 5, testIdentity("peer1")))
+		s.messages.Add(newTestMessage("remembered", 0, testIdentity("peer1")))
+
+		peers, messages := s.dump()
+		if len(peers) != 2 {
+			t.Fatalf("expected 2 peers, received %v", peers)
+		}
+		if peers[0].Identity != testIdentity("peer1").String() || len(peers[0].Conditions) != 2 || peers[0].Conditions[0] != "allow_digest_request" || peers[0].Pull != "outstanding" {
+			t.Errorf("unexpected conditions: %v", peers[0])
+		}
+		if peers[1].Identity != testIdentity("peer2").String() || peers[1].DelayedMessages != 2 || peers[1].Push != "requested" {
+			t.Errorf("unexpected delayed messages: %v", peers[1])
+		}
+		if messages.Count != 2 || messages.Spreadable != 1 || messages.Bytes != len("spread")+len("remembered") || messages.ByType[1].Count != 2 {
+			t.Errorf("unexpected message summary: %+v", messages)
+		}
+		if len(messages.Entries) != 2 || messages.Entries[0].Size != len("spread") || messages.Entries[0].Source != testIdentity("peer1").String() {
+			t.Errorf("unexpected message entries: %+v", messages.Entries)
+		}
+	})
+}
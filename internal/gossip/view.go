@@ -1,12 +1,28 @@
 package gossip
 
 import (
+	"crypto/rand"
+	"gossiphers/internal/clock"
+	"io"
+	"math/big"
 	"sync"
+	"time"
 )
 
 // View represents a view within Brahms algorithm.
+// A view contains each identity at most once and, if a maximum size is set, evicts random nodes to make room for new ones.
+// Nodes with the excluded identity, usually our own, are never added. Pinned nodes are never evicted or removed.
 type View struct {
-	nodes []Node
+	nodes    []Node
+	maxSize  int
+	excluded Identity
+	// pinned holds the identities of the pinned nodes, pinnedNodes the nodes passed in through WithPinnedNodes
+	pinned      map[Identity]struct{}
+	pinnedNodes []Node
+	// random is the source of randomness of the evictions, crypto/rand if nil
+	random io.Reader
+	// clock stamps the pings recorded for the nodes, the wall clock if nil
+	clock clock.Clock
 	mu    sync.Mutex
 }
 
@@ -19,6 +35,14 @@ func NewView(options ...Option) *View {
 	for _, option := range options {
 		option(v)
 	}
+
+	// enforce uniqueness and the maximum size on nodes passed in through options, pinned nodes first so they take precedence
+	nodes := append(append([]Node(nil), v.pinnedNodes...), v.nodes...)
+	v.pinnedNodes = nil
+	v.nodes = make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		v.appendLocked(node)
+	}
 	return v
 }
 
@@ -32,6 +56,46 @@ func WithBootstrapNodes(nodes []Node) Option {
 	}
 }
 
+// WithPinnedNodes adds nodes to the view that are never evicted to make room for other nodes and never removed.
+// They take up room within the view like any other node.
+func WithPinnedNodes(nodes []Node) Option {
+	return func(v *View) {
+		v.pinned = make(map[Identity]struct{}, len(nodes))
+		for _, node := range nodes {
+			v.pinned[node.Identity] = struct{}{}
+		}
+		v.pinnedNodes = nodes
+	}
+}
+
+// WithMaxSize limits the number of nodes within the view. A value of 0 represents no limit.
+func WithMaxSize(maxSize int) Option {
+	return func(v *View) {
+		v.maxSize = maxSize
+	}
+}
+
+// WithExcludedIdentity prevents nodes with the given identity, usually the identity of the local node, from being added to the view.
+func WithExcludedIdentity(identity Identity) Option {
+	return func(v *View) {
+		v.excluded = identity
+	}
+}
+
+// WithRandom sets the source of randomness the nodes evicted from a full view are chosen with, crypto/rand by default.
+func WithRandom(random io.Reader) Option {
+	return func(v *View) {
+		v.random = random
+	}
+}
+
+// WithClock sets the clock stamping the pings recorded for the nodes of the view, the wall clock by default.
+func WithClock(c clock.Clock) Option {
+	return func(v *View) {
+		v.clock = c
+	}
+}
+
 // Clear resets the view back to 0 nodes.
 func (v *View) Clear() {
 	v.mu.Lock()
@@ -39,14 +103,143 @@ func (v *View) Clear() {
 	v.nodes = make([]Node, 0, 30)
 }
 
-// Append adds a node to the view.
+// Append adds a node to the view unless a node with the same identity is already part of it, in which case only newer metadata is taken over.
+// If the view is full, a random node that is not pinned is evicted to make room for the new one, or the new node is dropped if all nodes are pinned.
 func (v *View) Append(n Node) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
+	v.appendLocked(n)
+}
+
+// appendLocked appends a node while the caller holds the lock.
+func (v *View) appendLocked(n Node) {
+	if v.isExcluded(n.Identity) {
+		return
+	}
+	for i := range v.nodes {
+		if v.nodes[i].Identity == n.Identity {
+			v.nodes[i].mergeMetadata(n)
+			return
+		}
+	}
+	if v.maxSize > 0 && len(v.nodes) >= v.maxSize {
+		evictable := make([]int, 0, len(v.nodes))
+		for i := range v.nodes {
+			if !v.isPinnedLocked(v.nodes[i].Identity) {
+				evictable = append(evictable, i)
+			}
+		}
+		if len(evictable) == 0 {
+			return
+		}
+		j, err := rand.Int(randomOr(v.random), big.NewInt(int64(len(evictable))))
+		if err != nil {
+			panic(err)
+		}
+		randomIndex := evictable[j.Int64()]
+		v.nodes[randomIndex] = v.nodes[len(v.nodes)-1]
+		v.nodes = v.nodes[:len(v.nodes)-1]
+	}
 	v.nodes = append(v.nodes, n)
 }
 
-// NodeCount returns the number of nodes in the view (not checked for uniqueness)
+// isExcluded checks whether nodes with the given identity must not be part of the view.
+func (v *View) isExcluded(identity Identity) bool {
+	return v.excluded != (Identity{}) && identity == v.excluded
+}
+
+// IsPinned checks whether the node with the given identity is pinned to the view.
+func (v *View) IsPinned(identity Identity) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.isPinnedLocked(identity)
+}
+
+// isPinnedLocked checks whether the node with the given identity is pinned while the caller holds the lock.
+func (v *View) isPinnedLocked(identity Identity) bool {
+	_, ok := v.pinned[identity]
+	return ok
+}
+
+// Remove removes the node with the given identity from the view. Returns false if no such node is part of the view or if it is pinned.
+func (v *View) Remove(identity Identity) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.isPinnedLocked(identity) {
+		return false
+	}
+	for i, node := range v.nodes {
+		if node.Identity == identity {
+			v.nodes = append(v.nodes[:i], v.nodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Contains checks whether a node with the given identity is part of the view.
+func (v *View) Contains(identity Identity) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, node := range v.nodes {
+		if node.Identity == identity {
+			return true
+		}
+	}
+	return false
+}
+
+// Replace replaces the node with the old identity by the new node, keeping its position within the view.
+// Returns false if no node with the old identity is part of the view, if it is pinned, if the new node's identity is already held by another node, or if it is excluded.
+func (v *View) Replace(old Identity, n Node) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	oldIndex := -1
+	for i, node := range v.nodes {
+		if node.Identity == old {
+			oldIndex = i
+		} else if node.Identity == n.Identity {
+			return false
+		}
+	}
+	if oldIndex < 0 || v.isPinnedLocked(old) || v.isExcluded(n.Identity) {
+		return false
+	}
+	v.nodes[oldIndex] = n
+	return true
+}
+
+// RecordPing stores the round trip time of a successful ping to the node with the given identity and marks it as seen.
+// Returns false if no such node is part of the view.
+func (v *View) RecordPing(identity Identity, rtt time.Duration) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i := range v.nodes {
+		if v.nodes[i].Identity == identity {
+			v.nodes[i].RTT = rtt
+			v.nodes[i].LastSeen = clock.Or(v.clock).Now()
+			return true
+		}
+	}
+	return false
+}
+
+// MergeMetadata takes over the newer metadata of the given nodes that are part of the view, e.g. of the previous main view
+// when it is replaced, without adding any other node.
+func (v *View) MergeMetadata(nodes []Node) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, node := range nodes {
+		for i := range v.nodes {
+			if v.nodes[i].Identity == node.Identity {
+				v.nodes[i].mergeMetadata(node)
+				break
+			}
+		}
+	}
+}
+
+// NodeCount returns the number of nodes in the view
 func (v *View) NodeCount() int {
 	v.mu.Lock()
 	defer v.mu.Unlock()
@@ -0,0 +1,58 @@
+package gossip
+
+// ViewChange describes how the main view changed when it was replaced at the end of a round.
+type ViewChange struct {
+	// Round is the round at whose end the main view was replaced.
+	Round int
+	// Added contains nodes that are part of the new view but were not part of the old one.
+	Added []Node
+	// Removed contains nodes that were part of the old view but are not part of the new one.
+	Removed []Node
+}
+
+// ViewChangeHandler represents a handler that is called whenever the membership of the main view changes.
+// Handlers are called synchronously from the gossip loop and should therefore return quickly.
+type ViewChangeHandler func(change ViewChange)
+
+// RegisterViewChangeHandler registers a ViewChangeHandler. Handlers must be registered before the gossip protocol is started.
+func (g *Gossip) RegisterViewChangeHandler(fn ViewChangeHandler) {
+	g.viewChangeHandlers = append(g.viewChangeHandlers, fn)
+}
+
+// notifyViewChange calls all registered handlers with the difference between the old and the new main view, unless the membership did not change.
+// A PeerAddedEvent is published for each node that joined the main view. Returns the nodes that joined and left the main view.
+func (g *Gossip) notifyViewChange(round int, oldNodes []Node, newNodes []Node) (added []Node, removed []Node) {
+	added, removed = diffNodes(oldNodes, newNodes)
+	if len(added) == 0 && len(removed) == 0 {
+		return added, removed
+	}
+	for _, node := range added {
+		g.events.Publish(PeerAddedEvent{Round: round, Node: node})
+	}
+	change := ViewChange{Round: round, Added: added, Removed: removed}
+	for _, handler := range g.viewChangeHandlers {
+		handler(change)
+	}
+	return added, removed
+}
+
+// diffNodes returns the nodes only contained in newNodes and the nodes only contained in oldNodes, compared by identity and address.
+func diffNodes(oldNodes []Node, newNodes []Node) (added []Node, removed []Node) {
+	oldSet := make(map[string]struct{}, len(oldNodes))
+	for _, node := range oldNodes {
+		oldSet[node.String()] = struct{}{}
+	}
+	newSet := make(map[string]struct{}, len(newNodes))
+	for _, node := range newNodes {
+		newSet[node.String()] = struct{}{}
+		if _, ok := oldSet[node.String()]; !ok {
+			added = append(added, node)
+		}
+	}
+	for _, node := range oldNodes {
+		if _, ok := newSet[node.String()]; !ok {
+			removed = append(removed, node)
+		}
+	}
+	return added, removed
+}
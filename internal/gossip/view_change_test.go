@@ -0,0 +1,58 @@
+package gossip
+
+import (
+	"testing"
+)
+
+func TestViewChange_diffNodes(t *testing.T) {
+	t.Parallel()
+	t.Run("reports added and removed nodes", func(t *testing.T) {
+		nodes, err := createNodes(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		added, removed := diffNodes([]Node{nodes[0], nodes[1], nodes[2]}, []Node{nodes[1], nodes[2], nodes[3]})
+		if len(added) != 1 || added[0].Identity != nodes[3].Identity {
+			t.Errorf("unexpected added nodes: %v", added)
+		}
+		if len(removed) != 1 || removed[0].Identity != nodes[0].Identity {
+			t.Errorf("unexpected removed nodes: %v", removed)
+		}
+	})
+	t.Run("treats an address change as a replacement", func(t *testing.T) {
+		nodes, err := createNodes(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moved := nodes[0]
+		moved.Address = "elsewhere:7002"
+		added, removed := diffNodes(nodes, []Node{moved})
+		if len(added) != 1 || len(removed) != 1 {
+			t.Errorf("expected the moved node to be added and removed, received %v and %v", added, removed)
+		}
+	})
+}
+
+func TestViewChange_notifyViewChange(t *testing.T) {
+	t.Parallel()
+	t.Run("handlers are only called on membership changes", func(t *testing.T) {
+		nodes, err := createNodes(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := &Gossip{}
+		var changes []ViewChange
+		g.RegisterViewChangeHandler(func(change ViewChange) {
+			changes = append(changes, change)
+		})
+
+		g.notifyViewChange(1, nodes, nodes)
+		if len(changes) != 0 {
+			t.Fatalf("handler was called without membership change")
+		}
+		g.notifyViewChange(2, nodes, nodes[:1])
+		if len(changes) != 1 || changes[0].Round != 2 || len(changes[0].Removed) != 1 || len(changes[0].Added) != 0 {
+			t.Fatalf("unexpected view changes: %v", changes)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package gossip
+
+import (
+	"fmt"
+	"io"
+)
+
+// ViewUpdateInput contains everything a ViewUpdatePolicy may base the next main view on.
+type ViewUpdateInput struct {
+	// PushNodes are the nodes that pushed their identity to us during the round.
+	PushNodes []Node
+	// PullNodes are the nodes received within pull responses during the round.
+	PullNodes []Node
+	// PullRequests is the number of pull requests sent during the round.
+	PullRequests int
+	// Samplers provides the history samples.
+	Samplers *SamplerGroup
+	// AlphaL1, BetaL1, and GammaL1 are the number of pushed, pulled, and sampled nodes within the next view.
+	AlphaL1 int
+	BetaL1  int
+	GammaL1 int
+	// Random is the source of randomness of the node selection, crypto/rand if nil.
+	Random io.Reader
+}
+
+// ViewUpdatePolicy decides on the main view at the end of a round.
+type ViewUpdatePolicy interface {
+	// NextView returns the nodes of the next main view. If false is returned, the current view is kept.
+	NextView(input ViewUpdateInput) ([]Node, bool, error)
+}
+
+// BrahmsViewUpdatePolicy implements the view update rule of Brahms: the next view consists of up to AlphaL1 pushed, BetaL1 pulled, and GammaL1 sampled nodes.
+// The view is only updated if at least one node was pushed and at most AlphaL1 nodes were pushed, as more pushes indicate a push flood by an adversary.
+// Unless no pull requests were sent, at least one pulled node is required as well, so a round without responses does not replace the view.
+type BrahmsViewUpdatePolicy struct{}
+
+// NextView returns the next main view according to the Brahms view update rule.
+func (p BrahmsViewUpdatePolicy) NextView(input ViewUpdateInput) ([]Node, bool, error) {
+	if len(input.PushNodes) == 0 || len(input.PushNodes) > input.AlphaL1 {
+		return nil, false, nil
+	}
+	if len(input.PullNodes) == 0 && input.PullRequests > 0 {
+		return nil, false, nil
+	}
+
+	pushSubset, err := randSubset(input.Random, input.PushNodes, input.AlphaL1)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not select pushed nodes: %w", err)
+	}
+	pullSubset, err := randSubset(input.Random, input.PullNodes, input.BetaL1)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not select pulled nodes: %w", err)
+	}
+	var samplerSubset []*Node
+	if input.Samplers != nil {
+		samplerSubset, err = input.Samplers.RandomNodeSubset(input.GammaL1)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not select sampled nodes: %w", err)
+		}
+	}
+	return trimDuplicates(pullSubset, pushSubset, samplerSubset), true, nil
+}
+
+// OutboundOnlyViewUpdatePolicy implements the view update of outbound-only nodes, which never receive pushes: the next view
+// consists of up to AlphaL1+BetaL1 pulled and GammaL1 sampled nodes. The view is updated whenever at least one node was pulled.
+// Without pushes, the push limit does not protect the view against floods, only the samplers dilute poisoned pull responses.
+type OutboundOnlyViewUpdatePolicy struct{}
+
+// NextView returns the next main view of pulled and sampled nodes.
+func (p OutboundOnlyViewUpdatePolicy) NextView(input ViewUpdateInput) ([]Node, bool, error) {
+	if len(input.PullNodes) == 0 {
+		return nil, false, nil
+	}
+	pullSubset, err := randSubset(input.Random, input.PullNodes, input.AlphaL1+input.BetaL1)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not select pulled nodes: %w", err)
+	}
+	var samplerSubset []*Node
+	if input.Samplers != nil {
+		samplerSubset, err = input.Samplers.RandomNodeSubset(input.GammaL1)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not select sampled nodes: %w", err)
+		}
+	}
+	return trimDuplicates(pullSubset, samplerSubset), true, nil
+}
@@ -0,0 +1,113 @@
+package gossip
+
+import (
+	"testing"
+)
+
+func TestBrahmsViewUpdatePolicy_NextView(t *testing.T) {
+	t.Parallel()
+	t.Run("mixes pushed, pulled, and sampled nodes proportionally", func(t *testing.T) {
+		nodes, err := createNodes(30)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samplers, err := NewSamplerGroup(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samplers.Update(nodes[20:])
+
+		next, update, err := BrahmsViewUpdatePolicy{}.NextView(ViewUpdateInput{
+			PushNodes:    nodes[:4],
+			PullNodes:    nodes[4:20],
+			PullRequests: 3,
+			Samplers:     samplers,
+			AlphaL1:      4,
+			BetaL1:       4,
+			GammaL1:      2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !update {
+			t.Fatal("expected the view to be updated")
+		}
+		pushed, pulled, sampled := 0, 0, 0
+		for _, node := range next {
+			for i := range nodes {
+				if nodes[i].Identity != node.Identity {
+					continue
+				}
+				switch {
+				case i < 4:
+					pushed++
+				case i < 20:
+					pulled++
+				default:
+					sampled++
+				}
+			}
+		}
+		if pushed != 4 || pulled != 4 || sampled < 1 || sampled > 2 {
+			t.Errorf("unexpected mix of %d pushed, %d pulled, and %d sampled nodes", pushed, pulled, sampled)
+		}
+	})
+	t.Run("keeps the view on push floods and missing pushes", func(t *testing.T) {
+		nodes, err := createNodes(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, update, err := BrahmsViewUpdatePolicy{}.NextView(ViewUpdateInput{PushNodes: nodes[:5], PullNodes: nodes[5:], PullRequests: 1, AlphaL1: 4, BetaL1: 4})
+		if err != nil || update {
+			t.Errorf("expected the view to be kept on push flood, received update=%t, err=%v", update, err)
+		}
+		_, update, err = BrahmsViewUpdatePolicy{}.NextView(ViewUpdateInput{PullNodes: nodes[5:], PullRequests: 1, AlphaL1: 4, BetaL1: 4})
+		if err != nil || update {
+			t.Errorf("expected the view to be kept without pushes, received update=%t, err=%v", update, err)
+		}
+	})
+	t.Run("requires pulled nodes only if pull requests were sent", func(t *testing.T) {
+		nodes, err := createNodes(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, update, err := BrahmsViewUpdatePolicy{}.NextView(ViewUpdateInput{PushNodes: nodes, PullRequests: 1, AlphaL1: 4, BetaL1: 4})
+		if err != nil || update {
+			t.Errorf("expected the view to be kept without pull responses, received update=%t, err=%v", update, err)
+		}
+		next, update, err := BrahmsViewUpdatePolicy{}.NextView(ViewUpdateInput{PushNodes: nodes, AlphaL1: 4, BetaL1: 0})
+		if err != nil || !update || len(next) != 2 {
+			t.Errorf("expected the view to be updated with the pushed nodes, received %v, update=%t, err=%v", next, update, err)
+		}
+	})
+}
+
+func TestOutboundOnlyViewUpdatePolicy_NextView(t *testing.T) {
+	t.Parallel()
+	t.Run("fills the share of pushed nodes with pulled nodes", func(t *testing.T) {
+		nodes, err := createNodes(20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next, update, err := OutboundOnlyViewUpdatePolicy{}.NextView(ViewUpdateInput{PullNodes: nodes, PullRequests: 3, AlphaL1: 4, BetaL1: 4, GammaL1: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !update || len(next) != 8 {
+			t.Errorf("expected the view to be updated with 8 pulled nodes, received %v", next)
+		}
+	})
+	t.Run("keeps the view without pulled nodes", func(t *testing.T) {
+		nodes, err := createNodes(5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, update, err := OutboundOnlyViewUpdatePolicy{}.NextView(ViewUpdateInput{PushNodes: nodes, PullRequests: 1, AlphaL1: 4, BetaL1: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if update {
+			t.Error("expected the view to be kept without pulled nodes")
+		}
+	})
+}
@@ -1,8 +1,10 @@
 package gossip
 
 import (
+	"gossiphers/internal/clock"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestView_WithBootstrapNodes(t *testing.T) {
@@ -10,12 +12,12 @@ func TestView_WithBootstrapNodes(t *testing.T) {
 	t.Run("the view's node slice is set to the passed-in bootstrap node slice", func(t *testing.T) {
 		// Create some mock nodes and append them to the View
 		node1 := Node{
-			Identity: "id1",
+			Identity: testIdentity("id1"),
 			Address:  "node1.example.com",
 		}
 
 		node2 := Node{
-			Identity: "id2",
+			Identity: testIdentity("id2"),
 			Address:  "node2.example.com",
 		}
 
@@ -28,11 +30,11 @@ func TestView_WithBootstrapNodes(t *testing.T) {
 		}
 
 		// Check if the nodes were appended correctly
-		if view.nodes[0].Identity != "id1" || view.nodes[0].Address != "node1.example.com" {
+		if view.nodes[0].Identity != testIdentity("id1") || view.nodes[0].Address != "node1.example.com" {
 			t.Fatalf("Node 1 was not appended correctly")
 		}
 
-		if view.nodes[1].Identity != "id2" || view.nodes[1].Address != "node2.example.com" {
+		if view.nodes[1].Identity != testIdentity("id2") || view.nodes[1].Address != "node2.example.com" {
 			t.Fatalf("Node 2 was not appended correctly")
 		}
 	})
@@ -46,12 +48,12 @@ func TestView_Clear(t *testing.T) {
 
 		// Create some mock nodes and append them to the View
 		node1 := Node{
-			Identity: "id1",
+			Identity: testIdentity("id1"),
 			Address:  "node1.example.com",
 		}
 
 		node2 := Node{
-			Identity: "id2",
+			Identity: testIdentity("id2"),
 			Address:  "node2.example.com",
 		}
 
@@ -76,12 +78,12 @@ func TestView_Append(t *testing.T) {
 
 		// Create some mock nodes
 		node1 := Node{
-			Identity: "id1",
+			Identity: testIdentity("id1"),
 			Address:  "node1.example.com",
 		}
 
 		node2 := Node{
-			Identity: "id2",
+			Identity: testIdentity("id2"),
 			Address:  "node2.example.com",
 		}
 
@@ -95,11 +97,11 @@ func TestView_Append(t *testing.T) {
 		}
 
 		// Check if the nodes were appended correctly
-		if view.nodes[0].Identity != "id1" || view.nodes[0].Address != "node1.example.com" {
+		if view.nodes[0].Identity != testIdentity("id1") || view.nodes[0].Address != "node1.example.com" {
 			t.Fatalf("Node 1 was not appended correctly")
 		}
 
-		if view.nodes[1].Identity != "id2" || view.nodes[1].Address != "node2.example.com" {
+		if view.nodes[1].Identity != testIdentity("id2") || view.nodes[1].Address != "node2.example.com" {
 			t.Fatalf("Node 2 was not appended correctly")
 		}
 	})
@@ -113,11 +115,11 @@ func TestView_GetAll(t *testing.T) {
 
 		// Mock nodes to add to the View
 		node1 := Node{
-			Identity: "id1",
+			Identity: testIdentity("id1"),
 			Address:  "address1",
 		}
 		node2 := Node{
-			Identity: "id2",
+			Identity: testIdentity("id2"),
 			Address:  "address2",
 		}
 
@@ -144,3 +146,253 @@ func TestView_GetAll(t *testing.T) {
 		}
 	})
 }
+
+func TestView_AppendDeduplication(t *testing.T) {
+	t.Parallel()
+	t.Run("append ignores nodes with an identity already in the view", func(t *testing.T) {
+		view := NewView()
+		view.Append(Node{Identity: testIdentity("id1"), Address: "node1.example.com"})
+		view.Append(Node{Identity: testIdentity("id1"), Address: "node1.example.com"})
+		view.Append(Node{Identity: testIdentity("id1"), Address: "other.example.com"})
+
+		if view.NodeCount() != 1 {
+			t.Fatalf("Expected 1 node, but got %d", view.NodeCount())
+		}
+		if view.nodes[0].Address != "node1.example.com" {
+			t.Fatalf("Node was replaced by a duplicate")
+		}
+	})
+	t.Run("bootstrap nodes are deduplicated", func(t *testing.T) {
+		node1 := Node{Identity: testIdentity("id1"), Address: "node1.example.com"}
+		node2 := Node{Identity: testIdentity("id2"), Address: "node2.example.com"}
+		view := NewView(WithBootstrapNodes([]Node{node1, node2, node1}))
+
+		if view.NodeCount() != 2 {
+			t.Fatalf("Expected 2 nodes, but got %d", view.NodeCount())
+		}
+	})
+}
+
+func TestView_WithMaxSize(t *testing.T) {
+	t.Parallel()
+	t.Run("append evicts a random node once the view is full", func(t *testing.T) {
+		nodes, err := createNodes(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		view := NewView(WithMaxSize(5))
+		for _, node := range nodes {
+			view.Append(node)
+		}
+
+		if view.NodeCount() != 5 {
+			t.Fatalf("Expected 5 nodes, but got %d", view.NodeCount())
+		}
+		// the most recently appended node is never evicted right away
+		if view.nodes[4].Identity != nodes[9].Identity {
+			t.Fatalf("Most recently appended node is missing")
+		}
+	})
+	t.Run("bootstrap nodes are trimmed to the maximum size", func(t *testing.T) {
+		nodes, err := createNodes(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		view := NewView(WithBootstrapNodes(nodes), WithMaxSize(3))
+
+		if view.NodeCount() != 3 {
+			t.Fatalf("Expected 3 nodes, but got %d", view.NodeCount())
+		}
+	})
+	t.Run("a maximum size of 0 does not limit the view", func(t *testing.T) {
+		nodes, err := createNodes(50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		view := NewView(WithMaxSize(0))
+		for _, node := range nodes {
+			view.Append(node)
+		}
+
+		if view.NodeCount() != 50 {
+			t.Fatalf("Expected 50 nodes, but got %d", view.NodeCount())
+		}
+	})
+}
+
+func TestView_Remove(t *testing.T) {
+	t.Parallel()
+	t.Run("remove deletes only the node with the given identity", func(t *testing.T) {
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("id1"), Address: "address1"}, {Identity: testIdentity("id2"), Address: "address2"}, {Identity: testIdentity("id3"), Address: "address3"}}))
+
+		if !view.Remove(testIdentity("id2")) {
+			t.Fatalf("Remove did not find the node")
+		}
+		if view.NodeCount() != 2 || view.nodes[0].Identity != testIdentity("id1") || view.nodes[1].Identity != testIdentity("id3") {
+			t.Fatalf("Unexpected nodes after removal: %v", view.nodes)
+		}
+		if view.Remove(testIdentity("id2")) {
+			t.Fatalf("Remove reported success for a node that is not part of the view")
+		}
+	})
+}
+
+func TestView_Contains(t *testing.T) {
+	t.Parallel()
+	t.Run("contains reports whether an identity is part of the view", func(t *testing.T) {
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("id1"), Address: "address1"}}))
+
+		if !view.Contains(testIdentity("id1")) {
+			t.Fatalf("Expected id1 to be part of the view")
+		}
+		if view.Contains(testIdentity("id2")) {
+			t.Fatalf("Expected id2 not to be part of the view")
+		}
+	})
+}
+
+func TestView_Replace(t *testing.T) {
+	t.Parallel()
+	t.Run("replace swaps the node in place", func(t *testing.T) {
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("id1"), Address: "address1"}, {Identity: testIdentity("id2"), Address: "address2"}}))
+
+		if !view.Replace(testIdentity("id1"), Node{Identity: testIdentity("id3"), Address: "address3"}) {
+			t.Fatalf("Replace did not succeed")
+		}
+		if view.nodes[0].Identity != testIdentity("id3") || view.nodes[0].Address != "address3" || view.nodes[1].Identity != testIdentity("id2") {
+			t.Fatalf("Unexpected nodes after replacement: %v", view.nodes)
+		}
+	})
+	t.Run("replace updates the address of the same identity", func(t *testing.T) {
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("id1"), Address: "address1"}}))
+
+		if !view.Replace(testIdentity("id1"), Node{Identity: testIdentity("id1"), Address: "address2"}) {
+			t.Fatalf("Replace did not succeed")
+		}
+		if view.nodes[0].Address != "address2" {
+			t.Fatalf("Address was not updated")
+		}
+	})
+	t.Run("replace fails for unknown or duplicate identities", func(t *testing.T) {
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("id1"), Address: "address1"}, {Identity: testIdentity("id2"), Address: "address2"}}))
+
+		if view.Replace(testIdentity("id3"), Node{Identity: testIdentity("id4"), Address: "address4"}) {
+			t.Fatalf("Replace succeeded for an unknown identity")
+		}
+		if view.Replace(testIdentity("id1"), Node{Identity: testIdentity("id2"), Address: "address2"}) {
+			t.Fatalf("Replace created a duplicate identity")
+		}
+		if view.NodeCount() != 2 || view.nodes[0].Identity != testIdentity("id1") {
+			t.Fatalf("View was modified by failed replacements: %v", view.nodes)
+		}
+	})
+}
+
+func TestView_Metadata(t *testing.T) {
+	t.Parallel()
+	t.Run("append of a known node takes over newer metadata only", func(t *testing.T) {
+		seen := time.Now()
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("id1"), Address: "address1", Source: SourceBootstrap, LastSeen: seen}}))
+
+		view.Append(Node{Identity: testIdentity("id1"), Address: "address1", Source: SourcePull})
+		nodes := view.GetAll()
+		if nodes[0].Source != SourceBootstrap || !nodes[0].LastSeen.Equal(seen) {
+			t.Fatalf("Metadata was overwritten by older information: %+v", nodes[0])
+		}
+
+		view.Append(Node{Identity: testIdentity("id1"), Address: "address1", Source: SourcePush, LastSeen: seen.Add(time.Second)})
+		nodes = view.GetAll()
+		if !nodes[0].LastSeen.Equal(seen.Add(time.Second)) {
+			t.Fatalf("Last seen timestamp was not updated: %+v", nodes[0])
+		}
+	})
+	t.Run("record ping stores the rtt and marks the node as seen", func(t *testing.T) {
+		fakeClock := clock.NewFake(time.Unix(1000, 0))
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("id1"), Address: "address1"}}), WithClock(fakeClock))
+
+		if view.RecordPing(testIdentity("id2"), time.Millisecond) {
+			t.Fatalf("RecordPing succeeded for an unknown node")
+		}
+		if !view.RecordPing(testIdentity("id1"), 5*time.Millisecond) {
+			t.Fatalf("RecordPing did not find the node")
+		}
+		nodes := view.GetAll()
+		if nodes[0].RTT != 5*time.Millisecond || !nodes[0].LastSeen.Equal(fakeClock.Now()) {
+			t.Fatalf("Ping was not recorded: %+v", nodes[0])
+		}
+	})
+	t.Run("merging the metadata of a previous view keeps the metadata of staying nodes only", func(t *testing.T) {
+		seen := time.Now()
+		previous := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("id1"), Address: "address1"}, {Identity: testIdentity("id2"), Address: "address2"}}))
+		previous.RecordPing(testIdentity("id1"), 5*time.Millisecond)
+		previous.RecordPing(testIdentity("id2"), 7*time.Millisecond)
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("id1"), Address: "address1", Source: SourcePull}, {Identity: testIdentity("id3"), Address: "address3"}}))
+
+		view.MergeMetadata(previous.GetAll())
+		nodes := view.GetAll()
+		if len(nodes) != 2 || view.Contains(testIdentity("id2")) {
+			t.Fatalf("Nodes of the previous view were added: %+v", nodes)
+		}
+		if nodes[0].RTT != 5*time.Millisecond || nodes[0].LastSeen.Before(seen) || nodes[0].Source != SourcePull {
+			t.Fatalf("Metadata of the staying node was not taken over: %+v", nodes[0])
+		}
+		if nodes[1].RTT != 0 || !nodes[1].LastSeen.IsZero() {
+			t.Fatalf("Metadata was taken over for a new node: %+v", nodes[1])
+		}
+	})
+}
+
+func TestView_WithExcludedIdentity(t *testing.T) {
+	t.Parallel()
+	t.Run("the excluded identity is never added to the view", func(t *testing.T) {
+		view := NewView(WithBootstrapNodes([]Node{{Identity: testIdentity("own"), Address: "address0"}, {Identity: testIdentity("id1"), Address: "address1"}}), WithExcludedIdentity(testIdentity("own")))
+
+		if view.Contains(testIdentity("own")) || view.NodeCount() != 1 {
+			t.Fatalf("Excluded identity was taken over from the bootstrap nodes: %v", view.nodes)
+		}
+		view.Append(Node{Identity: testIdentity("own"), Address: "address0"})
+		if view.Contains(testIdentity("own")) {
+			t.Fatalf("Excluded identity was appended")
+		}
+		if view.Replace(testIdentity("id1"), Node{Identity: testIdentity("own"), Address: "address0"}) || view.Contains(testIdentity("own")) {
+			t.Fatalf("Excluded identity replaced another node")
+		}
+	})
+}
+
+func TestView_WithPinnedNodes(t *testing.T) {
+	t.Parallel()
+	pinned := []Node{{Identity: testIdentity("hub1"), Address: "address1"}, {Identity: testIdentity("hub2"), Address: "address2"}}
+	t.Run("pinned nodes are never evicted from a full view", func(t *testing.T) {
+		nodes, err := createNodes(20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		view := NewView(WithBootstrapNodes(nodes), WithPinnedNodes(pinned), WithMaxSize(3))
+		for _, node := range nodes {
+			view.Append(node)
+		}
+		if view.NodeCount() != 3 || !view.Contains(testIdentity("hub1")) || !view.Contains(testIdentity("hub2")) {
+			t.Fatalf("Expected both pinned nodes within the full view, but got %v", view.nodes)
+		}
+	})
+	t.Run("pinned nodes are neither removed nor replaced", func(t *testing.T) {
+		view := NewView(WithPinnedNodes(pinned))
+		if view.Remove(testIdentity("hub1")) || !view.Contains(testIdentity("hub1")) {
+			t.Fatalf("Pinned node was removed")
+		}
+		if view.Replace(testIdentity("hub2"), Node{Identity: testIdentity("id1"), Address: "address3"}) || !view.Contains(testIdentity("hub2")) {
+			t.Fatalf("Pinned node was replaced")
+		}
+		if !view.IsPinned(testIdentity("hub1")) || view.IsPinned(testIdentity("id1")) {
+			t.Fatalf("Unexpected pinned identities")
+		}
+	})
+	t.Run("nodes are dropped once all nodes of a full view are pinned", func(t *testing.T) {
+		view := NewView(WithPinnedNodes(pinned), WithMaxSize(2))
+		view.Append(Node{Identity: testIdentity("id1"), Address: "address3"})
+		if view.Contains(testIdentity("id1")) || view.NodeCount() != 2 {
+			t.Fatalf("Expected the appended node to be dropped, but got %v", view.nodes)
+		}
+	})
+}
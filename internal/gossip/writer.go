@@ -2,6 +2,7 @@ package gossip
 
 import (
 	"encoding/binary"
+	"fmt"
 )
 
 // WritablePacket represents a packet struct that can be converted to a slice of bytes.
@@ -9,18 +10,22 @@ type WritablePacket interface {
 	ToBytes() []byte
 }
 
-// ToBytes converts the Identity struct to a slice of bytes
+// ToBytes converts the Identity struct to a slice of bytes, which is a copy the caller may modify.
 func (n *Identity) ToBytes() []byte {
-	return []byte(*n)
+	return append([]byte(nil), n[:]...)
 }
 
 // ToBytes converts the Node struct to a slice of bytes.
-// The Node object takes the form of <Identity>\t<Address>\n
+// The Node object takes the form of <Identity>\t<Address>\n, or <Identity>\t<Address>\t<Capabilities>\n with the capabilities
+// as 4 hex digits if any capability is set.
 func (n *Node) ToBytes() []byte {
 	var bytes []byte
 	bytes = append(bytes, n.Identity.ToBytes()...)
 	bytes = append(bytes, []byte("\t")...)
 	bytes = append(bytes, []byte(n.Address)...)
+	if n.Capabilities != 0 {
+		bytes = append(bytes, []byte(fmt.Sprintf("\t%04x", uint16(n.Capabilities)))...)
+	}
 	bytes = append(bytes, []byte("\n")...)
 	return bytes
 }
@@ -31,7 +36,7 @@ func (p *PacketHeader) ToBytes() []byte {
 	bytes = binary.BigEndian.AppendUint16(bytes, p.Size)
 	bytes = binary.BigEndian.AppendUint16(bytes, uint16(p.Type))
 	bytes = binary.BigEndian.AppendUint64(bytes, p.Timestamp)
-	bytes = append(bytes, p.SenderIdentity...)
+	bytes = append(bytes, p.SenderIdentity[:]...)
 	return bytes
 }
 
@@ -44,6 +49,15 @@ func (p *PacketFooter) ToBytes() []byte {
 
 // ToBytes converts the PacketPing struct to a slice of bytes.
 func (p *PacketPing) ToBytes() []byte {
+	var bytes []byte
+	bytes = append(bytes, p.PacketHeader.ToBytes()...)
+	bytes = binary.BigEndian.AppendUint64(bytes, p.Nonce)
+	bytes = append(bytes, p.PacketFooter.ToBytes()...)
+	return bytes
+}
+
+// ToBytes converts the PacketLeave struct to a slice of bytes.
+func (p *PacketLeave) ToBytes() []byte {
 	var bytes []byte
 	bytes = append(bytes, p.PacketHeader.ToBytes()...)
 	bytes = append(bytes, p.PacketFooter.ToBytes()...)
@@ -54,6 +68,7 @@ func (p *PacketPing) ToBytes() []byte {
 func (p *PacketPong) ToBytes() []byte {
 	var bytes []byte
 	bytes = append(bytes, p.PacketHeader.ToBytes()...)
+	bytes = binary.BigEndian.AppendUint64(bytes, p.Nonce)
 	bytes = append(bytes, p.PacketFooter.ToBytes()...)
 	return bytes
 }
@@ -111,10 +126,78 @@ func (p *PacketMessage) ToBytes() []byte {
 	var bytes []byte
 	bytes = append(bytes, p.PacketHeader.ToBytes()...)
 	bytes = append(bytes, byte(p.TTL))
-	// Appending 0x00 as the reserved byte.
-	bytes = append(bytes, byte(0x00))
+	bytes = append(bytes, p.Flags)
 	bytes = binary.BigEndian.AppendUint16(bytes, p.DataType)
+	if p.Flags&MessageFlagOriginTimestamp != 0 {
+		bytes = binary.BigEndian.AppendUint64(bytes, p.OriginTimestamp)
+	}
 	bytes = append(bytes, p.Data...)
 	bytes = append(bytes, p.PacketFooter.ToBytes()...)
 	return bytes
 }
+
+// ToBytes converts the MessageBatchEntry struct to a slice of bytes.
+func (e *MessageBatchEntry) ToBytes() []byte {
+	var bytes []byte
+	bytes = append(bytes, byte(e.TTL))
+	bytes = append(bytes, e.Flags)
+	bytes = binary.BigEndian.AppendUint16(bytes, e.DataType)
+	bytes = binary.BigEndian.AppendUint16(bytes, uint16(len(e.Data)))
+	if e.Flags&MessageFlagOriginTimestamp != 0 {
+		bytes = binary.BigEndian.AppendUint64(bytes, e.OriginTimestamp)
+	}
+	bytes = append(bytes, e.Data...)
+	return bytes
+}
+
+// ToBytes converts the PacketMessageBatch struct to a slice of bytes.
+func (p *PacketMessageBatch) ToBytes() []byte {
+	var bytes []byte
+	bytes = append(bytes, p.PacketHeader.ToBytes()...)
+	for _, msg := range p.Messages {
+		bytes = append(bytes, msg.ToBytes()...)
+	}
+	bytes = append(bytes, p.PacketFooter.ToBytes()...)
+	return bytes
+}
+
+// ToBytes converts the DigestEntry struct to a slice of bytes.
+func (e *DigestEntry) ToBytes() []byte {
+	var bytes []byte
+	bytes = binary.BigEndian.AppendUint16(bytes, e.DataType)
+	bytes = append(bytes, e.DataHash...)
+	return bytes
+}
+
+// ToBytes converts the PacketDigest struct to a slice of bytes.
+func (p *PacketDigest) ToBytes() []byte {
+	var bytes []byte
+	bytes = append(bytes, p.PacketHeader.ToBytes()...)
+	for _, entry := range p.Entries {
+		bytes = append(bytes, entry.ToBytes()...)
+	}
+	bytes = append(bytes, p.PacketFooter.ToBytes()...)
+	return bytes
+}
+
+// ToBytes converts the PacketDigestRequest struct to a slice of bytes.
+func (p *PacketDigestRequest) ToBytes() []byte {
+	var bytes []byte
+	bytes = append(bytes, p.PacketHeader.ToBytes()...)
+	for _, entry := range p.Entries {
+		bytes = append(bytes, entry.ToBytes()...)
+	}
+	bytes = append(bytes, p.PacketFooter.ToBytes()...)
+	return bytes
+}
+
+// ToBytes converts the PacketIHave struct to a slice of bytes.
+func (p *PacketIHave) ToBytes() []byte {
+	var bytes []byte
+	bytes = append(bytes, p.PacketHeader.ToBytes()...)
+	for _, entry := range p.Entries {
+		bytes = append(bytes, entry.ToBytes()...)
+	}
+	bytes = append(bytes, p.PacketFooter.ToBytes()...)
+	return bytes
+}
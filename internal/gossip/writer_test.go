@@ -13,13 +13,13 @@ func TestIdentity_ToBytes(t *testing.T) {
 	t.Parallel()
 	t.Run("identity is serialized successfully to a byte slice", func(t *testing.T) {
 		// Create an Identity value for testing
-		id := Identity("test_identity")
+		id := testIdentity("test_identity")
 
 		// Call the ToBytes method to get the byte slice
 		b := id.ToBytes()
 
 		// Define the expected byte slice based on the string representation
-		expectedBytes := []byte(id)
+		expectedBytes := id[:]
 
 		// Compare the actual bytes with the expected bytes
 		if !bytes.Equal(b, expectedBytes) {
@@ -54,6 +54,13 @@ func TestNode_ToBytes(t *testing.T) {
 			t.Errorf("Second delimiter incorrect: expected %s, received %s", "\n", delim2)
 		}
 	})
+	t.Run("capabilities follow the address", func(t *testing.T) {
+		node := Node{Identity: Identity(sliceRepeat(IdentitySize, byte(0x12))), Address: "1.2.3.4:5678", Capabilities: CapabilityFragmentation | CapabilityEd25519}
+		rest := string(node.ToBytes()[IdentitySize:])
+		if rest != "\t1.2.3.4:5678\t0006\n" {
+			t.Errorf("expected the capabilities 0006 after the address, received %q", rest)
+		}
+	})
 }
 
 func TestPacketHeader_ToBytes(t *testing.T) {
@@ -159,12 +166,13 @@ func TestPacketPing_ToBytes(t *testing.T) {
 		}
 		p := PacketPing{
 			PacketHeader: ph,
+			Nonce:        0x0123456789abcdef,
 			PacketFooter: pf,
 		}
 
 		b := p.ToBytes()
-		if len(b) != 556 {
-			t.Errorf("wrong binary blob size: expected 556, received %d", len(b))
+		if len(b) != 564 {
+			t.Errorf("wrong binary blob size: expected 564, received %d", len(b))
 		}
 		size := binary.BigEndian.Uint16(b[0:2])
 		if size != 100 {
@@ -183,7 +191,11 @@ func TestPacketPing_ToBytes(t *testing.T) {
 		if !bytes.Equal(si, mockSenderIdentity.ToBytes()) {
 			t.Errorf("pingPacket.SenderIdentity incorrect: expected %v, received %v", mockSenderIdentity, si)
 		}
-		sig := b[44:]
+		nonce := binary.BigEndian.Uint64(b[44:52])
+		if nonce != 0x0123456789abcdef {
+			t.Errorf("pingPacket.Nonce incorrect: expected 0x0123456789abcdef, received %#x", nonce)
+		}
+		sig := b[52:]
 		if !bytes.Equal(sig, mockSignature) {
 			t.Errorf("pingPacket.Signature incorrect: expected %v, received %v", mockSignature, sig)
 		}
@@ -212,12 +224,13 @@ func TestPacketPong_ToBytes(t *testing.T) {
 		}
 		p := PacketPong{
 			PacketHeader: ph,
+			Nonce:        0x0123456789abcdef,
 			PacketFooter: pf,
 		}
 
 		b := p.ToBytes()
-		if len(b) != 556 {
-			t.Errorf("wrong binary blob size: expected 556, received %d", len(b))
+		if len(b) != 564 {
+			t.Errorf("wrong binary blob size: expected 564, received %d", len(b))
 		}
 		size := binary.BigEndian.Uint16(b[0:2])
 		if size != 100 {
@@ -232,7 +245,11 @@ func TestPacketPong_ToBytes(t *testing.T) {
 		if !bytes.Equal(si, mockSenderIdentity.ToBytes()) {
 			t.Errorf("pingPacket.SenderIdentity incorrect: expected %v, received %v", mockSenderIdentity, si)
 		}
-		sig := b[44:]
+		nonce := binary.BigEndian.Uint64(b[44:52])
+		if nonce != 0x0123456789abcdef {
+			t.Errorf("pingPacket.Nonce incorrect: expected 0x0123456789abcdef, received %#x", nonce)
+		}
+		sig := b[52:]
 		if !bytes.Equal(sig, mockSignature) {
 			t.Errorf("pingPacket.Signature incorrect: expected %v, received %v", mockSignature, sig)
 		}
@@ -681,3 +698,47 @@ func createMockSignature() []byte {
 	}
 	return mockSignature
 }
+
+func TestPacketDigest_ToBytes(t *testing.T) {
+	t.Parallel()
+	t.Run("packet digest is serialized successfully to byte slice", func(t *testing.T) {
+		temp := sha256.Sum256(nil)
+		mockSenderIdentity, err := NewIdentity(temp[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		mockHash := sliceRepeat(sha256.Size, byte(0x12))
+		p, err := NewPacketDigest(*mockSenderIdentity, []DigestEntry{{DataType: 456, DataHash: mockHash}}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Signature = createMockSignature()
+		b := p.ToBytes()
+		if len(b) != PacketHeaderSize+DigestEntrySize+SignatureSize || binary.BigEndian.Uint16(b[0:2]) != uint16(len(b)) {
+			t.Fatalf("packet size incorrect: %d bytes, size attribute %d", len(b), binary.BigEndian.Uint16(b[0:2]))
+		}
+		if MessageType(binary.BigEndian.Uint16(b[2:4])) != MessageTypeGossipDigest {
+			t.Errorf("packet message type incorrect: expected 0x0070, received %x", b[2:4])
+		}
+		if dt := binary.BigEndian.Uint16(b[44:46]); dt != 456 {
+			t.Errorf("entry data type incorrect: expected %d, received %d", 456, dt)
+		}
+		if !bytes.Equal(b[46:46+sha256.Size], mockHash) {
+			t.Errorf("entry data hash incorrect: expected %v, received %v", mockHash, b[46:46+sha256.Size])
+		}
+		if !bytes.Equal(b[46+sha256.Size:], p.Signature) {
+			t.Errorf("packet signature incorrect: expected %v, received %v", p.Signature, b[46+sha256.Size:])
+		}
+	})
+	t.Run("too many entries fail", func(t *testing.T) {
+		temp := sha256.Sum256(nil)
+		entries := make([]DigestEntry, MaxDigestEntries+1)
+		for i := range entries {
+			entries[i] = DigestEntry{DataHash: temp[:]}
+		}
+		_, err := NewPacketDigest(Identity(temp[:]), entries, time.Now())
+		if err != ErrCreatePacketInvalidComponentSize {
+			t.Errorf("expected ErrCreatePacketInvalidComponentSize, received %v", err)
+		}
+	})
+}
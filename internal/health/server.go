@@ -0,0 +1,71 @@
+// Package health provides HTTP liveness and readiness probes of a running node, e.g. for Kubernetes or container orchestration.
+package health
+
+import (
+	"errors"
+	"gossiphers/internal/logging"
+	"net"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// ReadinessCheck returns nil if the node is ready to serve, otherwise an error describing why it is not.
+type ReadinessCheck func() error
+
+// Server represents the HTTP listener serving /healthz, which succeeds as long as the process is running, and /readyz, which succeeds once the ReadinessCheck passes.
+type Server struct {
+	address  string
+	ready    ReadinessCheck
+	listener net.Listener
+}
+
+// logger returns the logger of the gossip subsystem, to which the probes belong.
+func logger() *zap.Logger {
+	return logging.L(logging.Gossip)
+}
+
+// NewServer returns a new instance of Server listening at the given address once started.
+func NewServer(address string, ready ReadinessCheck) *Server {
+	return &Server{address: address, ready: ready}
+}
+
+// Start starts listening for HTTP requests.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if err := s.ready(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok\n"))
+	})
+
+	logger().Info("Health Server listening", zap.String("address", listener.Addr().String()))
+	go func() {
+		err := http.Serve(listener, mux)
+		if err != nil && !errors.Is(err, net.ErrClosed) {
+			logger().Warn("Health Server stopped", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the server is listening at, which differs from the configured one if port 0 was given.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops listening for HTTP requests.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
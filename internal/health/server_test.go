@@ -0,0 +1,47 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestServer_Start(t *testing.T) {
+	t.Parallel()
+	t.Run("reports readiness according to the check", func(t *testing.T) {
+		t.Parallel()
+		var ready atomic.Bool
+		server := NewServer("127.0.0.1:0", func() error {
+			if !ready.Load() {
+				return errors.New("not ready")
+			}
+			return nil
+		})
+		err := server.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer server.Close()
+
+		// statusOf returns the status code of a GET request to the given path.
+		statusOf := func(path string) int {
+			resp, err := http.Get("http://" + server.Addr().String() + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+		if status := statusOf("/healthz"); status != http.StatusOK {
+			t.Errorf("expected /healthz to succeed, received %d", status)
+		}
+		if status := statusOf("/readyz"); status != http.StatusServiceUnavailable {
+			t.Errorf("expected /readyz to fail before being ready, received %d", status)
+		}
+		ready.Store(true)
+		if status := statusOf("/readyz"); status != http.StatusOK {
+			t.Errorf("expected /readyz to succeed once ready, received %d", status)
+		}
+	})
+}
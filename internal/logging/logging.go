@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"gossiphers/internal/config"
+)
+
+// Subsystems whose log level can be configured individually.
+const (
+	Gossip    = "gossip"
+	API       = "api"
+	Challenge = "challenge"
+	Crypto    = "crypto"
+)
+
+var (
+	// loggers maps the name of a subsystem to its configured logger.
+	loggers      = make(map[string]*zap.Logger)
+	mutexLoggers sync.RWMutex
+)
+
+// Setup builds the global logger as well as the loggers of all subsystems according to the configuration and replaces the global zap logger.
+// Subsystems without an explicitly configured log level use the global log level.
+func Setup(cfg *config.GossipConfig) error {
+	globalLevel, err := zapcore.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log_level: %w", err)
+	}
+
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	var encoder zapcore.Encoder
+	switch cfg.LogEncoding {
+	case "json":
+		encoder = zapcore.NewJSONEncoder(encoderCfg)
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(encoderCfg)
+	default:
+		return fmt.Errorf("invalid log_encoding, expected json or console: received %s", cfg.LogEncoding)
+	}
+
+	var output zapcore.WriteSyncer = zapcore.Lock(os.Stderr)
+	if cfg.LogFile != "" {
+		output = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   cfg.LogFile,
+			MaxSize:    cfg.LogFileMaxSizeMB,
+			MaxBackups: cfg.LogFileMaxBackups,
+			MaxAge:     cfg.LogFileMaxAgeDays,
+		})
+	}
+
+	newLogger := func(level zapcore.Level) *zap.Logger {
+		return zap.New(zapcore.NewCore(encoder, output, level), zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+	}
+
+	subsystemLoggers := make(map[string]*zap.Logger)
+	for _, subsystem := range []string{Gossip, API, Challenge, Crypto} {
+		level := globalLevel
+		if levelStr := cfg.SubsystemLogLevels[subsystem]; levelStr != "" {
+			level, err = zapcore.ParseLevel(levelStr)
+			if err != nil {
+				return fmt.Errorf("invalid log level of subsystem %s: %w", subsystem, err)
+			}
+		}
+		subsystemLoggers[subsystem] = newLogger(level).Named(subsystem)
+	}
+
+	mutexLoggers.Lock()
+	loggers = subsystemLoggers
+	mutexLoggers.Unlock()
+	zap.ReplaceGlobals(newLogger(globalLevel))
+	return nil
+}
+
+// L returns the logger of the given subsystem, falling back to the global logger if no logger was set up for it.
+func L(subsystem string) *zap.Logger {
+	mutexLoggers.RLock()
+	defer mutexLoggers.RUnlock()
+	if logger, ok := loggers[subsystem]; ok {
+		return logger
+	}
+	return zap.L()
+}
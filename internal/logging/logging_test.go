@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	"gossiphers/internal/config"
+)
+
+func TestLogging_Setup(t *testing.T) {
+	t.Run("subsystems use their own log level or fall back to the global one", func(t *testing.T) {
+		logFile := filepath.Join(t.TempDir(), "gossip.log")
+		cfg := &config.GossipConfig{
+			LogLevel:           "warn",
+			LogEncoding:        "console",
+			SubsystemLogLevels: map[string]string{Gossip: "debug"},
+			LogFile:            logFile,
+			LogFileMaxSizeMB:   1,
+		}
+		err := Setup(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !L(Gossip).Core().Enabled(zapcore.DebugLevel) {
+			t.Error("gossip subsystem should log debug entries")
+		}
+		if L(API).Core().Enabled(zapcore.InfoLevel) || !L(API).Core().Enabled(zapcore.WarnLevel) {
+			t.Error("api subsystem should use the global log level")
+		}
+
+		L(Crypto).Warn("written to the log file")
+		_ = L(Crypto).Sync()
+		content, err := os.ReadFile(logFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), "written to the log file") || !strings.Contains(string(content), Crypto) {
+			t.Errorf("log file does not contain the expected entry: %s", content)
+		}
+	})
+	t.Run("returns error on invalid configuration", func(t *testing.T) {
+		err := Setup(&config.GossipConfig{LogLevel: "verbose", LogEncoding: "json"})
+		if err == nil {
+			t.Error("expecting error on invalid log level")
+		}
+		err = Setup(&config.GossipConfig{LogLevel: "info", LogEncoding: "xml"})
+		if err == nil {
+			t.Error("expecting error on invalid encoding")
+		}
+		err = Setup(&config.GossipConfig{LogLevel: "info", LogEncoding: "json", SubsystemLogLevels: map[string]string{API: "loud"}})
+		if err == nil {
+			t.Error("expecting error on invalid subsystem log level")
+		}
+	})
+}
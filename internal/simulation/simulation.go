@@ -0,0 +1,395 @@
+// Package simulation runs the membership layer of many gossip nodes within a single process. The nodes use the main views,
+// samplers, and view update policy of the real protocol, but exchange pushes and pull responses over an in-memory network
+// and advance a virtual clock by one round interval per step, so hundreds of nodes can be studied without Docker or sockets.
+package simulation
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"gossiphers/internal/config"
+	"gossiphers/internal/gossip"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTooFewNodes is returned if a simulation is created with fewer than two nodes.
+	ErrTooFewNodes = errors.New("a simulation requires at least two nodes")
+	// ErrAdversaryNotSimulated is returned for adversarial behaviors that only affect the dissemination of messages, which is not simulated.
+	ErrAdversaryNotSimulated = errors.New("the adversarial behavior is not simulated")
+)
+
+// simNode represents the membership state of a simulated node.
+type simNode struct {
+	node     gossip.Node
+	mainView *gossip.View
+	samplers *gossip.SamplerGroup
+	// pushView and pullView collect the nodes received during the current round
+	pushView *gossip.View
+	pullView *gossip.View
+	// pullRequests is the number of pull requests sent during the current round
+	pullRequests int
+	alive        bool
+	// adversary is the adversarial behavior of the node as defined by the config package, empty for honest nodes
+	adversary string
+	// random is the source of randomness of the samplers, views, and view updates of the node, derived from the simulation's seed
+	random io.Reader
+}
+
+// answers reports whether the node answers pings and pull requests.
+func (n *simNode) answers() bool {
+	return n.alive && n.adversary != config.AdversarySilent
+}
+
+// Simulation represents a network of simulated nodes sharing one configuration.
+type Simulation struct {
+	cfg            *config.GossipConfig
+	nodes          []*simNode
+	byIdentity     map[gossip.Identity]*simNode
+	policy         gossip.ViewUpdatePolicy
+	samplerHash    gossip.SamplerHashFunc
+	bootstrapPeers int
+	rng            *rand.Rand
+	round          int
+}
+
+// Option represents a functional option for the Simulation's 'constructor'.
+type Option func(*Simulation)
+
+// WithSeed seeds the choice of push and pull targets, failed nodes, and bootstrap peers, as well as the samplers, views, and
+// view updates of every node, so runs with the same seed are identical.
+func WithSeed(seed int64) Option {
+	return func(s *Simulation) {
+		s.rng = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithBootstrapPeers lets every node join with the given number of random nodes that joined before it, 3 by default.
+// A star topology, in which every node bootstraps with the first one, does not converge for large networks, as the pushes
+// of all nodes to the first one exceed AlphaL1 and are discarded as a push flood.
+func WithBootstrapPeers(n int) Option {
+	return func(s *Simulation) {
+		s.bootstrapPeers = n
+	}
+}
+
+// WithViewUpdatePolicy replaces the policy deciding on the next main view of every node, which defaults to gossip.BrahmsViewUpdatePolicy.
+func WithViewUpdatePolicy(policy gossip.ViewUpdatePolicy) Option {
+	return func(s *Simulation) {
+		s.policy = policy
+	}
+}
+
+// New returns a simulation of numNodes nodes using the view size, weights, sampler size, and ping interval of the given configuration.
+func New(cfg *config.GossipConfig, numNodes int, options ...Option) (*Simulation, error) {
+	if numNodes < 2 {
+		return nil, ErrTooFewNodes
+	}
+	if cfg.ViewSize <= 0 || cfg.SamplerSize <= 0 || cfg.RoundsBetweenPings <= 0 {
+		return nil, fmt.Errorf("degree (%d), l2 (%d), and rounds_between_pings (%d) must be greater than 0", cfg.ViewSize, cfg.SamplerSize, cfg.RoundsBetweenPings)
+	}
+	samplerHash, err := gossip.SamplerHashByName(cfg.SamplerHash)
+	if err != nil {
+		return nil, err
+	}
+	s := &Simulation{
+		cfg:            cfg,
+		byIdentity:     make(map[gossip.Identity]*simNode),
+		policy:         gossip.BrahmsViewUpdatePolicy{},
+		samplerHash:    samplerHash,
+		bootstrapPeers: 3,
+		rng:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	err = s.Join(numNodes)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Join adds nodes which bootstrap with live nodes that joined before them.
+func (s *Simulation) Join(count int) error {
+	for i := 0; i < count; i++ {
+		var bootstrapNodes []gossip.Node
+		alive := s.aliveNodes()
+		for _, j := range s.rng.Perm(len(alive))[:minInt(s.bootstrapPeers, len(alive))] {
+			bootstrapNode := alive[j].node
+			bootstrapNode.Source = gossip.SourceBootstrap
+			bootstrapNodes = append(bootstrapNodes, bootstrapNode)
+		}
+		err := s.addNode(bootstrapNodes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addNode adds a live node with the given bootstrap nodes.
+func (s *Simulation) addNode(bootstrapNodes []gossip.Node) error {
+	index := len(s.nodes)
+	identity := sha256.Sum256([]byte(fmt.Sprintf("simulated node %d", index)))
+	node, err := gossip.NewNode(identity[:], fmt.Sprintf("node%d:7002", index))
+	if err != nil {
+		return err
+	}
+	random := gossip.NewSeededRandom(s.rng.Int63())
+	samplers, err := gossip.NewSamplerGroup(s.cfg.SamplerSize, gossip.WithSamplerExcludedIdentity(node.Identity), gossip.WithSamplerHash(s.samplerHash), gossip.WithSamplerRandom(random))
+	if err != nil {
+		return err
+	}
+	samplers.Update(bootstrapNodes)
+	n := &simNode{
+		node:     *node,
+		mainView: gossip.NewView(gossip.WithBootstrapNodes(bootstrapNodes), gossip.WithMaxSize(s.cfg.ViewSize), gossip.WithExcludedIdentity(node.Identity), gossip.WithRandom(random)),
+		samplers: samplers,
+		pushView: gossip.NewView(gossip.WithMaxSize(s.cfg.PushPullViewMaxSize), gossip.WithExcludedIdentity(node.Identity), gossip.WithRandom(random)),
+		pullView: gossip.NewView(gossip.WithMaxSize(s.cfg.PushPullViewMaxSize), gossip.WithExcludedIdentity(node.Identity), gossip.WithRandom(random)),
+		alive:    true,
+		random:   random,
+	}
+	s.nodes = append(s.nodes, n)
+	s.byIdentity[node.Identity] = n
+	return nil
+}
+
+// Fail stops count random live nodes, which neither send nor answer packets from then on, and returns the number of failed nodes.
+func (s *Simulation) Fail(count int) int {
+	alive := s.aliveNodes()
+	count = minInt(count, len(alive))
+	for _, i := range s.rng.Perm(len(alive))[:count] {
+		alive[i].alive = false
+	}
+	return count
+}
+
+// Corrupt turns count random live honest nodes into adversaries with the given behavior and returns their number.
+// Push flooding nodes push to every node of their main view and samplers, pull poisoning nodes answer pull requests with
+// fabricated nodes, and silent nodes never answer pings or pull requests. Message spam is not simulated.
+func (s *Simulation) Corrupt(behavior string, count int) (int, error) {
+	switch behavior {
+	case config.AdversaryPushFlood, config.AdversaryPullPoison, config.AdversarySilent:
+	case config.AdversaryMessageSpam:
+		return 0, fmt.Errorf("%w: %s", ErrAdversaryNotSimulated, behavior)
+	default:
+		return 0, fmt.Errorf("unknown adversarial behavior %q", behavior)
+	}
+	var honest []*simNode
+	for _, n := range s.aliveNodes() {
+		if n.adversary == "" {
+			honest = append(honest, n)
+		}
+	}
+	count = minInt(count, len(honest))
+	for _, i := range s.rng.Perm(len(honest))[:count] {
+		honest[i].adversary = behavior
+	}
+	return count, nil
+}
+
+// Round returns the number of completed rounds.
+func (s *Simulation) Round() int {
+	return s.round
+}
+
+// Elapsed returns the virtual time passed since the start of the simulation, one round interval per completed round.
+func (s *Simulation) Elapsed() time.Duration {
+	return time.Duration(s.round*s.cfg.RoundIntervalMs) * time.Millisecond
+}
+
+// Step simulates a single round of all live nodes and returns the state of the network at its end.
+// Like in the real protocol, every node pushes its identity to AlphaL1 and pulls the main views of BetaL1 random nodes of its
+// main view at the start of the round, then updates its main view and samplers based on what it received.
+func (s *Simulation) Step() (RoundStats, error) {
+	s.round++
+	alive := s.aliveNodes()
+	// pull responses contain the main view at the start of the round
+	roundViews := make(map[gossip.Identity][]gossip.Node, len(alive))
+	pullResponses := make(map[gossip.Identity][]gossip.Node, len(alive))
+	for _, n := range alive {
+		roundViews[n.node.Identity] = n.mainView.GetAll()
+		pullResponses[n.node.Identity] = roundViews[n.node.Identity]
+		if n.adversary == config.AdversaryPullPoison {
+			fabricated, err := gossip.FabricateNodes(n.random, s.cfg.ViewSize)
+			if err != nil {
+				return RoundStats{}, err
+			}
+			pullResponses[n.node.Identity] = fabricated
+		}
+	}
+
+	// the in-memory network delivers packets instantly, packets to failed nodes are lost
+	for _, n := range alive {
+		view := roundViews[n.node.Identity]
+		for _, target := range s.pushTargets(n, view) {
+			if peer, ok := s.byIdentity[target.Identity]; ok && peer.alive {
+				pushed := n.node
+				pushed.Source = gossip.SourcePush
+				peer.pushView.Append(pushed)
+			}
+		}
+		pullTargets := s.randomSubset(view, s.betaL1())
+		n.pullRequests = len(pullTargets)
+		for _, target := range pullTargets {
+			if peer, ok := s.byIdentity[target.Identity]; ok && peer.answers() {
+				for _, pulled := range pullResponses[target.Identity] {
+					pulled.Source = gossip.SourcePull
+					n.pullView.Append(pulled)
+				}
+			}
+		}
+	}
+
+	// the nodes update their state independently of each other
+	updated := make([]bool, len(alive))
+	errs := make([]error, len(alive))
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				updated[i], errs[i] = s.completeRound(alive[i])
+			}
+		}()
+	}
+	for i := range alive {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	err := errors.Join(errs...)
+	if err != nil {
+		return RoundStats{}, err
+	}
+
+	stats := s.Stats()
+	for _, u := range updated {
+		if u {
+			stats.UpdatedViews++
+		}
+	}
+	return stats, nil
+}
+
+// Run simulates the given number of rounds and passes the state of the network at the end of each round to fn, which may be nil.
+func (s *Simulation) Run(rounds int, fn func(RoundStats)) error {
+	for i := 0; i < rounds; i++ {
+		stats, err := s.Step()
+		if err != nil {
+			return fmt.Errorf("round %d failed: %w", s.round, err)
+		}
+		if fn != nil {
+			fn(stats)
+		}
+	}
+	return nil
+}
+
+// completeRound applies the view update policy to the nodes received by a node during the round, health-checks its due
+// sampled nodes, and feeds the received nodes into its samplers. Returns whether the main view was replaced.
+func (s *Simulation) completeRound(n *simNode) (bool, error) {
+	pushNodes := n.pushView.GetAll()
+	pullNodes := n.pullView.GetAll()
+	n.pushView.Clear()
+	n.pullView.Clear()
+	nodes, update, err := s.policy.NextView(gossip.ViewUpdateInput{
+		PushNodes:    pushNodes,
+		PullNodes:    pullNodes,
+		PullRequests: n.pullRequests,
+		Samplers:     n.samplers,
+		AlphaL1:      s.alphaL1(),
+		BetaL1:       s.betaL1(),
+		GammaL1:      s.gammaL1(),
+		Random:       n.random,
+	})
+	if err != nil {
+		return false, err
+	}
+	if update {
+		n.mainView = gossip.NewView(gossip.WithBootstrapNodes(nodes), gossip.WithMaxSize(s.cfg.ViewSize), gossip.WithExcludedIdentity(n.node.Identity), gossip.WithRandom(n.random))
+	}
+
+	// failed and silent nodes never answer pings, fabricated ones do not exist
+	pinged, err := n.samplers.DuePings(s.round, s.cfg.RoundsBetweenPings)
+	if err != nil {
+		return false, err
+	}
+	for _, node := range pinged {
+		if peer, ok := s.byIdentity[node.Identity]; ok && peer.answers() {
+			n.samplers.MarkValidated(node.Identity)
+			continue
+		}
+		err = n.samplers.Invalidate(node.Identity)
+		if err != nil {
+			return false, err
+		}
+	}
+	n.samplers.Update(pushNodes)
+	n.samplers.Update(pullNodes)
+	return update, nil
+}
+
+// aliveNodes returns the live nodes in the order they joined.
+func (s *Simulation) aliveNodes() []*simNode {
+	var alive []*simNode
+	for _, n := range s.nodes {
+		if n.alive {
+			alive = append(alive, n)
+		}
+	}
+	return alive
+}
+
+// pushTargets returns the nodes a node pushes to during the round: AlphaL1 random nodes of its main view, or all nodes of
+// its main view and samplers if it floods pushes.
+func (s *Simulation) pushTargets(n *simNode, view []gossip.Node) []gossip.Node {
+	if n.adversary != config.AdversaryPushFlood {
+		return s.randomSubset(view, s.alphaL1())
+	}
+	targets := append([]gossip.Node(nil), view...)
+	for _, sample := range n.samplers.SampleAll() {
+		targets = append(targets, *sample)
+	}
+	return targets
+}
+
+// randomSubset returns up to n random nodes of the given ones.
+func (s *Simulation) randomSubset(nodes []gossip.Node, n int) []gossip.Node {
+	subset := make([]gossip.Node, 0, minInt(n, len(nodes)))
+	for _, i := range s.rng.Perm(len(nodes))[:minInt(n, len(nodes))] {
+		subset = append(subset, nodes[i])
+	}
+	return subset
+}
+
+// alphaL1, betaL1, and gammaL1 return the number of pushed, pulled, and sampled nodes within a main view, like their
+// counterparts of gossip.Gossip.
+func (s *Simulation) alphaL1() int {
+	return int(math.Round(float64(s.cfg.ViewSize) * s.cfg.Alpha))
+}
+
+func (s *Simulation) betaL1() int {
+	return int(math.Round(float64(s.cfg.ViewSize) * s.cfg.Beta))
+}
+
+func (s *Simulation) gammaL1() int {
+	return int(math.Round(float64(s.cfg.ViewSize) * s.cfg.Gamma))
+}
+
+// minInt returns the smaller of two integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
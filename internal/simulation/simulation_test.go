@@ -0,0 +1,164 @@
+package simulation
+
+import (
+	"errors"
+	"gossiphers/internal/config"
+	"testing"
+	"time"
+)
+
+// testConfig returns the membership parameters of the default configuration with a smaller view.
+func testConfig() *config.GossipConfig {
+	return &config.GossipConfig{
+		ViewSize:            8,
+		SamplerSize:         8,
+		PushPullViewMaxSize: 100,
+		Alpha:               .45,
+		Beta:                .45,
+		Gamma:               .1,
+		RoundIntervalMs:     1000,
+		RoundsBetweenPings:  2,
+		SamplerHash:         "sha256",
+	}
+}
+
+func TestSimulation(t *testing.T) {
+	t.Parallel()
+	t.Run("rejects networks of a single node", func(t *testing.T) {
+		t.Parallel()
+		_, err := New(testConfig(), 1)
+		if !errors.Is(err, ErrTooFewNodes) {
+			t.Fatalf("expected ErrTooFewNodes, received %v", err)
+		}
+	})
+	t.Run("runs with the same seed are identical", func(t *testing.T) {
+		t.Parallel()
+		var runs [2][]RoundStats
+		for i := range runs {
+			sim, err := New(testConfig(), 30, WithSeed(9))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = sim.Corrupt(config.AdversaryPullPoison, 3); err != nil {
+				t.Fatal(err)
+			}
+			err = sim.Run(10, func(stats RoundStats) { runs[i] = append(runs[i], stats) })
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for round := range runs[0] {
+			if runs[0][round] != runs[1][round] {
+				t.Fatalf("runs diverged in round %d: %s and %s", round+1, runs[0][round], runs[1][round])
+			}
+		}
+	})
+	t.Run("converges to connected full views", func(t *testing.T) {
+		t.Parallel()
+		sim, err := New(testConfig(), 50, WithSeed(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		initial := sim.Stats()
+		if initial.MeanViewSize > 3 {
+			t.Fatalf("expected every node to bootstrap with at most 3 nodes: %s", initial)
+		}
+		// the bootstrap nodes always joined earlier, so no two nodes reach each other yet
+		if initial.Connected != 1.0/50 {
+			t.Fatalf("expected no connected nodes before the first round: %s", initial)
+		}
+		var last RoundStats
+		err = sim.Run(30, func(stats RoundStats) { last = stats })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last.Round != 30 || last.Elapsed != 30*time.Second {
+			t.Errorf("expected the virtual clock at round 30 and 30s, received %d and %s", last.Round, last.Elapsed)
+		}
+		// small views occasionally leave single nodes unknown for a few rounds
+		if last.MeanViewSize < 7 || last.Connected < .9 {
+			t.Errorf("expected full views of a connected network: %s", last)
+		}
+		if last.InDegreeMax > 3*8 {
+			t.Errorf("expected no hubs known by most nodes: %s", last)
+		}
+	})
+	t.Run("forgets failed nodes", func(t *testing.T) {
+		t.Parallel()
+		sim, err := New(testConfig(), 40, WithSeed(2), WithBootstrapPeers(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sim.Run(20, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if failed := sim.Fail(10); failed != 10 {
+			t.Fatalf("expected 10 failed nodes, received %d", failed)
+		}
+		failedStats := sim.Stats()
+		if failedStats.Alive != 30 || failedStats.DeadEntries == 0 {
+			t.Fatalf("expected views referring to the failed nodes: %s", failedStats)
+		}
+		err = sim.Run(30, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats := sim.Stats(); stats.DeadEntries >= failedStats.DeadEntries/2 || stats.Connected < .9 {
+			t.Errorf("expected the failed nodes to be replaced, after the failure: %s, now: %s", failedStats, stats)
+		}
+	})
+	t.Run("joins nodes during the simulation", func(t *testing.T) {
+		t.Parallel()
+		sim, err := New(testConfig(), 10, WithSeed(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sim.Join(5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = sim.Run(20, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats := sim.Stats(); stats.Alive != 15 || stats.Connected < .9 {
+			t.Errorf("expected the joined nodes to be known by others: %s", stats)
+		}
+	})
+	t.Run("rejects adversaries that are not simulated", func(t *testing.T) {
+		t.Parallel()
+		sim, err := New(testConfig(), 10, WithSeed(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = sim.Corrupt(config.AdversaryMessageSpam, 1)
+		if !errors.Is(err, ErrAdversaryNotSimulated) {
+			t.Errorf("expected ErrAdversaryNotSimulated, received %v", err)
+		}
+		if _, err = sim.Corrupt("byzantine", 1); err == nil {
+			t.Error("expected an error for an unknown behavior")
+		}
+	})
+	for i, behavior := range []string{config.AdversaryPushFlood, config.AdversaryPullPoison, config.AdversarySilent} {
+		behavior, seed := behavior, int64(5+i)
+		t.Run("resists "+behavior+" adversaries", func(t *testing.T) {
+			t.Parallel()
+			sim, err := New(testConfig(), 60, WithSeed(seed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if corrupted, err := sim.Corrupt(behavior, 6); err != nil || corrupted != 6 {
+				t.Fatalf("expected 6 adversaries, received %d and %v", corrupted, err)
+			}
+			err = sim.Run(40, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// small views occasionally consist of adversaries only for a few rounds, but 10% of adversaries must never take over
+			if stats := sim.Stats(); stats.Adversaries != 6 || stats.Eclipsed > 3 || stats.AdversaryEntries > .4 {
+				t.Errorf("expected honest nodes to keep honest nodes within their views: %s", stats)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package simulation
+
+import (
+	"fmt"
+	"gossiphers/internal/gossip"
+	"math"
+	"time"
+)
+
+// RoundStats describes the state of the simulated network at the end of a round.
+type RoundStats struct {
+	Round int
+	// Elapsed is the virtual time passed since the start of the simulation
+	Elapsed time.Duration
+	Alive   int
+	// UpdatedViews is the number of live nodes that replaced their main view during the round
+	UpdatedViews int
+	// MeanViewSize is the average number of nodes within the main views of live nodes
+	MeanViewSize float64
+	// InDegreeMin, InDegreeMax, and InDegreeStdDev describe how often live nodes are contained within the main views of
+	// other live nodes. Brahms converges towards a narrow in-degree distribution.
+	InDegreeMin    int
+	InDegreeMax    int
+	InDegreeStdDev float64
+	// DeadEntries is the share of main view entries of live nodes that refer to failed nodes
+	DeadEntries float64
+	// Connected is the share of live nodes within the largest group of nodes that can all reach each other by following the
+	// main views of live nodes. A message spread along the main views reaches at most this share of the network.
+	Connected float64
+	// Adversaries is the number of live adversarial nodes
+	Adversaries int
+	// AdversaryEntries is the share of main view entries of live honest nodes that refer to adversaries or fabricated nodes
+	AdversaryEntries float64
+	// Eclipsed is the number of live honest nodes whose main view contains no live honest node
+	Eclipsed int
+}
+
+// String returns a single line summary of the statistics.
+func (rs RoundStats) String() string {
+	summary := fmt.Sprintf("round=%d elapsed=%s alive=%d updated=%d view=%.1f in_degree=%d..%d (sd %.2f) dead_entries=%.1f%% connected=%.1f%%",
+		rs.Round, rs.Elapsed, rs.Alive, rs.UpdatedViews, rs.MeanViewSize, rs.InDegreeMin, rs.InDegreeMax, rs.InDegreeStdDev,
+		100*rs.DeadEntries, 100*rs.Connected)
+	if rs.Adversaries > 0 {
+		summary += fmt.Sprintf(" adversaries=%d adversary_entries=%.1f%% eclipsed=%d", rs.Adversaries, 100*rs.AdversaryEntries, rs.Eclipsed)
+	}
+	return summary
+}
+
+// Stats returns the state of the simulated network. UpdatedViews is only known at the end of a round and thus left 0.
+func (s *Simulation) Stats() RoundStats {
+	stats := RoundStats{Round: s.round, Elapsed: s.Elapsed()}
+	alive := s.aliveNodes()
+	stats.Alive = len(alive)
+	if len(alive) == 0 {
+		return stats
+	}
+
+	views := make(map[gossip.Identity][]gossip.Node, len(alive))
+	inDegree := make(map[gossip.Identity]int, len(alive))
+	entries, deadEntries := 0, 0
+	for _, n := range alive {
+		view := n.mainView.GetAll()
+		views[n.node.Identity] = view
+		entries += len(view)
+		for _, node := range view {
+			if peer, ok := s.byIdentity[node.Identity]; ok && peer.alive {
+				inDegree[node.Identity]++
+			} else {
+				deadEntries++
+			}
+		}
+	}
+	stats.MeanViewSize = float64(entries) / float64(len(alive))
+	if entries > 0 {
+		stats.DeadEntries = float64(deadEntries) / float64(entries)
+	}
+
+	stats.InDegreeMin = math.MaxInt
+	mean := float64(entries-deadEntries) / float64(len(alive))
+	variance := 0.0
+	for _, n := range alive {
+		degree := inDegree[n.node.Identity]
+		stats.InDegreeMin = minInt(stats.InDegreeMin, degree)
+		if degree > stats.InDegreeMax {
+			stats.InDegreeMax = degree
+		}
+		variance += (float64(degree) - mean) * (float64(degree) - mean)
+	}
+	stats.InDegreeStdDev = math.Sqrt(variance / float64(len(alive)))
+
+	stats.Connected = float64(largestComponent(alive, views)) / float64(len(alive))
+
+	honestEntries, adversaryEntries := 0, 0
+	for _, n := range alive {
+		if n.adversary != "" {
+			stats.Adversaries++
+			continue
+		}
+		eclipsed := true
+		for _, node := range views[n.node.Identity] {
+			honestEntries++
+			peer, ok := s.byIdentity[node.Identity]
+			switch {
+			case !ok || peer.adversary != "":
+				adversaryEntries++
+			case peer.alive:
+				eclipsed = false
+			}
+		}
+		if eclipsed {
+			stats.Eclipsed++
+		}
+	}
+	if honestEntries > 0 {
+		stats.AdversaryEntries = float64(adversaryEntries) / float64(honestEntries)
+	}
+	return stats
+}
+
+// largestComponent returns the number of nodes within the largest strongly connected component of the graph formed by the
+// given main views, edges to failed nodes are ignored. Uses Kosaraju's algorithm with iterative depth-first searches.
+func largestComponent(alive []*simNode, views map[gossip.Identity][]gossip.Node) int {
+	reversed := make(map[gossip.Identity][]gossip.Identity, len(alive))
+	for identity, view := range views {
+		for _, node := range view {
+			if _, ok := views[node.Identity]; ok {
+				reversed[node.Identity] = append(reversed[node.Identity], identity)
+			}
+		}
+	}
+
+	// order the nodes by the time their depth-first search finished
+	type frame struct {
+		identity gossip.Identity
+		next     int
+	}
+	visited := make(map[gossip.Identity]bool, len(alive))
+	finished := make([]gossip.Identity, 0, len(alive))
+	for _, n := range alive {
+		if visited[n.node.Identity] {
+			continue
+		}
+		visited[n.node.Identity] = true
+		stack := []frame{{identity: n.node.Identity}}
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			view := views[top.identity]
+			if top.next == len(view) {
+				finished = append(finished, top.identity)
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			node := view[top.next]
+			top.next++
+			if _, ok := views[node.Identity]; ok && !visited[node.Identity] {
+				visited[node.Identity] = true
+				stack = append(stack, frame{identity: node.Identity})
+			}
+		}
+	}
+
+	// every search on the reversed graph in reverse finishing order covers exactly one component
+	assigned := make(map[gossip.Identity]bool, len(alive))
+	largest := 0
+	for i := len(finished) - 1; i >= 0; i-- {
+		if assigned[finished[i]] {
+			continue
+		}
+		assigned[finished[i]] = true
+		size := 0
+		stack := []gossip.Identity{finished[i]}
+		for len(stack) > 0 {
+			identity := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			size++
+			for _, predecessor := range reversed[identity] {
+				if !assigned[predecessor] {
+					assigned[predecessor] = true
+					stack = append(stack, predecessor)
+				}
+			}
+		}
+		if size > largest {
+			largest = size
+		}
+	}
+	return largest
+}
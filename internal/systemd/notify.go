@@ -0,0 +1,70 @@
+// Package systemd implements the sd_notify protocol, which services of Type=notify use to report readiness and watchdog pings to systemd.
+// It talks to the socket given by systemd directly, so no libsystemd is required.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// States understood by systemd, see sd_notify(3).
+const (
+	Ready     = "READY=1"
+	Reloading = "RELOADING=1"
+	Stopping  = "STOPPING=1"
+	Watchdog  = "WATCHDOG=1"
+)
+
+// Notify sends the given newline-separated state assignments to systemd. It returns false without an error if the
+// process was not started by systemd with a notification socket, e.g. during development or for services not of Type=notify.
+func Notify(state string) (bool, error) {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return false, nil
+	}
+	// an abstract socket is indicated by a leading @
+	if socketPath[0] == '@' {
+		socketPath = "\x00" + socketPath[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("could not connect to the notification socket: %w", err)
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	if err != nil {
+		return false, fmt.Errorf("could not send %s to the notification socket: %w", state, err)
+	}
+	return true, nil
+}
+
+// Status returns the state assignment of a free-form status shown by systemctl status.
+func Status(status string) string {
+	return "STATUS=" + status
+}
+
+// WatchdogInterval returns the interval in which systemd expects watchdog pings from this process, 0 if the watchdog is disabled.
+func WatchdogInterval() (time.Duration, error) {
+	usecStr := os.Getenv("WATCHDOG_USEC")
+	if usecStr == "" {
+		return 0, nil
+	}
+	// the watchdog applies to another process, e.g. the parent shell
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			return 0, fmt.Errorf("invalid WATCHDOG_PID %s: %w", pidStr, err)
+		}
+		if pid != os.Getpid() {
+			return 0, nil
+		}
+	}
+	usec, err := strconv.ParseInt(usecStr, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %s", usecStr)
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
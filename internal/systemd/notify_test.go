@@ -0,0 +1,76 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	t.Run("does nothing without notification socket", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+		sent, err := Notify(Ready)
+		if sent || err != nil {
+			t.Errorf("expected nothing to be sent, received %t, %v", sent, err)
+		}
+	})
+	t.Run("sends the state to the notification socket", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "notify.sock")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+		if err != nil {
+			t.Skipf("unixgram sockets are not supported: %v", err)
+		}
+		defer conn.Close()
+		t.Setenv("NOTIFY_SOCKET", socketPath)
+
+		sent, err := Notify(Ready + "\n" + Status("round 1"))
+		if !sent || err != nil {
+			t.Fatalf("expected the state to be sent, received %t, %v", sent, err)
+		}
+		buf := make([]byte, 64)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != "READY=1\nSTATUS=round 1" {
+			t.Errorf("unexpected state %q", buf[:n])
+		}
+	})
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Run("disabled without WATCHDOG_USEC", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "")
+		interval, err := WatchdogInterval()
+		if interval != 0 || err != nil {
+			t.Errorf("expected the watchdog to be disabled, received %s, %v", interval, err)
+		}
+	})
+	t.Run("returns the interval for this process", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "30000000")
+		t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+		interval, err := WatchdogInterval()
+		if interval != 30*time.Second || err != nil {
+			t.Errorf("expected 30s, received %s, %v", interval, err)
+		}
+	})
+	t.Run("disabled for other processes", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "30000000")
+		t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+		interval, err := WatchdogInterval()
+		if interval != 0 || err != nil {
+			t.Errorf("expected the watchdog to be disabled, received %s, %v", interval, err)
+		}
+	})
+	t.Run("invalid interval fails", func(t *testing.T) {
+		t.Setenv("WATCHDOG_USEC", "soon")
+		t.Setenv("WATCHDOG_PID", "")
+		if _, err := WatchdogInterval(); err == nil {
+			t.Error("expected an error for an invalid interval")
+		}
+	})
+}
@@ -0,0 +1,180 @@
+// Package transport abstracts the datagram sockets of the gossip server, so several servers can be connected in memory within a single test.
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// Transport represents a datagram socket. It is the subset of net.PacketConn used by the gossip server.
+type Transport interface {
+	// ReadFrom blocks until a datagram is received and copies it into p. Once the transport is closed, an error wrapping net.ErrClosed is returned.
+	ReadFrom(p []byte) (n int, addr net.Addr, err error)
+	// WriteTo sends a datagram to the given address.
+	WriteTo(p []byte, addr net.Addr) (n int, err error)
+	// LocalAddr returns the address the transport is bound to.
+	LocalAddr() net.Addr
+	// Close stops the transport, blocked ReadFrom calls return with an error.
+	Close() error
+}
+
+// Network represents a network transports are bound to.
+type Network interface {
+	// Listen returns a transport bound to the given <host>:<port> address.
+	Listen(address string) (Transport, error)
+}
+
+// udpNetwork implements Network with UDP sockets of the operating system.
+type udpNetwork struct{}
+
+// UDP returns the network of UDP sockets.
+func UDP() Network {
+	return udpNetwork{}
+}
+
+// Listen returns a UDP socket bound to the given address.
+func (udpNetwork) Listen(address string) (Transport, error) {
+	return net.ListenPacket("udp", address)
+}
+
+// Or returns the given network, or the network of UDP sockets if it is nil.
+func Or(n Network) Network {
+	if n == nil {
+		return UDP()
+	}
+	return n
+}
+
+var (
+	ErrAddressInUse = errors.New("address already in use")
+)
+
+// loopbackQueueSize is the number of datagrams queued per loopback transport, further datagrams are dropped like by a full socket buffer.
+const loopbackQueueSize = 1024
+
+// Loopback is an in-memory Network delivering datagrams between its transports without sockets.
+// Like UDP, datagrams to addresses nobody listens on are silently dropped.
+type Loopback struct {
+	transports map[string]*loopbackTransport
+	nextPort   int
+	mutex      sync.Mutex
+}
+
+// NewLoopback returns a new in-memory network without transports.
+func NewLoopback() *Loopback {
+	return &Loopback{
+		transports: make(map[string]*loopbackTransport),
+		nextPort:   49152,
+	}
+}
+
+// Listen returns a transport bound to the given address, which must consist of an IP address and a port.
+// A port of 0 binds the transport to an unused port.
+func (l *Loopback) Listen(address string) (Transport, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("loopback address %s does not contain an IP address", address)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("loopback address %s contains an invalid port: %w", address, err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	addr := &net.UDPAddr{IP: ip, Port: port}
+	for port == 0 {
+		addr.Port = l.nextPort
+		l.nextPort++
+		if _, ok := l.transports[addr.String()]; !ok {
+			break
+		}
+	}
+	if _, ok := l.transports[addr.String()]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrAddressInUse, addr)
+	}
+	t := &loopbackTransport{
+		network:  l,
+		addr:     addr,
+		incoming: make(chan datagram, loopbackQueueSize),
+		closed:   make(chan struct{}),
+	}
+	l.transports[addr.String()] = t
+	return t, nil
+}
+
+// deliver queues a datagram at the transport bound to the address, it is dropped if there is none or its queue is full.
+func (l *Loopback) deliver(d datagram, to net.Addr) {
+	l.mutex.Lock()
+	t, ok := l.transports[to.String()]
+	l.mutex.Unlock()
+	if !ok {
+		return
+	}
+	select {
+	case t.incoming <- d:
+	case <-t.closed:
+	default:
+	}
+}
+
+// datagram represents a datagram in transit within a Loopback network.
+type datagram struct {
+	data []byte
+	from net.Addr
+}
+
+// loopbackTransport implements Transport within a Loopback network.
+type loopbackTransport struct {
+	network   *Loopback
+	addr      *net.UDPAddr
+	incoming  chan datagram
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// ReadFrom blocks until a datagram is received or the transport is closed.
+func (t *loopbackTransport) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case d := <-t.incoming:
+		return copy(p, d.data), d.from, nil
+	case <-t.closed:
+		return 0, nil, &net.OpError{Op: "read", Net: "loopback", Addr: t.addr, Err: net.ErrClosed}
+	}
+}
+
+// WriteTo sends a copy of the datagram to the transport bound to the address.
+func (t *loopbackTransport) WriteTo(p []byte, addr net.Addr) (int, error) {
+	select {
+	case <-t.closed:
+		return 0, &net.OpError{Op: "write", Net: "loopback", Addr: addr, Err: net.ErrClosed}
+	default:
+	}
+	t.network.deliver(datagram{data: append([]byte(nil), p...), from: t.addr}, addr)
+	return len(p), nil
+}
+
+// LocalAddr returns the address the transport is bound to.
+func (t *loopbackTransport) LocalAddr() net.Addr {
+	return t.addr
+}
+
+// Close unbinds the transport from its address.
+func (t *loopbackTransport) Close() error {
+	err := error(&net.OpError{Op: "close", Net: "loopback", Addr: t.addr, Err: net.ErrClosed})
+	t.closeOnce.Do(func() {
+		t.network.mutex.Lock()
+		delete(t.network.transports, t.addr.String())
+		t.network.mutex.Unlock()
+		close(t.closed)
+		err = nil
+	})
+	return err
+}
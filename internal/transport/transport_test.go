@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestLoopback(t *testing.T) {
+	t.Parallel()
+	t.Run("delivers datagrams between transports", func(t *testing.T) {
+		t.Parallel()
+		network := NewLoopback()
+		a, err := network.Listen("127.0.0.1:7001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := network.Listen("127.0.0.1:7002")
+		if err != nil {
+			t.Fatal(err)
+		}
+		to, err := net.ResolveUDPAddr("udp", "127.0.0.1:7002")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent := []byte("gossip")
+		if _, err = a.WriteTo(sent, to); err != nil {
+			t.Fatal(err)
+		}
+		sent[0] = 'G'
+
+		buf := make([]byte, 64)
+		n, from, err := b.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != "gossip" {
+			t.Errorf("expected the datagram to be copied when sent, received %q", buf[:n])
+		}
+		if from.String() != a.LocalAddr().String() {
+			t.Errorf("expected the datagram from %s, received it from %s", a.LocalAddr(), from)
+		}
+	})
+	t.Run("drops datagrams to addresses nobody listens on", func(t *testing.T) {
+		t.Parallel()
+		a, err := NewLoopback().Listen("127.0.0.1:7001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := a.WriteTo([]byte("gossip"), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 7002})
+		if err != nil || n != 6 {
+			t.Errorf("expected the datagram to be sent silently, received %d and %v", n, err)
+		}
+	})
+	t.Run("assigns unused ports and rejects used addresses", func(t *testing.T) {
+		t.Parallel()
+		network := NewLoopback()
+		a, err := network.Listen("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.LocalAddr().(*net.UDPAddr).Port == 0 {
+			t.Error("expected a port to be assigned")
+		}
+		_, err = network.Listen(a.LocalAddr().String())
+		if !errors.Is(err, ErrAddressInUse) {
+			t.Errorf("expected ErrAddressInUse, received %v", err)
+		}
+	})
+	t.Run("closing unblocks reads and frees the address", func(t *testing.T) {
+		t.Parallel()
+		network := NewLoopback()
+		a, err := network.Listen("127.0.0.1:7001")
+		if err != nil {
+			t.Fatal(err)
+		}
+		readErr := make(chan error)
+		go func() {
+			_, _, err := a.ReadFrom(make([]byte, 64))
+			readErr <- err
+		}()
+		if err = a.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err = <-readErr; !errors.Is(err, net.ErrClosed) {
+			t.Errorf("expected net.ErrClosed, received %v", err)
+		}
+		if err = a.Close(); !errors.Is(err, net.ErrClosed) {
+			t.Errorf("expected closing twice to fail with net.ErrClosed, received %v", err)
+		}
+		if _, err = network.Listen("127.0.0.1:7001"); err != nil {
+			t.Errorf("expected the address to be free again, received %v", err)
+		}
+	})
+}
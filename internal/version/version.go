@@ -0,0 +1,73 @@
+// Package version holds the build metadata of the gossip binary.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// The build metadata is embedded at build time, e.g.
+//
+//	go build -ldflags "-X gossiphers/internal/version.Version=1.2.0 -X gossiphers/internal/version.Commit=$(git rev-parse HEAD) -X gossiphers/internal/version.BuildDate=$(date -u +%FT%TZ)" ./cmd/gossip
+var (
+	// Version represents the semantic version of the build, "dev" for development builds.
+	Version = "dev"
+	// Commit represents the git commit the binary was built from. If unset, the revision recorded by the Go toolchain is used.
+	Commit = ""
+	// BuildDate represents the time the binary was built in RFC 3339 format.
+	BuildDate = ""
+)
+
+// Info represents the build metadata of the running binary.
+type Info struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	// CommitDate is the time of the commit as recorded by the Go toolchain
+	CommitDate string `json:"commit_date,omitempty"`
+	// Modified is set if the working tree had uncommitted changes at build time
+	Modified  bool   `json:"modified,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build metadata of the running binary. The commit falls back to the VCS information recorded by the Go toolchain if it was not embedded at build time.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			info.CommitDate = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// String returns the version followed by the short commit, e.g. "1.2.0 (3f2a9c1)".
+func (i Info) String() string {
+	commit := i.Commit
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	if commit == "" {
+		commit = "unknown commit"
+	}
+	if i.Modified {
+		commit += ", modified"
+	}
+	return i.Version + " (" + commit + ")"
+}
@@ -0,0 +1,25 @@
+package version
+
+import "testing"
+
+func TestInfo_String(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		info     Info
+		expected string
+	}{
+		{"shortens the commit", Info{Version: "1.2.0", Commit: "3f2a9c1d8e7b"}, "1.2.0 (3f2a9c1)"},
+		{"marks modified builds", Info{Version: "dev", Commit: "3f2a9c1", Modified: true}, "dev (3f2a9c1, modified)"},
+		{"without commit", Info{Version: "dev"}, "dev (unknown commit)"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if s := tt.info.String(); s != tt.expected {
+				t.Errorf("expected %s, received %s", tt.expected, s)
+			}
+		})
+	}
+}